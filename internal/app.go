@@ -37,6 +37,7 @@ import (
 	"go.uber.org/zap"
 	"gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
 	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
@@ -160,6 +161,26 @@ func (app *Application) Init() {
 				Value: "",
 				Usage: "force rebuild accounting entries for order",
 			},
+			cli.StringFlag{
+				Name:  "from",
+				Value: "",
+				Usage: "first day of the export period, i.e. 2006-01-02",
+			},
+			cli.StringFlag{
+				Name:  "to",
+				Value: "",
+				Usage: "last day of the export period, i.e. 2006-01-31",
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Value: "",
+				Usage: "format of the accounting entries export, csv or gl_csv",
+			},
+			cli.StringFlag{
+				Name:  "output",
+				Value: "",
+				Usage: "path of the export file",
+			},
 		),
 	}
 
@@ -251,6 +272,12 @@ func (app *Application) Init() {
 		app.logger.Fatal("Service init failed", zap.Error(err))
 	}
 
+	err = service.RegisterBillingServiceExtendedHandler(app.service.Server(), app.svc)
+
+	if err != nil {
+		app.logger.Fatal("Service init failed", zap.Error(err))
+	}
+
 	app.router = http.NewServeMux()
 	app.initHealth()
 	app.initMetrics()
@@ -406,10 +433,80 @@ func (app *Application) TaskRebuildAccountingEntries(orderId string, force bool)
 	return app.svc.RebuildAccountingEntries(context.TODO(), orderId, force)
 }
 
+// TaskExportAccountingEntries writes the export of the accounting entries created from the first till the last day
+// of the period inclusive to the output file, or to the file in the current directory if the output isn't passed.
+func (app *Application) TaskExportAccountingEntries(from, to, format, output string) error {
+	dateFrom, err := time.Parse("2006-01-02", from)
+
+	if err != nil {
+		return err
+	}
+
+	dateTo, err := time.Parse("2006-01-02", to)
+
+	if err != nil {
+		return err
+	}
+
+	req := &pkg.ExportAccountingEntriesRequest{
+		DateFrom: dateFrom.Unix(),
+		DateTo:   dateTo.AddDate(0, 0, 1).Unix(),
+		Format:   format,
+	}
+	rsp := &pkg.ExportAccountingEntriesResponse{}
+	err = app.svc.ExportAccountingEntries(context.TODO(), req, rsp)
+
+	if err != nil {
+		return err
+	}
+
+	if rsp.Status != billingpb.ResponseStatusOk {
+		return rsp.Message
+	}
+
+	if output == "" {
+		output = rsp.Item.FileName
+	}
+
+	return ioutil.WriteFile(output, rsp.Item.File, 0644)
+}
+
 func (app *Application) TaskFixReportDates() error {
 	return app.svc.TaskFixReportDates(context.TODO())
 }
 
+func (app *Application) TaskExpireDisputes() error {
+	return app.svc.ExpireDisputes(context.TODO())
+}
+
+func (app *Application) TaskExpireHeldOrders() error {
+	return app.svc.ExpireHeldOrders(context.TODO())
+}
+
+func (app *Application) TaskConvertSubscriptionTrials() error {
+	return app.svc.ConvertSubscriptionTrials(context.TODO())
+}
+
+func (app *Application) TaskProcessSubscriptionDunning() error {
+	return app.svc.ProcessSubscriptionDunning(context.TODO())
+}
+
+func (app *Application) TaskResumeSubscriptions() error {
+	return app.svc.ResumeSubscriptions(context.TODO())
+}
+
+func (app *Application) TaskNotifyExpiringSavedCards() error {
+	return app.svc.NotifyExpiringSavedCards(context.TODO())
+}
+
+func (app *Application) TaskExpireGiftCards() error {
+	return app.svc.ExpireGiftCards(context.TODO())
+}
+
+func (app *Application) TaskChainAuditLog() error {
+	return app.svc.ChainAuditLog(context.TODO())
+}
+
 func (app *Application) TaskMerchantsMigrate() error {
 	return app.svc.MerchantsMigrate(context.TODO())
 }
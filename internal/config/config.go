@@ -16,6 +16,20 @@ type PaymentSystemConfig struct {
 	CardPayApiSandboxUrl string `envconfig:"CARD_PAY_API_SANDBOX_URL" required:"true"`
 	RedirectUrlSuccess   string `envconfig:"REDIRECT_URL_SUCCESS" default:"https://checkout.pay.super.com/pay/order/?result=success"`
 	RedirectUrlFail      string `envconfig:"REDIRECT_URL_FAIL" default:"https://checkout.pay.super.com/pay/order/?result=fail"`
+
+	CheckoutApiUrl              string `envconfig:"CHECKOUT_API_URL" default:"https://api.checkout.com"`
+	CheckoutApiSandboxUrl       string `envconfig:"CHECKOUT_API_SANDBOX_URL" default:"https://api.sandbox.checkout.com"`
+	CheckoutSecretKey           string `envconfig:"CHECKOUT_SECRET_KEY" default:""`
+	CheckoutWebhookSecret       string `envconfig:"CHECKOUT_WEBHOOK_SECRET" default:""`
+	CheckoutProcessingChannelId string `envconfig:"CHECKOUT_PROCESSING_CHANNEL_ID" default:""`
+
+	PaymentSystemBreakerThreshold int   `envconfig:"PAYMENT_SYSTEM_BREAKER_THRESHOLD" default:"5"`
+	PaymentSystemBreakerCooldown  int64 `envconfig:"PAYMENT_SYSTEM_BREAKER_COOLDOWN" default:"60"`
+
+	PaymentSystemSimulatorEnabled              bool   `envconfig:"PAYMENT_SYSTEM_SIMULATOR_ENABLED" default:"false"`
+	PaymentSystemSimulatorOutcome              string `envconfig:"PAYMENT_SYSTEM_SIMULATOR_OUTCOME" default:"success"`
+	PaymentSystemSimulatorCallbackDelay        int64  `envconfig:"PAYMENT_SYSTEM_SIMULATOR_CALLBACK_DELAY" default:"1"`
+	PaymentSystemSimulatorDelayedCallbackDelay int64  `envconfig:"PAYMENT_SYSTEM_SIMULATOR_DELAYED_CALLBACK_DELAY" default:"60"`
 }
 
 type CustomerTokenConfig struct {
@@ -55,6 +69,34 @@ type EmailTemplates struct {
 	MerchantAgreementSigned        string `envconfig:"EMAIL_MERCHANT_AGREEMENT_SIGNED" default:"p1_agreement_fully_signed"`
 	RoyaltyReportFinancier         string `envconfig:"EMAIL_ROYALTY_REPORT_FINANCIER" default:"p1_royalty_report_financier"`
 	PayoutInvoiceFinancier         string `envconfig:"EMAIL_PAYOUT_INVOICE_FINANCIER" default:"p1_payout_invoice_financier"`
+	SubscriptionPaymentFailed      string `envconfig:"EMAIL_SUBSCRIPTION_PAYMENT_FAILED_TEMPLATE" default:"p1_subscription_payment_failed"`
+	SavedCardExpiring              string `envconfig:"EMAIL_SAVED_CARD_EXPIRING_TEMPLATE" default:"p1_saved_card_expiring"`
+	RefundApprovalRequired         string `envconfig:"EMAIL_REFUND_APPROVAL_REQUIRED_TEMPLATE" default:"p1_refund_approval_required"`
+	RefundFallback                 string `envconfig:"EMAIL_REFUND_FALLBACK_TEMPLATE" default:"p1_refund_fallback"`
+}
+
+// FraudConfig defines the rule set of the fraud screening of payments. Every matched rule adds its score to the risk
+// score of the order, rules with zero score are disabled. The order is sent to review or declined when the risk score
+// reaches the threshold of the action. Payment of the order sent to review is held until the manual review or timeout.
+type FraudConfig struct {
+	FraudScreeningEnabled bool `envconfig:"FRAUD_SCREENING_ENABLED" default:"false"`
+	FraudReviewScore      int  `envconfig:"FRAUD_REVIEW_SCORE" default:"50"`
+	FraudDeclineScore     int  `envconfig:"FRAUD_DECLINE_SCORE" default:"100"`
+
+	FraudReviewTimeout int64 `envconfig:"FRAUD_REVIEW_TIMEOUT" default:"86400"`
+
+	FraudVelocityPeriod        int64 `envconfig:"FRAUD_VELOCITY_PERIOD" default:"3600"`
+	FraudCustomerVelocityLimit int64 `envconfig:"FRAUD_CUSTOMER_VELOCITY_LIMIT" default:"5"`
+	FraudCustomerVelocityScore int   `envconfig:"FRAUD_CUSTOMER_VELOCITY_SCORE" default:"40"`
+	FraudIpVelocityLimit       int64 `envconfig:"FRAUD_IP_VELOCITY_LIMIT" default:"10"`
+	FraudIpVelocityScore       int   `envconfig:"FRAUD_IP_VELOCITY_SCORE" default:"40"`
+	FraudBinVelocityLimit      int64 `envconfig:"FRAUD_BIN_VELOCITY_LIMIT" default:"30"`
+	FraudBinVelocityScore      int   `envconfig:"FRAUD_BIN_VELOCITY_SCORE" default:"20"`
+
+	FraudCountryMismatchScore int `envconfig:"FRAUD_COUNTRY_MISMATCH_SCORE" default:"30"`
+
+	FraudEmailDomainBlacklist []string `envconfig:"FRAUD_EMAIL_DOMAIN_BLACKLIST" default:""`
+	FraudEmailDomainScore     int      `envconfig:"FRAUD_EMAIL_DOMAIN_SCORE" default:"100"`
 }
 
 type Centrifugo struct {
@@ -103,10 +145,13 @@ type Config struct {
 	UserInviteTokenSecret  string `envconfig:"USER_INVITE_TOKEN_SECRET" required:"true"`
 	UserInviteTokenTimeout int64  `envconfig:"USER_INVITE_TOKEN_TIMEOUT" default:"48"`
 
+	AuditLogHmacSecret string `envconfig:"AUDIT_LOG_HMAC_SECRET" required:"true"`
+
 	*PaymentSystemConfig
 	*CustomerTokenConfig
 	*CacheRedis
 	*EmailTemplates
+	*FraudConfig
 
 	CentrifugoPaymentForm *Centrifugo `envconfig:"CENTRIFUGO_PAYMENT_FORM"`
 	CentrifugoDashboard   *Centrifugo `envconfig:"CENTRIFUGO_DASHBOARD"`
@@ -117,6 +162,16 @@ type Config struct {
 
 	MigrationsLockTimeout int64 `envconfig:"MIGRATIONS_LOCK_TIMEOUT" default:"60"`
 
+	IdempotencyKeyTtl     int64 `envconfig:"IDEMPOTENCY_KEY_TTL" default:"86400"`
+	IdempotencyKeyLockTtl int64 `envconfig:"IDEMPOTENCY_KEY_LOCK_TTL" default:"300"`
+
+	DisputeEvidencePeriod int64 `envconfig:"DISPUTE_EVIDENCE_PERIOD" default:"604800"`
+
+	// Default schedule of retries of failed recurring payments in days since the payment failure and the period in
+	// days while the subscription stays unpaid before the cancellation, projects can override them.
+	DunningRetryDays  []int32 `envconfig:"DUNNING_RETRY_DAYS" default:"1,3,7"`
+	DunningUnpaidDays int32   `envconfig:"DUNNING_UNPAID_DAYS" default:"7"`
+
 	DashboardUrl string `envconfig:"DASHBOARD_URL" default:"https://paysupermgmt.tst.protocol.one"`
 	CheckoutUrl  string `envconfig:"CHECKOUT_URL" default:"https://checkout.tst.pay.super.com"`
 
@@ -190,6 +245,38 @@ func (cfg *Config) GetEmailConfirmTokenLifetime() time.Duration {
 	return time.Second * time.Duration(cfg.EmailConfirmTokenLifetime)
 }
 
+func (cfg *Config) GetPaymentSystemBreakerCooldown() time.Duration {
+	return time.Second * time.Duration(cfg.PaymentSystemBreakerCooldown)
+}
+
+func (cfg *Config) GetIdempotencyKeyTtl() time.Duration {
+	return time.Second * time.Duration(cfg.IdempotencyKeyTtl)
+}
+
+func (cfg *Config) GetIdempotencyKeyLockTtl() time.Duration {
+	return time.Second * time.Duration(cfg.IdempotencyKeyLockTtl)
+}
+
+func (cfg *Config) GetFraudVelocityPeriod() time.Duration {
+	return time.Second * time.Duration(cfg.FraudVelocityPeriod)
+}
+
+func (cfg *Config) GetFraudReviewTimeout() time.Duration {
+	return time.Second * time.Duration(cfg.FraudReviewTimeout)
+}
+
+func (cfg *Config) GetDisputeEvidencePeriod() time.Duration {
+	return time.Second * time.Duration(cfg.DisputeEvidencePeriod)
+}
+
+func (cfg *Config) GetPaymentSystemSimulatorCallbackDelay() time.Duration {
+	return time.Second * time.Duration(cfg.PaymentSystemSimulatorCallbackDelay)
+}
+
+func (cfg *Config) GetPaymentSystemSimulatorDelayedCallbackDelay() time.Duration {
+	return time.Second * time.Duration(cfg.PaymentSystemSimulatorDelayedCallbackDelay)
+}
+
 func (cfg *Config) GetUserConfirmEmailUrl(params map[string]string) string {
 	query := cfg.EmailConfirmUrlParsed.Query()
 
@@ -275,3 +362,19 @@ func (cfg *Config) GetAdminOnboardingRequestsUrl() string {
 func (cfg *Config) GetUserInviteUrl(token string) string {
 	return fmt.Sprintf(pkg.UserInviteUrl, cfg.DashboardUrl, token)
 }
+
+func (cfg *Config) GetSubscriptionUpdateCardUrl(subscriptionId string) string {
+	return fmt.Sprintf(pkg.SubscriptionUpdateCardUrl, cfg.CheckoutUrl, subscriptionId)
+}
+
+func (cfg *Config) GetSavedCardUpdateUrl(token string) string {
+	return fmt.Sprintf(pkg.SavedCardUpdateUrl, cfg.CheckoutUrl, token)
+}
+
+func (cfg *Config) GetRefundApprovalUrl(orderId, refundId string) string {
+	return fmt.Sprintf(pkg.RefundApprovalUrl, cfg.DashboardUrl, orderId, refundId)
+}
+
+func (cfg *Config) GetRefundFallbackUrl(token string) string {
+	return fmt.Sprintf(pkg.RefundFallbackUrl, cfg.CheckoutUrl, token)
+}
@@ -226,6 +226,29 @@ func (_m *AccountingEntryRepositoryInterface) GetDistinctBySourceId(_a0 context.
 	return r0, r1
 }
 
+// GetExportSummary provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4
+func (_m *AccountingEntryRepositoryInterface) GetExportSummary(_a0 context.Context, _a1 string, _a2 string, _a3 time.Time, _a4 time.Time) ([]*pkg.AccountingExportQueryResItem, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4)
+
+	var r0 []*pkg.AccountingExportQueryResItem
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time, time.Time) []*pkg.AccountingExportQueryResItem); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*pkg.AccountingExportQueryResItem)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRollingReserveForBalance provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4
 func (_m *AccountingEntryRepositoryInterface) GetRollingReserveForBalance(_a0 context.Context, _a1 string, _a2 string, _a3 []string, _a4 time.Time) ([]*pkg.ReserveQueryResItem, error) {
 	ret := _m.Called(_a0, _a1, _a2, _a3, _a4)
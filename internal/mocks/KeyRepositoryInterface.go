@@ -160,3 +160,26 @@ func (_m *KeyRepositoryInterface) ReserveKey(_a0 context.Context, _a1 string, _a
 
 	return r0, r1
 }
+
+// RevokeById provides a mock function with given fields: _a0, _a1
+func (_m *KeyRepositoryInterface) RevokeById(_a0 context.Context, _a1 string) (*billingpb.Key, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *billingpb.Key
+	if rf, ok := ret.Get(0).(func(context.Context, string) *billingpb.Key); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*billingpb.Key)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
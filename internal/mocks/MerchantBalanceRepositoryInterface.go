@@ -55,6 +55,29 @@ func (_m *MerchantBalanceRepositoryInterface) GetByIdAndCurrency(_a0 context.Con
 	return r0, r1
 }
 
+// GetCurrencies provides a mock function with given fields: _a0, _a1
+func (_m *MerchantBalanceRepositoryInterface) GetCurrencies(_a0 context.Context, _a1 string) ([]string, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, string) []string); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Insert provides a mock function with given fields: _a0, _a1
 func (_m *MerchantBalanceRepositoryInterface) Insert(_a0 context.Context, _a1 *billingpb.MerchantBalance) error {
 	ret := _m.Called(_a0, _a1)
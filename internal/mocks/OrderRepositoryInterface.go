@@ -13,6 +13,27 @@ type OrderRepositoryInterface struct {
 	mock.Mock
 }
 
+// CountBy provides a mock function with given fields: ctx, filter
+func (_m *OrderRepositoryInterface) CountBy(ctx context.Context, filter primitive.M) (int64, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.M) int64); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, primitive.M) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetById provides a mock function with given fields: _a0, _a1
 func (_m *OrderRepositoryInterface) GetById(_a0 context.Context, _a1 string) (*billingpb.Order, error) {
 	ret := _m.Called(_a0, _a1)
@@ -230,6 +251,27 @@ func (_m *OrderRepositoryInterface) Update(_a0 context.Context, _a1 *billingpb.O
 	return r0
 }
 
+// UpdateOneBy provides a mock function with given fields: ctx, filter, update
+func (_m *OrderRepositoryInterface) UpdateOneBy(ctx context.Context, filter bson.M, update bson.M) (bool, error) {
+	ret := _m.Called(ctx, filter, update)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, bson.M, bson.M) bool); ok {
+		r0 = rf(ctx, filter, update)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, bson.M, bson.M) error); ok {
+		r1 = rf(ctx, filter, update)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateOrderView provides a mock function with given fields: _a0, _a1
 func (_m *OrderRepositoryInterface) UpdateOrderView(_a0 context.Context, _a1 []string) error {
 	ret := _m.Called(_a0, _a1)
@@ -302,6 +302,29 @@ func (_m *OrderViewRepositoryInterface) GetPublicOrderBy(ctx context.Context, id
 	return r0, r1
 }
 
+// GetRoyaltyCurrencies provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4
+func (_m *OrderViewRepositoryInterface) GetRoyaltyCurrencies(_a0 context.Context, _a1 string, _a2 []string, _a3 time.Time, _a4 time.Time) ([]string, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string, time.Time, time.Time) []string); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, []string, time.Time, time.Time) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRoyaltyForMerchants provides a mock function with given fields: _a0, _a1, _a2, _a3
 func (_m *OrderViewRepositoryInterface) GetRoyaltyForMerchants(_a0 context.Context, _a1 []string, _a2 time.Time, _a3 time.Time) ([]*pkg.RoyaltyReportMerchant, error) {
 	ret := _m.Called(_a0, _a1, _a2, _a3)
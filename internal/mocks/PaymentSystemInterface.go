@@ -7,6 +7,7 @@ import mock "github.com/stretchr/testify/mock"
 
 import protoiface "google.golang.org/protobuf/runtime/protoiface"
 import recurringpb "github.com/paysuper/paysuper-proto/go/recurringpb"
+import time "time"
 
 // PaymentSystemInterface is an autogenerated mock type for the PaymentSystemInterface type
 type PaymentSystemInterface struct {
@@ -27,6 +28,41 @@ func (_m *PaymentSystemInterface) CanSaveCard(request protoiface.MessageV1) bool
 	return r0
 }
 
+// Capture provides a mock function with given fields: order, amount
+func (_m *PaymentSystemInterface) Capture(order *billingpb.Order, amount float64) error {
+	ret := _m.Called(order, amount)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*billingpb.Order, float64) error); ok {
+		r0 = rf(order, amount)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateAuthorization provides a mock function with given fields: order, successUrl, failUrl, requisites
+func (_m *PaymentSystemInterface) CreateAuthorization(order *billingpb.Order, successUrl string, failUrl string, requisites map[string]string) (string, error) {
+	ret := _m.Called(order, successUrl, failUrl, requisites)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*billingpb.Order, string, string, map[string]string) string); ok {
+		r0 = rf(order, successUrl, failUrl, requisites)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*billingpb.Order, string, string, map[string]string) error); ok {
+		r1 = rf(order, successUrl, failUrl, requisites)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreatePayment provides a mock function with given fields: order, successUrl, failUrl, requisites
 func (_m *PaymentSystemInterface) CreatePayment(order *billingpb.Order, successUrl string, failUrl string, requisites map[string]string) (string, error) {
 	ret := _m.Called(order, successUrl, failUrl, requisites)
@@ -139,6 +175,20 @@ func (_m *PaymentSystemInterface) IsSubscriptionCallback(request protoiface.Mess
 	return r0
 }
 
+// PauseRecurringSubscription provides a mock function with given fields: order, subscription
+func (_m *PaymentSystemInterface) PauseRecurringSubscription(order *billingpb.Order, subscription *recurringpb.Subscription) error {
+	ret := _m.Called(order, subscription)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*billingpb.Order, *recurringpb.Subscription) error); ok {
+		r0 = rf(order, subscription)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ProcessPayment provides a mock function with given fields: order, message, raw, signature
 func (_m *PaymentSystemInterface) ProcessPayment(order *billingpb.Order, message protoiface.MessageV1, raw string, signature string) error {
 	ret := _m.Called(order, message, raw, signature)
@@ -166,3 +216,59 @@ func (_m *PaymentSystemInterface) ProcessRefund(order *billingpb.Order, refund *
 
 	return r0
 }
+
+// ResumeRecurringSubscription provides a mock function with given fields: order, subscription, nextBillingAt
+func (_m *PaymentSystemInterface) ResumeRecurringSubscription(order *billingpb.Order, subscription *recurringpb.Subscription, nextBillingAt time.Time) error {
+	ret := _m.Called(order, subscription, nextBillingAt)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*billingpb.Order, *recurringpb.Subscription, time.Time) error); ok {
+		r0 = rf(order, subscription, nextBillingAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateRecurringSubscriptionCard provides a mock function with given fields: order, subscription, recurringId
+func (_m *PaymentSystemInterface) UpdateRecurringSubscriptionCard(order *billingpb.Order, subscription *recurringpb.Subscription, recurringId string) error {
+	ret := _m.Called(order, subscription, recurringId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*billingpb.Order, *recurringpb.Subscription, string) error); ok {
+		r0 = rf(order, subscription, recurringId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateRecurringSubscriptionPlan provides a mock function with given fields: order, subscription
+func (_m *PaymentSystemInterface) UpdateRecurringSubscriptionPlan(order *billingpb.Order, subscription *recurringpb.Subscription) error {
+	ret := _m.Called(order, subscription)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*billingpb.Order, *recurringpb.Subscription) error); ok {
+		r0 = rf(order, subscription)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Void provides a mock function with given fields: order
+func (_m *PaymentSystemInterface) Void(order *billingpb.Order) error {
+	ret := _m.Called(order)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*billingpb.Order) error); ok {
+		r0 = rf(order)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
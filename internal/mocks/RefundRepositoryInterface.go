@@ -76,6 +76,29 @@ func (_m *RefundRepositoryInterface) GetAmountByOrderId(_a0 context.Context, _a1
 	return r0, r1
 }
 
+// GetByExternalId provides a mock function with given fields: _a0, _a1
+func (_m *RefundRepositoryInterface) GetByExternalId(_a0 context.Context, _a1 string) (*billingpb.Refund, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *billingpb.Refund
+	if rf, ok := ret.Get(0).(func(context.Context, string) *billingpb.Refund); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*billingpb.Refund)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetById provides a mock function with given fields: _a0, _a1
 func (_m *RefundRepositoryInterface) GetById(_a0 context.Context, _a1 string) (*billingpb.Refund, error) {
 	ret := _m.Called(_a0, _a1)
@@ -99,6 +122,90 @@ func (_m *RefundRepositoryInterface) GetById(_a0 context.Context, _a1 string) (*
 	return r0, r1
 }
 
+// GetCompletedAmountByOrderId provides a mock function with given fields: _a0, _a1
+func (_m *RefundRepositoryInterface) GetCompletedAmountByOrderId(_a0 context.Context, _a1 string) (float64, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 float64
+	if rf, ok := ret.Get(0).(func(context.Context, string) float64); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(float64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderSequence provides a mock function with given fields: _a0, _a1
+func (_m *RefundRepositoryInterface) GetOrderSequence(_a0 context.Context, _a1 string) (int64, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, string) int64); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetReservedAmountByOrderId provides a mock function with given fields: _a0, _a1
+func (_m *RefundRepositoryInterface) GetReservedAmountByOrderId(_a0 context.Context, _a1 string) (float64, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 float64
+	if rf, ok := ret.Get(0).(func(context.Context, string) float64); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(float64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IncrementOrderSequence provides a mock function with given fields: _a0, _a1, _a2
+func (_m *RefundRepositoryInterface) IncrementOrderSequence(_a0 context.Context, _a1 string, _a2 int64) (bool, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) bool); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Insert provides a mock function with given fields: _a0, _a1
 func (_m *RefundRepositoryInterface) Insert(_a0 context.Context, _a1 *billingpb.Refund) error {
 	ret := _m.Called(_a0, _a1)
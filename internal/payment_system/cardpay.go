@@ -35,8 +35,9 @@ const (
 	cardPayGrantTypePassword     = "password"
 	cardPayGrantTypeRefreshToken = "refresh_token"
 
-	CardPayDateFormat          = "2006-01-02T15:04:05Z"
-	cardPayInitiatorCardholder = "cit"
+	CardPayDateFormat              = "2006-01-02T15:04:05Z"
+	cardPayInitiatorCardholder     = "cit"
+	cardPaySubscriptionStartFormat = "2006-01-02"
 
 	cardPayMaxItemNameLength        = 50
 	cardPayMaxItemDescriptionLength = 200
@@ -44,6 +45,13 @@ const (
 	cardPayStatusActive    = "ACTIVE"
 	cardPayStatusInactive  = "INACTIVE"
 	cardPayStatusCancelled = "CANCELLED"
+
+	cardPayOperationChangeStatus = "CHANGE_STATUS"
+	cardPayOperationChangePlan   = "CHANGE_PLAN"
+	cardPayOperationChangeFiling = "CHANGE_FILING"
+	cardPayStatusToComplete      = "COMPLETE"
+	cardPayStatusToReverse       = "REVERSE"
+	cardPayPaymentStatusVoided   = "VOIDED"
 )
 
 var (
@@ -100,16 +108,18 @@ type CardPayPaymentData struct {
 	Amount     float64 `json:"amount"`
 	Descriptor string  `json:"dynamic_descriptor"`
 	Note       string  `json:"note"`
+	Preauth    bool    `json:"preauth,omitempty"`
 }
 
 type CardPayRecurringData struct {
-	Currency   string                      `json:"currency"`
-	Amount     float64                     `json:"amount"`
-	Filing     *CardPayRecurringDataFiling `json:"filing,omitempty"`
-	Descriptor string                      `json:"dynamic_descriptor"`
-	Note       string                      `json:"note"`
-	Initiator  string                      `json:"initiator"`
-	Plan       *CardPayRecurringPlan       `json:"plan"`
+	Currency          string                      `json:"currency"`
+	Amount            float64                     `json:"amount"`
+	Filing            *CardPayRecurringDataFiling `json:"filing,omitempty"`
+	Descriptor        string                      `json:"dynamic_descriptor"`
+	Note              string                      `json:"note"`
+	Initiator         string                      `json:"initiator"`
+	Plan              *CardPayRecurringPlan       `json:"plan"`
+	SubscriptionStart string                      `json:"subscription_start,omitempty"`
 }
 
 type CardPayRecurringPlan struct {
@@ -293,7 +303,32 @@ type CardPayRecurringSubscriptionUpdateRequest struct {
 }
 
 type CardPaySubscriptionDataRequest struct {
-	StatusTo string `json:"status_to"`
+	StatusTo        string                      `json:"status_to,omitempty"`
+	Plan            *CardPayRecurringPlan       `json:"plan,omitempty"`
+	Filing          *CardPayRecurringDataFiling `json:"filing,omitempty"`
+	NextPaymentDate string                      `json:"next_payment_date,omitempty"`
+	ExpireDate      string                      `json:"expire_date,omitempty"`
+}
+
+type CardPayPaymentUpdateRequest struct {
+	Request     *CardPayRequest                  `json:"request"`
+	Operation   string                           `json:"operation"`
+	PaymentData *CardPayPaymentUpdateDataRequest `json:"payment_data"`
+}
+
+type CardPayPaymentUpdateDataRequest struct {
+	StatusTo string  `json:"status_to"`
+	Amount   float64 `json:"amount,omitempty"`
+}
+
+type CardPayPaymentUpdateResponse struct {
+	PaymentData *CardPayPaymentUpdateResponsePaymentData `json:"payment_data"`
+}
+
+type CardPayPaymentUpdateResponsePaymentData struct {
+	Id     string  `json:"id"`
+	Status string  `json:"status"`
+	Amount float64 `json:"amount"`
 }
 
 func (m *CardPayRefundResponse) IsSuccessStatus() bool {
@@ -322,6 +357,32 @@ func (h *cardPay) CreatePayment(
 		return "", nil
 	}
 
+	return h.createPayment(order, data)
+}
+
+// CreateAuthorization creates payment with preauth flag, so funds are only held on the customer account until
+// the payment will be captured or voided. Preauth isn't supported for recurring payments.
+func (h *cardPay) CreateAuthorization(
+	order *billingpb.Order,
+	successUrl, failUrl string,
+	requisites map[string]string,
+) (string, error) {
+	data, err := h.getCardPayOrder(order, successUrl, failUrl, requisites)
+
+	if err != nil {
+		return "", err
+	}
+
+	if data.PaymentData == nil {
+		return "", PaymentSystemErrorOperationNotSupported
+	}
+
+	data.PaymentData.Preauth = true
+
+	return h.createPayment(order, data)
+}
+
+func (h *cardPay) createPayment(order *billingpb.Order, data *CardPayOrder) (string, error) {
 	action := pkg.PaymentSystemActionCreatePayment
 
 	if data.RecurringData != nil {
@@ -499,6 +560,13 @@ func (h *cardPay) ProcessPayment(order *billingpb.Order, message proto.Message,
 		order.PrivateStatus = recurringpb.OrderStatusPaymentSystemComplete
 		order.IsRefundAllowed = order.PaymentMethod.RefundAllowed
 
+		break
+	case billingpb.CardPayPaymentResponseStatusAuthorized:
+		if !IsManualCaptureOrder(order) {
+			return errors2.NewBillingServerResponseError(pkg.StatusTemporary, PaymentSystemErrorRequestTemporarySkipped)
+		}
+
+		order.PrivateStatus = pkg.OrderStatusPaymentSystemAuthorized
 		break
 	default:
 		return errors2.NewBillingServerResponseError(pkg.StatusTemporary, PaymentSystemErrorRequestTemporarySkipped)
@@ -1124,7 +1192,15 @@ func (h *cardPay) CreateRecurringSubscription(
 		redirectUrl string
 	)
 
-	subscription.CardpayPlanId, err = h.createRecurringPlan(order)
+	planData := &CardPayRecurringPlanData{
+		Amount:   GetRecurringPlanAmount(order),
+		Currency: order.ChargeCurrency,
+		Interval: order.RecurringSettings.Interval,
+		Name:     order.Id,
+		Period:   order.RecurringSettings.Period,
+		Retries:  1,
+	}
+	subscription.CardpayPlanId, err = h.createRecurringPlan(order, planData)
 
 	if err != nil {
 		return "", err
@@ -1139,20 +1215,13 @@ func (h *cardPay) CreateRecurringSubscription(
 	return redirectUrl, nil
 }
 
-func (h *cardPay) createRecurringPlan(order *billingpb.Order) (string, error) {
+func (h *cardPay) createRecurringPlan(order *billingpb.Order, planData *CardPayRecurringPlanData) (string, error) {
 	data := &CardPayRecurringPlanRequest{
 		Request: &CardPayRequest{
-			Id:   order.Id,
+			Id:   planData.Name,
 			Time: time.Now().UTC().Format(CardPayDateFormat),
 		},
-		PlanData: &CardPayRecurringPlanData{
-			Amount:   order.ChargeAmount,
-			Currency: order.ChargeCurrency,
-			Interval: order.RecurringSettings.Interval,
-			Name:     order.Id,
-			Period:   order.RecurringSettings.Period,
-			Retries:  1,
-		},
+		PlanData: planData,
 	}
 
 	req, err := h.getRequestWithAuth(order, data, pkg.PaymentSystemActionRecurringPlan)
@@ -1269,6 +1338,14 @@ func (h *cardPay) createRecurringSubscription(
 		},
 	}
 
+	// regular payments of the subscription with trial start at the trial end, trial price is charged by
+	// the initial payment
+	if days := GetRecurringTrialDays(order); days > 0 {
+		data.RecurringData.Amount = order.ChargeAmount
+		data.RecurringData.Currency = order.ChargeCurrency
+		data.RecurringData.SubscriptionStart = time.Now().UTC().AddDate(0, 0, days).Format(cardPaySubscriptionStartFormat)
+	}
+
 	if order.PaymentMethod.ExternalId == recurringpb.PaymentSystemGroupAliasBankCard {
 		expire := requisites[billingpb.PaymentCreateFieldMonth] + "/" + requisites[billingpb.PaymentCreateFieldYear]
 
@@ -1361,7 +1438,8 @@ func (h *cardPay) IsSubscriptionCallback(request proto.Message) bool {
 }
 
 func (h *cardPay) DeleteRecurringSubscription(order *billingpb.Order, subscription *recurringpb.Subscription) error {
-	err := h.updateRecurringSubscription(order, subscription, cardPayStatusCancelled)
+	data := &CardPaySubscriptionDataRequest{StatusTo: cardPayStatusCancelled}
+	err := h.updateRecurringSubscription(order, subscription, cardPayOperationChangeStatus, data)
 
 	if err != nil {
 		zap.L().Error(
@@ -1377,16 +1455,145 @@ func (h *cardPay) DeleteRecurringSubscription(order *billingpb.Order, subscripti
 	return nil
 }
 
-func (h *cardPay) updateRecurringSubscription(order *billingpb.Order, subscription *recurringpb.Subscription, status string) error {
+// UpdateRecurringSubscriptionPlan moves the subscription to the new plan created with the amount, currency and period
+// of the subscription. The new plan is applied by CardPay from the next regular payment, the previous plan is deleted.
+func (h *cardPay) UpdateRecurringSubscriptionPlan(order *billingpb.Order, subscription *recurringpb.Subscription) error {
+	interval := int32(1)
+
+	if order.RecurringSettings != nil && order.RecurringSettings.Interval > 0 {
+		interval = order.RecurringSettings.Interval
+	}
+
+	planData := &CardPayRecurringPlanData{
+		Amount:   subscription.Amount,
+		Currency: subscription.Currency,
+		Interval: interval,
+		Name:     subscription.Id + time.Now().UTC().Format(CardPayDateFormat),
+		Period:   subscription.Period,
+		Retries:  1,
+	}
+	planId, err := h.createRecurringPlan(order, planData)
+
+	if err != nil {
+		return err
+	}
+
+	data := &CardPaySubscriptionDataRequest{Plan: &CardPayRecurringPlan{Id: planId}}
+	err = h.updateRecurringSubscription(order, subscription, cardPayOperationChangePlan, data)
+
+	if err != nil {
+		zap.L().Error(
+			"cardpay API: change plan of recurring subscription request failed",
+			zap.Error(err),
+			zap.String("method", pkg.CardPayPaths[pkg.PaymentSystemActionUpdateRecurringSubscription].Method),
+			zap.Any(pkg.LogFieldRequest, subscription),
+			zap.Any(pkg.LogFieldOrder, order),
+		)
+		return err
+	}
+
+	if err = h.deleteRecurringPlan(order, subscription); err != nil {
+		zap.L().Error(
+			"cardpay API: delete previous plan of recurring subscription failed",
+			zap.Error(err),
+			zap.String("plan_id", subscription.CardpayPlanId),
+			zap.Any(pkg.LogFieldOrder, order),
+		)
+	}
+
+	subscription.CardpayPlanId = planId
+
+	return nil
+}
+
+// PauseRecurringSubscription deactivates the subscription, so regular payments aren't charged until the resume.
+func (h *cardPay) PauseRecurringSubscription(order *billingpb.Order, subscription *recurringpb.Subscription) error {
+	data := &CardPaySubscriptionDataRequest{StatusTo: cardPayStatusInactive}
+	err := h.updateRecurringSubscription(order, subscription, cardPayOperationChangeStatus, data)
+
+	if err != nil {
+		zap.L().Error(
+			"cardpay API: pause recurring subscription request failed",
+			zap.Error(err),
+			zap.String("method", pkg.CardPayPaths[pkg.PaymentSystemActionUpdateRecurringSubscription].Method),
+			zap.Any(pkg.LogFieldRequest, subscription),
+			zap.Any(pkg.LogFieldOrder, order),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// ResumeRecurringSubscription activates the paused subscription. Paused time isn't counted in the subscription term,
+// so the next payment date and the expiration date moved by the billing server are sent with the activation.
+func (h *cardPay) ResumeRecurringSubscription(
+	order *billingpb.Order,
+	subscription *recurringpb.Subscription,
+	nextBillingAt time.Time,
+) error {
+	data := &CardPaySubscriptionDataRequest{
+		StatusTo:        cardPayStatusActive,
+		NextPaymentDate: nextBillingAt.UTC().Format(cardPaySubscriptionStartFormat),
+	}
+
+	if subscription.ExpireAt != nil {
+		expireAt, _ := ptypes.Timestamp(subscription.ExpireAt)
+		data.ExpireDate = expireAt.UTC().Format(cardPaySubscriptionStartFormat)
+	}
+	err := h.updateRecurringSubscription(order, subscription, cardPayOperationChangeStatus, data)
+
+	if err != nil {
+		zap.L().Error(
+			"cardpay API: resume recurring subscription request failed",
+			zap.Error(err),
+			zap.String("method", pkg.CardPayPaths[pkg.PaymentSystemActionUpdateRecurringSubscription].Method),
+			zap.Any(pkg.LogFieldRequest, subscription),
+			zap.Any(pkg.LogFieldOrder, order),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateRecurringSubscriptionCard replaces the card of the subscription with the card filed by the recurring identifier,
+// regular payments of the subscription are charged from the new card.
+func (h *cardPay) UpdateRecurringSubscriptionCard(
+	order *billingpb.Order,
+	subscription *recurringpb.Subscription,
+	recurringId string,
+) error {
+	data := &CardPaySubscriptionDataRequest{Filing: &CardPayRecurringDataFiling{Id: recurringId}}
+	err := h.updateRecurringSubscription(order, subscription, cardPayOperationChangeFiling, data)
+
+	if err != nil {
+		zap.L().Error(
+			"cardpay API: change card of recurring subscription request failed",
+			zap.Error(err),
+			zap.String("method", pkg.CardPayPaths[pkg.PaymentSystemActionUpdateRecurringSubscription].Method),
+			zap.Any(pkg.LogFieldRequest, subscription),
+			zap.Any(pkg.LogFieldOrder, order),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (h *cardPay) updateRecurringSubscription(
+	order *billingpb.Order,
+	subscription *recurringpb.Subscription,
+	operation string,
+	subscriptionData *CardPaySubscriptionDataRequest,
+) error {
 	data := &CardPayRecurringSubscriptionUpdateRequest{
 		Request: &CardPayRequest{
 			Id:   subscription.CardpaySubscriptionId + time.Now().UTC().Format(CardPayDateFormat),
 			Time: time.Now().UTC().Format(CardPayDateFormat),
 		},
-		Operation: "CHANGE_STATUS",
-		SubscriptionData: &CardPaySubscriptionDataRequest{
-			StatusTo: status,
-		},
+		Operation:        operation,
+		SubscriptionData: subscriptionData,
 	}
 
 	req, err := h.getRequestWithAuth(order, data, pkg.PaymentSystemActionUpdateRecurringSubscription, subscription.CardpaySubscriptionId)
@@ -1423,6 +1630,132 @@ func (h *cardPay) updateRecurringSubscription(order *billingpb.Order, subscripti
 	return nil
 }
 
+// Capture completes the authorized payment. If amount is less than the authorized amount then only this amount will
+// be charged from customer and the rest of the funds will be released.
+func (h *cardPay) Capture(order *billingpb.Order, amount float64) error {
+	data := &CardPayPaymentUpdateDataRequest{StatusTo: cardPayStatusToComplete}
+
+	if amount < order.ChargeAmount {
+		data.Amount = amount
+	}
+
+	status, err := h.updatePayment(order, data)
+
+	if err != nil {
+		return paymentSystemErrorCaptureFailed
+	}
+
+	if status != billingpb.CardPayPaymentResponseStatusCompleted {
+		zap.L().Error(
+			"cardpay API: payment wasn't completed by capture request",
+			zap.String("status", status),
+			zap.Any(pkg.LogFieldOrder, order),
+		)
+		return paymentSystemErrorCaptureFailed
+	}
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemComplete
+	order.IsRefundAllowed = order.PaymentMethod.RefundAllowed
+	order.PaymentMethodOrderClosedAt = ptypes.TimestampNow()
+
+	return nil
+}
+
+// Void cancels the authorized payment and releases held funds on the customer account.
+func (h *cardPay) Void(order *billingpb.Order) error {
+	status, err := h.updatePayment(order, &CardPayPaymentUpdateDataRequest{StatusTo: cardPayStatusToReverse})
+
+	if err != nil {
+		return paymentSystemErrorVoidFailed
+	}
+
+	if status != cardPayPaymentStatusVoided {
+		zap.L().Error(
+			"cardpay API: payment wasn't voided by void request",
+			zap.String("status", status),
+			zap.Any(pkg.LogFieldOrder, order),
+		)
+		return paymentSystemErrorVoidFailed
+	}
+
+	order.PrivateStatus = pkg.OrderStatusPaymentSystemVoided
+	order.CanceledAt = ptypes.TimestampNow()
+
+	return nil
+}
+
+func (h *cardPay) updatePayment(order *billingpb.Order, paymentData *CardPayPaymentUpdateDataRequest) (string, error) {
+	data := &CardPayPaymentUpdateRequest{
+		Request: &CardPayRequest{
+			Id:   order.Transaction + time.Now().UTC().Format(CardPayDateFormat),
+			Time: time.Now().UTC().Format(CardPayDateFormat),
+		},
+		Operation:   cardPayOperationChangeStatus,
+		PaymentData: paymentData,
+	}
+
+	req, err := h.getRequestWithAuth(order, data, pkg.PaymentSystemActionUpdatePayment, order.Transaction)
+
+	if err != nil {
+		zap.L().Error(
+			"cardpay API: update payment request failed",
+			zap.Error(err),
+			zap.String("method", pkg.CardPayPaths[pkg.PaymentSystemActionUpdatePayment].Method),
+			zap.Any(pkg.LogFieldOrder, order),
+			zap.Any(pkg.LogFieldBody, data),
+		)
+		return "", err
+	}
+
+	resp, err := h.httpClient.Do(req)
+
+	if err != nil {
+		zap.L().Error(
+			"cardpay API: send update payment request failed",
+			zap.Error(err),
+			zap.String("method", pkg.CardPayPaths[pkg.PaymentSystemActionUpdatePayment].Method),
+			zap.Any(pkg.LogFieldRequest, req),
+			zap.Any(pkg.LogFieldOrder, order),
+			zap.Any(pkg.LogFieldBody, data),
+		)
+		return "", err
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil || resp.StatusCode != http.StatusOK {
+		zap.L().Error(
+			"update payment response returned with bad http status",
+			zap.Error(err),
+			zap.String("method", pkg.CardPayPaths[pkg.PaymentSystemActionUpdatePayment].Method),
+			zap.Any("status", resp.StatusCode),
+			zap.Any(pkg.LogFieldRequest, req),
+			zap.Any(pkg.LogFieldOrder, order),
+			zap.Any(pkg.LogFieldBody, data),
+			zap.ByteString(pkg.LogFieldResponse, b),
+		)
+		return "", paymentSystemErrorCreateRequestFailed
+	}
+
+	cpRsp := &CardPayPaymentUpdateResponse{}
+	err = json.Unmarshal(b, &cpRsp)
+
+	if err != nil || cpRsp.PaymentData == nil {
+		zap.L().Error(
+			"update payment response contain invalid json",
+			zap.Error(err),
+			zap.String("method", pkg.CardPayPaths[pkg.PaymentSystemActionUpdatePayment].Method),
+			zap.Any(pkg.LogFieldRequest, req),
+			zap.Any(pkg.LogFieldOrder, order),
+			zap.ByteString(pkg.LogFieldResponse, b),
+		)
+		return "", paymentSystemErrorCreateRequestFailed
+	}
+
+	return cpRsp.PaymentData.Status, nil
+}
+
 func (h *cardPay) deleteRecurringPlan(order *billingpb.Order, subscription *recurringpb.Subscription) error {
 	req, err := h.getRequestWithAuth(order, nil, pkg.PaymentSystemActionDeleteRecurringPlan, subscription.CardpayPlanId)
 
@@ -3,6 +3,7 @@ package payment_system
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/paysuper/paysuper-billing-server/internal/config"
 	"github.com/paysuper/paysuper-billing-server/pkg"
@@ -15,7 +16,9 @@ import (
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
 	"io/ioutil"
+	"net/http"
 	"testing"
+	"time"
 )
 
 var (
@@ -272,3 +275,156 @@ func (suite *CardPayTestSuite) TestCardPay_DeleteRecurringSubscription_Ok() {
 	)
 	assert.NoError(suite.T(), err)
 }
+
+func (suite *CardPayTestSuite) TestCardPay_PauseRecurringSubscription_Ok() {
+	suite.typedHandler.httpClient = NewCardPayHttpClientStatusOk()
+
+	subscription := &recurringpb.Subscription{
+		CardpayPlanId:         "planId",
+		CardpaySubscriptionId: "subscriptionId",
+	}
+
+	err := suite.handler.PauseRecurringSubscription(orderSimpleBankCard, subscription)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *CardPayTestSuite) TestCardPay_ResumeRecurringSubscription_Ok() {
+	suite.typedHandler.httpClient = NewCardPayHttpClientStatusOk()
+
+	subscription := &recurringpb.Subscription{
+		CardpayPlanId:         "planId",
+		CardpaySubscriptionId: "subscriptionId",
+	}
+
+	err := suite.handler.ResumeRecurringSubscription(orderSimpleBankCard, subscription, time.Now().AddDate(0, 0, 10))
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *CardPayTestSuite) TestCardPay_UpdateRecurringSubscriptionPlan_Ok() {
+	suite.typedHandler.httpClient = NewCardPayHttpClientStatusOk()
+	suite.typedHandler.httpClient.Transport = &TransportCardPayRecurringPlanOk{}
+
+	subscription := &recurringpb.Subscription{
+		Id:                    primitive.NewObjectID().Hex(),
+		CardpayPlanId:         "previousPlanId",
+		CardpaySubscriptionId: "subscriptionId",
+		Amount:                20,
+		Currency:              "USD",
+		Period:                recurringpb.RecurringPeriodYear,
+	}
+
+	err := suite.handler.UpdateRecurringSubscriptionPlan(orderSimpleBankCard, subscription)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "planId", subscription.CardpayPlanId)
+}
+
+func (suite *CardPayTestSuite) TestCardPay_UpdateRecurringSubscriptionPlan_InactivePlan() {
+	suite.typedHandler.httpClient = NewCardPayHttpClientStatusOk()
+	suite.typedHandler.httpClient.Transport = &TransportCardPayRecurringPlanInactive{}
+
+	subscription := &recurringpb.Subscription{
+		Id:                    primitive.NewObjectID().Hex(),
+		CardpayPlanId:         "previousPlanId",
+		CardpaySubscriptionId: "subscriptionId",
+		Amount:                20,
+		Currency:              "USD",
+		Period:                recurringpb.RecurringPeriodYear,
+	}
+
+	err := suite.handler.UpdateRecurringSubscriptionPlan(orderSimpleBankCard, subscription)
+	assert.Equal(suite.T(), paymentSystemErrorCreateRecurringPlanFailed, err)
+	assert.Equal(suite.T(), "previousPlanId", subscription.CardpayPlanId)
+}
+
+func (suite *CardPayTestSuite) getAuthorizedOrder() *billingpb.Order {
+	order := proto.Clone(orderSimpleBankCard).(*billingpb.Order)
+	order.ChargeAmount = 10.2
+	order.ChargeCurrency = "USD"
+	order.Transaction = "paymentId"
+	order.PrivateStatus = pkg.OrderStatusPaymentSystemAuthorized
+	order.PrivateMetadata = map[string]string{pkg.OrderPrivateMetadataCaptureMode: pkg.OrderCaptureModeManual}
+
+	return order
+}
+
+func (suite *CardPayTestSuite) TestCardPay_CreateAuthorization_Ok() {
+	suite.typedHandler.httpClient = NewCardPayHttpClientStatusOk()
+	order := suite.getAuthorizedOrder()
+	order.PrivateStatus = recurringpb.OrderStatusNew
+
+	url, err := suite.handler.CreateAuthorization(
+		order,
+		suite.cfg.GetRedirectUrlSuccess(nil),
+		suite.cfg.GetRedirectUrlFail(nil),
+		bankCardRequisites,
+	)
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), url)
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemCreate), order.PrivateStatus)
+}
+
+func (suite *CardPayTestSuite) TestCardPay_CreateAuthorization_StoreCard_NotSupported() {
+	suite.typedHandler.httpClient = NewCardPayHttpClientStatusOk()
+	requisites := map[string]string{billingpb.PaymentCreateFieldStoreData: "1"}
+
+	for k, v := range bankCardRequisites {
+		requisites[k] = v
+	}
+
+	_, err := suite.handler.CreateAuthorization(
+		suite.getAuthorizedOrder(),
+		suite.cfg.GetRedirectUrlSuccess(nil),
+		suite.cfg.GetRedirectUrlFail(nil),
+		requisites,
+	)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), PaymentSystemErrorOperationNotSupported, err)
+}
+
+func (suite *CardPayTestSuite) TestCardPay_Capture_Ok() {
+	transport := &TransportCardPayPaymentUpdate{}
+	suite.typedHandler.httpClient = &http.Client{Transport: transport}
+	order := suite.getAuthorizedOrder()
+
+	err := suite.handler.Capture(order, order.ChargeAmount)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemComplete), order.PrivateStatus)
+	assert.NotNil(suite.T(), order.PaymentMethodOrderClosedAt)
+	assert.NotNil(suite.T(), transport.Request)
+	assert.Equal(suite.T(), cardPayOperationChangeStatus, transport.Request.Operation)
+	assert.Equal(suite.T(), cardPayStatusToComplete, transport.Request.PaymentData.StatusTo)
+	assert.Zero(suite.T(), transport.Request.PaymentData.Amount)
+}
+
+func (suite *CardPayTestSuite) TestCardPay_Capture_Partial_Ok() {
+	transport := &TransportCardPayPaymentUpdate{}
+	suite.typedHandler.httpClient = &http.Client{Transport: transport}
+	order := suite.getAuthorizedOrder()
+
+	err := suite.handler.Capture(order, 5)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemComplete), order.PrivateStatus)
+	assert.Equal(suite.T(), float64(5), transport.Request.PaymentData.Amount)
+}
+
+func (suite *CardPayTestSuite) TestCardPay_Capture_BadHttpStatus_Error() {
+	suite.typedHandler.httpClient = &http.Client{Transport: &TransportStatusError{}}
+	order := suite.getAuthorizedOrder()
+
+	err := suite.handler.Capture(order, order.ChargeAmount)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), paymentSystemErrorCaptureFailed, err)
+	assert.Equal(suite.T(), pkg.OrderStatusPaymentSystemAuthorized, order.PrivateStatus)
+}
+
+func (suite *CardPayTestSuite) TestCardPay_Void_Ok() {
+	transport := &TransportCardPayPaymentUpdate{}
+	suite.typedHandler.httpClient = &http.Client{Transport: transport}
+	order := suite.getAuthorizedOrder()
+
+	err := suite.handler.Void(order)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.OrderStatusPaymentSystemVoided, order.PrivateStatus)
+	assert.NotNil(suite.T(), order.CanceledAt)
+	assert.Equal(suite.T(), cardPayStatusToReverse, transport.Request.PaymentData.StatusTo)
+}
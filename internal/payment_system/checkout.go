@@ -0,0 +1,883 @@
+package payment_system
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	errors2 "github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	tools "github.com/paysuper/paysuper-tools/string"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	checkoutSourceTypeCard   = "card"
+	checkoutSourceTypeId     = "id"
+	checkoutSourceTypeAlipay = "alipay"
+
+	checkoutPaymentTypeRegular   = "Regular"
+	checkoutPaymentTypeRecurring = "Recurring"
+
+	checkoutPaymentStatusDeclined = "Declined"
+
+	checkoutEventPaymentApproved       = "payment_approved"
+	checkoutEventPaymentPending        = "payment_pending"
+	checkoutEventPaymentCaptured       = "payment_captured"
+	checkoutEventPaymentDeclined       = "payment_declined"
+	checkoutEventPaymentCaptureDecline = "payment_capture_declined"
+	checkoutEventPaymentCanceled       = "payment_canceled"
+	checkoutEventPaymentExpired        = "payment_expired"
+	checkoutEventPaymentRefunded       = "payment_refunded"
+	checkoutEventPaymentRefundDeclined = "payment_refund_declined"
+
+	checkoutMetadataFieldOrderId       = "order_id"
+	checkoutMetadataFieldPaymentMethod = "payment_method"
+	checkoutMetadataFieldStoreData     = "store_data"
+
+	checkoutMaskedPanPadding = "******"
+)
+
+var (
+	// Currencies which minor unit differs from the default two digits exponent
+	checkoutCurrencyExponents = map[string]int{
+		"BIF": 0, "CLF": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+		"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+		"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	}
+)
+
+type checkout struct {
+	httpClient *http.Client
+}
+
+type checkoutTransport struct {
+	Transport http.RoundTripper
+}
+
+type checkoutContextKey struct {
+	name string
+}
+
+type CheckoutSource struct {
+	Type        string `json:"type"`
+	Id          string `json:"id,omitempty"`
+	Number      string `json:"number,omitempty"`
+	ExpiryMonth int    `json:"expiry_month,omitempty"`
+	ExpiryYear  int    `json:"expiry_year,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Cvv         string `json:"cvv,omitempty"`
+}
+
+type CheckoutCustomer struct {
+	Email string `json:"email,omitempty"`
+	Name  string `json:"name,omitempty"`
+}
+
+type CheckoutThreeDs struct {
+	Enabled bool `json:"enabled"`
+}
+
+type CheckoutPaymentRequest struct {
+	Source              *CheckoutSource   `json:"source"`
+	Amount              int64             `json:"amount"`
+	Currency            string            `json:"currency"`
+	PaymentType         string            `json:"payment_type"`
+	MerchantInitiated   bool              `json:"merchant_initiated"`
+	Reference           string            `json:"reference"`
+	Description         string            `json:"description,omitempty"`
+	Capture             bool              `json:"capture"`
+	Customer            *CheckoutCustomer `json:"customer,omitempty"`
+	ThreeDs             *CheckoutThreeDs  `json:"3ds,omitempty"`
+	PaymentIp           string            `json:"payment_ip,omitempty"`
+	ProcessingChannelId string            `json:"processing_channel_id,omitempty"`
+	SuccessUrl          string            `json:"success_url,omitempty"`
+	FailureUrl          string            `json:"failure_url,omitempty"`
+	Metadata            map[string]string `json:"metadata,omitempty"`
+}
+
+type CheckoutLink struct {
+	Href string `json:"href"`
+}
+
+type CheckoutPaymentResponseSource struct {
+	Id     string `json:"id"`
+	Type   string `json:"type"`
+	Last4  string `json:"last4"`
+	Bin    string `json:"bin"`
+	Scheme string `json:"scheme"`
+}
+
+type CheckoutPaymentResponse struct {
+	Id              string                         `json:"id"`
+	ActionId        string                         `json:"action_id"`
+	Amount          int64                          `json:"amount"`
+	Currency        string                         `json:"currency"`
+	Approved        bool                           `json:"approved"`
+	Status          string                         `json:"status"`
+	ResponseCode    string                         `json:"response_code"`
+	ResponseSummary string                         `json:"response_summary"`
+	Source          *CheckoutPaymentResponseSource `json:"source"`
+	Links           map[string]*CheckoutLink       `json:"_links"`
+}
+
+type CheckoutRefundRequest struct {
+	Amount    int64             `json:"amount"`
+	Reference string            `json:"reference"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+type CheckoutRefundResponse struct {
+	ActionId  string `json:"action_id"`
+	Reference string `json:"reference"`
+}
+
+type CheckoutWebhookSource struct {
+	Id            string `json:"id"`
+	Type          string `json:"type"`
+	Last4         string `json:"last4"`
+	Bin           string `json:"bin"`
+	Scheme        string `json:"scheme"`
+	IssuerCountry string `json:"issuer_country"`
+	Name          string `json:"name"`
+	ExpiryMonth   int    `json:"expiry_month"`
+	ExpiryYear    int    `json:"expiry_year"`
+}
+
+type CheckoutWebhookData struct {
+	Id              string                 `json:"id"`
+	ActionId        string                 `json:"action_id"`
+	Reference       string                 `json:"reference"`
+	Amount          int64                  `json:"amount"`
+	Currency        string                 `json:"currency"`
+	ResponseCode    string                 `json:"response_code"`
+	ResponseSummary string                 `json:"response_summary"`
+	PaymentType     string                 `json:"payment_type"`
+	Source          *CheckoutWebhookSource `json:"source"`
+	Metadata        map[string]string      `json:"metadata"`
+}
+
+// CheckoutWebhook is a notification sent by Checkout.com about payment and refund state changes
+type CheckoutWebhook struct {
+	Id        string               `json:"id"`
+	Type      string               `json:"type"`
+	CreatedOn string               `json:"created_on"`
+	Data      *CheckoutWebhookData `json:"data"`
+}
+
+func (m *CheckoutWebhook) Reset()         { *m = CheckoutWebhook{} }
+func (m *CheckoutWebhook) String() string { b, _ := json.Marshal(m); return string(b) }
+func (*CheckoutWebhook) ProtoMessage()    {}
+
+// GetOrderId returns identifier of order to which notification belongs
+func (m *CheckoutWebhook) GetOrderId() string {
+	if m.Data == nil {
+		return ""
+	}
+
+	if id, ok := m.Data.Metadata[checkoutMetadataFieldOrderId]; ok && id != "" {
+		return id
+	}
+
+	return m.Data.Reference
+}
+
+func (m *CheckoutWebhook) isRefundEvent() bool {
+	return m.Type == checkoutEventPaymentRefunded || m.Type == checkoutEventPaymentRefundDeclined
+}
+
+func (m *CheckoutPaymentResponse) getRedirectUrl() string {
+	link, ok := m.Links["redirect"]
+
+	if !ok || link == nil {
+		return ""
+	}
+
+	return link.Href
+}
+
+func NewCheckoutHandler() PaymentSystemInterface {
+	return &checkout{
+		httpClient: &http.Client{
+			Transport: &checkoutTransport{},
+			Timeout:   defaultHttpClientTimeout * time.Second,
+		},
+	}
+}
+
+func (h *checkout) CreatePayment(
+	order *billingpb.Order,
+	successUrl, failUrl string,
+	requisites map[string]string,
+) (string, error) {
+	data, err := h.getCheckoutPayment(order, successUrl, failUrl, requisites)
+
+	if err != nil {
+		return "", err
+	}
+
+	action := pkg.PaymentSystemActionCreatePayment
+
+	if data.PaymentType == checkoutPaymentTypeRecurring {
+		action = pkg.PaymentSystemActionRecurringPayment
+	}
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemRejectOnCreate
+	rsp, err := h.sendPayment(order, data, action)
+
+	if err != nil {
+		return "", err
+	}
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemCreate
+
+	if redirectUrl := rsp.getRedirectUrl(); redirectUrl != "" {
+		return redirectUrl, nil
+	}
+
+	return successUrl, nil
+}
+
+func (h *checkout) ProcessPayment(order *billingpb.Order, message proto.Message, raw, signature string) error {
+	req := message.(*CheckoutWebhook)
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemReject
+	err := h.checkCallbackRequestSignature(order, raw, signature)
+
+	if err != nil {
+		return err
+	}
+
+	if req.Data == nil || req.isRefundEvent() {
+		return errors2.NewBillingServerResponseError(pkg.StatusErrorValidation, paymentSystemErrorRequestStatusIsInvalid)
+	}
+
+	if pm, ok := req.Data.Metadata[checkoutMetadataFieldPaymentMethod]; ok && pm != order.PaymentMethod.ExternalId {
+		return errors2.NewBillingServerResponseError(pkg.StatusErrorValidation, paymentSystemErrorRequestPaymentMethodIsInvalid)
+	}
+
+	if req.Data.Amount != checkoutAmountToMinor(order.ChargeAmount, order.ChargeCurrency) ||
+		req.Data.Currency != order.ChargeCurrency {
+		return errors2.NewBillingServerResponseError(pkg.StatusErrorValidation, PaymentSystemErrorRequestAmountOrCurrencyIsInvalid)
+	}
+
+	ts, err := h.parseTime(req.CreatedOn)
+
+	if err != nil {
+		return errors2.NewBillingServerResponseError(pkg.StatusErrorValidation, paymentSystemErrorRequestTimeFieldIsInvalid)
+	}
+
+	order.PaymentMethodTxnParams = h.getTxnParams(order, req)
+
+	switch req.Type {
+	case checkoutEventPaymentDeclined, checkoutEventPaymentCaptureDecline:
+		order.PrivateStatus = recurringpb.OrderStatusPaymentSystemDeclined
+		break
+	case checkoutEventPaymentCanceled, checkoutEventPaymentExpired:
+		order.PrivateStatus = recurringpb.OrderStatusPaymentSystemCanceled
+		order.CanceledAt = ptypes.TimestampNow()
+		break
+	case checkoutEventPaymentCaptured:
+		order.PrivateStatus = recurringpb.OrderStatusPaymentSystemComplete
+		order.IsRefundAllowed = order.PaymentMethod.RefundAllowed
+		break
+	default:
+		return errors2.NewBillingServerResponseError(pkg.StatusTemporary, PaymentSystemErrorRequestTemporarySkipped)
+	}
+
+	if order.PrivateStatus != recurringpb.OrderStatusPaymentSystemComplete &&
+		(req.Data.ResponseCode != "" || req.Data.ResponseSummary != "") {
+		order.Cancellation = &billingpb.OrderNotificationCancellation{
+			Code:   req.Data.ResponseCode,
+			Reason: req.Data.ResponseSummary,
+		}
+	}
+
+	order.Transaction = req.Data.Id
+	order.PaymentMethodOrderClosedAt = ts
+
+	return nil
+}
+
+func (h *checkout) IsRecurringCallback(request proto.Message) bool {
+	req := request.(*CheckoutWebhook)
+	return req.Data != nil && req.Data.PaymentType == checkoutPaymentTypeRecurring &&
+		req.Data.Metadata[checkoutMetadataFieldPaymentMethod] == recurringpb.PaymentSystemGroupAliasBankCard
+}
+
+func (h *checkout) CanSaveCard(request proto.Message) bool {
+	req := request.(*CheckoutWebhook)
+	return h.IsRecurringCallback(request) && req.Data.Metadata[checkoutMetadataFieldStoreData] == "1" &&
+		req.Data.Source != nil && req.Data.Source.Id != ""
+}
+
+func (h *checkout) GetRecurringId(request proto.Message) string {
+	return request.(*CheckoutWebhook).Data.Source.Id
+}
+
+func (h *checkout) CreateRefund(order *billingpb.Order, refund *billingpb.Refund) error {
+	data := &CheckoutRefundRequest{
+		Amount:    checkoutAmountToMinor(refund.Amount, refund.Currency),
+		Reference: refund.Id,
+		Metadata: map[string]string{
+			checkoutMetadataFieldOrderId: order.Id,
+		},
+	}
+
+	refund.Status = pkg.RefundStatusRejected
+
+	req, err := h.getRequest(order, data, pkg.PaymentSystemActionRefund, order.Transaction)
+
+	if err != nil {
+		zap.L().Error(
+			"checkout API: create refund request failed",
+			zap.Error(err),
+			zap.String("method", pkg.CheckoutPaths[pkg.PaymentSystemActionRefund].Method),
+			zap.Any(pkg.LogFieldOrder, order),
+			zap.Any(pkg.LogFieldBody, data),
+			zap.String(pkg.LogFieldHandler, pkg.PaymentSystemHandlerCheckout),
+			zap.Any("refund", refund),
+		)
+		return errors.New(pkg.PaymentSystemErrorCreateRefundFailed)
+	}
+
+	resp, err := h.httpClient.Do(req)
+
+	if err != nil {
+		zap.L().Error(
+			"checkout API: refund request failed",
+			zap.Error(err),
+			zap.String(pkg.LogFieldHandler, pkg.PaymentSystemHandlerCheckout),
+			zap.Any(pkg.LogFieldRequest, data),
+			zap.Any("refund", refund),
+		)
+		return errors.New(pkg.PaymentSystemErrorCreateRefundFailed)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		zap.L().Error(
+			"checkout API: refund response body can't be read",
+			zap.Error(err),
+			zap.String(pkg.LogFieldHandler, pkg.PaymentSystemHandlerCheckout),
+			zap.Any(pkg.LogFieldRequest, data),
+			zap.Any("refund", refund),
+		)
+		return errors.New(pkg.PaymentSystemErrorCreateRefundFailed)
+	}
+
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnprocessableEntity {
+		zap.L().Error(
+			"checkout API: refund rejected by payment system",
+			zap.Int("status", resp.StatusCode),
+			zap.String(pkg.LogFieldHandler, pkg.PaymentSystemHandlerCheckout),
+			zap.Any(pkg.LogFieldRequest, data),
+			zap.ByteString(pkg.LogFieldResponse, b),
+			zap.Any("refund", refund),
+		)
+		return errors.New(pkg.PaymentSystemErrorCreateRefundRejected)
+	}
+
+	if resp.StatusCode != http.StatusAccepted {
+		zap.L().Error(
+			"checkout API: refund response returned with bad http status",
+			zap.Int("status", resp.StatusCode),
+			zap.String(pkg.LogFieldHandler, pkg.PaymentSystemHandlerCheckout),
+			zap.Any(pkg.LogFieldRequest, data),
+			zap.ByteString(pkg.LogFieldResponse, b),
+			zap.Any("refund", refund),
+		)
+		return errors.New(pkg.PaymentSystemErrorCreateRefundFailed)
+	}
+
+	rsp := &CheckoutRefundResponse{}
+	err = json.Unmarshal(b, &rsp)
+
+	if err != nil {
+		zap.L().Error(
+			"checkout API: refund response contain invalid json",
+			zap.Error(err),
+			zap.String(pkg.LogFieldHandler, pkg.PaymentSystemHandlerCheckout),
+			zap.Any(pkg.LogFieldRequest, data),
+			zap.ByteString(pkg.LogFieldResponse, b),
+			zap.Any("refund", refund),
+		)
+		return errors.New(pkg.PaymentSystemErrorCreateRefundFailed)
+	}
+
+	refund.Status = pkg.RefundStatusInProgress
+	refund.ExternalId = rsp.ActionId
+
+	return nil
+}
+
+func (h *checkout) ProcessRefund(
+	order *billingpb.Order,
+	refund *billingpb.Refund,
+	message proto.Message,
+	raw, signature string,
+) error {
+	req := message.(*CheckoutWebhook)
+	refundInitialStatus := refund.Status
+	refund.Status = pkg.RefundStatusRejected
+
+	err := h.checkCallbackRequestSignature(order, raw, signature)
+
+	if err != nil {
+		err.(*billingpb.ResponseError).Status = billingpb.ResponseStatusBadData
+		return err
+	}
+
+	if req.Data == nil || !req.isRefundEvent() {
+		return errors2.NewBillingServerResponseError(billingpb.ResponseStatusBadData, paymentSystemErrorRequestStatusIsInvalid)
+	}
+
+	if req.Data.Amount != checkoutAmountToMinor(refund.Amount, refund.Currency) || req.Data.Currency != refund.Currency {
+		return errors2.NewBillingServerResponseError(billingpb.ResponseStatusBadData, PaymentSystemErrorRefundRequestAmountOrCurrencyIsInvalid)
+	}
+
+	ts, err := h.parseTime(req.CreatedOn)
+
+	if err != nil {
+		return errors2.NewBillingServerResponseError(pkg.StatusErrorValidation, paymentSystemErrorRequestTimeFieldIsInvalid)
+	}
+
+	switch req.Type {
+	case checkoutEventPaymentRefundDeclined:
+		refund.Status = pkg.RefundStatusPaymentSystemDeclined
+		break
+	case checkoutEventPaymentRefunded:
+		refund.Status = pkg.RefundStatusCompleted
+		break
+	default:
+		refund.Status = refundInitialStatus
+		return errors2.NewBillingServerResponseError(billingpb.ResponseStatusTemporary, PaymentSystemErrorRequestTemporarySkipped)
+	}
+
+	if req.Data.ActionId != "" {
+		refund.ExternalId = req.Data.ActionId
+	}
+
+	refund.UpdatedAt = ptypes.TimestampNow()
+	order.PaymentMethodOrderClosedAt = ts
+
+	return nil
+}
+
+// Checkout.com has no hosted plans and the billing doesn't charge regular payments of subscriptions itself, so
+// recurring orders aren't routed to Checkout.com and subscriptions can't be created by it.
+func (h *checkout) CreateRecurringSubscription(
+	order *billingpb.Order, subscription *recurringpb.Subscription, successUrl, failUrl string, requisites map[string]string,
+) (string, error) {
+	return "", PaymentSystemErrorOperationNotSupported
+}
+
+func (h *checkout) IsSubscriptionCallback(request proto.Message) bool {
+	return false
+}
+
+func (h *checkout) DeleteRecurringSubscription(order *billingpb.Order, subscription *recurringpb.Subscription) error {
+	return PaymentSystemErrorOperationNotSupported
+}
+
+func (h *checkout) UpdateRecurringSubscriptionPlan(order *billingpb.Order, subscription *recurringpb.Subscription) error {
+	return PaymentSystemErrorOperationNotSupported
+}
+
+func (h *checkout) PauseRecurringSubscription(order *billingpb.Order, subscription *recurringpb.Subscription) error {
+	return PaymentSystemErrorOperationNotSupported
+}
+
+func (h *checkout) ResumeRecurringSubscription(
+	order *billingpb.Order,
+	subscription *recurringpb.Subscription,
+	nextBillingAt time.Time,
+) error {
+	return PaymentSystemErrorOperationNotSupported
+}
+
+func (h *checkout) UpdateRecurringSubscriptionCard(
+	order *billingpb.Order,
+	subscription *recurringpb.Subscription,
+	recurringId string,
+) error {
+	return PaymentSystemErrorOperationNotSupported
+}
+
+func (h *checkout) CreateAuthorization(
+	order *billingpb.Order,
+	successUrl, failUrl string,
+	requisites map[string]string,
+) (string, error) {
+	return "", PaymentSystemErrorOperationNotSupported
+}
+
+func (h *checkout) Capture(order *billingpb.Order, amount float64) error {
+	return PaymentSystemErrorOperationNotSupported
+}
+
+func (h *checkout) Void(order *billingpb.Order) error {
+	return PaymentSystemErrorOperationNotSupported
+}
+
+func (h *checkout) sendPayment(order *billingpb.Order, data *CheckoutPaymentRequest, action string) (*CheckoutPaymentResponse, error) {
+	req, err := h.getRequest(order, data, action)
+
+	if err != nil {
+		zap.L().Error(
+			"checkout API: create payment request failed",
+			zap.Error(err),
+			zap.String("method", pkg.CheckoutPaths[action].Method),
+			zap.Any(pkg.LogFieldOrder, order),
+		)
+		return nil, err
+	}
+
+	resp, err := h.httpClient.Do(req)
+
+	if err != nil {
+		zap.L().Error(
+			"checkout API: send payment request failed",
+			zap.Error(err),
+			zap.String("method", pkg.CheckoutPaths[action].Method),
+			zap.Any(pkg.LogFieldOrder, order),
+		)
+		return nil, err
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		zap.L().Error(
+			"checkout API: payment response body can't be read",
+			zap.Error(err),
+			zap.String("method", pkg.CheckoutPaths[action].Method),
+			zap.Any(pkg.LogFieldOrder, order),
+		)
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
+		zap.L().Error(
+			"checkout API: payment response returned with bad http status",
+			zap.Int("status", resp.StatusCode),
+			zap.String("method", pkg.CheckoutPaths[action].Method),
+			zap.Any(pkg.LogFieldOrder, order),
+			zap.ByteString(pkg.LogFieldResponse, b),
+		)
+		return nil, paymentSystemErrorCreateRequestFailed
+	}
+
+	rsp := &CheckoutPaymentResponse{}
+	err = json.Unmarshal(b, &rsp)
+
+	if err != nil {
+		zap.L().Error(
+			"checkout API: payment response contain invalid json",
+			zap.Error(err),
+			zap.String("method", pkg.CheckoutPaths[action].Method),
+			zap.Any(pkg.LogFieldOrder, order),
+			zap.ByteString(pkg.LogFieldResponse, b),
+		)
+		return nil, err
+	}
+
+	if rsp.Status == checkoutPaymentStatusDeclined {
+		return nil, paymentSystemErrorPaymentDeclined
+	}
+
+	return rsp, nil
+}
+
+func (h *checkout) getCheckoutPayment(
+	order *billingpb.Order,
+	successUrl, failUrl string,
+	requisites map[string]string,
+) (*CheckoutPaymentRequest, error) {
+	data := &CheckoutPaymentRequest{
+		Amount:              checkoutAmountToMinor(order.ChargeAmount, order.ChargeCurrency),
+		Currency:            order.ChargeCurrency,
+		PaymentType:         checkoutPaymentTypeRegular,
+		Reference:           order.Id,
+		Description:         order.Description,
+		Capture:             true,
+		PaymentIp:           order.User.Ip,
+		ProcessingChannelId: order.PaymentMethod.Params.TerminalId,
+		SuccessUrl:          successUrl,
+		FailureUrl:          failUrl,
+		Customer: &CheckoutCustomer{
+			Email: order.User.TechEmail,
+		},
+		Metadata: map[string]string{
+			checkoutMetadataFieldOrderId:       order.Id,
+			checkoutMetadataFieldPaymentMethod: order.PaymentMethod.ExternalId,
+		},
+	}
+
+	switch order.PaymentMethod.ExternalId {
+	case recurringpb.PaymentSystemGroupAliasBankCard:
+		storeData, okStoreData := requisites[billingpb.PaymentCreateFieldStoreData]
+		recurringId, okRecurringId := requisites[billingpb.PaymentCreateFieldRecurringId]
+
+		data.ThreeDs = &CheckoutThreeDs{Enabled: true}
+
+		if okRecurringId && recurringId != "" {
+			data.PaymentType = checkoutPaymentTypeRecurring
+			data.Source = &CheckoutSource{
+				Type: checkoutSourceTypeId,
+				Id:   recurringId,
+				Cvv:  requisites[billingpb.PaymentCreateFieldCvv],
+			}
+
+			return data, nil
+		}
+
+		if okStoreData && storeData == "1" {
+			data.PaymentType = checkoutPaymentTypeRecurring
+			data.Metadata[checkoutMetadataFieldStoreData] = storeData
+		}
+
+		month, _ := strconv.Atoi(requisites[billingpb.PaymentCreateFieldMonth])
+		year, _ := strconv.Atoi(requisites[billingpb.PaymentCreateFieldYear])
+
+		if year > 0 && year < 100 {
+			year += 2000
+		}
+
+		data.Source = &CheckoutSource{
+			Type:        checkoutSourceTypeCard,
+			Number:      requisites[billingpb.PaymentCreateFieldPan],
+			ExpiryMonth: month,
+			ExpiryYear:  year,
+			Name:        strings.ToUpper(requisites[billingpb.PaymentCreateFieldHolder]),
+			Cvv:         requisites[billingpb.PaymentCreateFieldCvv],
+		}
+		data.Customer.Name = data.Source.Name
+		break
+	case recurringpb.PaymentSystemGroupAliasAlipay:
+		data.Source = &CheckoutSource{Type: checkoutSourceTypeAlipay}
+		break
+	default:
+		zap.L().Error(
+			"checkout API: requested create payment for unknown payment Method",
+			zap.Any(pkg.LogFieldOrder, order),
+		)
+		return nil, paymentSystemErrorUnknownPaymentMethod
+	}
+
+	return data, nil
+}
+
+func (h *checkout) getTxnParams(order *billingpb.Order, req *CheckoutWebhook) map[string]string {
+	params := make(map[string]string)
+
+	if req.Data.ResponseCode != "" {
+		params[billingpb.TxnParamsFieldDeclineCode] = req.Data.ResponseCode
+	}
+
+	if req.Data.ResponseSummary != "" {
+		params[billingpb.TxnParamsFieldDeclineReason] = req.Data.ResponseSummary
+	}
+
+	source := req.Data.Source
+
+	if source == nil {
+		return params
+	}
+
+	switch order.PaymentMethod.ExternalId {
+	case recurringpb.PaymentSystemGroupAliasBankCard:
+		if source.Bin != "" && source.Last4 != "" {
+			params[billingpb.PaymentCreateFieldPan] = source.Bin + checkoutMaskedPanPadding + source.Last4
+		}
+
+		params[billingpb.PaymentCreateFieldHolder] = source.Name
+		break
+	case recurringpb.PaymentSystemGroupAliasAlipay:
+		params[billingpb.PaymentCreateFieldEWallet] = source.Id
+		break
+	}
+
+	return params
+}
+
+func (h *checkout) parseTime(value string) (*timestamp.Timestamp, error) {
+	t, err := time.Parse(time.RFC3339, value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ptypes.TimestampProto(t)
+}
+
+func (h *checkout) checkCallbackRequestSignature(order *billingpb.Order, raw, signature string) error {
+	mac := hmac.New(sha256.New, []byte(order.PaymentMethod.Params.SecretCallback))
+	mac.Write([]byte(raw))
+
+	if !hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(strings.ToLower(signature))) {
+		zap.L().Error(
+			"checkout API: payment callback signature is invalid",
+			zap.Any(pkg.LogFieldOrder, order),
+		)
+		return errors2.NewBillingServerResponseError(pkg.StatusErrorValidation, paymentSystemErrorRequestSignatureIsInvalid)
+	}
+
+	return nil
+}
+
+func (h *checkout) getUrl(apiUrl, action string, params ...interface{}) (string, error) {
+	u, err := url.ParseRequestURI(apiUrl)
+
+	if err != nil {
+		zap.L().Error(
+			"checkout API: api url is invalid",
+			zap.Error(err),
+			zap.String("url", apiUrl),
+		)
+		return "", err
+	}
+
+	paths, ok := pkg.CheckoutPaths[action]
+
+	if !ok {
+		return "", fmt.Errorf("unable to find action %s", action)
+	}
+
+	path := paths.Path
+
+	if len(params) > 0 {
+		path = fmt.Sprintf(path, params...)
+	}
+
+	u.Path = path
+
+	return u.String(), nil
+}
+
+func (h *checkout) getRequest(order *billingpb.Order, data interface{}, action string, urlParams ...interface{}) (*http.Request, error) {
+	u, err := h.getUrl(order.GetPaymentSystemApiUrl(), action, urlParams...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+
+	if data != nil {
+		b, err := json.Marshal(data)
+
+		if err != nil {
+			return nil, err
+		}
+
+		body = bytes.NewBuffer(b)
+	}
+
+	req, err := http.NewRequest(pkg.CheckoutPaths[action].Method, u, body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add(pkg.HeaderContentType, pkg.MIMEApplicationJSON)
+	req.Header.Add(pkg.HeaderAuthorization, "Bearer "+order.PaymentMethod.Params.Secret)
+
+	return req, nil
+}
+
+func checkoutAmountToMinor(amount float64, currency string) int64 {
+	exp, ok := checkoutCurrencyExponents[currency]
+
+	if !ok {
+		exp = 2
+	}
+
+	return int64(math.Round(amount * math.Pow10(exp)))
+}
+
+func (t *checkoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := context.WithValue(req.Context(), &checkoutContextKey{name: "CheckoutRequestStart"}, time.Now())
+	req = req.WithContext(ctx)
+
+	var reqBody []byte
+
+	if req.Body != nil {
+		reqBody, _ = ioutil.ReadAll(req.Body)
+	}
+	req.Body = ioutil.NopCloser(bytes.NewBuffer(reqBody))
+
+	resp, err := t.transport().RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	t.log(req.URL.Path, req.Header, reqBody, resp)
+
+	return resp, err
+}
+
+func (t *checkoutTransport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+
+	return http.DefaultTransport
+}
+
+func (t *checkoutTransport) log(reqUrl string, reqHeader http.Header, reqBody []byte, rsp *http.Response) {
+	var rspBody []byte
+
+	if rsp.Body != nil {
+		rspBody, _ = ioutil.ReadAll(rsp.Body)
+	}
+	rsp.Body = ioutil.NopCloser(bytes.NewBuffer(rspBody))
+
+	request := reqBody
+	payment := &CheckoutPaymentRequest{}
+
+	if err := json.Unmarshal(reqBody, payment); err == nil && payment.Source != nil {
+		payment.Source.Number = tools.MaskBankCardNumber(payment.Source.Number)
+		payment.Source.Cvv = "***"
+
+		b, err := json.Marshal(payment)
+
+		if err != nil {
+			return
+		}
+
+		request = b
+	}
+
+	headers := reqHeader.Clone()
+	headers.Del(pkg.HeaderAuthorization)
+
+	zap.L().Info(
+		reqUrl,
+		zap.String("action", "checkout_request"),
+		zap.Any("request_headers", headers),
+		zap.ByteString("request_body", request),
+		zap.Int("response_status", rsp.StatusCode),
+		zap.Any("response_headers", rsp.Header),
+		zap.ByteString("response_body", rspBody),
+	)
+}
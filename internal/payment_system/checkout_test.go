@@ -0,0 +1,257 @@
+package payment_system
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"testing"
+)
+
+type CheckoutTestSuite struct {
+	suite.Suite
+	handler      PaymentSystemInterface
+	typedHandler *checkout
+	order        *billingpb.Order
+}
+
+func Test_Checkout(t *testing.T) {
+	suite.Run(t, new(CheckoutTestSuite))
+}
+
+func (suite *CheckoutTestSuite) SetupTest() {
+	zap.ReplaceGlobals(zap.NewNop())
+
+	suite.handler = NewCheckoutHandler()
+	handler, ok := suite.handler.(*checkout)
+	assert.True(suite.T(), ok)
+	suite.typedHandler = handler
+
+	suite.order = &billingpb.Order{
+		Id:             primitive.NewObjectID().Hex(),
+		Description:    "unit test",
+		PrivateStatus:  recurringpb.OrderStatusNew,
+		ChargeAmount:   10.25,
+		ChargeCurrency: "USD",
+		User: &billingpb.OrderUser{
+			Id:        primitive.NewObjectID().Hex(),
+			Ip:        "127.0.0.1",
+			TechEmail: "test@unit.test",
+		},
+		PaymentMethod: &billingpb.PaymentMethodOrder{
+			Id:            primitive.NewObjectID().Hex(),
+			Name:          "Bank card",
+			Handler:       pkg.PaymentSystemHandlerCheckout,
+			ExternalId:    recurringpb.PaymentSystemGroupAliasBankCard,
+			RefundAllowed: true,
+			Params: &billingpb.PaymentMethodParams{
+				Currency:       "USD",
+				TerminalId:     "pc_channel",
+				Secret:         "sk_test",
+				SecretCallback: "webhook_secret",
+				ApiUrl:         "https://api.sandbox.checkout.com",
+			},
+			PaymentSystemId: primitive.NewObjectID().Hex(),
+			Group:           recurringpb.PaymentSystemGroupAliasBankCard,
+		},
+	}
+}
+
+func (suite *CheckoutTestSuite) sign(raw string) string {
+	mac := hmac.New(sha256.New, []byte(suite.order.PaymentMethod.Params.SecretCallback))
+	mac.Write([]byte(raw))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (suite *CheckoutTestSuite) getWebhook(event string) (*CheckoutWebhook, string) {
+	raw := fmt.Sprintf(
+		`{"id":"evt_id","type":"%s","created_on":"2020-11-10T10:00:00Z","data":{"id":"pay_id","amount":1025,"currency":"USD","reference":"%s","payment_type":"Regular","source":{"id":"src_id","bin":"400000","last4":"0002","name":"CARD HOLDER"},"metadata":{"order_id":"%s","payment_method":"BANKCARD"}}}`,
+		event, suite.order.Id, suite.order.Id,
+	)
+	return &CheckoutWebhook{
+		Id:        "evt_id",
+		Type:      event,
+		CreatedOn: "2020-11-10T10:00:00Z",
+		Data: &CheckoutWebhookData{
+			Id:          "pay_id",
+			Amount:      1025,
+			Currency:    "USD",
+			Reference:   suite.order.Id,
+			PaymentType: checkoutPaymentTypeRegular,
+			Source:      &CheckoutWebhookSource{Id: "src_id", Bin: "400000", Last4: "0002", Name: "CARD HOLDER"},
+			Metadata: map[string]string{
+				checkoutMetadataFieldOrderId:       suite.order.Id,
+				checkoutMetadataFieldPaymentMethod: recurringpb.PaymentSystemGroupAliasBankCard,
+			},
+		},
+	}, raw
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_GetCheckoutPayment_Ok() {
+	res, err := suite.typedHandler.getCheckoutPayment(suite.order, "http://localhost/success", "http://localhost/fail", bankCardRequisites)
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), res.Source)
+	assert.Equal(suite.T(), checkoutSourceTypeCard, res.Source.Type)
+	assert.Equal(suite.T(), 12, res.Source.ExpiryMonth)
+	assert.Equal(suite.T(), 2019, res.Source.ExpiryYear)
+	assert.EqualValues(suite.T(), 1025, res.Amount)
+	assert.Equal(suite.T(), checkoutPaymentTypeRegular, res.PaymentType)
+	assert.Equal(suite.T(), suite.order.PaymentMethod.Params.TerminalId, res.ProcessingChannelId)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_GetCheckoutPayment_StoredCard_Ok() {
+	requisites := map[string]string{billingpb.PaymentCreateFieldRecurringId: "src_id"}
+	res, err := suite.typedHandler.getCheckoutPayment(suite.order, "http://localhost/success", "http://localhost/fail", requisites)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), checkoutSourceTypeId, res.Source.Type)
+	assert.Equal(suite.T(), "src_id", res.Source.Id)
+	assert.Equal(suite.T(), checkoutPaymentTypeRecurring, res.PaymentType)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_GetCheckoutPayment_UnknownPaymentMethod_Error() {
+	suite.order.PaymentMethod.ExternalId = recurringpb.PaymentSystemGroupAliasBitcoin
+	_, err := suite.typedHandler.getCheckoutPayment(suite.order, "http://localhost/success", "http://localhost/fail", bankCardRequisites)
+	assert.Equal(suite.T(), paymentSystemErrorUnknownPaymentMethod, err)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_CreatePayment_Ok() {
+	suite.typedHandler.httpClient = NewCheckoutHttpClientStatusOk()
+	url, err := suite.handler.CreatePayment(suite.order, "http://localhost/success", "http://localhost/fail", bankCardRequisites)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "http://localhost/3ds", url)
+	assert.Equal(suite.T(), recurringpb.OrderStatusPaymentSystemCreate, suite.order.PrivateStatus)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_CreatePayment_Declined() {
+	suite.typedHandler.httpClient.Transport = &TransportCheckoutDeclined{}
+	_, err := suite.handler.CreatePayment(suite.order, "http://localhost/success", "http://localhost/fail", bankCardRequisites)
+	assert.Equal(suite.T(), paymentSystemErrorPaymentDeclined, err)
+	assert.Equal(suite.T(), recurringpb.OrderStatusPaymentSystemRejectOnCreate, suite.order.PrivateStatus)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_CreatePayment_BadHttpStatus() {
+	suite.typedHandler.httpClient.Transport = &TransportStatusError{}
+	_, err := suite.handler.CreatePayment(suite.order, "http://localhost/success", "http://localhost/fail", bankCardRequisites)
+	assert.Equal(suite.T(), paymentSystemErrorCreateRequestFailed, err)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_ProcessPayment_Captured_Ok() {
+	req, raw := suite.getWebhook(checkoutEventPaymentCaptured)
+	err := suite.handler.ProcessPayment(suite.order, req, raw, suite.sign(raw))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), recurringpb.OrderStatusPaymentSystemComplete, suite.order.PrivateStatus)
+	assert.Equal(suite.T(), "pay_id", suite.order.Transaction)
+	assert.True(suite.T(), suite.order.IsRefundAllowed)
+	assert.Equal(suite.T(), "400000******0002", suite.order.PaymentMethodTxnParams[billingpb.PaymentCreateFieldPan])
+	assert.NotNil(suite.T(), suite.order.PaymentMethodOrderClosedAt)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_ProcessPayment_Declined_Ok() {
+	req, raw := suite.getWebhook(checkoutEventPaymentDeclined)
+	req.Data.ResponseCode = "20005"
+	req.Data.ResponseSummary = "Declined - Do Not Honour"
+	err := suite.handler.ProcessPayment(suite.order, req, raw, suite.sign(raw))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), recurringpb.OrderStatusPaymentSystemDeclined, suite.order.PrivateStatus)
+	assert.NotNil(suite.T(), suite.order.Cancellation)
+	assert.Equal(suite.T(), "20005", suite.order.Cancellation.Code)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_ProcessPayment_TemporaryStatus() {
+	req, raw := suite.getWebhook(checkoutEventPaymentApproved)
+	err := suite.handler.ProcessPayment(suite.order, req, raw, suite.sign(raw))
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), pkg.StatusTemporary, err.(*billingpb.ResponseError).Status)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_ProcessPayment_InvalidSignature() {
+	req, raw := suite.getWebhook(checkoutEventPaymentCaptured)
+	err := suite.handler.ProcessPayment(suite.order, req, raw, "invalid")
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), paymentSystemErrorRequestSignatureIsInvalid, err.(*billingpb.ResponseError).Message)
+	assert.Equal(suite.T(), recurringpb.OrderStatusPaymentSystemReject, suite.order.PrivateStatus)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_ProcessPayment_AmountMismatch() {
+	req, raw := suite.getWebhook(checkoutEventPaymentCaptured)
+	req.Data.Amount = 1000
+	err := suite.handler.ProcessPayment(suite.order, req, raw, suite.sign(raw))
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), PaymentSystemErrorRequestAmountOrCurrencyIsInvalid, err.(*billingpb.ResponseError).Message)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_CanSaveCard() {
+	req, _ := suite.getWebhook(checkoutEventPaymentCaptured)
+	assert.False(suite.T(), suite.handler.CanSaveCard(req))
+
+	req.Data.PaymentType = checkoutPaymentTypeRecurring
+	req.Data.Metadata[checkoutMetadataFieldStoreData] = "1"
+	assert.True(suite.T(), suite.handler.IsRecurringCallback(req))
+	assert.True(suite.T(), suite.handler.CanSaveCard(req))
+	assert.Equal(suite.T(), "src_id", suite.handler.GetRecurringId(req))
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_CreateRefund_Ok() {
+	suite.typedHandler.httpClient = NewCheckoutHttpClientStatusOk()
+	suite.order.Transaction = "pay_id"
+	refund := &billingpb.Refund{Id: primitive.NewObjectID().Hex(), Amount: 5, Currency: "USD"}
+
+	err := suite.handler.CreateRefund(suite.order, refund)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundStatusInProgress, refund.Status)
+	assert.Equal(suite.T(), "act_refund_id", refund.ExternalId)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_CreateRefund_BadHttpStatus() {
+	suite.typedHandler.httpClient.Transport = &TransportStatusError{}
+	refund := &billingpb.Refund{Id: primitive.NewObjectID().Hex(), Amount: 5, Currency: "USD"}
+
+	err := suite.handler.CreateRefund(suite.order, refund)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundStatusRejected, refund.Status)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_ProcessRefund_Ok() {
+	refund := &billingpb.Refund{Id: primitive.NewObjectID().Hex(), Amount: 10.25, Currency: "USD", Status: pkg.RefundStatusInProgress}
+	req, raw := suite.getWebhook(checkoutEventPaymentRefunded)
+	req.Data.ActionId = "act_refund_id"
+
+	err := suite.handler.ProcessRefund(suite.order, refund, req, raw, suite.sign(raw))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundStatusCompleted, refund.Status)
+	assert.Equal(suite.T(), "act_refund_id", refund.ExternalId)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_ProcessRefund_NotRefundEvent() {
+	refund := &billingpb.Refund{Id: primitive.NewObjectID().Hex(), Amount: 10.25, Currency: "USD", Status: pkg.RefundStatusInProgress}
+	req, raw := suite.getWebhook(checkoutEventPaymentCaptured)
+
+	err := suite.handler.ProcessRefund(suite.order, refund, req, raw, suite.sign(raw))
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundStatusRejected, refund.Status)
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_RecurringSubscription_NotSupported() {
+	subscription := &recurringpb.Subscription{Id: primitive.NewObjectID().Hex()}
+
+	_, err := suite.handler.CreateRecurringSubscription(suite.order, subscription, "http://localhost/success", "http://localhost/fail", bankCardRequisites)
+	assert.Equal(suite.T(), PaymentSystemErrorOperationNotSupported, err)
+	assert.Equal(suite.T(), PaymentSystemErrorOperationNotSupported, suite.handler.UpdateRecurringSubscriptionPlan(suite.order, subscription))
+	assert.Equal(suite.T(), PaymentSystemErrorOperationNotSupported, suite.handler.PauseRecurringSubscription(suite.order, subscription))
+
+	req, _ := suite.getWebhook(checkoutEventPaymentCaptured)
+	assert.False(suite.T(), suite.handler.IsSubscriptionCallback(req))
+}
+
+func (suite *CheckoutTestSuite) TestCheckout_AmountToMinor() {
+	assert.EqualValues(suite.T(), 1025, checkoutAmountToMinor(10.25, "USD"))
+	assert.EqualValues(suite.T(), 1000, checkoutAmountToMinor(1000, "JPY"))
+	assert.EqualValues(suite.T(), 10250, checkoutAmountToMinor(10.25, "KWD"))
+}
@@ -3,7 +3,9 @@ package payment_system
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"go.uber.org/zap"
 	"io/ioutil"
 	"net/http"
@@ -36,6 +38,12 @@ type TransportCardPayRecurringPlanInactive struct {
 	Transport http.RoundTripper
 }
 
+// TransportCardPayPaymentUpdate changes status of the payment as requested and keeps the last payment update request.
+type TransportCardPayPaymentUpdate struct {
+	Transport http.RoundTripper
+	Request   *CardPayPaymentUpdateRequest
+}
+
 func NewClientStatusOk() *http.Client {
 	return &http.Client{
 		Transport: &TransportStatusOk{},
@@ -134,3 +142,69 @@ func (h *TransportCardPayRecurringPlanInactive) RoundTrip(req *http.Request) (*h
 		Header:     make(http.Header),
 	}, nil
 }
+
+func (h *TransportCardPayPaymentUpdate) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := []byte("{}")
+
+	if req.URL.Path == pkg.CardPayPaths[pkg.PaymentSystemActionAuthenticate].Path {
+		body = []byte(`{"token_type": "bearer", "access_token": "123", "refresh_token": "123", "expires_in": 300, "refresh_expires_in": 900}`)
+	}
+
+	if req.Method == http.MethodPatch && req.Body != nil {
+		reqBody, _ := ioutil.ReadAll(req.Body)
+		h.Request = &CardPayPaymentUpdateRequest{}
+		_ = json.Unmarshal(reqBody, h.Request)
+
+		status := cardPayPaymentStatusVoided
+
+		if h.Request.PaymentData != nil && h.Request.PaymentData.StatusTo == cardPayStatusToComplete {
+			status = billingpb.CardPayPaymentResponseStatusCompleted
+		}
+
+		body = []byte(`{"payment_data": {"id": "paymentId", "status": "` + status + `"}}`)
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Header:     make(http.Header),
+	}, nil
+}
+
+type TransportCheckoutOk struct {
+	Transport http.RoundTripper
+}
+
+type TransportCheckoutDeclined struct {
+	Transport http.RoundTripper
+}
+
+func NewCheckoutHttpClientStatusOk() *http.Client {
+	return &http.Client{
+		Transport: &TransportCheckoutOk{},
+	}
+}
+
+func (h *TransportCheckoutOk) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := http.StatusCreated
+	body := []byte(`{"id": "pay_id", "action_id": "act_id", "status": "Pending", "_links": {"redirect": {"href": "http://localhost/3ds"}}}`)
+
+	if strings.HasSuffix(req.URL.Path, "/refunds") {
+		status = http.StatusAccepted
+		body = []byte(`{"action_id": "act_refund_id"}`)
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Header:     make(http.Header),
+	}, nil
+}
+
+func (h *TransportCheckoutDeclined) RoundTrip(_ *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusCreated,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"id": "pay_id", "approved": false, "status": "Declined", "response_code": "20005"}`)),
+		Header:     make(http.Header),
+	}, nil
+}
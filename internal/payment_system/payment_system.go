@@ -2,9 +2,14 @@ package payment_system
 
 import (
 	"github.com/golang/protobuf/proto"
+	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/errors"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
 )
 
 const (
@@ -30,8 +35,56 @@ var (
 	paymentSystemErrorCreateRecurringSubscriptionFailed      = errors.NewBillingServerErrorMsg("ph000016", "create recurring subscription failed")
 	paymentSystemErrorDeleteRecurringPlanFailed              = errors.NewBillingServerErrorMsg("ph000017", "delete recurring plan failed")
 	paymentSystemErrorUpdateRecurringSubscriptionFailed      = errors.NewBillingServerErrorMsg("ph000018", "update recurring subscription failed")
+	paymentSystemErrorPaymentDeclined                        = errors.NewBillingServerErrorMsg("ph000019", "payment declined by payment system")
+	PaymentSystemErrorOperationNotSupported                  = errors.NewBillingServerErrorMsg("ph000020", "operation not supported by payment system")
+	paymentSystemErrorCaptureFailed                          = errors.NewBillingServerErrorMsg("ph000021", "payment capture failed")
+	paymentSystemErrorVoidFailed                             = errors.NewBillingServerErrorMsg("ph000022", "payment void failed")
 )
 
+// IsUnavailableError checks that payment creation failed because the payment system is unavailable
+// (payment system rejected request or transport failed), so payment can be retried with another payment system.
+func IsUnavailableError(err error) bool {
+	if err == paymentSystemErrorCreateRequestFailed || err == paymentSystemErrorAuthenticateFailed {
+		return true
+	}
+
+	if _, ok := err.(*url.Error); ok {
+		return true
+	}
+
+	e, ok := err.(net.Error)
+
+	return ok && e.Timeout()
+}
+
+// IsManualCaptureOrder checks that payment of the order must be only authorized by payment system and the funds
+// will be captured (or released) later by the separate request.
+func IsManualCaptureOrder(order *billingpb.Order) bool {
+	return order.PrivateMetadata[pkg.OrderPrivateMetadataCaptureMode] == pkg.OrderCaptureModeManual
+}
+
+// GetRecurringTrialDays returns the length of the trial period of the recurring subscription of the order in days,
+// zero value means the subscription hasn't trial.
+func GetRecurringTrialDays(order *billingpb.Order) int {
+	days, err := strconv.Atoi(order.PrivateMetadata[pkg.OrderPrivateMetadataTrialDays])
+
+	if err != nil || days < 0 || order.RecurringSettings == nil {
+		return 0
+	}
+
+	return days
+}
+
+// GetRecurringPlanAmount returns the amount of regular payments of the recurring subscription of the order. It differs
+// from the order charge amount when the order is the trial payment of the subscription.
+func GetRecurringPlanAmount(order *billingpb.Order) float64 {
+	if amount, err := strconv.ParseFloat(order.PrivateMetadata[pkg.OrderPrivateMetadataTrialPlanAmount], 64); err == nil {
+		return amount
+	}
+
+	return order.ChargeAmount
+}
+
 type PaymentSystemInterface interface {
 	CreatePayment(order *billingpb.Order, successUrl, failUrl string, requisites map[string]string) (string, error)
 	ProcessPayment(order *billingpb.Order, message proto.Message, raw, signature string) error
@@ -43,6 +96,13 @@ type PaymentSystemInterface interface {
 	CreateRecurringSubscription(order *billingpb.Order, subscription *recurringpb.Subscription, successUrl, failUrl string, requisites map[string]string) (string, error)
 	IsSubscriptionCallback(request proto.Message) bool
 	DeleteRecurringSubscription(order *billingpb.Order, subscription *recurringpb.Subscription) error
+	UpdateRecurringSubscriptionPlan(order *billingpb.Order, subscription *recurringpb.Subscription) error
+	PauseRecurringSubscription(order *billingpb.Order, subscription *recurringpb.Subscription) error
+	ResumeRecurringSubscription(order *billingpb.Order, subscription *recurringpb.Subscription, nextBillingAt time.Time) error
+	UpdateRecurringSubscriptionCard(order *billingpb.Order, subscription *recurringpb.Subscription, recurringId string) error
+	CreateAuthorization(order *billingpb.Order, successUrl, failUrl string, requisites map[string]string) (string, error)
+	Capture(order *billingpb.Order, amount float64) error
+	Void(order *billingpb.Order) error
 }
 
 type PaymentSystemManagerInterface interface {
@@ -0,0 +1,179 @@
+package payment_system
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SettlementReportLineTypePayment = "payment"
+	SettlementReportLineTypeRefund  = "refund"
+
+	cardPaySettlementColumnTransactionId   = "transaction_id"
+	cardPaySettlementColumnTransactionType = "transaction_type"
+	cardPaySettlementColumnTransactionDate = "transaction_date"
+	cardPaySettlementColumnAmount          = "amount"
+	cardPaySettlementColumnCurrency        = "currency"
+	cardPaySettlementColumnFee             = "fee"
+)
+
+var (
+	PaymentSystemErrorSettlementReportNotSupported = errors.NewBillingServerErrorMsg("ph000023", "settlement report of the payment system isn't supported")
+	PaymentSystemErrorSettlementReportInvalid      = errors.NewBillingServerErrorMsg("ph000024", "settlement report file is invalid")
+
+	settlementReportParsers = map[string]func([]byte) ([]*SettlementReportLine, error){
+		billingpb.PaymentSystemHandlerCardPay: parseCardPaySettlementReport,
+	}
+
+	cardPaySettlementRequiredColumns = []string{
+		cardPaySettlementColumnTransactionId,
+		cardPaySettlementColumnTransactionType,
+		cardPaySettlementColumnTransactionDate,
+		cardPaySettlementColumnAmount,
+		cardPaySettlementColumnCurrency,
+		cardPaySettlementColumnFee,
+	}
+
+	cardPaySettlementLineTypes = map[string]string{
+		"PAYMENT":    SettlementReportLineTypePayment,
+		"REFUND":     SettlementReportLineTypeRefund,
+		"CHARGEBACK": SettlementReportLineTypeRefund,
+	}
+
+	cardPaySettlementDateLayouts = []string{
+		"2006-01-02 15:04:05",
+		time.RFC3339,
+		"2006-01-02",
+	}
+)
+
+// SettlementReportLine is the transaction settled by the payment system. Amount and fee are positive
+// for payments and refunds.
+type SettlementReportLine struct {
+	Line          int
+	TransactionId string
+	Type          string
+	Amount        float64
+	Currency      string
+	Fee           float64
+	Date          time.Time
+}
+
+// ParseSettlementReport returns transactions of the settlement report file of the payment system by its handler.
+func ParseSettlementReport(handler string, data []byte) ([]*SettlementReportLine, error) {
+	parser, ok := settlementReportParsers[handler]
+
+	if !ok {
+		return nil, PaymentSystemErrorSettlementReportNotSupported
+	}
+
+	return parser(data)
+}
+
+// parseCardPaySettlementReport parses the CSV settlement report of CardPay. Columns are found by the header row,
+// so their order doesn't matter. Lines of transactions other than payments, refunds and chargebacks are skipped.
+func parseCardPaySettlementReport(data []byte) ([]*SettlementReportLine, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+
+	if err != nil {
+		return nil, PaymentSystemErrorSettlementReportInvalid
+	}
+
+	columns := make(map[string]int)
+
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[strings.ReplaceAll(name, " ", "_")] = i
+	}
+
+	for _, name := range cardPaySettlementRequiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, PaymentSystemErrorSettlementReportInvalid
+		}
+	}
+
+	var lines []*SettlementReportLine
+	number := 1
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		number++
+
+		if err != nil {
+			return nil, PaymentSystemErrorSettlementReportInvalid
+		}
+
+		lineType, ok := cardPaySettlementLineTypes[strings.ToUpper(strings.TrimSpace(record[columns[cardPaySettlementColumnTransactionType]]))]
+
+		if !ok {
+			continue
+		}
+
+		line := &SettlementReportLine{
+			Line:          number,
+			TransactionId: strings.TrimSpace(record[columns[cardPaySettlementColumnTransactionId]]),
+			Type:          lineType,
+			Currency:      strings.ToUpper(strings.TrimSpace(record[columns[cardPaySettlementColumnCurrency]])),
+		}
+
+		if line.TransactionId == "" || len(line.Currency) != 3 {
+			return nil, PaymentSystemErrorSettlementReportInvalid
+		}
+
+		line.Amount, err = strconv.ParseFloat(strings.TrimSpace(record[columns[cardPaySettlementColumnAmount]]), 64)
+
+		if err != nil {
+			return nil, PaymentSystemErrorSettlementReportInvalid
+		}
+
+		line.Fee, err = strconv.ParseFloat(strings.TrimSpace(record[columns[cardPaySettlementColumnFee]]), 64)
+
+		if err != nil {
+			return nil, PaymentSystemErrorSettlementReportInvalid
+		}
+
+		line.Amount = math.Abs(line.Amount)
+		line.Fee = math.Abs(line.Fee)
+		line.Date, err = parseCardPaySettlementDate(record[columns[cardPaySettlementColumnTransactionDate]])
+
+		if err != nil {
+			return nil, PaymentSystemErrorSettlementReportInvalid
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+func parseCardPaySettlementDate(value string) (time.Time, error) {
+	var (
+		date time.Time
+		err  error
+	)
+
+	for _, layout := range cardPaySettlementDateLayouts {
+		date, err = time.ParseInLocation(layout, strings.TrimSpace(value), time.UTC)
+
+		if err == nil {
+			return date, nil
+		}
+	}
+
+	return date, err
+}
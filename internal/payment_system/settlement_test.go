@@ -0,0 +1,102 @@
+package payment_system
+
+import (
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type SettlementReportTestSuite struct {
+	suite.Suite
+}
+
+func Test_SettlementReport(t *testing.T) {
+	suite.Run(t, new(SettlementReportTestSuite))
+}
+
+func (suite *SettlementReportTestSuite) SetupTest() {
+	zap.ReplaceGlobals(zap.NewNop())
+}
+
+func (suite *SettlementReportTestSuite) TestSettlementReport_ParseCardPay_Ok() {
+	data := "\ufeffTransaction ID,Transaction Type,Transaction Date,Amount,Currency,Fee\n" +
+		"1001,PAYMENT,2020-11-20 10:15:00,100.50,rub,-3.02\n" +
+		"1002,PAYOUT,2020-11-20 11:00:00,50.00,RUB,0\n" +
+		"1003,refund,2020-11-21T09:00:00Z,-20.00,RUB,0.50\n" +
+		"1004,CHARGEBACK,2020-11-22,30.00,USD,1\n"
+
+	lines, err := ParseSettlementReport(billingpb.PaymentSystemHandlerCardPay, []byte(data))
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), lines, 3)
+
+	assert.Equal(suite.T(), 2, lines[0].Line)
+	assert.Equal(suite.T(), "1001", lines[0].TransactionId)
+	assert.Equal(suite.T(), SettlementReportLineTypePayment, lines[0].Type)
+	assert.Equal(suite.T(), 100.50, lines[0].Amount)
+	assert.Equal(suite.T(), "RUB", lines[0].Currency)
+	assert.Equal(suite.T(), 3.02, lines[0].Fee)
+	assert.Equal(suite.T(), time.Date(2020, 11, 20, 10, 15, 0, 0, time.UTC), lines[0].Date)
+
+	assert.Equal(suite.T(), 4, lines[1].Line)
+	assert.Equal(suite.T(), SettlementReportLineTypeRefund, lines[1].Type)
+	assert.Equal(suite.T(), float64(20), lines[1].Amount)
+	assert.Equal(suite.T(), time.Date(2020, 11, 21, 9, 0, 0, 0, time.UTC), lines[1].Date)
+
+	assert.Equal(suite.T(), SettlementReportLineTypeRefund, lines[2].Type)
+	assert.Equal(suite.T(), time.Date(2020, 11, 22, 0, 0, 0, 0, time.UTC), lines[2].Date)
+}
+
+func (suite *SettlementReportTestSuite) TestSettlementReport_ParseCardPay_ColumnsOrder_Ok() {
+	data := "currency,fee,amount,transaction_date,transaction_type,transaction_id\n" +
+		"EUR,0.3,10,2020-11-20 10:15:00,PAYMENT,1001\n"
+
+	lines, err := ParseSettlementReport(billingpb.PaymentSystemHandlerCardPay, []byte(data))
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), lines, 1)
+	assert.Equal(suite.T(), "1001", lines[0].TransactionId)
+	assert.Equal(suite.T(), "EUR", lines[0].Currency)
+	assert.Equal(suite.T(), float64(10), lines[0].Amount)
+	assert.Equal(suite.T(), 0.3, lines[0].Fee)
+}
+
+func (suite *SettlementReportTestSuite) TestSettlementReport_NotSupported_Error() {
+	lines, err := ParseSettlementReport("unknown", []byte("transaction_id\n"))
+	assert.Nil(suite.T(), lines)
+	assert.Equal(suite.T(), PaymentSystemErrorSettlementReportNotSupported, err)
+}
+
+func (suite *SettlementReportTestSuite) TestSettlementReport_ParseCardPay_ColumnMissing_Error() {
+	data := "transaction_id,transaction_type,transaction_date,amount,currency\n" +
+		"1001,PAYMENT,2020-11-20 10:15:00,100,RUB\n"
+
+	lines, err := ParseSettlementReport(billingpb.PaymentSystemHandlerCardPay, []byte(data))
+	assert.Nil(suite.T(), lines)
+	assert.Equal(suite.T(), PaymentSystemErrorSettlementReportInvalid, err)
+}
+
+func (suite *SettlementReportTestSuite) TestSettlementReport_ParseCardPay_AmountInvalid_Error() {
+	data := "transaction_id,transaction_type,transaction_date,amount,currency,fee\n" +
+		"1001,PAYMENT,2020-11-20 10:15:00,abc,RUB,0\n"
+
+	lines, err := ParseSettlementReport(billingpb.PaymentSystemHandlerCardPay, []byte(data))
+	assert.Nil(suite.T(), lines)
+	assert.Equal(suite.T(), PaymentSystemErrorSettlementReportInvalid, err)
+}
+
+func (suite *SettlementReportTestSuite) TestSettlementReport_ParseCardPay_DateInvalid_Error() {
+	data := "transaction_id,transaction_type,transaction_date,amount,currency,fee\n" +
+		"1001,PAYMENT,20.11.2020,100,RUB,0\n"
+
+	lines, err := ParseSettlementReport(billingpb.PaymentSystemHandlerCardPay, []byte(data))
+	assert.Nil(suite.T(), lines)
+	assert.Equal(suite.T(), PaymentSystemErrorSettlementReportInvalid, err)
+}
+
+func (suite *SettlementReportTestSuite) TestSettlementReport_ParseCardPay_Empty_Error() {
+	lines, err := ParseSettlementReport(billingpb.PaymentSystemHandlerCardPay, []byte{})
+	assert.Nil(suite.T(), lines)
+	assert.Equal(suite.T(), PaymentSystemErrorSettlementReportInvalid, err)
+}
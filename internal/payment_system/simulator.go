@@ -0,0 +1,667 @@
+package payment_system
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	errors2 "github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SimulatorOutcomeSuccess    = "success"
+	SimulatorOutcomeDecline    = "decline"
+	SimulatorOutcome3ds        = "3ds"
+	SimulatorOutcomeChargeback = "chargeback"
+	SimulatorOutcomeDelayed    = "delayed"
+
+	// Refund reason prefix to choose outcome of the refund, for example "simulator:decline"
+	SimulatorRefundReasonPrefix = "simulator:"
+
+	simulatorCallbackTypePayment = "payment"
+	simulatorCallbackTypeRefund  = "refund"
+
+	simulatorStatusCompleted  = "completed"
+	simulatorStatusDeclined   = "declined"
+	simulatorStatusAuthorized = "authorized"
+
+	simulatorDeclineCode   = "05"
+	simulatorDeclineReason = "Do not honor"
+
+	simulatorQueryParam3ds = "simulator_3ds"
+	simulatorMaskedPan     = "******"
+
+	// Callback is sent from CreatePayment before the order with payment system status is saved by the billing
+	// server, so callbacks can't be sent earlier than the order will be saved
+	simulatorMinCallbackDelay = time.Second
+
+	// Number of checks of the order saved by the billing server before the payment callback, the callback is
+	// postponed by the callback delay while the order is still new
+	simulatorOrderSavedAttempts = 10
+)
+
+var (
+	// Test cards to choose outcome of the payment regardless of the simulator default outcome
+	simulatorCardOutcomes = map[string]string{
+		"4000000000000002": SimulatorOutcomeDecline,
+		"4000000000003063": SimulatorOutcome3ds,
+		"4000000000000259": SimulatorOutcomeChargeback,
+		"4000000000000077": SimulatorOutcomeDelayed,
+	}
+
+	simulatorOutcomes = map[string]bool{
+		SimulatorOutcomeSuccess:    true,
+		SimulatorOutcomeDecline:    true,
+		SimulatorOutcome3ds:        true,
+		SimulatorOutcomeChargeback: true,
+		SimulatorOutcomeDelayed:    true,
+	}
+)
+
+// SimulatorCallbackProcessor is the billing server side which receives callbacks sent by the simulator.
+type SimulatorCallbackProcessor interface {
+	PaymentCallbackProcess(ctx context.Context, req *billingpb.PaymentNotifyRequest, rsp *billingpb.PaymentNotifyResponse) error
+	ProcessRefundCallback(ctx context.Context, req *billingpb.CallbackRequest, rsp *billingpb.PaymentNotifyResponse) error
+	CreateRefund(ctx context.Context, req *billingpb.CreateRefundRequest, rsp *billingpb.CreateRefundResponse) error
+	GetOrderPrivateStatus(ctx context.Context, orderId string) (int32, error)
+}
+
+type SimulatorSettings struct {
+	// Outcome of payments which card is not one of the test cards
+	Outcome string
+	// Delay before callback will be sent
+	CallbackDelay time.Duration
+	// Delay before callback will be sent for payments with the delayed outcome
+	DelayedCallbackDelay time.Duration
+}
+
+// SimulatorCallback is the body of callbacks which simulator sends for payments and refunds.
+type SimulatorCallback struct {
+	Id             string  `json:"id"`
+	Type           string  `json:"type"`
+	Status         string  `json:"status"`
+	OrderId        string  `json:"order_id"`
+	RefundId       string  `json:"refund_id,omitempty"`
+	SubscriptionId string  `json:"subscription_id,omitempty"`
+	RecurringId    string  `json:"recurring_id,omitempty"`
+	StoreData      bool    `json:"store_data,omitempty"`
+	PaymentMethod  string  `json:"payment_method"`
+	Amount         float64 `json:"amount"`
+	Currency       string  `json:"currency"`
+	Pan            string  `json:"pan,omitempty"`
+	Holder         string  `json:"holder,omitempty"`
+	Account        string  `json:"account,omitempty"`
+	Is3ds          bool    `json:"is_3ds"`
+	DeclineCode    string  `json:"decline_code,omitempty"`
+	DeclineReason  string  `json:"decline_reason,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+func (m *SimulatorCallback) Reset()         { *m = SimulatorCallback{} }
+func (m *SimulatorCallback) String() string { return proto.CompactTextString(m) }
+func (*SimulatorCallback) ProtoMessage()    {}
+
+func (m *SimulatorCallback) GetOrderId() string {
+	return m.OrderId
+}
+
+type simulator struct {
+	processor SimulatorCallbackProcessor
+	settings  *SimulatorSettings
+	after     func(d time.Duration, f func())
+	mx        sync.Mutex
+	outcomes  map[string]string
+}
+
+// NewSimulatorHandler create in-process payment system which doesn't send any request outside and instead sends
+// signed callbacks to the processor with the outcome chosen by test card or by the default outcome from settings.
+func NewSimulatorHandler(processor SimulatorCallbackProcessor, settings *SimulatorSettings) PaymentSystemInterface {
+	if !simulatorOutcomes[settings.Outcome] {
+		settings.Outcome = SimulatorOutcomeSuccess
+	}
+
+	if settings.CallbackDelay < simulatorMinCallbackDelay {
+		settings.CallbackDelay = simulatorMinCallbackDelay
+	}
+
+	if settings.DelayedCallbackDelay < simulatorMinCallbackDelay {
+		settings.DelayedCallbackDelay = simulatorMinCallbackDelay
+	}
+
+	return &simulator{
+		processor: processor,
+		settings:  settings,
+		after: func(d time.Duration, f func()) {
+			time.AfterFunc(d, f)
+		},
+		outcomes: make(map[string]string),
+	}
+}
+
+func (h *simulator) CreatePayment(
+	order *billingpb.Order,
+	successUrl, failUrl string,
+	requisites map[string]string,
+) (string, error) {
+	outcome := h.getOutcome(requisites)
+	callback := h.getPaymentCallback(order, outcome, requisites)
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemCreate
+	h.setOutcome(order.Id, outcome)
+	h.send(order, callback, outcome)
+
+	return h.getRedirectUrl(order, successUrl, outcome), nil
+}
+
+func (h *simulator) ProcessPayment(order *billingpb.Order, message proto.Message, raw, signature string) error {
+	req := message.(*SimulatorCallback)
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemReject
+	err := h.checkCallbackRequestSignature(order, raw, signature)
+
+	if err != nil {
+		return err
+	}
+
+	if req.Type != simulatorCallbackTypePayment {
+		return errors2.NewBillingServerResponseError(pkg.StatusErrorValidation, paymentSystemErrorRequestStatusIsInvalid)
+	}
+
+	if req.PaymentMethod != order.PaymentMethod.ExternalId {
+		return errors2.NewBillingServerResponseError(pkg.StatusErrorValidation, paymentSystemErrorRequestPaymentMethodIsInvalid)
+	}
+
+	if req.Amount != order.ChargeAmount || req.Currency != order.ChargeCurrency {
+		return errors2.NewBillingServerResponseError(pkg.StatusErrorValidation, PaymentSystemErrorRequestAmountOrCurrencyIsInvalid)
+	}
+
+	t, err := time.Parse(time.RFC3339, req.CreatedAt)
+
+	if err != nil {
+		return errors2.NewBillingServerResponseError(pkg.StatusErrorValidation, paymentSystemErrorRequestTimeFieldIsInvalid)
+	}
+
+	ts, _ := ptypes.TimestampProto(t)
+	order.PaymentMethodTxnParams = h.getTxnParams(order, req)
+
+	switch req.Status {
+	case simulatorStatusDeclined:
+		order.PrivateStatus = recurringpb.OrderStatusPaymentSystemDeclined
+		order.Cancellation = &billingpb.OrderNotificationCancellation{
+			Code:   req.DeclineCode,
+			Reason: req.DeclineReason,
+		}
+		break
+	case simulatorStatusCompleted:
+		order.PrivateStatus = recurringpb.OrderStatusPaymentSystemComplete
+		order.IsRefundAllowed = order.PaymentMethod.RefundAllowed
+		break
+	case simulatorStatusAuthorized:
+		order.PrivateStatus = pkg.OrderStatusPaymentSystemAuthorized
+		break
+	default:
+		return errors2.NewBillingServerResponseError(pkg.StatusTemporary, PaymentSystemErrorRequestTemporarySkipped)
+	}
+
+	order.Transaction = req.Id
+	order.PaymentMethodOrderClosedAt = ts
+
+	return nil
+}
+
+func (h *simulator) IsRecurringCallback(request proto.Message) bool {
+	req := request.(*SimulatorCallback)
+	return req.PaymentMethod == recurringpb.PaymentSystemGroupAliasBankCard && req.RecurringId != ""
+}
+
+func (h *simulator) CanSaveCard(request proto.Message) bool {
+	return h.IsRecurringCallback(request) && request.(*SimulatorCallback).StoreData
+}
+
+func (h *simulator) GetRecurringId(request proto.Message) string {
+	return request.(*SimulatorCallback).RecurringId
+}
+
+func (h *simulator) CreateRefund(order *billingpb.Order, refund *billingpb.Refund) error {
+	outcome := SimulatorOutcomeSuccess
+
+	if strings.HasPrefix(refund.Reason, SimulatorRefundReasonPrefix) {
+		outcome = strings.TrimPrefix(refund.Reason, SimulatorRefundReasonPrefix)
+	}
+
+	callback := &SimulatorCallback{
+		Id:            primitive.NewObjectID().Hex(),
+		Type:          simulatorCallbackTypeRefund,
+		Status:        simulatorStatusCompleted,
+		OrderId:       order.Id,
+		RefundId:      refund.Id,
+		PaymentMethod: order.PaymentMethod.ExternalId,
+		Amount:        refund.Amount,
+		Currency:      refund.Currency,
+	}
+
+	if outcome == SimulatorOutcomeDecline {
+		callback.Status = simulatorStatusDeclined
+	}
+
+	refund.Status = pkg.RefundStatusInProgress
+	refund.ExternalId = callback.Id
+
+	h.send(order, callback, outcome)
+
+	return nil
+}
+
+func (h *simulator) ProcessRefund(
+	order *billingpb.Order,
+	refund *billingpb.Refund,
+	message proto.Message,
+	raw, signature string,
+) error {
+	req := message.(*SimulatorCallback)
+	refundInitialStatus := refund.Status
+	refund.Status = pkg.RefundStatusRejected
+
+	err := h.checkCallbackRequestSignature(order, raw, signature)
+
+	if err != nil {
+		err.(*billingpb.ResponseError).Status = billingpb.ResponseStatusBadData
+		return err
+	}
+
+	if req.Type != simulatorCallbackTypeRefund {
+		return errors2.NewBillingServerResponseError(billingpb.ResponseStatusBadData, paymentSystemErrorRequestStatusIsInvalid)
+	}
+
+	if req.Amount != refund.Amount || req.Currency != refund.Currency {
+		return errors2.NewBillingServerResponseError(billingpb.ResponseStatusBadData, PaymentSystemErrorRefundRequestAmountOrCurrencyIsInvalid)
+	}
+
+	t, err := time.Parse(time.RFC3339, req.CreatedAt)
+
+	if err != nil {
+		return errors2.NewBillingServerResponseError(pkg.StatusErrorValidation, paymentSystemErrorRequestTimeFieldIsInvalid)
+	}
+
+	ts, _ := ptypes.TimestampProto(t)
+
+	switch req.Status {
+	case simulatorStatusDeclined:
+		refund.Status = pkg.RefundStatusPaymentSystemDeclined
+		break
+	case simulatorStatusCompleted:
+		refund.Status = pkg.RefundStatusCompleted
+		break
+	default:
+		refund.Status = refundInitialStatus
+		return errors2.NewBillingServerResponseError(billingpb.ResponseStatusTemporary, PaymentSystemErrorRequestTemporarySkipped)
+	}
+
+	refund.ExternalId = req.Id
+	refund.UpdatedAt = ptypes.TimestampNow()
+	order.PaymentMethodOrderClosedAt = ts
+
+	return nil
+}
+
+func (h *simulator) CreateRecurringSubscription(
+	order *billingpb.Order,
+	subscription *recurringpb.Subscription,
+	successUrl, failUrl string,
+	requisites map[string]string,
+) (string, error) {
+	outcome := h.getOutcome(requisites)
+	callback := h.getPaymentCallback(order, outcome, requisites)
+	callback.SubscriptionId = subscription.Id
+
+	subscription.CardpaySubscriptionId = callback.Id
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemCreate
+	h.setOutcome(order.Id, outcome)
+	h.send(order, callback, outcome)
+
+	return h.getRedirectUrl(order, successUrl, outcome), nil
+}
+
+func (h *simulator) IsSubscriptionCallback(request proto.Message) bool {
+	return request.(*SimulatorCallback).SubscriptionId != ""
+}
+
+func (h *simulator) DeleteRecurringSubscription(order *billingpb.Order, subscription *recurringpb.Subscription) error {
+	return nil
+}
+
+func (h *simulator) UpdateRecurringSubscriptionPlan(order *billingpb.Order, subscription *recurringpb.Subscription) error {
+	return nil
+}
+
+func (h *simulator) PauseRecurringSubscription(order *billingpb.Order, subscription *recurringpb.Subscription) error {
+	return nil
+}
+
+func (h *simulator) ResumeRecurringSubscription(
+	order *billingpb.Order,
+	subscription *recurringpb.Subscription,
+	nextBillingAt time.Time,
+) error {
+	return nil
+}
+
+func (h *simulator) UpdateRecurringSubscriptionCard(
+	order *billingpb.Order,
+	subscription *recurringpb.Subscription,
+	recurringId string,
+) error {
+	return nil
+}
+
+func (h *simulator) CreateAuthorization(
+	order *billingpb.Order,
+	successUrl, failUrl string,
+	requisites map[string]string,
+) (string, error) {
+	outcome := h.getOutcome(requisites)
+	callback := h.getPaymentCallback(order, outcome, requisites)
+
+	if callback.Status == simulatorStatusCompleted {
+		callback.Status = simulatorStatusAuthorized
+	}
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemCreate
+	h.setOutcome(order.Id, outcome)
+	h.send(order, callback, outcome)
+
+	return h.getRedirectUrl(order, successUrl, outcome), nil
+}
+
+// Capture and void of the authorized payment are completed by simulator immediately, without callbacks.
+func (h *simulator) Capture(order *billingpb.Order, amount float64) error {
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemComplete
+	order.IsRefundAllowed = order.PaymentMethod.RefundAllowed
+	order.PaymentMethodOrderClosedAt = ptypes.TimestampNow()
+
+	return nil
+}
+
+func (h *simulator) Void(order *billingpb.Order) error {
+	order.PrivateStatus = pkg.OrderStatusPaymentSystemVoided
+	order.CanceledAt = ptypes.TimestampNow()
+
+	return nil
+}
+
+func (h *simulator) getOutcome(requisites map[string]string) string {
+	if outcome, ok := simulatorCardOutcomes[requisites[billingpb.PaymentCreateFieldPan]]; ok {
+		return outcome
+	}
+
+	return h.settings.Outcome
+}
+
+func (h *simulator) setOutcome(orderId, outcome string) {
+	h.mx.Lock()
+	h.outcomes[orderId] = outcome
+	h.mx.Unlock()
+}
+
+func (h *simulator) popOutcome(orderId string) string {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	outcome := h.outcomes[orderId]
+	delete(h.outcomes, orderId)
+
+	return outcome
+}
+
+func (h *simulator) getPaymentCallback(
+	order *billingpb.Order,
+	outcome string,
+	requisites map[string]string,
+) *SimulatorCallback {
+	callback := &SimulatorCallback{
+		Id:            primitive.NewObjectID().Hex(),
+		Type:          simulatorCallbackTypePayment,
+		Status:        simulatorStatusCompleted,
+		OrderId:       order.Id,
+		PaymentMethod: order.PaymentMethod.ExternalId,
+		Amount:        order.ChargeAmount,
+		Currency:      order.ChargeCurrency,
+		Is3ds:         outcome == SimulatorOutcome3ds,
+	}
+
+	if outcome == SimulatorOutcomeDecline {
+		callback.Status = simulatorStatusDeclined
+		callback.DeclineCode = simulatorDeclineCode
+		callback.DeclineReason = simulatorDeclineReason
+	}
+
+	switch order.PaymentMethod.ExternalId {
+	case recurringpb.PaymentSystemGroupAliasBankCard:
+		pan := requisites[billingpb.PaymentCreateFieldPan]
+
+		if len(pan) > 10 {
+			callback.Pan = pan[:6] + simulatorMaskedPan + pan[len(pan)-4:]
+		}
+
+		callback.Holder = strings.ToUpper(requisites[billingpb.PaymentCreateFieldHolder])
+
+		if recurringId := requisites[billingpb.PaymentCreateFieldRecurringId]; recurringId != "" {
+			callback.RecurringId = recurringId
+		} else if requisites[billingpb.PaymentCreateFieldStoreData] == "1" {
+			callback.RecurringId = primitive.NewObjectID().Hex()
+			callback.StoreData = true
+		}
+		break
+	default:
+		callback.Account = requisites[billingpb.PaymentCreateFieldEWallet]
+		break
+	}
+
+	return callback
+}
+
+func (h *simulator) getTxnParams(order *billingpb.Order, req *SimulatorCallback) map[string]string {
+	params := make(map[string]string)
+
+	if req.DeclineCode != "" {
+		params[billingpb.TxnParamsFieldDeclineCode] = req.DeclineCode
+	}
+
+	if req.DeclineReason != "" {
+		params[billingpb.TxnParamsFieldDeclineReason] = req.DeclineReason
+	}
+
+	switch order.PaymentMethod.ExternalId {
+	case recurringpb.PaymentSystemGroupAliasBankCard:
+		params[billingpb.PaymentCreateFieldPan] = req.Pan
+		params[billingpb.PaymentCreateFieldHolder] = req.Holder
+		params[billingpb.TxnParamsFieldBankCardIs3DS] = "0"
+
+		if req.Is3ds {
+			params[billingpb.TxnParamsFieldBankCardIs3DS] = "1"
+		}
+		break
+	default:
+		params[billingpb.PaymentCreateFieldEWallet] = req.Account
+		break
+	}
+
+	return params
+}
+
+func (h *simulator) getRedirectUrl(order *billingpb.Order, successUrl, outcome string) string {
+	if outcome != SimulatorOutcome3ds {
+		return successUrl
+	}
+
+	u, err := url.Parse(successUrl)
+
+	if err != nil {
+		return successUrl
+	}
+
+	query := u.Query()
+	query.Set(simulatorQueryParam3ds, order.Id)
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+func (h *simulator) send(order *billingpb.Order, callback *SimulatorCallback, outcome string) {
+	delay := h.settings.CallbackDelay
+
+	if outcome == SimulatorOutcomeDelayed {
+		delay = h.settings.DelayedCallbackDelay
+	}
+
+	secret := order.PaymentMethod.Params.SecretCallback
+	chargeback := &billingpb.CreateRefundRequest{
+		OrderId:      order.Uuid,
+		Amount:       order.ChargeAmount,
+		CreatorId:    primitive.NilObjectID.Hex(),
+		Reason:       SimulatorOutcomeChargeback,
+		IsChargeback: true,
+		MerchantId:   order.GetMerchantId(),
+	}
+
+	h.after(delay, func() {
+		switch callback.Type {
+		case simulatorCallbackTypePayment:
+			h.sendPaymentCallback(callback, secret, chargeback, 1)
+			break
+		case simulatorCallbackTypeRefund:
+			h.sendRefundCallback(callback, secret)
+			break
+		}
+	})
+}
+
+func (h *simulator) sendPaymentCallback(
+	callback *SimulatorCallback,
+	secret string,
+	chargeback *billingpb.CreateRefundRequest,
+	attempt int,
+) {
+	if attempt < simulatorOrderSavedAttempts && !h.isOrderSaved(callback.OrderId) {
+		h.after(h.settings.CallbackDelay, func() {
+			h.sendPaymentCallback(callback, secret, chargeback, attempt+1)
+		})
+		return
+	}
+
+	callback.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	b, _ := json.Marshal(callback)
+	req := &billingpb.PaymentNotifyRequest{
+		OrderId:   callback.OrderId,
+		Request:   b,
+		Signature: h.getSignature(b, secret),
+	}
+	rsp := &billingpb.PaymentNotifyResponse{}
+	err := h.processor.PaymentCallbackProcess(context.Background(), req, rsp)
+	outcome := h.popOutcome(callback.OrderId)
+
+	if err != nil || rsp.Status != billingpb.ResponseStatusOk {
+		zap.L().Error(
+			"simulator: payment callback processing failed",
+			zap.Error(err),
+			zap.Any(pkg.LogFieldRequest, callback),
+			zap.Any(pkg.LogFieldResponse, rsp),
+		)
+		return
+	}
+
+	if outcome != SimulatorOutcomeChargeback || callback.Status != simulatorStatusCompleted {
+		return
+	}
+
+	h.after(h.settings.CallbackDelay, func() {
+		h.sendChargeback(chargeback)
+	})
+}
+
+// Chargeback is registered in billing server as refund with chargeback flag, so simulator creates it through
+// the refund flow and the refund callback will be sent as for the usual refund.
+func (h *simulator) sendChargeback(req *billingpb.CreateRefundRequest) {
+	rsp := &billingpb.CreateRefundResponse{}
+	err := h.processor.CreateRefund(context.Background(), req, rsp)
+
+	if err != nil || rsp.Status != billingpb.ResponseStatusOk {
+		zap.L().Error(
+			"simulator: chargeback creation failed",
+			zap.Error(err),
+			zap.Any(pkg.LogFieldRequest, req),
+			zap.Any(pkg.LogFieldResponse, rsp),
+		)
+	}
+}
+
+// isOrderSaved checks the order is saved by the billing server after the payment creation. Callback processed before
+// it would be overwritten by the order with the payment system status.
+func (h *simulator) isOrderSaved(orderId string) bool {
+	status, err := h.processor.GetOrderPrivateStatus(context.Background(), orderId)
+
+	if err != nil {
+		zap.L().Error(
+			"simulator: order status checking failed",
+			zap.Error(err),
+			zap.String("order_id", orderId),
+		)
+		return false
+	}
+
+	return status != recurringpb.OrderStatusNew
+}
+
+func (h *simulator) sendRefundCallback(callback *SimulatorCallback, secret string) {
+	callback.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	b, _ := json.Marshal(callback)
+	req := &billingpb.CallbackRequest{
+		Handler:   pkg.PaymentSystemHandlerSimulator,
+		Body:      b,
+		Signature: h.getSignature(b, secret),
+	}
+	rsp := &billingpb.PaymentNotifyResponse{}
+	err := h.processor.ProcessRefundCallback(context.Background(), req, rsp)
+
+	if err != nil || rsp.Status != billingpb.ResponseStatusOk {
+		zap.L().Error(
+			"simulator: refund callback processing failed",
+			zap.Error(err),
+			zap.Any(pkg.LogFieldRequest, callback),
+			zap.Any(pkg.LogFieldResponse, rsp),
+		)
+	}
+}
+
+func (h *simulator) getSignature(raw []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(raw)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (h *simulator) checkCallbackRequestSignature(order *billingpb.Order, raw, signature string) error {
+	expected := h.getSignature([]byte(raw), order.PaymentMethod.Params.SecretCallback)
+
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		zap.L().Error(
+			"simulator: callback signature is invalid",
+			zap.Any(pkg.LogFieldOrder, order),
+		)
+		return errors2.NewBillingServerResponseError(pkg.StatusErrorValidation, paymentSystemErrorRequestSignatureIsInvalid)
+	}
+
+	return nil
+}
@@ -0,0 +1,319 @@
+package payment_system
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type simulatorProcessorMock struct {
+	handler     PaymentSystemInterface
+	order       *billingpb.Order
+	refund      *billingpb.Refund
+	paymentErr  error
+	refundErr   error
+	chargebacks []*billingpb.CreateRefundRequest
+	// statuses of the order returned before the order status
+	savedStatuses []int32
+}
+
+func (m *simulatorProcessorMock) PaymentCallbackProcess(
+	_ context.Context,
+	req *billingpb.PaymentNotifyRequest,
+	rsp *billingpb.PaymentNotifyResponse,
+) error {
+	data := &SimulatorCallback{}
+	_ = json.Unmarshal(req.Request, data)
+
+	m.paymentErr = m.handler.ProcessPayment(m.order, data, string(req.Request), req.Signature)
+	rsp.Status = billingpb.ResponseStatusOk
+
+	return nil
+}
+
+func (m *simulatorProcessorMock) ProcessRefundCallback(
+	_ context.Context,
+	req *billingpb.CallbackRequest,
+	rsp *billingpb.PaymentNotifyResponse,
+) error {
+	data := &SimulatorCallback{}
+	_ = json.Unmarshal(req.Body, data)
+
+	m.refundErr = m.handler.ProcessRefund(m.order, m.refund, data, string(req.Body), req.Signature)
+	rsp.Status = billingpb.ResponseStatusOk
+
+	return nil
+}
+
+func (m *simulatorProcessorMock) CreateRefund(
+	_ context.Context,
+	req *billingpb.CreateRefundRequest,
+	rsp *billingpb.CreateRefundResponse,
+) error {
+	m.chargebacks = append(m.chargebacks, req)
+	rsp.Status = billingpb.ResponseStatusOk
+
+	return nil
+}
+
+func (m *simulatorProcessorMock) GetOrderPrivateStatus(_ context.Context, _ string) (int32, error) {
+	if len(m.savedStatuses) > 0 {
+		status := m.savedStatuses[0]
+		m.savedStatuses = m.savedStatuses[1:]
+
+		return status, nil
+	}
+
+	return m.order.PrivateStatus, nil
+}
+
+type SimulatorTestSuite struct {
+	suite.Suite
+	handler   PaymentSystemInterface
+	processor *simulatorProcessorMock
+	delays    []time.Duration
+	order     *billingpb.Order
+}
+
+func Test_Simulator(t *testing.T) {
+	suite.Run(t, new(SimulatorTestSuite))
+}
+
+func (suite *SimulatorTestSuite) SetupTest() {
+	zap.ReplaceGlobals(zap.NewNop())
+
+	suite.order = &billingpb.Order{
+		Id:             primitive.NewObjectID().Hex(),
+		Uuid:           primitive.NewObjectID().Hex(),
+		PrivateStatus:  recurringpb.OrderStatusNew,
+		ChargeAmount:   10.25,
+		ChargeCurrency: "USD",
+		Project: &billingpb.ProjectOrder{
+			MerchantId: primitive.NewObjectID().Hex(),
+		},
+		PaymentMethod: &billingpb.PaymentMethodOrder{
+			Id:            primitive.NewObjectID().Hex(),
+			Handler:       pkg.PaymentSystemHandlerSimulator,
+			ExternalId:    recurringpb.PaymentSystemGroupAliasBankCard,
+			RefundAllowed: true,
+			Params: &billingpb.PaymentMethodParams{
+				Currency:       "USD",
+				SecretCallback: "simulator_secret",
+			},
+		},
+	}
+
+	suite.processor = &simulatorProcessorMock{order: suite.order}
+	suite.delays = nil
+	suite.handler = NewSimulatorHandler(
+		suite.processor,
+		&SimulatorSettings{
+			Outcome:              SimulatorOutcomeSuccess,
+			CallbackDelay:        time.Second,
+			DelayedCallbackDelay: time.Minute,
+		},
+	)
+	suite.processor.handler = suite.handler
+
+	suite.handler.(*simulator).after = func(d time.Duration, f func()) {
+		suite.delays = append(suite.delays, d)
+		f()
+	}
+}
+
+func (suite *SimulatorTestSuite) createPayment(pan string) string {
+	requisites := map[string]string{
+		billingpb.PaymentCreateFieldPan:    pan,
+		billingpb.PaymentCreateFieldHolder: "unit test",
+	}
+
+	url, err := suite.handler.CreatePayment(suite.order, "http://localhost/success", "http://localhost/fail", requisites)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.processor.paymentErr)
+
+	return url
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreatePayment_Success() {
+	url := suite.createPayment("4000000000000010")
+	assert.Equal(suite.T(), "http://localhost/success", url)
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemComplete), suite.order.PrivateStatus)
+	assert.Equal(suite.T(), "400000******0010", suite.order.PaymentMethodTxnParams[billingpb.PaymentCreateFieldPan])
+	assert.Equal(suite.T(), "UNIT TEST", suite.order.PaymentMethodTxnParams[billingpb.PaymentCreateFieldHolder])
+	assert.Equal(suite.T(), "0", suite.order.PaymentMethodTxnParams[billingpb.TxnParamsFieldBankCardIs3DS])
+	assert.NotEmpty(suite.T(), suite.order.Transaction)
+	assert.NotNil(suite.T(), suite.order.PaymentMethodOrderClosedAt)
+	assert.True(suite.T(), suite.order.IsRefundAllowed)
+	assert.Equal(suite.T(), []time.Duration{time.Second}, suite.delays)
+	assert.Empty(suite.T(), suite.processor.chargebacks)
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreatePayment_Decline() {
+	suite.createPayment("4000000000000002")
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemDeclined), suite.order.PrivateStatus)
+	assert.NotNil(suite.T(), suite.order.Cancellation)
+	assert.Equal(suite.T(), simulatorDeclineCode, suite.order.Cancellation.Code)
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreatePayment_3ds() {
+	url := suite.createPayment("4000000000003063")
+	assert.Contains(suite.T(), url, simulatorQueryParam3ds+"="+suite.order.Id)
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemComplete), suite.order.PrivateStatus)
+	assert.Equal(suite.T(), "1", suite.order.PaymentMethodTxnParams[billingpb.TxnParamsFieldBankCardIs3DS])
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreatePayment_Delayed() {
+	suite.createPayment("4000000000000077")
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemComplete), suite.order.PrivateStatus)
+	assert.Equal(suite.T(), []time.Duration{time.Minute}, suite.delays)
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreatePayment_ZeroDelay() {
+	handler := NewSimulatorHandler(suite.processor, &SimulatorSettings{Outcome: SimulatorOutcomeSuccess})
+	handler.(*simulator).after = suite.handler.(*simulator).after
+	suite.handler = handler
+	suite.processor.handler = handler
+
+	suite.createPayment("4000000000000010")
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemComplete), suite.order.PrivateStatus)
+	assert.Equal(suite.T(), []time.Duration{simulatorMinCallbackDelay}, suite.delays)
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreatePayment_OrderNotSaved() {
+	suite.processor.savedStatuses = []int32{recurringpb.OrderStatusNew, recurringpb.OrderStatusNew}
+
+	suite.createPayment("4000000000000010")
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemComplete), suite.order.PrivateStatus)
+	assert.Equal(suite.T(), []time.Duration{time.Second, time.Second, time.Second}, suite.delays)
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreatePayment_Chargeback() {
+	suite.createPayment("4000000000000259")
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemComplete), suite.order.PrivateStatus)
+	assert.Len(suite.T(), suite.processor.chargebacks, 1)
+	assert.True(suite.T(), suite.processor.chargebacks[0].IsChargeback)
+	assert.Equal(suite.T(), suite.order.Uuid, suite.processor.chargebacks[0].OrderId)
+	assert.Equal(suite.T(), suite.order.GetMerchantId(), suite.processor.chargebacks[0].MerchantId)
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreatePayment_DefaultOutcome() {
+	suite.handler.(*simulator).settings.Outcome = SimulatorOutcomeDecline
+	suite.createPayment("4000000000000010")
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemDeclined), suite.order.PrivateStatus)
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreatePayment_StoreCard() {
+	requisites := map[string]string{
+		billingpb.PaymentCreateFieldPan:       "4000000000000010",
+		billingpb.PaymentCreateFieldStoreData: "1",
+	}
+	callback := suite.handler.(*simulator).getPaymentCallback(suite.order, SimulatorOutcomeSuccess, requisites)
+	assert.True(suite.T(), suite.handler.IsRecurringCallback(callback))
+	assert.True(suite.T(), suite.handler.CanSaveCard(callback))
+	assert.NotEmpty(suite.T(), suite.handler.GetRecurringId(callback))
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_ProcessPayment_InvalidSignature() {
+	callback := suite.handler.(*simulator).getPaymentCallback(suite.order, SimulatorOutcomeSuccess, map[string]string{})
+	callback.CreatedAt = time.Now().Format(time.RFC3339)
+	b, _ := json.Marshal(callback)
+
+	err := suite.handler.ProcessPayment(suite.order, callback, string(b), "invalid")
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), paymentSystemErrorRequestSignatureIsInvalid, err.(*billingpb.ResponseError).Message)
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemReject), suite.order.PrivateStatus)
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreateRefund() {
+	suite.processor.refund = &billingpb.Refund{
+		Id:       primitive.NewObjectID().Hex(),
+		Amount:   5,
+		Currency: "USD",
+		Reason:   "unit test",
+	}
+
+	err := suite.handler.CreateRefund(suite.order, suite.processor.refund)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.processor.refundErr)
+	assert.Equal(suite.T(), pkg.RefundStatusCompleted, suite.processor.refund.Status)
+	assert.NotEmpty(suite.T(), suite.processor.refund.ExternalId)
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreateRefund_Decline() {
+	suite.processor.refund = &billingpb.Refund{
+		Id:       primitive.NewObjectID().Hex(),
+		Amount:   5,
+		Currency: "USD",
+		Reason:   SimulatorRefundReasonPrefix + SimulatorOutcomeDecline,
+	}
+
+	err := suite.handler.CreateRefund(suite.order, suite.processor.refund)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.processor.refundErr)
+	assert.Equal(suite.T(), pkg.RefundStatusPaymentSystemDeclined, suite.processor.refund.Status)
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreateRecurringSubscription() {
+	subscription := &recurringpb.Subscription{Id: primitive.NewObjectID().Hex()}
+	url, err := suite.handler.CreateRecurringSubscription(
+		suite.order,
+		subscription,
+		"http://localhost/success",
+		"http://localhost/fail",
+		map[string]string{billingpb.PaymentCreateFieldPan: "4000000000000010"},
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "http://localhost/success", url)
+	assert.NotEmpty(suite.T(), subscription.CardpaySubscriptionId)
+	assert.NoError(suite.T(), suite.processor.paymentErr)
+	assert.Equal(suite.T(), subscription.CardpaySubscriptionId, suite.order.Transaction)
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreateAuthorization() {
+	url, err := suite.handler.CreateAuthorization(
+		suite.order,
+		"http://localhost/success",
+		"http://localhost/fail",
+		map[string]string{billingpb.PaymentCreateFieldPan: "4000000000000010"},
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "http://localhost/success", url)
+	assert.NoError(suite.T(), suite.processor.paymentErr)
+	assert.Equal(suite.T(), pkg.OrderStatusPaymentSystemAuthorized, suite.order.PrivateStatus)
+	assert.NotEmpty(suite.T(), suite.order.Transaction)
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CreateAuthorization_Decline() {
+	_, err := suite.handler.CreateAuthorization(
+		suite.order,
+		"http://localhost/success",
+		"http://localhost/fail",
+		map[string]string{billingpb.PaymentCreateFieldPan: "4000000000000002"},
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemDeclined), suite.order.PrivateStatus)
+}
+
+func (suite *SimulatorTestSuite) TestSimulator_CaptureAndVoid() {
+	suite.order.PrivateStatus = pkg.OrderStatusPaymentSystemAuthorized
+
+	err := suite.handler.Capture(suite.order, 5)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemComplete), suite.order.PrivateStatus)
+	assert.True(suite.T(), suite.order.IsRefundAllowed)
+
+	suite.order.PrivateStatus = pkg.OrderStatusPaymentSystemAuthorized
+
+	err = suite.handler.Void(suite.order)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.OrderStatusPaymentSystemVoided, suite.order.PrivateStatus)
+	assert.NotNil(suite.T(), suite.order.CanceledAt)
+}
@@ -114,3 +114,572 @@ type BalanceQueryResult struct {
 	CorrectionAmount     float64 `bson:"correction_amount"`
 	RollingReserveAmount float64 `bson:"rolling_reserve_total_amount"`
 }
+
+// AccountingExportQueryResItem is the sum of accounting entries of the same type, merchant, country, operating
+// company and currencies.
+type AccountingExportQueryResItem struct {
+	Type               string             `bson:"type"`
+	MerchantId         primitive.ObjectID `bson:"merchant_id"`
+	Country            string             `bson:"country"`
+	OperatingCompanyId string             `bson:"operating_company_id"`
+	Currency           string             `bson:"currency"`
+	Amount             float64            `bson:"amount"`
+	OriginalCurrency   string             `bson:"original_currency"`
+	OriginalAmount     float64            `bson:"original_amount"`
+	LocalCurrency      string             `bson:"local_currency"`
+	LocalAmount        float64            `bson:"local_amount"`
+	Count              int64              `bson:"count"`
+}
+
+// PaymentMethodRoute describes which payment systems process payments by the payment method for the country.
+// The first payment system in the list is primary, the rest are used as fallbacks in the specified order.
+// Route with empty country is used for all countries which have no own route.
+type PaymentMethodRoute struct {
+	Id               primitive.ObjectID   `bson:"_id"`
+	PaymentMethodId  primitive.ObjectID   `bson:"payment_method_id"`
+	Country          string               `bson:"country"`
+	PaymentSystemIds []primitive.ObjectID `bson:"payment_system_ids"`
+	CreatedAt        time.Time            `bson:"created_at"`
+	UpdatedAt        time.Time            `bson:"updated_at"`
+}
+
+// OrderHistory is a record about the change of the order made by the billing server automatically.
+type OrderHistory struct {
+	Id        primitive.ObjectID `bson:"_id"`
+	OrderId   primitive.ObjectID `bson:"order_id"`
+	Type      string             `bson:"type"`
+	Data      map[string]string  `bson:"data"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// IdempotencyKey is the stored result of the request made with the idempotency key. Retries of the request with
+// the same key get the stored response instead of processing the request again.
+type IdempotencyKey struct {
+	Id          primitive.ObjectID `bson:"_id"`
+	ProjectId   string             `bson:"project_id"`
+	Operation   string             `bson:"operation"`
+	Key         string             `bson:"key"`
+	RequestHash string             `bson:"request_hash"`
+	Response    []byte             `bson:"response"`
+	CreatedAt   time.Time          `bson:"created_at"`
+	ExpireAt    time.Time          `bson:"expire_at"`
+}
+
+// Dispute is the chargeback dispute of the customer payment opened by the issuing bank. Merchant can defend
+// the payment by the representment with evidence documents before the evidence due date. Dispute is closed with
+// the outcome of the issuing bank: the won dispute doesn't change the payment, the lost dispute is a chargeback.
+type Dispute struct {
+	Id                       primitive.ObjectID `bson:"_id"`
+	OrderId                  primitive.ObjectID `bson:"order_id"`
+	OrderUuid                string             `bson:"order_uuid"`
+	MerchantId               primitive.ObjectID `bson:"merchant_id"`
+	ProjectId                primitive.ObjectID `bson:"project_id"`
+	Status                   string             `bson:"status"`
+	IsOpen                   bool               `bson:"is_open"`
+	ReasonCode               string             `bson:"reason_code"`
+	Reason                   string             `bson:"reason"`
+	Amount                   float64            `bson:"amount"`
+	Currency                 string             `bson:"currency"`
+	EvidenceDueAt            time.Time          `bson:"evidence_due_at"`
+	Evidence                 []*DisputeEvidence `bson:"evidence"`
+	RepresentmentSubmittedAt time.Time          `bson:"representment_submitted_at"`
+	ChargebackId             string             `bson:"chargeback_id"`
+	CreatedAt                time.Time          `bson:"created_at"`
+	UpdatedAt                time.Time          `bson:"updated_at"`
+	ClosedAt                 time.Time          `bson:"closed_at"`
+}
+
+// DisputeEvidence is the document which merchant provides to defend the payment in the dispute.
+type DisputeEvidence struct {
+	Id          primitive.ObjectID `bson:"_id"`
+	Name        string             `bson:"name"`
+	Url         string             `bson:"url"`
+	Description string             `bson:"description"`
+	CreatedAt   time.Time          `bson:"created_at"`
+}
+
+// ProjectCaptureSettings is the capture mode of payments of the project orders. Payments of orders of the project
+// with the manual capture are only authorized by payment system and captured later by the separate request.
+type ProjectCaptureSettings struct {
+	Id            primitive.ObjectID `bson:"_id"`
+	ProjectId     primitive.ObjectID `bson:"project_id"`
+	ManualCapture bool               `bson:"manual_capture"`
+	CreatedAt     time.Time          `bson:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at"`
+}
+
+// PaymentMethodRule enables or disables the payment method of the project for orders matched by the country,
+// currency, amount range and platform. Empty condition of the rule matches any order.
+type PaymentMethodRule struct {
+	Id              primitive.ObjectID `bson:"_id"`
+	MerchantId      primitive.ObjectID `bson:"merchant_id"`
+	ProjectId       primitive.ObjectID `bson:"project_id"`
+	PaymentMethodId primitive.ObjectID `bson:"payment_method_id"`
+	Action          string             `bson:"action"`
+	Countries       []string           `bson:"countries"`
+	Currencies      []string           `bson:"currencies"`
+	MinAmount       float64            `bson:"min_amount"`
+	MaxAmount       float64            `bson:"max_amount"`
+	Platforms       []string           `bson:"platforms"`
+	CreatedAt       time.Time          `bson:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at"`
+}
+
+// PromoCode is the discount code of the project which customer applies on the payment form. The discount is
+// the percent of the order amount or the fixed amount in the order currency. Code with product restrictions
+// discounts only the restricted products of the order. Orders which payments used the promo code with the usage
+// limit are reserved by the conditional update, so the number of them can't exceed the limit.
+type PromoCode struct {
+	Id                 primitive.ObjectID `bson:"_id"`
+	MerchantId         primitive.ObjectID `bson:"merchant_id"`
+	ProjectId          primitive.ObjectID `bson:"project_id"`
+	Code               string             `bson:"code"`
+	Type               string             `bson:"type"`
+	Percent            float64            `bson:"percent"`
+	Amounts            []*PromoCodeAmount `bson:"amounts"`
+	ProductIds         []string           `bson:"product_ids"`
+	StartsAt           time.Time          `bson:"starts_at"`
+	ExpiresAt          time.Time          `bson:"expires_at"`
+	MaxUses            int64              `bson:"max_uses"`
+	MaxUsesPerCustomer int64              `bson:"max_uses_per_customer"`
+	ReservedOrderIds   []string           `bson:"reserved_order_ids"`
+	Reservations       []*PromoCodeUse    `bson:"reservations"`
+	IsActive           bool               `bson:"is_active"`
+	CreatedAt          time.Time          `bson:"created_at"`
+	UpdatedAt          time.Time          `bson:"updated_at"`
+}
+
+// PromoCodeUse is the use of the promo code reserved by the order of the customer.
+type PromoCodeUse struct {
+	OrderId    string `bson:"order_id"`
+	CustomerId string `bson:"customer_id"`
+}
+
+// PromoCodeAmount is the fixed discount amount of the promo code in the currency.
+type PromoCodeAmount struct {
+	Currency string  `bson:"currency"`
+	Amount   float64 `bson:"amount"`
+}
+
+// SubscriptionTrial is the trial period of the recurring subscription. Trial payment is charged with the trial price
+// and the regular payments of the subscription plan start at the trial end, when the trial is converted to the paid plan.
+// Free trial is charged with the minimal price to verify the card, the charge is refunded when the trial is closed.
+type SubscriptionTrial struct {
+	Id             primitive.ObjectID `bson:"_id"`
+	SubscriptionId string             `bson:"subscription_id"`
+	OrderId        primitive.ObjectID `bson:"order_id"`
+	MerchantId     primitive.ObjectID `bson:"merchant_id"`
+	ProjectId      primitive.ObjectID `bson:"project_id"`
+	CustomerId     string             `bson:"customer_id"`
+	Status         string             `bson:"status"`
+	TrialDays      int32              `bson:"trial_days"`
+	TrialPrice     float64            `bson:"trial_price"`
+	IsFree         bool               `bson:"is_free"`
+	RefundId       string             `bson:"refund_id"`
+	PlanAmount     float64            `bson:"plan_amount"`
+	Currency       string             `bson:"currency"`
+	TrialEndsAt    time.Time          `bson:"trial_ends_at"`
+	ConvertedAt    time.Time          `bson:"converted_at"`
+	CanceledAt     time.Time          `bson:"canceled_at"`
+	CreatedAt      time.Time          `bson:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at"`
+}
+
+// SubscriptionPlanChange is the change of the amount or the period of the recurring subscription. The rest of
+// the current period is prorated: the positive proration is charged by the new order and the negative one
+// is refunded from the order of the current period. Change with the proration charge is pending until the payment
+// system callback of the order.
+type SubscriptionPlanChange struct {
+	Id              primitive.ObjectID `bson:"_id"`
+	SubscriptionId  string             `bson:"subscription_id"`
+	MerchantId      primitive.ObjectID `bson:"merchant_id"`
+	ProjectId       primitive.ObjectID `bson:"project_id"`
+	CustomerId      string             `bson:"customer_id"`
+	Status          string             `bson:"status"`
+	PreviousAmount  float64            `bson:"previous_amount"`
+	PreviousPeriod  string             `bson:"previous_period"`
+	Amount          float64            `bson:"amount"`
+	Period          string             `bson:"period"`
+	Currency        string             `bson:"currency"`
+	ProrationAmount float64            `bson:"proration_amount"`
+	PeriodOrderId   primitive.ObjectID `bson:"period_order_id"`
+	OrderId         string             `bson:"order_id"`
+	RefundId        string             `bson:"refund_id"`
+	PeriodEndsAt    time.Time          `bson:"period_ends_at"`
+	CreatedAt       time.Time          `bson:"created_at"`
+	UpdatedAt       time.Time          `bson:"updated_at"`
+}
+
+// SubscriptionPause is the pause of regular payments of the recurring subscription for a number of periods or until
+// the date. Paid time left in the current period at the pause is moved after the resume, so the next billing date
+// is recalculated and the subscription end date is extended by the paused time.
+type SubscriptionPause struct {
+	Id             primitive.ObjectID `bson:"_id"`
+	SubscriptionId string             `bson:"subscription_id"`
+	MerchantId     primitive.ObjectID `bson:"merchant_id"`
+	ProjectId      primitive.ObjectID `bson:"project_id"`
+	CustomerId     string             `bson:"customer_id"`
+	Status         string             `bson:"status"`
+	Periods        int32              `bson:"periods"`
+	PeriodEndsAt   time.Time          `bson:"period_ends_at"`
+	PausedAt       time.Time          `bson:"paused_at"`
+	ResumeAt       time.Time          `bson:"resume_at"`
+	ResumedAt      time.Time          `bson:"resumed_at"`
+	NextBillingAt  time.Time          `bson:"next_billing_at"`
+	CreatedAt      time.Time          `bson:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at"`
+}
+
+// SavedCardUpdate is the request to the customer to replace the saved card which expires. The card is replaced by
+// the zero-amount verification of the new card by the secure link with the token, the new card is filed for
+// the active subscriptions of the customer instead of the expiring one. Subscriptions with the card filing failed
+// keep the expiring card.
+type SavedCardUpdate struct {
+	Id                    primitive.ObjectID `bson:"_id"`
+	Token                 string             `bson:"token"`
+	SavedCardId           string             `bson:"saved_card_id"`
+	CustomerId            string             `bson:"customer_id"`
+	MerchantId            primitive.ObjectID `bson:"merchant_id"`
+	ProjectId             primitive.ObjectID `bson:"project_id"`
+	SubscriptionId        string             `bson:"subscription_id"`
+	CustomerEmail         string             `bson:"customer_email"`
+	MaskedPan             string             `bson:"masked_pan"`
+	ExpireMonth           string             `bson:"expire_month"`
+	ExpireYear            string             `bson:"expire_year"`
+	Status                string             `bson:"status"`
+	OrderId               string             `bson:"order_id"`
+	RecurringId           string             `bson:"recurring_id"`
+	FailedSubscriptionIds []string           `bson:"failed_subscription_ids"`
+	ExpiresAt             time.Time          `bson:"expires_at"`
+	CompletedAt           time.Time          `bson:"completed_at"`
+	CreatedAt             time.Time          `bson:"created_at"`
+	UpdatedAt             time.Time          `bson:"updated_at"`
+}
+
+// RefundItem is the order line item returned to the customer by the refund. The refund amount of the item is the share
+// of the order charge amount which falls on the item price, so the tax and the discount are returned proportionally.
+type RefundItem struct {
+	Id           primitive.ObjectID `bson:"_id"`
+	RefundId     primitive.ObjectID `bson:"refund_id"`
+	OrderId      primitive.ObjectID `bson:"order_id"`
+	ItemId       string             `bson:"item_id"`
+	Line         int32              `bson:"line"`
+	Sku          string             `bson:"sku"`
+	Name         string             `bson:"name"`
+	Amount       float64            `bson:"amount"`
+	Currency     string             `bson:"currency"`
+	RefundAmount float64            `bson:"refund_amount"`
+	KeyId        string             `bson:"key_id"`
+	CreatedAt    time.Time          `bson:"created_at"`
+}
+
+// RefundApprovalSettings are the thresholds of the merchant above which refunds wait for the approval by the merchant
+// user with the approver role before they are sent to the payment system. Zero threshold is disabled.
+type RefundApprovalSettings struct {
+	Id           primitive.ObjectID `bson:"_id"`
+	MerchantId   primitive.ObjectID `bson:"merchant_id"`
+	Amount       float64            `bson:"amount"`
+	Currency     string             `bson:"currency"`
+	Percent      float64            `bson:"percent"`
+	ApproverRole string             `bson:"approver_role"`
+	CreatedAt    time.Time          `bson:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at"`
+}
+
+// RefundApproval is the decision of the merchant user about the refund which exceeded the approval thresholds.
+// The refund is sent to the payment system when it's approved and rejected otherwise.
+type RefundApproval struct {
+	Id           primitive.ObjectID `bson:"_id"`
+	RefundId     primitive.ObjectID `bson:"refund_id"`
+	OrderId      primitive.ObjectID `bson:"order_id"`
+	MerchantId   primitive.ObjectID `bson:"merchant_id"`
+	CreatorId    string             `bson:"creator_id"`
+	Amount       float64            `bson:"amount"`
+	Currency     string             `bson:"currency"`
+	ApproverRole string             `bson:"approver_role"`
+	Status       string             `bson:"status"`
+	ReviewerId   string             `bson:"reviewer_id"`
+	Comment      string             `bson:"comment"`
+	ReviewedAt   time.Time          `bson:"reviewed_at"`
+	CreatedAt    time.Time          `bson:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at"`
+}
+
+// RefundFallback is the refund declined by the payment system which is returned to the customer by other way.
+// The customer chooses the bank transfer or the store credit by the secure link with the token, the bank transfer is
+// completed by the finance team after the money is sent.
+type RefundFallback struct {
+	Id                primitive.ObjectID `bson:"_id"`
+	Token             string             `bson:"token"`
+	RefundId          primitive.ObjectID `bson:"refund_id"`
+	OrderId           primitive.ObjectID `bson:"order_id"`
+	MerchantId        primitive.ObjectID `bson:"merchant_id"`
+	ProjectId         primitive.ObjectID `bson:"project_id"`
+	CustomerId        string             `bson:"customer_id"`
+	CustomerEmail     string             `bson:"customer_email"`
+	Amount            float64            `bson:"amount"`
+	Currency          string             `bson:"currency"`
+	Destination       string             `bson:"destination"`
+	BankAccount       *RefundBankAccount `bson:"bank_account"`
+	Status            string             `bson:"status"`
+	TransferReference string             `bson:"transfer_reference"`
+	CompletedAt       time.Time          `bson:"completed_at"`
+	CreatedAt         time.Time          `bson:"created_at"`
+	UpdatedAt         time.Time          `bson:"updated_at"`
+}
+
+// RefundBankAccount is the bank account of the customer for the bank transfer of the declined refund.
+type RefundBankAccount struct {
+	Holder   string `bson:"holder"`
+	Iban     string `bson:"iban"`
+	Swift    string `bson:"swift"`
+	BankName string `bson:"bank_name"`
+	Country  string `bson:"country"`
+}
+
+// CustomerWallet is the balance of the customer on the project in the currency. Wallet is topped up by refunds,
+// goodwill credits of the merchant and gift cards and is spent to pay orders of the project in the same currency.
+type CustomerWallet struct {
+	Id         primitive.ObjectID `bson:"_id"`
+	CustomerId string             `bson:"customer_id"`
+	MerchantId primitive.ObjectID `bson:"merchant_id"`
+	ProjectId  primitive.ObjectID `bson:"project_id"`
+	Currency   string             `bson:"currency"`
+	Balance    float64            `bson:"balance"`
+	// CreditIds are identifiers of the store credits added to the wallet only once, so repeated top up is skipped.
+	CreditIds []primitive.ObjectID `bson:"credit_ids,omitempty"`
+	CreatedAt time.Time            `bson:"created_at"`
+	UpdatedAt time.Time            `bson:"updated_at"`
+}
+
+// StoreCredit is the movement of the money of the customer wallet. Positive amount tops up the wallet, negative
+// amount is spent from it. The source is the reason of the movement, for example the declined refund or the payment.
+type StoreCredit struct {
+	Id         primitive.ObjectID `bson:"_id"`
+	WalletId   primitive.ObjectID `bson:"wallet_id"`
+	CustomerId string             `bson:"customer_id"`
+	MerchantId primitive.ObjectID `bson:"merchant_id"`
+	ProjectId  primitive.ObjectID `bson:"project_id"`
+	Amount     float64            `bson:"amount"`
+	Currency   string             `bson:"currency"`
+	Source     string             `bson:"source"`
+	SourceId   string             `bson:"source_id"`
+	Reason     string             `bson:"reason"`
+	CreatorId  string             `bson:"creator_id"`
+	CreatedAt  time.Time          `bson:"created_at"`
+}
+
+// GiftCard is the code with the monetary value generated by the platform and sold as the key of the key product.
+// Balance of the card is spent by redemption to the customer wallet or by payments of orders, the rest of
+// the balance of the expired card is the breakage of the merchant.
+type GiftCard struct {
+	Id             primitive.ObjectID `bson:"_id"`
+	Code           string             `bson:"code"`
+	KeyId          string             `bson:"key_id"`
+	KeyProductId   string             `bson:"key_product_id"`
+	MerchantId     primitive.ObjectID `bson:"merchant_id"`
+	ProjectId      primitive.ObjectID `bson:"project_id"`
+	Value          float64            `bson:"value"`
+	Balance        float64            `bson:"balance"`
+	Currency       string             `bson:"currency"`
+	ValidityDays   int32              `bson:"validity_days"`
+	Status         string             `bson:"status"`
+	OrderId        string             `bson:"order_id"`
+	BuyerId        string             `bson:"buyer_id"`
+	BreakageAmount float64            `bson:"breakage_amount"`
+	ActivatedAt    time.Time          `bson:"activated_at"`
+	ExpiresAt      time.Time          `bson:"expires_at"`
+	CreatedAt      time.Time          `bson:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at"`
+}
+
+// JournalEntry is the compound double-entry posting of all accounting entries of one payment, refund or manual
+// correction. Sum of debits of the lines is equal to sum of credits in each currency of the lines.
+type JournalEntry struct {
+	Id                 primitive.ObjectID `bson:"_id"`
+	OperatingCompanyId string             `bson:"operating_company_id"`
+	MerchantId         string             `bson:"merchant_id"`
+	SourceId           string             `bson:"source_id"`
+	SourceType         string             `bson:"source_type"`
+	EventType          string             `bson:"event_type"`
+	Lines              []*JournalLine     `bson:"lines"`
+	CreatedAt          time.Time          `bson:"created_at"`
+}
+
+// JournalLine is the debit or the credit of the ledger account by the accounting entry.
+type JournalLine struct {
+	Account             string  `bson:"account"`
+	Debit               float64 `bson:"debit"`
+	Credit              float64 `bson:"credit"`
+	Currency            string  `bson:"currency"`
+	AccountingEntryId   string  `bson:"accounting_entry_id"`
+	AccountingEntryType string  `bson:"accounting_entry_type"`
+}
+
+// TrialBalanceItem is the sum of debits and credits of the ledger account.
+type TrialBalanceItem struct {
+	Account string  `bson:"_id"`
+	Debit   float64 `bson:"debit"`
+	Credit  float64 `bson:"credit"`
+}
+
+// ReconciliationRun is the check of the settlement report file of the payment system against orders, refunds
+// and accounting entries of the billing server for the period of the report.
+type ReconciliationRun struct {
+	Id                  primitive.ObjectID          `bson:"_id"`
+	PaymentSystem       string                      `bson:"payment_system"`
+	ReportId            string                      `bson:"report_id"`
+	FileName            string                      `bson:"file_name"`
+	Status              string                      `bson:"status"`
+	PeriodFrom          time.Time                   `bson:"period_from"`
+	PeriodTo            time.Time                   `bson:"period_to"`
+	LinesCount          int32                       `bson:"lines_count"`
+	MatchedCount        int32                       `bson:"matched_count"`
+	MissingCount        int32                       `bson:"missing_count"`
+	ExtraCount          int32                       `bson:"extra_count"`
+	AmountMismatchCount int32                       `bson:"amount_mismatch_count"`
+	FeeMismatchCount    int32                       `bson:"fee_mismatch_count"`
+	UnresolvedCount     int32                       `bson:"unresolved_count"`
+	DailyTotals         []*ReconciliationDailyTotal `bson:"daily_totals"`
+	CreatorId           string                      `bson:"creator_id"`
+	CreatedAt           time.Time                   `bson:"created_at"`
+	UpdatedAt           time.Time                   `bson:"updated_at"`
+}
+
+// ReconciliationDailyTotal is the sum of unmatched items of the reconciliation run by the day and the currency.
+type ReconciliationDailyTotal struct {
+	Date             string  `bson:"date"`
+	Currency         string  `bson:"currency"`
+	MissingAmount    float64 `bson:"missing_amount"`
+	ExtraAmount      float64 `bson:"extra_amount"`
+	AmountDifference float64 `bson:"amount_difference"`
+	FeeDifference    float64 `bson:"fee_difference"`
+}
+
+// ReconciliationItem is the transaction of the settlement report matched to the order or the refund, or
+// the transaction found only on one side. Unmatched items are resolved by the finance team.
+type ReconciliationItem struct {
+	Id               primitive.ObjectID `bson:"_id"`
+	RunId            primitive.ObjectID `bson:"run_id"`
+	Status           string             `bson:"status"`
+	Type             string             `bson:"type"`
+	TransactionId    string             `bson:"transaction_id"`
+	OrderId          string             `bson:"order_id"`
+	RefundId         string             `bson:"refund_id"`
+	Line             int32              `bson:"line"`
+	Date             time.Time          `bson:"date"`
+	Amount           float64            `bson:"amount"`
+	Fee              float64            `bson:"fee"`
+	Currency         string             `bson:"currency"`
+	ExpectedAmount   float64            `bson:"expected_amount"`
+	ExpectedFee      float64            `bson:"expected_fee"`
+	ExpectedCurrency string             `bson:"expected_currency"`
+	IsResolved       bool               `bson:"is_resolved"`
+	Resolution       string             `bson:"resolution"`
+	ResolvedBy       string             `bson:"resolved_by"`
+	ResolvedAt       time.Time          `bson:"resolved_at"`
+	CreatedAt        time.Time          `bson:"created_at"`
+}
+
+// AuditLogEntry is the record of the financial or administrative change made by the RPC. Entries are chained by
+// hashes: hash of the entry is calculated with the hash of the previous entry, so change or removal of any entry
+// breaks the chain. The entry is added as pending with zero sequence number and is chained after that, so writes
+// of business operations don't wait for each other on the chain.
+type AuditLogEntry struct {
+	Id           primitive.ObjectID `bson:"_id"`
+	Sequence     int64              `bson:"sequence"`
+	ActorId      string             `bson:"actor_id"`
+	Ip           string             `bson:"ip"`
+	Rpc          string             `bson:"rpc"`
+	EntityType   string             `bson:"entity_type"`
+	EntityId     string             `bson:"entity_id"`
+	Changes      []*AuditLogChange  `bson:"changes"`
+	PreviousHash string             `bson:"previous_hash"`
+	Hash         string             `bson:"hash"`
+	CreatedAt    time.Time          `bson:"created_at"`
+}
+
+// AuditLogChange is the changed field of the entity. Nested fields are named by the path separated by dots,
+// values are JSON representations of the field before and after the change.
+type AuditLogChange struct {
+	Field  string `bson:"field"`
+	Before string `bson:"before"`
+	After  string `bson:"after"`
+}
+
+// MerchantBalanceConversion is the explicit exchange of the amount between balances of the merchant in different
+// currencies by the rate of the currencies service. Each side of the conversion is included to the payout document
+// of its currency. Conversions from the currency are numbered in sequence, the unique index of the sequence rejects
+// concurrent conversions checked against the same balance.
+type MerchantBalanceConversion struct {
+	Id                   primitive.ObjectID `bson:"_id"`
+	MerchantId           primitive.ObjectID `bson:"merchant_id"`
+	FromCurrency         string             `bson:"from_currency"`
+	FromAmount           float64            `bson:"from_amount"`
+	FromSequence         int64              `bson:"from_sequence"`
+	ToCurrency           string             `bson:"to_currency"`
+	ToAmount             float64            `bson:"to_amount"`
+	Rate                 float64            `bson:"rate"`
+	RateType             string             `bson:"rate_type"`
+	FromPayoutDocumentId string             `bson:"from_payout_document_id"`
+	ToPayoutDocumentId   string             `bson:"to_payout_document_id"`
+	UserId               string             `bson:"user_id"`
+	CreatedAt            time.Time          `bson:"created_at"`
+}
+
+// DunningSchedule is the project schedule of retries of failed recurring payments. Retry days are counted since
+// the payment failure, unpaid days are counted since the last failed retry.
+type DunningSchedule struct {
+	Id         primitive.ObjectID `bson:"_id"`
+	MerchantId primitive.ObjectID `bson:"merchant_id"`
+	ProjectId  primitive.ObjectID `bson:"project_id"`
+	RetryDays  []int32            `bson:"retry_days"`
+	UnpaidDays int32              `bson:"unpaid_days"`
+	CreatedAt  time.Time          `bson:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at"`
+}
+
+// SubscriptionDunning is the recovery of the failed payment of the recurring subscription. The schedule of the project
+// is copied to the dunning when the payment fails, so the schedule changes don't affect dunning in progress.
+// Retry order is the order of the retry waiting for the payment system callback.
+type SubscriptionDunning struct {
+	Id             primitive.ObjectID `bson:"_id"`
+	SubscriptionId string             `bson:"subscription_id"`
+	OrderId        primitive.ObjectID `bson:"order_id"`
+	MerchantId     primitive.ObjectID `bson:"merchant_id"`
+	ProjectId      primitive.ObjectID `bson:"project_id"`
+	CustomerId     string             `bson:"customer_id"`
+	CustomerEmail  string             `bson:"customer_email"`
+	Status         string             `bson:"status"`
+	Attempts       int32              `bson:"attempts"`
+	RetryDays      []int32            `bson:"retry_days"`
+	UnpaidDays     int32              `bson:"unpaid_days"`
+	Amount         float64            `bson:"amount"`
+	Currency       string             `bson:"currency"`
+	RetryOrderId   string             `bson:"retry_order_id"`
+	NextAttemptAt  time.Time          `bson:"next_attempt_at"`
+	CreatedAt      time.Time          `bson:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at"`
+	ClosedAt       time.Time          `bson:"closed_at"`
+}
+
+// OrderReview is the manual review of the order payment held by the fraud screening. Reviewer approves or rejects
+// the payment before the expiration date, otherwise the order is canceled.
+type OrderReview struct {
+	Id         primitive.ObjectID `bson:"_id"`
+	OrderId    primitive.ObjectID `bson:"order_id"`
+	OrderUuid  string             `bson:"order_uuid"`
+	MerchantId primitive.ObjectID `bson:"merchant_id"`
+	ProjectId  primitive.ObjectID `bson:"project_id"`
+	Status     string             `bson:"status"`
+	FraudScore int                `bson:"fraud_score"`
+	FraudRules []string           `bson:"fraud_rules"`
+	Amount     float64            `bson:"amount"`
+	Currency   string             `bson:"currency"`
+	ReviewerId string             `bson:"reviewer_id"`
+	Reason     string             `bson:"reason"`
+	ExpiresAt  time.Time          `bson:"expires_at"`
+	CreatedAt  time.Time          `bson:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at"`
+	ResolvedAt time.Time          `bson:"resolved_at"`
+}
@@ -631,6 +631,111 @@ func (r *accountingEntryRepository) GetRollingReserveForBalance(
 	return items, nil
 }
 
+func (r *accountingEntryRepository) GetExportSummary(
+	ctx context.Context,
+	operatingCompanyId, merchantId string,
+	from, to time.Time,
+) ([]*pkg2.AccountingExportQueryResItem, error) {
+	match := bson.M{
+		"created_at": bson.M{"$gte": from, "$lt": to},
+	}
+
+	if operatingCompanyId != "" {
+		match["operating_company_id"] = operatingCompanyId
+	}
+
+	if merchantId != "" {
+		merchantOid, err := primitive.ObjectIDFromHex(merchantId)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseInvalidObjectId,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+				zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+			)
+			return nil, err
+		}
+
+		match["merchant_id"] = merchantOid
+	}
+
+	query := []bson.M{
+		{"$match": match},
+		{
+			"$group": bson.M{
+				"_id": bson.M{
+					"type":                 "$type",
+					"merchant_id":          "$merchant_id",
+					"country":              "$country",
+					"operating_company_id": "$operating_company_id",
+					"currency":             "$currency",
+					"original_currency":    "$original_currency",
+					"local_currency":       "$local_currency",
+				},
+				"amount":          bson.M{"$sum": "$amount"},
+				"original_amount": bson.M{"$sum": "$original_amount"},
+				"local_amount":    bson.M{"$sum": "$local_amount"},
+				"count":           bson.M{"$sum": 1},
+			},
+		},
+		{
+			"$project": bson.M{
+				"_id":                  0,
+				"type":                 "$_id.type",
+				"merchant_id":          "$_id.merchant_id",
+				"country":              "$_id.country",
+				"operating_company_id": "$_id.operating_company_id",
+				"currency":             "$_id.currency",
+				"original_currency":    "$_id.original_currency",
+				"local_currency":       "$_id.local_currency",
+				"amount":               "$amount",
+				"original_amount":      "$original_amount",
+				"local_amount":         "$local_amount",
+				"count":                "$count",
+			},
+		},
+		{
+			"$sort": bson.D{
+				{"operating_company_id", 1},
+				{"merchant_id", 1},
+				{"country", 1},
+				{"type", 1},
+				{"currency", 1},
+				{"original_currency", 1},
+				{"local_currency", 1},
+			},
+		},
+	}
+
+	cursor, err := r.db.Collection(collectionAccountingEntry).Aggregate(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var items []*pkg2.AccountingExportQueryResItem
+	err = cursor.All(ctx, &items)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAccountingEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return items, nil
+}
+
 func (r *accountingEntryRepository) BulkWrite(ctx context.Context, list []*billingpb.AccountingEntry) error {
 	var operations []mongo.WriteModel
 
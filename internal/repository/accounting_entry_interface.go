@@ -45,6 +45,10 @@ type AccountingEntryRepositoryInterface interface {
 	// FindByTypeCountryDates returns the account entries by type, country and dates.
 	FindByTypeCountryDates(context.Context, string, []string, time.Time, time.Time) ([]*billingpb.AccountingEntry, error)
 
+	// GetExportSummary returns sums of the account entries created in the period grouped by type, merchant, country,
+	// operating company and currencies. Empty operating company and merchant identifiers match all entries.
+	GetExportSummary(context.Context, string, string, time.Time, time.Time) ([]*pkg.AccountingExportQueryResItem, error)
+
 	// BulkWrite writing account entries.
 	BulkWrite(context.Context, []*billingpb.AccountingEntry) error
 }
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionAuditLog = "audit_log"
+)
+
+type auditLogRepository repository
+
+// NewAuditLogRepository create and return an object for working with the audit log repository.
+// The returned object implements the AuditLogRepositoryInterface interface.
+func NewAuditLogRepository(db mongodb.SourceInterface) AuditLogRepositoryInterface {
+	s := &auditLogRepository{db: db}
+	return s
+}
+
+func (r *auditLogRepository) Insert(ctx context.Context, obj *intPkg.AuditLogEntry) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	_, err := r.db.Collection(collectionAuditLog).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAuditLog),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *auditLogRepository) GetLast(ctx context.Context) (*intPkg.AuditLogEntry, error) {
+	entry := &intPkg.AuditLogEntry{}
+	query := bson.M{"sequence": bson.M{"$gt": 0}}
+	opts := options.FindOne().SetSort(bson.M{"sequence": -1})
+	err := r.db.Collection(collectionAuditLog).FindOne(ctx, query, opts).Decode(entry)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAuditLog),
+		)
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (r *auditLogRepository) FindPending(ctx context.Context, limit int64) ([]*intPkg.AuditLogEntry, error) {
+	query := bson.M{"sequence": 0}
+	opts := options.Find().
+		SetSort(bson.M{"_id": 1}).
+		SetLimit(limit)
+
+	return r.find(ctx, query, opts)
+}
+
+func (r *auditLogRepository) Chain(ctx context.Context, obj *intPkg.AuditLogEntry) (bool, error) {
+	filter := bson.M{"_id": obj.Id, "sequence": 0}
+	update := bson.M{
+		"$set": bson.M{
+			"sequence":      obj.Sequence,
+			"previous_hash": obj.PreviousHash,
+			"hash":          obj.Hash,
+		},
+	}
+	res, err := r.db.Collection(collectionAuditLog).UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		if !mongodb.IsDuplicate(err) {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionAuditLog),
+				zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			)
+		}
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+func (r *auditLogRepository) Find(
+	ctx context.Context,
+	entityType, entityId, actorId string,
+	from, to time.Time,
+	offset, limit int64,
+) ([]*intPkg.AuditLogEntry, error) {
+	query := r.getFindQuery(entityType, entityId, actorId, from, to)
+	opts := options.Find().
+		SetSort(bson.M{"sequence": -1}).
+		SetLimit(limit).
+		SetSkip(offset)
+
+	return r.find(ctx, query, opts)
+}
+
+func (r *auditLogRepository) FindCount(
+	ctx context.Context,
+	entityType, entityId, actorId string,
+	from, to time.Time,
+) (int64, error) {
+	query := r.getFindQuery(entityType, entityId, actorId, from, to)
+	count, err := r.db.Collection(collectionAuditLog).CountDocuments(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAuditLog),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationCount),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *auditLogRepository) FindBySequence(ctx context.Context, sequence, limit int64) ([]*intPkg.AuditLogEntry, error) {
+	query := bson.M{"sequence": bson.M{"$gte": sequence}}
+	opts := options.Find().
+		SetSort(bson.M{"sequence": 1}).
+		SetLimit(limit)
+
+	return r.find(ctx, query, opts)
+}
+
+func (r *auditLogRepository) find(
+	ctx context.Context,
+	query bson.M,
+	opts *options.FindOptions,
+) ([]*intPkg.AuditLogEntry, error) {
+	cursor, err := r.db.Collection(collectionAuditLog).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAuditLog),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.AuditLogEntry
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionAuditLog),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *auditLogRepository) getFindQuery(entityType, entityId, actorId string, from, to time.Time) bson.M {
+	query := make(bson.M)
+
+	if entityType != "" {
+		query["entity_type"] = entityType
+	}
+
+	if entityId != "" {
+		query["entity_id"] = entityId
+	}
+
+	if actorId != "" {
+		query["actor_id"] = actorId
+	}
+
+	if !from.IsZero() || !to.IsZero() {
+		date := make(bson.M)
+
+		if !from.IsZero() {
+			date["$gte"] = from
+		}
+
+		if !to.IsZero() {
+			date["$lte"] = to
+		}
+
+		query["created_at"] = date
+	}
+
+	return query
+}
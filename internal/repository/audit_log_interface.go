@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"time"
+)
+
+// AuditLogRepositoryInterface is abstraction layer for working with the append-only audit log of financial
+// and administrative changes and representation in database. Entries can't be updated or removed, the pending entry
+// can only be chained once.
+type AuditLogRepositoryInterface interface {
+	// Insert adds the pending entry to the audit log.
+	Insert(context.Context, *intPkg.AuditLogEntry) error
+
+	// GetLast returns the last chained entry of the audit log or nil if no entry is chained yet.
+	GetLast(context.Context) (*intPkg.AuditLogEntry, error)
+
+	// FindPending returns pending entries in the order of addition.
+	FindPending(context.Context, int64) ([]*intPkg.AuditLogEntry, error)
+
+	// Chain sets the sequence number and hashes of the pending entry. Returns false if the entry is already chained,
+	// returns the duplicate error if the sequence number is taken by another entry.
+	Chain(context.Context, *intPkg.AuditLogEntry) (bool, error)
+
+	// Find returns entries by entity type, entity identifier, actor and period of creation with pagination,
+	// the newest entries go first.
+	Find(context.Context, string, string, string, time.Time, time.Time, int64, int64) ([]*intPkg.AuditLogEntry, error)
+
+	// FindCount returns count of entries by entity type, entity identifier, actor and period of creation.
+	FindCount(context.Context, string, string, string, time.Time, time.Time) (int64, error)
+
+	// FindBySequence returns entries starting from the sequence number in the order of the chain.
+	FindBySequence(context.Context, int64, int64) ([]*intPkg.AuditLogEntry, error)
+}
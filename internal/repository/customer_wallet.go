@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionCustomerWallet = "customer_wallet"
+)
+
+type customerWalletRepository repository
+
+// NewCustomerWalletRepository create and return an object for working with the customer wallet repository.
+// The returned object implements the CustomerWalletRepositoryInterface interface.
+func NewCustomerWalletRepository(db mongodb.SourceInterface) CustomerWalletRepositoryInterface {
+	s := &customerWalletRepository{db: db}
+	return s
+}
+
+func (r *customerWalletRepository) Increase(
+	ctx context.Context,
+	obj *intPkg.CustomerWallet,
+	amount float64,
+) (*intPkg.CustomerWallet, error) {
+	now := time.Now()
+	filter := bson.M{"customer_id": obj.CustomerId, "project_id": obj.ProjectId, "currency": obj.Currency}
+	update := bson.M{
+		"$inc": bson.M{"balance": amount},
+		"$set": bson.M{"updated_at": now},
+		"$setOnInsert": bson.M{
+			"_id":         primitive.NewObjectID(),
+			"merchant_id": obj.MerchantId,
+			"created_at":  now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	wallet := &intPkg.CustomerWallet{}
+	err := r.db.Collection(collectionCustomerWallet).FindOneAndUpdate(ctx, filter, update, opts).Decode(wallet)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionCustomerWallet),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+func (r *customerWalletRepository) Decrease(
+	ctx context.Context,
+	id string,
+	amount float64,
+) (*intPkg.CustomerWallet, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionCustomerWallet),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return nil, err
+	}
+
+	filter := bson.M{"_id": oid, "balance": bson.M{"$gte": amount}}
+	update := bson.M{
+		"$inc": bson.M{"balance": -amount},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	wallet := &intPkg.CustomerWallet{}
+	err = r.db.Collection(collectionCustomerWallet).FindOneAndUpdate(ctx, filter, update, opts).Decode(wallet)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionCustomerWallet),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+func (r *customerWalletRepository) IncreaseOnce(
+	ctx context.Context,
+	id string,
+	creditId primitive.ObjectID,
+	amount float64,
+) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionCustomerWallet),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return false, err
+	}
+
+	filter := bson.M{"_id": oid, "credit_ids": bson.M{"$ne": creditId}}
+	update := bson.M{
+		"$inc":  bson.M{"balance": amount},
+		"$set":  bson.M{"updated_at": time.Now()},
+		"$push": bson.M{"credit_ids": creditId},
+	}
+	res, err := r.db.Collection(collectionCustomerWallet).UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionCustomerWallet),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+func (r *customerWalletRepository) GetById(ctx context.Context, id string) (*intPkg.CustomerWallet, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionCustomerWallet),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return nil, err
+	}
+
+	return r.getOneBy(ctx, bson.M{"_id": oid})
+}
+
+func (r *customerWalletRepository) GetByCustomerId(
+	ctx context.Context,
+	customerId, projectId, currency string,
+) (*intPkg.CustomerWallet, error) {
+	oid, err := primitive.ObjectIDFromHex(projectId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionCustomerWallet),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, projectId),
+		)
+		return nil, err
+	}
+
+	return r.getOneBy(ctx, bson.M{"customer_id": customerId, "project_id": oid, "currency": currency})
+}
+
+func (r *customerWalletRepository) FindByCustomerId(
+	ctx context.Context,
+	customerId, projectId string,
+) ([]*intPkg.CustomerWallet, error) {
+	oid, err := primitive.ObjectIDFromHex(projectId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionCustomerWallet),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, projectId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"customer_id": customerId, "project_id": oid}
+	opts := options.Find().SetSort(bson.M{"currency": 1})
+	cursor, err := r.db.Collection(collectionCustomerWallet).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionCustomerWallet),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.CustomerWallet
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionCustomerWallet),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *customerWalletRepository) getOneBy(ctx context.Context, query bson.M) (*intPkg.CustomerWallet, error) {
+	wallet := &intPkg.CustomerWallet{}
+	err := r.db.Collection(collectionCustomerWallet).FindOne(ctx, query).Decode(wallet)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionCustomerWallet),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return wallet, nil
+}
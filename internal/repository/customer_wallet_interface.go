@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CustomerWalletRepositoryInterface is abstraction layer for working with wallets of customers.
+type CustomerWalletRepositoryInterface interface {
+	// Increase adds the amount to the balance of the customer wallet on the project in the currency of the passed
+	// wallet and returns the wallet with the new balance. The wallet is created if it doesn't exist.
+	Increase(context.Context, *intPkg.CustomerWallet, float64) (*intPkg.CustomerWallet, error)
+
+	// Decrease subtracts the amount from the balance of the wallet by the identifier and returns the wallet with
+	// the new balance. Returns error if the balance of the wallet is less than the amount.
+	Decrease(context.Context, string, float64) (*intPkg.CustomerWallet, error)
+
+	// IncreaseOnce adds the amount of the store credit to the balance of the wallet by the identifier unless
+	// the credit with the same identifier was already added to it. Returns false if the credit was already added.
+	IncreaseOnce(context.Context, string, primitive.ObjectID, float64) (bool, error)
+
+	// GetById returns the wallet by the identifier.
+	GetById(context.Context, string) (*intPkg.CustomerWallet, error)
+
+	// GetByCustomerId returns the wallet by the customer and the project identifiers and the currency.
+	GetByCustomerId(context.Context, string, string, string) (*intPkg.CustomerWallet, error)
+
+	// FindByCustomerId returns wallets of the customer in all currencies by the customer and the project identifiers.
+	FindByCustomerId(context.Context, string, string) ([]*intPkg.CustomerWallet, error)
+}
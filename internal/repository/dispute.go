@@ -0,0 +1,317 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionDispute = "dispute"
+)
+
+type disputeRepository repository
+
+// NewDisputeRepository create and return an object for working with the dispute repository.
+// The returned object implements the DisputeRepositoryInterface interface.
+func NewDisputeRepository(db mongodb.SourceInterface) DisputeRepositoryInterface {
+	s := &disputeRepository{db: db}
+	return s
+}
+
+func (r *disputeRepository) Insert(ctx context.Context, obj *intPkg.Dispute) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = obj.CreatedAt
+	_, err := r.db.Collection(collectionDispute).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDispute),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *disputeRepository) Update(ctx context.Context, obj *intPkg.Dispute) error {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionDispute).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDispute),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *disputeRepository) AddEvidence(
+	ctx context.Context,
+	id primitive.ObjectID,
+	evidence *intPkg.DisputeEvidence,
+) (bool, error) {
+	filter := bson.M{
+		"_id":             id,
+		"status":          pkg.DisputeStatusOpen,
+		"evidence_due_at": bson.M{"$gt": evidence.CreatedAt},
+	}
+	update := bson.M{
+		"$push": bson.M{"evidence": evidence},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	res, err := r.db.Collection(collectionDispute).UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDispute),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+func (r *disputeRepository) UpdateStatus(ctx context.Context, obj *intPkg.Dispute, fromStatuses ...string) (bool, error) {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{
+		"_id":    obj.Id,
+		"status": bson.M{"$in": fromStatuses},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":                     obj.Status,
+			"is_open":                    obj.IsOpen,
+			"representment_submitted_at": obj.RepresentmentSubmittedAt,
+			"chargeback_id":              obj.ChargebackId,
+			"closed_at":                  obj.ClosedAt,
+			"updated_at":                 obj.UpdatedAt,
+		},
+	}
+	res, err := r.db.Collection(collectionDispute).UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDispute),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+func (r *disputeRepository) GetById(ctx context.Context, id string) (*intPkg.Dispute, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDispute),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return nil, err
+	}
+
+	dispute := &intPkg.Dispute{}
+	query := bson.M{"_id": oid}
+	err = r.db.Collection(collectionDispute).FindOne(ctx, query).Decode(dispute)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDispute),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return dispute, nil
+}
+
+func (r *disputeRepository) GetOpenByOrderId(ctx context.Context, orderId string) (*intPkg.Dispute, error) {
+	oid, err := primitive.ObjectIDFromHex(orderId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDispute),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, orderId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{
+		"order_id": oid,
+		"status":   bson.M{"$in": []string{pkg.DisputeStatusOpen, pkg.DisputeStatusEvidenceSubmitted}},
+	}
+	dispute := &intPkg.Dispute{}
+	err = r.db.Collection(collectionDispute).FindOne(ctx, query).Decode(dispute)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDispute),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return dispute, nil
+}
+
+func (r *disputeRepository) Find(
+	ctx context.Context,
+	merchantId, orderUuid, status string,
+	offset, limit int64,
+) ([]*intPkg.Dispute, error) {
+	query, err := r.getFindQuery(merchantId, orderUuid, status)
+
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetLimit(limit).
+		SetSkip(offset)
+
+	return r.find(ctx, query, opts)
+}
+
+func (r *disputeRepository) FindCount(ctx context.Context, merchantId, orderUuid, status string) (int64, error) {
+	query, err := r.getFindQuery(merchantId, orderUuid, status)
+
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := r.db.Collection(collectionDispute).CountDocuments(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDispute),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationCount),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *disputeRepository) FindExpired(ctx context.Context, date time.Time) ([]*intPkg.Dispute, error) {
+	query := bson.M{
+		"status":          pkg.DisputeStatusOpen,
+		"evidence_due_at": bson.M{"$lt": date},
+	}
+
+	return r.find(ctx, query, options.Find().SetSort(bson.M{"evidence_due_at": 1}))
+}
+
+func (r *disputeRepository) getFindQuery(merchantId, orderUuid, status string) (bson.M, error) {
+	var err error
+
+	query := make(bson.M)
+
+	if merchantId != "" {
+		query["merchant_id"], err = primitive.ObjectIDFromHex(merchantId)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseInvalidObjectId,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionDispute),
+				zap.String(pkg.ErrorDatabaseFieldDocumentId, merchantId),
+			)
+			return nil, err
+		}
+	}
+
+	if orderUuid != "" {
+		query["order_uuid"] = orderUuid
+	}
+
+	if status != "" {
+		query["status"] = status
+	}
+
+	return query, nil
+}
+
+func (r *disputeRepository) find(
+	ctx context.Context,
+	query bson.M,
+	opts *options.FindOptions,
+) ([]*intPkg.Dispute, error) {
+	cursor, err := r.db.Collection(collectionDispute).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDispute),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.Dispute
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDispute),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// DisputeRepositoryInterface is abstraction layer for working with chargeback disputes of orders.
+type DisputeRepositoryInterface interface {
+	// Insert adds the dispute to the collection.
+	Insert(context.Context, *intPkg.Dispute) error
+
+	// Update updates the dispute in the collection.
+	Update(context.Context, *intPkg.Dispute) error
+
+	// AddEvidence adds the evidence document to the dispute if the dispute is still open and its evidence due date
+	// isn't passed at the evidence creation time. Returns false if the dispute doesn't accept evidence anymore.
+	AddEvidence(context.Context, primitive.ObjectID, *intPkg.DisputeEvidence) (bool, error)
+
+	// UpdateStatus saves the status of the dispute with its status dates and the chargeback if the dispute is still
+	// in one of the specified statuses. Returns false if the dispute status is changed already.
+	UpdateStatus(ctx context.Context, dispute *intPkg.Dispute, fromStatuses ...string) (bool, error)
+
+	// GetById returns the dispute by unique identifier.
+	GetById(context.Context, string) (*intPkg.Dispute, error)
+
+	// GetOpenByOrderId returns the dispute of the order which isn't closed yet or nil if the order has no such dispute.
+	GetOpenByOrderId(context.Context, string) (*intPkg.Dispute, error)
+
+	// Find returns disputes by merchant, order uuid and status with pagination, the newest disputes go first.
+	Find(context.Context, string, string, string, int64, int64) ([]*intPkg.Dispute, error)
+
+	// FindCount returns count of disputes by merchant, order uuid and status.
+	FindCount(context.Context, string, string, string) (int64, error)
+
+	// FindExpired returns open disputes without representment which evidence due date is before the specified time.
+	FindExpired(context.Context, time.Time) ([]*intPkg.Dispute, error)
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionDunningSchedule = "dunning_schedule"
+)
+
+type dunningScheduleRepository repository
+
+// NewDunningScheduleRepository create and return an object for working with the dunning schedule repository.
+// The returned object implements the DunningScheduleRepositoryInterface interface.
+func NewDunningScheduleRepository(db mongodb.SourceInterface) DunningScheduleRepositoryInterface {
+	s := &dunningScheduleRepository{db: db}
+	return s
+}
+
+func (r *dunningScheduleRepository) Upsert(ctx context.Context, obj *intPkg.DunningSchedule) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"project_id": obj.ProjectId}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.db.Collection(collectionDunningSchedule).ReplaceOne(ctx, filter, obj, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDunningSchedule),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *dunningScheduleRepository) GetByProjectId(ctx context.Context, projectId string) (*intPkg.DunningSchedule, error) {
+	oid, err := primitive.ObjectIDFromHex(projectId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDunningSchedule),
+			zap.String(pkg.ErrorDatabaseFieldQuery, projectId),
+		)
+		return nil, err
+	}
+
+	schedule := &intPkg.DunningSchedule{}
+	query := bson.M{"project_id": oid}
+	err = r.db.Collection(collectionDunningSchedule).FindOne(ctx, query).Decode(schedule)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionDunningSchedule),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return schedule, nil
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// DunningScheduleRepositoryInterface is abstraction layer for working with schedules of retries of failed recurring
+// payments of projects.
+type DunningScheduleRepositoryInterface interface {
+	// Upsert adds or replaces the schedule of the project.
+	Upsert(context.Context, *intPkg.DunningSchedule) error
+
+	// GetByProjectId returns the schedule of the project.
+	GetByProjectId(context.Context, string) (*intPkg.DunningSchedule, error)
+}
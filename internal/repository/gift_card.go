@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionGiftCard = "gift_card"
+)
+
+type giftCardRepository repository
+
+// NewGiftCardRepository create and return an object for working with the gift card repository.
+// The returned object implements the GiftCardRepositoryInterface interface.
+func NewGiftCardRepository(db mongodb.SourceInterface) GiftCardRepositoryInterface {
+	s := &giftCardRepository{db: db}
+	return s
+}
+
+func (r *giftCardRepository) Insert(ctx context.Context, obj *intPkg.GiftCard) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = obj.CreatedAt
+	_, err := r.db.Collection(collectionGiftCard).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *giftCardRepository) Update(ctx context.Context, obj *intPkg.GiftCard) error {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionGiftCard).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *giftCardRepository) GetById(ctx context.Context, id string) (*intPkg.GiftCard, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return nil, err
+	}
+
+	return r.getOneBy(ctx, bson.M{"_id": oid})
+}
+
+func (r *giftCardRepository) GetByCode(ctx context.Context, projectId, code string) (*intPkg.GiftCard, error) {
+	oid, err := primitive.ObjectIDFromHex(projectId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, projectId),
+		)
+		return nil, err
+	}
+
+	return r.getOneBy(ctx, bson.M{"project_id": oid, "code": code})
+}
+
+func (r *giftCardRepository) GetByKeyId(ctx context.Context, keyId string) (*intPkg.GiftCard, error) {
+	card := &intPkg.GiftCard{}
+	query := bson.M{"key_id": keyId}
+	err := r.db.Collection(collectionGiftCard).FindOne(ctx, query).Decode(card)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return card, nil
+}
+
+func (r *giftCardRepository) Decrease(ctx context.Context, id string, amount float64) (*intPkg.GiftCard, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return nil, err
+	}
+
+	filter := bson.M{"_id": oid, "status": pkg.GiftCardStatusActive, "balance": bson.M{"$gte": amount}}
+	update := bson.M{
+		"$inc": bson.M{"balance": -amount},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	card := &intPkg.GiftCard{}
+	err = r.db.Collection(collectionGiftCard).FindOneAndUpdate(ctx, filter, update, opts).Decode(card)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return nil, err
+	}
+
+	return card, nil
+}
+
+func (r *giftCardRepository) Increase(ctx context.Context, id string, amount float64) (*intPkg.GiftCard, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return nil, err
+	}
+
+	filter := bson.M{"_id": oid}
+	update := bson.M{
+		"$inc": bson.M{"balance": amount},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	card := &intPkg.GiftCard{}
+	err = r.db.Collection(collectionGiftCard).FindOneAndUpdate(ctx, filter, update, opts).Decode(card)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return nil, err
+	}
+
+	return card, nil
+}
+
+func (r *giftCardRepository) Expire(ctx context.Context, card *intPkg.GiftCard) (bool, error) {
+	filter := bson.M{"_id": card.Id, "status": pkg.GiftCardStatusActive, "balance": card.Balance}
+	update := bson.M{
+		"$set": bson.M{
+			"status":          pkg.GiftCardStatusExpired,
+			"balance":         float64(0),
+			"breakage_amount": card.Balance,
+			"updated_at":      time.Now(),
+		},
+	}
+	res, err := r.db.Collection(collectionGiftCard).UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+func (r *giftCardRepository) Cancel(ctx context.Context, card *intPkg.GiftCard) (bool, error) {
+	filter := bson.M{"_id": card.Id, "status": card.Status, "balance": card.Balance}
+	update := bson.M{
+		"$set": bson.M{
+			"status":     pkg.GiftCardStatusCanceled,
+			"balance":    float64(0),
+			"updated_at": time.Now(),
+		},
+	}
+	res, err := r.db.Collection(collectionGiftCard).UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+func (r *giftCardRepository) FindExpired(ctx context.Context, date time.Time) ([]*intPkg.GiftCard, error) {
+	query := bson.M{
+		"status":     pkg.GiftCardStatusActive,
+		"expires_at": bson.M{"$gt": time.Time{}, "$lte": date},
+	}
+	cursor, err := r.db.Collection(collectionGiftCard).Find(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.GiftCard
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *giftCardRepository) getOneBy(ctx context.Context, query bson.M) (*intPkg.GiftCard, error) {
+	card := &intPkg.GiftCard{}
+	err := r.db.Collection(collectionGiftCard).FindOne(ctx, query).Decode(card)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionGiftCard),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return card, nil
+}
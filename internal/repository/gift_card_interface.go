@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"time"
+)
+
+// GiftCardRepositoryInterface is abstraction layer for working with gift cards and representation in database.
+type GiftCardRepositoryInterface interface {
+	// Insert adds the gift card to the collection.
+	Insert(context.Context, *intPkg.GiftCard) error
+
+	// Update updates the gift card in the collection.
+	Update(context.Context, *intPkg.GiftCard) error
+
+	// GetById returns the gift card by the identifier.
+	GetById(context.Context, string) (*intPkg.GiftCard, error)
+
+	// GetByCode returns the gift card by the project identifier and the code.
+	GetByCode(context.Context, string, string) (*intPkg.GiftCard, error)
+
+	// GetByKeyId returns the gift card by the identifier of the key of the key product.
+	// Returns nil without error if the key isn't the gift card.
+	GetByKeyId(context.Context, string) (*intPkg.GiftCard, error)
+
+	// Decrease subtracts the amount from the balance of the active gift card by the identifier and returns the card
+	// with the new balance. Returns error if the balance of the card is less than the amount.
+	Decrease(context.Context, string, float64) (*intPkg.GiftCard, error)
+
+	// Increase adds the amount to the balance of the gift card by the identifier and returns the card with the new
+	// balance. It returns the amount of the failed redemption or payment to the card.
+	Increase(context.Context, string, float64) (*intPkg.GiftCard, error)
+
+	// Expire moves the rest of the balance of the active gift card to the breakage amount and expires the card.
+	// Card is expired by the conditional update, so it returns false if the card was spent or expired after it was
+	// read.
+	Expire(context.Context, *intPkg.GiftCard) (bool, error)
+
+	// Cancel cancels the sold gift card of the refunded key. Card is canceled by the conditional update, so it returns
+	// false if the card was spent after it was read.
+	Cancel(context.Context, *intPkg.GiftCard) (bool, error)
+
+	// FindExpired returns active gift cards which expire before the passed date.
+	FindExpired(context.Context, time.Time) ([]*intPkg.GiftCard, error)
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionIdempotencyKey = "idempotency_key"
+)
+
+type idempotencyKeyRepository repository
+
+// NewIdempotencyKeyRepository create and return an object for working with the idempotency key repository.
+// The returned object implements the IdempotencyKeyRepositoryInterface interface.
+func NewIdempotencyKeyRepository(db mongodb.SourceInterface) IdempotencyKeyRepositoryInterface {
+	s := &idempotencyKeyRepository{db: db}
+	return s
+}
+
+func (r *idempotencyKeyRepository) Reserve(
+	ctx context.Context,
+	obj *intPkg.IdempotencyKey,
+) (*intPkg.IdempotencyKey, error) {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	filter := bson.M{"project_id": obj.ProjectId, "operation": obj.Operation, "key": obj.Key}
+	set := bson.M{
+		"$setOnInsert": bson.M{
+			"_id":          obj.Id,
+			"request_hash": obj.RequestHash,
+			"response":     obj.Response,
+			"created_at":   obj.CreatedAt,
+			"expire_at":    obj.ExpireAt,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+
+	var existing intPkg.IdempotencyKey
+	err := r.db.Collection(collectionIdempotencyKey).FindOneAndUpdate(ctx, filter, set, opts).Decode(&existing)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	// concurrent upserts of the same key can't both insert it because of the unique index, so the request which
+	// lost the race gets the key inserted by another one
+	if mongodb.IsDuplicate(err) {
+		err = r.db.Collection(collectionIdempotencyKey).FindOne(ctx, filter).Decode(&existing)
+
+		if err == nil {
+			return &existing, nil
+		}
+
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionIdempotencyKey),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+		)
+		return nil, err
+	}
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionIdempotencyKey),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, set),
+		)
+		return nil, err
+	}
+
+	return &existing, nil
+}
+
+func (r *idempotencyKeyRepository) SetResponse(ctx context.Context, obj *intPkg.IdempotencyKey) error {
+	filter := bson.M{"_id": obj.Id}
+	set := bson.M{"$set": bson.M{"response": obj.Response, "expire_at": obj.ExpireAt}}
+	_, err := r.db.Collection(collectionIdempotencyKey).UpdateOne(ctx, filter, set)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionIdempotencyKey),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *idempotencyKeyRepository) Delete(ctx context.Context, obj *intPkg.IdempotencyKey) error {
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionIdempotencyKey).DeleteOne(ctx, filter)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionIdempotencyKey),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationDelete),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+		)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// IdempotencyKeyRepositoryInterface is abstraction layer for working with idempotency keys of requests.
+type IdempotencyKeyRepositoryInterface interface {
+	// Reserve adds the idempotency key if it doesn't exist yet and returns nil.
+	// If the key already exists or was inserted by concurrent request then the existing key is returned.
+	Reserve(context.Context, *intPkg.IdempotencyKey) (*intPkg.IdempotencyKey, error)
+
+	// SetResponse saves the response of the request and the expiration time of the response to the idempotency key.
+	SetResponse(context.Context, *intPkg.IdempotencyKey) error
+
+	// Delete removes the idempotency key.
+	Delete(context.Context, *intPkg.IdempotencyKey) error
+}
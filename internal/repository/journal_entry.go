@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionJournalEntry = "journal_entry"
+)
+
+type journalEntryRepository repository
+
+// NewJournalEntryRepository create and return an object for working with the journal entry repository.
+// The returned object implements the JournalEntryRepositoryInterface interface.
+func NewJournalEntryRepository(db mongodb.SourceInterface) JournalEntryRepositoryInterface {
+	s := &journalEntryRepository{db: db}
+	return s
+}
+
+func (r *journalEntryRepository) MultipleInsert(ctx context.Context, objs []*intPkg.JournalEntry) error {
+	if len(objs) <= 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(objs))
+
+	for i, obj := range objs {
+		if obj.Id.IsZero() {
+			obj.Id = primitive.NewObjectID()
+		}
+
+		if obj.CreatedAt.IsZero() {
+			obj.CreatedAt = time.Now()
+		}
+
+		docs[i] = obj
+	}
+
+	_, err := r.db.Collection(collectionJournalEntry).InsertMany(ctx, docs)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionJournalEntry),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, docs),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *journalEntryRepository) DeleteBySource(ctx context.Context, sourceId, sourceType string) error {
+	query := bson.M{
+		"source_id":   sourceId,
+		"source_type": sourceType,
+	}
+	_, err := r.db.Collection(collectionJournalEntry).DeleteMany(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionJournalEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *journalEntryRepository) GetTrialBalance(
+	ctx context.Context,
+	operatingCompanyId, currency string,
+	to time.Time,
+) ([]*intPkg.TrialBalanceItem, error) {
+	match := bson.M{
+		"operating_company_id": operatingCompanyId,
+		"lines.currency":       currency,
+	}
+
+	if !to.IsZero() {
+		match["created_at"] = bson.M{"$lte": to}
+	}
+
+	query := []bson.M{
+		{"$match": match},
+		{"$unwind": "$lines"},
+		{"$match": bson.M{"lines.currency": currency}},
+		{
+			"$group": bson.M{
+				"_id":    "$lines.account",
+				"debit":  bson.M{"$sum": "$lines.debit"},
+				"credit": bson.M{"$sum": "$lines.credit"},
+			},
+		},
+		{"$sort": bson.M{"_id": 1}},
+	}
+
+	cursor, err := r.db.Collection(collectionJournalEntry).Aggregate(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionJournalEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var items []*intPkg.TrialBalanceItem
+	err = cursor.All(ctx, &items)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionJournalEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (r *journalEntryRepository) FindByPeriod(
+	ctx context.Context,
+	operatingCompanyId, merchantId string,
+	from, to time.Time,
+) ([]*intPkg.JournalEntry, error) {
+	query := bson.M{
+		"created_at": bson.M{"$gte": from, "$lt": to},
+	}
+
+	if operatingCompanyId != "" {
+		query["operating_company_id"] = operatingCompanyId
+	}
+
+	if merchantId != "" {
+		query["merchant_id"] = merchantId
+	}
+
+	opts := options.Find().SetSort(bson.D{{"created_at", 1}, {"_id", 1}})
+	cursor, err := r.db.Collection(collectionJournalEntry).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionJournalEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var journals []*intPkg.JournalEntry
+	err = cursor.All(ctx, &journals)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionJournalEntry),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return journals, nil
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"time"
+)
+
+// JournalEntryRepositoryInterface is abstraction layer for working with journal entries of the double-entry ledger
+// and representation in database.
+type JournalEntryRepositoryInterface interface {
+	// MultipleInsert adds the multiple journal entries to the collection.
+	MultipleInsert(context.Context, []*intPkg.JournalEntry) error
+
+	// DeleteBySource deletes the journal entries by the source identifier and the source type.
+	DeleteBySource(context.Context, string, string) error
+
+	// GetTrialBalance returns sums of debits and credits of the ledger accounts by the operating company identifier
+	// and the currency of the journal lines. Journal entries created after the passed date aren't included, zero date
+	// includes all entries.
+	GetTrialBalance(context.Context, string, string, time.Time) ([]*intPkg.TrialBalanceItem, error)
+
+	// FindByPeriod returns the journal entries created in the period by the operating company identifier and
+	// the merchant identifier in order of creation. Empty identifiers aren't used in the filter.
+	FindByPeriod(context.Context, string, string, time.Time, time.Time) ([]*intPkg.JournalEntry, error)
+}
@@ -246,6 +246,52 @@ func (r *keyRepository) FinishRedeemById(ctx context.Context, id string) (*billi
 	return obj.(*billingpb.Key), nil
 }
 
+func (r *keyRepository) RevokeById(ctx context.Context, id string) (*billingpb.Key, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionKey),
+			zap.String(pkg.ErrorDatabaseFieldQuery, id),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"_id": oid}
+	update := bson.M{
+		"$set": bson.M{
+			"revoked_at": time.Now().UTC(),
+		},
+	}
+	mgo := &models.MgoKey{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = r.db.Collection(collectionKey).FindOneAndUpdate(ctx, query, update, opts).Decode(mgo)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionKey),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	obj, err := r.mapper.MapMgoToObject(mgo)
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorMapModelFailed,
+			zap.Error(err),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, mgo),
+		)
+		return nil, err
+	}
+
+	return obj.(*billingpb.Key), nil
+}
+
 func (r *keyRepository) CountKeysByProductPlatform(ctx context.Context, keyProductId string, platformId string) (int64, error) {
 	oid, err := primitive.ObjectIDFromHex(keyProductId)
 
@@ -22,6 +22,9 @@ type KeyRepositoryInterface interface {
 	// FinishRedeemById marks the reserved key as successfully used.
 	FinishRedeemById(context.Context, string) (*billingpb.Key, error)
 
+	// RevokeById marks the redeemed key as revoked after the refund of the order.
+	RevokeById(context.Context, string) (*billingpb.Key, error)
+
 	// CountKeysByProductPlatform returns the number of keys for the product and the specified platform.
 	CountKeysByProductPlatform(context.Context, string, string) (int64, error)
 
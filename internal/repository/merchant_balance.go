@@ -169,3 +169,40 @@ func (r merchantBalanceRepository) CountByIdAndCurrency(ctx context.Context, mer
 
 	return count, nil
 }
+
+func (r merchantBalanceRepository) GetCurrencies(ctx context.Context, merchantId string) ([]string, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalances),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"merchant_id": oid}
+	res, err := r.db.Collection(collectionMerchantBalances).Distinct(ctx, "currency", query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalances),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var currencies []string
+
+	for _, currency := range res {
+		if val, ok := currency.(string); ok && val != "" {
+			currencies = append(currencies, val)
+		}
+	}
+
+	return currencies, nil
+}
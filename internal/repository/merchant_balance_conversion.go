@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+)
+
+const (
+	collectionMerchantBalanceConversions = "merchant_balance_conversions"
+)
+
+type merchantBalanceConversionRepository repository
+
+// NewMerchantBalanceConversionRepository create and return an object for working with the merchant balance
+// conversions repository. The returned object implements the MerchantBalanceConversionRepositoryInterface interface.
+func NewMerchantBalanceConversionRepository(db mongodb.SourceInterface) MerchantBalanceConversionRepositoryInterface {
+	s := &merchantBalanceConversionRepository{db: db}
+	return s
+}
+
+func (r *merchantBalanceConversionRepository) Insert(ctx context.Context, obj *intPkg.MerchantBalanceConversion) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	_, err := r.db.Collection(collectionMerchantBalanceConversions).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceConversions),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *merchantBalanceConversionRepository) Find(
+	ctx context.Context,
+	merchantId, currency string,
+	offset, limit int64,
+) ([]*intPkg.MerchantBalanceConversion, error) {
+	query, err := r.getMerchantQuery(merchantId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if currency != "" {
+		query["$or"] = []bson.M{{"from_currency": currency}, {"to_currency": currency}}
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetLimit(limit).
+		SetSkip(offset)
+
+	return r.find(ctx, query, opts)
+}
+
+func (r *merchantBalanceConversionRepository) GetLastFrom(
+	ctx context.Context,
+	merchantId, currency string,
+) (*intPkg.MerchantBalanceConversion, error) {
+	query, err := r.getMerchantQuery(merchantId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	query["from_currency"] = currency
+	conversion := &intPkg.MerchantBalanceConversion{}
+	opts := options.FindOne().SetSort(bson.M{"from_sequence": -1})
+	err = r.db.Collection(collectionMerchantBalanceConversions).FindOne(ctx, query, opts).Decode(conversion)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceConversions),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return conversion, nil
+}
+
+func (r *merchantBalanceConversionRepository) FindCount(ctx context.Context, merchantId, currency string) (int64, error) {
+	query, err := r.getMerchantQuery(merchantId)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if currency != "" {
+		query["$or"] = []bson.M{{"from_currency": currency}, {"to_currency": currency}}
+	}
+
+	count, err := r.db.Collection(collectionMerchantBalanceConversions).CountDocuments(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceConversions),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationCount),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *merchantBalanceConversionRepository) GetNonPayoutConversions(
+	ctx context.Context,
+	merchantId, currency string,
+) ([]*intPkg.MerchantBalanceConversion, error) {
+	query, err := r.getMerchantQuery(merchantId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	from := bson.M{"from_payout_document_id": ""}
+	to := bson.M{"to_payout_document_id": ""}
+
+	if currency != "" {
+		from["from_currency"] = currency
+		to["to_currency"] = currency
+	}
+
+	query["$or"] = []bson.M{from, to}
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+
+	return r.find(ctx, query, opts)
+}
+
+func (r *merchantBalanceConversionRepository) SetPayoutDocumentId(
+	ctx context.Context,
+	ids []primitive.ObjectID,
+	currency, payoutDocumentId string,
+) error {
+	updates := []struct {
+		filter bson.M
+		update bson.M
+	}{
+		{
+			filter: bson.M{"_id": bson.M{"$in": ids}, "from_currency": currency, "from_payout_document_id": ""},
+			update: bson.M{"$set": bson.M{"from_payout_document_id": payoutDocumentId}},
+		},
+		{
+			filter: bson.M{"_id": bson.M{"$in": ids}, "to_currency": currency, "to_payout_document_id": ""},
+			update: bson.M{"$set": bson.M{"to_payout_document_id": payoutDocumentId}},
+		},
+	}
+
+	for _, val := range updates {
+		if err := r.updateMany(ctx, val.filter, val.update); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *merchantBalanceConversionRepository) UnsetPayoutDocumentId(ctx context.Context, payoutDocumentId string) error {
+	for _, field := range []string{"from_payout_document_id", "to_payout_document_id"} {
+		filter := bson.M{field: payoutDocumentId}
+		update := bson.M{"$set": bson.M{field: ""}}
+
+		if err := r.updateMany(ctx, filter, update); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *merchantBalanceConversionRepository) updateMany(ctx context.Context, filter, update bson.M) error {
+	_, err := r.db.Collection(collectionMerchantBalanceConversions).UpdateMany(ctx, filter, update)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceConversions),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+	}
+
+	return err
+}
+
+func (r *merchantBalanceConversionRepository) find(
+	ctx context.Context,
+	query bson.M,
+	opts *options.FindOptions,
+) ([]*intPkg.MerchantBalanceConversion, error) {
+	cursor, err := r.db.Collection(collectionMerchantBalanceConversions).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceConversions),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.MerchantBalanceConversion
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceConversions),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *merchantBalanceConversionRepository) getMerchantQuery(merchantId string) (bson.M, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionMerchantBalanceConversions),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	return bson.M{"merchant_id": oid}, nil
+}
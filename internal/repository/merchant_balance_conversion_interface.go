@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MerchantBalanceConversionRepositoryInterface is abstraction layer for working with conversions between
+// balances of the merchant in different currencies and representation in database.
+type MerchantBalanceConversionRepositoryInterface interface {
+	// Insert adds the conversion to the collection.
+	Insert(context.Context, *intPkg.MerchantBalanceConversion) error
+
+	// Find returns conversions of the merchant from or to the currency with pagination, the newest conversions
+	// go first. Conversions in all currencies are returned if the currency is empty.
+	Find(context.Context, string, string, int64, int64) ([]*intPkg.MerchantBalanceConversion, error)
+
+	// GetLastFrom returns the last conversion of the merchant from the currency or nil if there are no conversions.
+	GetLastFrom(context.Context, string, string) (*intPkg.MerchantBalanceConversion, error)
+
+	// FindCount returns count of conversions of the merchant from or to the currency.
+	FindCount(context.Context, string, string) (int64, error)
+
+	// GetNonPayoutConversions returns conversions of the merchant which side in the currency isn't included
+	// to payouts yet. Conversions in all currencies are returned if the currency is empty.
+	GetNonPayoutConversions(context.Context, string, string) ([]*intPkg.MerchantBalanceConversion, error)
+
+	// SetPayoutDocumentId includes sides of the conversions in the currency to the payout document.
+	SetPayoutDocumentId(context.Context, []primitive.ObjectID, string, string) error
+
+	// UnsetPayoutDocumentId excludes sides of the conversions from the payout document.
+	UnsetPayoutDocumentId(context.Context, string) error
+}
@@ -15,4 +15,7 @@ type MerchantBalanceRepositoryInterface interface {
 
 	// CountByIdAndCurrency return count balance records for merchant and currency
 	CountByIdAndCurrency(context.Context, string, string) (int64, error)
+
+	// GetCurrencies returns currencies of all balances of the merchant
+	GetCurrencies(context.Context, string) ([]string, error)
 }
@@ -16,6 +16,7 @@ type MgoKey struct {
 	CreatedAt    time.Time           `bson:"created_at"`
 	ReservedTo   time.Time           `bson:"reserved_to"`
 	RedeemedAt   time.Time           `bson:"redeemed_at"`
+	RevokedAt    time.Time           `bson:"revoked_at"`
 }
 
 type keyMapper struct {
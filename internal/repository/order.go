@@ -276,6 +276,11 @@ func (h *orderRepository) UpdateOrderView(ctx context.Context, ids []string) err
 			"real_tax_fee":                              {},
 			"reverse_tax_fee":                           {},
 			"reverse_tax_fee_delta":                     {},
+			"gift_card_payment":                         {},
+			"wallet_payment":                            {},
+			"wallet_payment_tax_fee":                    {},
+			"wallet_refund":                             {},
+			"wallet_refund_tax_fee":                     {},
 		}
 
 		// Now setting current values
@@ -285,6 +290,44 @@ func (h *orderRepository) UpdateOrderView(ctx context.Context, ids []string) err
 
 		order, err := h.GetById(ctx, id)
 
+		// The parts of the order paid by the wallet and the gift card are the merchant revenue as the payment by
+		// the payment system, they have no payment system fees. Merchant tax of them is included in
+		// merchant_tax_fee_cost_value, wallet_payment_tax_fee is the tax of the wallet part of the earlier orders.
+		revenueCurrency := entries["real_gross_revenue"].Currency
+
+		if revenueCurrency == "" {
+			revenueCurrency = entries["wallet_payment"].Currency
+		}
+
+		if revenueCurrency == "" {
+			revenueCurrency = entries["gift_card_payment"].Currency
+		}
+
+		taxFeeCurrency := entries["merchant_tax_fee_cost_value"].Currency
+
+		if taxFeeCurrency == "" {
+			taxFeeCurrency = entries["wallet_payment_tax_fee"].Currency
+		}
+
+		refundCurrency := entries["merchant_refund"].Currency
+
+		if refundCurrency == "" {
+			refundCurrency = entries["wallet_refund"].Currency
+		}
+
+		refundTaxFeeCurrency := entries["reverse_tax_fee"].Currency
+
+		if refundTaxFeeCurrency == "" {
+			refundTaxFeeCurrency = entries["wallet_refund_tax_fee"].Currency
+		}
+
+		prepaidRevenue := entries["wallet_payment"].Amount + entries["gift_card_payment"].Amount
+		prepaidRevenueRounded := entries["wallet_payment"].AmountRounded + entries["gift_card_payment"].AmountRounded
+		prepaidNetRevenue := prepaidRevenue - entries["wallet_payment_tax_fee"].Amount
+		prepaidNetRevenueRounded := prepaidRevenueRounded - entries["wallet_payment_tax_fee"].AmountRounded
+		walletRefund := entries["wallet_refund"].Amount - entries["wallet_refund_tax_fee"].Amount
+		walletRefundRounded := entries["wallet_refund"].AmountRounded - entries["wallet_refund_tax_fee"].AmountRounded
+
 		view := &billingpb.OrderViewPrivate{
 			Id:                 order.Id,
 			Uuid:               order.Uuid,
@@ -359,9 +402,9 @@ func (h *orderRepository) UpdateOrderView(ctx context.Context, ids []string) err
 				AmountRounded: helper.Round(entries["ps_gross_revenue_fx"].AmountRounded - entries["ps_gross_revenue_fx_tax_fee"].AmountRounded),
 			},
 			GrossRevenue: &billingpb.OrderViewMoney{
-				Amount:        entries["real_gross_revenue"].Amount - entries["ps_gross_revenue_fx"].Amount,
-				Currency:      entries["real_gross_revenue"].Currency,
-				AmountRounded: helper.Round(entries["real_gross_revenue"].AmountRounded - entries["ps_gross_revenue_fx"].AmountRounded),
+				Amount:        entries["real_gross_revenue"].Amount - entries["ps_gross_revenue_fx"].Amount + prepaidRevenue,
+				Currency:      revenueCurrency,
+				AmountRounded: helper.Round(entries["real_gross_revenue"].AmountRounded - entries["ps_gross_revenue_fx"].AmountRounded + prepaidRevenueRounded),
 			},
 			TaxFee: &billingpb.OrderViewMoney{
 				Amount:        entries["merchant_tax_fee_cost_value"].Amount,
@@ -374,9 +417,9 @@ func (h *orderRepository) UpdateOrderView(ctx context.Context, ids []string) err
 				AmountRounded: helper.Round(entries["merchant_tax_fee_central_bank_fx"].AmountRounded),
 			},
 			TaxFeeTotal: &billingpb.OrderViewMoney{
-				Amount:        entries["merchant_tax_fee_cost_value"].Amount + entries["merchant_tax_fee_central_bank_fx"].Amount,
-				Currency:      entries["merchant_tax_fee_cost_value"].Currency,
-				AmountRounded: helper.Round(entries["merchant_tax_fee_cost_value"].AmountRounded + entries["merchant_tax_fee_central_bank_fx"].AmountRounded),
+				Amount:        entries["merchant_tax_fee_cost_value"].Amount + entries["merchant_tax_fee_central_bank_fx"].Amount + entries["wallet_payment_tax_fee"].Amount,
+				Currency:      taxFeeCurrency,
+				AmountRounded: helper.Round(entries["merchant_tax_fee_cost_value"].AmountRounded + entries["merchant_tax_fee_central_bank_fx"].AmountRounded + entries["wallet_payment_tax_fee"].AmountRounded),
 			},
 			MethodFeeTotal: &billingpb.OrderViewMoney{
 				Amount:        entries["ps_method_fee"].Amount,
@@ -439,9 +482,9 @@ func (h *orderRepository) UpdateOrderView(ctx context.Context, ids []string) err
 				AmountRounded: helper.Round(entries["ps_method_fee"].LocalAmountRounded + entries["merchant_ps_fixed_fee"].LocalAmountRounded),
 			},
 			NetRevenue: &billingpb.OrderViewMoney{
-				Amount:        entries["real_gross_revenue"].Amount - entries["ps_gross_revenue_fx"].Amount - entries["merchant_ps_fixed_fee"].Amount - entries["merchant_tax_fee_central_bank_fx"].Amount - entries["ps_method_fee"].Amount - entries["merchant_tax_fee_cost_value"].Amount,
-				Currency:      entries["real_gross_revenue"].Currency,
-				AmountRounded: helper.Round(entries["real_gross_revenue"].AmountRounded - entries["ps_gross_revenue_fx"].AmountRounded - entries["merchant_ps_fixed_fee"].AmountRounded - entries["merchant_tax_fee_central_bank_fx"].AmountRounded - entries["ps_method_fee"].AmountRounded - entries["merchant_tax_fee_cost_value"].AmountRounded),
+				Amount:        entries["real_gross_revenue"].Amount - entries["ps_gross_revenue_fx"].Amount - entries["merchant_ps_fixed_fee"].Amount - entries["merchant_tax_fee_central_bank_fx"].Amount - entries["ps_method_fee"].Amount - entries["merchant_tax_fee_cost_value"].Amount + prepaidNetRevenue,
+				Currency:      revenueCurrency,
+				AmountRounded: helper.Round(entries["real_gross_revenue"].AmountRounded - entries["ps_gross_revenue_fx"].AmountRounded - entries["merchant_ps_fixed_fee"].AmountRounded - entries["merchant_tax_fee_central_bank_fx"].AmountRounded - entries["ps_method_fee"].AmountRounded - entries["merchant_tax_fee_cost_value"].AmountRounded + prepaidNetRevenueRounded),
 			},
 			PaysuperMethodTotalProfit: &billingpb.OrderViewMoney{
 				Amount:        entries["ps_method_fee"].Amount + entries["merchant_ps_fixed_fee"].Amount - entries["merchant_method_fee_cost_value"].Amount - entries["real_merchant_method_fixed_fee_cost_value"].Amount,
@@ -494,9 +537,9 @@ func (h *orderRepository) UpdateOrderView(ctx context.Context, ids []string) err
 				AmountRounded: helper.Round(entries["real_refund_fixed_fee"].AmountRounded),
 			},
 			RefundGrossRevenue: &billingpb.OrderViewMoney{
-				Amount:        entries["merchant_refund"].Amount,
-				Currency:      entries["merchant_refund"].Currency,
-				AmountRounded: helper.Round(entries["merchant_refund"].AmountRounded),
+				Amount:        entries["merchant_refund"].Amount + entries["wallet_refund"].Amount,
+				Currency:      refundCurrency,
+				AmountRounded: helper.Round(entries["merchant_refund"].AmountRounded + entries["wallet_refund"].AmountRounded),
 			},
 			RefundGrossRevenueFx: &billingpb.OrderViewMoney{
 				Amount:        entries["merchant_refund"].Amount - entries["real_refund"].Amount,
@@ -544,14 +587,14 @@ func (h *orderRepository) UpdateOrderView(ctx context.Context, ids []string) err
 				AmountRounded: helper.Round(entries["ps_reverse_tax_fee_delta"].AmountRounded),
 			},
 			RefundTaxFeeTotal: &billingpb.OrderViewMoney{
-				Amount:        entries["reverse_tax_fee"].Amount + entries["reverse_tax_fee_delta"].Amount,
-				Currency:      entries["reverse_tax_fee"].Currency,
-				AmountRounded: helper.Round(entries["reverse_tax_fee"].AmountRounded + entries["reverse_tax_fee_delta"].AmountRounded),
+				Amount:        entries["reverse_tax_fee"].Amount + entries["reverse_tax_fee_delta"].Amount + entries["wallet_refund_tax_fee"].Amount,
+				Currency:      refundTaxFeeCurrency,
+				AmountRounded: helper.Round(entries["reverse_tax_fee"].AmountRounded + entries["reverse_tax_fee_delta"].AmountRounded + entries["wallet_refund_tax_fee"].AmountRounded),
 			},
 			RefundReverseRevenue: &billingpb.OrderViewMoney{
-				Amount:        entries["merchant_refund"].Amount + entries["merchant_refund_fee"].Amount + entries["merchant_refund_fixed_fee"].Amount + entries["reverse_tax_fee_delta"].Amount - entries["reverse_tax_fee"].Amount,
-				Currency:      entries["merchant_refund"].Currency,
-				AmountRounded: helper.Round(entries["merchant_refund"].AmountRounded + entries["merchant_refund_fee"].AmountRounded + entries["merchant_refund_fixed_fee"].AmountRounded + entries["reverse_tax_fee_delta"].AmountRounded - entries["reverse_tax_fee"].AmountRounded),
+				Amount:        entries["merchant_refund"].Amount + entries["merchant_refund_fee"].Amount + entries["merchant_refund_fixed_fee"].Amount + entries["reverse_tax_fee_delta"].Amount - entries["reverse_tax_fee"].Amount + walletRefund,
+				Currency:      refundCurrency,
+				AmountRounded: helper.Round(entries["merchant_refund"].AmountRounded + entries["merchant_refund_fee"].AmountRounded + entries["merchant_refund_fixed_fee"].AmountRounded + entries["reverse_tax_fee_delta"].AmountRounded - entries["reverse_tax_fee"].AmountRounded + walletRefundRounded),
 			},
 			RefundFeesTotal: &billingpb.OrderViewMoney{
 				Amount:        entries["merchant_refund_fee"].Amount + entries["merchant_refund_fixed_fee"].Amount,
@@ -672,6 +715,24 @@ func (h *orderRepository) IncludeOrdersToRoyaltyReport(
 	return err
 }
 
+func (h *orderRepository) UpdateOneBy(ctx context.Context, filter bson.M, update bson.M) (bool, error) {
+	res, err := h.db.Collection(CollectionOrder).UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionOrder),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
 func (h *orderRepository) GetManyBy(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*billingpb.Order, error) {
 	var mgo []*models.MgoOrder
 	cursor, err := h.db.Collection(CollectionOrder).Find(ctx, filter, opts...)
@@ -713,3 +774,20 @@ func (h *orderRepository) GetManyBy(ctx context.Context, filter bson.M, opts ...
 
 	return orders, nil
 }
+
+func (h *orderRepository) CountBy(ctx context.Context, filter bson.M) (int64, error) {
+	count, err := h.db.Collection(CollectionOrder).CountDocuments(ctx, filter)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionOrder),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationCount),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+		)
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionOrderHistory = "order_history"
+)
+
+type orderHistoryRepository repository
+
+// NewOrderHistoryRepository create and return an object for working with the order history repository.
+// The returned object implements the OrderHistoryRepositoryInterface interface.
+func NewOrderHistoryRepository(db mongodb.SourceInterface) OrderHistoryRepositoryInterface {
+	s := &orderHistoryRepository{db: db}
+	return s
+}
+
+func (r *orderHistoryRepository) Insert(ctx context.Context, obj *intPkg.OrderHistory) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	_, err := r.db.Collection(collectionOrderHistory).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderHistory),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *orderHistoryRepository) FindByOrderId(ctx context.Context, orderId string) ([]*intPkg.OrderHistory, error) {
+	oid, err := primitive.ObjectIDFromHex(orderId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderHistory),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, orderId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"order_id": oid}
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+	cursor, err := r.db.Collection(collectionOrderHistory).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderHistory),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.OrderHistory
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderHistory),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
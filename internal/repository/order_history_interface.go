@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// OrderHistoryRepositoryInterface is abstraction layer for working with order history and representation in database.
+type OrderHistoryRepositoryInterface interface {
+	// Insert adds the order history record to the collection.
+	Insert(context.Context, *intPkg.OrderHistory) error
+
+	// FindByOrderId returns the order history records by order identifier ordered by creation date.
+	FindByOrderId(context.Context, string) ([]*intPkg.OrderHistory, error)
+}
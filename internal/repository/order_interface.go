@@ -37,9 +37,16 @@ type OrderRepositoryInterface interface {
 	// Mark orders as included to royalty report.
 	IncludeOrdersToRoyaltyReport(ctx context.Context, royaltyReportId string, orderIds []primitive.ObjectID) error
 
+	// UpdateOneBy applies the update to the order matched by the filter and reports whether any order was matched.
+	// The filter is used to change the order only when it's still in the expected state.
+	UpdateOneBy(ctx context.Context, filter bson.M, update bson.M) (bool, error)
+
 	// Get first payment for merchant
 	GetFirstPaymentForMerchant(ctx context.Context, merchantId string) (*billingpb.Order, error)
 
 	// Return orders by some conditions and with options
 	GetManyBy(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*billingpb.Order, error)
+
+	// Return count of orders by some conditions
+	CountBy(ctx context.Context, filter bson.M) (int64, error)
 }
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionOrderReview = "order_review"
+)
+
+type orderReviewRepository repository
+
+// NewOrderReviewRepository create and return an object for working with the order review repository.
+// The returned object implements the OrderReviewRepositoryInterface interface.
+func NewOrderReviewRepository(db mongodb.SourceInterface) OrderReviewRepositoryInterface {
+	s := &orderReviewRepository{db: db}
+	return s
+}
+
+func (r *orderReviewRepository) Insert(ctx context.Context, obj *intPkg.OrderReview) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = obj.CreatedAt
+	_, err := r.db.Collection(collectionOrderReview).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderReview),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *orderReviewRepository) Update(ctx context.Context, obj *intPkg.OrderReview) error {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionOrderReview).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderReview),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *orderReviewRepository) Resolve(ctx context.Context, obj *intPkg.OrderReview) (bool, error) {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id, "status": pkg.OrderReviewStatusHeld}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      obj.Status,
+			"reviewer_id": obj.ReviewerId,
+			"reason":      obj.Reason,
+			"resolved_at": obj.ResolvedAt,
+			"updated_at":  obj.UpdatedAt,
+		},
+	}
+	res, err := r.db.Collection(collectionOrderReview).UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderReview),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+func (r *orderReviewRepository) GetById(ctx context.Context, id string) (*intPkg.OrderReview, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderReview),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return nil, err
+	}
+
+	review := &intPkg.OrderReview{}
+	query := bson.M{"_id": oid}
+	err = r.db.Collection(collectionOrderReview).FindOne(ctx, query).Decode(review)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderReview),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return review, nil
+}
+
+func (r *orderReviewRepository) Find(
+	ctx context.Context,
+	merchantId, status string,
+	offset, limit int64,
+) ([]*intPkg.OrderReview, error) {
+	query, err := r.getFindQuery(merchantId, status)
+
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"created_at": 1}).
+		SetLimit(limit).
+		SetSkip(offset)
+
+	return r.find(ctx, query, opts)
+}
+
+func (r *orderReviewRepository) FindCount(ctx context.Context, merchantId, status string) (int64, error) {
+	query, err := r.getFindQuery(merchantId, status)
+
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := r.db.Collection(collectionOrderReview).CountDocuments(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderReview),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationCount),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *orderReviewRepository) FindExpired(ctx context.Context, date time.Time) ([]*intPkg.OrderReview, error) {
+	query := bson.M{
+		"status":     pkg.OrderReviewStatusHeld,
+		"expires_at": bson.M{"$lt": date},
+	}
+
+	return r.find(ctx, query, options.Find().SetSort(bson.M{"expires_at": 1}))
+}
+
+func (r *orderReviewRepository) getFindQuery(merchantId, status string) (bson.M, error) {
+	var err error
+
+	query := make(bson.M)
+
+	if merchantId != "" {
+		query["merchant_id"], err = primitive.ObjectIDFromHex(merchantId)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorDatabaseInvalidObjectId,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderReview),
+				zap.String(pkg.ErrorDatabaseFieldDocumentId, merchantId),
+			)
+			return nil, err
+		}
+	}
+
+	if status != "" {
+		query["status"] = status
+	}
+
+	return query, nil
+}
+
+func (r *orderReviewRepository) find(
+	ctx context.Context,
+	query bson.M,
+	opts *options.FindOptions,
+) ([]*intPkg.OrderReview, error) {
+	cursor, err := r.db.Collection(collectionOrderReview).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderReview),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.OrderReview
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionOrderReview),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"time"
+)
+
+// OrderReviewRepositoryInterface is abstraction layer for working with manual reviews of orders held by
+// the fraud screening.
+type OrderReviewRepositoryInterface interface {
+	// Insert adds the review to the collection.
+	Insert(context.Context, *intPkg.OrderReview) error
+
+	// Update updates the review in the collection.
+	Update(context.Context, *intPkg.OrderReview) error
+
+	// Resolve closes the review with its resolution if the review is still held, returns false when the review
+	// has already been resolved.
+	Resolve(context.Context, *intPkg.OrderReview) (bool, error)
+
+	// GetById returns the review by unique identifier.
+	GetById(context.Context, string) (*intPkg.OrderReview, error)
+
+	// Find returns reviews by merchant and status with pagination, the oldest reviews go first.
+	Find(context.Context, string, string, int64, int64) ([]*intPkg.OrderReview, error)
+
+	// FindCount returns count of reviews by merchant and status.
+	FindCount(context.Context, string, string) (int64, error)
+
+	// FindExpired returns held reviews which expiration date is before the specified time.
+	FindExpired(context.Context, time.Time) ([]*intPkg.OrderReview, error)
+}
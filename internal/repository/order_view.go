@@ -1028,6 +1028,50 @@ func (r *orderViewRepository) GetRoyaltyForMerchants(
 	return merchants, nil
 }
 
+func (r *orderViewRepository) GetRoyaltyCurrencies(
+	ctx context.Context, merchantId string, statuses []string, from time.Time, to time.Time,
+) ([]string, error) {
+	merchantOid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionOrderView),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{
+		"merchant_id":         merchantOid,
+		"pm_order_close_date": bson.M{"$gte": from, "$lte": to},
+		"status":              bson.M{"$in": statuses},
+		"is_production":       true,
+	}
+	res, err := r.db.Collection(CollectionOrderView).Distinct(ctx, "merchant_payout_currency", query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionOrderView),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var currencies []string
+
+	for _, currency := range res {
+		if val, ok := currency.(string); ok && val != "" {
+			currencies = append(currencies, val)
+		}
+	}
+
+	return currencies, nil
+}
+
 func (r *orderViewRepository) GetById(ctx context.Context, id string) (*billingpb.OrderViewPublic, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 
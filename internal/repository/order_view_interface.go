@@ -60,6 +60,9 @@ type OrderViewRepositoryInterface interface {
 	// GetRoyaltyForMerchants returns orders for merchants royal report by statuses and dates.
 	GetRoyaltyForMerchants(context.Context, []string, time.Time, time.Time) ([]*pkg.RoyaltyReportMerchant, error)
 
+	// GetRoyaltyCurrencies returns payout currencies of the merchant orders for royalty report by statuses and dates.
+	GetRoyaltyCurrencies(context.Context, string, []string, time.Time, time.Time) ([]string, error)
+
 	// Return orders by some conditions and with options
 	GetManyBy(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*billingpb.OrderViewPrivate, error)
 
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionPaymentMethodRoute = "payment_method_route"
+)
+
+type paymentMethodRouteRepository repository
+
+// NewPaymentMethodRouteRepository create and return an object for working with the payment method route repository.
+// The returned object implements the PaymentMethodRouteRepositoryInterface interface.
+func NewPaymentMethodRouteRepository(db mongodb.SourceInterface) PaymentMethodRouteRepositoryInterface {
+	s := &paymentMethodRouteRepository{db: db}
+	return s
+}
+
+func (r *paymentMethodRouteRepository) Upsert(ctx context.Context, obj *intPkg.PaymentMethodRoute) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = time.Now()
+
+	filter := bson.M{"payment_method_id": obj.PaymentMethodId, "country": obj.Country}
+	set := bson.M{
+		"$set": bson.M{
+			"payment_system_ids": obj.PaymentSystemIds,
+			"updated_at":         obj.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{
+			"_id":        obj.Id,
+			"created_at": obj.CreatedAt,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := r.db.Collection(collectionPaymentMethodRoute).FindOneAndUpdate(ctx, filter, set, opts).Decode(obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPaymentMethodRoute),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, set),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *paymentMethodRouteRepository) GetByPaymentMethodAndCountry(
+	ctx context.Context,
+	paymentMethodId, country string,
+) (*intPkg.PaymentMethodRoute, error) {
+	oid, err := primitive.ObjectIDFromHex(paymentMethodId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPaymentMethodRoute),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, paymentMethodId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"payment_method_id": oid, "country": bson.M{"$in": []string{country, ""}}}
+	opts := options.FindOne().SetSort(bson.M{"country": -1})
+
+	var obj intPkg.PaymentMethodRoute
+	err = r.db.Collection(collectionPaymentMethodRoute).FindOne(ctx, query, opts).Decode(&obj)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionPaymentMethodRoute),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+
+		return nil, err
+	}
+
+	return &obj, nil
+}
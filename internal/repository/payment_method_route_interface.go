@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// PaymentMethodRouteRepositoryInterface is abstraction layer for working with routes of payment methods to payment systems.
+type PaymentMethodRouteRepositoryInterface interface {
+	// Upsert adds or replaces the route of payment method for the country.
+	Upsert(context.Context, *intPkg.PaymentMethodRoute) error
+
+	// GetByPaymentMethodAndCountry returns the route of payment method for the country.
+	// If the country has no own route then route with empty country is returned.
+	GetByPaymentMethodAndCountry(context.Context, string, string) (*intPkg.PaymentMethodRoute, error)
+}
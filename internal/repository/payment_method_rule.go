@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionPaymentMethodRule = "payment_method_rule"
+)
+
+type paymentMethodRuleRepository repository
+
+// NewPaymentMethodRuleRepository create and return an object for working with the payment method rule repository.
+// The returned object implements the PaymentMethodRuleRepositoryInterface interface.
+func NewPaymentMethodRuleRepository(db mongodb.SourceInterface) PaymentMethodRuleRepositoryInterface {
+	s := &paymentMethodRuleRepository{db: db}
+	return s
+}
+
+func (r *paymentMethodRuleRepository) Insert(ctx context.Context, obj *intPkg.PaymentMethodRule) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = obj.CreatedAt
+	_, err := r.db.Collection(collectionPaymentMethodRule).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPaymentMethodRule),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *paymentMethodRuleRepository) Update(ctx context.Context, obj *intPkg.PaymentMethodRule) error {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionPaymentMethodRule).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPaymentMethodRule),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *paymentMethodRuleRepository) Delete(ctx context.Context, obj *intPkg.PaymentMethodRule) error {
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionPaymentMethodRule).DeleteOne(ctx, filter)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPaymentMethodRule),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationDelete),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *paymentMethodRuleRepository) GetById(ctx context.Context, id string) (*intPkg.PaymentMethodRule, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPaymentMethodRule),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return nil, err
+	}
+
+	rule := &intPkg.PaymentMethodRule{}
+	query := bson.M{"_id": oid}
+	err = r.db.Collection(collectionPaymentMethodRule).FindOne(ctx, query).Decode(rule)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPaymentMethodRule),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (r *paymentMethodRuleRepository) GetByProjectId(
+	ctx context.Context,
+	projectId string,
+) ([]*intPkg.PaymentMethodRule, error) {
+	oid, err := primitive.ObjectIDFromHex(projectId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPaymentMethodRule),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, projectId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"project_id": oid}
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+	cursor, err := r.db.Collection(collectionPaymentMethodRule).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPaymentMethodRule),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.PaymentMethodRule
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPaymentMethodRule),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// PaymentMethodRuleRepositoryInterface is abstraction layer for working with rules of payment methods availability
+// for projects.
+type PaymentMethodRuleRepositoryInterface interface {
+	// Insert adds the rule to the collection.
+	Insert(context.Context, *intPkg.PaymentMethodRule) error
+
+	// Update updates the rule in the collection.
+	Update(context.Context, *intPkg.PaymentMethodRule) error
+
+	// Delete deletes the rule from the collection.
+	Delete(context.Context, *intPkg.PaymentMethodRule) error
+
+	// GetById returns the rule by unique identifier.
+	GetById(context.Context, string) (*intPkg.PaymentMethodRule, error)
+
+	// GetByProjectId returns all rules of the project.
+	GetByProjectId(context.Context, string) ([]*intPkg.PaymentMethodRule, error)
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionProjectCaptureSettings = "project_capture_settings"
+)
+
+type projectCaptureSettingsRepository repository
+
+// NewProjectCaptureSettingsRepository create and return an object for working with the project capture settings
+// repository. The returned object implements the ProjectCaptureSettingsRepositoryInterface interface.
+func NewProjectCaptureSettingsRepository(db mongodb.SourceInterface) ProjectCaptureSettingsRepositoryInterface {
+	s := &projectCaptureSettingsRepository{db: db}
+	return s
+}
+
+func (r *projectCaptureSettingsRepository) Upsert(ctx context.Context, obj *intPkg.ProjectCaptureSettings) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"project_id": obj.ProjectId}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.db.Collection(collectionProjectCaptureSettings).ReplaceOne(ctx, filter, obj, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionProjectCaptureSettings),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *projectCaptureSettingsRepository) GetByProjectId(
+	ctx context.Context,
+	projectId string,
+) (*intPkg.ProjectCaptureSettings, error) {
+	oid, err := primitive.ObjectIDFromHex(projectId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionProjectCaptureSettings),
+			zap.String(pkg.ErrorDatabaseFieldQuery, projectId),
+		)
+		return nil, err
+	}
+
+	settings := &intPkg.ProjectCaptureSettings{}
+	query := bson.M{"project_id": oid}
+	err = r.db.Collection(collectionProjectCaptureSettings).FindOne(ctx, query).Decode(settings)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionProjectCaptureSettings),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return settings, nil
+}
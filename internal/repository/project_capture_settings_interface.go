@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// ProjectCaptureSettingsRepositoryInterface is abstraction layer for working with the capture mode of payments
+// of the project orders.
+type ProjectCaptureSettingsRepositoryInterface interface {
+	// Upsert adds or replaces the settings of the project.
+	Upsert(context.Context, *intPkg.ProjectCaptureSettings) error
+
+	// GetByProjectId returns the settings of the project or nil if the project doesn't have own settings.
+	GetByProjectId(context.Context, string) (*intPkg.ProjectCaptureSettings, error)
+}
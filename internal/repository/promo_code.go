@@ -0,0 +1,292 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionPromoCode = "promo_code"
+)
+
+type promoCodeRepository repository
+
+// NewPromoCodeRepository create and return an object for working with the promo code repository.
+// The returned object implements the PromoCodeRepositoryInterface interface.
+func NewPromoCodeRepository(db mongodb.SourceInterface) PromoCodeRepositoryInterface {
+	s := &promoCodeRepository{db: db}
+	return s
+}
+
+func (r *promoCodeRepository) Insert(ctx context.Context, obj *intPkg.PromoCode) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = obj.CreatedAt
+	_, err := r.db.Collection(collectionPromoCode).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPromoCode),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *promoCodeRepository) Update(ctx context.Context, obj *intPkg.PromoCode) error {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id}
+	// reserved orders are changed only by the conditional updates, so they aren't overwritten by the stale value
+	set := bson.M{
+		"$set": bson.M{
+			"merchant_id":           obj.MerchantId,
+			"project_id":            obj.ProjectId,
+			"code":                  obj.Code,
+			"type":                  obj.Type,
+			"percent":               obj.Percent,
+			"amounts":               obj.Amounts,
+			"product_ids":           obj.ProductIds,
+			"starts_at":             obj.StartsAt,
+			"expires_at":            obj.ExpiresAt,
+			"max_uses":              obj.MaxUses,
+			"max_uses_per_customer": obj.MaxUsesPerCustomer,
+			"is_active":             obj.IsActive,
+			"updated_at":            obj.UpdatedAt,
+		},
+	}
+	_, err := r.db.Collection(collectionPromoCode).UpdateOne(ctx, filter, set)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPromoCode),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *promoCodeRepository) GetById(ctx context.Context, id string) (*intPkg.PromoCode, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPromoCode),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return nil, err
+	}
+
+	promoCode := &intPkg.PromoCode{}
+	query := bson.M{"_id": oid}
+	err = r.db.Collection(collectionPromoCode).FindOne(ctx, query).Decode(promoCode)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPromoCode),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return promoCode, nil
+}
+
+func (r *promoCodeRepository) GetByCode(ctx context.Context, projectId, code string) (*intPkg.PromoCode, error) {
+	oid, err := primitive.ObjectIDFromHex(projectId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPromoCode),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, projectId),
+		)
+		return nil, err
+	}
+
+	promoCode := &intPkg.PromoCode{}
+	query := bson.M{"project_id": oid, "code": code}
+	err = r.db.Collection(collectionPromoCode).FindOne(ctx, query).Decode(promoCode)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPromoCode),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return promoCode, nil
+}
+
+func (r *promoCodeRepository) GetByProjectId(
+	ctx context.Context,
+	projectId string,
+) ([]*intPkg.PromoCode, error) {
+	oid, err := primitive.ObjectIDFromHex(projectId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPromoCode),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, projectId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"project_id": oid}
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+	cursor, err := r.db.Collection(collectionPromoCode).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPromoCode),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.PromoCode
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPromoCode),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *promoCodeRepository) ReserveOrder(
+	ctx context.Context,
+	obj *intPkg.PromoCode,
+	orderId, customerId string,
+) (bool, error) {
+	limits := bson.M{}
+
+	if obj.MaxUses > 0 {
+		limits[fmt.Sprintf("reserved_order_ids.%d", obj.MaxUses-1)] = bson.M{"$exists": false}
+	}
+
+	// uses of the customer are counted by the database, so concurrent orders of the customer can't exceed the limit
+	if obj.MaxUsesPerCustomer > 0 && customerId != "" {
+		limits["$expr"] = bson.M{
+			"$lt": []interface{}{
+				bson.M{
+					"$size": bson.M{
+						"$filter": bson.M{
+							"input": bson.M{"$ifNull": []interface{}{"$reservations", bson.A{}}},
+							"cond":  bson.M{"$eq": []interface{}{"$$this.customer_id", customerId}},
+						},
+					},
+				},
+				obj.MaxUsesPerCustomer,
+			},
+		}
+	}
+
+	// order is added if it's reserved already or the usage limits of the promo code aren't reached
+	filter := bson.M{
+		"_id": obj.Id,
+		"$or": []bson.M{
+			{"reserved_order_ids": orderId},
+			limits,
+		},
+	}
+	set := bson.M{
+		"$addToSet": bson.M{
+			"reserved_order_ids": orderId,
+			"reservations":       &intPkg.PromoCodeUse{OrderId: orderId, CustomerId: customerId},
+		},
+	}
+	res, err := r.db.Collection(collectionPromoCode).UpdateOne(ctx, filter, set)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPromoCode),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, set),
+		)
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+func (r *promoCodeRepository) ReleaseOrder(ctx context.Context, id, orderId string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPromoCode),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return err
+	}
+
+	filter := bson.M{"_id": oid}
+	set := bson.M{
+		"$pull": bson.M{
+			"reserved_order_ids": orderId,
+			"reservations":       bson.M{"order_id": orderId},
+		},
+	}
+	_, err = r.db.Collection(collectionPromoCode).UpdateOne(ctx, filter, set)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionPromoCode),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, set),
+		)
+		return err
+	}
+
+	return nil
+}
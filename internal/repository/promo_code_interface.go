@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// PromoCodeRepositoryInterface is abstraction layer for working with promo codes of projects.
+type PromoCodeRepositoryInterface interface {
+	// Insert adds the promo code to the collection.
+	Insert(context.Context, *intPkg.PromoCode) error
+
+	// Update updates the promo code in the collection.
+	Update(context.Context, *intPkg.PromoCode) error
+
+	// GetById returns the promo code by unique identifier.
+	GetById(context.Context, string) (*intPkg.PromoCode, error)
+
+	// GetByCode returns the promo code of the project by the code.
+	GetByCode(ctx context.Context, projectId, code string) (*intPkg.PromoCode, error)
+
+	// GetByProjectId returns all promo codes of the project.
+	GetByProjectId(context.Context, string) ([]*intPkg.PromoCode, error)
+
+	// ReserveOrder adds the order of the customer to orders reserved the promo code if the usage limit and the usage
+	// limit per customer aren't reached yet or the order is reserved already. Returns false if a limit is reached.
+	ReserveOrder(ctx context.Context, promoCode *intPkg.PromoCode, orderId, customerId string) (bool, error)
+
+	// ReleaseOrder removes the order from orders reserved the promo code.
+	ReleaseOrder(ctx context.Context, id, orderId string) error
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionReconciliationItem = "reconciliation_item"
+)
+
+type reconciliationItemRepository repository
+
+// NewReconciliationItemRepository create and return an object for working with the reconciliation item repository.
+// The returned object implements the ReconciliationItemRepositoryInterface interface.
+func NewReconciliationItemRepository(db mongodb.SourceInterface) ReconciliationItemRepositoryInterface {
+	s := &reconciliationItemRepository{db: db}
+	return s
+}
+
+func (r *reconciliationItemRepository) MultipleInsert(ctx context.Context, objs []*intPkg.ReconciliationItem) error {
+	if len(objs) <= 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(objs))
+
+	for i, obj := range objs {
+		if obj.Id.IsZero() {
+			obj.Id = primitive.NewObjectID()
+		}
+
+		if obj.CreatedAt.IsZero() {
+			obj.CreatedAt = time.Now()
+		}
+
+		docs[i] = obj
+	}
+
+	_, err := r.db.Collection(collectionReconciliationItem).InsertMany(ctx, docs)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationItem),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, docs),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *reconciliationItemRepository) Update(ctx context.Context, obj *intPkg.ReconciliationItem) error {
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionReconciliationItem).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationItem),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *reconciliationItemRepository) GetById(ctx context.Context, id string) (*intPkg.ReconciliationItem, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationItem),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return nil, err
+	}
+
+	item := &intPkg.ReconciliationItem{}
+	query := bson.M{"_id": oid}
+	err = r.db.Collection(collectionReconciliationItem).FindOne(ctx, query).Decode(item)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationItem),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (r *reconciliationItemRepository) Find(
+	ctx context.Context,
+	runId, status string,
+	offset, limit int64,
+) ([]*intPkg.ReconciliationItem, error) {
+	query, err := r.getFindQuery(runId, status)
+
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"line": 1}).
+		SetLimit(limit).
+		SetSkip(offset)
+	cursor, err := r.db.Collection(collectionReconciliationItem).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationItem),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.ReconciliationItem
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationItem),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *reconciliationItemRepository) FindCount(ctx context.Context, runId, status string) (int64, error) {
+	query, err := r.getFindQuery(runId, status)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return r.count(ctx, query)
+}
+
+func (r *reconciliationItemRepository) CountUnresolved(ctx context.Context, runId string) (int64, error) {
+	query, err := r.getFindQuery(runId, "")
+
+	if err != nil {
+		return 0, err
+	}
+
+	query["status"] = bson.M{"$ne": pkg.ReconciliationItemStatusMatched}
+	query["is_resolved"] = false
+
+	return r.count(ctx, query)
+}
+
+func (r *reconciliationItemRepository) getFindQuery(runId, status string) (bson.M, error) {
+	oid, err := primitive.ObjectIDFromHex(runId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationItem),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, runId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"run_id": oid}
+
+	if status != "" {
+		query["status"] = status
+	}
+
+	return query, nil
+}
+
+func (r *reconciliationItemRepository) count(ctx context.Context, query bson.M) (int64, error) {
+	count, err := r.db.Collection(collectionReconciliationItem).CountDocuments(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationItem),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationCount),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// ReconciliationItemRepositoryInterface is abstraction layer for working with items of reconciliation runs
+// and representation in database.
+type ReconciliationItemRepositoryInterface interface {
+	// MultipleInsert adds the multiple reconciliation items to the collection.
+	MultipleInsert(context.Context, []*intPkg.ReconciliationItem) error
+
+	// Update updates the reconciliation item in the collection.
+	Update(context.Context, *intPkg.ReconciliationItem) error
+
+	// GetById returns the reconciliation item by unique identifier.
+	GetById(context.Context, string) (*intPkg.ReconciliationItem, error)
+
+	// Find returns items of the reconciliation run by status with pagination in order of lines of the report.
+	Find(context.Context, string, string, int64, int64) ([]*intPkg.ReconciliationItem, error)
+
+	// FindCount returns count of items of the reconciliation run by status.
+	FindCount(context.Context, string, string) (int64, error)
+
+	// CountUnresolved returns count of unmatched items of the reconciliation run which aren't resolved yet.
+	CountUnresolved(context.Context, string) (int64, error)
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionReconciliationRun = "reconciliation_run"
+)
+
+type reconciliationRunRepository repository
+
+// NewReconciliationRunRepository create and return an object for working with the reconciliation run repository.
+// The returned object implements the ReconciliationRunRepositoryInterface interface.
+func NewReconciliationRunRepository(db mongodb.SourceInterface) ReconciliationRunRepositoryInterface {
+	s := &reconciliationRunRepository{db: db}
+	return s
+}
+
+func (r *reconciliationRunRepository) Insert(ctx context.Context, obj *intPkg.ReconciliationRun) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = obj.CreatedAt
+	_, err := r.db.Collection(collectionReconciliationRun).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationRun),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *reconciliationRunRepository) Update(ctx context.Context, obj *intPkg.ReconciliationRun) error {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionReconciliationRun).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationRun),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *reconciliationRunRepository) GetById(ctx context.Context, id string) (*intPkg.ReconciliationRun, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationRun),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return nil, err
+	}
+
+	run := &intPkg.ReconciliationRun{}
+	query := bson.M{"_id": oid}
+	err = r.db.Collection(collectionReconciliationRun).FindOne(ctx, query).Decode(run)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationRun),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return run, nil
+}
+
+func (r *reconciliationRunRepository) GetByReportId(
+	ctx context.Context,
+	paymentSystem, reportId string,
+) (*intPkg.ReconciliationRun, error) {
+	run := &intPkg.ReconciliationRun{}
+	query := bson.M{"payment_system": paymentSystem, "report_id": reportId}
+	err := r.db.Collection(collectionReconciliationRun).FindOne(ctx, query).Decode(run)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationRun),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return run, nil
+}
+
+func (r *reconciliationRunRepository) Find(
+	ctx context.Context,
+	paymentSystem, status string,
+	offset, limit int64,
+) ([]*intPkg.ReconciliationRun, error) {
+	query := r.getFindQuery(paymentSystem, status)
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetLimit(limit).
+		SetSkip(offset)
+	cursor, err := r.db.Collection(collectionReconciliationRun).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationRun),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.ReconciliationRun
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationRun),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *reconciliationRunRepository) FindCount(ctx context.Context, paymentSystem, status string) (int64, error) {
+	query := r.getFindQuery(paymentSystem, status)
+	count, err := r.db.Collection(collectionReconciliationRun).CountDocuments(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionReconciliationRun),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationCount),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *reconciliationRunRepository) getFindQuery(paymentSystem, status string) bson.M {
+	query := make(bson.M)
+
+	if paymentSystem != "" {
+		query["payment_system"] = paymentSystem
+	}
+
+	if status != "" {
+		query["status"] = status
+	}
+
+	return query
+}
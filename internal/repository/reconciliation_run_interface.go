@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// ReconciliationRunRepositoryInterface is abstraction layer for working with reconciliation runs of settlement
+// reports of payment systems and representation in database.
+type ReconciliationRunRepositoryInterface interface {
+	// Insert adds the reconciliation run to the collection.
+	Insert(context.Context, *intPkg.ReconciliationRun) error
+
+	// Update updates the reconciliation run in the collection.
+	Update(context.Context, *intPkg.ReconciliationRun) error
+
+	// GetById returns the reconciliation run by unique identifier.
+	GetById(context.Context, string) (*intPkg.ReconciliationRun, error)
+
+	// GetByReportId returns the reconciliation run by the payment system handler and the settlement report
+	// identifier. It returns nil if the report wasn't imported.
+	GetByReportId(context.Context, string, string) (*intPkg.ReconciliationRun, error)
+
+	// Find returns reconciliation runs by payment system handler and status with pagination, the newest runs go first.
+	Find(context.Context, string, string, int64, int64) ([]*intPkg.ReconciliationRun, error)
+
+	// FindCount returns count of reconciliation runs by payment system handler and status.
+	FindCount(context.Context, string, string) (int64, error)
+}
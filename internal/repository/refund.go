@@ -7,16 +7,29 @@ import (
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
 )
 
 const (
 	// CollectionRefund is name of table for collection the refund.
 	CollectionRefund = "refund"
+
+	collectionRefundOrderSequence = "refund_order_sequence"
 )
 
+// refundReservedStatuses are statuses of refunds which amount and order lines aren't available to refund again.
+var refundReservedStatuses = []int32{
+	pkg.RefundStatusCreated,
+	pkg.RefundStatusInProgress,
+	pkg.RefundStatusCompleted,
+	pkg.RefundStatusPendingApproval,
+	pkg.RefundStatusFallbackInProgress,
+}
+
 type refundRepository repository
 
 // NewRefundRepository create and return an object for working with the refund repository.
@@ -134,6 +147,34 @@ func (h *refundRepository) GetById(ctx context.Context, id string) (*billingpb.R
 	return obj.(*billingpb.Refund), nil
 }
 
+func (h *refundRepository) GetByExternalId(ctx context.Context, id string) (*billingpb.Refund, error) {
+	mgo := &models.MgoRefund{}
+	query := bson.M{"external_id": id}
+	err := h.db.Collection(CollectionRefund).FindOne(ctx, query).Decode(mgo)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, CollectionRefund),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	obj, err := h.mapper.MapMgoToObject(mgo)
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseMapModelFailed,
+			zap.Error(err),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, mgo),
+		)
+		return nil, err
+	}
+
+	return obj.(*billingpb.Refund), nil
+}
+
 func (h *refundRepository) FindByOrderUuid(ctx context.Context, id string, limit int64, offset int64) ([]*billingpb.Refund, error) {
 	query := bson.M{"original_order.uuid": id}
 	opts := options.Find().
@@ -199,6 +240,23 @@ func (h *refundRepository) CountByOrderUuid(ctx context.Context, id string) (int
 }
 
 func (h *refundRepository) GetAmountByOrderId(ctx context.Context, orderId string) (float64, error) {
+	statuses := []int32{pkg.RefundStatusCreated, pkg.RefundStatusInProgress, pkg.RefundStatusCompleted}
+	return h.getAmountByOrderIdAndStatuses(ctx, orderId, statuses)
+}
+
+func (h *refundRepository) GetReservedAmountByOrderId(ctx context.Context, orderId string) (float64, error) {
+	return h.getAmountByOrderIdAndStatuses(ctx, orderId, refundReservedStatuses)
+}
+
+func (h *refundRepository) GetCompletedAmountByOrderId(ctx context.Context, orderId string) (float64, error) {
+	return h.getAmountByOrderIdAndStatuses(ctx, orderId, []int32{pkg.RefundStatusCompleted})
+}
+
+func (h *refundRepository) getAmountByOrderIdAndStatuses(
+	ctx context.Context,
+	orderId string,
+	statuses []int32,
+) (float64, error) {
 	var res struct {
 		Amount float64 `bson:"amount"`
 	}
@@ -207,11 +265,7 @@ func (h *refundRepository) GetAmountByOrderId(ctx context.Context, orderId strin
 	query := []bson.M{
 		{
 			"$match": bson.M{
-				"status": bson.M{"$in": []int32{
-					pkg.RefundStatusCreated,
-					pkg.RefundStatusInProgress,
-					pkg.RefundStatusCompleted,
-				}},
+				"status":            bson.M{"$in": statuses},
 				"original_order.id": oid,
 			},
 		},
@@ -248,3 +302,59 @@ func (h *refundRepository) GetAmountByOrderId(ctx context.Context, orderId strin
 
 	return res.Amount, nil
 }
+
+func (h *refundRepository) GetOrderSequence(ctx context.Context, orderId string) (int64, error) {
+	var res struct {
+		Sequence int64 `bson:"sequence"`
+	}
+
+	oid, _ := primitive.ObjectIDFromHex(orderId)
+	query := bson.M{"_id": oid}
+	err := h.db.Collection(collectionRefundOrderSequence).FindOne(ctx, query).Decode(&res)
+
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundOrderSequence),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return 0, err
+	}
+
+	return res.Sequence, nil
+}
+
+func (h *refundRepository) IncrementOrderSequence(ctx context.Context, orderId string, sequence int64) (bool, error) {
+	oid, _ := primitive.ObjectIDFromHex(orderId)
+	filter := bson.M{"_id": oid, "sequence": sequence}
+	update := bson.M{
+		"$inc": bson.M{"sequence": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	// the first refund of the order creates the counter, the concurrent one is rejected by the duplicate identifier
+	opts := options.Update().SetUpsert(sequence == 0)
+	res, err := h.db.Collection(collectionRefundOrderSequence).UpdateOne(ctx, filter, update, opts)
+
+	if mongodb.IsDuplicate(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundOrderSequence),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+			zap.Any(pkg.ErrorDatabaseFieldSet, update),
+		)
+		return false, err
+	}
+
+	return res.MatchedCount > 0 || res.UpsertedCount > 0, nil
+}
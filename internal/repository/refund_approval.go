@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionRefundApproval = "refund_approval"
+)
+
+type refundApprovalRepository repository
+
+// NewRefundApprovalRepository create and return an object for working with the refund approval repository.
+// The returned object implements the RefundApprovalRepositoryInterface interface.
+func NewRefundApprovalRepository(db mongodb.SourceInterface) RefundApprovalRepositoryInterface {
+	s := &refundApprovalRepository{db: db}
+	return s
+}
+
+func (r *refundApprovalRepository) Insert(ctx context.Context, obj *intPkg.RefundApproval) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = obj.CreatedAt
+	_, err := r.db.Collection(collectionRefundApproval).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundApproval),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *refundApprovalRepository) Update(ctx context.Context, obj *intPkg.RefundApproval) error {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionRefundApproval).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundApproval),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *refundApprovalRepository) UpdateFromStatus(
+	ctx context.Context,
+	obj *intPkg.RefundApproval,
+	status string,
+) (bool, error) {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id, "status": status}
+	res, err := r.db.Collection(collectionRefundApproval).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundApproval),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+		)
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+func (r *refundApprovalRepository) GetByRefundId(ctx context.Context, refundId string) (*intPkg.RefundApproval, error) {
+	oid, err := primitive.ObjectIDFromHex(refundId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundApproval),
+			zap.String(pkg.ErrorDatabaseFieldQuery, refundId),
+		)
+		return nil, err
+	}
+
+	approval := &intPkg.RefundApproval{}
+	query := bson.M{"refund_id": oid}
+	err = r.db.Collection(collectionRefundApproval).FindOne(ctx, query).Decode(approval)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundApproval),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return approval, nil
+}
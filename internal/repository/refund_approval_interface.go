@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// RefundApprovalRepositoryInterface is abstraction layer for working with approvals of refunds by merchant users.
+type RefundApprovalRepositoryInterface interface {
+	// Insert adds the refund approval to the collection.
+	Insert(context.Context, *intPkg.RefundApproval) error
+
+	// Update updates the refund approval in the collection.
+	Update(context.Context, *intPkg.RefundApproval) error
+
+	// UpdateFromStatus updates the refund approval if it's still in the status. Returns false if the status of
+	// the approval was changed by the concurrent request.
+	UpdateFromStatus(ctx context.Context, approval *intPkg.RefundApproval, status string) (bool, error)
+
+	// GetByRefundId returns the approval of the refund.
+	GetByRefundId(context.Context, string) (*intPkg.RefundApproval, error)
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionRefundApprovalSettings = "refund_approval_settings"
+)
+
+type refundApprovalSettingsRepository repository
+
+// NewRefundApprovalSettingsRepository create and return an object for working with the refund approval settings
+// repository. The returned object implements the RefundApprovalSettingsRepositoryInterface interface.
+func NewRefundApprovalSettingsRepository(db mongodb.SourceInterface) RefundApprovalSettingsRepositoryInterface {
+	s := &refundApprovalSettingsRepository{db: db}
+	return s
+}
+
+func (r *refundApprovalSettingsRepository) Upsert(ctx context.Context, obj *intPkg.RefundApprovalSettings) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"merchant_id": obj.MerchantId}
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.db.Collection(collectionRefundApprovalSettings).ReplaceOne(ctx, filter, obj, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundApprovalSettings),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *refundApprovalSettingsRepository) GetByMerchantId(
+	ctx context.Context,
+	merchantId string,
+) (*intPkg.RefundApprovalSettings, error) {
+	oid, err := primitive.ObjectIDFromHex(merchantId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundApprovalSettings),
+			zap.String(pkg.ErrorDatabaseFieldQuery, merchantId),
+		)
+		return nil, err
+	}
+
+	settings := &intPkg.RefundApprovalSettings{}
+	query := bson.M{"merchant_id": oid}
+	err = r.db.Collection(collectionRefundApprovalSettings).FindOne(ctx, query).Decode(settings)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundApprovalSettings),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return settings, nil
+}
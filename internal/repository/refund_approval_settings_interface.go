@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// RefundApprovalSettingsRepositoryInterface is abstraction layer for working with thresholds of refunds which
+// require the approval of merchant users.
+type RefundApprovalSettingsRepositoryInterface interface {
+	// Upsert adds or replaces the settings of the merchant.
+	Upsert(context.Context, *intPkg.RefundApprovalSettings) error
+
+	// GetByMerchantId returns the settings of the merchant.
+	GetByMerchantId(context.Context, string) (*intPkg.RefundApprovalSettings, error)
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionRefundFallback = "refund_fallback"
+)
+
+type refundFallbackRepository repository
+
+// NewRefundFallbackRepository create and return an object for working with the refund fallback repository.
+// The returned object implements the RefundFallbackRepositoryInterface interface.
+func NewRefundFallbackRepository(db mongodb.SourceInterface) RefundFallbackRepositoryInterface {
+	s := &refundFallbackRepository{db: db}
+	return s
+}
+
+func (r *refundFallbackRepository) Insert(ctx context.Context, obj *intPkg.RefundFallback) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = obj.CreatedAt
+	_, err := r.db.Collection(collectionRefundFallback).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundFallback),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *refundFallbackRepository) Update(ctx context.Context, obj *intPkg.RefundFallback) error {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionRefundFallback).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundFallback),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *refundFallbackRepository) UpdateFromStatus(
+	ctx context.Context,
+	obj *intPkg.RefundFallback,
+	status string,
+) (bool, error) {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id, "status": status}
+	res, err := r.db.Collection(collectionRefundFallback).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundFallback),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+		)
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+func (r *refundFallbackRepository) GetByToken(ctx context.Context, token string) (*intPkg.RefundFallback, error) {
+	fallback := &intPkg.RefundFallback{}
+	query := bson.M{"token": token}
+	err := r.db.Collection(collectionRefundFallback).FindOne(ctx, query).Decode(fallback)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundFallback),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return fallback, nil
+}
+
+func (r *refundFallbackRepository) GetByRefundId(ctx context.Context, refundId string) (*intPkg.RefundFallback, error) {
+	oid, err := primitive.ObjectIDFromHex(refundId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundFallback),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, refundId),
+		)
+		return nil, err
+	}
+
+	fallback := &intPkg.RefundFallback{}
+	query := bson.M{"refund_id": oid}
+	err = r.db.Collection(collectionRefundFallback).FindOne(ctx, query).Decode(fallback)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundFallback),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return fallback, nil
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// RefundFallbackRepositoryInterface is abstraction layer for working with refunds declined by the payment system
+// which are returned to the customer by other way.
+type RefundFallbackRepositoryInterface interface {
+	// Insert adds the refund fallback to the collection.
+	Insert(context.Context, *intPkg.RefundFallback) error
+
+	// Update updates the refund fallback in the collection.
+	Update(context.Context, *intPkg.RefundFallback) error
+
+	// UpdateFromStatus updates the refund fallback if it's still in the status. Returns false if the status of
+	// the fallback was changed by the concurrent request.
+	UpdateFromStatus(ctx context.Context, fallback *intPkg.RefundFallback, status string) (bool, error)
+
+	// GetByToken returns the refund fallback by the token of the link sent to the customer.
+	GetByToken(context.Context, string) (*intPkg.RefundFallback, error)
+
+	// GetByRefundId returns the fallback of the refund or nil if the refund has no fallback.
+	GetByRefundId(context.Context, string) (*intPkg.RefundFallback, error)
+}
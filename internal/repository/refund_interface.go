@@ -16,6 +16,9 @@ type RefundRepositoryInterface interface {
 	// GetById returns a refund by its identifier.
 	GetById(context.Context, string) (*billingpb.Refund, error)
 
+	// GetByExternalId returns a refund by the identifier of the refund in the payment system.
+	GetByExternalId(context.Context, string) (*billingpb.Refund, error)
+
 	// FindByOrderUuid returns a list of refunds by the public identifier of the purchase order.
 	FindByOrderUuid(context.Context, string, int64, int64) ([]*billingpb.Refund, error)
 
@@ -24,4 +27,18 @@ type RefundRepositoryInterface interface {
 
 	// GetAmountByOrderId returns the amount of refunds produced by order ID.
 	GetAmountByOrderId(context.Context, string) (float64, error)
+
+	// GetReservedAmountByOrderId returns the amount of refunds by order ID which isn't available to refund again,
+	// including refunds waiting for the approval and fallback refunds in progress.
+	GetReservedAmountByOrderId(context.Context, string) (float64, error)
+
+	// GetCompletedAmountByOrderId returns the amount of completed refunds by order ID.
+	GetCompletedAmountByOrderId(context.Context, string) (float64, error)
+
+	// GetOrderSequence returns the number of refunds created by order ID, it's zero for the order without refunds.
+	GetOrderSequence(context.Context, string) (int64, error)
+
+	// IncrementOrderSequence increments the number of refunds created by order ID if it's still equal to the number
+	// the refund is checked with. It returns false if other refund of the order was created in meantime.
+	IncrementOrderSequence(context.Context, string, int64) (bool, error)
 }
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionRefundItem = "refund_item"
+)
+
+type refundItemRepository repository
+
+// NewRefundItemRepository create and return an object for working with the refund item repository.
+// The returned object implements the RefundItemRepositoryInterface interface.
+func NewRefundItemRepository(db mongodb.SourceInterface) RefundItemRepositoryInterface {
+	s := &refundItemRepository{db: db}
+	return s
+}
+
+func (r *refundItemRepository) InsertMany(ctx context.Context, list []*intPkg.RefundItem) error {
+	docs := make([]interface{}, len(list))
+
+	for i, obj := range list {
+		if obj.Id.IsZero() {
+			obj.Id = primitive.NewObjectID()
+		}
+
+		if obj.CreatedAt.IsZero() {
+			obj.CreatedAt = time.Now()
+		}
+
+		docs[i] = obj
+	}
+
+	_, err := r.db.Collection(collectionRefundItem).InsertMany(ctx, docs)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundItem),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, list),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *refundItemRepository) FindByOrderId(ctx context.Context, orderId string) ([]*intPkg.RefundItem, error) {
+	return r.findByObjectId(ctx, "order_id", orderId)
+}
+
+func (r *refundItemRepository) FindByRefundId(ctx context.Context, refundId string) ([]*intPkg.RefundItem, error) {
+	return r.findByObjectId(ctx, "refund_id", refundId)
+}
+
+func (r *refundItemRepository) FindReservedByOrderId(ctx context.Context, orderId string) ([]*intPkg.RefundItem, error) {
+	oid, err := primitive.ObjectIDFromHex(orderId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundItem),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, orderId),
+		)
+		return nil, err
+	}
+
+	query := []bson.M{
+		{"$match": bson.M{"order_id": oid}},
+		{
+			"$lookup": bson.M{
+				"from":         CollectionRefund,
+				"localField":   "refund_id",
+				"foreignField": "_id",
+				"as":           "refund",
+			},
+		},
+		{"$match": bson.M{"refund.status": bson.M{"$in": refundReservedStatuses}}},
+		{"$project": bson.M{"refund": 0}},
+		{"$sort": bson.M{"line": 1}},
+	}
+	cursor, err := r.db.Collection(collectionRefundItem).Aggregate(ctx, query)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundItem),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.RefundItem
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundItem),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *refundItemRepository) findByObjectId(
+	ctx context.Context,
+	field string,
+	id string,
+) ([]*intPkg.RefundItem, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundItem),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, id),
+		)
+		return nil, err
+	}
+
+	query := bson.M{field: oid}
+	opts := options.Find().SetSort(bson.M{"line": 1})
+	cursor, err := r.db.Collection(collectionRefundItem).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundItem),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.RefundItem
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionRefundItem),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
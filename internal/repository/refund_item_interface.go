@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// RefundItemRepositoryInterface is abstraction layer for working with order line items returned by refunds.
+type RefundItemRepositoryInterface interface {
+	// InsertMany adds the refund items to the collection.
+	InsertMany(context.Context, []*intPkg.RefundItem) error
+
+	// FindByOrderId returns the refund items of all refunds of the order.
+	FindByOrderId(context.Context, string) ([]*intPkg.RefundItem, error)
+
+	// FindReservedByOrderId returns the refund items of the order which aren't available to refund again, the items
+	// of the rejected and declined refunds are skipped.
+	FindReservedByOrderId(context.Context, string) ([]*intPkg.RefundItem, error)
+
+	// FindByRefundId returns the items returned by the refund.
+	FindByRefundId(context.Context, string) ([]*intPkg.RefundItem, error)
+}
@@ -452,3 +452,66 @@ func (suite *RefundTestSuite) TestRefund_GetAmountByOrderId_SkipRejectStatus() {
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), float64(0), amount)
 }
+
+func (suite *RefundTestSuite) TestRefund_GetReservedAndCompletedAmountByOrderId_Ok() {
+	orderId := primitive.NewObjectID().Hex()
+	statuses := map[int32]float64{
+		pkg.RefundStatusCompleted:          10,
+		pkg.RefundStatusInProgress:         20,
+		pkg.RefundStatusPendingApproval:    30,
+		pkg.RefundStatusFallbackInProgress: 40,
+		pkg.RefundStatusRejected:           50,
+	}
+
+	for status, amount := range statuses {
+		refund := &billingpb.Refund{
+			Id:            primitive.NewObjectID().Hex(),
+			CreatorId:     primitive.NewObjectID().Hex(),
+			OriginalOrder: &billingpb.RefundOrder{Id: orderId},
+			Status:        status,
+			Amount:        amount,
+		}
+		err := suite.repository.Insert(context.TODO(), refund)
+		assert.NoError(suite.T(), err)
+	}
+
+	amount, err := suite.repository.GetAmountByOrderId(context.TODO(), orderId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), float64(30), amount)
+
+	amount, err = suite.repository.GetReservedAmountByOrderId(context.TODO(), orderId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), float64(100), amount)
+
+	amount, err = suite.repository.GetCompletedAmountByOrderId(context.TODO(), orderId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), float64(10), amount)
+}
+
+func (suite *RefundTestSuite) TestRefund_IncrementOrderSequence_Ok() {
+	orderId := primitive.NewObjectID().Hex()
+
+	sequence, err := suite.repository.GetOrderSequence(context.TODO(), orderId)
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), sequence)
+
+	isIncremented, err := suite.repository.IncrementOrderSequence(context.TODO(), orderId, sequence)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), isIncremented)
+
+	isIncremented, err = suite.repository.IncrementOrderSequence(context.TODO(), orderId, sequence)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), isIncremented)
+
+	sequence, err = suite.repository.GetOrderSequence(context.TODO(), orderId)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1, sequence)
+
+	isIncremented, err = suite.repository.IncrementOrderSequence(context.TODO(), orderId, sequence)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), isIncremented)
+
+	isIncremented, err = suite.repository.IncrementOrderSequence(context.TODO(), orderId, sequence)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), isIncremented)
+}
@@ -69,11 +69,14 @@ func (r *royaltyReportRepository) GetNonPayoutReports(
 	}
 	query := bson.M{
 		"merchant_id":        oid,
-		"currency":           currency,
 		"status":             bson.M{"$in": royaltyReportsStatusActive},
 		"payout_document_id": "",
 	}
 
+	if currency != "" {
+		query["currency"] = currency
+	}
+
 	sorts := bson.M{"period_from": 1}
 	opts := options.Find().SetSort(sorts)
 	cursor, err := r.db.Collection(CollectionRoyaltyReport).Find(ctx, query, opts)
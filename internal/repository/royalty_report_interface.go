@@ -22,7 +22,8 @@ type RoyaltyReportRepositoryInterface interface {
 	// GetById returns the royalty report by unique identity.
 	GetById(ctx context.Context, id string) (*billingpb.RoyaltyReport, error)
 
-	// GetNonPayoutReports returns the royalty report by unique identity.
+	// GetNonPayoutReports returns the royalty reports of the merchant which aren't included to payouts yet.
+	// Reports in all currencies are returned if the currency is empty.
 	GetNonPayoutReports(ctx context.Context, merchantId, currency string) ([]*billingpb.RoyaltyReport, error)
 
 	// GetByPayoutId returns the royalty report by payout identity.
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionSavedCardUpdate = "saved_card_update"
+)
+
+type savedCardUpdateRepository repository
+
+// NewSavedCardUpdateRepository create and return an object for working with the saved card update repository.
+// The returned object implements the SavedCardUpdateRepositoryInterface interface.
+func NewSavedCardUpdateRepository(db mongodb.SourceInterface) SavedCardUpdateRepositoryInterface {
+	s := &savedCardUpdateRepository{db: db}
+	return s
+}
+
+func (r *savedCardUpdateRepository) Insert(ctx context.Context, obj *intPkg.SavedCardUpdate) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = obj.CreatedAt
+	_, err := r.db.Collection(collectionSavedCardUpdate).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSavedCardUpdate),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *savedCardUpdateRepository) Update(ctx context.Context, obj *intPkg.SavedCardUpdate) error {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionSavedCardUpdate).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSavedCardUpdate),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *savedCardUpdateRepository) GetById(ctx context.Context, id string) (*intPkg.SavedCardUpdate, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSavedCardUpdate),
+			zap.String(pkg.ErrorDatabaseFieldQuery, id),
+		)
+		return nil, err
+	}
+
+	return r.getOneBy(ctx, bson.M{"_id": oid})
+}
+
+func (r *savedCardUpdateRepository) GetByToken(ctx context.Context, token string) (*intPkg.SavedCardUpdate, error) {
+	return r.getOneBy(ctx, bson.M{"token": token})
+}
+
+func (r *savedCardUpdateRepository) GetBySavedCardId(
+	ctx context.Context,
+	savedCardId string,
+) (*intPkg.SavedCardUpdate, error) {
+	return r.getOneBy(ctx, bson.M{"saved_card_id": savedCardId})
+}
+
+func (r *savedCardUpdateRepository) getOneBy(ctx context.Context, query bson.M) (*intPkg.SavedCardUpdate, error) {
+	update := &intPkg.SavedCardUpdate{}
+	err := r.db.Collection(collectionSavedCardUpdate).FindOne(ctx, query).Decode(update)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSavedCardUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return update, nil
+}
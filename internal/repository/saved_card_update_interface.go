@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// SavedCardUpdateRepositoryInterface is abstraction layer for working with requests to update expiring saved cards.
+type SavedCardUpdateRepositoryInterface interface {
+	// Insert adds the card update to the collection.
+	Insert(context.Context, *intPkg.SavedCardUpdate) error
+
+	// Update updates the card update in the collection.
+	Update(context.Context, *intPkg.SavedCardUpdate) error
+
+	// GetById returns the card update by unique identifier.
+	GetById(context.Context, string) (*intPkg.SavedCardUpdate, error)
+
+	// GetByToken returns the card update by the token of the secure link.
+	GetByToken(context.Context, string) (*intPkg.SavedCardUpdate, error)
+
+	// GetBySavedCardId returns the card update of the saved card.
+	GetBySavedCardId(context.Context, string) (*intPkg.SavedCardUpdate, error)
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionStoreCredit = "store_credit"
+)
+
+type storeCreditRepository repository
+
+// NewStoreCreditRepository create and return an object for working with the store credit repository.
+// The returned object implements the StoreCreditRepositoryInterface interface.
+func NewStoreCreditRepository(db mongodb.SourceInterface) StoreCreditRepositoryInterface {
+	s := &storeCreditRepository{db: db}
+	return s
+}
+
+func (r *storeCreditRepository) Insert(ctx context.Context, obj *intPkg.StoreCredit) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	_, err := r.db.Collection(collectionStoreCredit).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionStoreCredit),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *storeCreditRepository) FindByCustomerId(
+	ctx context.Context,
+	customerId, projectId string,
+) ([]*intPkg.StoreCredit, error) {
+	oid, err := primitive.ObjectIDFromHex(projectId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionStoreCredit),
+			zap.String(pkg.ErrorDatabaseFieldDocumentId, projectId),
+		)
+		return nil, err
+	}
+
+	query := bson.M{"customer_id": customerId, "project_id": oid}
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+	cursor, err := r.db.Collection(collectionStoreCredit).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionStoreCredit),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.StoreCredit
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionStoreCredit),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r *storeCreditRepository) GetBySourceId(
+	ctx context.Context,
+	source, sourceId string,
+) (*intPkg.StoreCredit, error) {
+	credit := &intPkg.StoreCredit{}
+	query := bson.M{"source": source, "source_id": sourceId}
+	err := r.db.Collection(collectionStoreCredit).FindOne(ctx, query).Decode(credit)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionStoreCredit),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return credit, nil
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// StoreCreditRepositoryInterface is abstraction layer for working with store credits of customers.
+type StoreCreditRepositoryInterface interface {
+	// Insert adds the store credit to the collection.
+	Insert(context.Context, *intPkg.StoreCredit) error
+
+	// FindByCustomerId returns store credits of the customer by the customer and the project identifiers,
+	// the oldest credits go first.
+	FindByCustomerId(context.Context, string, string) ([]*intPkg.StoreCredit, error)
+
+	// GetBySourceId returns the store credit by the source and the source identifier or nil if it isn't found.
+	GetBySourceId(context.Context, string, string) (*intPkg.StoreCredit, error)
+}
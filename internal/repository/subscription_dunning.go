@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionSubscriptionDunning = "subscription_dunning"
+)
+
+var (
+	subscriptionDunningOpenStatuses = []string{
+		pkg.SubscriptionDunningStatusPastDue,
+		pkg.SubscriptionDunningStatusUnpaid,
+	}
+)
+
+type subscriptionDunningRepository repository
+
+// NewSubscriptionDunningRepository create and return an object for working with the subscription dunning repository.
+// The returned object implements the SubscriptionDunningRepositoryInterface interface.
+func NewSubscriptionDunningRepository(db mongodb.SourceInterface) SubscriptionDunningRepositoryInterface {
+	s := &subscriptionDunningRepository{db: db}
+	return s
+}
+
+func (r *subscriptionDunningRepository) Insert(ctx context.Context, obj *intPkg.SubscriptionDunning) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = obj.CreatedAt
+	_, err := r.db.Collection(collectionSubscriptionDunning).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionDunning),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *subscriptionDunningRepository) Update(ctx context.Context, obj *intPkg.SubscriptionDunning) error {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionSubscriptionDunning).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionDunning),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *subscriptionDunningRepository) UpdateFromState(
+	ctx context.Context,
+	obj *intPkg.SubscriptionDunning,
+	status string,
+	attempts int32,
+	retryOrderId string,
+) (bool, error) {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id, "status": status, "attempts": attempts, "retry_order_id": retryOrderId}
+	res, err := r.db.Collection(collectionSubscriptionDunning).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionDunning),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, filter),
+		)
+		return false, err
+	}
+
+	return res.MatchedCount > 0, nil
+}
+
+func (r *subscriptionDunningRepository) GetById(ctx context.Context, id string) (*intPkg.SubscriptionDunning, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionDunning),
+			zap.String(pkg.ErrorDatabaseFieldQuery, id),
+		)
+		return nil, err
+	}
+
+	return r.findOne(ctx, bson.M{"_id": oid})
+}
+
+func (r *subscriptionDunningRepository) GetOpenBySubscriptionId(
+	ctx context.Context,
+	subscriptionId string,
+) (*intPkg.SubscriptionDunning, error) {
+	query := bson.M{
+		"subscription_id": subscriptionId,
+		"status":          bson.M{"$in": subscriptionDunningOpenStatuses},
+	}
+
+	return r.findOne(ctx, query)
+}
+
+func (r *subscriptionDunningRepository) FindBySubscriptionId(
+	ctx context.Context,
+	subscriptionId string,
+) ([]*intPkg.SubscriptionDunning, error) {
+	query := bson.M{"subscription_id": subscriptionId}
+
+	return r.find(ctx, query, options.Find().SetSort(bson.M{"created_at": -1}))
+}
+
+func (r *subscriptionDunningRepository) FindDue(ctx context.Context, date time.Time) ([]*intPkg.SubscriptionDunning, error) {
+	query := bson.M{
+		"status":          bson.M{"$in": subscriptionDunningOpenStatuses},
+		"next_attempt_at": bson.M{"$lte": date},
+	}
+
+	return r.find(ctx, query, options.Find().SetSort(bson.M{"next_attempt_at": 1}))
+}
+
+func (r *subscriptionDunningRepository) findOne(ctx context.Context, query bson.M) (*intPkg.SubscriptionDunning, error) {
+	dunning := &intPkg.SubscriptionDunning{}
+	err := r.db.Collection(collectionSubscriptionDunning).FindOne(ctx, query).Decode(dunning)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionDunning),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return dunning, nil
+}
+
+func (r *subscriptionDunningRepository) find(
+	ctx context.Context,
+	query bson.M,
+	opts *options.FindOptions,
+) ([]*intPkg.SubscriptionDunning, error) {
+	cursor, err := r.db.Collection(collectionSubscriptionDunning).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionDunning),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.SubscriptionDunning
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionDunning),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
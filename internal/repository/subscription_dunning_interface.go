@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"time"
+)
+
+// SubscriptionDunningRepositoryInterface is abstraction layer for working with dunning of failed payments of recurring
+// subscriptions.
+type SubscriptionDunningRepositoryInterface interface {
+	// Insert adds the dunning to the collection.
+	Insert(context.Context, *intPkg.SubscriptionDunning) error
+
+	// Update updates the dunning in the collection.
+	Update(context.Context, *intPkg.SubscriptionDunning) error
+
+	// UpdateFromState updates the dunning in the collection if its status, number of attempts and retry order are
+	// still equal to the passed ones. It returns false if the dunning was changed in meantime.
+	UpdateFromState(context.Context, *intPkg.SubscriptionDunning, string, int32, string) (bool, error)
+
+	// GetById returns the dunning by unique identity.
+	GetById(context.Context, string) (*intPkg.SubscriptionDunning, error)
+
+	// GetOpenBySubscriptionId returns the past due or unpaid dunning of the recurring subscription.
+	GetOpenBySubscriptionId(context.Context, string) (*intPkg.SubscriptionDunning, error)
+
+	// FindBySubscriptionId returns all dunning of the recurring subscription, the latest dunning goes first.
+	FindBySubscriptionId(context.Context, string) ([]*intPkg.SubscriptionDunning, error)
+
+	// FindDue returns past due and unpaid dunning with the next attempt before the date.
+	FindDue(context.Context, time.Time) ([]*intPkg.SubscriptionDunning, error)
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionSubscriptionPause = "subscription_pause"
+)
+
+type subscriptionPauseRepository repository
+
+// NewSubscriptionPauseRepository create and return an object for working with the subscription pause repository.
+// The returned object implements the SubscriptionPauseRepositoryInterface interface.
+func NewSubscriptionPauseRepository(db mongodb.SourceInterface) SubscriptionPauseRepositoryInterface {
+	s := &subscriptionPauseRepository{db: db}
+	return s
+}
+
+func (r *subscriptionPauseRepository) Insert(ctx context.Context, obj *intPkg.SubscriptionPause) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = obj.CreatedAt
+	_, err := r.db.Collection(collectionSubscriptionPause).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionPause),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *subscriptionPauseRepository) Update(ctx context.Context, obj *intPkg.SubscriptionPause) error {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionSubscriptionPause).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionPause),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *subscriptionPauseRepository) GetPausedBySubscriptionId(
+	ctx context.Context,
+	subscriptionId string,
+) (*intPkg.SubscriptionPause, error) {
+	pause := &intPkg.SubscriptionPause{}
+	query := bson.M{"subscription_id": subscriptionId, "status": pkg.SubscriptionPauseStatusPaused}
+	err := r.db.Collection(collectionSubscriptionPause).FindOne(ctx, query).Decode(pause)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionPause),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return pause, nil
+}
+
+func (r *subscriptionPauseRepository) FindDue(ctx context.Context, date time.Time) ([]*intPkg.SubscriptionPause, error) {
+	query := bson.M{
+		"status":    pkg.SubscriptionPauseStatusPaused,
+		"resume_at": bson.M{"$lte": date},
+	}
+	opts := options.Find().SetSort(bson.M{"resume_at": 1})
+	cursor, err := r.db.Collection(collectionSubscriptionPause).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionPause),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.SubscriptionPause
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionPause),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
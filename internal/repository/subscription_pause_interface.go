@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"time"
+)
+
+// SubscriptionPauseRepositoryInterface is abstraction layer for working with pauses of recurring subscriptions.
+type SubscriptionPauseRepositoryInterface interface {
+	// Insert adds the pause to the collection.
+	Insert(context.Context, *intPkg.SubscriptionPause) error
+
+	// Update updates the pause in the collection.
+	Update(context.Context, *intPkg.SubscriptionPause) error
+
+	// GetPausedBySubscriptionId returns the pause in progress of the recurring subscription.
+	GetPausedBySubscriptionId(context.Context, string) (*intPkg.SubscriptionPause, error)
+
+	// FindDue returns pauses in progress which must be resumed before the date.
+	FindDue(context.Context, time.Time) ([]*intPkg.SubscriptionPause, error)
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionSubscriptionPlanChange = "subscription_plan_change"
+)
+
+type subscriptionPlanChangeRepository repository
+
+// NewSubscriptionPlanChangeRepository create and return an object for working with the subscription plan change
+// repository. The returned object implements the SubscriptionPlanChangeRepositoryInterface interface.
+func NewSubscriptionPlanChangeRepository(db mongodb.SourceInterface) SubscriptionPlanChangeRepositoryInterface {
+	s := &subscriptionPlanChangeRepository{db: db}
+	return s
+}
+
+func (r *subscriptionPlanChangeRepository) Insert(ctx context.Context, obj *intPkg.SubscriptionPlanChange) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = obj.CreatedAt
+
+	_, err := r.db.Collection(collectionSubscriptionPlanChange).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionPlanChange),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *subscriptionPlanChangeRepository) Update(ctx context.Context, obj *intPkg.SubscriptionPlanChange) error {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionSubscriptionPlanChange).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionPlanChange),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *subscriptionPlanChangeRepository) GetById(ctx context.Context, id string) (*intPkg.SubscriptionPlanChange, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseInvalidObjectId,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionPlanChange),
+			zap.String(pkg.ErrorDatabaseFieldQuery, id),
+		)
+		return nil, err
+	}
+
+	change := &intPkg.SubscriptionPlanChange{}
+	query := bson.M{"_id": oid}
+	err = r.db.Collection(collectionSubscriptionPlanChange).FindOne(ctx, query).Decode(change)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionPlanChange),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return change, nil
+}
+
+func (r *subscriptionPlanChangeRepository) GetPendingBySubscriptionId(
+	ctx context.Context,
+	subscriptionId string,
+) (*intPkg.SubscriptionPlanChange, error) {
+	change := &intPkg.SubscriptionPlanChange{}
+	query := bson.M{"subscription_id": subscriptionId, "status": pkg.SubscriptionPlanChangeStatusPending}
+	err := r.db.Collection(collectionSubscriptionPlanChange).FindOne(ctx, query).Decode(change)
+
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			zap.L().Error(
+				pkg.ErrorDatabaseQueryFailed,
+				zap.Error(err),
+				zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionPlanChange),
+				zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+			)
+		}
+
+		return nil, err
+	}
+
+	return change, nil
+}
+
+func (r *subscriptionPlanChangeRepository) FindBySubscriptionId(
+	ctx context.Context,
+	subscriptionId string,
+) ([]*intPkg.SubscriptionPlanChange, error) {
+	query := bson.M{"subscription_id": subscriptionId}
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cursor, err := r.db.Collection(collectionSubscriptionPlanChange).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionPlanChange),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.SubscriptionPlanChange
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionPlanChange),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
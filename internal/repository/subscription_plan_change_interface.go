@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+)
+
+// SubscriptionPlanChangeRepositoryInterface is abstraction layer for working with plan changes of recurring subscriptions.
+type SubscriptionPlanChangeRepositoryInterface interface {
+	// Insert adds the plan change to the collection.
+	Insert(context.Context, *intPkg.SubscriptionPlanChange) error
+
+	// Update updates the plan change in the collection.
+	Update(context.Context, *intPkg.SubscriptionPlanChange) error
+
+	// GetById returns the plan change by unique identity.
+	GetById(context.Context, string) (*intPkg.SubscriptionPlanChange, error)
+
+	// FindBySubscriptionId returns plan changes of the recurring subscription, the latest goes first.
+	FindBySubscriptionId(context.Context, string) ([]*intPkg.SubscriptionPlanChange, error)
+
+	// GetPendingBySubscriptionId returns the plan change of the subscription waiting for the proration charge.
+	GetPendingBySubscriptionId(context.Context, string) (*intPkg.SubscriptionPlanChange, error)
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	collectionSubscriptionTrial = "subscription_trial"
+)
+
+type subscriptionTrialRepository repository
+
+// NewSubscriptionTrialRepository create and return an object for working with the subscription trial repository.
+// The returned object implements the SubscriptionTrialRepositoryInterface interface.
+func NewSubscriptionTrialRepository(db mongodb.SourceInterface) SubscriptionTrialRepositoryInterface {
+	s := &subscriptionTrialRepository{db: db}
+	return s
+}
+
+func (r *subscriptionTrialRepository) Insert(ctx context.Context, obj *intPkg.SubscriptionTrial) error {
+	if obj.Id.IsZero() {
+		obj.Id = primitive.NewObjectID()
+	}
+
+	if obj.CreatedAt.IsZero() {
+		obj.CreatedAt = time.Now()
+	}
+
+	obj.UpdatedAt = obj.CreatedAt
+	_, err := r.db.Collection(collectionSubscriptionTrial).InsertOne(ctx, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionTrial),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationInsert),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *subscriptionTrialRepository) Update(ctx context.Context, obj *intPkg.SubscriptionTrial) error {
+	obj.UpdatedAt = time.Now()
+	filter := bson.M{"_id": obj.Id}
+	_, err := r.db.Collection(collectionSubscriptionTrial).ReplaceOne(ctx, filter, obj)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionTrial),
+			zap.String(pkg.ErrorDatabaseFieldOperation, pkg.ErrorDatabaseFieldOperationUpdate),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, obj),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *subscriptionTrialRepository) GetBySubscriptionId(
+	ctx context.Context,
+	subscriptionId string,
+) (*intPkg.SubscriptionTrial, error) {
+	trial := &intPkg.SubscriptionTrial{}
+	query := bson.M{"subscription_id": subscriptionId}
+	err := r.db.Collection(collectionSubscriptionTrial).FindOne(ctx, query).Decode(trial)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionTrial),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return trial, nil
+}
+
+func (r *subscriptionTrialRepository) FindBySubscriptionIds(
+	ctx context.Context,
+	subscriptionIds []string,
+) ([]*intPkg.SubscriptionTrial, error) {
+	query := bson.M{"subscription_id": bson.M{"$in": subscriptionIds}}
+
+	return r.find(ctx, query, options.Find())
+}
+
+func (r *subscriptionTrialRepository) FindEnded(ctx context.Context, date time.Time) ([]*intPkg.SubscriptionTrial, error) {
+	query := bson.M{
+		"status":        pkg.SubscriptionTrialStatusActive,
+		"trial_ends_at": bson.M{"$lt": date},
+	}
+
+	return r.find(ctx, query, options.Find().SetSort(bson.M{"trial_ends_at": 1}))
+}
+
+func (r *subscriptionTrialRepository) find(
+	ctx context.Context,
+	query bson.M,
+	opts *options.FindOptions,
+) ([]*intPkg.SubscriptionTrial, error) {
+	cursor, err := r.db.Collection(collectionSubscriptionTrial).Find(ctx, query, opts)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorDatabaseQueryFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionTrial),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	var list []*intPkg.SubscriptionTrial
+	err = cursor.All(ctx, &list)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorQueryCursorExecutionFailed,
+			zap.Error(err),
+			zap.String(pkg.ErrorDatabaseFieldCollection, collectionSubscriptionTrial),
+			zap.Any(pkg.ErrorDatabaseFieldQuery, query),
+		)
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"time"
+)
+
+// SubscriptionTrialRepositoryInterface is abstraction layer for working with trial periods of recurring subscriptions.
+type SubscriptionTrialRepositoryInterface interface {
+	// Insert adds the trial to the collection.
+	Insert(context.Context, *intPkg.SubscriptionTrial) error
+
+	// Update updates the trial in the collection.
+	Update(context.Context, *intPkg.SubscriptionTrial) error
+
+	// GetBySubscriptionId returns the trial of the recurring subscription.
+	GetBySubscriptionId(context.Context, string) (*intPkg.SubscriptionTrial, error)
+
+	// FindBySubscriptionIds returns trials of the recurring subscriptions.
+	FindBySubscriptionIds(context.Context, []string) ([]*intPkg.SubscriptionTrial, error)
+
+	// FindEnded returns active trials which ended before the date.
+	FindEnded(context.Context, time.Time) ([]*intPkg.SubscriptionTrial, error)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/paysuper/paysuper-billing-server/internal/helper"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/internal/repository"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	errors2 "github.com/paysuper/paysuper-billing-server/pkg/errors"
@@ -85,6 +86,15 @@ var (
 		pkg.AccountingEntryTypeRealRefundTaxFee:                    true,
 		pkg.AccountingEntryTypeRealRefundFee:                       true,
 		pkg.AccountingEntryTypeRealRefundFixedFee:                  true,
+		pkg.AccountingEntryTypeRefundBankTransfer:                  true,
+		pkg.AccountingEntryTypeRefundStoreCredit:                   true,
+		pkg.AccountingEntryTypeWalletGoodwill:                      true,
+		pkg.AccountingEntryTypeWalletPayment:                       true,
+		pkg.AccountingEntryTypeWalletPaymentTaxFee:                 true,
+		pkg.AccountingEntryTypeWalletRefund:                        true,
+		pkg.AccountingEntryTypeWalletRefundTaxFee:                  true,
+		pkg.AccountingEntryTypeGiftCardPayment:                     true,
+		pkg.AccountingEntryTypeGiftCardBreakage:                    true,
 		pkg.AccountingEntryTypeMerchantRefund:                      true,
 		pkg.AccountingEntryTypePsMerchantRefundFx:                  true,
 		pkg.AccountingEntryTypeMerchantRefundFee:                   true,
@@ -134,10 +144,12 @@ type accountingEntry struct {
 	order             *billingpb.Order
 	refund            *billingpb.Refund
 	refundOrder       *billingpb.Order
+	refundDestination string
 	merchant          *billingpb.Merchant
 	country           *billingpb.Country
 	datetime          *timestamp.Timestamp
 	accountingEntries []*billingpb.AccountingEntry
+	journalEntries    []*intPkg.JournalEntry
 	req               *billingpb.CreateAccountingEntryRequest
 }
 
@@ -250,8 +262,24 @@ func (s *Service) CreateAccountingEntry(
 		return nil
 	}
 
+	// Entries are already saved, so the failed audit log doesn't stop the update of the merchant balance
+	var auditErr error
+
+	for _, entry := range handler.accountingEntries {
+		err = s.addAuditLog(ctx, &auditLogRecord{
+			rpc:        "CreateAccountingEntry",
+			entityType: pkg.AuditLogEntityAccountingEntry,
+			entityId:   entry.Id,
+			after:      entry,
+		})
+
+		if err != nil {
+			auditErr = err
+		}
+	}
+
 	if _, ok := rollingReserveAccountingEntries[req.Type]; ok {
-		_, err = s.updateMerchantBalance(ctx, handler.merchant.Id)
+		_, err = s.updateMerchantBalance(ctx, handler.merchant.Id, handler.accountingEntries[0].Currency)
 		if err != nil {
 			rsp.Status = billingpb.ResponseStatusSystemError
 			rsp.Message = accountingEntryBalanceUpdateFailed
@@ -260,6 +288,12 @@ func (s *Service) CreateAccountingEntry(
 		}
 	}
 
+	if auditErr != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	rsp.Status = billingpb.ResponseStatusOk
 	rsp.Item = handler.accountingEntries[0]
 
@@ -315,15 +349,22 @@ func (s *Service) onRefundNotify(ctx context.Context, refund *billingpb.Refund,
 		return merchantErrorNotFound
 	}
 
+	refundDestination, err := s.getRefundDestination(ctx, refund)
+
+	if err != nil {
+		return err
+	}
+
 	handler := &accountingEntry{
-		Service:     s,
-		refund:      refund,
-		order:       order,
-		refundOrder: refundOrder,
-		ctx:         ctx,
-		country:     country,
-		merchant:    merchant,
-		datetime:    refundOrder.PaymentMethodOrderClosedAt,
+		Service:           s,
+		refund:            refund,
+		order:             order,
+		refundOrder:       refundOrder,
+		refundDestination: refundDestination,
+		ctx:               ctx,
+		country:           country,
+		merchant:          merchant,
+		datetime:          refundOrder.PaymentMethodOrderClosedAt,
 	}
 
 	return s.processEvent(handler, accountingEventTypeRefund)
@@ -353,6 +394,10 @@ func (s *Service) processEvent(handler *accountingEntry, eventType string) error
 		return err
 	}
 
+	if err = handler.createJournalEntries(eventType); err != nil {
+		return err
+	}
+
 	return handler.saveAccountingEntries(s.orderViewRepository, s.paylinkRepository, s.paylinkVisitsRepository)
 }
 
@@ -384,6 +429,114 @@ func (h *accountingEntry) processManualCorrectionEvent() error {
 	return nil
 }
 
+// processWalletPaymentEntries creates the entry of the order part paid by the customer wallet and returns its amount.
+// The part paid by the wallet is the merchant revenue without the payment system fees, its tax is included in
+// the merchant tax of the order.
+func (h *accountingEntry) processWalletPaymentEntries() (float64, error) {
+	amount := getOrderWalletAmount(h.order)
+
+	if amount <= 0 || h.order.PrivateMetadata[pkg.OrderPrivateMetadataWalletCharged] == "" {
+		return 0, nil
+	}
+
+	var err error
+
+	walletPayment := h.newEntry(pkg.AccountingEntryTypeWalletPayment)
+	walletPayment.Amount, err = h.GetExchangePsByDateCommon(h.order.Currency, amount)
+	if err != nil {
+		return 0, err
+	}
+	walletPayment.OriginalAmount = amount
+	walletPayment.OriginalCurrency = h.order.Currency
+	if err = h.addEntry(walletPayment); err != nil {
+		return 0, err
+	}
+
+	return walletPayment.Amount, nil
+}
+
+// processGiftCardPaymentEntries creates the entry of the order part paid by the gift card and returns its amount.
+// The part paid by the gift card is the merchant revenue without the payment system fees, its tax is included in
+// the merchant tax of the order.
+func (h *accountingEntry) processGiftCardPaymentEntries() (float64, error) {
+	amount := getOrderGiftCardAmount(h.order)
+
+	if amount <= 0 || h.order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardCharged] == "" {
+		return 0, nil
+	}
+
+	var err error
+
+	giftCardPayment := h.newEntry(pkg.AccountingEntryTypeGiftCardPayment)
+	giftCardPayment.Amount, err = h.GetExchangePsByDateCommon(h.order.Currency, amount)
+	if err != nil {
+		return 0, err
+	}
+	giftCardPayment.OriginalAmount = amount
+	giftCardPayment.OriginalCurrency = h.order.Currency
+	if err = h.addEntry(giftCardPayment); err != nil {
+		return 0, err
+	}
+
+	return giftCardPayment.Amount, nil
+}
+
+// processMerchantTaxFeeEntries creates entries of the merchant tax of the merchant gross revenue of the whole order,
+// whatever paid for it.
+func (h *accountingEntry) processMerchantTaxFeeEntries(merchantGrossRevenue float64) error {
+	// 9. merchantTaxFeeCostValue
+	merchantTaxFeeCostValue := h.newEntry(pkg.AccountingEntryTypeMerchantTaxFeeCostValue)
+	merchantTaxFeeCostValue.Amount = tools.GetPercentPartFromAmount(merchantGrossRevenue, h.order.Tax.Rate)
+	if err := h.addEntry(merchantTaxFeeCostValue); err != nil {
+		return err
+	}
+
+	// 10. merchantTaxFeeCentralBankFx
+	merchantTaxFeeCentralBankFx := h.newEntry(pkg.AccountingEntryTypeMerchantTaxFeeCentralBankFx)
+	if h.country.VatEnabled {
+		amount, err := h.GetExchangeCbByDateCommon(h.order.GetMerchantRoyaltyCurrency(), merchantTaxFeeCostValue.Amount)
+		if err != nil {
+			return err
+		}
+		amount, err = h.GetExchangeStockByDateCommon(h.country.GetVatCurrencyCode(), amount)
+		if err != nil {
+			return err
+		}
+		merchantTaxFeeCentralBankFx.Amount = amount - merchantTaxFeeCostValue.Amount
+		// merchantTaxFeeCentralBankFx amount can not be negative
+		// @see https://protocolone.tpondemand.com/entity/196061
+		if merchantTaxFeeCentralBankFx.Amount < 0 {
+			merchantTaxFeeCentralBankFx.Amount = 0
+		}
+	}
+
+	return h.addEntry(merchantTaxFeeCentralBankFx)
+}
+
+// processWalletRefundEntries creates entries of the refund returned to the customer wallet. The tax of the wallet
+// part is returned as it's due when the wallet is spent on the order.
+func (h *accountingEntry) processWalletRefundEntries() error {
+	var err error
+
+	walletRefund := h.newEntry(pkg.AccountingEntryTypeWalletRefund)
+	walletRefund.Amount, err = h.GetExchangePsByDateCommon(h.refund.Currency, h.refund.Amount)
+	if err != nil {
+		return err
+	}
+	walletRefund.OriginalAmount = h.refund.Amount
+	walletRefund.OriginalCurrency = h.refund.Currency
+	if err = h.addEntry(walletRefund); err != nil {
+		return err
+	}
+
+	walletRefundTaxFee := h.newEntry(pkg.AccountingEntryTypeWalletRefundTaxFee)
+	walletRefundTaxFee.Amount = tools.GetPercentPartFromAmount(walletRefund.Amount, h.order.GetTax().GetRate())
+	walletRefundTaxFee.OriginalAmount = tools.GetPercentPartFromAmount(h.refund.Amount, h.order.GetTax().GetRate())
+	walletRefundTaxFee.OriginalCurrency = h.refund.Currency
+
+	return h.addEntry(walletRefundTaxFee)
+}
+
 func (h *accountingEntry) processPaymentEvent() error {
 	var (
 		amount float64
@@ -409,6 +562,25 @@ func (h *accountingEntry) processPaymentEvent() error {
 		// todo: is there must be an update of existing entry, instead of error?
 	}
 
+	// 0. walletPayment, giftCardPayment
+	walletRevenue, err := h.processWalletPaymentEntries()
+	if err != nil {
+		return err
+	}
+
+	giftCardRevenue, err := h.processGiftCardPaymentEntries()
+	if err != nil {
+		return err
+	}
+
+	prepaidRevenue := walletRevenue + giftCardRevenue
+
+	// order paid by the customer wallet and the gift card only has no charge of the payment system, so the merchant
+	// gross revenue of the order is the prepaid amount without the payment system fees
+	if h.order.PaymentMethod == nil {
+		return h.processMerchantTaxFeeEntries(prepaidRevenue)
+	}
+
 	// 1. realGrossRevenue
 	realGrossRevenue := h.newEntry(pkg.AccountingEntryTypeRealGrossRevenue)
 	realGrossRevenue.Amount, err = h.GetExchangePsByDateCommon(h.order.ChargeCurrency, h.order.ChargeAmount)
@@ -466,36 +638,16 @@ func (h *accountingEntry) processPaymentEvent() error {
 	// calculated in order_view
 
 	// 8. merchantGrossRevenue
+	// the part of the order charged by the payment system is the base of the payment system fees, the merchant gross
+	// revenue is the whole order amount including the prepaid part
+	chargedRevenue := realGrossRevenue.Amount - psGrossRevenueFx.Amount
 	merchantGrossRevenue := h.newEntry(pkg.AccountingEntryTypeMerchantGrossRevenue)
-	merchantGrossRevenue.Amount = realGrossRevenue.Amount - psGrossRevenueFx.Amount
+	merchantGrossRevenue.Amount = chargedRevenue + prepaidRevenue
 	// not store in DB - calculated in order_view, but used further in the method code
 
 	// 9. merchantTaxFeeCostValue
-	merchantTaxFeeCostValue := h.newEntry(pkg.AccountingEntryTypeMerchantTaxFeeCostValue)
-	merchantTaxFeeCostValue.Amount = tools.GetPercentPartFromAmount(merchantGrossRevenue.Amount, h.order.Tax.Rate)
-	if err = h.addEntry(merchantTaxFeeCostValue); err != nil {
-		return err
-	}
-
 	// 10. merchantTaxFeeCentralBankFx
-	merchantTaxFeeCentralBankFx := h.newEntry(pkg.AccountingEntryTypeMerchantTaxFeeCentralBankFx)
-	if h.country.VatEnabled {
-		amount, err = h.GetExchangeCbByDateCommon(h.order.GetMerchantRoyaltyCurrency(), merchantTaxFeeCostValue.Amount)
-		if err != nil {
-			return err
-		}
-		amount, err = h.GetExchangeStockByDateCommon(h.country.GetVatCurrencyCode(), amount)
-		if err != nil {
-			return err
-		}
-		merchantTaxFeeCentralBankFx.Amount = amount - merchantTaxFeeCostValue.Amount
-		// merchantTaxFeeCentralBankFx amount can not be negative
-		// @see https://protocolone.tpondemand.com/entity/196061
-		if merchantTaxFeeCentralBankFx.Amount < 0 {
-			merchantTaxFeeCentralBankFx.Amount = 0
-		}
-	}
-	if err = h.addEntry(merchantTaxFeeCentralBankFx); err != nil {
+	if err = h.processMerchantTaxFeeEntries(merchantGrossRevenue.Amount); err != nil {
 		return err
 	}
 
@@ -514,14 +666,14 @@ func (h *accountingEntry) processPaymentEvent() error {
 
 	// 12. psMethodFee
 	psMethodFee := h.newEntry(pkg.AccountingEntryTypePsMethodFee)
-	psMethodFee.Amount = merchantGrossRevenue.Amount * paymentChannelCostMerchant.PsPercent
+	psMethodFee.Amount = chargedRevenue * paymentChannelCostMerchant.PsPercent
 	if err = h.addEntry(psMethodFee); err != nil {
 		return err
 	}
 
 	// 13. merchantMethodFee
 	merchantMethodFee := h.newEntry(pkg.AccountingEntryTypeMerchantMethodFee)
-	merchantMethodFee.Amount = merchantGrossRevenue.Amount * paymentChannelCostMerchant.MethodPercent
+	merchantMethodFee.Amount = chargedRevenue * paymentChannelCostMerchant.MethodPercent
 	if err = h.addEntry(merchantMethodFee); err != nil {
 		return err
 	}
@@ -636,6 +788,11 @@ func (h *accountingEntry) processRefundEvent() error {
 		// todo: is there must be an update of existing entry, instead of error?
 	}
 
+	// part of the order paid by the customer wallet is returned to the wallet without the payment system
+	if h.refundDestination == pkg.RefundDestinationWallet {
+		return h.processWalletRefundEntries()
+	}
+
 	// info: reversal rates are applied after the transaction has been physically processed by the payment method
 	// but refund is the return of payment _before_ of the transaction was physically processed by the payment method.
 	// Now, at this moment we can't determine that it is a refund or reversal
@@ -707,23 +864,44 @@ func (h *accountingEntry) processRefundEvent() error {
 	}
 
 	// 3. realRefundFee
+	// 4. realRefundFixedFee
+	// refund declined by the payment system is returned by the bank transfer or the store credit,
+	// so the payment system doesn't charge money back costs for it
 	realRefundFee := h.newEntry(pkg.AccountingEntryTypeRealRefundFee)
-	realRefundFee.Amount = realRefund.Amount * moneyBackCostSystem.Percent
-	if err = h.addEntry(realRefundFee); err != nil {
-		return err
+	realRefundFixedFee := h.newEntry(pkg.AccountingEntryTypeRealRefundFixedFee)
+
+	if h.refundDestination == "" {
+		realRefundFee.Amount = realRefund.Amount * moneyBackCostSystem.Percent
+		realRefundFixedFee.Amount, err = h.GetExchangePsByDateCommon(moneyBackCostSystem.FixAmountCurrency, moneyBackCostSystem.FixAmount)
+		if err != nil {
+			return err
+		}
 	}
 
-	// 4. realRefundFixedFee
-	realRefundFixedFee := h.newEntry(pkg.AccountingEntryTypeRealRefundFixedFee)
-	realRefundFixedFee.Amount, err = h.GetExchangePsByDateCommon(moneyBackCostSystem.FixAmountCurrency, moneyBackCostSystem.FixAmount)
-	if err != nil {
+	if err = h.addEntry(realRefundFee); err != nil {
 		return err
 	}
-
 	if err = h.addEntry(realRefundFixedFee); err != nil {
 		return err
 	}
 
+	// refundBankTransfer or refundStoreCredit
+	if h.refundDestination != "" {
+		entryType := pkg.AccountingEntryTypeRefundBankTransfer
+
+		if h.refundDestination == pkg.RefundFallbackDestinationStoreCredit {
+			entryType = pkg.AccountingEntryTypeRefundStoreCredit
+		}
+
+		refundDestination := h.newEntry(entryType)
+		refundDestination.Amount = realRefund.Amount
+		refundDestination.OriginalAmount = h.refund.Amount
+		refundDestination.OriginalCurrency = h.refund.Currency
+		if err = h.addEntry(refundDestination); err != nil {
+			return err
+		}
+	}
+
 	// 5. merchantRefund
 	merchantRefund := h.newEntry(pkg.AccountingEntryTypeMerchantRefund)
 	merchantRefund.Amount, err = h.GetExchangePsByDateMerchant(h.refund.Currency, h.refund.Amount)
@@ -808,10 +986,14 @@ func (h *accountingEntry) processRefundEvent() error {
 			return err
 		}
 
-		reverseTaxFee.Amount = (merchantTaxFeeCostValue.Amount + merchantTaxFeeCentralBankFx.Amount) * partialRefundCorrection
-		reverseTaxFee.OriginalAmount = (merchantTaxFeeCostValue.OriginalAmount + merchantTaxFeeCentralBankFx.Amount) * partialRefundCorrection
+		// merchant tax is calculated for the whole order amount including the prepaid part, so the refunded part of
+		// it is the share of the refund in the whole order amount
+		merchantRefundCorrection := h.refund.Amount / (h.order.ChargeAmount + getOrderPrepaidChargeAmount(h.order))
+
+		reverseTaxFee.Amount = (merchantTaxFeeCostValue.Amount + merchantTaxFeeCentralBankFx.Amount) * merchantRefundCorrection
+		reverseTaxFee.OriginalAmount = (merchantTaxFeeCostValue.OriginalAmount + merchantTaxFeeCentralBankFx.Amount) * merchantRefundCorrection
 		reverseTaxFee.OriginalCurrency = merchantTaxFeeCostValue.OriginalCurrency
-		reverseTaxFee.LocalAmount = (merchantTaxFeeCostValue.LocalAmount + merchantTaxFeeCentralBankFx.Amount) * partialRefundCorrection
+		reverseTaxFee.LocalAmount = (merchantTaxFeeCostValue.LocalAmount + merchantTaxFeeCentralBankFx.Amount) * merchantRefundCorrection
 		reverseTaxFee.LocalCurrency = merchantTaxFeeCostValue.LocalCurrency
 	}
 	if err = h.addEntry(reverseTaxFee); err != nil {
@@ -1108,6 +1290,12 @@ func (h *accountingEntry) saveAccountingEntries(
 		return err
 	}
 
+	err = h.journalEntryRepository.MultipleInsert(h.ctx, h.journalEntries)
+
+	if err != nil {
+		return err
+	}
+
 	var ids []string
 	var paylinks = map[string]string{}
 	if h.order != nil {
@@ -1529,6 +1717,12 @@ func (s *Service) RebuildAccountingEntries(ctx context.Context, orderId string,
 			zap.L().Error("accountingRepository.DeleteBySource failed with error", zap.Error(err))
 			return err
 		}
+
+		err = s.journalEntryRepository.DeleteBySource(ctx, orderId, order.Type)
+		if err != nil {
+			zap.L().Error("journalEntryRepository.DeleteBySource failed with error", zap.Error(err))
+			return err
+		}
 	}
 
 	switch order.Type {
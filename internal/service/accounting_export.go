@@ -0,0 +1,303 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/currenciespb"
+	tools "github.com/paysuper/paysuper-tools/number"
+	"strconv"
+	"time"
+)
+
+const (
+	accountingExportDateFormat  = "2006-01-02"
+	accountingExportContentType = "text/csv"
+	accountingExportFileName    = "accounting_entries_%s_%s_%s.csv"
+)
+
+var (
+	accountingEntryErrorExportPeriodInvalid = errors.NewBillingServerErrorMsg("ae00021", "end date of the export period must be later than start date")
+	accountingEntryErrorExportFormatInvalid = errors.NewBillingServerErrorMsg("ae00022", "format of the accounting entries export isn't supported")
+
+	accountingExportCsvHeader = []string{
+		"operating_company_id",
+		"merchant_id",
+		"country",
+		"entry_type",
+		"entries_count",
+		"currency",
+		"amount",
+		"original_currency",
+		"original_amount",
+		"exchange_rate",
+		"exchange_rate_type",
+		"local_currency",
+		"local_amount",
+		"local_exchange_rate",
+		"local_exchange_rate_type",
+		"local_exchange_rate_source",
+	}
+
+	accountingExportGlCsvHeader = []string{
+		"date",
+		"journal_number",
+		"account",
+		"debit",
+		"credit",
+		"currency",
+		"description",
+		"operating_company_id",
+		"merchant_id",
+		"source_type",
+		"source_id",
+		"entry_type",
+		"accounting_entry_id",
+	}
+
+	// accountingExportCentralBankEntries are the accounting entries which amounts are calculated by the central bank
+	// rate, amounts of other entries are converted to the royalty currency of the merchant by the paysuper rate.
+	accountingExportCentralBankEntries = map[string]bool{
+		pkg.AccountingEntryTypeMerchantTaxFeeCentralBankFx: true,
+		pkg.AccountingEntryTypeReverseTaxFeeDelta:          true,
+		pkg.AccountingEntryTypePsReverseTaxFeeDelta:        true,
+	}
+)
+
+type accountingExportRate struct {
+	rateType string
+	source   string
+}
+
+// ExportAccountingEntries exports the accounting entries created in the period. The csv format lists sums of
+// the entries grouped by entry type, merchant, country and operating company with the exchange rates to the royalty
+// and the local currencies, the gl_csv format lists lines of the journal entries posted to the ledger in the period
+// with debit and credit columns.
+func (s *Service) ExportAccountingEntries(
+	ctx context.Context,
+	req *pkg.ExportAccountingEntriesRequest,
+	rsp *pkg.ExportAccountingEntriesResponse,
+) error {
+	if req.Format == "" {
+		req.Format = pkg.AccountingExportFormatCsv
+	}
+
+	if req.Format != pkg.AccountingExportFormatCsv && req.Format != pkg.AccountingExportFormatGlCsv {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = accountingEntryErrorExportFormatInvalid
+		return nil
+	}
+
+	if req.DateTo <= req.DateFrom {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = accountingEntryErrorExportPeriodInvalid
+		return nil
+	}
+
+	if req.OperatingCompanyId != "" {
+		if _, err := s.operatingCompanyRepository.GetById(ctx, req.OperatingCompanyId); err != nil {
+			rsp.Status = billingpb.ResponseStatusNotFound
+			rsp.Message = errorOperatingCompanyNotFound
+			return nil
+		}
+	}
+
+	from := time.Unix(req.DateFrom, 0).UTC()
+	to := time.Unix(req.DateTo, 0).UTC()
+
+	var rows [][]string
+
+	if req.Format == pkg.AccountingExportFormatGlCsv {
+		journals, err := s.journalEntryRepository.FindByPeriod(ctx, req.OperatingCompanyId, req.MerchantId, from, to)
+
+		if err != nil {
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = accountingEntryErrorUnknown
+			return nil
+		}
+
+		rows = getAccountingExportGlRows(journals)
+	} else {
+		items, err := s.accountingRepository.GetExportSummary(ctx, req.OperatingCompanyId, req.MerchantId, from, to)
+
+		if err != nil {
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = accountingEntryErrorUnknown
+			return nil
+		}
+
+		rows = s.getAccountingExportRows(ctx, items)
+	}
+
+	file, err := writeAccountingExportCsv(rows)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = accountingEntryErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = &pkg.AccountingExport{
+		FileName: fmt.Sprintf(
+			accountingExportFileName,
+			req.Format,
+			from.Format(accountingExportDateFormat),
+			to.Format(accountingExportDateFormat),
+		),
+		ContentType: accountingExportContentType,
+		File:        file,
+		RowsCount:   int32(len(rows) - 1),
+	}
+
+	return nil
+}
+
+func (s *Service) getAccountingExportRows(ctx context.Context, items []*intPkg.AccountingExportQueryResItem) [][]string {
+	rows := [][]string{accountingExportCsvHeader}
+	localRates := make(map[string]*accountingExportRate)
+
+	for _, item := range items {
+		rate, rateType := getAccountingExportRate(item)
+		localRate, localRateType, localRateSource := float64(1), "", ""
+
+		if item.LocalCurrency != "" && item.LocalCurrency != item.OriginalCurrency {
+			localRate = getAccountingExportExchangeRate(item.OriginalAmount, item.LocalAmount)
+			countryRate, ok := localRates[item.Country]
+
+			if !ok {
+				countryRate = s.getAccountingExportLocalRate(ctx, item.Country)
+				localRates[item.Country] = countryRate
+			}
+
+			localRateType = countryRate.rateType
+			localRateSource = countryRate.source
+		}
+
+		rows = append(rows, []string{
+			item.OperatingCompanyId,
+			getAccountingExportMerchantId(item),
+			item.Country,
+			item.Type,
+			strconv.FormatInt(item.Count, 10),
+			item.Currency,
+			formatAccountingExportAmount(item.Amount),
+			item.OriginalCurrency,
+			formatAccountingExportAmount(item.OriginalAmount),
+			formatAccountingExportRate(rate),
+			rateType,
+			item.LocalCurrency,
+			formatAccountingExportAmount(item.LocalAmount),
+			formatAccountingExportRate(localRate),
+			localRateType,
+			localRateSource,
+		})
+	}
+
+	return rows
+}
+
+// getAccountingExportLocalRate returns the rate used to convert amounts of the accounting entries to the local
+// currency of the country. Countries with VAT use the central bank rate of the VAT currency rates source.
+func (s *Service) getAccountingExportLocalRate(ctx context.Context, code string) *accountingExportRate {
+	rate := &accountingExportRate{rateType: currenciespb.RateTypeOxr}
+	country, err := s.country.GetByIsoCodeA2(ctx, code)
+
+	if err != nil {
+		return rate
+	}
+
+	if country.VatEnabled {
+		rate.rateType = currenciespb.RateTypeCentralbanks
+		rate.source = country.VatCurrencyRatesSource
+	}
+
+	return rate
+}
+
+// getAccountingExportGlRows returns the general ledger lines of the journal entries posted to the ledger. The journal
+// number is the identifier of the journal entry, so lines of the same journal have the same number in any export.
+func getAccountingExportGlRows(journals []*intPkg.JournalEntry) [][]string {
+	rows := [][]string{accountingExportGlCsvHeader}
+
+	for _, journal := range journals {
+		date := journal.CreatedAt.UTC().Format(accountingExportDateFormat)
+		number := journal.Id.Hex()
+
+		for _, line := range journal.Lines {
+			rows = append(rows, []string{
+				date,
+				number,
+				line.Account,
+				formatAccountingExportAmount(line.Debit),
+				formatAccountingExportAmount(line.Credit),
+				line.Currency,
+				journal.EventType + ", " + line.AccountingEntryType,
+				journal.OperatingCompanyId,
+				journal.MerchantId,
+				journal.SourceType,
+				journal.SourceId,
+				line.AccountingEntryType,
+				line.AccountingEntryId,
+			})
+		}
+	}
+
+	return rows
+}
+
+// getAccountingExportRate returns the average rate of conversion of the original amounts of the entries
+// to the royalty currency of the merchant and the type of the rate.
+func getAccountingExportRate(item *intPkg.AccountingExportQueryResItem) (float64, string) {
+	if item.OriginalCurrency == "" || item.OriginalCurrency == item.Currency {
+		return 1, ""
+	}
+
+	rateType := currenciespb.RateTypePaysuper
+
+	if accountingExportCentralBankEntries[item.Type] {
+		rateType = currenciespb.RateTypeCentralbanks
+	}
+
+	return getAccountingExportExchangeRate(item.OriginalAmount, item.Amount), rateType
+}
+
+func getAccountingExportExchangeRate(from, to float64) float64 {
+	if from == 0 {
+		return 0
+	}
+
+	return to / from
+}
+
+func getAccountingExportMerchantId(item *intPkg.AccountingExportQueryResItem) string {
+	if item.MerchantId.IsZero() {
+		return ""
+	}
+
+	return item.MerchantId.Hex()
+}
+
+func formatAccountingExportAmount(amount float64) string {
+	return strconv.FormatFloat(tools.FormatAmount(amount), 'f', 2, 64)
+}
+
+func formatAccountingExportRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', 6, 64)
+}
+
+func writeAccountingExportCsv(rows [][]string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	"github.com/paysuper/paysuper-proto/go/currenciespb"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type AccountingExportTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant *billingpb.Merchant
+}
+
+func Test_AccountingExport(t *testing.T) {
+	suite.Run(t, new(AccountingExportTestSuite))
+}
+
+func (suite *AccountingExportTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	suite.merchant, _, _, _, _ = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+}
+
+func (suite *AccountingExportTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *AccountingExportTestSuite) createAccountingEntry(entryType string, amount float64) {
+	req := &billingpb.CreateAccountingEntryRequest{
+		Type:       entryType,
+		MerchantId: suite.merchant.Id,
+		Amount:     amount,
+		Currency:   suite.merchant.GetPayoutCurrency(),
+		Status:     pkg.BalanceTransactionStatusAvailable,
+		Date:       time.Now().Add(-1 * time.Hour).Unix(),
+		Reason:     "unit test",
+	}
+	rsp := &billingpb.CreateAccountingEntryResponse{}
+	err := suite.service.CreateAccountingEntry(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+}
+
+func (suite *AccountingExportTestSuite) TestAccountingExport_ExportAccountingEntries_Csv_Ok() {
+	suite.createAccountingEntry(pkg.AccountingEntryTypeMerchantRoyaltyCorrection, 100)
+	suite.createAccountingEntry(pkg.AccountingEntryTypeMerchantRoyaltyCorrection, 50)
+	suite.createAccountingEntry(pkg.AccountingEntryTypeMerchantRollingReserveCreate, 30)
+
+	rsp := suite.exportAccountingEntries(pkg.AccountingExportFormatCsv)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), "text/csv", rsp.Item.ContentType)
+	assert.Contains(suite.T(), rsp.Item.FileName, "accounting_entries_csv_")
+	assert.EqualValues(suite.T(), 2, rsp.Item.RowsCount)
+
+	rows := suite.readCsv(rsp.Item.File)
+	assert.Len(suite.T(), rows, 3)
+	assert.Equal(suite.T(), accountingExportCsvHeader, rows[0])
+
+	for _, row := range rows[1:] {
+		assert.Equal(suite.T(), suite.merchant.OperatingCompanyId, row[0])
+		assert.Equal(suite.T(), suite.merchant.Id, row[1])
+		assert.Equal(suite.T(), suite.merchant.GetPayoutCurrency(), row[5])
+		assert.Equal(suite.T(), "1.000000", row[9])
+		assert.Empty(suite.T(), row[10])
+	}
+
+	assert.Equal(suite.T(), pkg.AccountingEntryTypeMerchantRollingReserveCreate, rows[1][3])
+	assert.Equal(suite.T(), "1", rows[1][4])
+	assert.Equal(suite.T(), "30.00", rows[1][6])
+
+	assert.Equal(suite.T(), pkg.AccountingEntryTypeMerchantRoyaltyCorrection, rows[2][3])
+	assert.Equal(suite.T(), "2", rows[2][4])
+	assert.Equal(suite.T(), "150.00", rows[2][6])
+}
+
+func (suite *AccountingExportTestSuite) TestAccountingExport_ExportAccountingEntries_GlCsv_Ok() {
+	suite.createAccountingEntry(pkg.AccountingEntryTypeMerchantRoyaltyCorrection, 100)
+	suite.createAccountingEntry(pkg.AccountingEntryTypeMerchantRollingReserveCreate, -30)
+
+	rsp := suite.exportAccountingEntries(pkg.AccountingExportFormatGlCsv)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 4, rsp.Item.RowsCount)
+
+	rows := suite.readCsv(rsp.Item.File)
+	assert.Len(suite.T(), rows, 5)
+	assert.Equal(suite.T(), accountingExportGlCsvHeader, rows[0])
+
+	for _, row := range rows[1:] {
+		assert.Equal(suite.T(), suite.merchant.GetPayoutCurrency(), row[5])
+		assert.Equal(suite.T(), suite.merchant.OperatingCompanyId, row[7])
+		assert.Equal(suite.T(), suite.merchant.Id, row[8])
+		assert.Equal(suite.T(), suite.merchant.Id, row[10])
+		assert.NotEmpty(suite.T(), row[12])
+	}
+
+	assert.Equal(suite.T(), pkg.LedgerAccountPlatformRevenue, rows[1][2])
+	assert.Equal(suite.T(), "100.00", rows[1][3])
+	assert.Equal(suite.T(), pkg.LedgerAccountMerchantPayable, rows[2][2])
+	assert.Equal(suite.T(), "100.00", rows[2][4])
+	assert.Equal(suite.T(), pkg.AccountingEntryTypeMerchantRoyaltyCorrection, rows[2][11])
+	assert.Equal(suite.T(), rows[1][1], rows[2][1])
+
+	// negative amount reverses the posting
+	assert.Equal(suite.T(), pkg.LedgerAccountRollingReserve, rows[3][2])
+	assert.Equal(suite.T(), "30.00", rows[3][3])
+	assert.Equal(suite.T(), "0.00", rows[3][4])
+	assert.Equal(suite.T(), pkg.LedgerAccountMerchantPayable, rows[4][2])
+	assert.Equal(suite.T(), "0.00", rows[4][3])
+	assert.Equal(suite.T(), "30.00", rows[4][4])
+	assert.Equal(suite.T(), rows[3][1], rows[4][1])
+	assert.NotEqual(suite.T(), rows[1][1], rows[3][1])
+}
+
+func (suite *AccountingExportTestSuite) TestAccountingExport_ExportAccountingEntries_GlCsv_PersistedJournal_Ok() {
+	journal := &intPkg.JournalEntry{
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+		MerchantId:         suite.merchant.Id,
+		SourceId:           suite.merchant.Id,
+		SourceType:         "merchant",
+		EventType:          accountingEventTypeManualCorrection,
+		CreatedAt:          time.Now().Add(-1 * time.Hour),
+		Lines: []*intPkg.JournalLine{
+			{Account: pkg.LedgerAccountGatewayReceivable, Debit: 12.5, Currency: "EUR", AccountingEntryType: pkg.AccountingEntryTypeRealGrossRevenue},
+			{Account: pkg.LedgerAccountMerchantPayable, Credit: 12.5, Currency: "EUR", AccountingEntryType: pkg.AccountingEntryTypeRealGrossRevenue},
+		},
+	}
+	err := suite.service.journalEntryRepository.MultipleInsert(context.TODO(), []*intPkg.JournalEntry{journal})
+	assert.NoError(suite.T(), err)
+
+	// the general ledger is exported from the persisted journal entries, not from the accounting entries
+	rsp := suite.exportAccountingEntries(pkg.AccountingExportFormatGlCsv)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 2, rsp.Item.RowsCount)
+
+	rows := suite.readCsv(rsp.Item.File)
+	assert.Equal(suite.T(), journal.Id.Hex(), rows[1][1])
+	assert.Equal(suite.T(), "12.50", rows[1][3])
+	assert.Equal(suite.T(), "EUR", rows[1][5])
+	assert.Equal(suite.T(), "12.50", rows[2][4])
+}
+
+func (suite *AccountingExportTestSuite) TestAccountingExport_ExportAccountingEntries_GlCsv_RepositoryError() {
+	journalEntryRep := &mocks.JournalEntryRepositoryInterface{}
+	journalEntryRep.On("FindByPeriod", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, errors.New("some error"))
+	suite.service.journalEntryRepository = journalEntryRep
+
+	rsp := suite.exportAccountingEntries(pkg.AccountingExportFormatGlCsv)
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+	assert.Equal(suite.T(), accountingEntryErrorUnknown, rsp.Message)
+}
+
+func (suite *AccountingExportTestSuite) TestAccountingExport_ExportAccountingEntries_OtherPeriod_Ok() {
+	suite.createAccountingEntry(pkg.AccountingEntryTypeMerchantRoyaltyCorrection, 100)
+
+	req := &pkg.ExportAccountingEntriesRequest{
+		DateFrom: time.Now().Add(-72 * time.Hour).Unix(),
+		DateTo:   time.Now().Add(-48 * time.Hour).Unix(),
+	}
+	rsp := &pkg.ExportAccountingEntriesResponse{}
+	err := suite.service.ExportAccountingEntries(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), pkg.AccountingExportFormatCsv, req.Format)
+	assert.EqualValues(suite.T(), 0, rsp.Item.RowsCount)
+	assert.Len(suite.T(), suite.readCsv(rsp.Item.File), 1)
+}
+
+func (suite *AccountingExportTestSuite) TestAccountingExport_ExportAccountingEntries_PeriodInvalid_Error() {
+	req := &pkg.ExportAccountingEntriesRequest{
+		DateFrom: time.Now().Unix(),
+		DateTo:   time.Now().Add(-1 * time.Hour).Unix(),
+	}
+	rsp := &pkg.ExportAccountingEntriesResponse{}
+	err := suite.service.ExportAccountingEntries(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), accountingEntryErrorExportPeriodInvalid, rsp.Message)
+	assert.Nil(suite.T(), rsp.Item)
+}
+
+func (suite *AccountingExportTestSuite) TestAccountingExport_ExportAccountingEntries_FormatInvalid_Error() {
+	rsp := suite.exportAccountingEntries("saf-t")
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), accountingEntryErrorExportFormatInvalid, rsp.Message)
+	assert.Nil(suite.T(), rsp.Item)
+}
+
+func (suite *AccountingExportTestSuite) TestAccountingExport_ExportAccountingEntries_OperatingCompanyNotFound_Error() {
+	req := &pkg.ExportAccountingEntriesRequest{
+		OperatingCompanyId: "5bdc39a95d1e1100019fb7df",
+		DateFrom:           time.Now().Add(-24 * time.Hour).Unix(),
+		DateTo:             time.Now().Add(time.Hour).Unix(),
+	}
+	rsp := &pkg.ExportAccountingEntriesResponse{}
+	err := suite.service.ExportAccountingEntries(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), errorOperatingCompanyNotFound, rsp.Message)
+}
+
+func (suite *AccountingExportTestSuite) TestAccountingExport_ExportAccountingEntries_RepositoryError() {
+	accountingRep := &mocks.AccountingEntryRepositoryInterface{}
+	accountingRep.On("GetExportSummary", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]*intPkg.AccountingExportQueryResItem(nil), errors.New("some error"))
+	suite.service.accountingRepository = accountingRep
+
+	rsp := suite.exportAccountingEntries(pkg.AccountingExportFormatCsv)
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+	assert.Equal(suite.T(), accountingEntryErrorUnknown, rsp.Message)
+}
+
+func (suite *AccountingExportTestSuite) TestAccountingExport_GetAccountingExportRate_Ok() {
+	item := &intPkg.AccountingExportQueryResItem{
+		Type:             pkg.AccountingEntryTypeRealGrossRevenue,
+		Currency:         "USD",
+		Amount:           20,
+		OriginalCurrency: "RUB",
+		OriginalAmount:   1000,
+	}
+	rate, rateType := getAccountingExportRate(item)
+	assert.Equal(suite.T(), 0.02, rate)
+	assert.Equal(suite.T(), currenciespb.RateTypePaysuper, rateType)
+
+	item.Type = pkg.AccountingEntryTypeMerchantTaxFeeCentralBankFx
+	_, rateType = getAccountingExportRate(item)
+	assert.Equal(suite.T(), currenciespb.RateTypeCentralbanks, rateType)
+}
+
+func (suite *AccountingExportTestSuite) exportAccountingEntries(format string) *pkg.ExportAccountingEntriesResponse {
+	req := &pkg.ExportAccountingEntriesRequest{
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+		DateFrom:           time.Now().Add(-24 * time.Hour).Unix(),
+		DateTo:             time.Now().Add(time.Hour).Unix(),
+		Format:             format,
+	}
+	rsp := &pkg.ExportAccountingEntriesResponse{}
+	err := suite.service.ExportAccountingEntries(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *AccountingExportTestSuite) readCsv(file []byte) [][]string {
+	rows, err := csv.NewReader(bytes.NewReader(file)).ReadAll()
+	assert.NoError(suite.T(), err)
+
+	return rows
+}
@@ -124,7 +124,7 @@ func (s *Service) GetActOfCompletion(
 		from:    dateFrom,
 		to:      dateTo,
 	}
-	report, _, err := royaltyHandler.buildMerchantRoyaltyReportRoundedAmounts(ctx, merchant, false)
+	report, _, err := royaltyHandler.buildMerchantRoyaltyReportRoundedAmounts(ctx, merchant, merchant.GetPayoutCurrency(), false)
 	if err != nil {
 		return err
 	}
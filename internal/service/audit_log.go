@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	auditLogActorMetadataField = "X-User-Id"
+	auditLogIpMetadataField    = "X-Real-Ip"
+
+	auditLogDefaultLimit    = 100
+	auditLogVerifyBatchSize = 1000
+	auditLogChainBatchSize  = 100
+)
+
+var (
+	auditLogErrorUnknown       = errors.NewBillingServerErrorMsg("al000001", "unknown error. try request later")
+	auditLogErrorPeriodInvalid = errors.NewBillingServerErrorMsg("al000002", "end date of the period must be later than start date")
+)
+
+// auditLogRecord is the change of the entity made by the RPC. Before and after are states of the entity,
+// nil state means that the entity didn't exist before the change or was removed by the change.
+type auditLogRecord struct {
+	rpc        string
+	entityType string
+	entityId   string
+	ip         string
+	before     interface{}
+	after      interface{}
+}
+
+// addAuditLog adds the entry with the changed fields of the entity to the audit log. The actor and the IP address
+// are taken from the request metadata, the IP address of the record is used if the request passes it explicitly.
+// The database doesn't support transactions of several collections, so the request must fail when the entry
+// can't be added, otherwise the change would be saved without the audit trail. The entry is added as pending
+// and is chained right after that, the failed chaining doesn't fail the request because pending entries are chained
+// by the next request or by the task.
+func (s *Service) addAuditLog(ctx context.Context, record *auditLogRecord) error {
+	changes, err := getAuditLogChanges(record.before, record.after)
+
+	if err != nil {
+		zap.L().Error(
+			"Unable to get changes of the entity for the audit log",
+			zap.Error(err),
+			zap.String("rpc", record.rpc),
+			zap.String("entity_type", record.entityType),
+			zap.String("entity_id", record.entityId),
+		)
+		return err
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	entry := &intPkg.AuditLogEntry{
+		ActorId:    getMetadataValue(ctx, auditLogActorMetadataField),
+		Ip:         getMetadataValue(ctx, auditLogIpMetadataField),
+		Rpc:        record.rpc,
+		EntityType: record.entityType,
+		EntityId:   record.entityId,
+		Changes:    changes,
+		// Database keeps dates with milliseconds precision, so more precise date would change the hash after reading.
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+
+	if record.ip != "" {
+		entry.Ip = record.ip
+	}
+
+	if err = s.auditLogRepository.Insert(ctx, entry); err != nil {
+		zap.L().Error(
+			"Unable to add entry to the audit log",
+			zap.Error(err),
+			zap.Any("entry", entry),
+		)
+		return err
+	}
+
+	if err = s.ChainAuditLog(ctx); err != nil {
+		zap.L().Warn(
+			"Unable to chain entries of the audit log",
+			zap.Error(err),
+			zap.String("entry_id", entry.Id.Hex()),
+		)
+	}
+
+	return nil
+}
+
+// ChainAuditLog chains pending entries of the audit log in the order of addition: the entry gets the next sequence
+// number and is hashed with the hash of the last chained entry. Entries chained concurrently get the same sequence
+// number, the unique index of the sequence rejects all of them except the first one, so the rest of pending entries
+// are left to the request which chained the first one.
+func (s *Service) ChainAuditLog(ctx context.Context) error {
+	for {
+		entries, err := s.auditLogRepository.FindPending(ctx, auditLogChainBatchSize)
+
+		if err != nil || len(entries) == 0 {
+			return err
+		}
+
+		last, err := s.auditLogRepository.GetLast(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			entry.Sequence = 1
+			entry.PreviousHash = ""
+
+			if last != nil {
+				entry.Sequence = last.Sequence + 1
+				entry.PreviousHash = last.Hash
+			}
+
+			entry.Hash, err = getAuditLogHash(entry, s.cfg.AuditLogHmacSecret)
+
+			if err != nil {
+				return err
+			}
+
+			ok, err := s.auditLogRepository.Chain(ctx, entry)
+
+			if err != nil {
+				if mongodb.IsDuplicate(err) {
+					return nil
+				}
+				return err
+			}
+
+			// The entry is chained concurrently, so the last entry must be read again
+			if !ok {
+				break
+			}
+
+			last = entry
+		}
+	}
+}
+
+// ListAuditLog returns entries of the audit log by entity, actor and period, the newest entries go first.
+func (s *Service) ListAuditLog(
+	ctx context.Context,
+	req *pkg.ListAuditLogRequest,
+	rsp *pkg.ListAuditLogResponse,
+) error {
+	if req.DateFrom > 0 && req.DateTo > 0 && req.DateTo <= req.DateFrom {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = auditLogErrorPeriodInvalid
+		return nil
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = auditLogDefaultLimit
+	}
+
+	var from, to time.Time
+
+	if req.DateFrom > 0 {
+		from = time.Unix(req.DateFrom, 0).UTC()
+	}
+
+	if req.DateTo > 0 {
+		to = time.Unix(req.DateTo, 0).UTC()
+	}
+
+	count, err := s.auditLogRepository.FindCount(ctx, req.EntityType, req.EntityId, req.ActorId, from, to)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = auditLogErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Count = count
+	rsp.Items = []*pkg.AuditLogEntry{}
+
+	if count <= 0 {
+		return nil
+	}
+
+	entries, err := s.auditLogRepository.Find(
+		ctx,
+		req.EntityType,
+		req.EntityId,
+		req.ActorId,
+		from,
+		to,
+		req.Offset,
+		req.Limit,
+	)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = auditLogErrorUnknown
+		return nil
+	}
+
+	for _, entry := range entries {
+		rsp.Items = append(rsp.Items, getAuditLogEntryMessage(entry))
+	}
+
+	return nil
+}
+
+// VerifyAuditLog walks the audit log chain from the first entry and checks sequence numbers and hashes of entries.
+// The first entry which is changed, removed or follows the removed entry is returned as broken.
+func (s *Service) VerifyAuditLog(
+	ctx context.Context,
+	_ *billingpb.EmptyRequest,
+	rsp *pkg.VerifyAuditLogResponse,
+) error {
+	verification := &pkg.AuditLogVerification{IsValid: true}
+	sequence := int64(1)
+	previousHash := ""
+
+	for {
+		entries, err := s.auditLogRepository.FindBySequence(ctx, sequence, auditLogVerifyBatchSize)
+
+		if err != nil {
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = auditLogErrorUnknown
+			return nil
+		}
+
+		for _, entry := range entries {
+			hash, err := getAuditLogHash(entry, s.cfg.AuditLogHmacSecret)
+
+			if err != nil || entry.Sequence != sequence || entry.PreviousHash != previousHash ||
+				!hmac.Equal([]byte(entry.Hash), []byte(hash)) {
+				verification.IsValid = false
+				verification.BrokenSequence = sequence
+				rsp.Status = billingpb.ResponseStatusOk
+				rsp.Item = verification
+				return nil
+			}
+
+			verification.EntriesCount++
+			previousHash = entry.Hash
+			sequence++
+		}
+
+		if len(entries) < auditLogVerifyBatchSize {
+			break
+		}
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = verification
+
+	return nil
+}
+
+// getAuditLogHash returns the HMAC-SHA256 of the audit log entry signed with the secret. The hash covers all fields
+// of the entry except the identifier, including the hash of the previous entry, so the chain can't be rebuilt
+// without the secret after the entries are changed in the database.
+func getAuditLogHash(entry *intPkg.AuditLogEntry, secret string) (string, error) {
+	data, err := json.Marshal([]interface{}{
+		entry.Sequence,
+		entry.ActorId,
+		entry.Ip,
+		entry.Rpc,
+		entry.EntityType,
+		entry.EntityId,
+		entry.Changes,
+		entry.PreviousHash,
+		entry.CreatedAt.UnixNano() / int64(time.Millisecond),
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// getAuditLogChanges compares JSON representations of the entity states and returns the changed fields
+// in alphabetical order.
+func getAuditLogChanges(before, after interface{}) ([]*intPkg.AuditLogChange, error) {
+	beforeFields, err := getAuditLogFields(before)
+
+	if err != nil {
+		return nil, err
+	}
+
+	afterFields, err := getAuditLogFields(after)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	for name := range beforeFields {
+		names = append(names, name)
+	}
+
+	for name := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	var changes []*intPkg.AuditLogChange
+
+	for _, name := range names {
+		if beforeFields[name] == afterFields[name] {
+			continue
+		}
+
+		changes = append(changes, &intPkg.AuditLogChange{
+			Field:  name,
+			Before: beforeFields[name],
+			After:  afterFields[name],
+		})
+	}
+
+	return changes, nil
+}
+
+// getAuditLogFields returns JSON representations of the scalar fields of the value by their paths.
+// Empty fields aren't returned.
+func getAuditLogFields(value interface{}) (map[string]string, error) {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded interface{}
+
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]string)
+
+	if err = flattenAuditLogValue(fields, "", decoded); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func flattenAuditLogValue(fields map[string]string, path string, value interface{}) error {
+	switch val := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		for key, item := range val {
+			if err := flattenAuditLogValue(fields, getAuditLogFieldPath(path, key), item); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			if err := flattenAuditLogValue(fields, getAuditLogFieldPath(path, strconv.Itoa(i)), item); err != nil {
+				return err
+			}
+		}
+	default:
+		data, err := json.Marshal(val)
+
+		if err != nil {
+			return err
+		}
+
+		fields[path] = string(data)
+	}
+
+	return nil
+}
+
+func getAuditLogFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func getAuditLogEntryMessage(entry *intPkg.AuditLogEntry) *pkg.AuditLogEntry {
+	msg := &pkg.AuditLogEntry{
+		Id:           entry.Id.Hex(),
+		Sequence:     entry.Sequence,
+		ActorId:      entry.ActorId,
+		Ip:           entry.Ip,
+		Rpc:          entry.Rpc,
+		EntityType:   entry.EntityType,
+		EntityId:     entry.EntityId,
+		Changes:      []*pkg.AuditLogChange{},
+		PreviousHash: entry.PreviousHash,
+		Hash:         entry.Hash,
+		CreatedAt:    getTimestampProto(entry.CreatedAt),
+	}
+
+	for _, change := range entry.Changes {
+		msg.Changes = append(msg.Changes, &pkg.AuditLogChange{
+			Field:  change.Field,
+			Before: change.Before,
+			After:  change.After,
+		})
+	}
+
+	return msg
+}
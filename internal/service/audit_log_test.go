@@ -0,0 +1,451 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/micro/go-micro/metadata"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type AuditLogTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant *billingpb.Merchant
+}
+
+func Test_AuditLog(t *testing.T) {
+	suite.Run(t, new(AuditLogTestSuite))
+}
+
+func (suite *AuditLogTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	suite.merchant, _, _, _, _ = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+}
+
+func (suite *AuditLogTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *AuditLogTestSuite) getContext(actorId string) context.Context {
+	return metadata.NewContext(context.TODO(), metadata.Metadata{
+		"x-user-id": actorId,
+		"X-Real-IP": "127.0.0.1",
+	})
+}
+
+func (suite *AuditLogTestSuite) changeManualPayouts(ctx context.Context, enabled bool) {
+	req := &billingpb.ChangeMerchantManualPayoutsRequest{
+		MerchantId:           suite.merchant.Id,
+		ManualPayoutsEnabled: enabled,
+	}
+	rsp := &billingpb.ChangeMerchantManualPayoutsResponse{}
+	err := suite.service.ChangeMerchantManualPayouts(ctx, req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+}
+
+func (suite *AuditLogTestSuite) listAuditLog(req *pkg.ListAuditLogRequest) *pkg.ListAuditLogResponse {
+	rsp := &pkg.ListAuditLogResponse{}
+	err := suite.service.ListAuditLog(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *AuditLogTestSuite) verifyAuditLog() *pkg.AuditLogVerification {
+	rsp := &pkg.VerifyAuditLogResponse{}
+	err := suite.service.VerifyAuditLog(context.TODO(), &billingpb.EmptyRequest{}, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	return rsp.Item
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_ChangeMerchantManualPayouts_Ok() {
+	actorId := "5be2c3022b9bb6000765d132"
+	suite.changeManualPayouts(suite.getContext(actorId), !suite.merchant.ManualPayoutsEnabled)
+
+	rsp := suite.listAuditLog(&pkg.ListAuditLogRequest{
+		EntityType: pkg.AuditLogEntityMerchant,
+		EntityId:   suite.merchant.Id,
+	})
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 1, rsp.Count)
+	assert.Len(suite.T(), rsp.Items, 1)
+
+	entry := rsp.Items[0]
+	assert.EqualValues(suite.T(), 1, entry.Sequence)
+	assert.Equal(suite.T(), actorId, entry.ActorId)
+	assert.Equal(suite.T(), "127.0.0.1", entry.Ip)
+	assert.Equal(suite.T(), "ChangeMerchantManualPayouts", entry.Rpc)
+	assert.Empty(suite.T(), entry.PreviousHash)
+	assert.NotEmpty(suite.T(), entry.Hash)
+
+	var change *pkg.AuditLogChange
+
+	for _, val := range entry.Changes {
+		if val.Field == "manual_payouts_enabled" {
+			change = val
+		}
+	}
+
+	assert.NotNil(suite.T(), change)
+	assert.Equal(suite.T(), "true", change.After)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_NotChanged_Skipped() {
+	err := suite.service.addAuditLog(context.TODO(), &auditLogRecord{
+		rpc:        "ChangeMerchantManualPayouts",
+		entityType: pkg.AuditLogEntityMerchant,
+		entityId:   suite.merchant.Id,
+		before:     suite.merchant,
+		after:      suite.merchant,
+	})
+	assert.NoError(suite.T(), err)
+
+	rsp := suite.listAuditLog(&pkg.ListAuditLogRequest{})
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 0, rsp.Count)
+	assert.Empty(suite.T(), rsp.Items)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_RecordIp_Ok() {
+	err := suite.service.addAuditLog(suite.getContext("actor"), &auditLogRecord{
+		rpc:        "UpdatePayoutDocument",
+		entityType: pkg.AuditLogEntityPayoutDocument,
+		entityId:   "payout",
+		ip:         "10.0.0.1",
+		before:     &billingpb.PayoutDocument{Status: pkg.PayoutDocumentStatusPending},
+		after:      &billingpb.PayoutDocument{Status: pkg.PayoutDocumentStatusPaid},
+	})
+	assert.NoError(suite.T(), err)
+
+	rsp := suite.listAuditLog(&pkg.ListAuditLogRequest{EntityType: pkg.AuditLogEntityPayoutDocument})
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Len(suite.T(), rsp.Items, 1)
+	assert.Equal(suite.T(), "10.0.0.1", rsp.Items[0].Ip)
+	assert.Len(suite.T(), rsp.Items[0].Changes, 1)
+	assert.Equal(suite.T(), "status", rsp.Items[0].Changes[0].Field)
+	assert.Equal(suite.T(), `"pending"`, rsp.Items[0].Changes[0].Before)
+	assert.Equal(suite.T(), `"paid"`, rsp.Items[0].Changes[0].After)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_ListAuditLog_Filters_Ok() {
+	suite.changeManualPayouts(suite.getContext("actor1"), !suite.merchant.ManualPayoutsEnabled)
+	suite.changeManualPayouts(suite.getContext("actor2"), suite.merchant.ManualPayoutsEnabled)
+	suite.changeManualPayouts(suite.getContext("actor1"), !suite.merchant.ManualPayoutsEnabled)
+
+	rsp := suite.listAuditLog(&pkg.ListAuditLogRequest{ActorId: "actor1"})
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 2, rsp.Count)
+	assert.Len(suite.T(), rsp.Items, 2)
+	assert.EqualValues(suite.T(), 3, rsp.Items[0].Sequence)
+	assert.EqualValues(suite.T(), 1, rsp.Items[1].Sequence)
+
+	rsp = suite.listAuditLog(&pkg.ListAuditLogRequest{Limit: 1, Offset: 1})
+	assert.EqualValues(suite.T(), 3, rsp.Count)
+	assert.Len(suite.T(), rsp.Items, 1)
+	assert.EqualValues(suite.T(), 2, rsp.Items[0].Sequence)
+
+	rsp = suite.listAuditLog(&pkg.ListAuditLogRequest{
+		DateFrom: time.Now().Add(-1 * time.Hour).Unix(),
+		DateTo:   time.Now().Add(1 * time.Hour).Unix(),
+	})
+	assert.EqualValues(suite.T(), 3, rsp.Count)
+
+	rsp = suite.listAuditLog(&pkg.ListAuditLogRequest{DateFrom: time.Now().Add(1 * time.Hour).Unix()})
+	assert.EqualValues(suite.T(), 0, rsp.Count)
+
+	rsp = suite.listAuditLog(&pkg.ListAuditLogRequest{EntityType: pkg.AuditLogEntityUserRole})
+	assert.EqualValues(suite.T(), 0, rsp.Count)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_ListAuditLog_PeriodInvalid_Error() {
+	rsp := suite.listAuditLog(&pkg.ListAuditLogRequest{
+		DateFrom: time.Now().Unix(),
+		DateTo:   time.Now().Add(-1 * time.Hour).Unix(),
+	})
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), auditLogErrorPeriodInvalid, rsp.Message)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_ListAuditLog_RepositoryError() {
+	repository := &mocks.AuditLogRepositoryInterface{}
+	repository.On("FindCount", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(int64(0), errors.New("some error"))
+	suite.service.auditLogRepository = repository
+
+	rsp := suite.listAuditLog(&pkg.ListAuditLogRequest{})
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+	assert.Equal(suite.T(), auditLogErrorUnknown, rsp.Message)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_RepositoryError_RequestFailed() {
+	repository := &mocks.AuditLogRepositoryInterface{}
+	repository.On("Insert", mock.Anything, mock.Anything).Return(errors.New("some error"))
+	suite.service.auditLogRepository = repository
+
+	req := &billingpb.ChangeMerchantManualPayoutsRequest{
+		MerchantId:           suite.merchant.Id,
+		ManualPayoutsEnabled: !suite.merchant.ManualPayoutsEnabled,
+	}
+	rsp := &billingpb.ChangeMerchantManualPayoutsResponse{}
+	err := suite.service.ChangeMerchantManualPayouts(suite.getContext("actor"), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+	assert.Equal(suite.T(), auditLogErrorUnknown, rsp.Message)
+	repository.AssertNumberOfCalls(suite.T(), "Insert", 1)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_ChainError_RequestNotFailed() {
+	repository := &mocks.AuditLogRepositoryInterface{}
+	repository.On("Insert", mock.Anything, mock.Anything).Return(nil)
+	repository.On("FindPending", mock.Anything, mock.Anything).Return(nil, errors.New("some error"))
+	suite.service.auditLogRepository = repository
+
+	suite.changeManualPayouts(suite.getContext("actor"), !suite.merchant.ManualPayoutsEnabled)
+	repository.AssertNumberOfCalls(suite.T(), "Insert", 1)
+	repository.AssertNotCalled(suite.T(), "Chain", mock.Anything, mock.Anything)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_ChainAuditLog_PendingEntries_Ok() {
+	suite.changeManualPayouts(suite.getContext("actor"), !suite.merchant.ManualPayoutsEnabled)
+
+	for _, entityId := range []string{"entity_1", "entity_2"} {
+		err := suite.service.auditLogRepository.Insert(context.TODO(), &intPkg.AuditLogEntry{
+			Rpc:        "ChangeMerchantManualPayouts",
+			EntityType: pkg.AuditLogEntityMerchant,
+			EntityId:   entityId,
+			Changes:    []*intPkg.AuditLogChange{{Field: "manual_payouts_enabled", Before: "false", After: "true"}},
+			CreatedAt:  time.Now().UTC().Truncate(time.Millisecond),
+		})
+		assert.NoError(suite.T(), err)
+	}
+
+	verification := suite.verifyAuditLog()
+	assert.True(suite.T(), verification.IsValid)
+	assert.EqualValues(suite.T(), 1, verification.EntriesCount)
+
+	err := suite.service.ChainAuditLog(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	pending, err := suite.service.auditLogRepository.FindPending(context.TODO(), auditLogChainBatchSize)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), pending)
+
+	rsp := suite.listAuditLog(&pkg.ListAuditLogRequest{})
+	assert.Len(suite.T(), rsp.Items, 3)
+	assert.EqualValues(suite.T(), 3, rsp.Items[0].Sequence)
+	assert.Equal(suite.T(), "entity_2", rsp.Items[0].EntityId)
+	assert.Equal(suite.T(), rsp.Items[1].Hash, rsp.Items[0].PreviousHash)
+
+	verification = suite.verifyAuditLog()
+	assert.True(suite.T(), verification.IsValid)
+	assert.EqualValues(suite.T(), 3, verification.EntriesCount)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_VerifyAuditLog_Ok() {
+	verification := suite.verifyAuditLog()
+	assert.True(suite.T(), verification.IsValid)
+	assert.EqualValues(suite.T(), 0, verification.EntriesCount)
+
+	suite.changeManualPayouts(suite.getContext("actor"), !suite.merchant.ManualPayoutsEnabled)
+	suite.changeManualPayouts(suite.getContext("actor"), suite.merchant.ManualPayoutsEnabled)
+
+	rsp := suite.listAuditLog(&pkg.ListAuditLogRequest{})
+	assert.Len(suite.T(), rsp.Items, 2)
+	assert.Equal(suite.T(), rsp.Items[1].Hash, rsp.Items[0].PreviousHash)
+
+	verification = suite.verifyAuditLog()
+	assert.True(suite.T(), verification.IsValid)
+	assert.EqualValues(suite.T(), 2, verification.EntriesCount)
+	assert.Zero(suite.T(), verification.BrokenSequence)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_VerifyAuditLog_EntryChanged() {
+	suite.changeManualPayouts(suite.getContext("actor"), !suite.merchant.ManualPayoutsEnabled)
+	suite.changeManualPayouts(suite.getContext("actor"), suite.merchant.ManualPayoutsEnabled)
+	suite.changeManualPayouts(suite.getContext("actor"), !suite.merchant.ManualPayoutsEnabled)
+
+	_, err := suite.service.db.Collection("audit_log").UpdateOne(
+		context.TODO(),
+		bson.M{"sequence": 2},
+		bson.M{"$set": bson.M{"actor_id": "another_actor"}},
+	)
+	assert.NoError(suite.T(), err)
+
+	verification := suite.verifyAuditLog()
+	assert.False(suite.T(), verification.IsValid)
+	assert.EqualValues(suite.T(), 1, verification.EntriesCount)
+	assert.EqualValues(suite.T(), 2, verification.BrokenSequence)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_VerifyAuditLog_EntryRemoved() {
+	suite.changeManualPayouts(suite.getContext("actor"), !suite.merchant.ManualPayoutsEnabled)
+	suite.changeManualPayouts(suite.getContext("actor"), suite.merchant.ManualPayoutsEnabled)
+	suite.changeManualPayouts(suite.getContext("actor"), !suite.merchant.ManualPayoutsEnabled)
+
+	_, err := suite.service.db.Collection("audit_log").DeleteOne(context.TODO(), bson.M{"sequence": 2})
+	assert.NoError(suite.T(), err)
+
+	verification := suite.verifyAuditLog()
+	assert.False(suite.T(), verification.IsValid)
+	assert.EqualValues(suite.T(), 2, verification.BrokenSequence)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_VerifyAuditLog_AnotherSecret() {
+	suite.changeManualPayouts(suite.getContext("actor"), !suite.merchant.ManualPayoutsEnabled)
+
+	verification := suite.verifyAuditLog()
+	assert.True(suite.T(), verification.IsValid)
+
+	suite.service.cfg.AuditLogHmacSecret = "another_secret"
+
+	verification = suite.verifyAuditLog()
+	assert.False(suite.T(), verification.IsValid)
+	assert.EqualValues(suite.T(), 1, verification.BrokenSequence)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_GetAuditLogHash_Ok() {
+	entry := &intPkg.AuditLogEntry{
+		Sequence:  1,
+		ActorId:   "actor",
+		Rpc:       "ChangeMerchantManualPayouts",
+		CreatedAt: time.Now().UTC(),
+	}
+
+	hash, err := getAuditLogHash(entry, "secret")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), hash, 64)
+
+	sameHash, err := getAuditLogHash(entry, "secret")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), hash, sameHash)
+
+	anotherHash, err := getAuditLogHash(entry, "another_secret")
+	assert.NoError(suite.T(), err)
+	assert.NotEqual(suite.T(), hash, anotherHash)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_VerifyAuditLog_RepositoryError() {
+	repository := &mocks.AuditLogRepositoryInterface{}
+	repository.On("FindBySequence", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, errors.New("some error"))
+	suite.service.auditLogRepository = repository
+
+	rsp := &pkg.VerifyAuditLogResponse{}
+	err := suite.service.VerifyAuditLog(context.TODO(), &billingpb.EmptyRequest{}, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+	assert.Equal(suite.T(), auditLogErrorUnknown, rsp.Message)
+}
+
+func (suite *AuditLogTestSuite) TestAuditLog_GetAuditLogChanges_Ok() {
+	before := map[string]interface{}{
+		"status": "pending",
+		"tariff": map[string]interface{}{"region": "europe", "rates": []float64{1, 2}},
+		"name":   "merchant",
+	}
+	after := map[string]interface{}{
+		"status": "pending",
+		"tariff": map[string]interface{}{"region": "asia", "rates": []float64{1, 3}},
+	}
+
+	changes, err := getAuditLogChanges(before, after)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []*intPkg.AuditLogChange{
+		{Field: "name", Before: `"merchant"`, After: ""},
+		{Field: "tariff.rates.1", Before: "2", After: "3"},
+		{Field: "tariff.region", Before: `"europe"`, After: `"asia"`},
+	}, changes)
+
+	changes, err = getAuditLogChanges(nil, map[string]interface{}{"amount": 10})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []*intPkg.AuditLogChange{{Field: "amount", Before: "", After: "10"}}, changes)
+}
@@ -0,0 +1,547 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/paysuper/paysuper-billing-server/internal/helper"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	disputeDefaultLimit         = int64(100)
+	disputeChargebackReasonMask = "Chargeback by dispute #%s, reason code %s"
+
+	disputeOpenedMessage = "Chargeback dispute was opened for the order #%s with the reason code %s. Submit evidence documents till %s to defend the payment."
+	disputeWonMessage    = "Chargeback dispute for the order #%s is won. The payment stays in your balance."
+	disputeLostMessage   = "Chargeback dispute for the order #%s is lost. The payment amount with chargeback fees is deducted from your balance."
+)
+
+var (
+	disputeErrorUnknown                = errors.NewBillingServerErrorMsg("dp000001", "dispute can't be processed. try request later")
+	disputeErrorNotFound               = errors.NewBillingServerErrorMsg("dp000002", "dispute with specified data not found")
+	disputeErrorOrderNotFound          = errors.NewBillingServerErrorMsg("dp000003", "order for dispute not found")
+	disputeErrorOrderNotProcessed      = errors.NewBillingServerErrorMsg("dp000004", "dispute can be opened only for processed order")
+	disputeErrorAlreadyOpened          = errors.NewBillingServerErrorMsg("dp000005", "order already has opened dispute")
+	disputeErrorEvidenceDueAtInvalid   = errors.NewBillingServerErrorMsg("dp000006", "evidence due date of dispute must be in the future")
+	disputeErrorNotOpen                = errors.NewBillingServerErrorMsg("dp000007", "evidence can't be changed after representment submission or dispute closing")
+	disputeErrorEvidenceDeadlinePassed = errors.NewBillingServerErrorMsg("dp000008", "evidence due date of dispute has passed")
+	disputeErrorEvidenceRequired       = errors.NewBillingServerErrorMsg("dp000009", "at least one evidence document is required to submit representment")
+	disputeErrorAlreadyClosed          = errors.NewBillingServerErrorMsg("dp000010", "dispute already closed")
+	disputeErrorOutcomeInvalid         = errors.NewBillingServerErrorMsg("dp000011", "dispute outcome must be won or lost")
+	disputeErrorChargebackFailed       = errors.NewBillingServerErrorMsg("dp000012", "chargeback by lost dispute can't be created")
+)
+
+// CreateDispute opens the chargeback dispute for the processed order. Only one dispute of the order can be open
+// at the same time. Merchant is notified about the dispute and the evidence due date.
+func (s *Service) CreateDispute(
+	ctx context.Context,
+	req *pkg.CreateDisputeRequest,
+	rsp *pkg.DisputeResponse,
+) error {
+	order, err := s.orderRepository.GetByUuidAndMerchantId(ctx, req.OrderId, req.MerchantId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = disputeErrorOrderNotFound
+		return nil
+	}
+
+	if order.GetPublicStatus() != recurringpb.OrderPublicStatusProcessed {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = disputeErrorOrderNotProcessed
+		return nil
+	}
+
+	existing, err := s.disputeRepository.GetOpenByOrderId(ctx, order.Id)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = disputeErrorUnknown
+		return nil
+	}
+
+	if existing != nil {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = disputeErrorAlreadyOpened
+		return nil
+	}
+
+	evidenceDueAt := time.Now().Add(s.cfg.GetDisputeEvidencePeriod())
+
+	if req.EvidenceDueAt != nil {
+		evidenceDueAt, err = ptypes.Timestamp(req.EvidenceDueAt)
+
+		if err != nil || evidenceDueAt.Before(time.Now()) {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = disputeErrorEvidenceDueAtInvalid
+			return nil
+		}
+	}
+
+	dispute := &intPkg.Dispute{
+		OrderUuid:     order.Uuid,
+		Status:        pkg.DisputeStatusOpen,
+		IsOpen:        true,
+		ReasonCode:    req.ReasonCode,
+		Reason:        req.Reason,
+		Amount:        order.ChargeAmount,
+		Currency:      order.ChargeCurrency,
+		EvidenceDueAt: evidenceDueAt,
+		Evidence:      []*intPkg.DisputeEvidence{},
+	}
+	dispute.OrderId, _ = primitive.ObjectIDFromHex(order.Id)
+	dispute.MerchantId, _ = primitive.ObjectIDFromHex(order.GetMerchantId())
+	dispute.ProjectId, _ = primitive.ObjectIDFromHex(order.GetProjectId())
+
+	// the unique index of open disputes rejects the concurrent opening of the second dispute of the order
+	if err = s.disputeRepository.Insert(ctx, dispute); err != nil {
+		if mongodb.IsDuplicate(err) {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = disputeErrorAlreadyOpened
+			return nil
+		}
+
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = disputeErrorUnknown
+		return nil
+	}
+
+	s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypeDisputeOpened, map[string]string{
+		pkg.OrderHistoryFieldDisputeId:  dispute.Id.Hex(),
+		pkg.OrderHistoryFieldReasonCode: dispute.ReasonCode,
+	})
+
+	msg := fmt.Sprintf(disputeOpenedMessage, order.Uuid, dispute.ReasonCode, evidenceDueAt.Format(time.RFC3339))
+	s.notifyDisputeMerchant(ctx, dispute, msg)
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getDisputeMessage(dispute)
+
+	return nil
+}
+
+// AddDisputeEvidence adds the evidence document to the open dispute. Evidence can be added till the evidence due date
+// and before the representment submission only.
+func (s *Service) AddDisputeEvidence(
+	ctx context.Context,
+	req *pkg.AddDisputeEvidenceRequest,
+	rsp *pkg.DisputeResponse,
+) error {
+	dispute, err := s.getOpenDispute(ctx, req.DisputeId, req.MerchantId)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseError); ok {
+			rsp.Status = e.Status
+			rsp.Message = e.Message
+			return nil
+		}
+		return err
+	}
+
+	evidence := &intPkg.DisputeEvidence{
+		Id:          primitive.NewObjectID(),
+		Name:        req.Name,
+		Url:         req.Url,
+		Description: req.Description,
+		CreatedAt:   time.Now(),
+	}
+	isAdded, err := s.disputeRepository.AddEvidence(ctx, dispute.Id, evidence)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = disputeErrorUnknown
+		return nil
+	}
+
+	// representment is submitted or the dispute is closed after the dispute was read
+	if !isAdded {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = disputeErrorNotOpen
+		return nil
+	}
+
+	dispute.Evidence = append(dispute.Evidence, evidence)
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getDisputeMessage(dispute)
+
+	return nil
+}
+
+// SubmitDisputeRepresentment submits the evidence documents of the dispute to the issuing bank, after that
+// the dispute waits for the final outcome and the evidence can't be changed.
+func (s *Service) SubmitDisputeRepresentment(
+	ctx context.Context,
+	req *pkg.SubmitDisputeRepresentmentRequest,
+	rsp *pkg.DisputeResponse,
+) error {
+	dispute, err := s.getOpenDispute(ctx, req.DisputeId, req.MerchantId)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseError); ok {
+			rsp.Status = e.Status
+			rsp.Message = e.Message
+			return nil
+		}
+		return err
+	}
+
+	if len(dispute.Evidence) <= 0 {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = disputeErrorEvidenceRequired
+		return nil
+	}
+
+	dispute.Status = pkg.DisputeStatusEvidenceSubmitted
+	dispute.RepresentmentSubmittedAt = time.Now()
+	isUpdated, err := s.disputeRepository.UpdateStatus(ctx, dispute, pkg.DisputeStatusOpen)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = disputeErrorUnknown
+		return nil
+	}
+
+	if !isUpdated {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = disputeErrorNotOpen
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getDisputeMessage(dispute)
+
+	return nil
+}
+
+// SetDisputeOutcome closes the dispute with the final outcome of the issuing bank. The won dispute doesn't change
+// the payment. The lost dispute creates the chargeback of the order with reversal of the order accounting entries.
+// Merchant is notified about the outcome in both cases.
+func (s *Service) SetDisputeOutcome(
+	ctx context.Context,
+	req *pkg.SetDisputeOutcomeRequest,
+	rsp *pkg.DisputeResponse,
+) error {
+	if req.Outcome != pkg.DisputeStatusWon && req.Outcome != pkg.DisputeStatusLost {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = disputeErrorOutcomeInvalid
+		return nil
+	}
+
+	dispute, err := s.disputeRepository.GetById(ctx, req.DisputeId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = disputeErrorNotFound
+		return nil
+	}
+
+	err = s.closeDispute(ctx, dispute, req.Outcome, pkg.DisputeStatusOpen, pkg.DisputeStatusEvidenceSubmitted)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseError); ok {
+			rsp.Status = e.Status
+			rsp.Message = e.Message
+			return nil
+		}
+		return err
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getDisputeMessage(dispute)
+
+	return nil
+}
+
+func (s *Service) GetDispute(
+	ctx context.Context,
+	req *pkg.GetDisputeRequest,
+	rsp *pkg.DisputeResponse,
+) error {
+	dispute, err := s.disputeRepository.GetById(ctx, req.DisputeId)
+
+	if err != nil || (req.MerchantId != "" && dispute.MerchantId.Hex() != req.MerchantId) {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = disputeErrorNotFound
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getDisputeMessage(dispute)
+
+	return nil
+}
+
+func (s *Service) ListDisputes(
+	ctx context.Context,
+	req *pkg.ListDisputesRequest,
+	rsp *pkg.ListDisputesResponse,
+) error {
+	if req.Limit <= 0 {
+		req.Limit = disputeDefaultLimit
+	}
+
+	count, err := s.disputeRepository.FindCount(ctx, req.MerchantId, req.OrderId, req.Status)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = disputeErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Count = count
+	rsp.Items = []*pkg.Dispute{}
+
+	if count <= 0 {
+		return nil
+	}
+
+	disputes, err := s.disputeRepository.Find(ctx, req.MerchantId, req.OrderId, req.Status, req.Offset, req.Limit)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = disputeErrorUnknown
+		return nil
+	}
+
+	for _, dispute := range disputes {
+		rsp.Items = append(rsp.Items, getDisputeMessage(dispute))
+	}
+
+	return nil
+}
+
+// ExpireDisputes closes as lost the open disputes which evidence due date has passed without representment.
+func (s *Service) ExpireDisputes(ctx context.Context) error {
+	disputes, err := s.disputeRepository.FindExpired(ctx, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	for _, dispute := range disputes {
+		// dispute which representment is submitted after the search waits for the outcome of the issuing bank
+		if err = s.closeDispute(ctx, dispute, pkg.DisputeStatusLost, pkg.DisputeStatusOpen); err != nil {
+			zap.L().Error(
+				"expired dispute closing failed",
+				zap.Error(err),
+				zap.String("dispute_id", dispute.Id.Hex()),
+			)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) getOpenDispute(ctx context.Context, id, merchantId string) (*intPkg.Dispute, error) {
+	dispute, err := s.disputeRepository.GetById(ctx, id)
+
+	if err != nil || dispute.MerchantId.Hex() != merchantId {
+		return nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusNotFound, disputeErrorNotFound)
+	}
+
+	if dispute.Status != pkg.DisputeStatusOpen {
+		return nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, disputeErrorNotOpen)
+	}
+
+	if dispute.EvidenceDueAt.Before(time.Now()) {
+		return nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, disputeErrorEvidenceDeadlinePassed)
+	}
+
+	return dispute, nil
+}
+
+// closeDispute closes the dispute with the outcome if the dispute is still in one of the specified statuses, so only
+// one of the concurrent closings changes the dispute. Identifier of the chargeback of the lost dispute is saved with
+// the outcome before the chargeback creation, so the chargeback which failed is completed by the repeated closing of
+// the lost dispute and the order is charged back once only.
+func (s *Service) closeDispute(ctx context.Context, dispute *intPkg.Dispute, outcome string, fromStatuses ...string) error {
+	isResumed := outcome == pkg.DisputeStatusLost && dispute.Status == pkg.DisputeStatusLost && dispute.ChargebackId != ""
+
+	if !isResumed && !helper.Contains(fromStatuses, dispute.Status) {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, disputeErrorAlreadyClosed)
+	}
+
+	order, err := s.getOrderById(ctx, dispute.OrderId.Hex())
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusNotFound, disputeErrorOrderNotFound)
+	}
+
+	if !isResumed {
+		dispute.Status = outcome
+		dispute.IsOpen = false
+		dispute.ClosedAt = time.Now()
+
+		if outcome == pkg.DisputeStatusLost {
+			dispute.ChargebackId = primitive.NewObjectID().Hex()
+		}
+
+		isUpdated, err := s.disputeRepository.UpdateStatus(ctx, dispute, fromStatuses...)
+
+		if err != nil {
+			return errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, disputeErrorUnknown)
+		}
+
+		if !isUpdated {
+			return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, disputeErrorAlreadyClosed)
+		}
+	}
+
+	msg := fmt.Sprintf(disputeWonMessage, order.Uuid)
+
+	if outcome == pkg.DisputeStatusLost {
+		if err = s.chargebackDisputedOrder(ctx, dispute, order); err != nil {
+			return err
+		}
+
+		msg = fmt.Sprintf(disputeLostMessage, order.Uuid)
+	}
+
+	s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypeDisputeClosed, map[string]string{
+		pkg.OrderHistoryFieldDisputeId: dispute.Id.Hex(),
+		pkg.OrderHistoryFieldOutcome:   outcome,
+	})
+	s.notifyDisputeMerchant(ctx, dispute, msg)
+
+	return nil
+}
+
+// chargebackDisputedOrder registers the chargeback of the lost dispute with the identifier saved in the dispute.
+// Money is already withdrawn from the merchant account by the issuing bank, so the chargeback is completed at once
+// without request to the payment system. Chargeback which is created already is completed without creating it again
+// and the completed chargeback isn't processed anymore.
+func (s *Service) chargebackDisputedOrder(ctx context.Context, dispute *intPkg.Dispute, order *billingpb.Order) error {
+	refund, err := s.refundRepository.GetById(ctx, dispute.ChargebackId)
+
+	if err != nil {
+		processor := &createRefundProcessor{
+			service: s,
+			request: &billingpb.CreateRefundRequest{
+				OrderId:      order.Uuid,
+				MerchantId:   order.GetMerchantId(),
+				CreatorId:    primitive.NilObjectID.Hex(),
+				Reason:       fmt.Sprintf(disputeChargebackReasonMask, dispute.Id.Hex(), dispute.ReasonCode),
+				IsChargeback: true,
+			},
+			refundId: dispute.ChargebackId,
+			checked:  &createRefundChecked{},
+			ctx:      ctx,
+		}
+
+		refund, err = processor.processCreateRefund()
+
+		if err != nil {
+			zap.L().Error(
+				pkg.MethodFinishedWithError,
+				zap.String("method", "processCreateRefund"),
+				zap.Error(err),
+				zap.String("dispute_id", dispute.Id.Hex()),
+			)
+			return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, disputeErrorChargebackFailed)
+		}
+
+		order = processor.checked.order
+	}
+
+	if refund.Status == pkg.RefundStatusCompleted {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, disputeErrorAlreadyClosed)
+	}
+
+	// chargeback rejected by the concurrent refund of the order can't be returned with the same identifier
+	if refund.Status == pkg.RefundStatusRejected {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, disputeErrorChargebackFailed)
+	}
+
+	refund.Status = pkg.RefundStatusCompleted
+	refund.UpdatedAt = ptypes.TimestampNow()
+	refundOrder, err := s.createOrderByRefund(ctx, order, refund)
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, disputeErrorChargebackFailed)
+	}
+
+	refund.CreatedOrderId = refundOrder.Id
+
+	if err = s.refundRepository.Update(ctx, refund); err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, disputeErrorUnknown)
+	}
+
+	if err = s.finishRefund(ctx, order, refund, refundOrder); err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "finishRefund"),
+			zap.Error(err),
+			zap.String("refundId", refund.Id),
+			zap.String("dispute_id", dispute.Id.Hex()),
+		)
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, disputeErrorUnknown)
+	}
+
+	return nil
+}
+
+func (s *Service) notifyDisputeMerchant(ctx context.Context, dispute *intPkg.Dispute, msg string) {
+	_, err := s.addNotification(ctx, msg, dispute.MerchantId.Hex(), "", nil)
+
+	if err != nil {
+		zap.L().Error(
+			"dispute notification sending failed",
+			zap.Error(err),
+			zap.String("dispute_id", dispute.Id.Hex()),
+		)
+	}
+}
+
+func getDisputeMessage(dispute *intPkg.Dispute) *pkg.Dispute {
+	msg := &pkg.Dispute{
+		Id:                       dispute.Id.Hex(),
+		OrderId:                  dispute.OrderUuid,
+		MerchantId:               dispute.MerchantId.Hex(),
+		ProjectId:                dispute.ProjectId.Hex(),
+		Status:                   dispute.Status,
+		ReasonCode:               dispute.ReasonCode,
+		Reason:                   dispute.Reason,
+		Amount:                   dispute.Amount,
+		Currency:                 dispute.Currency,
+		EvidenceDueAt:            getTimestampProto(dispute.EvidenceDueAt),
+		Evidence:                 []*pkg.DisputeEvidence{},
+		RepresentmentSubmittedAt: getTimestampProto(dispute.RepresentmentSubmittedAt),
+		ChargebackId:             dispute.ChargebackId,
+		CreatedAt:                getTimestampProto(dispute.CreatedAt),
+		UpdatedAt:                getTimestampProto(dispute.UpdatedAt),
+		ClosedAt:                 getTimestampProto(dispute.ClosedAt),
+	}
+
+	for _, evidence := range dispute.Evidence {
+		msg.Evidence = append(msg.Evidence, &pkg.DisputeEvidence{
+			Id:          evidence.Id.Hex(),
+			Name:        evidence.Name,
+			Url:         evidence.Url,
+			Description: evidence.Description,
+			CreatedAt:   getTimestampProto(evidence.CreatedAt),
+		})
+	}
+
+	return msg
+}
+
+func getTimestampProto(t time.Time) *timestamp.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	ts, _ := ptypes.TimestampProto(t)
+	return ts
+}
@@ -0,0 +1,464 @@
+package service
+
+import (
+	"context"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type DisputeTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+	cookie        string
+}
+
+func Test_Dispute(t *testing.T) {
+	suite.Run(t, new(DisputeTestSuite))
+}
+
+func (suite *DisputeTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	var customer *billingpb.Customer
+	suite.merchant, suite.project, suite.paymentMethod, _, customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.cookie, err = suite.service.generateBrowserCookie(&BrowserCookieCustomer{
+		CustomerId: customer.Id,
+		Ip:         "127.0.0.1",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	})
+
+	if err != nil {
+		suite.FailNow("Generate browser cookie failed", "%v", err)
+	}
+}
+
+func (suite *DisputeTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *DisputeTestSuite) createDispute() (*billingpb.Order, *pkg.Dispute) {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.project, suite.paymentMethod, suite.cookie)
+
+	req := &pkg.CreateDisputeRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.merchant.Id,
+		ReasonCode: "10.4",
+		Reason:     "Other Fraud - Card Absent Environment",
+	}
+	rsp := &pkg.DisputeResponse{}
+	err := suite.service.CreateDispute(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Empty(suite.T(), rsp.Message)
+
+	return order, rsp.Item
+}
+
+func (suite *DisputeTestSuite) addEvidence(dispute *pkg.Dispute) *pkg.DisputeResponse {
+	req := &pkg.AddDisputeEvidenceRequest{
+		DisputeId:   dispute.Id,
+		MerchantId:  suite.merchant.Id,
+		Name:        "delivery_log.pdf",
+		Url:         "https://docs.unit.test/delivery_log.pdf",
+		Description: "unit test",
+	}
+	rsp := &pkg.DisputeResponse{}
+	err := suite.service.AddDisputeEvidence(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *DisputeTestSuite) TestDispute_CreateDispute_Ok() {
+	order, dispute := suite.createDispute()
+	assert.Equal(suite.T(), order.Uuid, dispute.OrderId)
+	assert.Equal(suite.T(), suite.merchant.Id, dispute.MerchantId)
+	assert.Equal(suite.T(), pkg.DisputeStatusOpen, dispute.Status)
+	assert.Equal(suite.T(), order.ChargeAmount, dispute.Amount)
+	assert.Equal(suite.T(), order.ChargeCurrency, dispute.Currency)
+	assert.NotNil(suite.T(), dispute.EvidenceDueAt)
+	assert.Nil(suite.T(), dispute.ClosedAt)
+
+	count, err := suite.service.notificationRepository.FindCount(context.TODO(), suite.merchant.Id, "", 2)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1, count)
+
+	history, err := suite.service.orderHistoryRepository.FindByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), history, 1)
+	assert.Equal(suite.T(), pkg.OrderHistoryTypeDisputeOpened, history[0].Type)
+}
+
+func (suite *DisputeTestSuite) TestDispute_CreateDispute_AlreadyOpened_Error() {
+	order, _ := suite.createDispute()
+
+	req := &pkg.CreateDisputeRequest{OrderId: order.Uuid, MerchantId: suite.merchant.Id, ReasonCode: "13.1"}
+	rsp := &pkg.DisputeResponse{}
+	err := suite.service.CreateDispute(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), disputeErrorAlreadyOpened, rsp.Message)
+}
+
+func (suite *DisputeTestSuite) TestDispute_CreateDispute_EvidenceDueAtInPast_Error() {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.project, suite.paymentMethod, suite.cookie)
+	dueAt, _ := ptypes.TimestampProto(time.Now().Add(-time.Hour))
+
+	req := &pkg.CreateDisputeRequest{
+		OrderId:       order.Uuid,
+		MerchantId:    suite.merchant.Id,
+		ReasonCode:    "10.4",
+		EvidenceDueAt: dueAt,
+	}
+	rsp := &pkg.DisputeResponse{}
+	err := suite.service.CreateDispute(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), disputeErrorEvidenceDueAtInvalid, rsp.Message)
+}
+
+func (suite *DisputeTestSuite) TestDispute_SubmitDisputeRepresentment_Ok() {
+	_, dispute := suite.createDispute()
+
+	rsp := suite.addEvidence(dispute)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Len(suite.T(), rsp.Item.Evidence, 1)
+
+	req := &pkg.SubmitDisputeRepresentmentRequest{DisputeId: dispute.Id, MerchantId: suite.merchant.Id}
+	rsp = &pkg.DisputeResponse{}
+	err := suite.service.SubmitDisputeRepresentment(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), pkg.DisputeStatusEvidenceSubmitted, rsp.Item.Status)
+	assert.NotNil(suite.T(), rsp.Item.RepresentmentSubmittedAt)
+
+	rsp = suite.addEvidence(dispute)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), disputeErrorNotOpen, rsp.Message)
+}
+
+func (suite *DisputeTestSuite) TestDispute_SubmitDisputeRepresentment_WithoutEvidence_Error() {
+	_, dispute := suite.createDispute()
+
+	req := &pkg.SubmitDisputeRepresentmentRequest{DisputeId: dispute.Id, MerchantId: suite.merchant.Id}
+	rsp := &pkg.DisputeResponse{}
+	err := suite.service.SubmitDisputeRepresentment(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), disputeErrorEvidenceRequired, rsp.Message)
+}
+
+func (suite *DisputeTestSuite) TestDispute_AddDisputeEvidence_AnotherMerchant_NotFound() {
+	_, dispute := suite.createDispute()
+
+	req := &pkg.AddDisputeEvidenceRequest{
+		DisputeId:  dispute.Id,
+		MerchantId: primitive.NewObjectID().Hex(),
+		Name:       "delivery_log.pdf",
+		Url:        "https://docs.unit.test/delivery_log.pdf",
+	}
+	rsp := &pkg.DisputeResponse{}
+	err := suite.service.AddDisputeEvidence(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), disputeErrorNotFound, rsp.Message)
+}
+
+func (suite *DisputeTestSuite) TestDispute_SetDisputeOutcome_Won_OrderNotChanged() {
+	order, dispute := suite.createDispute()
+
+	req := &pkg.SetDisputeOutcomeRequest{DisputeId: dispute.Id, Outcome: pkg.DisputeStatusWon}
+	rsp := &pkg.DisputeResponse{}
+	err := suite.service.SetDisputeOutcome(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), pkg.DisputeStatusWon, rsp.Item.Status)
+	assert.Empty(suite.T(), rsp.Item.ChargebackId)
+	assert.NotNil(suite.T(), rsp.Item.ClosedAt)
+
+	order, err = suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), recurringpb.OrderPublicStatusProcessed, order.GetPublicStatus())
+
+	refunded, err := suite.service.refundRepository.GetAmountByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), refunded)
+
+	count, err := suite.service.notificationRepository.FindCount(context.TODO(), suite.merchant.Id, "", 2)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 2, count)
+
+	err = suite.service.SetDisputeOutcome(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), disputeErrorAlreadyClosed, rsp.Message)
+}
+
+func (suite *DisputeTestSuite) TestDispute_SetDisputeOutcome_Lost_Chargeback() {
+	order, dispute := suite.createDispute()
+
+	req := &pkg.SetDisputeOutcomeRequest{DisputeId: dispute.Id, Outcome: pkg.DisputeStatusLost}
+	rsp := &pkg.DisputeResponse{}
+	err := suite.service.SetDisputeOutcome(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), pkg.DisputeStatusLost, rsp.Item.Status)
+	assert.NotEmpty(suite.T(), rsp.Item.ChargebackId)
+
+	refund, err := suite.service.refundRepository.GetById(context.TODO(), rsp.Item.ChargebackId)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), refund.IsChargeback)
+	assert.Equal(suite.T(), pkg.RefundStatusCompleted, refund.Status)
+	assert.Equal(suite.T(), order.ChargeAmount, refund.Amount)
+	assert.NotEmpty(suite.T(), refund.CreatedOrderId)
+
+	order, err = suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusChargeback), order.PrivateStatus)
+	assert.Equal(suite.T(), recurringpb.OrderPublicStatusChargeback, order.GetPublicStatus())
+
+	entries, err := suite.service.accountingRepository.FindBySource(context.TODO(), refund.CreatedOrderId, repository.CollectionRefund)
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), entries)
+}
+
+func (suite *DisputeTestSuite) TestDispute_SetDisputeOutcome_LostRepeated_ChargebackOnce() {
+	order, dispute := suite.createDispute()
+
+	req := &pkg.SetDisputeOutcomeRequest{DisputeId: dispute.Id, Outcome: pkg.DisputeStatusLost}
+	rsp := &pkg.DisputeResponse{}
+	err := suite.service.SetDisputeOutcome(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	rsp = &pkg.DisputeResponse{}
+	err = suite.service.SetDisputeOutcome(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), disputeErrorAlreadyClosed, rsp.Message)
+
+	count, err := suite.service.refundRepository.CountByOrderUuid(context.TODO(), order.Uuid)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1, count)
+}
+
+func (suite *DisputeTestSuite) TestDispute_SetDisputeOutcome_LostChargebackFailed_CompletedOnRepeat() {
+	order, dispute := suite.createDispute()
+
+	// dispute is closed as lost, but the chargeback wasn't created
+	d, err := suite.service.disputeRepository.GetById(context.TODO(), dispute.Id)
+	assert.NoError(suite.T(), err)
+	d.Status = pkg.DisputeStatusLost
+	d.IsOpen = false
+	d.ClosedAt = time.Now()
+	d.ChargebackId = primitive.NewObjectID().Hex()
+	isUpdated, err := suite.service.disputeRepository.UpdateStatus(context.TODO(), d, pkg.DisputeStatusOpen)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), isUpdated)
+
+	req := &pkg.SetDisputeOutcomeRequest{DisputeId: dispute.Id, Outcome: pkg.DisputeStatusLost}
+	rsp := &pkg.DisputeResponse{}
+	err = suite.service.SetDisputeOutcome(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Equal(suite.T(), d.ChargebackId, rsp.Item.ChargebackId)
+
+	refund, err := suite.service.refundRepository.GetById(context.TODO(), d.ChargebackId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundStatusCompleted, refund.Status)
+
+	order, err = suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), recurringpb.OrderPublicStatusChargeback, order.GetPublicStatus())
+}
+
+func (suite *DisputeTestSuite) TestDispute_CloseDispute_ClosedConcurrently_NoChargeback() {
+	order, dispute := suite.createDispute()
+
+	staleDispute, err := suite.service.disputeRepository.GetById(context.TODO(), dispute.Id)
+	assert.NoError(suite.T(), err)
+
+	req := &pkg.SetDisputeOutcomeRequest{DisputeId: dispute.Id, Outcome: pkg.DisputeStatusWon}
+	rsp := &pkg.DisputeResponse{}
+	err = suite.service.SetDisputeOutcome(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	err = suite.service.closeDispute(context.TODO(), staleDispute, pkg.DisputeStatusLost, pkg.DisputeStatusOpen)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), disputeErrorAlreadyClosed, err.(*billingpb.ResponseError).Message)
+
+	order, err = suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), recurringpb.OrderPublicStatusProcessed, order.GetPublicStatus())
+
+	count, err := suite.service.refundRepository.CountByOrderUuid(context.TODO(), order.Uuid)
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), count)
+}
+
+func (suite *DisputeTestSuite) TestDispute_AddDisputeEvidence_RepresentmentSubmitted_Error() {
+	_, dispute := suite.createDispute()
+
+	rsp := suite.addEvidence(dispute)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	d, err := suite.service.disputeRepository.GetById(context.TODO(), dispute.Id)
+	assert.NoError(suite.T(), err)
+	d.Status = pkg.DisputeStatusEvidenceSubmitted
+	d.RepresentmentSubmittedAt = time.Now()
+	isUpdated, err := suite.service.disputeRepository.UpdateStatus(context.TODO(), d, pkg.DisputeStatusOpen)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), isUpdated)
+
+	isAdded, err := suite.service.disputeRepository.AddEvidence(context.TODO(), d.Id, &intPkg.DisputeEvidence{
+		Id:        primitive.NewObjectID(),
+		Name:      "late.pdf",
+		CreatedAt: time.Now(),
+	})
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), isAdded)
+
+	d, err = suite.service.disputeRepository.GetById(context.TODO(), dispute.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), d.Evidence, 1)
+}
+
+func (suite *DisputeTestSuite) TestDispute_ExpireDisputes_Lost() {
+	_, dispute := suite.createDispute()
+
+	d, err := suite.service.disputeRepository.GetById(context.TODO(), dispute.Id)
+	assert.NoError(suite.T(), err)
+	d.EvidenceDueAt = time.Now().Add(-time.Minute)
+	err = suite.service.disputeRepository.Update(context.TODO(), d)
+	assert.NoError(suite.T(), err)
+
+	err = suite.service.ExpireDisputes(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	d, err = suite.service.disputeRepository.GetById(context.TODO(), dispute.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.DisputeStatusLost, d.Status)
+	assert.NotEmpty(suite.T(), d.ChargebackId)
+}
+
+func (suite *DisputeTestSuite) TestDispute_CreateRefund_DisputeOpened_Error() {
+	order, _ := suite.createDispute()
+
+	req := &billingpb.CreateRefundRequest{
+		OrderId:    order.Uuid,
+		Amount:     10,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		Reason:     "unit test",
+		MerchantId: suite.merchant.Id,
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), refundErrorDisputeOpened, rsp.Message)
+}
+
+func (suite *DisputeTestSuite) TestDispute_ListDisputes_Ok() {
+	order, _ := suite.createDispute()
+	suite.createDispute()
+
+	req := &pkg.ListDisputesRequest{MerchantId: suite.merchant.Id}
+	rsp := &pkg.ListDisputesResponse{}
+	err := suite.service.ListDisputes(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 2, rsp.Count)
+	assert.Len(suite.T(), rsp.Items, 2)
+
+	req.OrderId = order.Uuid
+	err = suite.service.ListDisputes(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1, rsp.Count)
+	assert.Equal(suite.T(), order.Uuid, rsp.Items[0].OrderId)
+}
@@ -0,0 +1,466 @@
+package service
+
+import (
+	"context"
+	"github.com/micro/go-micro/server"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+)
+
+// BillingServiceExtended serves methods of the billing server which messages aren't declared in billingpb.
+// Methods are available by the "BillingServiceExtended.<Method>" endpoints of the billing service.
+type BillingServiceExtended struct {
+	svc *Service
+}
+
+func RegisterBillingServiceExtendedHandler(s server.Server, svc *Service, opts ...server.HandlerOption) error {
+	return s.Handle(s.NewHandler(&BillingServiceExtended{svc: svc}, opts...))
+}
+
+func (h *BillingServiceExtended) CaptureOrder(
+	ctx context.Context,
+	req *pkg.CaptureOrderRequest,
+	rsp *pkg.OrderPaymentResponse,
+) error {
+	return h.svc.CaptureOrder(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) VoidOrder(
+	ctx context.Context,
+	req *pkg.VoidOrderRequest,
+	rsp *pkg.OrderPaymentResponse,
+) error {
+	return h.svc.VoidOrder(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) SetProjectCaptureSettings(
+	ctx context.Context,
+	req *pkg.SetProjectCaptureSettingsRequest,
+	rsp *pkg.ProjectCaptureSettingsResponse,
+) error {
+	return h.svc.SetProjectCaptureSettings(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) GetProjectCaptureSettings(
+	ctx context.Context,
+	req *pkg.GetProjectCaptureSettingsRequest,
+	rsp *pkg.ProjectCaptureSettingsResponse,
+) error {
+	return h.svc.GetProjectCaptureSettings(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) CreateDispute(
+	ctx context.Context,
+	req *pkg.CreateDisputeRequest,
+	rsp *pkg.DisputeResponse,
+) error {
+	return h.svc.CreateDispute(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) AddDisputeEvidence(
+	ctx context.Context,
+	req *pkg.AddDisputeEvidenceRequest,
+	rsp *pkg.DisputeResponse,
+) error {
+	return h.svc.AddDisputeEvidence(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) SubmitDisputeRepresentment(
+	ctx context.Context,
+	req *pkg.SubmitDisputeRepresentmentRequest,
+	rsp *pkg.DisputeResponse,
+) error {
+	return h.svc.SubmitDisputeRepresentment(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) SetDisputeOutcome(
+	ctx context.Context,
+	req *pkg.SetDisputeOutcomeRequest,
+	rsp *pkg.DisputeResponse,
+) error {
+	return h.svc.SetDisputeOutcome(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) GetDispute(
+	ctx context.Context,
+	req *pkg.GetDisputeRequest,
+	rsp *pkg.DisputeResponse,
+) error {
+	return h.svc.GetDispute(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ListDisputes(
+	ctx context.Context,
+	req *pkg.ListDisputesRequest,
+	rsp *pkg.ListDisputesResponse,
+) error {
+	return h.svc.ListDisputes(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ListHeldOrders(
+	ctx context.Context,
+	req *pkg.ListHeldOrdersRequest,
+	rsp *pkg.ListHeldOrdersResponse,
+) error {
+	return h.svc.ListHeldOrders(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ApproveHeldOrder(
+	ctx context.Context,
+	req *pkg.ResolveHeldOrderRequest,
+	rsp *pkg.OrderReviewResponse,
+) error {
+	return h.svc.ApproveHeldOrder(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) RejectHeldOrder(
+	ctx context.Context,
+	req *pkg.ResolveHeldOrderRequest,
+	rsp *pkg.OrderReviewResponse,
+) error {
+	return h.svc.RejectHeldOrder(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) CreateOrUpdatePaymentMethodRule(
+	ctx context.Context,
+	req *pkg.CreateOrUpdatePaymentMethodRuleRequest,
+	rsp *pkg.PaymentMethodRuleResponse,
+) error {
+	return h.svc.CreateOrUpdatePaymentMethodRule(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) DeletePaymentMethodRule(
+	ctx context.Context,
+	req *pkg.DeletePaymentMethodRuleRequest,
+	rsp *billingpb.EmptyResponseWithStatus,
+) error {
+	return h.svc.DeletePaymentMethodRule(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ListPaymentMethodRules(
+	ctx context.Context,
+	req *pkg.ListPaymentMethodRulesRequest,
+	rsp *pkg.ListPaymentMethodRulesResponse,
+) error {
+	return h.svc.ListPaymentMethodRules(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) CreateOrUpdatePromoCode(
+	ctx context.Context,
+	req *pkg.CreateOrUpdatePromoCodeRequest,
+	rsp *pkg.PromoCodeResponse,
+) error {
+	return h.svc.CreateOrUpdatePromoCode(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) GetPromoCode(
+	ctx context.Context,
+	req *pkg.GetPromoCodeRequest,
+	rsp *pkg.PromoCodeResponse,
+) error {
+	return h.svc.GetPromoCode(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ListPromoCodes(
+	ctx context.Context,
+	req *pkg.ListPromoCodesRequest,
+	rsp *pkg.ListPromoCodesResponse,
+) error {
+	return h.svc.ListPromoCodes(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ApplyPromoCode(
+	ctx context.Context,
+	req *pkg.ApplyPromoCodeRequest,
+	rsp *pkg.ApplyPromoCodeResponse,
+) error {
+	return h.svc.ApplyPromoCode(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) FindSubscriptionTrials(
+	ctx context.Context,
+	req *pkg.FindSubscriptionTrialsRequest,
+	rsp *pkg.FindSubscriptionTrialsResponse,
+) error {
+	return h.svc.FindSubscriptionTrials(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) SetDunningSchedule(
+	ctx context.Context,
+	req *pkg.SetDunningScheduleRequest,
+	rsp *pkg.DunningScheduleResponse,
+) error {
+	return h.svc.SetDunningSchedule(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) GetDunningSchedule(
+	ctx context.Context,
+	req *pkg.GetDunningScheduleRequest,
+	rsp *pkg.DunningScheduleResponse,
+) error {
+	return h.svc.GetDunningSchedule(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ListSubscriptionDunning(
+	ctx context.Context,
+	req *pkg.ListSubscriptionDunningRequest,
+	rsp *pkg.ListSubscriptionDunningResponse,
+) error {
+	return h.svc.ListSubscriptionDunning(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ChangeSubscriptionPlan(
+	ctx context.Context,
+	req *pkg.ChangeSubscriptionPlanRequest,
+	rsp *pkg.ChangeSubscriptionPlanResponse,
+) error {
+	return h.svc.ChangeSubscriptionPlan(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) PauseSubscription(
+	ctx context.Context,
+	req *pkg.PauseSubscriptionRequest,
+	rsp *pkg.SubscriptionPauseResponse,
+) error {
+	return h.svc.PauseSubscription(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ResumeSubscription(
+	ctx context.Context,
+	req *pkg.ResumeSubscriptionRequest,
+	rsp *pkg.SubscriptionPauseResponse,
+) error {
+	return h.svc.ResumeSubscription(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) GetSavedCardUpdate(
+	ctx context.Context,
+	req *pkg.GetSavedCardUpdateRequest,
+	rsp *pkg.SavedCardUpdateResponse,
+) error {
+	return h.svc.GetSavedCardUpdate(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) VerifySavedCardUpdate(
+	ctx context.Context,
+	req *pkg.VerifySavedCardUpdateRequest,
+	rsp *pkg.SavedCardUpdateResponse,
+) error {
+	return h.svc.VerifySavedCardUpdate(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) CreateItemsRefund(
+	ctx context.Context,
+	req *pkg.CreateItemsRefundRequest,
+	rsp *billingpb.CreateRefundResponse,
+) error {
+	return h.svc.CreateItemsRefund(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) SetRefundApprovalSettings(
+	ctx context.Context,
+	req *pkg.SetRefundApprovalSettingsRequest,
+	rsp *pkg.RefundApprovalSettingsResponse,
+) error {
+	return h.svc.SetRefundApprovalSettings(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) GetRefundApprovalSettings(
+	ctx context.Context,
+	req *pkg.GetRefundApprovalSettingsRequest,
+	rsp *pkg.RefundApprovalSettingsResponse,
+) error {
+	return h.svc.GetRefundApprovalSettings(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ApproveRefund(
+	ctx context.Context,
+	req *pkg.ReviewRefundRequest,
+	rsp *billingpb.CreateRefundResponse,
+) error {
+	return h.svc.ApproveRefund(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) RejectRefund(
+	ctx context.Context,
+	req *pkg.ReviewRefundRequest,
+	rsp *billingpb.CreateRefundResponse,
+) error {
+	return h.svc.RejectRefund(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) GetRefundFallback(
+	ctx context.Context,
+	req *pkg.GetRefundFallbackRequest,
+	rsp *pkg.RefundFallbackResponse,
+) error {
+	return h.svc.GetRefundFallback(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) SetRefundFallbackDestination(
+	ctx context.Context,
+	req *pkg.SetRefundFallbackDestinationRequest,
+	rsp *pkg.RefundFallbackResponse,
+) error {
+	return h.svc.SetRefundFallbackDestination(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) CompleteRefundFallback(
+	ctx context.Context,
+	req *pkg.CompleteRefundFallbackRequest,
+	rsp *pkg.RefundFallbackResponse,
+) error {
+	return h.svc.CompleteRefundFallback(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) GetCustomerWallets(
+	ctx context.Context,
+	req *pkg.GetCustomerWalletsRequest,
+	rsp *pkg.CustomerWalletsResponse,
+) error {
+	return h.svc.GetCustomerWallets(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) AddWalletCredit(
+	ctx context.Context,
+	req *pkg.AddWalletCreditRequest,
+	rsp *pkg.CustomerWalletResponse,
+) error {
+	return h.svc.AddWalletCredit(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ApplyWalletToOrder(
+	ctx context.Context,
+	req *pkg.ApplyWalletRequest,
+	rsp *pkg.ApplyWalletResponse,
+) error {
+	return h.svc.ApplyWalletToOrder(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) GenerateGiftCards(
+	ctx context.Context,
+	req *pkg.GenerateGiftCardsRequest,
+	rsp *pkg.GenerateGiftCardsResponse,
+) error {
+	return h.svc.GenerateGiftCards(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) GetGiftCard(
+	ctx context.Context,
+	req *pkg.GetGiftCardRequest,
+	rsp *pkg.GiftCardResponse,
+) error {
+	return h.svc.GetGiftCard(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) RedeemGiftCard(
+	ctx context.Context,
+	req *pkg.RedeemGiftCardRequest,
+	rsp *pkg.CustomerWalletResponse,
+) error {
+	return h.svc.RedeemGiftCard(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ApplyGiftCardToOrder(
+	ctx context.Context,
+	req *pkg.ApplyGiftCardRequest,
+	rsp *pkg.ApplyGiftCardResponse,
+) error {
+	return h.svc.ApplyGiftCardToOrder(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) GetTrialBalance(
+	ctx context.Context,
+	req *pkg.GetTrialBalanceRequest,
+	rsp *pkg.TrialBalanceResponse,
+) error {
+	return h.svc.GetTrialBalance(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ImportSettlementReport(
+	ctx context.Context,
+	req *pkg.ImportSettlementReportRequest,
+	rsp *pkg.ReconciliationRunResponse,
+) error {
+	return h.svc.ImportSettlementReport(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) GetReconciliationRun(
+	ctx context.Context,
+	req *pkg.GetReconciliationRunRequest,
+	rsp *pkg.ReconciliationRunResponse,
+) error {
+	return h.svc.GetReconciliationRun(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ListReconciliationRuns(
+	ctx context.Context,
+	req *pkg.ListReconciliationRunsRequest,
+	rsp *pkg.ListReconciliationRunsResponse,
+) error {
+	return h.svc.ListReconciliationRuns(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ListReconciliationItems(
+	ctx context.Context,
+	req *pkg.ListReconciliationItemsRequest,
+	rsp *pkg.ListReconciliationItemsResponse,
+) error {
+	return h.svc.ListReconciliationItems(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ResolveReconciliationItem(
+	ctx context.Context,
+	req *pkg.ResolveReconciliationItemRequest,
+	rsp *pkg.ReconciliationItemResponse,
+) error {
+	return h.svc.ResolveReconciliationItem(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ExportAccountingEntries(
+	ctx context.Context,
+	req *pkg.ExportAccountingEntriesRequest,
+	rsp *pkg.ExportAccountingEntriesResponse,
+) error {
+	return h.svc.ExportAccountingEntries(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ListAuditLog(
+	ctx context.Context,
+	req *pkg.ListAuditLogRequest,
+	rsp *pkg.ListAuditLogResponse,
+) error {
+	return h.svc.ListAuditLog(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) VerifyAuditLog(
+	ctx context.Context,
+	req *billingpb.EmptyRequest,
+	rsp *pkg.VerifyAuditLogResponse,
+) error {
+	return h.svc.VerifyAuditLog(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ListMerchantBalances(
+	ctx context.Context,
+	req *pkg.ListMerchantBalancesRequest,
+	rsp *pkg.ListMerchantBalancesResponse,
+) error {
+	return h.svc.ListMerchantBalances(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ConvertMerchantBalance(
+	ctx context.Context,
+	req *pkg.ConvertMerchantBalanceRequest,
+	rsp *pkg.ConvertMerchantBalanceResponse,
+) error {
+	return h.svc.ConvertMerchantBalance(ctx, req, rsp)
+}
+
+func (h *BillingServiceExtended) ListMerchantBalanceConversions(
+	ctx context.Context,
+	req *pkg.ListMerchantBalanceConversionsRequest,
+	rsp *pkg.ListMerchantBalanceConversionsResponse,
+) error {
+	return h.svc.ListMerchantBalanceConversions(ctx, req, rsp)
+}
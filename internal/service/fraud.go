@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	fraudBinRegex = regexp.MustCompile(`^\d{6}`)
+)
+
+// fraudRule is the check of the payment in the fraud screening. Rules are plugged to the screening by newFraudRules.
+type fraudRule interface {
+	// GetName returns the unique name of the rule which is saved to the order when the rule is matched.
+	GetName() string
+
+	// GetScore returns the risk score which is added to the order risk score when the rule is matched.
+	GetScore() int
+
+	// Match checks the payment and returns details of the match or empty string if the payment doesn't match the rule.
+	Match(ctx context.Context, data *fraudScreeningData) (string, error)
+}
+
+type fraudScreeningData struct {
+	order       *billingpb.Order
+	ip          string
+	bin         string
+	binCountry  string
+	emailDomain string
+}
+
+type fraudScreeningResult struct {
+	score  int
+	action string
+	rules  map[string]string
+}
+
+type fraudVelocityRule struct {
+	service *Service
+	name    string
+	score   int
+	limit   int64
+	field   string
+	value   func(data *fraudScreeningData) interface{}
+}
+
+type fraudCountryMismatchRule struct {
+	service *Service
+	score   int
+}
+
+type fraudEmailDomainRule struct {
+	score     int
+	blacklist []string
+}
+
+// newFraudRules returns the rule set of the fraud screening by the service configuration, rules with zero score
+// are skipped.
+func newFraudRules(s *Service) []fraudRule {
+	candidates := []fraudRule{
+		&fraudVelocityRule{
+			service: s,
+			name:    pkg.FraudRuleCustomerVelocity,
+			score:   s.cfg.FraudCustomerVelocityScore,
+			limit:   s.cfg.FraudCustomerVelocityLimit,
+			field:   "user.id",
+			value: func(data *fraudScreeningData) interface{} {
+				if data.order.User == nil || data.order.User.Id == "" {
+					return nil
+				}
+				return data.order.User.Id
+			},
+		},
+		&fraudVelocityRule{
+			service: s,
+			name:    pkg.FraudRuleIpVelocity,
+			score:   s.cfg.FraudIpVelocityScore,
+			limit:   s.cfg.FraudIpVelocityLimit,
+			field:   "user.ip",
+			value: func(data *fraudScreeningData) interface{} {
+				if data.ip == "" {
+					return nil
+				}
+				return data.ip
+			},
+		},
+		&fraudVelocityRule{
+			service: s,
+			name:    pkg.FraudRuleBinVelocity,
+			score:   s.cfg.FraudBinVelocityScore,
+			limit:   s.cfg.FraudBinVelocityLimit,
+			field:   "payment_requisites." + billingpb.PaymentCreateFieldPan,
+			value: func(data *fraudScreeningData) interface{} {
+				if data.bin == "" {
+					return nil
+				}
+				return bson.M{"$regex": "^" + data.bin}
+			},
+		},
+		&fraudCountryMismatchRule{
+			service: s,
+			score:   s.cfg.FraudCountryMismatchScore,
+		},
+		&fraudEmailDomainRule{
+			score:     s.cfg.FraudEmailDomainScore,
+			blacklist: s.cfg.FraudEmailDomainBlacklist,
+		},
+	}
+
+	var rules []fraudRule
+
+	for _, rule := range candidates {
+		if rule.GetScore() > 0 {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+// screenPayment calculates the risk score of the order payment by the fraud rule set and chooses the action by
+// the score. The result is saved to the order private metadata and to the order history for audit.
+// Rule which can't be checked because of the error is skipped, so the payment isn't blocked by infrastructure problems.
+func (s *Service) screenPayment(ctx context.Context, order *billingpb.Order, ip string) *fraudScreeningResult {
+	result := &fraudScreeningResult{action: pkg.FraudActionAllow, rules: map[string]string{}}
+
+	if !s.cfg.FraudScreeningEnabled || len(s.fraudRules) <= 0 {
+		return result
+	}
+
+	// payment of the order approved by the manual review isn't screened again
+	if order.PrivateMetadata[pkg.OrderPrivateMetadataReviewStatus] == pkg.OrderReviewStatusApproved {
+		return result
+	}
+
+	data := &fraudScreeningData{order: order, ip: ip}
+
+	if order.PaymentRequisites != nil {
+		data.bin = fraudBinRegex.FindString(order.PaymentRequisites[billingpb.PaymentCreateFieldPan])
+		data.binCountry = order.PaymentRequisites[billingpb.PaymentCreateBankCardFieldIssuerCountryIsoCode]
+	}
+
+	if order.User != nil {
+		if i := strings.LastIndex(order.User.Email, "@"); i >= 0 {
+			data.emailDomain = strings.ToLower(order.User.Email[i+1:])
+		}
+	}
+
+	for _, rule := range s.fraudRules {
+		details, err := rule.Match(ctx, data)
+
+		if err != nil {
+			zap.L().Error(
+				"fraud rule check failed",
+				zap.Error(err),
+				zap.String("rule", rule.GetName()),
+				zap.String("order_id", order.Id),
+			)
+			continue
+		}
+
+		if details == "" {
+			continue
+		}
+
+		result.score += rule.GetScore()
+		result.rules[rule.GetName()] = details
+	}
+
+	if result.score >= s.cfg.FraudDeclineScore {
+		result.action = pkg.FraudActionDecline
+	} else if result.score >= s.cfg.FraudReviewScore {
+		result.action = pkg.FraudActionReview
+	}
+
+	names := make([]string, 0, len(result.rules))
+
+	for name := range result.rules {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	if order.PrivateMetadata == nil {
+		order.PrivateMetadata = make(map[string]string)
+	}
+
+	order.PrivateMetadata[pkg.OrderPrivateMetadataFraudScore] = strconv.Itoa(result.score)
+	order.PrivateMetadata[pkg.OrderPrivateMetadataFraudAction] = result.action
+	order.PrivateMetadata[pkg.OrderPrivateMetadataFraudRules] = strings.Join(names, ",")
+
+	history := map[string]string{
+		pkg.OrderHistoryFieldFraudScore:  strconv.Itoa(result.score),
+		pkg.OrderHistoryFieldFraudAction: result.action,
+		pkg.OrderHistoryFieldFraudRules:  strings.Join(names, ","),
+	}
+
+	for name, details := range result.rules {
+		history[name] = details
+	}
+
+	s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypeFraudScreening, history)
+
+	return result
+}
+
+func (r *fraudVelocityRule) GetName() string {
+	return r.name
+}
+
+func (r *fraudVelocityRule) GetScore() int {
+	return r.score
+}
+
+// Match counts orders with the same value of the rule field created in the velocity period. Each field of the rules
+// is indexed together with the creation date of the order, so new rules must be added with the index.
+func (r *fraudVelocityRule) Match(ctx context.Context, data *fraudScreeningData) (string, error) {
+	value := r.value(data)
+
+	if value == nil {
+		return "", nil
+	}
+
+	period := r.service.cfg.GetFraudVelocityPeriod()
+	query := bson.M{
+		r.field:      value,
+		"created_at": bson.M{"$gte": time.Now().Add(-period)},
+	}
+
+	if oid, err := primitive.ObjectIDFromHex(data.order.Id); err == nil {
+		query["_id"] = bson.M{"$ne": oid}
+	}
+
+	count, err := r.service.orderRepository.CountBy(ctx, query)
+
+	if err != nil {
+		return "", err
+	}
+
+	if count < r.limit {
+		return "", nil
+	}
+
+	return fmt.Sprintf("%d orders for last %s", count, period), nil
+}
+
+func (r *fraudCountryMismatchRule) GetName() string {
+	return pkg.FraudRuleCountryMismatch
+}
+
+func (r *fraudCountryMismatchRule) GetScore() int {
+	return r.score
+}
+
+func (r *fraudCountryMismatchRule) Match(ctx context.Context, data *fraudScreeningData) (string, error) {
+	if data.ip == "" || data.binCountry == "" {
+		return "", nil
+	}
+
+	address, err := r.service.getAddressByIp(ctx, data.ip)
+
+	if err != nil {
+		return "", err
+	}
+
+	if address.Country == "" || strings.EqualFold(address.Country, data.binCountry) {
+		return "", nil
+	}
+
+	return fmt.Sprintf("ip country %s, card issuer country %s", address.Country, data.binCountry), nil
+}
+
+func (r *fraudEmailDomainRule) GetName() string {
+	return pkg.FraudRuleEmailDomain
+}
+
+func (r *fraudEmailDomainRule) GetScore() int {
+	return r.score
+}
+
+func (r *fraudEmailDomainRule) Match(_ context.Context, data *fraudScreeningData) (string, error) {
+	if data.emailDomain == "" {
+		return "", nil
+	}
+
+	for _, domain := range r.blacklist {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+
+		if domain == "" {
+			continue
+		}
+
+		if data.emailDomain == domain || strings.HasSuffix(data.emailDomain, "."+domain) {
+			return data.emailDomain, nil
+		}
+	}
+
+	return "", nil
+}
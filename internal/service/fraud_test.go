@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/google/uuid"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type FraudTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+	cookie        string
+}
+
+func Test_Fraud(t *testing.T) {
+	suite.Run(t, new(FraudTestSuite))
+}
+
+func (suite *FraudTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	suite.service.cfg.FraudScreeningEnabled = true
+	suite.service.cfg.FraudReviewScore = 50
+	suite.service.cfg.FraudDeclineScore = 100
+	suite.service.cfg.FraudCustomerVelocityScore = 0
+	suite.service.cfg.FraudIpVelocityScore = 0
+	suite.service.cfg.FraudBinVelocityScore = 0
+	suite.service.cfg.FraudCountryMismatchScore = 0
+	suite.service.cfg.FraudEmailDomainScore = 0
+
+	var customer *billingpb.Customer
+	suite.merchant, suite.project, suite.paymentMethod, _, customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.cookie, err = suite.service.generateBrowserCookie(&BrowserCookieCustomer{
+		CustomerId: customer.Id,
+		Ip:         "127.0.0.1",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	})
+
+	if err != nil {
+		suite.FailNow("Generate browser cookie failed", "%v", err)
+	}
+}
+
+func (suite *FraudTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+// createPayment creates the order and sends the payment of the order with the test bank card.
+func (suite *FraudTestSuite) createPayment(email string) (*billingpb.Order, *billingpb.PaymentCreateResponse) {
+	suite.service.fraudRules = newFraudRules(suite.service)
+
+	req := &billingpb.OrderCreateRequest{
+		Type:        pkg.OrderType_simple,
+		ProjectId:   suite.project.Id,
+		Amount:      100,
+		Currency:    "RUB",
+		Account:     "unit test",
+		Description: "unit test",
+		User: &billingpb.OrderUser{
+			Id:    primitive.NewObjectID().Hex(),
+			Uuid:  uuid.New().String(),
+			Email: email,
+			Ip:    "127.0.0.1",
+			Address: &billingpb.OrderBillingAddress{
+				Country: "RU",
+			},
+		},
+	}
+
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	req1 := &billingpb.PaymentCreateRequest{
+		Data: map[string]string{
+			billingpb.PaymentCreateFieldOrderId:         rsp.Item.Uuid,
+			billingpb.PaymentCreateFieldPaymentMethodId: suite.paymentMethod.Id,
+			billingpb.PaymentCreateFieldEmail:           email,
+			billingpb.PaymentCreateFieldPan:             "4000000000000002",
+			billingpb.PaymentCreateFieldCvv:             "123",
+			billingpb.PaymentCreateFieldMonth:           "02",
+			billingpb.PaymentCreateFieldYear:            time.Now().AddDate(1, 0, 0).Format("2006"),
+			billingpb.PaymentCreateFieldHolder:          "MR. CARD HOLDER",
+		},
+		Ip:     "127.0.0.1",
+		Cookie: suite.cookie,
+	}
+
+	rsp1 := &billingpb.PaymentCreateResponse{}
+	err = suite.service.PaymentCreateProcess(context.TODO(), req1, rsp1)
+	assert.NoError(suite.T(), err)
+
+	order, err := suite.service.orderRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+
+	return order, rsp1
+}
+
+func (suite *FraudTestSuite) TestFraud_ScreeningDisabled_Allow() {
+	suite.service.cfg.FraudScreeningEnabled = false
+	suite.service.cfg.FraudEmailDomainScore = 100
+	suite.service.cfg.FraudEmailDomainBlacklist = []string{"unit.unit"}
+
+	order, rsp := suite.createPayment("test@unit.unit")
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.NotContains(suite.T(), order.PrivateMetadata, pkg.OrderPrivateMetadataFraudAction)
+}
+
+func (suite *FraudTestSuite) TestFraud_NoMatchedRules_Allow() {
+	suite.service.cfg.FraudEmailDomainScore = 100
+	suite.service.cfg.FraudEmailDomainBlacklist = []string{"fraud.test"}
+
+	order, rsp := suite.createPayment("test@unit.unit")
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Equal(suite.T(), pkg.FraudActionAllow, order.PrivateMetadata[pkg.OrderPrivateMetadataFraudAction])
+	assert.Equal(suite.T(), "0", order.PrivateMetadata[pkg.OrderPrivateMetadataFraudScore])
+	assert.Empty(suite.T(), order.PrivateMetadata[pkg.OrderPrivateMetadataFraudRules])
+}
+
+func (suite *FraudTestSuite) TestFraud_EmailDomainBlacklist_Declined() {
+	suite.service.cfg.FraudEmailDomainScore = 100
+	suite.service.cfg.FraudEmailDomainBlacklist = []string{"fraud.test"}
+
+	order, rsp := suite.createPayment("test@mail.fraud.test")
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, rsp.Status)
+	assert.Equal(suite.T(), orderErrorFraudDeclined, rsp.Message)
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemDeclined), order.PrivateStatus)
+	assert.Equal(suite.T(), pkg.FraudActionDecline, order.PrivateMetadata[pkg.OrderPrivateMetadataFraudAction])
+	assert.Equal(suite.T(), "100", order.PrivateMetadata[pkg.OrderPrivateMetadataFraudScore])
+	assert.Equal(suite.T(), pkg.FraudRuleEmailDomain, order.PrivateMetadata[pkg.OrderPrivateMetadataFraudRules])
+
+	history, err := suite.service.orderHistoryRepository.FindByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), history, 1)
+	assert.Equal(suite.T(), pkg.OrderHistoryTypeFraudScreening, history[0].Type)
+	assert.Equal(suite.T(), "mail.fraud.test", history[0].Data[pkg.FraudRuleEmailDomain])
+}
+
+func (suite *FraudTestSuite) TestFraud_IpVelocity_Review() {
+	suite.service.cfg.FraudIpVelocityScore = 60
+	suite.service.cfg.FraudIpVelocityLimit = 1
+
+	order, rsp := suite.createPayment("test@unit.unit")
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Equal(suite.T(), pkg.FraudActionAllow, order.PrivateMetadata[pkg.OrderPrivateMetadataFraudAction])
+
+	order, rsp = suite.createPayment("test@unit.unit")
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Equal(suite.T(), pkg.FraudActionReview, order.PrivateMetadata[pkg.OrderPrivateMetadataFraudAction])
+	assert.Equal(suite.T(), pkg.OrderStatusReviewHeld, order.PrivateStatus)
+	assert.Equal(suite.T(), "60", order.PrivateMetadata[pkg.OrderPrivateMetadataFraudScore])
+	assert.Equal(suite.T(), pkg.FraudRuleIpVelocity, order.PrivateMetadata[pkg.OrderPrivateMetadataFraudRules])
+}
+
+func (suite *FraudTestSuite) TestFraud_CountryMismatch_ScoreAdded() {
+	suite.service.cfg.FraudCountryMismatchScore = 30
+
+	order, rsp := suite.createPayment("test@unit.unit")
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Equal(suite.T(), pkg.FraudActionAllow, order.PrivateMetadata[pkg.OrderPrivateMetadataFraudAction])
+	assert.Equal(suite.T(), "30", order.PrivateMetadata[pkg.OrderPrivateMetadataFraudScore])
+	assert.Equal(suite.T(), pkg.FraudRuleCountryMismatch, order.PrivateMetadata[pkg.OrderPrivateMetadataFraudRules])
+}
+
+func (suite *FraudTestSuite) TestFraud_NewFraudRules_ZeroScoreSkipped() {
+	suite.service.cfg.FraudBinVelocityScore = 20
+	suite.service.cfg.FraudEmailDomainScore = 100
+
+	rules := newFraudRules(suite.service)
+	assert.Len(suite.T(), rules, 2)
+	assert.Equal(suite.T(), pkg.FraudRuleBinVelocity, rules[0].GetName())
+	assert.Equal(suite.T(), pkg.FraudRuleEmailDomain, rules[1].GetName())
+}
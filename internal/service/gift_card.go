@@ -0,0 +1,719 @@
+package service
+
+import (
+	"context"
+	cryptoRand "crypto/rand"
+	"github.com/paysuper/paysuper-billing-server/internal/helper"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// characters of the gift card code without the similar looking ones (0 and O, 1 and I)
+	giftCardCodeAlphabet    = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	giftCardCodeGroupsCount = 4
+	giftCardCodeGroupLength = 4
+
+	giftCardGenerateMaxCount = 1000
+)
+
+var (
+	giftCardErrorUnknown             = errors.NewBillingServerErrorMsg("gc000001", "gift card can't be processed. try request later")
+	giftCardErrorNotFound            = errors.NewBillingServerErrorMsg("gc000002", "gift card with specified code not found")
+	giftCardErrorInactive            = errors.NewBillingServerErrorMsg("gc000003", "gift card is already spent or expired")
+	giftCardErrorInsufficientBalance = errors.NewBillingServerErrorMsg("gc000004", "gift card balance is less than requested amount")
+	giftCardErrorCurrencyMismatch    = errors.NewBillingServerErrorMsg("gc000005", "gift card currency doesn't match order currency")
+	giftCardErrorOrderStatusInvalid  = errors.NewBillingServerErrorMsg("gc000006", "gift card can't be changed for order in current status")
+	giftCardErrorOrderNotApplicable  = errors.NewBillingServerErrorMsg("gc000007", "gift card isn't applicable to order")
+	giftCardErrorValueInvalid        = errors.NewBillingServerErrorMsg("gc000008", "gift card value must be greater than zero")
+	giftCardErrorCountInvalid        = errors.NewBillingServerErrorMsg("gc000009", "count of gift cards must be from 1 to 1000")
+	giftCardErrorCurrencyInvalid     = errors.NewBillingServerErrorMsg("gc000010", "gift card currency isn't supported")
+	giftCardErrorRefundSpent         = errors.NewBillingServerErrorMsg("gc000011", "refunded gift card is already spent or redeemed")
+)
+
+// GenerateGiftCards generates codes of the gift cards with the value and adds them to the keys of the key product,
+// so the gift cards are sold as keys of the key product. Gift card becomes active when its key is sold.
+func (s *Service) GenerateGiftCards(
+	ctx context.Context,
+	req *pkg.GenerateGiftCardsRequest,
+	rsp *pkg.GenerateGiftCardsResponse,
+) error {
+	if req.Value <= 0 {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = giftCardErrorValueInvalid
+		return nil
+	}
+
+	if req.Count <= 0 || req.Count > giftCardGenerateMaxCount {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = giftCardErrorCountInvalid
+		return nil
+	}
+
+	currency := strings.ToUpper(req.Currency)
+
+	if !helper.Contains(s.supportedCurrencies, currency) {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = giftCardErrorCurrencyInvalid
+		return nil
+	}
+
+	keyProduct, err := s.keyProductRepository.GetById(ctx, req.KeyProductId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = keyProductNotFound
+		return nil
+	}
+
+	if keyProduct.MerchantId != req.MerchantId {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = keyProductMerchantMismatch
+		return nil
+	}
+
+	merchantId, _ := primitive.ObjectIDFromHex(keyProduct.MerchantId)
+	projectId, _ := primitive.ObjectIDFromHex(keyProduct.ProjectId)
+
+	for i := int32(0); i < req.Count; i++ {
+		code, err := generateGiftCardCode()
+
+		if err != nil {
+			zap.L().Error("Gift card code generation failed", zap.Error(err))
+			break
+		}
+
+		card := &intPkg.GiftCard{
+			Code:         code,
+			KeyId:        primitive.NewObjectID().Hex(),
+			KeyProductId: keyProduct.Id,
+			MerchantId:   merchantId,
+			ProjectId:    projectId,
+			Value:        s.FormatAmount(req.Value, currency),
+			Balance:      s.FormatAmount(req.Value, currency),
+			Currency:     currency,
+			ValidityDays: req.ValidityDays,
+			Status:       pkg.GiftCardStatusIssued,
+		}
+
+		if err = s.giftCardRepository.Insert(ctx, card); err != nil {
+			continue
+		}
+
+		key := &billingpb.Key{
+			Id:           card.KeyId,
+			Code:         card.Code,
+			KeyProductId: keyProduct.Id,
+			PlatformId:   req.PlatformId,
+		}
+
+		if err = s.keyRepository.Insert(ctx, key); err != nil {
+			zap.S().Errorf(errors.KeyErrorFailedToInsert.Message, "err", err, "key", key.Id)
+			continue
+		}
+
+		rsp.Count++
+	}
+
+	if rsp.Count <= 0 {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = giftCardErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+
+	return nil
+}
+
+// GetGiftCard returns the balance and the expiration date of the sold gift card by the code.
+func (s *Service) GetGiftCard(
+	ctx context.Context,
+	req *pkg.GetGiftCardRequest,
+	rsp *pkg.GiftCardResponse,
+) error {
+	card, err := s.getSoldGiftCard(ctx, req.ProjectId, req.Code)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = giftCardErrorNotFound
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getGiftCardMessage(card)
+
+	return nil
+}
+
+// RedeemGiftCard moves the whole balance of the gift card to the wallet of the customer in the gift card currency.
+// The customer can be the buyer of the gift card or the recipient of the gift.
+func (s *Service) RedeemGiftCard(
+	ctx context.Context,
+	req *pkg.RedeemGiftCardRequest,
+	rsp *pkg.CustomerWalletResponse,
+) error {
+	card, err := s.getSoldGiftCard(ctx, req.ProjectId, req.Code)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = giftCardErrorNotFound
+		return nil
+	}
+
+	if !isGiftCardActive(card) {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = giftCardErrorInactive
+		return nil
+	}
+
+	if _, err = s.customerRepository.GetById(ctx, req.CustomerId); err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = customerNotFound
+		return nil
+	}
+
+	amount := card.Balance
+	card, err = s.giftCardRepository.Decrease(ctx, card.Id.Hex(), amount)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = giftCardErrorInactive
+		return nil
+	}
+
+	credit := &intPkg.StoreCredit{
+		CustomerId: req.CustomerId,
+		MerchantId: card.MerchantId,
+		ProjectId:  card.ProjectId,
+		Amount:     amount,
+		Currency:   card.Currency,
+		Source:     pkg.StoreCreditSourceGiftCard,
+		SourceId:   card.Id.Hex(),
+	}
+	wallet, err := s.topUpCustomerWallet(ctx, credit)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "topUpCustomerWallet"),
+			zap.Error(err),
+			zap.String("gift_card_id", card.Id.Hex()),
+			zap.Float64("amount", amount),
+		)
+
+		if _, err = s.giftCardRepository.Increase(ctx, card.Id.Hex(), amount); err != nil {
+			zap.L().Error(
+				pkg.MethodFinishedWithError,
+				zap.String("method", "giftCardRepository.Increase"),
+				zap.Error(err),
+				zap.String("gift_card_id", card.Id.Hex()),
+				zap.Float64("amount", amount),
+			)
+		}
+
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = giftCardErrorUnknown
+		return nil
+	}
+
+	card.Status = pkg.GiftCardStatusRedeemed
+
+	if err = s.giftCardRepository.Update(ctx, card); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = giftCardErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getCustomerWalletMessage(wallet)
+
+	return nil
+}
+
+// ApplyGiftCardToOrder applies the balance of the gift card to the order on the payment form. The gift card pays
+// the order up to its balance, the rest of the balance stays on the card. The gift card applied before is replaced,
+// empty code removes it.
+func (s *Service) ApplyGiftCardToOrder(
+	ctx context.Context,
+	req *pkg.ApplyGiftCardRequest,
+	rsp *pkg.ApplyGiftCardResponse,
+) error {
+	order, err := s.getOrderByUuidToForm(ctx, req.OrderId)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = e
+			return nil
+		}
+		return err
+	}
+
+	if order.PrivateStatus != recurringpb.OrderStatusNew || isOrderPrepaidAmountCharged(order) {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = giftCardErrorOrderStatusInvalid
+		return nil
+	}
+
+	removed := s.removeOrderGiftCard(order)
+	code := normalizeGiftCardCode(req.Code)
+
+	if code != "" {
+		// regular payments of the subscription are charged without the customer, so the gift card can't pay them
+		if order.RecurringSettings != nil {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = giftCardErrorOrderNotApplicable
+			return nil
+		}
+
+		card, err := s.getSoldGiftCard(ctx, order.GetProjectId(), code)
+
+		if err != nil {
+			rsp.Status = billingpb.ResponseStatusNotFound
+			rsp.Message = giftCardErrorNotFound
+			return nil
+		}
+
+		if !isGiftCardActive(card) {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = giftCardErrorInactive
+			return nil
+		}
+
+		if card.Currency != order.Currency {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = giftCardErrorCurrencyMismatch
+			return nil
+		}
+
+		amount := math.Min(card.Balance, order.TotalPaymentAmount-getOrderWalletAmount(order))
+		amount = s.FormatAmount(amount, order.Currency)
+
+		if amount <= 0 {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = giftCardErrorOrderNotApplicable
+			return nil
+		}
+
+		s.setOrderGiftCard(order, card, amount)
+		s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypeGiftCardApplied, map[string]string{
+			pkg.OrderHistoryFieldGiftCardId:     card.Id.Hex(),
+			pkg.OrderHistoryFieldGiftCardAmount: strconv.FormatFloat(amount, 'f', -1, 64),
+		})
+	} else if removed != "" {
+		s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypeGiftCardRemoved, map[string]string{
+			pkg.OrderHistoryFieldGiftCardId: removed,
+		})
+	}
+
+	rsp.IsPaid, err = s.processPrepaidOrder(ctx, order)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseError); ok {
+			rsp.Status = e.Status
+			rsp.Message = e.Message
+			return nil
+		}
+		return err
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.GiftCardAmount = getOrderGiftCardAmount(order)
+	rsp.Item = s.getPrepaidOrderAmounts(order)
+
+	return nil
+}
+
+// ExpireGiftCards expires the active gift cards after the validity period. The rest of the balance of the expired
+// card is the breakage. The value of the card was the merchant revenue when the card was sold, so the breakage is
+// recorded by the accounting entry for reports only and isn't added to the royalty report, the merchant balance or
+// the ledger.
+func (s *Service) ExpireGiftCards(ctx context.Context) error {
+	cards, err := s.giftCardRepository.FindExpired(ctx, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	for _, card := range cards {
+		ok, err := s.giftCardRepository.Expire(ctx, card)
+
+		if err != nil || !ok || card.Balance <= 0 {
+			continue
+		}
+
+		entryReq := &billingpb.CreateAccountingEntryRequest{
+			Type:       pkg.AccountingEntryTypeGiftCardBreakage,
+			MerchantId: card.MerchantId.Hex(),
+			Amount:     card.Balance,
+			Currency:   card.Currency,
+			Status:     pkg.BalanceTransactionStatusAvailable,
+			Date:       time.Now().Unix(),
+			Reason:     "gift card " + card.Id.Hex() + " expired",
+		}
+		entryRsp := &billingpb.CreateAccountingEntryResponse{}
+		err = s.CreateAccountingEntry(ctx, entryReq, entryRsp)
+
+		if err != nil || entryRsp.Status != billingpb.ResponseStatusOk {
+			zap.L().Error(
+				pkg.MethodFinishedWithError,
+				zap.String("method", "CreateAccountingEntry"),
+				zap.Error(err),
+				zap.Any("response", entryRsp),
+				zap.String("gift_card_id", card.Id.Hex()),
+			)
+		}
+	}
+
+	return nil
+}
+
+// activateGiftCard activates the gift card sold as the key of the processed order. Keys which aren't gift cards
+// are skipped.
+func (s *Service) activateGiftCard(ctx context.Context, order *billingpb.Order, key *billingpb.Key) {
+	card, err := s.giftCardRepository.GetByKeyId(ctx, key.Id)
+
+	if err != nil || card == nil || card.Status != pkg.GiftCardStatusIssued {
+		return
+	}
+
+	card.Status = pkg.GiftCardStatusActive
+	card.OrderId = order.Id
+	card.BuyerId = order.GetUser().GetId()
+	card.ActivatedAt = time.Now()
+
+	if card.ValidityDays > 0 {
+		card.ExpiresAt = card.ActivatedAt.AddDate(0, 0, int(card.ValidityDays))
+	}
+
+	if err = s.giftCardRepository.Update(ctx, card); err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "giftCardRepository.Update"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+			zap.String("gift_card_id", card.Id.Hex()),
+		)
+	}
+}
+
+// processRefundGiftCards rejects the refund of the keys sold as the gift cards which are already spent or redeemed
+// to the wallet, the value of such card can't be taken back from the customer.
+func (p *createRefundProcessor) processRefundGiftCards() error {
+	for _, item := range p.checked.items {
+		if item.KeyId == "" {
+			continue
+		}
+
+		card, err := p.service.giftCardRepository.GetByKeyId(p.ctx, item.KeyId)
+
+		if err != nil {
+			return errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, refundErrorUnknown)
+		}
+
+		if card != nil && !isGiftCardRefundable(card) {
+			return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, giftCardErrorRefundSpent)
+		}
+	}
+
+	return nil
+}
+
+// cancelGiftCard cancels the gift card of the refunded key. Card is canceled by the conditional update, so the card
+// spent after the refund was created isn't canceled and is logged for the manual check.
+func (s *Service) cancelGiftCard(ctx context.Context, keyId string) {
+	card, err := s.giftCardRepository.GetByKeyId(ctx, keyId)
+
+	if err != nil || card == nil {
+		return
+	}
+
+	ok := false
+
+	if isGiftCardRefundable(card) {
+		ok, err = s.giftCardRepository.Cancel(ctx, card)
+	}
+
+	if err != nil || !ok {
+		zap.L().Error(
+			"Refunded gift card is already spent",
+			zap.Error(err),
+			zap.String("gift_card_id", card.Id.Hex()),
+			zap.Float64("value", card.Value),
+			zap.Float64("balance", card.Balance),
+		)
+	}
+}
+
+// validateOrderGiftCard checks that the gift card applied to the order is still active and has the balance to pay
+// the order before the payment.
+func (s *Service) validateOrderGiftCard(ctx context.Context, order *billingpb.Order) error {
+	amount := getOrderGiftCardAmount(order)
+
+	if amount <= 0 || order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardCharged] != "" {
+		return nil
+	}
+
+	card, err := s.giftCardRepository.GetById(ctx, order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardId])
+
+	if err != nil {
+		return giftCardErrorNotFound
+	}
+
+	if !isGiftCardActive(card) {
+		return giftCardErrorInactive
+	}
+
+	if card.Balance < amount {
+		return giftCardErrorInsufficientBalance
+	}
+
+	return nil
+}
+
+// chargeOrderGiftCard spends the gift card amount of the order. Charge flag is set by the conditional update of
+// the order before the card is decreased, so the repeated or concurrent payment attempt or callback doesn't spend
+// the card again.
+func (s *Service) chargeOrderGiftCard(ctx context.Context, order *billingpb.Order) error {
+	amount := getOrderGiftCardAmount(order)
+
+	if amount <= 0 || order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardCharged] != "" {
+		return nil
+	}
+
+	ok, err := s.setOrderPrivateMetadataFlag(ctx, order, pkg.OrderPrivateMetadataGiftCardCharged)
+
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return giftCardErrorOrderStatusInvalid
+	}
+
+	cardId := order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardId]
+	card, err := s.giftCardRepository.Decrease(ctx, cardId, amount)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "giftCardRepository.Decrease"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+			zap.String("gift_card_id", cardId),
+		)
+		_, _ = s.unsetOrderPrivateMetadataFlag(ctx, order, pkg.OrderPrivateMetadataGiftCardCharged)
+		return err
+	}
+
+	if card.Balance > 0 {
+		return nil
+	}
+
+	card.Status = pkg.GiftCardStatusRedeemed
+
+	if err = s.giftCardRepository.Update(ctx, card); err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "giftCardRepository.Update"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+			zap.String("gift_card_id", cardId),
+		)
+	}
+
+	return nil
+}
+
+// releaseOrderGiftCard returns the charged gift card amount to the card of the order which payment failed or was
+// declined. Charge flag is removed by the conditional update of the order before the card is increased, so only
+// one of the concurrent callbacks returns the amount. Failure is logged only because the order is already rejected.
+func (s *Service) releaseOrderGiftCard(ctx context.Context, order *billingpb.Order) {
+	amount := getOrderGiftCardAmount(order)
+
+	if amount <= 0 || order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardCharged] == "" {
+		return
+	}
+
+	ok, err := s.unsetOrderPrivateMetadataFlag(ctx, order, pkg.OrderPrivateMetadataGiftCardCharged)
+
+	if err != nil {
+		return
+	}
+
+	// amount is already returned by the concurrent callback
+	if !ok {
+		delete(order.PrivateMetadata, pkg.OrderPrivateMetadataGiftCardCharged)
+		return
+	}
+
+	if err = s.increaseGiftCard(ctx, order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardId], amount); err != nil {
+		_, _ = s.setOrderPrivateMetadataFlag(ctx, order, pkg.OrderPrivateMetadataGiftCardCharged)
+	}
+}
+
+// refundOrderGiftCard returns the gift card part of the refund in the charge currency to the gift card which paid
+// the order.
+func (s *Service) refundOrderGiftCard(ctx context.Context, order *billingpb.Order, amount float64) error {
+	if order.ChargeCurrency != order.Currency {
+		amount = s.FormatAmount(amount*getOrderGiftCardAmount(order)/getOrderGiftCardChargeAmount(order), order.Currency)
+	}
+
+	return s.increaseGiftCard(ctx, order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardId], amount)
+}
+
+// increaseGiftCard adds the amount to the balance of the gift card, the card redeemed by the payment becomes active
+// again.
+func (s *Service) increaseGiftCard(ctx context.Context, cardId string, amount float64) error {
+	card, err := s.giftCardRepository.Increase(ctx, cardId, amount)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "giftCardRepository.Increase"),
+			zap.Error(err),
+			zap.String("gift_card_id", cardId),
+		)
+		return err
+	}
+
+	if card.Status != pkg.GiftCardStatusRedeemed {
+		return nil
+	}
+
+	card.Status = pkg.GiftCardStatusActive
+
+	if err = s.giftCardRepository.Update(ctx, card); err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "giftCardRepository.Update"),
+			zap.Error(err),
+			zap.String("gift_card_id", cardId),
+		)
+	}
+
+	return nil
+}
+
+// getSoldGiftCard returns the gift card by the code. Gift cards which aren't sold yet aren't available to customers.
+func (s *Service) getSoldGiftCard(ctx context.Context, projectId, code string) (*intPkg.GiftCard, error) {
+	card, err := s.giftCardRepository.GetByCode(ctx, projectId, normalizeGiftCardCode(code))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if card.Status == pkg.GiftCardStatusIssued {
+		return nil, giftCardErrorNotFound
+	}
+
+	return card, nil
+}
+
+func (s *Service) setOrderGiftCard(order *billingpb.Order, card *intPkg.GiftCard, amount float64) {
+	if order.PrivateMetadata == nil {
+		order.PrivateMetadata = make(map[string]string)
+	}
+
+	order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardId] = card.Id.Hex()
+	order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardAmount] = strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// removeOrderGiftCard removes the gift card from the order and returns the identifier of the removed card.
+func (s *Service) removeOrderGiftCard(order *billingpb.Order) string {
+	id := order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardId]
+
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataGiftCardId)
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataGiftCardAmount)
+
+	return id
+}
+
+// getOrderGiftCardAmount returns the amount of the order paid by the gift card in the order currency.
+func getOrderGiftCardAmount(order *billingpb.Order) float64 {
+	value, ok := order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardAmount]
+
+	if !ok {
+		return 0
+	}
+
+	amount, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		return 0
+	}
+
+	return amount
+}
+
+// getOrderGiftCardChargeAmount returns the charged gift card amount of the order in the charge currency.
+func getOrderGiftCardChargeAmount(order *billingpb.Order) float64 {
+	amount := getOrderGiftCardAmount(order)
+
+	if amount <= 0 || order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardCharged] == "" {
+		return 0
+	}
+
+	return getOrderPrepaidAmountInChargeCurrency(order, amount)
+}
+
+// isGiftCardRefundable checks that the sold gift card isn't spent, so its value can be taken back by the refund.
+func isGiftCardRefundable(card *intPkg.GiftCard) bool {
+	return (card.Status == pkg.GiftCardStatusIssued || card.Status == pkg.GiftCardStatusActive) &&
+		card.Balance >= card.Value
+}
+
+func isGiftCardActive(card *intPkg.GiftCard) bool {
+	return card.Status == pkg.GiftCardStatusActive && card.Balance > 0 &&
+		(card.ExpiresAt.IsZero() || time.Now().Before(card.ExpiresAt))
+}
+
+// generateGiftCardCode returns the random code of the gift card split into groups, for example ABCD-EFGH-JKLM-NPQR.
+func generateGiftCardCode() (string, error) {
+	b := make([]byte, giftCardCodeGroupsCount*giftCardCodeGroupLength)
+
+	if _, err := cryptoRand.Read(b); err != nil {
+		return "", err
+	}
+
+	groups := make([]string, giftCardCodeGroupsCount)
+
+	for i := range groups {
+		group := make([]byte, giftCardCodeGroupLength)
+
+		for j := range group {
+			group[j] = giftCardCodeAlphabet[int(b[i*giftCardCodeGroupLength+j])%len(giftCardCodeAlphabet)]
+		}
+
+		groups[i] = string(group)
+	}
+
+	return strings.Join(groups, "-"), nil
+}
+
+func normalizeGiftCardCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func getGiftCardMessage(card *intPkg.GiftCard) *pkg.GiftCard {
+	return &pkg.GiftCard{
+		Id:           card.Id.Hex(),
+		KeyProductId: card.KeyProductId,
+		Value:        card.Value,
+		Balance:      card.Balance,
+		Currency:     card.Currency,
+		Status:       card.Status,
+		ActivatedAt:  getTimestampProto(card.ActivatedAt),
+		ExpiresAt:    getTimestampProto(card.ExpiresAt),
+	}
+}
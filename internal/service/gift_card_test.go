@@ -0,0 +1,567 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/google/uuid"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	tools "github.com/paysuper/paysuper-tools/number"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type GiftCardTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant *billingpb.Merchant
+	project  *billingpb.Project
+	customer *billingpb.Customer
+}
+
+func Test_GiftCard(t *testing.T) {
+	suite.Run(t, new(GiftCardTestSuite))
+}
+
+func (suite *GiftCardTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	suite.merchant, suite.project, _, _, suite.customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+}
+
+func (suite *GiftCardTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *GiftCardTestSuite) createOrder() *billingpb.Order {
+	req := &billingpb.OrderCreateRequest{
+		Type:        pkg.OrderType_simple,
+		ProjectId:   suite.project.Id,
+		Amount:      100,
+		Currency:    "RUB",
+		Account:     "unit test",
+		Description: "unit test",
+		User: &billingpb.OrderUser{
+			Id:    suite.customer.Id,
+			Uuid:  uuid.New().String(),
+			Email: "test@unit.unit",
+			Ip:    "127.0.0.1",
+			Address: &billingpb.OrderBillingAddress{
+				Country: "RU",
+			},
+		},
+	}
+
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	return rsp.Item
+}
+
+func (suite *GiftCardTestSuite) createGiftCard(status string, balance float64) *intPkg.GiftCard {
+	card := &intPkg.GiftCard{
+		Code:         primitive.NewObjectID().Hex(),
+		KeyId:        primitive.NewObjectID().Hex(),
+		KeyProductId: primitive.NewObjectID().Hex(),
+		Value:        balance,
+		Balance:      balance,
+		Currency:     "RUB",
+		Status:       status,
+	}
+	card.MerchantId, _ = primitive.ObjectIDFromHex(suite.merchant.Id)
+	card.ProjectId, _ = primitive.ObjectIDFromHex(suite.project.Id)
+	card.Code = normalizeGiftCardCode(card.Code)
+
+	err := suite.service.giftCardRepository.Insert(context.TODO(), card)
+	assert.NoError(suite.T(), err)
+
+	return card
+}
+
+func (suite *GiftCardTestSuite) TestGiftCard_GenerateGiftCards_Ok() {
+	keyProduct := &billingpb.KeyProduct{
+		Id:         primitive.NewObjectID().Hex(),
+		MerchantId: suite.merchant.Id,
+		ProjectId:  suite.project.Id,
+		Sku:        "gift_card",
+	}
+	err := suite.service.keyProductRepository.Upsert(context.TODO(), keyProduct)
+	assert.NoError(suite.T(), err)
+
+	req := &pkg.GenerateGiftCardsRequest{
+		MerchantId:   suite.merchant.Id,
+		KeyProductId: keyProduct.Id,
+		PlatformId:   "steam",
+		Count:        3,
+		Value:        50,
+		Currency:     "rub",
+		ValidityDays: 365,
+	}
+	rsp := &pkg.GenerateGiftCardsResponse{}
+	err = suite.service.GenerateGiftCards(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.EqualValues(suite.T(), 3, rsp.Count)
+
+	count, err := suite.service.keyRepository.CountKeysByProductPlatform(context.TODO(), keyProduct.Id, "steam")
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 3, count)
+
+	req.MerchantId = primitive.NewObjectID().Hex()
+	err = suite.service.GenerateGiftCards(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), keyProductMerchantMismatch, rsp.Message)
+}
+
+func (suite *GiftCardTestSuite) TestGiftCard_GenerateGiftCards_ValidationError() {
+	req := &pkg.GenerateGiftCardsRequest{
+		MerchantId:   suite.merchant.Id,
+		KeyProductId: primitive.NewObjectID().Hex(),
+		PlatformId:   "steam",
+		Count:        3,
+		Value:        0,
+		Currency:     "RUB",
+	}
+	rsp := &pkg.GenerateGiftCardsResponse{}
+	err := suite.service.GenerateGiftCards(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), giftCardErrorValueInvalid, rsp.Message)
+
+	req.Value = 50
+	req.Count = giftCardGenerateMaxCount + 1
+	rsp = &pkg.GenerateGiftCardsResponse{}
+	err = suite.service.GenerateGiftCards(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), giftCardErrorCountInvalid, rsp.Message)
+
+	req.Count = 3
+	req.Currency = "XXX"
+	rsp = &pkg.GenerateGiftCardsResponse{}
+	err = suite.service.GenerateGiftCards(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), giftCardErrorCurrencyInvalid, rsp.Message)
+}
+
+func (suite *GiftCardTestSuite) TestGiftCard_RefundSpentGiftCard_Rejected() {
+	card := suite.createGiftCard(pkg.GiftCardStatusActive, 50)
+	processor := &createRefundProcessor{
+		service: suite.service,
+		checked: &createRefundChecked{items: []*intPkg.RefundItem{{KeyId: card.KeyId}}},
+		ctx:     context.TODO(),
+	}
+	assert.NoError(suite.T(), processor.processRefundGiftCards())
+
+	_, err := suite.service.giftCardRepository.Decrease(context.TODO(), card.Id.Hex(), 10)
+	assert.NoError(suite.T(), err)
+
+	err = processor.processRefundGiftCards()
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), giftCardErrorRefundSpent, err.(*billingpb.ResponseError).Message)
+
+	// card spent after the refund was created isn't canceled
+	suite.service.cancelGiftCard(context.TODO(), card.KeyId)
+
+	card, err = suite.service.giftCardRepository.GetById(context.TODO(), card.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.GiftCardStatusActive, card.Status)
+	assert.EqualValues(suite.T(), 40, card.Balance)
+
+	notSpent := suite.createGiftCard(pkg.GiftCardStatusActive, 50)
+	suite.service.cancelGiftCard(context.TODO(), notSpent.KeyId)
+
+	notSpent, err = suite.service.giftCardRepository.GetById(context.TODO(), notSpent.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.GiftCardStatusCanceled, notSpent.Status)
+	assert.Zero(suite.T(), notSpent.Balance)
+}
+
+func (suite *GiftCardTestSuite) TestGiftCard_ActivateAndRedeem_Ok() {
+	card := suite.createGiftCard(pkg.GiftCardStatusIssued, 50)
+	card.ValidityDays = 30
+	err := suite.service.giftCardRepository.Update(context.TODO(), card)
+	assert.NoError(suite.T(), err)
+
+	req := &pkg.RedeemGiftCardRequest{ProjectId: suite.project.Id, Code: card.Code, CustomerId: suite.customer.Id}
+	rsp := &pkg.CustomerWalletResponse{}
+	err = suite.service.RedeemGiftCard(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), giftCardErrorNotFound, rsp.Message)
+
+	order := &billingpb.Order{Id: primitive.NewObjectID().Hex(), User: &billingpb.OrderUser{Id: suite.customer.Id}}
+	suite.service.activateGiftCard(context.TODO(), order, &billingpb.Key{Id: card.KeyId})
+
+	card, err = suite.service.giftCardRepository.GetById(context.TODO(), card.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.GiftCardStatusActive, card.Status)
+	assert.Equal(suite.T(), order.Id, card.OrderId)
+	assert.False(suite.T(), card.ExpiresAt.IsZero())
+
+	rsp = &pkg.CustomerWalletResponse{}
+	err = suite.service.RedeemGiftCard(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.EqualValues(suite.T(), 50, rsp.Item.Balance)
+	assert.Equal(suite.T(), "RUB", rsp.Item.Currency)
+
+	card, err = suite.service.giftCardRepository.GetById(context.TODO(), card.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.GiftCardStatusRedeemed, card.Status)
+	assert.Zero(suite.T(), card.Balance)
+
+	rsp = &pkg.CustomerWalletResponse{}
+	err = suite.service.RedeemGiftCard(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), giftCardErrorInactive, rsp.Message)
+}
+
+func (suite *GiftCardTestSuite) TestGiftCard_RedeemGiftCard_TopUpError_BalanceRestored() {
+	card := suite.createGiftCard(pkg.GiftCardStatusActive, 50)
+
+	walletRep := &mocks.CustomerWalletRepositoryInterface{}
+	walletRep.On("Increase", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, errors.New("some error"))
+	suite.service.customerWalletRepository = walletRep
+
+	req := &pkg.RedeemGiftCardRequest{ProjectId: suite.project.Id, Code: card.Code, CustomerId: suite.customer.Id}
+	rsp := &pkg.CustomerWalletResponse{}
+	err := suite.service.RedeemGiftCard(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+	assert.Equal(suite.T(), giftCardErrorUnknown, rsp.Message)
+
+	card, err = suite.service.giftCardRepository.GetById(context.TODO(), card.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.GiftCardStatusActive, card.Status)
+	assert.EqualValues(suite.T(), 50, card.Balance)
+}
+
+func (suite *GiftCardTestSuite) TestGiftCard_ApplyGiftCardToOrder_Ok() {
+	card := suite.createGiftCard(pkg.GiftCardStatusActive, 30)
+	order := suite.createOrder()
+
+	req := &pkg.ApplyGiftCardRequest{OrderId: order.Uuid, Code: card.Code}
+	rsp := &pkg.ApplyGiftCardResponse{}
+	err := suite.service.ApplyGiftCardToOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.EqualValues(suite.T(), 30, rsp.GiftCardAmount)
+	assert.False(suite.T(), rsp.IsPaid)
+	assert.EqualValues(suite.T(), rsp.Item.TotalAmount-30, rsp.Item.ChargeAmount)
+
+	order, err = suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), card.Id.Hex(), order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardId])
+	assert.NoError(suite.T(), suite.service.validateOrderGiftCard(context.TODO(), order))
+
+	assert.NoError(suite.T(), suite.service.chargeOrderGiftCard(context.TODO(), order))
+	assert.NoError(suite.T(), suite.service.chargeOrderGiftCard(context.TODO(), order))
+
+	card, err = suite.service.giftCardRepository.GetById(context.TODO(), card.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), card.Balance)
+	assert.Equal(suite.T(), pkg.GiftCardStatusRedeemed, card.Status)
+}
+
+func (suite *GiftCardTestSuite) TestGiftCard_ReleaseOrderGiftCard_Ok() {
+	card := suite.createGiftCard(pkg.GiftCardStatusActive, 30)
+	order := suite.createOrder()
+
+	req := &pkg.ApplyGiftCardRequest{OrderId: order.Uuid, Code: card.Code}
+	rsp := &pkg.ApplyGiftCardResponse{}
+	err := suite.service.ApplyGiftCardToOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	order, err = suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	order1, err := suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+
+	// concurrent payment read the order before the gift card was charged
+	assert.NoError(suite.T(), suite.service.chargeOrderGiftCard(context.TODO(), order))
+	assert.Equal(suite.T(), giftCardErrorOrderStatusInvalid, suite.service.chargeOrderGiftCard(context.TODO(), order1))
+
+	card, err = suite.service.giftCardRepository.GetById(context.TODO(), card.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), card.Balance)
+	assert.Equal(suite.T(), pkg.GiftCardStatusRedeemed, card.Status)
+
+	// charged gift card can't be changed on the payment form
+	err = suite.service.ApplyGiftCardToOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), giftCardErrorOrderStatusInvalid, rsp.Message)
+
+	order1, err = suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+
+	// declined payment returns the gift card amount once
+	suite.service.releaseOrderGiftCard(context.TODO(), order)
+	suite.service.releaseOrderGiftCard(context.TODO(), order1)
+	assert.Empty(suite.T(), order1.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardCharged])
+
+	card, err = suite.service.giftCardRepository.GetById(context.TODO(), card.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 30, card.Balance)
+	assert.Equal(suite.T(), pkg.GiftCardStatusActive, card.Status)
+}
+
+func (suite *GiftCardTestSuite) TestGiftCard_ChargeOrderPrepaidAmounts_SpentCard_Error() {
+	card := suite.createGiftCard(pkg.GiftCardStatusActive, 30)
+	order := suite.createOrder()
+
+	req := &pkg.ApplyGiftCardRequest{OrderId: order.Uuid, Code: card.Code}
+	rsp := &pkg.ApplyGiftCardResponse{}
+	err := suite.service.ApplyGiftCardToOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	order, err = suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+
+	// card is spent by another order after it was applied to this one
+	_, err = suite.service.giftCardRepository.Decrease(context.TODO(), card.Id.Hex(), 20)
+	assert.NoError(suite.T(), err)
+
+	err = suite.service.chargeOrderPrepaidAmounts(context.TODO(), order)
+	assert.Equal(suite.T(), giftCardErrorInsufficientBalance, err)
+
+	order, err = suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardCharged])
+
+	card, err = suite.service.giftCardRepository.GetById(context.TODO(), card.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 10, card.Balance)
+}
+
+func (suite *GiftCardTestSuite) TestGiftCard_ApplyGiftCardToOrder_FullyPaid_MerchantRevenue_Ok() {
+	card := suite.createGiftCard(pkg.GiftCardStatusActive, 1000)
+	order := suite.createOrder()
+
+	req := &pkg.ApplyGiftCardRequest{OrderId: order.Uuid, Code: card.Code}
+	rsp := &pkg.ApplyGiftCardResponse{}
+	err := suite.service.ApplyGiftCardToOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.True(suite.T(), rsp.IsPaid)
+
+	entries, err := suite.service.accountingRepository.FindBySource(context.TODO(), order.Id, repository.CollectionOrder)
+	assert.NoError(suite.T(), err)
+
+	amounts := make(map[string]*billingpb.AccountingEntry)
+
+	for _, entry := range entries {
+		amounts[entry.Type] = entry
+	}
+
+	payment, ok := amounts[pkg.AccountingEntryTypeGiftCardPayment]
+	assert.True(suite.T(), ok)
+	taxFee, ok := amounts[pkg.AccountingEntryTypeMerchantTaxFeeCostValue]
+	assert.True(suite.T(), ok)
+	taxFeeFx, ok := amounts[pkg.AccountingEntryTypeMerchantTaxFeeCentralBankFx]
+	assert.True(suite.T(), ok)
+
+	err = suite.service.updateOrderView(context.TODO(), []string{order.Id})
+	assert.NoError(suite.T(), err)
+
+	// part of the order paid by the gift card is the merchant revenue
+	view, err := suite.service.orderViewRepository.GetPrivateOrderBy(context.TODO(), order.Id, "", "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), payment.Currency, view.MerchantPayoutCurrency)
+	assert.Equal(suite.T(), tools.ToPrecise(payment.Amount), tools.ToPrecise(view.GrossRevenue.Amount))
+	assert.Equal(suite.T(), tools.ToPrecise(payment.Amount-taxFee.Amount-taxFeeFx.Amount), tools.ToPrecise(view.NetRevenue.Amount))
+}
+
+func (suite *GiftCardTestSuite) TestGiftCard_CreateRefund_FullyPaidOrder_ReturnedToGiftCard() {
+	card := suite.createGiftCard(pkg.GiftCardStatusActive, 1000)
+	order := suite.createOrder()
+
+	req := &pkg.ApplyGiftCardRequest{OrderId: order.Uuid, Code: card.Code}
+	rsp := &pkg.ApplyGiftCardResponse{}
+	err := suite.service.ApplyGiftCardToOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.True(suite.T(), rsp.IsPaid)
+
+	order, err = suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), order.IsRefundAllowed)
+	assert.Equal(suite.T(), getOrderGiftCardAmount(order), getOrderRefundableAmount(order))
+
+	req1 := &billingpb.CreateRefundRequest{
+		OrderId:    order.Uuid,
+		CreatorId:  suite.merchant.Id,
+		Reason:     "unit test",
+		MerchantId: suite.merchant.Id,
+	}
+	rsp1 := &billingpb.CreateRefundResponse{}
+	err = suite.service.CreateRefund(context.TODO(), req1, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+	assert.Equal(suite.T(), pkg.RefundStatusCompleted, rsp1.Item.Status)
+	assert.Equal(suite.T(), getOrderGiftCardAmount(order), rsp1.Item.Amount)
+
+	card, err = suite.service.giftCardRepository.GetById(context.TODO(), card.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1000, card.Balance)
+	assert.Equal(suite.T(), pkg.GiftCardStatusActive, card.Status)
+}
+
+func (suite *GiftCardTestSuite) TestGiftCard_ApplyGiftCardToOrder_CurrencyMismatch_Error() {
+	card := suite.createGiftCard(pkg.GiftCardStatusActive, 30)
+	card.Currency = "USD"
+	err := suite.service.giftCardRepository.Update(context.TODO(), card)
+	assert.NoError(suite.T(), err)
+
+	order := suite.createOrder()
+
+	req := &pkg.ApplyGiftCardRequest{OrderId: order.Uuid, Code: card.Code}
+	rsp := &pkg.ApplyGiftCardResponse{}
+	err = suite.service.ApplyGiftCardToOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), giftCardErrorCurrencyMismatch, rsp.Message)
+}
+
+func (suite *GiftCardTestSuite) TestGiftCard_ExpireGiftCards_Ok() {
+	card := suite.createGiftCard(pkg.GiftCardStatusActive, 40)
+	card.ExpiresAt = time.Now().Add(-time.Hour)
+	err := suite.service.giftCardRepository.Update(context.TODO(), card)
+	assert.NoError(suite.T(), err)
+
+	notExpired := suite.createGiftCard(pkg.GiftCardStatusActive, 40)
+
+	err = suite.service.ExpireGiftCards(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	card, err = suite.service.giftCardRepository.GetById(context.TODO(), card.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.GiftCardStatusExpired, card.Status)
+	assert.EqualValues(suite.T(), 40, card.BreakageAmount)
+	assert.Zero(suite.T(), card.Balance)
+
+	notExpired, err = suite.service.giftCardRepository.GetById(context.TODO(), notExpired.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.GiftCardStatusActive, notExpired.Status)
+
+	err = suite.service.ExpireGiftCards(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	entries, err := suite.service.accountingRepository.FindBySource(context.TODO(), suite.merchant.Id, repository.CollectionMerchant)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), entries, 1)
+	assert.Equal(suite.T(), pkg.AccountingEntryTypeGiftCardBreakage, entries[0].Type)
+	assert.EqualValues(suite.T(), 40, entries[0].Amount)
+}
+
+func (suite *GiftCardTestSuite) TestGiftCard_ExpireGiftCards_SpentAfterRead_NotExpired() {
+	card := suite.createGiftCard(pkg.GiftCardStatusActive, 40)
+	card.ExpiresAt = time.Now().Add(-time.Hour)
+	err := suite.service.giftCardRepository.Update(context.TODO(), card)
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.service.giftCardRepository.Decrease(context.TODO(), card.Id.Hex(), 10)
+	assert.NoError(suite.T(), err)
+
+	ok, err := suite.service.giftCardRepository.Expire(context.TODO(), card)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), ok)
+
+	card, err = suite.service.giftCardRepository.GetById(context.TODO(), card.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.GiftCardStatusActive, card.Status)
+	assert.EqualValues(suite.T(), 30, card.Balance)
+	assert.Zero(suite.T(), card.BreakageAmount)
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/golang/protobuf/proto"
+	"github.com/micro/go-micro/metadata"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	idempotencyKeyMetadataField = "Idempotency-Key"
+
+	idempotencyOperationOrderCreate  = "order_create"
+	idempotencyOperationRefundCreate = "refund_create"
+)
+
+var (
+	idempotencyErrorKeyConflict   = errors.NewBillingServerErrorMsg("ik000001", "idempotency key already used for the request with another payload")
+	idempotencyErrorKeyInProgress = errors.NewBillingServerErrorMsg("ik000002", "request with the idempotency key is processing now. try request later")
+	idempotencyErrorUnknown       = errors.NewBillingServerErrorMsg("ik000003", "unknown error. try request later")
+)
+
+type idempotentResponse interface {
+	proto.Message
+	GetStatus() int32
+}
+
+// getIdempotencyKey returns the idempotency key which is passed by client in the request metadata (headers).
+func getIdempotencyKey(ctx context.Context) string {
+	return getMetadataValue(ctx, idempotencyKeyMetadataField)
+}
+
+// getMetadataValue returns value of the request metadata field (header). Names of fields are case insensitive.
+func getMetadataValue(ctx context.Context, field string) string {
+	md, ok := metadata.FromContext(ctx)
+
+	if !ok {
+		return ""
+	}
+
+	for k, v := range md {
+		if strings.EqualFold(k, field) {
+			return strings.TrimSpace(v)
+		}
+	}
+
+	return ""
+}
+
+// getIdempotentRequestHash returns hash of the request payload to check that retry has the same payload.
+// Deterministic marshaling is used because order of map fields isn't stable otherwise.
+func getIdempotentRequestHash(req proto.Message) (string, error) {
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+
+	if err := buf.Marshal(req); err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(buf.Bytes())
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// processIdempotent calls fn only once for the idempotency key of the project and saves the response for the time to
+// live of the keys. The next calls with the same key get the saved response. If the key was used with another request
+// payload or the first request is processing yet then the error is returned and the response isn't changed.
+// The key of the processing request is reserved for the short lock time only, so the key of the request which was
+// interrupted without the response is taken over by the retry after the lock time.
+// Requests without idempotency key are processed as usual.
+func (s *Service) processIdempotent(
+	ctx context.Context,
+	operation, projectId string,
+	req proto.Message,
+	rsp idempotentResponse,
+	fn func() error,
+) error {
+	key := getIdempotencyKey(ctx)
+
+	if key == "" || projectId == "" {
+		return fn()
+	}
+
+	hash, err := getIdempotentRequestHash(req)
+
+	if err != nil {
+		zap.L().Error("idempotent request marshaling failed", zap.Error(err), zap.Any(pkg.LogFieldRequest, req))
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, idempotencyErrorUnknown)
+	}
+
+	record := &intPkg.IdempotencyKey{
+		ProjectId:   projectId,
+		Operation:   operation,
+		Key:         key,
+		RequestHash: hash,
+		ExpireAt:    time.Now().Add(s.cfg.GetIdempotencyKeyLockTtl()),
+	}
+
+	existing, err := s.idempotencyKeyRepository.Reserve(ctx, record)
+
+	// key can be expired or left by the interrupted request but not removed from database yet
+	if err == nil && existing != nil && existing.ExpireAt.Before(time.Now()) {
+		if err = s.idempotencyKeyRepository.Delete(ctx, existing); err == nil {
+			existing, err = s.idempotencyKeyRepository.Reserve(ctx, record)
+		}
+	}
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, idempotencyErrorUnknown)
+	}
+
+	if existing != nil {
+		if existing.RequestHash != record.RequestHash {
+			return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, idempotencyErrorKeyConflict)
+		}
+
+		if len(existing.Response) <= 0 {
+			return errors.NewBillingServerResponseError(billingpb.ResponseStatusTemporary, idempotencyErrorKeyInProgress)
+		}
+
+		if err = proto.Unmarshal(existing.Response, rsp); err != nil {
+			zap.L().Error("idempotent response unmarshaling failed", zap.Error(err), zap.Any("idempotency_key", existing))
+			return errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, idempotencyErrorUnknown)
+		}
+
+		return nil
+	}
+
+	err = fn()
+
+	// the request wasn't processed because of temporary problems, so client can retry it with the same key
+	if err != nil || rsp.GetStatus() == billingpb.ResponseStatusSystemError ||
+		rsp.GetStatus() == billingpb.ResponseStatusTemporary {
+		_ = s.idempotencyKeyRepository.Delete(ctx, record)
+		return err
+	}
+
+	record.Response, err = proto.Marshal(rsp)
+	record.ExpireAt = time.Now().Add(s.cfg.GetIdempotencyKeyTtl())
+
+	if err == nil {
+		err = s.idempotencyKeyRepository.SetResponse(ctx, record)
+	}
+
+	if err != nil {
+		zap.L().Error("idempotent response saving failed", zap.Error(err), zap.Any("idempotency_key", record))
+		_ = s.idempotencyKeyRepository.Delete(ctx, record)
+	}
+
+	return nil
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"github.com/micro/go-micro/metadata"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type IdempotencyTestSuite struct {
+	suite.Suite
+	service   *Service
+	cache     database.CacheInterface
+	projectId string
+	calls     int
+}
+
+func Test_Idempotency(t *testing.T) {
+	suite.Run(t, new(IdempotencyTestSuite))
+}
+
+func (suite *IdempotencyTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	suite.projectId = primitive.NewObjectID().Hex()
+	suite.calls = 0
+}
+
+func (suite *IdempotencyTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *IdempotencyTestSuite) getContext(key string) context.Context {
+	return metadata.NewContext(context.TODO(), metadata.Metadata{idempotencyKeyMetadataField: key})
+}
+
+func (suite *IdempotencyTestSuite) process(
+	ctx context.Context,
+	req *billingpb.CreateRefundRequest,
+	status int32,
+) (*billingpb.CreateRefundResponse, error) {
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.processIdempotent(ctx, idempotencyOperationRefundCreate, suite.projectId, req, rsp, func() error {
+		suite.calls++
+		rsp.Status = status
+		rsp.Item = &billingpb.Refund{Id: primitive.NewObjectID().Hex(), Amount: req.Amount}
+		return nil
+	})
+
+	return rsp, err
+}
+
+func (suite *IdempotencyTestSuite) getRequest() *billingpb.CreateRefundRequest {
+	return &billingpb.CreateRefundRequest{
+		OrderId:    primitive.NewObjectID().Hex(),
+		Amount:     10,
+		MerchantId: primitive.NewObjectID().Hex(),
+		Reason:     "unit test",
+	}
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_SameRequest_ReturnsStoredResponse() {
+	ctx := suite.getContext("key_1")
+	req := suite.getRequest()
+
+	rsp1, err := suite.process(ctx, req, billingpb.ResponseStatusOk)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+
+	rsp2, err := suite.process(ctx, req, billingpb.ResponseStatusOk)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, suite.calls)
+	assert.Equal(suite.T(), rsp1.Item.Id, rsp2.Item.Id)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_AnotherPayload_Conflict() {
+	ctx := suite.getContext("key_1")
+	req := suite.getRequest()
+
+	_, err := suite.process(ctx, req, billingpb.ResponseStatusOk)
+	assert.NoError(suite.T(), err)
+
+	req.Amount = 20
+	_, err = suite.process(ctx, req, billingpb.ResponseStatusOk)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, err.(*billingpb.ResponseError).Status)
+	assert.Equal(suite.T(), idempotencyErrorKeyConflict, err.(*billingpb.ResponseError).Message)
+	assert.Equal(suite.T(), 1, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_AnotherProject_Processed() {
+	ctx := suite.getContext("key_1")
+	req := suite.getRequest()
+
+	_, err := suite.process(ctx, req, billingpb.ResponseStatusOk)
+	assert.NoError(suite.T(), err)
+
+	suite.projectId = primitive.NewObjectID().Hex()
+	_, err = suite.process(ctx, req, billingpb.ResponseStatusOk)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_WithoutKey_ProcessedEveryTime() {
+	req := suite.getRequest()
+
+	_, err := suite.process(context.TODO(), req, billingpb.ResponseStatusOk)
+	assert.NoError(suite.T(), err)
+	_, err = suite.process(context.TODO(), req, billingpb.ResponseStatusOk)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_SystemError_KeyReleased() {
+	ctx := suite.getContext("key_1")
+	req := suite.getRequest()
+
+	rsp, err := suite.process(ctx, req, billingpb.ResponseStatusSystemError)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+
+	rsp, err = suite.process(ctx, req, billingpb.ResponseStatusOk)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), 2, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_InProgress() {
+	ctx := suite.getContext("key_1")
+	req := suite.getRequest()
+
+	hash, err := getIdempotentRequestHash(req)
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.service.idempotencyKeyRepository.Reserve(context.TODO(), &intPkg.IdempotencyKey{
+		ProjectId:   suite.projectId,
+		Operation:   idempotencyOperationRefundCreate,
+		Key:         "key_1",
+		RequestHash: hash,
+		ExpireAt:    time.Now().Add(time.Hour),
+	})
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.process(ctx, req, billingpb.ResponseStatusOk)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusTemporary, err.(*billingpb.ResponseError).Status)
+	assert.Equal(suite.T(), idempotencyErrorKeyInProgress, err.(*billingpb.ResponseError).Message)
+	assert.Equal(suite.T(), 0, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_InterruptedRequest_ProcessedAfterLock() {
+	ctx := suite.getContext("key_1")
+	req := suite.getRequest()
+
+	hash, err := getIdempotentRequestHash(req)
+	assert.NoError(suite.T(), err)
+
+	// the key of the request interrupted before the response is reserved for the lock time only
+	_, err = suite.service.idempotencyKeyRepository.Reserve(context.TODO(), &intPkg.IdempotencyKey{
+		ProjectId:   suite.projectId,
+		Operation:   idempotencyOperationRefundCreate,
+		Key:         "key_1",
+		RequestHash: hash,
+		ExpireAt:    time.Now().Add(-time.Minute),
+	})
+	assert.NoError(suite.T(), err)
+
+	rsp1, err := suite.process(ctx, req, billingpb.ResponseStatusOk)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+
+	// the response is kept for the time to live of keys, not for the lock time
+	suite.service.cfg.IdempotencyKeyLockTtl = -1
+	rsp2, err := suite.process(ctx, req, billingpb.ResponseStatusOk)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), rsp1.Item.Id, rsp2.Item.Id)
+	assert.Equal(suite.T(), 1, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_Expired_ProcessedAgain() {
+	ctx := suite.getContext("key_1")
+	req := suite.getRequest()
+
+	suite.service.cfg.IdempotencyKeyTtl = -1
+	_, err := suite.process(ctx, req, billingpb.ResponseStatusOk)
+	assert.NoError(suite.T(), err)
+
+	suite.service.cfg.IdempotencyKeyTtl = 3600
+	_, err = suite.process(ctx, req, billingpb.ResponseStatusOk)
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.process(ctx, req, billingpb.ResponseStatusOk)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_Concurrent_OneProcessed() {
+	ctx := suite.getContext("key_1")
+	req := suite.getRequest()
+
+	var calls int32
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 10)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			rsp := &billingpb.CreateRefundResponse{}
+			errs <- suite.service.processIdempotent(ctx, idempotencyOperationRefundCreate, suite.projectId, req, rsp, func() error {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(100 * time.Millisecond)
+				rsp.Status = billingpb.ResponseStatusOk
+				return nil
+			})
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	assert.Equal(suite.T(), int32(1), calls)
+
+	for err := range errs {
+		if err == nil {
+			continue
+		}
+
+		assert.Equal(suite.T(), billingpb.ResponseStatusTemporary, err.(*billingpb.ResponseError).Status)
+		assert.Equal(suite.T(), idempotencyErrorKeyInProgress, err.(*billingpb.ResponseError).Message)
+	}
+}
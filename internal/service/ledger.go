@@ -0,0 +1,483 @@
+package service
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	tools "github.com/paysuper/paysuper-tools/number"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	accountingEntryErrorJournalUnbalanced     = errors.NewBillingServerErrorMsg("ae00019", "sum of debits of the journal entry isn't equal to sum of credits")
+	accountingEntryErrorJournalUnknownAccount = errors.NewBillingServerErrorMsg("ae00020", "unknown ledger account of the journal entry")
+	accountingEntryErrorJournalUnmappedEntry  = errors.NewBillingServerErrorMsg("ae00021", "accounting entry type isn't mapped to the ledger accounts")
+)
+
+// ledgerPosting is the account debited or credited by the amount of the accounting entry. The entry transferring
+// the amount between two accounts debits one account and credits the other one, the entry of the money received or
+// paid by the event posts one side only and is balanced by the counter-entries of the same event.
+type ledgerPosting struct {
+	debit  string
+	credit string
+}
+
+// ledgerCounterEntry is the entry calculated in the order view that is posted to the ledger as the counter-entry of
+// the saved entries of the event. Its amount is the sum of the amounts of the entries of the listed types taken with
+// the sign.
+type ledgerCounterEntry struct {
+	entryType string
+	entries   map[string]float64
+}
+
+var (
+	availableLedgerAccounts = map[string]bool{
+		pkg.LedgerAccountGatewayReceivable: true,
+		pkg.LedgerAccountMerchantPayable:   true,
+		pkg.LedgerAccountPlatformRevenue:   true,
+		pkg.LedgerAccountTaxPayable:        true,
+		pkg.LedgerAccountRollingReserve:    true,
+		pkg.LedgerAccountCustomerCredit:    true,
+		pkg.LedgerAccountRefundPayable:     true,
+	}
+
+	// ledgerPostings are the accounts debited and credited by the amount of the accounting entry. Merchant tax is
+	// posted by the merchant entries of the whole order amount including the parts paid by the wallet and the gift card.
+	ledgerPostings = map[string]*ledgerPosting{
+		// payment
+		pkg.AccountingEntryTypeRealGrossRevenue: {
+			debit: pkg.LedgerAccountGatewayReceivable,
+		},
+		pkg.AccountingEntryTypeWalletPayment: {
+			debit: pkg.LedgerAccountCustomerCredit,
+		},
+		pkg.AccountingEntryTypeGiftCardPayment: {
+			debit: pkg.LedgerAccountCustomerCredit,
+		},
+		pkg.AccountingEntryTypeMerchantGrossRevenue: {
+			credit: pkg.LedgerAccountMerchantPayable,
+		},
+		pkg.AccountingEntryTypePsGrossRevenueFx: {
+			credit: pkg.LedgerAccountPlatformRevenue,
+		},
+		pkg.AccountingEntryTypeMerchantTaxFeeCostValue: {
+			debit:  pkg.LedgerAccountMerchantPayable,
+			credit: pkg.LedgerAccountTaxPayable,
+		},
+		pkg.AccountingEntryTypePsGrossRevenueFxTaxFee: {
+			debit:  pkg.LedgerAccountPlatformRevenue,
+			credit: pkg.LedgerAccountTaxPayable,
+		},
+		pkg.AccountingEntryTypeMerchantTaxFeeCentralBankFx: {
+			debit:  pkg.LedgerAccountMerchantPayable,
+			credit: pkg.LedgerAccountPlatformRevenue,
+		},
+		pkg.AccountingEntryTypePsMethodFee: {
+			debit:  pkg.LedgerAccountMerchantPayable,
+			credit: pkg.LedgerAccountPlatformRevenue,
+		},
+		pkg.AccountingEntryTypeMerchantMethodFee: {
+			debit:  pkg.LedgerAccountMerchantPayable,
+			credit: pkg.LedgerAccountPlatformRevenue,
+		},
+		pkg.AccountingEntryTypeMerchantMethodFeeCostValue: {
+			debit:  pkg.LedgerAccountPlatformRevenue,
+			credit: pkg.LedgerAccountGatewayReceivable,
+		},
+		pkg.AccountingEntryTypeMerchantMethodFixedFee: {
+			debit:  pkg.LedgerAccountMerchantPayable,
+			credit: pkg.LedgerAccountPlatformRevenue,
+		},
+		pkg.AccountingEntryTypeRealMerchantMethodFixedFeeCostValue: {
+			debit:  pkg.LedgerAccountPlatformRevenue,
+			credit: pkg.LedgerAccountGatewayReceivable,
+		},
+		pkg.AccountingEntryTypeMerchantPsFixedFee: {
+			debit:  pkg.LedgerAccountMerchantPayable,
+			credit: pkg.LedgerAccountPlatformRevenue,
+		},
+
+		// refund
+		pkg.AccountingEntryTypeRealRefund: {
+			credit: pkg.LedgerAccountGatewayReceivable,
+		},
+		pkg.AccountingEntryTypeMerchantRefund: {
+			debit: pkg.LedgerAccountMerchantPayable,
+		},
+		pkg.AccountingEntryTypePsMerchantRefundFx: {
+			credit: pkg.LedgerAccountPlatformRevenue,
+		},
+		pkg.AccountingEntryTypeReverseTaxFee: {
+			debit:  pkg.LedgerAccountTaxPayable,
+			credit: pkg.LedgerAccountMerchantPayable,
+		},
+		pkg.AccountingEntryTypeRealRefundFee: {
+			debit:  pkg.LedgerAccountPlatformRevenue,
+			credit: pkg.LedgerAccountGatewayReceivable,
+		},
+		pkg.AccountingEntryTypeRealRefundFixedFee: {
+			debit:  pkg.LedgerAccountPlatformRevenue,
+			credit: pkg.LedgerAccountGatewayReceivable,
+		},
+		pkg.AccountingEntryTypeRefundStoreCredit: {
+			debit:  pkg.LedgerAccountGatewayReceivable,
+			credit: pkg.LedgerAccountCustomerCredit,
+		},
+		pkg.AccountingEntryTypeRefundBankTransfer: {
+			debit:  pkg.LedgerAccountGatewayReceivable,
+			credit: pkg.LedgerAccountRefundPayable,
+		},
+		pkg.AccountingEntryTypeWalletRefund: {
+			debit:  pkg.LedgerAccountMerchantPayable,
+			credit: pkg.LedgerAccountCustomerCredit,
+		},
+		pkg.AccountingEntryTypeWalletRefundTaxFee: {
+			debit:  pkg.LedgerAccountTaxPayable,
+			credit: pkg.LedgerAccountMerchantPayable,
+		},
+		pkg.AccountingEntryTypeMerchantRefundFee: {
+			debit:  pkg.LedgerAccountMerchantPayable,
+			credit: pkg.LedgerAccountPlatformRevenue,
+		},
+		pkg.AccountingEntryTypeMerchantRefundFixedFee: {
+			debit:  pkg.LedgerAccountMerchantPayable,
+			credit: pkg.LedgerAccountPlatformRevenue,
+		},
+		pkg.AccountingEntryTypeReverseTaxFeeDelta: {
+			debit:  pkg.LedgerAccountPlatformRevenue,
+			credit: pkg.LedgerAccountMerchantPayable,
+		},
+		pkg.AccountingEntryTypePsReverseTaxFeeDelta: {
+			debit:  pkg.LedgerAccountMerchantPayable,
+			credit: pkg.LedgerAccountPlatformRevenue,
+		},
+
+		// manual correction
+		pkg.AccountingEntryTypeMerchantRollingReserveCreate: {
+			debit:  pkg.LedgerAccountMerchantPayable,
+			credit: pkg.LedgerAccountRollingReserve,
+		},
+		pkg.AccountingEntryTypeMerchantRollingReserveRelease: {
+			debit:  pkg.LedgerAccountRollingReserve,
+			credit: pkg.LedgerAccountMerchantPayable,
+		},
+		pkg.AccountingEntryTypeMerchantRoyaltyCorrection: {
+			debit:  pkg.LedgerAccountPlatformRevenue,
+			credit: pkg.LedgerAccountMerchantPayable,
+		},
+		pkg.AccountingEntryTypeWalletGoodwill: {
+			debit:  pkg.LedgerAccountMerchantPayable,
+			credit: pkg.LedgerAccountCustomerCredit,
+		},
+	}
+
+	// ledgerCounterEntries are calculated the same way as in the order view: the merchant gross revenue is the whole
+	// order amount without the exchange profit of the platform, the exchange profit of the refund is the difference of
+	// the merchant refund and the refund by the payment system.
+	ledgerCounterEntries = []*ledgerCounterEntry{
+		{
+			entryType: pkg.AccountingEntryTypeMerchantGrossRevenue,
+			entries: map[string]float64{
+				pkg.AccountingEntryTypeRealGrossRevenue: 1,
+				pkg.AccountingEntryTypeWalletPayment:    1,
+				pkg.AccountingEntryTypeGiftCardPayment:  1,
+				pkg.AccountingEntryTypePsGrossRevenueFx: -1,
+			},
+		},
+		{
+			entryType: pkg.AccountingEntryTypePsMerchantRefundFx,
+			entries: map[string]float64{
+				pkg.AccountingEntryTypeMerchantRefund: 1,
+				pkg.AccountingEntryTypeRealRefund:     -1,
+			},
+		},
+	}
+
+	// ledgerNotPostedEntries are alternative calculations of the posted amounts (by the other rate or for the order
+	// view) that don't change the ledger. Breakage of the gift card isn't posted as the sale of the card already
+	// credited its value to the merchant.
+	ledgerNotPostedEntries = map[string]bool{
+		pkg.AccountingEntryTypeRealTaxFee:                      true,
+		pkg.AccountingEntryTypeCentralBankTaxFee:               true,
+		pkg.AccountingEntryTypeRealTaxFeeTotal:                 true,
+		pkg.AccountingEntryTypePsGrossRevenueFxProfit:          true,
+		pkg.AccountingEntryTypeMerchantTaxFee:                  true,
+		pkg.AccountingEntryTypePsMarkupMerchantMethodFee:       true,
+		pkg.AccountingEntryTypeRealMerchantMethodFixedFee:      true,
+		pkg.AccountingEntryTypeMarkupMerchantMethodFixedFeeFx:  true,
+		pkg.AccountingEntryTypePsMethodFixedFeeProfit:          true,
+		pkg.AccountingEntryTypeRealMerchantPsFixedFee:          true,
+		pkg.AccountingEntryTypeMarkupMerchantPsFixedFee:        true,
+		pkg.AccountingEntryTypePsMethodProfit:                  true,
+		pkg.AccountingEntryTypeMerchantNetRevenue:              true,
+		pkg.AccountingEntryTypePsProfitTotal:                   true,
+		pkg.AccountingEntryTypeRealRefundTaxFee:                true,
+		pkg.AccountingEntryTypeWalletPaymentTaxFee:             true,
+		pkg.AccountingEntryTypeGiftCardBreakage:                true,
+		pkg.AccountingEntryTypePsMarkupMerchantRefundFee:       true,
+		pkg.AccountingEntryTypeMerchantRefundFixedFeeCostValue: true,
+		pkg.AccountingEntryTypePsMerchantRefundFixedFeeFx:      true,
+		pkg.AccountingEntryTypePsMerchantRefundFixedFeeProfit:  true,
+		pkg.AccountingEntryTypeMerchantReverseTaxFee:           true,
+		pkg.AccountingEntryTypeMerchantReverseRevenue:          true,
+		pkg.AccountingEntryTypePsRefundProfit:                  true,
+	}
+)
+
+// GetTrialBalance returns sums of debits and credits of the ledger accounts of the operating company in the currency.
+func (s *Service) GetTrialBalance(
+	ctx context.Context,
+	req *pkg.GetTrialBalanceRequest,
+	rsp *pkg.TrialBalanceResponse,
+) error {
+	_, err := s.operatingCompanyRepository.GetById(ctx, req.OperatingCompanyId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = errorOperatingCompanyNotFound
+		return nil
+	}
+
+	to := time.Time{}
+
+	if req.Date > 0 {
+		to = time.Unix(req.Date, 0)
+	}
+
+	items, err := s.journalEntryRepository.GetTrialBalance(ctx, req.OperatingCompanyId, req.Currency, to)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = accountingEntryErrorUnknown
+		return nil
+	}
+
+	balance := &pkg.TrialBalance{
+		OperatingCompanyId: req.OperatingCompanyId,
+		Currency:           req.Currency,
+		Accounts:           []*pkg.TrialBalanceAccount{},
+	}
+
+	for _, item := range items {
+		account := &pkg.TrialBalanceAccount{
+			Account: item.Account,
+			Debit:   tools.FormatAmount(item.Debit),
+			Credit:  tools.FormatAmount(item.Credit),
+		}
+		account.Balance = tools.FormatAmount(account.Debit - account.Credit)
+
+		balance.Accounts = append(balance.Accounts, account)
+		balance.TotalDebit += account.Debit
+		balance.TotalCredit += account.Credit
+	}
+
+	balance.TotalDebit = tools.FormatAmount(balance.TotalDebit)
+	balance.TotalCredit = tools.FormatAmount(balance.TotalCredit)
+	balance.IsBalanced = balance.TotalDebit == balance.TotalCredit
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = balance
+
+	return nil
+}
+
+// createJournalEntries posts the accounting entries of the business event to the ledger as one compound journal
+// entry. Lines of the journal entry can be in different currencies, the journal entry must balance in each of them.
+// Accounting entries aren't saved if the journal entry doesn't balance or if the type of the entry isn't mapped to
+// the ledger accounts.
+func (h *accountingEntry) createJournalEntries(eventType string) error {
+	var journal *intPkg.JournalEntry
+
+	counterEntries := make([]*billingpb.AccountingEntry, len(ledgerCounterEntries))
+
+	for _, entry := range h.accountingEntries {
+		if ledgerNotPostedEntries[entry.Type] {
+			continue
+		}
+
+		posting, ok := ledgerPostings[entry.Type]
+
+		if !ok {
+			zap.L().Error(
+				accountingEntryErrorJournalUnmappedEntry.Message,
+				zap.String("entry.type", entry.Type),
+				zap.String("source.type", entry.Source.Type),
+				zap.String("source.id", entry.Source.Id),
+			)
+			return accountingEntryErrorJournalUnmappedEntry
+		}
+
+		amount := tools.FormatAmount(entry.Amount)
+
+		for i, counter := range ledgerCounterEntries {
+			sign, ok := counter.entries[entry.Type]
+
+			if !ok || eventType == accountingEventTypeManualCorrection {
+				continue
+			}
+
+			if counterEntries[i] == nil {
+				counterEntries[i] = &billingpb.AccountingEntry{
+					Type:               counter.entryType,
+					Source:             entry.Source,
+					MerchantId:         entry.MerchantId,
+					Currency:           entry.Currency,
+					CreatedAt:          entry.CreatedAt,
+					OperatingCompanyId: entry.OperatingCompanyId,
+				}
+			}
+
+			counterEntries[i].Amount += sign * amount
+		}
+
+		// manual correction has no counter-entries in the event, so it's settled with the merchant
+		if eventType == accountingEventTypeManualCorrection && (posting.debit == "" || posting.credit == "") {
+			posting = getLedgerCorrectionPosting(posting)
+		}
+
+		journal = addJournalLines(journal, eventType, entry, entry.Id, posting, amount)
+	}
+
+	for _, entry := range counterEntries {
+		if entry == nil {
+			continue
+		}
+
+		journal = addJournalLines(journal, eventType, entry, "", ledgerPostings[entry.Type], tools.FormatAmount(entry.Amount))
+	}
+
+	h.journalEntries = nil
+
+	if journal == nil {
+		return nil
+	}
+
+	if err := validateJournalEntry(journal); err != nil {
+		zap.L().Error(
+			"journal entry validation failed",
+			zap.Error(err),
+			zap.String("source.type", journal.SourceType),
+			zap.String("source.id", journal.SourceId),
+			zap.Any("journal", journal),
+		)
+		return err
+	}
+
+	h.journalEntries = []*intPkg.JournalEntry{journal}
+
+	return nil
+}
+
+// addJournalLines adds lines of the accounting entry to the journal entry and creates the journal entry by the first
+// posted accounting entry of the event. Counter-entries calculated in the order view aren't saved, so their lines
+// have no identifier of the accounting entry.
+func addJournalLines(
+	journal *intPkg.JournalEntry,
+	eventType string,
+	entry *billingpb.AccountingEntry,
+	entryId string,
+	posting *ledgerPosting,
+	amount float64,
+) *intPkg.JournalEntry {
+	if amount == 0 {
+		return journal
+	}
+
+	debit, credit := posting.debit, posting.credit
+
+	// negative amount reverses the posting
+	if amount < 0 {
+		debit, credit = credit, debit
+		amount = -1 * amount
+	}
+
+	if journal == nil {
+		journal = &intPkg.JournalEntry{
+			OperatingCompanyId: entry.OperatingCompanyId,
+			MerchantId:         entry.MerchantId,
+			SourceId:           entry.Source.Id,
+			SourceType:         entry.Source.Type,
+			EventType:          eventType,
+			CreatedAt:          time.Now(),
+		}
+
+		if entry.CreatedAt != nil {
+			if createdAt, err := ptypes.Timestamp(entry.CreatedAt); err == nil {
+				journal.CreatedAt = createdAt
+			}
+		}
+	}
+
+	if debit != "" {
+		journal.Lines = append(journal.Lines, &intPkg.JournalLine{
+			Account:             debit,
+			Debit:               amount,
+			Currency:            entry.Currency,
+			AccountingEntryId:   entryId,
+			AccountingEntryType: entry.Type,
+		})
+	}
+
+	if credit != "" {
+		journal.Lines = append(journal.Lines, &intPkg.JournalLine{
+			Account:             credit,
+			Credit:              amount,
+			Currency:            entry.Currency,
+			AccountingEntryId:   entryId,
+			AccountingEntryType: entry.Type,
+		})
+	}
+
+	return journal
+}
+
+// getLedgerCorrectionPosting completes the posting of the money received or paid by the event with the merchant
+// payable account as the counter account, or the platform revenue account if the entry posts to the merchant payable.
+func getLedgerCorrectionPosting(posting *ledgerPosting) *ledgerPosting {
+	account := posting.debit + posting.credit
+	counter := pkg.LedgerAccountMerchantPayable
+
+	if account == pkg.LedgerAccountMerchantPayable {
+		counter = pkg.LedgerAccountPlatformRevenue
+	}
+
+	if posting.debit == "" {
+		return &ledgerPosting{debit: counter, credit: account}
+	}
+
+	return &ledgerPosting{debit: account, credit: counter}
+}
+
+// validateJournalEntry checks that every line of the journal entry debits or credits the known ledger account
+// and sum of debits is equal to sum of credits in each currency of the journal entry.
+func validateJournalEntry(journal *intPkg.JournalEntry) error {
+	if len(journal.Lines) < 2 {
+		return accountingEntryErrorJournalUnbalanced
+	}
+
+	balances := make(map[string]float64)
+
+	for _, line := range journal.Lines {
+		if _, ok := availableLedgerAccounts[line.Account]; !ok {
+			return accountingEntryErrorJournalUnknownAccount
+		}
+
+		if line.Debit < 0 || line.Credit < 0 || (line.Debit > 0) == (line.Credit > 0) {
+			return accountingEntryErrorJournalUnbalanced
+		}
+
+		balances[line.Currency] += line.Debit - line.Credit
+	}
+
+	for _, balance := range balances {
+		if tools.FormatAmount(balance) != 0 {
+			return accountingEntryErrorJournalUnbalanced
+		}
+	}
+
+	return nil
+}
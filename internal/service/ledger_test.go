@@ -0,0 +1,432 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type LedgerTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+	paymentSystem *billingpb.PaymentSystem
+	cookie        string
+}
+
+func Test_Ledger(t *testing.T) {
+	suite.Run(t, new(LedgerTestSuite))
+}
+
+func (suite *LedgerTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	var customer *billingpb.Customer
+	suite.merchant, suite.project, suite.paymentMethod, suite.paymentSystem, customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	browserCustomer := &BrowserCookieCustomer{
+		CustomerId: customer.Id,
+		Ip:         "127.0.0.1",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	suite.cookie, err = suite.service.generateBrowserCookie(browserCustomer)
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), suite.cookie)
+}
+
+func (suite *LedgerTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *LedgerTestSuite) createAccountingEntry(entryType string, amount float64) {
+	req := &billingpb.CreateAccountingEntryRequest{
+		Type:       entryType,
+		MerchantId: suite.merchant.Id,
+		Amount:     amount,
+		Currency:   suite.merchant.GetPayoutCurrency(),
+		Status:     pkg.BalanceTransactionStatusAvailable,
+		Date:       time.Now().Add(-1 * time.Hour).Unix(),
+		Reason:     "unit test",
+	}
+	rsp := &billingpb.CreateAccountingEntryResponse{}
+	err := suite.service.CreateAccountingEntry(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+}
+
+func (suite *LedgerTestSuite) getTrialBalance() *pkg.TrialBalance {
+	req := &pkg.GetTrialBalanceRequest{
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+		Currency:           suite.merchant.GetPayoutCurrency(),
+	}
+	rsp := &pkg.TrialBalanceResponse{}
+	err := suite.service.GetTrialBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	return rsp.Item
+}
+
+func (suite *LedgerTestSuite) TestLedger_GetTrialBalance_Ok() {
+	suite.createAccountingEntry(pkg.AccountingEntryTypeMerchantRollingReserveCreate, 150)
+	suite.createAccountingEntry(pkg.AccountingEntryTypeMerchantRollingReserveRelease, 50)
+
+	balance := suite.getTrialBalance()
+	assert.True(suite.T(), balance.IsBalanced)
+	assert.EqualValues(suite.T(), 200, balance.TotalDebit)
+	assert.EqualValues(suite.T(), 200, balance.TotalCredit)
+	assert.Len(suite.T(), balance.Accounts, 2)
+
+	assert.Equal(suite.T(), pkg.LedgerAccountMerchantPayable, balance.Accounts[0].Account)
+	assert.EqualValues(suite.T(), 150, balance.Accounts[0].Debit)
+	assert.EqualValues(suite.T(), 50, balance.Accounts[0].Credit)
+	assert.EqualValues(suite.T(), 100, balance.Accounts[0].Balance)
+
+	assert.Equal(suite.T(), pkg.LedgerAccountRollingReserve, balance.Accounts[1].Account)
+	assert.EqualValues(suite.T(), -100, balance.Accounts[1].Balance)
+}
+
+func (suite *LedgerTestSuite) TestLedger_GetTrialBalance_NegativeAmountReversed_Ok() {
+	suite.createAccountingEntry(pkg.AccountingEntryTypeMerchantRoyaltyCorrection, -30)
+
+	balance := suite.getTrialBalance()
+	assert.True(suite.T(), balance.IsBalanced)
+	assert.Len(suite.T(), balance.Accounts, 2)
+	assert.Equal(suite.T(), pkg.LedgerAccountMerchantPayable, balance.Accounts[0].Account)
+	assert.EqualValues(suite.T(), 30, balance.Accounts[0].Debit)
+	assert.Equal(suite.T(), pkg.LedgerAccountPlatformRevenue, balance.Accounts[1].Account)
+	assert.EqualValues(suite.T(), 30, balance.Accounts[1].Credit)
+}
+
+func (suite *LedgerTestSuite) TestLedger_GetTrialBalance_DateBeforeEntries_Ok() {
+	suite.createAccountingEntry(pkg.AccountingEntryTypeMerchantRollingReserveCreate, 150)
+
+	req := &pkg.GetTrialBalanceRequest{
+		OperatingCompanyId: suite.merchant.OperatingCompanyId,
+		Currency:           suite.merchant.GetPayoutCurrency(),
+		Date:               time.Now().Add(-2 * time.Hour).Unix(),
+	}
+	rsp := &pkg.TrialBalanceResponse{}
+	err := suite.service.GetTrialBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Empty(suite.T(), rsp.Item.Accounts)
+	assert.True(suite.T(), rsp.Item.IsBalanced)
+}
+
+func (suite *LedgerTestSuite) TestLedger_GetTrialBalance_OperatingCompanyNotFound_Error() {
+	req := &pkg.GetTrialBalanceRequest{
+		OperatingCompanyId: "ffffffffffffffffffffffff",
+		Currency:           "RUB",
+	}
+	rsp := &pkg.TrialBalanceResponse{}
+	err := suite.service.GetTrialBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), errorOperatingCompanyNotFound, rsp.Message)
+}
+
+func (suite *LedgerTestSuite) TestLedger_CreateAccountingEntry_JournalInsertFailed_Error() {
+	journalEntryRepository := &mocks.JournalEntryRepositoryInterface{}
+	journalEntryRepository.On("MultipleInsert", mock.Anything, mock.Anything).Return(errors.New("some error"))
+	suite.service.journalEntryRepository = journalEntryRepository
+
+	req := &billingpb.CreateAccountingEntryRequest{
+		Type:       pkg.AccountingEntryTypeMerchantRollingReserveCreate,
+		MerchantId: suite.merchant.Id,
+		Amount:     150,
+		Currency:   suite.merchant.GetPayoutCurrency(),
+		Status:     pkg.BalanceTransactionStatusAvailable,
+		Date:       time.Now().Unix(),
+		Reason:     "unit test",
+	}
+	rsp := &billingpb.CreateAccountingEntryResponse{}
+	err := suite.service.CreateAccountingEntry(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+}
+
+func (suite *LedgerTestSuite) TestLedger_ValidateJournalEntry_Unbalanced_Error() {
+	journal := &intPkg.JournalEntry{
+		Lines: []*intPkg.JournalLine{
+			{Account: pkg.LedgerAccountGatewayReceivable, Debit: 100},
+			{Account: pkg.LedgerAccountMerchantPayable, Credit: 99.99},
+		},
+	}
+	assert.Equal(suite.T(), accountingEntryErrorJournalUnbalanced, validateJournalEntry(journal))
+
+	journal.Lines[1].Credit = 100
+	assert.NoError(suite.T(), validateJournalEntry(journal))
+
+	journal.Lines[1].Debit = 100
+	assert.Equal(suite.T(), accountingEntryErrorJournalUnbalanced, validateJournalEntry(journal))
+
+	journal.Lines[1].Debit = 0
+	journal.Lines[1].Account = "unknown"
+	assert.Equal(suite.T(), accountingEntryErrorJournalUnknownAccount, validateJournalEntry(journal))
+
+	journal.Lines = journal.Lines[:1]
+	assert.Equal(suite.T(), accountingEntryErrorJournalUnbalanced, validateJournalEntry(journal))
+}
+
+func (suite *LedgerTestSuite) TestLedger_ValidateJournalEntry_MissingCounterEntry_Error() {
+	journal := &intPkg.JournalEntry{
+		Lines: []*intPkg.JournalLine{
+			{Account: pkg.LedgerAccountGatewayReceivable, Debit: 100, Currency: "USD"},
+			{Account: pkg.LedgerAccountMerchantPayable, Credit: 100, Currency: "USD"},
+			{Account: pkg.LedgerAccountMerchantPayable, Debit: 20, Currency: "USD"},
+		},
+	}
+	assert.Equal(suite.T(), accountingEntryErrorJournalUnbalanced, validateJournalEntry(journal))
+
+	// counter-entry in the other currency doesn't balance the journal entry
+	journal.Lines = append(journal.Lines, &intPkg.JournalLine{Account: pkg.LedgerAccountTaxPayable, Credit: 20, Currency: "EUR"})
+	assert.Equal(suite.T(), accountingEntryErrorJournalUnbalanced, validateJournalEntry(journal))
+
+	journal.Lines[3].Currency = "USD"
+	assert.NoError(suite.T(), validateJournalEntry(journal))
+}
+
+func (suite *LedgerTestSuite) TestLedger_CreateJournalEntries_CompoundJournal_Ok() {
+	source := &billingpb.AccountingEntrySource{Id: suite.merchant.Id, Type: "merchant"}
+	h := &accountingEntry{
+		Service: suite.service,
+		ctx:     context.TODO(),
+		accountingEntries: []*billingpb.AccountingEntry{
+			{Type: pkg.AccountingEntryTypeRealGrossRevenue, Amount: 100, Currency: "USD", Source: source},
+			{Type: pkg.AccountingEntryTypeMerchantTaxFeeCostValue, Amount: 20, Currency: "USD", Source: source},
+			{Type: pkg.AccountingEntryTypeMerchantRollingReserveCreate, Amount: 10, Currency: "EUR", Source: source},
+			{Type: pkg.AccountingEntryTypeGiftCardBreakage, Amount: 10, Currency: "EUR", Source: source},
+		},
+	}
+
+	err := h.createJournalEntries(accountingEventTypePayment)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), h.journalEntries, 1)
+
+	journal := h.journalEntries[0]
+	assert.Equal(suite.T(), suite.merchant.Id, journal.SourceId)
+	assert.Len(suite.T(), journal.Lines, 6)
+	assert.Equal(suite.T(), "USD", journal.Lines[0].Currency)
+	assert.Equal(suite.T(), pkg.LedgerAccountGatewayReceivable, journal.Lines[0].Account)
+	assert.EqualValues(suite.T(), 100, journal.Lines[0].Debit)
+	assert.Equal(suite.T(), "EUR", journal.Lines[4].Currency)
+	assert.Equal(suite.T(), pkg.LedgerAccountRollingReserve, journal.Lines[4].Account)
+
+	// gross revenue received by the gateway is balanced by the merchant gross revenue calculated in the order view
+	assert.Equal(suite.T(), pkg.AccountingEntryTypeMerchantGrossRevenue, journal.Lines[5].AccountingEntryType)
+	assert.Equal(suite.T(), pkg.LedgerAccountMerchantPayable, journal.Lines[5].Account)
+	assert.EqualValues(suite.T(), 100, journal.Lines[5].Credit)
+	assert.Empty(suite.T(), journal.Lines[5].AccountingEntryId)
+}
+
+func (suite *LedgerTestSuite) TestLedger_CreateJournalEntries_MissingCounterEntry_Error() {
+	source := &billingpb.AccountingEntrySource{Id: suite.merchant.Id, Type: "merchant"}
+	h := &accountingEntry{
+		Service: suite.service,
+		ctx:     context.TODO(),
+		accountingEntries: []*billingpb.AccountingEntry{
+			{Type: pkg.AccountingEntryTypeRealRefund, Amount: 100, Currency: "USD", Source: source},
+		},
+	}
+
+	err := h.createJournalEntries(accountingEventTypeRefund)
+	assert.Equal(suite.T(), accountingEntryErrorJournalUnbalanced, err)
+	assert.Empty(suite.T(), h.journalEntries)
+
+	h.accountingEntries = append(
+		h.accountingEntries,
+		&billingpb.AccountingEntry{Type: pkg.AccountingEntryTypeMerchantRefund, Amount: 102.5, Currency: "USD", Source: source},
+	)
+	err = h.createJournalEntries(accountingEventTypeRefund)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), h.journalEntries, 1)
+
+	// exchange profit of the refund is the difference of the merchant refund and the refund by the payment system
+	lines := h.journalEntries[0].Lines
+	assert.Len(suite.T(), lines, 3)
+	assert.Equal(suite.T(), pkg.AccountingEntryTypePsMerchantRefundFx, lines[2].AccountingEntryType)
+	assert.Equal(suite.T(), pkg.LedgerAccountPlatformRevenue, lines[2].Account)
+	assert.EqualValues(suite.T(), 2.5, lines[2].Credit)
+}
+
+func (suite *LedgerTestSuite) TestLedger_CreateJournalEntries_UnmappedEntry_Error() {
+	source := &billingpb.AccountingEntrySource{Id: suite.merchant.Id, Type: "merchant"}
+	h := &accountingEntry{
+		Service: suite.service,
+		ctx:     context.TODO(),
+		accountingEntries: []*billingpb.AccountingEntry{
+			{Type: pkg.AccountingEntryTypeMerchantRoyaltyCorrection, Amount: 100, Currency: "USD", Source: source},
+			{Type: "unmapped_entry", Amount: 100, Currency: "USD", Source: source},
+		},
+	}
+
+	err := h.createJournalEntries(accountingEventTypeManualCorrection)
+	assert.Equal(suite.T(), accountingEntryErrorJournalUnmappedEntry, err)
+	assert.Empty(suite.T(), h.journalEntries)
+}
+
+func (suite *LedgerTestSuite) TestLedger_AllAccountingEntriesMapped() {
+	for entryType := range availableAccountingEntries {
+		_, ok := ledgerPostings[entryType]
+		assert.True(suite.T(), ok != ledgerNotPostedEntries[entryType], entryType)
+	}
+}
+
+func (suite *LedgerTestSuite) TestLedger_CreateJournalEntries_ManualCorrectionOfPaymentEntry_Ok() {
+	suite.createAccountingEntry(pkg.AccountingEntryTypeRealGrossRevenue, 10)
+
+	balance := suite.getTrialBalance()
+	assert.True(suite.T(), balance.IsBalanced)
+	assert.Len(suite.T(), balance.Accounts, 2)
+	assert.Equal(suite.T(), pkg.LedgerAccountGatewayReceivable, balance.Accounts[0].Account)
+	assert.EqualValues(suite.T(), 10, balance.Accounts[0].Debit)
+	assert.Equal(suite.T(), pkg.LedgerAccountMerchantPayable, balance.Accounts[1].Account)
+	assert.EqualValues(suite.T(), 10, balance.Accounts[1].Credit)
+}
+
+func (suite *LedgerTestSuite) TestLedger_PaymentAndRefundEvents_Balanced() {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.project, suite.paymentMethod, suite.cookie)
+	assert.NotNil(suite.T(), order)
+
+	suite.paymentSystem.Handler = "mock_ok"
+	err := suite.service.paymentSystemRepository.Update(context.TODO(), suite.paymentSystem)
+	assert.NoError(suite.T(), err)
+
+	refund := HelperMakeRefund(suite.Suite, suite.service, order, order.ChargeAmount, false)
+	assert.NotNil(suite.T(), refund)
+
+	journals, err := suite.service.journalEntryRepository.FindByPeriod(
+		context.TODO(),
+		order.OperatingCompanyId,
+		order.GetMerchantId(),
+		time.Time{},
+		time.Now().Add(time.Hour),
+	)
+	assert.NoError(suite.T(), err)
+
+	events := make(map[string]*intPkg.JournalEntry)
+
+	for _, journal := range journals {
+		assert.NoError(suite.T(), validateJournalEntry(journal))
+		events[journal.EventType] = journal
+	}
+
+	assert.Len(suite.T(), events, 2)
+	assert.Contains(suite.T(), events, accountingEventTypePayment)
+	assert.Contains(suite.T(), events, accountingEventTypeRefund)
+
+	payment := events[accountingEventTypePayment]
+	assert.Equal(suite.T(), order.Id, payment.SourceId)
+	assert.Equal(suite.T(), pkg.AccountingEntryTypeRealGrossRevenue, payment.Lines[0].AccountingEntryType)
+	assert.Equal(suite.T(), pkg.LedgerAccountGatewayReceivable, payment.Lines[0].Account)
+	assert.Equal(suite.T(), pkg.AccountingEntryTypeMerchantGrossRevenue, payment.Lines[len(payment.Lines)-1].AccountingEntryType)
+
+	refundJournal := events[accountingEventTypeRefund]
+	assert.Equal(suite.T(), refund.CreatedOrderId, refundJournal.SourceId)
+
+	for _, line := range refundJournal.Lines {
+		if line.AccountingEntryType == pkg.AccountingEntryTypeRealRefund {
+			assert.Equal(suite.T(), pkg.LedgerAccountGatewayReceivable, line.Account)
+			assert.NotZero(suite.T(), line.Credit)
+		}
+	}
+
+	req := &pkg.GetTrialBalanceRequest{
+		OperatingCompanyId: order.OperatingCompanyId,
+		Currency:           order.GetMerchantRoyaltyCurrency(),
+	}
+	rsp := &pkg.TrialBalanceResponse{}
+	err = suite.service.GetTrialBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.True(suite.T(), rsp.Item.IsBalanced)
+	assert.NotZero(suite.T(), rsp.Item.TotalDebit)
+}
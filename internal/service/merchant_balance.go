@@ -2,18 +2,28 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"github.com/go-redis/redis"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/paysuper/paysuper-billing-server/internal/helper"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/errors"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
+	"sort"
 	"time"
 )
 
+const (
+	merchantBalanceLockKey = "merchant_balance:lock:%s:%s"
+	merchantBalanceLockTtl = time.Minute
+)
+
 var (
 	errorMerchantPayoutCurrencyNotSet = errors.NewBillingServerErrorMsg("ba000001", "merchant payout currency not set")
+	errorMerchantBalanceLocked        = errors.NewBillingServerErrorMsg("ba000008", "merchant balance is changed by another operation. try request later")
 
 	accountingEntriesForRollingReserve = []string{
 		pkg.AccountingEntryTypeMerchantRollingReserveCreate,
@@ -29,13 +39,13 @@ func (s *Service) GetMerchantBalance(
 	var err error
 	res.Status = billingpb.ResponseStatusOk
 
-	res.Item, err = s.getMerchantBalance(ctx, req.MerchantId)
+	res.Item, err = s.getMerchantBalance(ctx, req.MerchantId, "")
 	if err == nil {
 		return nil
 	}
 
 	if err == mongo.ErrNoDocuments {
-		res.Item, err = s.updateMerchantBalance(ctx, req.MerchantId)
+		res.Item, err = s.updateMerchantBalance(ctx, req.MerchantId, "")
 		if err != nil {
 			if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
 				res.Status = billingpb.ResponseStatusSystemError
@@ -56,42 +66,103 @@ func (s *Service) GetMerchantBalance(
 	return nil
 }
 
-func (s *Service) getMerchantBalance(ctx context.Context, merchantId string) (*billingpb.MerchantBalance, error) {
+// ListMerchantBalances returns the last balances of the merchant in all currencies, the balance in the payout
+// currency of the merchant goes first. Missing balances are calculated on the fly.
+func (s *Service) ListMerchantBalances(
+	ctx context.Context,
+	req *pkg.ListMerchantBalancesRequest,
+	res *pkg.ListMerchantBalancesResponse,
+) error {
+	currencies, err := s.getMerchantBalanceCurrencies(ctx, req.MerchantId)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			res.Status = billingpb.ResponseStatusSystemError
+			res.Message = e
+			return nil
+		}
+		return err
+	}
+
+	res.Items = make([]*billingpb.MerchantBalance, 0, len(currencies))
+
+	for _, currency := range currencies {
+		balance, err := s.getMerchantBalance(ctx, req.MerchantId, currency)
+
+		if err == mongo.ErrNoDocuments {
+			balance, err = s.updateMerchantBalance(ctx, req.MerchantId, currency)
+		}
+
+		if err != nil {
+			if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+				res.Status = billingpb.ResponseStatusSystemError
+				res.Message = e
+				return nil
+			}
+			return err
+		}
+
+		res.Items = append(res.Items, balance)
+	}
+
+	res.Status = billingpb.ResponseStatusOk
+
+	return nil
+}
+
+// getMerchantBalance returns the last balance of the merchant in the currency.
+// The payout currency of the merchant is used if the currency is empty.
+func (s *Service) getMerchantBalance(ctx context.Context, merchantId, currency string) (*billingpb.MerchantBalance, error) {
 	merchant, err := s.merchantRepository.GetById(ctx, merchantId)
 	if err != nil {
 		return nil, merchantErrorNotFound
 	}
 
-	if merchant.GetPayoutCurrency() == "" {
+	if currency == "" {
+		currency = merchant.GetPayoutCurrency()
+	}
+
+	if currency == "" {
 		zap.L().Error(errorMerchantPayoutCurrencyNotSet.Error(), zap.String("merchant_id", merchantId))
 		return nil, errorMerchantPayoutCurrencyNotSet
 	}
 
-	return s.merchantBalanceRepository.GetByIdAndCurrency(ctx, merchant.Id, merchant.GetPayoutCurrency())
+	return s.merchantBalanceRepository.GetByIdAndCurrency(ctx, merchant.Id, currency)
 }
 
-func (s *Service) updateMerchantBalance(ctx context.Context, merchantId string) (*billingpb.MerchantBalance, error) {
+// updateMerchantBalance calculates and saves the balance of the merchant in the currency.
+// The payout currency of the merchant is used if the currency is empty.
+func (s *Service) updateMerchantBalance(ctx context.Context, merchantId, currency string) (*billingpb.MerchantBalance, error) {
 	merchant, err := s.merchantRepository.GetById(ctx, merchantId)
 	if err != nil {
 		return nil, merchantErrorNotFound
 	}
 
-	if merchant.GetPayoutCurrency() == "" {
+	if currency == "" {
+		currency = merchant.GetPayoutCurrency()
+	}
+
+	if currency == "" {
 		zap.L().Error(errorMerchantPayoutCurrencyNotSet.Error(), zap.String("merchant_id", merchantId))
 		return nil, errorMerchantPayoutCurrencyNotSet
 	}
 
-	debit, err := s.royaltyReportRepository.GetBalanceAmount(ctx, merchant.Id, merchant.GetPayoutCurrency())
+	debit, err := s.royaltyReportRepository.GetBalanceAmount(ctx, merchant.Id, currency)
 	if err != nil {
 		return nil, err
 	}
 
-	credit, err := s.payoutRepository.GetBalanceAmount(ctx, merchant.Id, merchant.GetPayoutCurrency())
+	credit, err := s.payoutRepository.GetBalanceAmount(ctx, merchant.Id, currency)
 	if err != nil {
 		return nil, err
 	}
 
-	rr, err := s.getRollingReserveForBalance(ctx, merchantId, merchant.GetPayoutCurrency())
+	conversionIn, conversionOut, err := s.getMerchantBalanceConversionAmounts(ctx, merchant.Id, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	rr, err := s.getRollingReserveForBalance(ctx, merchantId, currency)
 	if err != nil {
 		return nil, err
 	}
@@ -99,9 +170,9 @@ func (s *Service) updateMerchantBalance(ctx context.Context, merchantId string)
 	balance := &billingpb.MerchantBalance{
 		Id:             primitive.NewObjectID().Hex(),
 		MerchantId:     merchantId,
-		Currency:       merchant.GetPayoutCurrency(),
-		Debit:          debit,
-		Credit:         credit,
+		Currency:       currency,
+		Debit:          debit + conversionIn,
+		Credit:         credit + conversionOut,
 		RollingReserve: rr,
 		CreatedAt:      ptypes.TimestampNow(),
 	}
@@ -117,6 +188,67 @@ func (s *Service) updateMerchantBalance(ctx context.Context, merchantId string)
 	return balance, nil
 }
 
+// updateMerchantBalances recalculates balances of the merchant in all currencies.
+func (s *Service) updateMerchantBalances(ctx context.Context, merchantId string) error {
+	currencies, err := s.getMerchantBalanceCurrencies(ctx, merchantId)
+
+	if err != nil {
+		return err
+	}
+
+	for _, currency := range currencies {
+		if _, err = s.updateMerchantBalance(ctx, merchantId, currency); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getMerchantBalanceCurrencies returns the payout currency of the merchant followed by other currencies
+// in which the merchant has balances in alphabetical order.
+func (s *Service) getMerchantBalanceCurrencies(ctx context.Context, merchantId string) ([]string, error) {
+	merchant, err := s.merchantRepository.GetById(ctx, merchantId)
+	if err != nil {
+		return nil, merchantErrorNotFound
+	}
+
+	if merchant.GetPayoutCurrency() == "" {
+		zap.L().Error(errorMerchantPayoutCurrencyNotSet.Error(), zap.String("merchant_id", merchantId))
+		return nil, errorMerchantPayoutCurrencyNotSet
+	}
+
+	currencies, err := s.merchantBalanceRepository.GetCurrencies(ctx, merchant.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return getMerchantCurrencies(merchant.GetPayoutCurrency(), currencies), nil
+}
+
+// getMerchantBalanceConversionAmounts returns amounts converted to and from the balance of the merchant
+// in the currency.
+func (s *Service) getMerchantBalanceConversionAmounts(ctx context.Context, merchantId, currency string) (float64, float64, error) {
+	conversions, err := s.merchantBalanceConversionRepository.Find(ctx, merchantId, currency, 0, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	in, out := float64(0), float64(0)
+
+	for _, conversion := range conversions {
+		if conversion.ToCurrency == currency {
+			in += conversion.ToAmount
+		}
+
+		if conversion.FromCurrency == currency {
+			out += conversion.FromAmount
+		}
+	}
+
+	return in, out, nil
+}
+
 func (s *Service) getRollingReserveForBalance(ctx context.Context, merchantId, currency string) (float64, error) {
 	pd, err := s.payoutRepository.GetLast(ctx, merchantId, currency)
 	if err != nil && err != mongo.ErrNoDocuments {
@@ -156,3 +288,65 @@ func (s *Service) getRollingReserveForBalance(ctx context.Context, merchantId, c
 
 	return result, nil
 }
+
+// getMerchantCurrencies returns the payout currency of the merchant followed by other unique currencies
+// in alphabetical order.
+func getMerchantCurrencies(payoutCurrency string, currencies []string) []string {
+	result := []string{payoutCurrency}
+	var other []string
+
+	for _, currency := range currencies {
+		if currency == "" || helper.Contains(result, currency) || helper.Contains(other, currency) {
+			continue
+		}
+
+		other = append(other, currency)
+	}
+
+	sort.Strings(other)
+
+	return append(result, other...)
+}
+
+// lockMerchantBalance takes the lock of the merchant balance in the currency. Balance conversions and payouts
+// debit the balance only while they hold the lock, so they can't spend the same funds twice. The lock expires
+// on its own if its holder stops before releasing it. The returned token must be passed to unlockMerchantBalance.
+func (s *Service) lockMerchantBalance(merchantId, currency string) (string, error) {
+	token := primitive.NewObjectID().Hex()
+	isLocked, err := s.redis.SetNX(fmt.Sprintf(merchantBalanceLockKey, merchantId, currency), token, merchantBalanceLockTtl).Result()
+
+	if err != nil {
+		zap.L().Error(
+			"Lock merchant balance failed",
+			zap.Error(err),
+			zap.String("merchant_id", merchantId),
+			zap.String("currency", currency),
+		)
+		return "", err
+	}
+
+	if !isLocked {
+		return "", errorMerchantBalanceLocked
+	}
+
+	return token, nil
+}
+
+// unlockMerchantBalance releases the lock of the merchant balance if it's still held with the token.
+func (s *Service) unlockMerchantBalance(merchantId, currency, token string) {
+	key := fmt.Sprintf(merchantBalanceLockKey, merchantId, currency)
+	current, err := s.redis.Get(key).Result()
+
+	if err == nil && current == token {
+		err = s.redis.Del(key).Err()
+	}
+
+	if err != nil && err != redis.Nil {
+		zap.L().Error(
+			"Unlock merchant balance failed",
+			zap.Error(err),
+			zap.String("merchant_id", merchantId),
+			zap.String("currency", currency),
+		)
+	}
+}
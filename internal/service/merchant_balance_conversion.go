@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/helper"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/currenciespb"
+	tools "github.com/paysuper/paysuper-tools/number"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"time"
+)
+
+const (
+	merchantBalanceConversionDefaultLimit   = int64(100)
+	merchantBalanceConversionInsertAttempts = 5
+)
+
+var (
+	merchantBalanceConversionErrorUnknown              = errors.NewBillingServerErrorMsg("ba000002", "unknown error. try request later")
+	merchantBalanceConversionErrorCurrenciesEqual      = errors.NewBillingServerErrorMsg("ba000003", "currencies of the balances to convert must be different")
+	merchantBalanceConversionErrorAmountInvalid        = errors.NewBillingServerErrorMsg("ba000004", "amount to convert must be greater than zero")
+	merchantBalanceConversionErrorNotEnoughBalance     = errors.NewBillingServerErrorMsg("ba000005", "merchant balance isn't enough to convert the amount")
+	merchantBalanceConversionErrorExchangeFailed       = errors.NewBillingServerErrorMsg("ba000006", "unable to exchange the amount between currencies of the balances")
+	merchantBalanceConversionErrorCurrencyNotSupported = errors.NewBillingServerErrorMsg("ba000007", "currency of the balance isn't supported")
+)
+
+// ConvertMerchantBalance moves the amount from the balance of the merchant in one currency to the balance in other
+// currency by the paysuper rate of the currencies service. The conversion is recorded with the rate used and each
+// side of it is included to the next payout document in its currency.
+// The conversion holds the lock of the balance in the source currency, the same lock is held by the payout
+// in this currency, so the funds checked by one of them can't be spent by the other in meantime.
+// The conversion gets the next sequence number of conversions from the currency before the balance is checked.
+// Concurrent conversion checked against the same balance gets the same number and is rejected by the unique index,
+// so it's checked again against the balance which includes the first conversion.
+func (s *Service) ConvertMerchantBalance(
+	ctx context.Context,
+	req *pkg.ConvertMerchantBalanceRequest,
+	rsp *pkg.ConvertMerchantBalanceResponse,
+) error {
+	merchant, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = merchantErrorNotFound
+		return nil
+	}
+
+	if req.FromCurrency == req.ToCurrency {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = merchantBalanceConversionErrorCurrenciesEqual
+		return nil
+	}
+
+	amount := tools.FormatAmount(req.Amount)
+
+	if amount <= 0 {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = merchantBalanceConversionErrorAmountInvalid
+		return nil
+	}
+
+	if !helper.Contains(s.supportedCurrencies, req.FromCurrency) || !helper.Contains(s.supportedCurrencies, req.ToCurrency) {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = merchantBalanceConversionErrorCurrencyNotSupported
+		return nil
+	}
+
+	lockToken, err := s.lockMerchantBalance(merchant.Id, req.FromCurrency)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = merchantBalanceConversionErrorUnknown
+
+		if err == errorMerchantBalanceLocked {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = errorMerchantBalanceLocked
+		}
+
+		return nil
+	}
+
+	defer s.unlockMerchantBalance(merchant.Id, req.FromCurrency, lockToken)
+
+	merchantOid, _ := primitive.ObjectIDFromHex(merchant.Id)
+	var conversion *intPkg.MerchantBalanceConversion
+
+	for attempt := 1; ; attempt++ {
+		last, err := s.merchantBalanceConversionRepository.GetLastFrom(ctx, merchant.Id, req.FromCurrency)
+
+		if err != nil {
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = merchantBalanceConversionErrorUnknown
+			return nil
+		}
+
+		sequence := int64(1)
+
+		if last != nil {
+			sequence = last.FromSequence + 1
+		}
+
+		balance, err := s.updateMerchantBalance(ctx, merchant.Id, req.FromCurrency)
+
+		if err != nil {
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = merchantBalanceConversionErrorUnknown
+
+			if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+				rsp.Message = e
+			}
+
+			return nil
+		}
+
+		if tools.FormatAmount(balance.Total) < amount {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = merchantBalanceConversionErrorNotEnoughBalance
+			return nil
+		}
+
+		exchangeReq := &currenciespb.ExchangeCurrencyCurrentForMerchantRequest{
+			From:              req.FromCurrency,
+			To:                req.ToCurrency,
+			MerchantId:        merchant.Id,
+			RateType:          currenciespb.RateTypePaysuper,
+			ExchangeDirection: currenciespb.ExchangeDirectionSell,
+			Amount:            amount,
+		}
+		exchangeRsp, err := s.curService.ExchangeCurrencyCurrentForMerchant(ctx, exchangeReq)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorGrpcServiceCallFailed,
+				zap.Error(err),
+				zap.String(errorFieldService, "CurrencyRatesService"),
+				zap.String(errorFieldMethod, "ExchangeCurrencyCurrentForMerchant"),
+				zap.Any(errorFieldRequest, exchangeReq),
+			)
+
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = merchantBalanceConversionErrorExchangeFailed
+			return nil
+		}
+
+		conversion = &intPkg.MerchantBalanceConversion{
+			Id:           primitive.NewObjectID(),
+			MerchantId:   merchantOid,
+			FromCurrency: req.FromCurrency,
+			FromAmount:   amount,
+			FromSequence: sequence,
+			ToCurrency:   req.ToCurrency,
+			ToAmount:     tools.FormatAmount(exchangeRsp.ExchangedAmount),
+			Rate:         exchangeRsp.ExchangeRate,
+			RateType:     currenciespb.RateTypePaysuper,
+			UserId:       req.UserId,
+			CreatedAt:    time.Now().UTC(),
+		}
+
+		err = s.merchantBalanceConversionRepository.Insert(ctx, conversion)
+
+		if err == nil {
+			break
+		}
+
+		if !mongodb.IsDuplicate(err) || attempt >= merchantBalanceConversionInsertAttempts {
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = merchantBalanceConversionErrorUnknown
+			return nil
+		}
+	}
+
+	// The conversion is already saved, so the failed audit log doesn't stop the update of balances
+	auditErr := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "ConvertMerchantBalance",
+		entityType: pkg.AuditLogEntityMerchantBalanceConversion,
+		entityId:   conversion.Id.Hex(),
+		after:      conversion,
+	})
+
+	for _, currency := range []string{conversion.FromCurrency, conversion.ToCurrency} {
+		if _, err = s.updateMerchantBalance(ctx, merchant.Id, currency); err != nil {
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = merchantBalanceConversionErrorUnknown
+			return nil
+		}
+	}
+
+	if auditErr != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = auditLogErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getMerchantBalanceConversionMessage(conversion)
+
+	return nil
+}
+
+// ListMerchantBalanceConversions returns conversions between balances of the merchant from or to the currency,
+// the newest conversions go first.
+func (s *Service) ListMerchantBalanceConversions(
+	ctx context.Context,
+	req *pkg.ListMerchantBalanceConversionsRequest,
+	rsp *pkg.ListMerchantBalanceConversionsResponse,
+) error {
+	if req.Limit <= 0 {
+		req.Limit = merchantBalanceConversionDefaultLimit
+	}
+
+	count, err := s.merchantBalanceConversionRepository.FindCount(ctx, req.MerchantId, req.Currency)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = merchantBalanceConversionErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Count = count
+	rsp.Items = []*pkg.MerchantBalanceConversion{}
+
+	if count <= 0 {
+		return nil
+	}
+
+	conversions, err := s.merchantBalanceConversionRepository.Find(ctx, req.MerchantId, req.Currency, req.Offset, req.Limit)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = merchantBalanceConversionErrorUnknown
+		return nil
+	}
+
+	for _, conversion := range conversions {
+		rsp.Items = append(rsp.Items, getMerchantBalanceConversionMessage(conversion))
+	}
+
+	return nil
+}
+
+func getMerchantBalanceConversionMessage(conversion *intPkg.MerchantBalanceConversion) *pkg.MerchantBalanceConversion {
+	return &pkg.MerchantBalanceConversion{
+		Id:                   conversion.Id.Hex(),
+		MerchantId:           conversion.MerchantId.Hex(),
+		FromCurrency:         conversion.FromCurrency,
+		FromAmount:           conversion.FromAmount,
+		ToCurrency:           conversion.ToCurrency,
+		ToAmount:             conversion.ToAmount,
+		Rate:                 conversion.Rate,
+		RateType:             conversion.RateType,
+		FromPayoutDocumentId: conversion.FromPayoutDocumentId,
+		ToPayoutDocumentId:   conversion.ToPayoutDocumentId,
+		UserId:               conversion.UserId,
+		CreatedAt:            getTimestampProto(conversion.CreatedAt),
+	}
+}
@@ -0,0 +1,493 @@
+package service
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/mongodb"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	"github.com/paysuper/paysuper-proto/go/currenciespb"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	rabbitmq "gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+)
+
+type MerchantBalanceConversionTestSuite struct {
+	suite.Suite
+	service *Service
+
+	merchant  *billingpb.Merchant
+	merchant2 *billingpb.Merchant
+}
+
+func Test_MerchantBalanceConversion(t *testing.T) {
+	suite.Run(t, new(MerchantBalanceConversionTestSuite))
+}
+
+func (suite *MerchantBalanceConversionTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New(
+		"file://../../migrations/tests",
+		cfg.MongoDsn)
+	assert.NoError(suite.T(), err, "Migrate init failed")
+
+	err = m.Up()
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	broker, err := rabbitmq.NewBroker(cfg.BrokerAddress)
+
+	if err != nil {
+		suite.FailNow("Creating RabbitMQ publisher failed", "%v", err)
+	}
+
+	redisClient := database.NewRedis(
+		&redis.Options{
+			Addr:     cfg.RedisHost,
+			Password: cfg.RedisPassword,
+		},
+	)
+
+	redisdb := mocks.NewTestRedis()
+	cache, err := database.NewCacheRedis(redisdb, "cache")
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		broker,
+		redisClient,
+		cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	operatingCompany := HelperOperatingCompany(suite.Suite, suite.service)
+
+	suite.merchant = HelperCreateMerchant(suite.Suite, suite.service, "RUB", "RU", nil, 13000, operatingCompany.Id)
+	suite.merchant2 = HelperCreateMerchant(suite.Suite, suite.service, "", "RU", nil, 0, operatingCompany.Id)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ConvertMerchantBalance_Ok() {
+	suite.helperInsertRoyaltyReport(1000)
+
+	req := &pkg.ConvertMerchantBalanceRequest{
+		MerchantId:   suite.merchant.Id,
+		FromCurrency: "RUB",
+		ToCurrency:   "EUR",
+		Amount:       400,
+		UserId:       primitive.NewObjectID().Hex(),
+	}
+	rsp := &pkg.ConvertMerchantBalanceResponse{}
+	err := suite.service.ConvertMerchantBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Empty(suite.T(), rsp.Message)
+	assert.NotNil(suite.T(), rsp.Item)
+	assert.Equal(suite.T(), suite.merchant.Id, rsp.Item.MerchantId)
+	assert.Equal(suite.T(), "RUB", rsp.Item.FromCurrency)
+	assert.EqualValues(suite.T(), 400, rsp.Item.FromAmount)
+	assert.Equal(suite.T(), "EUR", rsp.Item.ToCurrency)
+	assert.True(suite.T(), rsp.Item.ToAmount > 0)
+	assert.True(suite.T(), rsp.Item.Rate > 0)
+	assert.Equal(suite.T(), currenciespb.RateTypePaysuper, rsp.Item.RateType)
+	assert.Equal(suite.T(), req.UserId, rsp.Item.UserId)
+	assert.Empty(suite.T(), rsp.Item.FromPayoutDocumentId)
+	assert.Empty(suite.T(), rsp.Item.ToPayoutDocumentId)
+	assert.NotNil(suite.T(), rsp.Item.CreatedAt)
+
+	balance, err := suite.service.getMerchantBalance(context.TODO(), suite.merchant.Id, "RUB")
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1000, balance.Debit)
+	assert.EqualValues(suite.T(), 400, balance.Credit)
+	assert.EqualValues(suite.T(), 600, balance.Total)
+
+	balance, err = suite.service.getMerchantBalance(context.TODO(), suite.merchant.Id, "EUR")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), rsp.Item.ToAmount, balance.Debit)
+	assert.EqualValues(suite.T(), 0, balance.Credit)
+	assert.Equal(suite.T(), rsp.Item.ToAmount, balance.Total)
+
+	listRsp := &pkg.ListMerchantBalancesResponse{}
+	err = suite.service.ListMerchantBalances(
+		context.TODO(),
+		&pkg.ListMerchantBalancesRequest{MerchantId: suite.merchant.Id},
+		listRsp,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, listRsp.Status)
+	assert.Len(suite.T(), listRsp.Items, 2)
+	assert.Equal(suite.T(), "RUB", listRsp.Items[0].Currency)
+	assert.EqualValues(suite.T(), 600, listRsp.Items[0].Total)
+	assert.Equal(suite.T(), "EUR", listRsp.Items[1].Currency)
+	assert.Equal(suite.T(), rsp.Item.ToAmount, listRsp.Items[1].Total)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ConvertMerchantBalance_MerchantNotFound_Error() {
+	req := &pkg.ConvertMerchantBalanceRequest{
+		MerchantId:   primitive.NewObjectID().Hex(),
+		FromCurrency: "RUB",
+		ToCurrency:   "EUR",
+		Amount:       100,
+	}
+	rsp := &pkg.ConvertMerchantBalanceResponse{}
+	err := suite.service.ConvertMerchantBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), merchantErrorNotFound, rsp.Message)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ConvertMerchantBalance_CurrenciesEqual_Error() {
+	req := &pkg.ConvertMerchantBalanceRequest{
+		MerchantId:   suite.merchant.Id,
+		FromCurrency: "RUB",
+		ToCurrency:   "RUB",
+		Amount:       100,
+	}
+	rsp := &pkg.ConvertMerchantBalanceResponse{}
+	err := suite.service.ConvertMerchantBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), merchantBalanceConversionErrorCurrenciesEqual, rsp.Message)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ConvertMerchantBalance_AmountInvalid_Error() {
+	req := &pkg.ConvertMerchantBalanceRequest{
+		MerchantId:   suite.merchant.Id,
+		FromCurrency: "RUB",
+		ToCurrency:   "EUR",
+		Amount:       0.001,
+	}
+	rsp := &pkg.ConvertMerchantBalanceResponse{}
+	err := suite.service.ConvertMerchantBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), merchantBalanceConversionErrorAmountInvalid, rsp.Message)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ConvertMerchantBalance_CurrencyNotSupported_Error() {
+	req := &pkg.ConvertMerchantBalanceRequest{
+		MerchantId:   suite.merchant.Id,
+		FromCurrency: "RUB",
+		ToCurrency:   "XXX",
+		Amount:       100,
+	}
+	rsp := &pkg.ConvertMerchantBalanceResponse{}
+	err := suite.service.ConvertMerchantBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), merchantBalanceConversionErrorCurrencyNotSupported, rsp.Message)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ConvertMerchantBalance_NotEnoughBalance_Error() {
+	suite.helperInsertRoyaltyReport(100)
+
+	req := &pkg.ConvertMerchantBalanceRequest{
+		MerchantId:   suite.merchant.Id,
+		FromCurrency: "RUB",
+		ToCurrency:   "EUR",
+		Amount:       100.01,
+	}
+	rsp := &pkg.ConvertMerchantBalanceResponse{}
+	err := suite.service.ConvertMerchantBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), merchantBalanceConversionErrorNotEnoughBalance, rsp.Message)
+
+	count, err := suite.service.merchantBalanceConversionRepository.FindCount(context.TODO(), suite.merchant.Id, "")
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), count)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ConvertMerchantBalance_Sequence_Ok() {
+	suite.helperInsertRoyaltyReport(1000)
+
+	for i := 1; i <= 2; i++ {
+		req := &pkg.ConvertMerchantBalanceRequest{
+			MerchantId:   suite.merchant.Id,
+			FromCurrency: "RUB",
+			ToCurrency:   "EUR",
+			Amount:       400,
+		}
+		rsp := &pkg.ConvertMerchantBalanceResponse{}
+		err := suite.service.ConvertMerchantBalance(context.TODO(), req, rsp)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+		last, err := suite.service.merchantBalanceConversionRepository.GetLastFrom(context.TODO(), suite.merchant.Id, "RUB")
+		assert.NoError(suite.T(), err)
+		assert.NotNil(suite.T(), last)
+		assert.Equal(suite.T(), rsp.Item.Id, last.Id.Hex())
+		assert.EqualValues(suite.T(), i, last.FromSequence)
+	}
+
+	last, err := suite.service.merchantBalanceConversionRepository.GetLastFrom(context.TODO(), suite.merchant.Id, "EUR")
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), last)
+
+	req := &pkg.ConvertMerchantBalanceRequest{
+		MerchantId:   suite.merchant.Id,
+		FromCurrency: "RUB",
+		ToCurrency:   "EUR",
+		Amount:       400,
+	}
+	rsp := &pkg.ConvertMerchantBalanceResponse{}
+	err = suite.service.ConvertMerchantBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), merchantBalanceConversionErrorNotEnoughBalance, rsp.Message)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ConvertMerchantBalance_ConcurrentConversion_Retried() {
+	suite.helperInsertRoyaltyReport(1000)
+
+	duplicateErr := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}
+	repository := &mocks.MerchantBalanceConversionRepositoryInterface{}
+	repository.On("GetLastFrom", mock.Anything, suite.merchant.Id, "RUB").Return(nil, nil).Once()
+	repository.On("GetLastFrom", mock.Anything, suite.merchant.Id, "RUB").
+		Return(&intPkg.MerchantBalanceConversion{FromSequence: 1}, nil)
+	repository.On("Find", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	repository.On("Insert", mock.Anything, mock.MatchedBy(func(conversion *intPkg.MerchantBalanceConversion) bool {
+		return conversion.FromSequence == 1
+	})).Return(duplicateErr)
+	repository.On("Insert", mock.Anything, mock.MatchedBy(func(conversion *intPkg.MerchantBalanceConversion) bool {
+		return conversion.FromSequence == 2
+	})).Return(nil)
+	suite.service.merchantBalanceConversionRepository = repository
+
+	req := &pkg.ConvertMerchantBalanceRequest{
+		MerchantId:   suite.merchant.Id,
+		FromCurrency: "RUB",
+		ToCurrency:   "EUR",
+		Amount:       400,
+	}
+	rsp := &pkg.ConvertMerchantBalanceResponse{}
+	err := suite.service.ConvertMerchantBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	repository.AssertNumberOfCalls(suite.T(), "GetLastFrom", 2)
+	repository.AssertNumberOfCalls(suite.T(), "Insert", 2)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ConvertMerchantBalance_ConcurrentConversion_Error() {
+	suite.helperInsertRoyaltyReport(1000)
+
+	duplicateErr := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}
+	repository := &mocks.MerchantBalanceConversionRepositoryInterface{}
+	repository.On("GetLastFrom", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	repository.On("Find", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	repository.On("Insert", mock.Anything, mock.Anything).Return(duplicateErr)
+	suite.service.merchantBalanceConversionRepository = repository
+
+	req := &pkg.ConvertMerchantBalanceRequest{
+		MerchantId:   suite.merchant.Id,
+		FromCurrency: "RUB",
+		ToCurrency:   "EUR",
+		Amount:       400,
+	}
+	rsp := &pkg.ConvertMerchantBalanceResponse{}
+	err := suite.service.ConvertMerchantBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+	assert.Equal(suite.T(), merchantBalanceConversionErrorUnknown, rsp.Message)
+	repository.AssertNumberOfCalls(suite.T(), "Insert", merchantBalanceConversionInsertAttempts)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ConvertMerchantBalance_BalanceLocked_Error() {
+	suite.helperInsertRoyaltyReport(1000)
+
+	token, err := suite.service.lockMerchantBalance(suite.merchant.Id, "RUB")
+	assert.NoError(suite.T(), err)
+
+	req := &pkg.ConvertMerchantBalanceRequest{
+		MerchantId:   suite.merchant.Id,
+		FromCurrency: "RUB",
+		ToCurrency:   "EUR",
+		Amount:       400,
+	}
+	rsp := &pkg.ConvertMerchantBalanceResponse{}
+	err = suite.service.ConvertMerchantBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), errorMerchantBalanceLocked, rsp.Message)
+
+	suite.service.unlockMerchantBalance(suite.merchant.Id, "RUB", token)
+
+	rsp = &pkg.ConvertMerchantBalanceResponse{}
+	err = suite.service.ConvertMerchantBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ConvertMerchantBalance_ExchangeFailed_Error() {
+	suite.helperInsertRoyaltyReport(1000)
+	suite.service.curService = mocks.NewCurrencyServiceMockError()
+
+	req := &pkg.ConvertMerchantBalanceRequest{
+		MerchantId:   suite.merchant.Id,
+		FromCurrency: "RUB",
+		ToCurrency:   "EUR",
+		Amount:       100,
+	}
+	rsp := &pkg.ConvertMerchantBalanceResponse{}
+	err := suite.service.ConvertMerchantBalance(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+	assert.Equal(suite.T(), merchantBalanceConversionErrorExchangeFailed, rsp.Message)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ListMerchantBalanceConversions_Ok() {
+	suite.helperInsertRoyaltyReport(1000)
+
+	for _, currency := range []string{"EUR", "USD"} {
+		rsp := &pkg.ConvertMerchantBalanceResponse{}
+		err := suite.service.ConvertMerchantBalance(
+			context.TODO(),
+			&pkg.ConvertMerchantBalanceRequest{
+				MerchantId:   suite.merchant.Id,
+				FromCurrency: "RUB",
+				ToCurrency:   currency,
+				Amount:       100,
+			},
+			rsp,
+		)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	}
+
+	rsp := &pkg.ListMerchantBalanceConversionsResponse{}
+	err := suite.service.ListMerchantBalanceConversions(
+		context.TODO(),
+		&pkg.ListMerchantBalanceConversionsRequest{MerchantId: suite.merchant.Id},
+		rsp,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 2, rsp.Count)
+	assert.Len(suite.T(), rsp.Items, 2)
+
+	rsp = &pkg.ListMerchantBalanceConversionsResponse{}
+	err = suite.service.ListMerchantBalanceConversions(
+		context.TODO(),
+		&pkg.ListMerchantBalanceConversionsRequest{MerchantId: suite.merchant.Id, Currency: "USD"},
+		rsp,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 1, rsp.Count)
+	assert.Len(suite.T(), rsp.Items, 1)
+	assert.Equal(suite.T(), "USD", rsp.Items[0].ToCurrency)
+
+	rsp = &pkg.ListMerchantBalanceConversionsResponse{}
+	err = suite.service.ListMerchantBalanceConversions(
+		context.TODO(),
+		&pkg.ListMerchantBalanceConversionsRequest{MerchantId: suite.merchant.Id, Currency: "GBP"},
+		rsp,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Zero(suite.T(), rsp.Count)
+	assert.Empty(suite.T(), rsp.Items)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ListMerchantBalances_Ok_PayoutCurrencyOnly() {
+	rsp := &pkg.ListMerchantBalancesResponse{}
+	err := suite.service.ListMerchantBalances(
+		context.TODO(),
+		&pkg.ListMerchantBalancesRequest{MerchantId: suite.merchant.Id},
+		rsp,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Len(suite.T(), rsp.Items, 1)
+	assert.Equal(suite.T(), suite.merchant.GetPayoutCurrency(), rsp.Items[0].Currency)
+	assert.EqualValues(suite.T(), 0, rsp.Items[0].Total)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) TestMerchantBalanceConversion_ListMerchantBalances_NoPayoutCurrency_Error() {
+	rsp := &pkg.ListMerchantBalancesResponse{}
+	err := suite.service.ListMerchantBalances(
+		context.TODO(),
+		&pkg.ListMerchantBalancesRequest{MerchantId: suite.merchant2.Id},
+		rsp,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+	assert.Equal(suite.T(), errorMerchantPayoutCurrencyNotSet, rsp.Message)
+}
+
+func (suite *MerchantBalanceConversionTestSuite) helperInsertRoyaltyReport(amount float64) {
+	report := &billingpb.RoyaltyReport{
+		Id:         primitive.NewObjectID().Hex(),
+		MerchantId: suite.merchant.Id,
+		Totals: &billingpb.RoyaltyReportTotals{
+			TransactionsCount: 1,
+			PayoutAmount:      amount,
+		},
+		Summary: &billingpb.RoyaltyReportSummary{
+			ProductsTotal: &billingpb.RoyaltyReportProductSummaryItem{
+				SalesCount:        1,
+				TotalTransactions: 1,
+				GrossTotalAmount:  amount,
+				PayoutAmount:      amount,
+			},
+		},
+		Status:         billingpb.RoyaltyReportStatusAccepted,
+		CreatedAt:      ptypes.TimestampNow(),
+		PeriodFrom:     ptypes.TimestampNow(),
+		PeriodTo:       ptypes.TimestampNow(),
+		AcceptExpireAt: ptypes.TimestampNow(),
+		Currency:       suite.merchant.GetPayoutCurrency(),
+	}
+	err := suite.service.royaltyReportRepository.Insert(context.TODO(), report, "", pkg.RoyaltyReportChangeSourceAuto)
+	assert.NoError(suite.T(), err)
+}
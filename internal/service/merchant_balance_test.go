@@ -11,6 +11,7 @@ import (
 	"github.com/paysuper/paysuper-billing-server/internal/database"
 	"github.com/paysuper/paysuper-billing-server/internal/mocks"
 	"github.com/paysuper/paysuper-billing-server/internal/payment_system"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
 	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
@@ -170,7 +171,7 @@ func (suite *MerchantBalanceTestSuite) TestMerchantBalance_GetMerchantBalance_Ok
 	count := suite.mbRecordsCount(suite.merchant.Id, suite.merchant.GetPayoutCurrency())
 	assert.EqualValues(suite.T(), count, 0)
 
-	mb, err := suite.service.updateMerchantBalance(ctx, suite.merchant.Id)
+	mb, err := suite.service.updateMerchantBalance(ctx, suite.merchant.Id, "")
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), mb.MerchantId, suite.merchant.Id)
 	assert.Equal(suite.T(), mb.Currency, suite.merchant.GetPayoutCurrency())
@@ -250,7 +251,7 @@ func (suite *MerchantBalanceTestSuite) TestMerchantBalance_updateMerchantBalance
 	count := suite.mbRecordsCount(merchantId, "")
 	assert.EqualValues(suite.T(), count, 0)
 
-	mb, err := suite.service.updateMerchantBalance(ctx, merchantId)
+	mb, err := suite.service.updateMerchantBalance(ctx, merchantId, "")
 	assert.EqualError(suite.T(), err, "merchant with specified identifier not found")
 	assert.Nil(suite.T(), mb)
 
@@ -262,7 +263,7 @@ func (suite *MerchantBalanceTestSuite) TestMerchantBalance_updateMerchantBalance
 	count := suite.mbRecordsCount(suite.merchant2.Id, suite.merchant2.GetPayoutCurrency())
 	assert.EqualValues(suite.T(), count, 0)
 
-	mb, err := suite.service.updateMerchantBalance(ctx, suite.merchant2.Id)
+	mb, err := suite.service.updateMerchantBalance(ctx, suite.merchant2.Id, "")
 	assert.EqualError(suite.T(), err, errorMerchantPayoutCurrencyNotSet.Error())
 	assert.Nil(suite.T(), mb)
 
@@ -360,7 +361,7 @@ func (suite *MerchantBalanceTestSuite) TestMerchantBalance_updateMerchantBalance
 	count := suite.mbRecordsCount(suite.merchant.Id, suite.merchant.GetPayoutCurrency())
 	assert.EqualValues(suite.T(), count, 0)
 
-	mb, err := suite.service.updateMerchantBalance(ctx, suite.merchant.Id)
+	mb, err := suite.service.updateMerchantBalance(ctx, suite.merchant.Id, "")
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), mb.MerchantId, suite.merchant.Id)
 	assert.Equal(suite.T(), mb.Currency, suite.merchant.GetPayoutCurrency())
@@ -787,6 +788,58 @@ func (suite *MerchantBalanceTestSuite) TestMerchantBalance_UpdateBalanceTriggeri
 	assert.Equal(suite.T(), mbRes.Item.Total, float64(500))
 }
 
+func (suite *MerchantBalanceTestSuite) TestMerchantBalance_getMerchantCurrencies_Ok() {
+	currencies := getMerchantCurrencies("RUB", []string{"USD", "EUR", "RUB", "USD", ""})
+	assert.Equal(suite.T(), []string{"RUB", "EUR", "USD"}, currencies)
+
+	currencies = getMerchantCurrencies("RUB", nil)
+	assert.Equal(suite.T(), []string{"RUB"}, currencies)
+}
+
+func (suite *MerchantBalanceTestSuite) TestMerchantBalance_updateMerchantBalance_Ok_WithConversions() {
+	merchantOid, err := primitive.ObjectIDFromHex(suite.merchant.Id)
+	assert.NoError(suite.T(), err)
+
+	conversions := []*intPkg.MerchantBalanceConversion{
+		{
+			MerchantId:   merchantOid,
+			FromCurrency: "RUB",
+			FromAmount:   500,
+			ToCurrency:   "EUR",
+			ToAmount:     6.5,
+			CreatedAt:    time.Now().UTC(),
+		},
+		{
+			MerchantId:   merchantOid,
+			FromCurrency: "EUR",
+			FromAmount:   2,
+			ToCurrency:   "RUB",
+			ToAmount:     150,
+			CreatedAt:    time.Now().UTC(),
+		},
+	}
+
+	for _, conversion := range conversions {
+		err = suite.service.merchantBalanceConversionRepository.Insert(ctx, conversion)
+		assert.NoError(suite.T(), err)
+	}
+
+	mb, err := suite.service.updateMerchantBalance(ctx, suite.merchant.Id, "RUB")
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 150, mb.Debit)
+	assert.EqualValues(suite.T(), 500, mb.Credit)
+	assert.EqualValues(suite.T(), -350, mb.Total)
+
+	mb, err = suite.service.updateMerchantBalance(ctx, suite.merchant.Id, "EUR")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "EUR", mb.Currency)
+	assert.EqualValues(suite.T(), 6.5, mb.Debit)
+	assert.EqualValues(suite.T(), 2, mb.Credit)
+	assert.EqualValues(suite.T(), 4.5, mb.Total)
+
+	assert.EqualValues(suite.T(), 1, suite.mbRecordsCount(suite.merchant.Id, "EUR"))
+}
+
 func (suite *MerchantBalanceTestSuite) mbRecordsCount(merchantId, currency string) int64 {
 	count, err := suite.service.merchantBalanceRepository.CountByIdAndCurrency(ctx, merchantId, currency)
 
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/paysuper/paysuper-billing-server/internal/helper"
 	"github.com/paysuper/paysuper-billing-server/pkg"
@@ -117,6 +118,8 @@ func (s *Service) SetMoneyBackCostMerchant(
 	req.UpdatedAt = ptypes.TimestampNow()
 	req.IsActive = true
 
+	var before *billingpb.MoneyBackCostMerchant
+
 	if req.Id != "" {
 		val, err := s.moneyBackCostMerchantRepository.GetById(ctx, req.Id)
 		if err != nil {
@@ -124,6 +127,7 @@ func (s *Service) SetMoneyBackCostMerchant(
 			res.Message = errorMoneybackMerchantSetFailed
 			return nil
 		}
+		before = val
 		req.Id = val.Id
 		req.MerchantId = val.MerchantId
 		req.CreatedAt = val.CreatedAt
@@ -146,6 +150,18 @@ func (s *Service) SetMoneyBackCostMerchant(
 		return nil
 	}
 
+	if err := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "SetMoneyBackCostMerchant",
+		entityType: pkg.AuditLogEntityMoneyBackCostMerchant,
+		entityId:   req.Id,
+		before:     before,
+		after:      req,
+	}); err != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	res.Status = billingpb.ResponseStatusOk
 	res.Item = req
 
@@ -163,6 +179,8 @@ func (s *Service) DeleteMoneyBackCostMerchant(
 		res.Message = errorCostRateNotFound
 		return nil
 	}
+
+	before := proto.Clone(pc)
 	err = s.moneyBackCostMerchantRepository.Delete(ctx, pc)
 	if err != nil {
 		res.Status = billingpb.ResponseStatusSystemError
@@ -170,6 +188,18 @@ func (s *Service) DeleteMoneyBackCostMerchant(
 		return nil
 	}
 
+	if err := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "DeleteMoneyBackCostMerchant",
+		entityType: pkg.AuditLogEntityMoneyBackCostMerchant,
+		entityId:   pc.Id,
+		before:     before,
+		after:      pc,
+	}); err != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	res.Status = billingpb.ResponseStatusOk
 	return nil
 }
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/paysuper/paysuper-billing-server/internal/helper"
 	"github.com/paysuper/paysuper-billing-server/pkg"
@@ -115,6 +116,8 @@ func (s *Service) SetMoneyBackCostSystem(
 	req.UpdatedAt = ptypes.TimestampNow()
 	req.IsActive = true
 
+	var before *billingpb.MoneyBackCostSystem
+
 	if req.Id != "" {
 		val, err := s.moneyBackCostSystemRepository.GetById(ctx, req.Id)
 		if err != nil {
@@ -122,6 +125,7 @@ func (s *Service) SetMoneyBackCostSystem(
 			res.Message = errorMoneybackSystemSetFailed
 			return nil
 		}
+		before = val
 		req.Id = val.Id
 		req.CreatedAt = val.CreatedAt
 		err = s.moneyBackCostSystemRepository.Update(ctx, req)
@@ -142,6 +146,18 @@ func (s *Service) SetMoneyBackCostSystem(
 		return nil
 	}
 
+	if err := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "SetMoneyBackCostSystem",
+		entityType: pkg.AuditLogEntityMoneyBackCostSystem,
+		entityId:   req.Id,
+		before:     before,
+		after:      req,
+	}); err != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	res.Status = billingpb.ResponseStatusOk
 	res.Item = req
 
@@ -159,12 +175,27 @@ func (s *Service) DeleteMoneyBackCostSystem(
 		res.Message = errorCostRateNotFound
 		return nil
 	}
+
+	before := proto.Clone(pc)
 	err = s.moneyBackCostSystemRepository.Delete(ctx, pc)
 	if err != nil {
 		res.Status = billingpb.ResponseStatusSystemError
 		res.Message = errorMoneybackSystemDelete
 		return nil
 	}
+
+	if err := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "DeleteMoneyBackCostSystem",
+		entityType: pkg.AuditLogEntityMoneyBackCostSystem,
+		entityId:   pc.Id,
+		before:     before,
+		after:      pc,
+	}); err != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	res.Status = billingpb.ResponseStatusOk
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/divan/num2words"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/micro/go-micro/client"
 	"github.com/paysuper/paysuper-billing-server/internal/helper"
@@ -514,6 +515,7 @@ func (s *Service) ChangeMerchantStatus(
 		return nil
 	}
 
+	before := proto.Clone(merchant)
 	statusChange := &billingpb.SystemNotificationStatuses{From: merchant.Status, To: req.Status}
 	message, ok := merchantStatusChangesMessages[req.Status]
 
@@ -544,6 +546,18 @@ func (s *Service) ChangeMerchantStatus(
 		return nil
 	}
 
+	if err := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "ChangeMerchantStatus",
+		entityType: pkg.AuditLogEntityMerchant,
+		entityId:   merchant.Id,
+		before:     before,
+		after:      merchant,
+	}); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	rsp.Status = billingpb.ResponseStatusOk
 	rsp.Item = merchant
 
@@ -790,6 +804,7 @@ func (s *Service) ChangeMerchantManualPayouts(
 		return nil
 	}
 
+	before := proto.Clone(merchant)
 	merchant.ManualPayoutsEnabled = req.ManualPayoutsEnabled
 
 	err = s.merchantRepository.Update(ctx, merchant)
@@ -800,6 +815,18 @@ func (s *Service) ChangeMerchantManualPayouts(
 		return nil
 	}
 
+	if err := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "ChangeMerchantManualPayouts",
+		entityType: pkg.AuditLogEntityMerchant,
+		entityId:   merchant.Id,
+		before:     before,
+		after:      merchant,
+	}); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	rsp.Status = billingpb.ResponseStatusOk
 	rsp.Item = merchant
 
@@ -1373,6 +1400,7 @@ func (s *Service) SetMerchantTariffRates(
 		return nil
 	}
 
+	before := proto.Clone(merchant)
 	merchant, err = s.setMerchantTariffRates(ctx, merchant, req.HomeRegion, req.MerchantOperationsType)
 
 	if err != nil {
@@ -1408,6 +1436,18 @@ func (s *Service) SetMerchantTariffRates(
 		return nil
 	}
 
+	if err := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "SetMerchantTariffRates",
+		entityType: pkg.AuditLogEntityMerchant,
+		entityId:   merchant.Id,
+		before:     before,
+		after:      merchant,
+	}); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	rsp.Status = billingpb.ResponseStatusOk
 	return nil
 }
@@ -139,6 +139,15 @@ var (
 	orderErrorRecurringSubscriptionNotFound                   = errors2.NewBillingServerErrorMsg("fm000086", "recurring subscription not found")
 	orderErrorRecurringUnableToAdd                            = errors2.NewBillingServerErrorMsg("fm000087", "unable to add recurring subscription")
 	orderErrorRecurringUnableToUpdate                         = errors2.NewBillingServerErrorMsg("fm000088", "unable to update recurring subscription")
+	orderErrorPaymentSystemTemporaryUnavailable               = errors2.NewBillingServerErrorMsg("fm000089", "payment systems for payment method are temporary unavailable")
+	orderErrorPaymentNotAuthorized                            = errors2.NewBillingServerErrorMsg("fm000090", "order payment isn't authorized or already captured")
+	orderErrorCaptureAmountInvalid                            = errors2.NewBillingServerErrorMsg("fm000091", "capture amount can't be greater than authorized amount")
+	orderErrorCaptureFailed                                   = errors2.NewBillingServerErrorMsg("fm000092", "order payment capture failed")
+	orderErrorVoidFailed                                      = errors2.NewBillingServerErrorMsg("fm000093", "order payment void failed")
+	orderErrorFraudDeclined                                   = errors2.NewBillingServerErrorMsg("fm000094", "payment declined by risk check")
+	orderErrorRecurringTrialDaysInvalid                       = errors2.NewBillingServerErrorMsg("fm000095", "trial period of recurring subscription must be shorter than subscription period")
+	orderErrorRecurringTrialPriceInvalid                      = errors2.NewBillingServerErrorMsg("fm000096", "trial price of recurring subscription must be not less than minimal trial price and less than order amount")
+	orderErrorRecurringPaymentSystemNotSupported              = errors2.NewBillingServerErrorMsg("fm000097", "recurring subscription isn't supported by payment system of payment method")
 
 	virtualCurrencyPayoutCurrencyMissed = errors2.NewBillingServerErrorMsg("vc000001", "virtual currency don't have price in merchant payout currency")
 
@@ -259,6 +268,32 @@ func (s *Service) OrderCreateProcess(
 	ctx context.Context,
 	req *billingpb.OrderCreateRequest,
 	rsp *billingpb.OrderCreateProcessResponse,
+) error {
+	projectId := req.ProjectId
+
+	if projectId == "" && req.Token != "" {
+		if token, err := s.getTokenBy(req.Token); err == nil {
+			projectId = token.Settings.ProjectId
+		}
+	}
+
+	err := s.processIdempotent(ctx, idempotencyOperationOrderCreate, projectId, req, rsp, func() error {
+		return s.orderCreateProcess(ctx, req, rsp)
+	})
+
+	if e, ok := err.(*billingpb.ResponseError); ok {
+		rsp.Status = e.Status
+		rsp.Message = e.Message
+		return nil
+	}
+
+	return err
+}
+
+func (s *Service) orderCreateProcess(
+	ctx context.Context,
+	req *billingpb.OrderCreateRequest,
+	rsp *billingpb.OrderCreateProcessResponse,
 ) error {
 	rsp.Status = billingpb.ResponseStatusOk
 
@@ -524,7 +559,16 @@ func (s *Service) OrderCreateProcess(
 	}
 
 	processor.processMetadata()
-	processor.processPrivateMetadata()
+
+	if err := processor.processPrivateMetadata(); err != nil {
+		zap.S().Errorw(pkg.MethodFinishedWithError, "err", err.Error())
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = e
+			return nil
+		}
+		return err
+	}
 
 	order, err := processor.prepareOrder()
 
@@ -944,6 +988,7 @@ func (s *Service) PaymentCreateProcess(
 	}
 
 	order := processor.checked.order
+	s.restoreSubscriptionTrialAmounts(order)
 
 	decryptedBrowserCustomer.CustomerId = order.User.Id
 	cookie, err := s.generateBrowserCookie(decryptedBrowserCustomer)
@@ -965,6 +1010,22 @@ func (s *Service) PaymentCreateProcess(
 		return nil
 	}
 
+	screening := s.screenPayment(ctx, order, req.Ip)
+
+	if screening.action == pkg.FraudActionDecline {
+		order.PrivateStatus = recurringpb.OrderStatusPaymentSystemDeclined
+
+		if err = s.updateOrder(ctx, order); err != nil {
+			rsp.Message = orderErrorUnknown
+			rsp.Status = billingpb.ResponseStatusSystemError
+			return nil
+		}
+
+		rsp.Message = orderErrorFraudDeclined
+		rsp.Status = billingpb.ResponseStatusForbidden
+		return nil
+	}
+
 	if order.ProductType == pkg.OrderType_product {
 		err = s.ProcessOrderProducts(ctx, order)
 	} else if order.ProductType == pkg.OrderType_key {
@@ -1009,7 +1070,7 @@ func (s *Service) PaymentCreateProcess(
 		}
 	}
 
-	ps, err := s.paymentSystemRepository.GetById(ctx, processor.checked.paymentMethod.PaymentSystemId)
+	paymentSystems, err := s.getPaymentSystemsForPaymentMethod(ctx, processor.checked.paymentMethod, order.GetCountry())
 	if err != nil {
 		rsp.Message = orderErrorPaymentSystemInactive
 		rsp.Status = billingpb.ResponseStatusBadData
@@ -1017,6 +1078,18 @@ func (s *Service) PaymentCreateProcess(
 		return nil
 	}
 
+	if processor.checked.paymentMethod.RecurringAllowed && order.RecurringSettings != nil && order.User.Uuid != "" {
+		paymentSystems = getRecurringPaymentSystems(paymentSystems)
+
+		if len(paymentSystems) <= 0 {
+			rsp.Message = orderErrorRecurringPaymentSystemNotSupported
+			rsp.Status = billingpb.ResponseStatusBadData
+
+			return nil
+		}
+	}
+
+	ps := paymentSystems[0]
 	order.PaymentMethod = &billingpb.PaymentMethodOrder{
 		Id:               processor.checked.paymentMethod.Id,
 		Name:             processor.checked.paymentMethod.Name,
@@ -1049,7 +1122,7 @@ func (s *Service) PaymentCreateProcess(
 		return err
 	}
 
-	order.PaymentMethod.Params, err = s.getPaymentSettings(
+	pmParams, err := s.getPaymentSettings(
 		processor.checked.paymentMethod,
 		order.ChargeCurrency,
 		order.MccCode,
@@ -1068,6 +1141,8 @@ func (s *Service) PaymentCreateProcess(
 		return err
 	}
 
+	order.PaymentMethod.Params = s.getPaymentSystemParams(ps.Handler, pmParams, order.IsProduction)
+
 	if _, ok := order.PaymentRequisites[billingpb.PaymentCreateFieldRecurringId]; ok {
 		req.Data[billingpb.PaymentCreateFieldRecurringId] = order.PaymentRequisites[billingpb.PaymentCreateFieldRecurringId]
 		delete(order.PaymentRequisites, billingpb.PaymentCreateFieldRecurringId)
@@ -1090,6 +1165,15 @@ func (s *Service) PaymentCreateProcess(
 		return err
 	}
 
+	if err = s.reserveOrderPromoCode(ctx, order); err != nil {
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = e
+			return nil
+		}
+		return err
+	}
+
 	err = s.updateOrder(ctx, order)
 
 	if err != nil {
@@ -1115,21 +1199,66 @@ func (s *Service) PaymentCreateProcess(
 		return nil
 	}
 
-	h, err := s.paymentSystemGateway.GetGateway(order.PaymentMethod.Handler)
+	if screening.action == pkg.FraudActionReview {
+		if err = s.holdOrderForReview(ctx, order, screening); err != nil {
+			zap.L().Error(
+				"order holding for review failed",
+				zap.Error(err),
+				zap.String("order_id", order.Id),
+			)
+			rsp.Message = orderErrorUnknown
+			rsp.Status = billingpb.ResponseStatusSystemError
+			return nil
+		}
+
+		rsp.Status = billingpb.ResponseStatusOk
+		return nil
+	}
+
+	// wallet and gift card are charged before the payment system charges the rest of the order and saved
+	// to the order, so the callback of the declined payment returns them
+	if getOrderPrepaidAmount(order) > 0 {
+		if err = s.chargeOrderPrepaidAmounts(ctx, order); err != nil {
+			s.releaseOrderPromoCode(ctx, order)
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = err.(*billingpb.ResponseErrorMessage)
+			return nil
+		}
 
-	if err != nil {
-		zap.S().Errorw(pkg.MethodFinishedWithError, "err", err.Error())
-		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+		if err = s.updateOrder(ctx, order); err != nil {
+			s.releaseOrderWallet(ctx, order)
+			s.releaseOrderGiftCard(ctx, order)
+			s.releaseOrderPromoCode(ctx, order)
 			rsp.Status = billingpb.ResponseStatusSystemError
-			rsp.Message = e
+			rsp.Message = orderErrorUnknown
 			return nil
 		}
-		return err
 	}
 
 	var url string
 
 	if order.PaymentMethod.RecurringAllowed && order.RecurringSettings != nil && order.User.Uuid != "" {
+		h, err := s.paymentSystemGateway.GetGateway(order.PaymentMethod.Handler)
+
+		if err != nil {
+			zap.S().Errorw(pkg.MethodFinishedWithError, "err", err.Error())
+			if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+				rsp.Status = billingpb.ResponseStatusSystemError
+				rsp.Message = e
+				return nil
+			}
+			return err
+		}
+
+		if err = s.applySubscriptionTrial(order); err != nil {
+			if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+				rsp.Status = billingpb.ResponseStatusBadData
+				rsp.Message = e
+				return nil
+			}
+			return err
+		}
+
 		var subscription *recurringpb.Subscription
 
 		subscription, url, err = s.addRecurringSubscription(ctx, order, h, req.Data)
@@ -1148,12 +1277,7 @@ func (s *Service) PaymentCreateProcess(
 		order.RecurringId = subscription.Id
 		rsp.RecurringExpireDate = subscription.ExpireAt
 	} else {
-		url, err = h.CreatePayment(
-			order,
-			s.cfg.GetRedirectUrlSuccess(nil),
-			s.cfg.GetRedirectUrlFail(nil),
-			req.Data,
-		)
+		url, err = s.createPaymentWithFailover(ctx, order, paymentSystems, pmParams, req.Data)
 
 		if err != nil {
 			zap.L().Error(
@@ -1161,6 +1285,10 @@ func (s *Service) PaymentCreateProcess(
 				zap.Error(err),
 				zap.Any("order", order),
 			)
+			s.releaseOrderPromoCode(ctx, order)
+			s.releaseOrderWallet(ctx, order)
+			s.releaseOrderGiftCard(ctx, order)
+			_ = s.updateOrder(ctx, order)
 			if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
 				rsp.Status = billingpb.ResponseStatusSystemError
 				rsp.Message = e
@@ -1225,6 +1353,15 @@ func (s *Service) PaymentCallbackProcess(
 		return orderErrorNotFound
 	}
 
+	// payment of the authorized order is completed or voided by the separate request to payment system,
+	// so callbacks about the changes are already processed
+	if payment_system.IsManualCaptureOrder(order) && order.PrivateStatus != recurringpb.OrderStatusNew &&
+		order.PrivateStatus != recurringpb.OrderStatusPaymentSystemCreate &&
+		order.PrivateStatus != pkg.OrderStatusPaymentSystemAuthorized {
+		rsp.Status = pkg.StatusOK
+		return nil
+	}
+
 	var data protobuf.Message
 
 	ps, err := s.paymentSystemRepository.GetById(ctx, order.PaymentMethod.PaymentSystemId)
@@ -1237,6 +1374,22 @@ func (s *Service) PaymentCallbackProcess(
 		data = &billingpb.CardPayPaymentCallback{}
 		err := json.Unmarshal(req.Request, data)
 
+		if err != nil {
+			return errors.New(paymentRequestIncorrect)
+		}
+		break
+	case pkg.PaymentSystemHandlerCheckout:
+		data = &payment_system.CheckoutWebhook{}
+		err := json.Unmarshal(req.Request, data)
+
+		if err != nil {
+			return errors.New(paymentRequestIncorrect)
+		}
+		break
+	case pkg.PaymentSystemHandlerSimulator:
+		data = &payment_system.SimulatorCallback{}
+		err := json.Unmarshal(req.Request, data)
+
 		if err != nil {
 			return errors.New(paymentRequestIncorrect)
 		}
@@ -1291,6 +1444,10 @@ func (s *Service) PaymentCallbackProcess(
 		break
 	}
 
+	if _, ok := order.PrivateMetadata[pkg.OrderPrivateMetadataSavedCardUpdate]; ok {
+		return s.processSavedCardUpdateCallback(ctx, order, h, data, pErr, rsp)
+	}
+
 	merchant, err := s.merchantRepository.GetById(ctx, order.GetMerchantId())
 	if err != nil {
 		return err
@@ -1373,6 +1530,13 @@ func (s *Service) PaymentCallbackProcess(
 		}
 	}
 
+	if order.PrivateStatus == recurringpb.OrderStatusPaymentSystemDeclined ||
+		order.PrivateStatus == recurringpb.OrderStatusPaymentSystemCanceled {
+		s.releaseOrderPromoCode(ctx, order)
+		s.releaseOrderWallet(ctx, order)
+		s.releaseOrderGiftCard(ctx, order)
+	}
+
 	err = s.updateOrder(ctx, order)
 
 	if err != nil {
@@ -1386,8 +1550,43 @@ func (s *Service) PaymentCallbackProcess(
 	}
 
 	if pErr == nil {
+		if _, ok := order.PrivateMetadata[pkg.OrderPrivateMetadataSubscriptionDunning]; ok {
+			if err = s.processSubscriptionDunningCallback(ctx, order); err != nil {
+				zap.L().Error(
+					pkg.MethodFinishedWithError,
+					zap.String("Method", "processSubscriptionDunningCallback"),
+					zap.Error(err),
+					zap.String("orderId", order.Id),
+				)
+			}
+		}
+
+		if _, ok := order.PrivateMetadata[pkg.OrderPrivateMetadataSubscriptionPlanChange]; ok {
+			if err = s.processSubscriptionPlanChangeCallback(ctx, order); err != nil {
+				zap.L().Error(
+					pkg.MethodFinishedWithError,
+					zap.String("Method", "processSubscriptionPlanChangeCallback"),
+					zap.Error(err),
+					zap.String("orderId", order.Id),
+				)
+			}
+		}
+
 		if h.IsSubscriptionCallback(data) && subscription != nil {
-			if order.PrivateStatus != recurringpb.OrderStatusPaymentSystemComplete {
+			if order.PrivateStatus != recurringpb.OrderStatusPaymentSystemComplete && subscription.LastPaymentAt != nil {
+				// failed regular payment is retried by the dunning schedule before the subscription cancellation
+				err = s.startSubscriptionDunning(ctx, order, subscription)
+
+				if err != nil {
+					zap.L().Error(
+						pkg.MethodFinishedWithError,
+						zap.String("Method", "startSubscriptionDunning"),
+						zap.Error(err),
+						zap.String("orderId", order.Id),
+						zap.String("subscriptionId", order.RecurringId),
+					)
+				}
+			} else if order.PrivateStatus != recurringpb.OrderStatusPaymentSystemComplete {
 				err = h.DeleteRecurringSubscription(order, subscription)
 
 				if err != nil {
@@ -1407,7 +1606,8 @@ func (s *Service) PaymentCallbackProcess(
 
 				subscription.IsActive = true
 				subscription.LastPaymentAt = latestPayment
-				subscription.TotalAmount += subscription.Amount
+				// first payment of the subscription with trial is charged with the trial price
+				subscription.TotalAmount += order.ChargeAmount
 				updateRsp, err := s.rep.UpdateSubscription(ctx, subscription)
 
 				if err != nil || updateRsp.Status != billingpb.ResponseStatusOk {
@@ -1423,6 +1623,8 @@ func (s *Service) PaymentCallbackProcess(
 
 					return errors.New(subscriptionUpdateFailed)
 				}
+
+				s.recoverSubscriptionDunning(ctx, subscription.Id)
 			}
 		}
 
@@ -1957,7 +2159,10 @@ func (s *Service) updateOrder(ctx context.Context, order *billingpb.Order) error
 		s.orderNotifyKeyProducts(ctx, order)
 	}
 
-	if statusChanged && order.NeedCallbackNotification() {
+	// verification payment of the saved card update isn't the purchase in the merchant project
+	_, isCardVerification := order.PrivateMetadata[pkg.OrderPrivateMetadataSavedCardUpdate]
+
+	if statusChanged && order.NeedCallbackNotification() && !isCardVerification {
 		s.orderNotifyMerchant(ctx, order)
 	}
 
@@ -1972,8 +2177,15 @@ func (s *Service) orderNotifyKeyProducts(ctx context.Context, order *billingpb.O
 	}
 
 	keys := order.Keys
+	status := order.GetPublicStatus()
+
+	// voided authorization is the final status of the order, so reserved keys must be released
+	if order.PrivateStatus == pkg.OrderStatusPaymentSystemVoided {
+		status = recurringpb.OrderPublicStatusCanceled
+	}
+
 	var err error
-	switch order.GetPublicStatus() {
+	switch status {
 	case recurringpb.OrderPublicStatusCanceled, recurringpb.OrderPublicStatusRejected:
 		for _, key := range keys {
 			zap.S().Infow("[orderNotifyKeyProducts] trying to cancel reserving key", "order_id", order.Id, "key", key)
@@ -2004,6 +2216,7 @@ func (s *Service) orderNotifyKeyProducts(ctx context.Context, order *billingpb.O
 				continue
 			}
 
+			s.activateGiftCard(ctx, order, rsp.Key)
 			s.sendMailWithCode(ctx, order, rsp.Key)
 		}
 		order.IsKeyProductNotified = true
@@ -2148,6 +2361,30 @@ func (s *Service) getPayloadForReceipt(ctx context.Context, order *billingpb.Ord
 		}
 	}
 
+	// pass discount row info as struct, for email template condition
+	var discount *structpb.Value
+	if promoCode, amount := getOrderDiscount(order); amount > 0 {
+		price, err := s.formatter.FormatCurrency(DefaultLanguage, amount, order.Currency)
+		if err != nil {
+			return nil, orderErrorDuringFormattingCurrency
+		}
+
+		discount = &structpb.Value{
+			Kind: &structpb.Value_StructValue{
+				StructValue: &structpb.Struct{
+					Fields: map[string]*structpb.Value{
+						"code": {
+							Kind: &structpb.Value_StringValue{StringValue: promoCode},
+						},
+						"amount": {
+							Kind: &structpb.Value_StringValue{StringValue: price},
+						},
+					},
+				},
+			},
+		}
+	}
+
 	payload := &postmarkpb.Payload{
 		TemplateAlias: template,
 		TemplateModel: templateModel,
@@ -2173,6 +2410,10 @@ func (s *Service) getPayloadForReceipt(ctx context.Context, order *billingpb.Ord
 		fields["vat"] = vat
 	}
 
+	if discount != nil {
+		fields["discount"] = discount
+	}
+
 	payload.TemplateObjectModel = &structpb.Struct{
 		Fields: fields,
 	}
@@ -2607,8 +2848,34 @@ func (v *OrderCreateRequestProcessor) processMetadata() {
 	v.checked.metadata = v.request.Metadata
 }
 
-func (v *OrderCreateRequestProcessor) processPrivateMetadata() {
-	v.checked.privateMetadata = v.request.PrivateMetadata
+func (v *OrderCreateRequestProcessor) processPrivateMetadata() error {
+	manualCapture, err := v.isProjectManualCapture(v.ctx, v.checked.project.Id)
+
+	if err != nil {
+		return orderErrorUnknown
+	}
+
+	// capture mode is the setting of the project, so it can't be chosen by the order request
+	_, ok := v.request.PrivateMetadata[pkg.OrderPrivateMetadataCaptureMode]
+
+	if !ok && !manualCapture {
+		v.checked.privateMetadata = v.request.PrivateMetadata
+		return nil
+	}
+
+	v.checked.privateMetadata = make(map[string]string)
+
+	for k, val := range v.request.PrivateMetadata {
+		v.checked.privateMetadata[k] = val
+	}
+
+	delete(v.checked.privateMetadata, pkg.OrderPrivateMetadataCaptureMode)
+
+	if manualCapture {
+		v.checked.privateMetadata[pkg.OrderPrivateMetadataCaptureMode] = pkg.OrderCaptureModeManual
+	}
+
+	return nil
 }
 
 func (v *OrderCreateRequestProcessor) getCountry() string {
@@ -2821,6 +3088,22 @@ func (v *OrderCreateRequestProcessor) processRecurringSettings() (err error) {
 		return orderErrorRecurringDateEndOutOfRange
 	}
 
+	if value, ok := v.request.PrivateMetadata[pkg.OrderPrivateMetadataTrialDays]; ok {
+		trialDays, err := strconv.Atoi(value)
+
+		if err != nil || trialDays < 1 || !currentTime.AddDate(0, 0, trialDays).Before(dateEnd) {
+			return orderErrorRecurringTrialDaysInvalid
+		}
+
+		if value, ok := v.request.PrivateMetadata[pkg.OrderPrivateMetadataTrialPrice]; ok {
+			trialPrice, err := strconv.ParseFloat(value, 64)
+
+			if err != nil || trialPrice < subscriptionTrialMinPrice {
+				return orderErrorRecurringTrialPriceInvalid
+			}
+		}
+	}
+
 	v.checked.recurringPeriod = v.request.RecurringPeriod
 	v.checked.recurringInterval = int32(1)
 	v.checked.recurringDateEnd = dateEnd.Format(billingpb.FilterDateFormat)
@@ -3052,11 +3335,22 @@ func (v *PaymentFormProcessor) processRenderFormPaymentMethods(
 		return nil, orderErrorUnknown
 	}
 
+	rules, err := v.service.paymentMethodRuleRepository.GetByProjectId(ctx, v.order.GetProjectId())
+
+	if err != nil {
+		zap.S().Errorw("GetByProjectId failed", "error", err, "order_id", v.order.Id, "order_uuid", v.order.Uuid)
+		return nil, orderErrorUnknown
+	}
+
 	for _, pm := range paymentMethods {
 		if pm.IsActive == false {
 			continue
 		}
 
+		if !isPaymentMethodAllowedByRules(rules, pm.Id, v.order) {
+			continue
+		}
+
 		ps, err := v.service.paymentSystemRepository.GetById(ctx, pm.PaymentSystemId)
 
 		if err != nil {
@@ -3370,6 +3664,28 @@ func (v *PaymentCreateProcessor) processPaymentFormData(ctx context.Context) err
 		return orderCountryPaymentRestrictedError
 	}
 
+	rules, err := v.service.paymentMethodRuleRepository.GetByProjectId(ctx, order.GetProjectId())
+
+	if err != nil {
+		return orderErrorUnknown
+	}
+
+	if !isPaymentMethodAllowedByRules(rules, pm.Id, order) {
+		return orderErrorPaymentMethodNotAllowed
+	}
+
+	if err = v.service.validateOrderPromoCode(ctx, order); err != nil {
+		return err
+	}
+
+	if err = v.service.validateOrderWallet(ctx, order); err != nil {
+		return err
+	}
+
+	if err = v.service.validateOrderGiftCard(ctx, order); err != nil {
+		return err
+	}
+
 	var customer *billingpb.Customer
 
 	if helper.IsIdentified(order.User.Id) == true {
@@ -3905,6 +4221,8 @@ func (s *Service) ProcessOrderKeyProducts(ctx context.Context, order *billingpb.
 
 	order.Items = items
 
+	s.reapplyOrderPromoCode(ctx, order)
+
 	return platforms, nil
 }
 
@@ -3950,6 +4268,8 @@ func (s *Service) ProcessOrderProducts(ctx context.Context, order *billingpb.Ord
 
 	order.Items = items
 
+	s.reapplyOrderPromoCode(ctx, order)
+
 	return nil
 }
 
@@ -4592,6 +4912,24 @@ func (s *Service) getOrderReceiptObject(ctx context.Context, order *billingpb.Or
 		items[i] = &billingpb.OrderReceiptItem{Name: item.Name, Price: price}
 	}
 
+	promoCode, discount := getOrderDiscount(order)
+
+	if discount > 0 && len(items) > 0 {
+		price, err := s.formatter.FormatCurrency(DefaultLanguage, discount, order.Currency)
+
+		if err != nil {
+			zap.L().Error(
+				orderErrorDuringFormattingCurrency.Message,
+				zap.Float64("price", discount),
+				zap.String("locale", DefaultLanguage),
+				zap.String("currency", order.Currency),
+			)
+			return nil, orderErrorDuringFormattingCurrency
+		}
+
+		items = append(items, &billingpb.OrderReceiptItem{Name: "Promo code " + promoCode, Price: "-" + price})
+	}
+
 	var platformName = ""
 
 	if platform, ok := availablePlatforms[order.PlatformId]; ok {
@@ -4882,7 +5220,14 @@ func (s *Service) getOrderPriceGroup(ctx context.Context, order *billingpb.Order
 }
 
 func (s *Service) setOrderChargeAmountAndCurrency(ctx context.Context, order *billingpb.Order) (err error) {
-	order.ChargeAmount = order.TotalPaymentAmount
+	// part of the order amount paid by the customer wallet and the gift card isn't charged by the payment system
+	amount := order.TotalPaymentAmount
+
+	if prepaidAmount := getOrderPrepaidAmount(order); prepaidAmount > 0 {
+		amount = s.FormatAmount(math.Max(amount-prepaidAmount, 0), order.Currency)
+	}
+
+	order.ChargeAmount = amount
 	order.ChargeCurrency = order.Currency
 
 	if order.PaymentRequisites == nil {
@@ -4945,7 +5290,7 @@ func (s *Service) setOrderChargeAmountAndCurrency(ctx context.Context, order *bi
 		From:              order.Currency,
 		To:                binCountry.Currency,
 		RateType:          currenciespb.RateTypePaysuper,
-		Amount:            order.TotalPaymentAmount,
+		Amount:            amount,
 		ExchangeDirection: currenciespb.ExchangeDirectionSell,
 	}
 
@@ -5158,7 +5503,7 @@ func (s *Service) addRecurringSubscription(
 		IsActive:    false,
 		Period:      order.RecurringSettings.Period,
 		ExpireAt:    tsExpireAt,
-		Amount:      order.ChargeAmount,
+		Amount:      payment_system.GetRecurringPlanAmount(order),
 		Currency:    order.ChargeCurrency,
 		ProjectName: order.Project.Name,
 	}
@@ -5210,6 +5555,15 @@ func (s *Service) addRecurringSubscription(
 		return nil, "", orderErrorRecurringUnableToUpdate
 	}
 
+	if err = s.addSubscriptionTrial(ctx, order, subscription); err != nil {
+		zap.L().Error(
+			"Unable to add recurring subscription trial",
+			zap.Error(err),
+			zap.Any("subscription", subscription),
+		)
+		return nil, "", orderErrorRecurringUnableToUpdate
+	}
+
 	return subscription, url, nil
 }
 
@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/paysuper/paysuper-billing-server/internal/payment_system"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	tools "github.com/paysuper/paysuper-tools/number"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// CaptureOrder captures funds of the order payment which was only authorized by payment system (orders created
+// with the manual capture mode). Amount of the capture can be less than the authorized amount, in this case amounts
+// of the order are decreased proportionally. Accounting entries of the order are created at the capture time.
+func (s *Service) CaptureOrder(
+	ctx context.Context,
+	req *pkg.CaptureOrderRequest,
+	rsp *pkg.OrderPaymentResponse,
+) error {
+	order, h, err := s.getAuthorizedOrder(ctx, req.OrderId, req.MerchantId)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseError); ok {
+			rsp.Status = e.Status
+			rsp.Message = e.Message
+			return nil
+		}
+		return err
+	}
+
+	authorizedAmount := order.ChargeAmount
+	amount := req.Amount
+
+	if amount <= 0 {
+		amount = authorizedAmount
+	}
+
+	if amount > authorizedAmount {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = orderErrorCaptureAmountInvalid
+		return nil
+	}
+
+	if !s.lockAuthorizedOrder(ctx, order, rsp) {
+		return nil
+	}
+
+	if err = h.Capture(order, amount); err != nil {
+		s.unlockAuthorizedOrder(ctx, order)
+
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "Capture"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+			zap.Float64("amount", amount),
+		)
+
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = orderErrorCaptureFailed
+
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Message = e
+		}
+
+		return nil
+	}
+
+	if amount < authorizedAmount {
+		setOrderCapturedAmount(order, amount)
+	}
+
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataPaymentUpdate)
+
+	s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypePaymentCaptured, map[string]string{
+		pkg.OrderHistoryFieldAuthorizedAmount: fmt.Sprintf("%.2f", authorizedAmount),
+		pkg.OrderHistoryFieldCapturedAmount:   fmt.Sprintf("%.2f", amount),
+	})
+
+	if err = s.updateOrder(ctx, order); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = orderErrorUnknown
+		return nil
+	}
+
+	if err = s.onPaymentNotify(ctx, order); err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "onPaymentNotify"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+		)
+
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = orderErrorUnknown
+		return nil
+	}
+
+	s.sendMailWithReceipt(ctx, order)
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = order
+
+	return nil
+}
+
+// VoidOrder cancels the authorized payment of the order, held funds are released on the customer account.
+func (s *Service) VoidOrder(
+	ctx context.Context,
+	req *pkg.VoidOrderRequest,
+	rsp *pkg.OrderPaymentResponse,
+) error {
+	order, h, err := s.getAuthorizedOrder(ctx, req.OrderId, req.MerchantId)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseError); ok {
+			rsp.Status = e.Status
+			rsp.Message = e.Message
+			return nil
+		}
+		return err
+	}
+
+	if !s.lockAuthorizedOrder(ctx, order, rsp) {
+		return nil
+	}
+
+	if err = h.Void(order); err != nil {
+		s.unlockAuthorizedOrder(ctx, order)
+
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "Void"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+		)
+
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = orderErrorVoidFailed
+
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Message = e
+		}
+
+		return nil
+	}
+
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataPaymentUpdate)
+
+	s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypePaymentVoided, map[string]string{
+		pkg.OrderHistoryFieldAuthorizedAmount: fmt.Sprintf("%.2f", order.ChargeAmount),
+	})
+
+	if err = s.updateOrder(ctx, order); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = orderErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = order
+
+	return nil
+}
+
+// SetProjectCaptureSettings saves the capture mode of payments of the project orders. Only orders created after
+// the change get the new capture mode.
+func (s *Service) SetProjectCaptureSettings(
+	ctx context.Context,
+	req *pkg.SetProjectCaptureSettingsRequest,
+	rsp *pkg.ProjectCaptureSettingsResponse,
+) error {
+	project, err := s.project.GetById(ctx, req.ProjectId)
+
+	if err != nil || project.MerchantId != req.MerchantId {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = projectErrorNotFound
+		return nil
+	}
+
+	settings, err := s.projectCaptureSettingsRepository.GetByProjectId(ctx, req.ProjectId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = orderErrorUnknown
+		return nil
+	}
+
+	if settings == nil {
+		settings = &intPkg.ProjectCaptureSettings{}
+		settings.ProjectId, _ = primitive.ObjectIDFromHex(req.ProjectId)
+	}
+
+	settings.ManualCapture = req.ManualCapture
+
+	if err = s.projectCaptureSettingsRepository.Upsert(ctx, settings); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = orderErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getProjectCaptureSettingsMessage(settings)
+
+	return nil
+}
+
+// GetProjectCaptureSettings returns the capture mode of payments of the project orders, the automatic capture is
+// returned for the project without own settings.
+func (s *Service) GetProjectCaptureSettings(
+	ctx context.Context,
+	req *pkg.GetProjectCaptureSettingsRequest,
+	rsp *pkg.ProjectCaptureSettingsResponse,
+) error {
+	project, err := s.project.GetById(ctx, req.ProjectId)
+
+	if err != nil || project.MerchantId != req.MerchantId {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = projectErrorNotFound
+		return nil
+	}
+
+	settings, err := s.projectCaptureSettingsRepository.GetByProjectId(ctx, req.ProjectId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = orderErrorUnknown
+		return nil
+	}
+
+	if settings == nil {
+		settings = &intPkg.ProjectCaptureSettings{}
+		settings.ProjectId, _ = primitive.ObjectIDFromHex(req.ProjectId)
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getProjectCaptureSettingsMessage(settings)
+
+	return nil
+}
+
+// isProjectManualCapture checks that payments of the project orders must be only authorized by payment system.
+func (s *Service) isProjectManualCapture(ctx context.Context, projectId string) (bool, error) {
+	settings, err := s.projectCaptureSettingsRepository.GetByProjectId(ctx, projectId)
+
+	if err != nil {
+		return false, err
+	}
+
+	return settings != nil && settings.ManualCapture, nil
+}
+
+func (s *Service) getAuthorizedOrder(
+	ctx context.Context,
+	uuid, merchantId string,
+) (*billingpb.Order, payment_system.PaymentSystemInterface, error) {
+	order, err := s.orderRepository.GetByUuidAndMerchantId(ctx, uuid, merchantId)
+
+	if err != nil {
+		return nil, nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusNotFound, orderErrorNotFound)
+	}
+
+	if order.PrivateStatus != pkg.OrderStatusPaymentSystemAuthorized {
+		return nil, nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, orderErrorPaymentNotAuthorized)
+	}
+
+	h, err := s.paymentSystemGateway.GetGateway(order.PaymentMethod.Handler)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "GetGateway"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+			zap.String(pkg.LogFieldHandler, order.PaymentMethod.Handler),
+		)
+		return nil, nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, orderErrorPaymentSystemInactive)
+	}
+
+	return order, h, nil
+}
+
+// lockAuthorizedOrder marks the authorized order as being captured or voided by the conditional update of the order,
+// so the payment of the order is changed by one of the concurrent capture and void requests only and the status
+// set by the payment system is saved only if the order is still authorized.
+func (s *Service) lockAuthorizedOrder(ctx context.Context, order *billingpb.Order, rsp *pkg.OrderPaymentResponse) bool {
+	oid, _ := primitive.ObjectIDFromHex(order.Id)
+	field := "private_metadata." + pkg.OrderPrivateMetadataPaymentUpdate
+	filter := bson.M{
+		"_id":            oid,
+		"private_status": pkg.OrderStatusPaymentSystemAuthorized,
+		field:            bson.M{"$exists": false},
+	}
+	ok, err := s.orderRepository.UpdateOneBy(ctx, filter, bson.M{"$set": bson.M{field: "1"}})
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = orderErrorUnknown
+		return false
+	}
+
+	if !ok {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = orderErrorPaymentNotAuthorized
+		return false
+	}
+
+	if order.PrivateMetadata == nil {
+		order.PrivateMetadata = make(map[string]string)
+	}
+
+	order.PrivateMetadata[pkg.OrderPrivateMetadataPaymentUpdate] = "1"
+
+	return true
+}
+
+// unlockAuthorizedOrder removes the mark of the order payment change after the payment system failed to change it,
+// so the order can be captured or voided again.
+func (s *Service) unlockAuthorizedOrder(ctx context.Context, order *billingpb.Order) {
+	ok, err := s.unsetOrderPrivateMetadataFlag(ctx, order, pkg.OrderPrivateMetadataPaymentUpdate)
+
+	if err != nil || !ok {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "unsetOrderPrivateMetadataFlag"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+		)
+	}
+}
+
+func (s *Service) addOrderPaymentHistory(ctx context.Context, order *billingpb.Order, kind string, data map[string]string) {
+	history := &intPkg.OrderHistory{
+		Type: kind,
+		Data: data,
+	}
+	history.OrderId, _ = primitive.ObjectIDFromHex(order.Id)
+
+	if err := s.orderHistoryRepository.Insert(ctx, history); err != nil {
+		zap.L().Error(
+			"unable to record order payment change to order history",
+			zap.Error(err),
+			zap.Any("history", history),
+		)
+	}
+}
+
+func getProjectCaptureSettingsMessage(settings *intPkg.ProjectCaptureSettings) *pkg.ProjectCaptureSettings {
+	return &pkg.ProjectCaptureSettings{
+		ProjectId:     settings.ProjectId.Hex(),
+		ManualCapture: settings.ManualCapture,
+		UpdatedAt:     getTimestampProto(settings.UpdatedAt),
+	}
+}
+
+// setOrderCapturedAmount decreases amounts of the order proportionally to the captured part of the authorized amount,
+// so accounting entries are calculated by the amount which is really charged from customer.
+func setOrderCapturedAmount(order *billingpb.Order, amount float64) {
+	// parts of the order prepaid by the customer wallet and the gift card are charged in full, so the whole order
+	// is decreased by the part of the authorized amount which isn't captured
+	prepaidAmount := getOrderPrepaidChargeAmount(order)
+	ratio := (amount + prepaidAmount) / (order.ChargeAmount + prepaidAmount)
+
+	order.ChargeAmount = amount
+	order.TotalPaymentAmount = tools.FormatAmount(order.TotalPaymentAmount * ratio)
+	order.OrderAmount = tools.FormatAmount(order.OrderAmount * ratio)
+
+	if order.Tax != nil {
+		order.Tax.Amount = tools.FormatAmount(order.Tax.Amount * ratio)
+	}
+}
@@ -0,0 +1,404 @@
+package service
+
+import (
+	"context"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/google/uuid"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	tools "github.com/paysuper/paysuper-tools/number"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type OrderPaymentTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+	cookie        string
+}
+
+func Test_OrderPayment(t *testing.T) {
+	suite.Run(t, new(OrderPaymentTestSuite))
+}
+
+func (suite *OrderPaymentTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	var customer *billingpb.Customer
+	suite.merchant, suite.project, suite.paymentMethod, _, customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.cookie, err = suite.service.generateBrowserCookie(&BrowserCookieCustomer{
+		CustomerId: customer.Id,
+		Ip:         "127.0.0.1",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	})
+
+	if err != nil {
+		suite.FailNow("Generate browser cookie failed", "%v", err)
+	}
+}
+
+func (suite *OrderPaymentTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *OrderPaymentTestSuite) setManualCapture(manualCapture bool) {
+	req := &pkg.SetProjectCaptureSettingsRequest{
+		ProjectId:     suite.project.Id,
+		MerchantId:    suite.merchant.Id,
+		ManualCapture: manualCapture,
+	}
+	rsp := &pkg.ProjectCaptureSettingsResponse{}
+	err := suite.service.SetProjectCaptureSettings(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+}
+
+func (suite *OrderPaymentTestSuite) createOrder(privateMetadata map[string]string) *billingpb.Order {
+	req := &billingpb.OrderCreateRequest{
+		Type:        pkg.OrderType_simple,
+		ProjectId:   suite.project.Id,
+		Amount:      100,
+		Currency:    "RUB",
+		Account:     "unit test",
+		Description: "unit test",
+		User: &billingpb.OrderUser{
+			Id:    primitive.NewObjectID().Hex(),
+			Uuid:  uuid.New().String(),
+			Email: "test@unit.unit",
+			Ip:    "127.0.0.1",
+			Address: &billingpb.OrderBillingAddress{
+				Country: "RU",
+			},
+		},
+		PrivateMetadata: privateMetadata,
+	}
+
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	return rsp.Item
+}
+
+// createAuthorizedOrder creates the order of the project with manual capture mode and marks it as authorized by
+// payment system the same way as the authorization callback does.
+func (suite *OrderPaymentTestSuite) createAuthorizedOrder() *billingpb.Order {
+	suite.setManualCapture(true)
+	item := suite.createOrder(nil)
+
+	req1 := &billingpb.PaymentCreateRequest{
+		Data: map[string]string{
+			billingpb.PaymentCreateFieldOrderId:         item.Uuid,
+			billingpb.PaymentCreateFieldPaymentMethodId: suite.paymentMethod.Id,
+			billingpb.PaymentCreateFieldEmail:           "test@unit.unit",
+			billingpb.PaymentCreateFieldPan:             "4000000000000002",
+			billingpb.PaymentCreateFieldCvv:             "123",
+			billingpb.PaymentCreateFieldMonth:           "02",
+			billingpb.PaymentCreateFieldYear:            time.Now().AddDate(1, 0, 0).Format("2006"),
+			billingpb.PaymentCreateFieldHolder:          "MR. CARD HOLDER",
+		},
+		Ip:     "127.0.0.1",
+		Cookie: suite.cookie,
+	}
+
+	rsp1 := &billingpb.PaymentCreateResponse{}
+	err := suite.service.PaymentCreateProcess(context.TODO(), req1, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+
+	order, err := suite.service.orderRepository.GetById(context.TODO(), item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemCreate), order.PrivateStatus)
+
+	order.PrivateStatus = pkg.OrderStatusPaymentSystemAuthorized
+	order.Transaction = primitive.NewObjectID().Hex()
+	err = suite.service.orderRepository.Update(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	return order
+}
+
+func (suite *OrderPaymentTestSuite) getAccountingEntries(order *billingpb.Order) []*billingpb.AccountingEntry {
+	entries, err := suite.service.accountingRepository.FindBySource(context.TODO(), order.Id, repository.CollectionOrder)
+	assert.NoError(suite.T(), err)
+
+	return entries
+}
+
+func (suite *OrderPaymentTestSuite) TestOrderPayment_CaptureOrder_Ok() {
+	order := suite.createAuthorizedOrder()
+	assert.Empty(suite.T(), suite.getAccountingEntries(order))
+
+	req := &pkg.CaptureOrderRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.merchant.Id,
+	}
+	rsp := &pkg.OrderPaymentResponse{}
+	err := suite.service.CaptureOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), int32(recurringpb.OrderStatusPaymentSystemComplete), rsp.Item.PrivateStatus)
+	assert.Equal(suite.T(), order.ChargeAmount, rsp.Item.ChargeAmount)
+	assert.NotEmpty(suite.T(), suite.getAccountingEntries(order))
+
+	history, err := suite.service.orderHistoryRepository.FindByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), history, 1)
+	assert.Equal(suite.T(), pkg.OrderHistoryTypePaymentCaptured, history[0].Type)
+}
+
+func (suite *OrderPaymentTestSuite) TestOrderPayment_CaptureOrder_Partial_Ok() {
+	order := suite.createAuthorizedOrder()
+	amount := tools.FormatAmount(order.ChargeAmount / 2)
+
+	req := &pkg.CaptureOrderRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.merchant.Id,
+		Amount:     amount,
+	}
+	rsp := &pkg.OrderPaymentResponse{}
+	err := suite.service.CaptureOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), amount, rsp.Item.ChargeAmount)
+	assert.Equal(suite.T(), tools.FormatAmount(order.TotalPaymentAmount*amount/order.ChargeAmount), rsp.Item.TotalPaymentAmount)
+	assert.NotEmpty(suite.T(), suite.getAccountingEntries(order))
+
+	order, err = suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), amount, order.ChargeAmount)
+}
+
+func (suite *OrderPaymentTestSuite) TestOrderPayment_CaptureOrder_AmountGreaterThanAuthorized_Error() {
+	order := suite.createAuthorizedOrder()
+
+	req := &pkg.CaptureOrderRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.merchant.Id,
+		Amount:     order.ChargeAmount + 1,
+	}
+	rsp := &pkg.OrderPaymentResponse{}
+	err := suite.service.CaptureOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), orderErrorCaptureAmountInvalid, rsp.Message)
+	assert.Empty(suite.T(), suite.getAccountingEntries(order))
+}
+
+func (suite *OrderPaymentTestSuite) TestOrderPayment_CaptureOrder_AlreadyCaptured_Error() {
+	order := suite.createAuthorizedOrder()
+
+	req := &pkg.CaptureOrderRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.merchant.Id,
+	}
+	rsp := &pkg.OrderPaymentResponse{}
+	err := suite.service.CaptureOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	rsp = &pkg.OrderPaymentResponse{}
+	err = suite.service.CaptureOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), orderErrorPaymentNotAuthorized, rsp.Message)
+}
+
+func (suite *OrderPaymentTestSuite) TestOrderPayment_CaptureOrder_AnotherMerchant_NotFound() {
+	order := suite.createAuthorizedOrder()
+
+	req := &pkg.CaptureOrderRequest{
+		OrderId:    order.Uuid,
+		MerchantId: primitive.NewObjectID().Hex(),
+	}
+	rsp := &pkg.OrderPaymentResponse{}
+	err := suite.service.CaptureOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), orderErrorNotFound, rsp.Message)
+}
+
+func (suite *OrderPaymentTestSuite) TestOrderPayment_VoidOrder_Ok() {
+	order := suite.createAuthorizedOrder()
+
+	req := &pkg.VoidOrderRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.merchant.Id,
+	}
+	rsp := &pkg.OrderPaymentResponse{}
+	err := suite.service.VoidOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), pkg.OrderStatusPaymentSystemVoided, rsp.Item.PrivateStatus)
+	assert.NotNil(suite.T(), rsp.Item.CanceledAt)
+	assert.Empty(suite.T(), suite.getAccountingEntries(order))
+
+	rsp = &pkg.OrderPaymentResponse{}
+	err = suite.service.CaptureOrder(context.TODO(), &pkg.CaptureOrderRequest{OrderId: order.Uuid, MerchantId: suite.merchant.Id}, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), orderErrorPaymentNotAuthorized, rsp.Message)
+}
+
+func (suite *OrderPaymentTestSuite) TestOrderPayment_VoidOrder_CaptureInProgress_Error() {
+	order := suite.createAuthorizedOrder()
+
+	// concurrent capture request locked the order before calling the payment system
+	rsp := &pkg.OrderPaymentResponse{}
+	assert.True(suite.T(), suite.service.lockAuthorizedOrder(context.TODO(), order, rsp))
+
+	req := &pkg.VoidOrderRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.merchant.Id,
+	}
+	err := suite.service.VoidOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), orderErrorPaymentNotAuthorized, rsp.Message)
+
+	order, err = suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.OrderStatusPaymentSystemAuthorized, order.PrivateStatus)
+
+	suite.service.unlockAuthorizedOrder(context.TODO(), order)
+
+	rsp = &pkg.OrderPaymentResponse{}
+	err = suite.service.VoidOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.NotContains(suite.T(), rsp.Item.PrivateMetadata, pkg.OrderPrivateMetadataPaymentUpdate)
+}
+
+func (suite *OrderPaymentTestSuite) TestOrderPayment_ProjectCaptureSettings_Ok() {
+	req := &pkg.GetProjectCaptureSettingsRequest{ProjectId: suite.project.Id, MerchantId: suite.merchant.Id}
+	rsp := &pkg.ProjectCaptureSettingsResponse{}
+	err := suite.service.GetProjectCaptureSettings(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.False(suite.T(), rsp.Item.ManualCapture)
+
+	suite.setManualCapture(true)
+
+	rsp = &pkg.ProjectCaptureSettingsResponse{}
+	err = suite.service.GetProjectCaptureSettings(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.True(suite.T(), rsp.Item.ManualCapture)
+	assert.Equal(suite.T(), suite.project.Id, rsp.Item.ProjectId)
+}
+
+func (suite *OrderPaymentTestSuite) TestOrderPayment_ProjectCaptureSettings_AnotherMerchant_NotFound() {
+	req := &pkg.SetProjectCaptureSettingsRequest{
+		ProjectId:     suite.project.Id,
+		MerchantId:    primitive.NewObjectID().Hex(),
+		ManualCapture: true,
+	}
+	rsp := &pkg.ProjectCaptureSettingsResponse{}
+	err := suite.service.SetProjectCaptureSettings(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), projectErrorNotFound, rsp.Message)
+}
+
+func (suite *OrderPaymentTestSuite) TestOrderPayment_OrderCreate_CaptureModeFromProject() {
+	suite.setManualCapture(true)
+	order := suite.createOrder(nil)
+	assert.Equal(suite.T(), pkg.OrderCaptureModeManual, order.PrivateMetadata[pkg.OrderPrivateMetadataCaptureMode])
+}
+
+func (suite *OrderPaymentTestSuite) TestOrderPayment_OrderCreate_CaptureModeFromRequest_Ignored() {
+	order := suite.createOrder(map[string]string{
+		pkg.OrderPrivateMetadataCaptureMode: pkg.OrderCaptureModeManual,
+		"some_field":                        "some_value",
+	})
+	assert.NotContains(suite.T(), order.PrivateMetadata, pkg.OrderPrivateMetadataCaptureMode)
+	assert.Equal(suite.T(), "some_value", order.PrivateMetadata["some_field"])
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"sort"
+	"time"
+)
+
+const (
+	orderReviewDefaultLimit = int64(100)
+
+	orderReviewApprovedStatus = "PAYMENT_REVIEW_APPROVED"
+	orderReviewRejectedStatus = "PAYMENT_REVIEW_REJECTED"
+)
+
+var (
+	orderReviewErrorUnknown         = errors.NewBillingServerErrorMsg("rv000001", "order review can't be processed. try request later")
+	orderReviewErrorNotFound        = errors.NewBillingServerErrorMsg("rv000002", "order review with specified data not found")
+	orderReviewErrorAlreadyResolved = errors.NewBillingServerErrorMsg("rv000003", "order review already resolved")
+	orderReviewErrorExpired         = errors.NewBillingServerErrorMsg("rv000004", "order review has expired")
+	orderReviewErrorOrderNotFound   = errors.NewBillingServerErrorMsg("rv000005", "order for review not found")
+)
+
+// ListHeldOrders returns reviews of orders held by the fraud screening, the oldest reviews go first.
+func (s *Service) ListHeldOrders(
+	ctx context.Context,
+	req *pkg.ListHeldOrdersRequest,
+	rsp *pkg.ListHeldOrdersResponse,
+) error {
+	if req.Limit <= 0 {
+		req.Limit = orderReviewDefaultLimit
+	}
+
+	if req.Status == "" {
+		req.Status = pkg.OrderReviewStatusHeld
+	}
+
+	count, err := s.orderReviewRepository.FindCount(ctx, req.MerchantId, req.Status)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = orderReviewErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Count = count
+	rsp.Items = []*pkg.OrderReview{}
+
+	if count <= 0 {
+		return nil
+	}
+
+	reviews, err := s.orderReviewRepository.Find(ctx, req.MerchantId, req.Status, req.Offset, req.Limit)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = orderReviewErrorUnknown
+		return nil
+	}
+
+	for _, review := range reviews {
+		rsp.Items = append(rsp.Items, getOrderReviewMessage(review))
+	}
+
+	return nil
+}
+
+// ApproveHeldOrder releases the payment held by the fraud screening. Payment data isn't stored while the order is
+// held, so the payment form is notified to resend the payment which goes to the payment system without screening.
+func (s *Service) ApproveHeldOrder(
+	ctx context.Context,
+	req *pkg.ResolveHeldOrderRequest,
+	rsp *pkg.OrderReviewResponse,
+) error {
+	return s.resolveHeldOrderByReviewer(ctx, req, rsp, pkg.OrderReviewStatusApproved)
+}
+
+// RejectHeldOrder declines the payment held by the fraud screening, keys reserved for the order are released.
+func (s *Service) RejectHeldOrder(
+	ctx context.Context,
+	req *pkg.ResolveHeldOrderRequest,
+	rsp *pkg.OrderReviewResponse,
+) error {
+	return s.resolveHeldOrderByReviewer(ctx, req, rsp, pkg.OrderReviewStatusRejected)
+}
+
+// ExpireHeldOrders cancels orders which weren't reviewed before the review timeout, keys reserved for the orders
+// are released.
+func (s *Service) ExpireHeldOrders(ctx context.Context) error {
+	reviews, err := s.orderReviewRepository.FindExpired(ctx, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	for _, review := range reviews {
+		if err = s.resolveHeldOrder(ctx, review, pkg.OrderReviewStatusExpired); err != nil {
+			zap.L().Error(
+				"expired order review resolving failed",
+				zap.Error(err),
+				zap.String("review_id", review.Id.Hex()),
+			)
+		}
+	}
+
+	return nil
+}
+
+// holdOrderForReview stops the payment of the order which is sent to the manual review by the fraud screening.
+func (s *Service) holdOrderForReview(ctx context.Context, order *billingpb.Order, screening *fraudScreeningResult) error {
+	review := &intPkg.OrderReview{
+		OrderUuid:  order.Uuid,
+		Status:     pkg.OrderReviewStatusHeld,
+		FraudScore: screening.score,
+		FraudRules: make([]string, 0, len(screening.rules)),
+		Amount:     order.ChargeAmount,
+		Currency:   order.ChargeCurrency,
+		ExpiresAt:  time.Now().Add(s.cfg.GetFraudReviewTimeout()),
+	}
+	review.OrderId, _ = primitive.ObjectIDFromHex(order.Id)
+	review.MerchantId, _ = primitive.ObjectIDFromHex(order.GetMerchantId())
+	review.ProjectId, _ = primitive.ObjectIDFromHex(order.GetProjectId())
+
+	for name := range screening.rules {
+		review.FraudRules = append(review.FraudRules, name)
+	}
+
+	sort.Strings(review.FraudRules)
+
+	if err := s.orderReviewRepository.Insert(ctx, review); err != nil {
+		return err
+	}
+
+	if order.PrivateMetadata == nil {
+		order.PrivateMetadata = make(map[string]string)
+	}
+
+	order.PrivateStatus = pkg.OrderStatusReviewHeld
+	order.PrivateMetadata[pkg.OrderPrivateMetadataReviewStatus] = review.Status
+
+	s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypeReviewHeld, map[string]string{
+		pkg.OrderHistoryFieldReviewId: review.Id.Hex(),
+	})
+
+	return s.updateOrder(ctx, order)
+}
+
+func (s *Service) resolveHeldOrderByReviewer(
+	ctx context.Context,
+	req *pkg.ResolveHeldOrderRequest,
+	rsp *pkg.OrderReviewResponse,
+	status string,
+) error {
+	review, err := s.orderReviewRepository.GetById(ctx, req.ReviewId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = orderReviewErrorNotFound
+		return nil
+	}
+
+	if review.ExpiresAt.Before(time.Now()) && review.Status == pkg.OrderReviewStatusHeld {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = orderReviewErrorExpired
+		return nil
+	}
+
+	review.ReviewerId = req.ReviewerId
+	review.Reason = req.Reason
+
+	if err = s.resolveHeldOrder(ctx, review, status); err != nil {
+		if e, ok := err.(*billingpb.ResponseError); ok {
+			rsp.Status = e.Status
+			rsp.Message = e.Message
+			return nil
+		}
+		return err
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getOrderReviewMessage(review)
+
+	return nil
+}
+
+// resolveHeldOrder closes the review with the status and moves the held order to the status of the resolution:
+// approved order becomes new to accept the payment again, rejected order is declined and expired order is canceled.
+// The review is closed only while it is still held, so concurrent resolutions of the same review change the order once.
+func (s *Service) resolveHeldOrder(ctx context.Context, review *intPkg.OrderReview, status string) error {
+	if review.Status != pkg.OrderReviewStatusHeld {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, orderReviewErrorAlreadyResolved)
+	}
+
+	order, err := s.getOrderById(ctx, review.OrderId.Hex())
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusNotFound, orderReviewErrorOrderNotFound)
+	}
+
+	review.Status = status
+	review.ResolvedAt = time.Now()
+
+	isResolved, err := s.orderReviewRepository.Resolve(ctx, review)
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, orderReviewErrorUnknown)
+	}
+
+	if !isResolved {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, orderReviewErrorAlreadyResolved)
+	}
+
+	formStatus := orderReviewRejectedStatus
+
+	switch status {
+	case pkg.OrderReviewStatusApproved:
+		order.PrivateStatus = recurringpb.OrderStatusNew
+		formStatus = orderReviewApprovedStatus
+	case pkg.OrderReviewStatusRejected:
+		order.PrivateStatus = recurringpb.OrderStatusPaymentSystemDeclined
+		s.releaseOrderPromoCode(ctx, order)
+	default:
+		order.PrivateStatus = recurringpb.OrderStatusPaymentSystemCanceled
+		s.releaseOrderPromoCode(ctx, order)
+	}
+
+	if order.PrivateMetadata == nil {
+		order.PrivateMetadata = make(map[string]string)
+	}
+
+	order.PrivateMetadata[pkg.OrderPrivateMetadataReviewStatus] = status
+
+	s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypeReviewResolved, map[string]string{
+		pkg.OrderHistoryFieldReviewId:     review.Id.Hex(),
+		pkg.OrderHistoryFieldReviewStatus: status,
+		pkg.OrderHistoryFieldReviewerId:   review.ReviewerId,
+		pkg.OrderHistoryFieldReason:       review.Reason,
+	})
+
+	if err = s.updateOrder(ctx, order); err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, orderReviewErrorUnknown)
+	}
+
+	message := map[string]string{
+		billingpb.PaymentCreateFieldOrderId: order.Uuid,
+		"status":                            formStatus,
+	}
+	err = s.centrifugoPaymentForm.Publish(ctx, s.cfg.GetCentrifugoOrderChannel(order.Uuid), message)
+
+	if err != nil {
+		zap.L().Error(
+			"order review notification sending failed",
+			zap.Error(err),
+			zap.String("review_id", review.Id.Hex()),
+		)
+	}
+
+	return nil
+}
+
+func getOrderReviewMessage(review *intPkg.OrderReview) *pkg.OrderReview {
+	return &pkg.OrderReview{
+		Id:         review.Id.Hex(),
+		OrderId:    review.OrderUuid,
+		MerchantId: review.MerchantId.Hex(),
+		ProjectId:  review.ProjectId.Hex(),
+		Status:     review.Status,
+		FraudScore: int32(review.FraudScore),
+		FraudRules: review.FraudRules,
+		Amount:     review.Amount,
+		Currency:   review.Currency,
+		ReviewerId: review.ReviewerId,
+		Reason:     review.Reason,
+		ExpiresAt:  getTimestampProto(review.ExpiresAt),
+		CreatedAt:  getTimestampProto(review.CreatedAt),
+		UpdatedAt:  getTimestampProto(review.UpdatedAt),
+		ResolvedAt: getTimestampProto(review.ResolvedAt),
+	}
+}
@@ -0,0 +1,430 @@
+package service
+
+import (
+	"context"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/google/uuid"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type OrderReviewTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+	cookie        string
+}
+
+func Test_OrderReview(t *testing.T) {
+	suite.Run(t, new(OrderReviewTestSuite))
+}
+
+func (suite *OrderReviewTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	suite.service.cfg.FraudScreeningEnabled = true
+	suite.service.cfg.FraudReviewScore = 50
+	suite.service.cfg.FraudDeclineScore = 100
+	suite.service.cfg.FraudCustomerVelocityScore = 0
+	suite.service.cfg.FraudIpVelocityScore = 0
+	suite.service.cfg.FraudBinVelocityScore = 0
+	suite.service.cfg.FraudCountryMismatchScore = 0
+	suite.service.cfg.FraudEmailDomainScore = 60
+	suite.service.cfg.FraudEmailDomainBlacklist = []string{"review.test"}
+	suite.service.cfg.FraudReviewTimeout = 3600
+	suite.service.fraudRules = newFraudRules(suite.service)
+
+	var customer *billingpb.Customer
+	suite.merchant, suite.project, suite.paymentMethod, _, customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.cookie, err = suite.service.generateBrowserCookie(&BrowserCookieCustomer{
+		CustomerId: customer.Id,
+		Ip:         "127.0.0.1",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	})
+
+	if err != nil {
+		suite.FailNow("Generate browser cookie failed", "%v", err)
+	}
+}
+
+func (suite *OrderReviewTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+// createHeldOrder creates the order and sends the payment of the order which is held by the fraud screening.
+func (suite *OrderReviewTestSuite) createHeldOrder() (*billingpb.Order, *intPkg.OrderReview) {
+	req := &billingpb.OrderCreateRequest{
+		Type:        pkg.OrderType_simple,
+		ProjectId:   suite.project.Id,
+		Amount:      100,
+		Currency:    "RUB",
+		Account:     "unit test",
+		Description: "unit test",
+		User: &billingpb.OrderUser{
+			Id:    primitive.NewObjectID().Hex(),
+			Uuid:  uuid.New().String(),
+			Email: "test@review.test",
+			Ip:    "127.0.0.1",
+			Address: &billingpb.OrderBillingAddress{
+				Country: "RU",
+			},
+		},
+	}
+
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	rsp1 := suite.sendPayment(rsp.Item.Uuid)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+	assert.False(suite.T(), rsp1.NeedRedirect)
+	assert.Empty(suite.T(), rsp1.RedirectUrl)
+
+	order, err := suite.service.orderRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+
+	reviews, err := suite.service.orderReviewRepository.Find(context.TODO(), suite.merchant.Id, pkg.OrderReviewStatusHeld, 0, 100)
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), reviews)
+
+	return order, reviews[len(reviews)-1]
+}
+
+func (suite *OrderReviewTestSuite) sendPayment(orderUuid string) *billingpb.PaymentCreateResponse {
+	req := &billingpb.PaymentCreateRequest{
+		Data: map[string]string{
+			billingpb.PaymentCreateFieldOrderId:         orderUuid,
+			billingpb.PaymentCreateFieldPaymentMethodId: suite.paymentMethod.Id,
+			billingpb.PaymentCreateFieldEmail:           "test@review.test",
+			billingpb.PaymentCreateFieldPan:             "4000000000000002",
+			billingpb.PaymentCreateFieldCvv:             "123",
+			billingpb.PaymentCreateFieldMonth:           "02",
+			billingpb.PaymentCreateFieldYear:            time.Now().AddDate(1, 0, 0).Format("2006"),
+			billingpb.PaymentCreateFieldHolder:          "MR. CARD HOLDER",
+		},
+		Ip:     "127.0.0.1",
+		Cookie: suite.cookie,
+	}
+
+	rsp := &billingpb.PaymentCreateResponse{}
+	err := suite.service.PaymentCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *OrderReviewTestSuite) TestOrderReview_ReviewAction_OrderHeld() {
+	order, review := suite.createHeldOrder()
+	assert.Equal(suite.T(), pkg.OrderStatusReviewHeld, order.PrivateStatus)
+	assert.Equal(suite.T(), pkg.OrderReviewStatusHeld, order.PrivateMetadata[pkg.OrderPrivateMetadataReviewStatus])
+	assert.Equal(suite.T(), order.Uuid, review.OrderUuid)
+	assert.Equal(suite.T(), 60, review.FraudScore)
+	assert.Equal(suite.T(), []string{pkg.FraudRuleEmailDomain}, review.FraudRules)
+	assert.True(suite.T(), review.ExpiresAt.After(time.Now()))
+
+	rsp := suite.sendPayment(order.Uuid)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), orderErrorAlreadyProcessed, rsp.Message)
+}
+
+func (suite *OrderReviewTestSuite) TestOrderReview_ListHeldOrders_Ok() {
+	suite.createHeldOrder()
+	suite.createHeldOrder()
+
+	req := &pkg.ListHeldOrdersRequest{MerchantId: suite.merchant.Id}
+	rsp := &pkg.ListHeldOrdersResponse{}
+	err := suite.service.ListHeldOrders(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 2, rsp.Count)
+	assert.Len(suite.T(), rsp.Items, 2)
+	assert.Equal(suite.T(), pkg.OrderReviewStatusHeld, rsp.Items[0].Status)
+
+	req.Status = pkg.OrderReviewStatusApproved
+	err = suite.service.ListHeldOrders(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 0, rsp.Count)
+	assert.Empty(suite.T(), rsp.Items)
+}
+
+func (suite *OrderReviewTestSuite) TestOrderReview_ApproveHeldOrder_Ok() {
+	order, review := suite.createHeldOrder()
+
+	req := &pkg.ResolveHeldOrderRequest{
+		ReviewId:   review.Id.Hex(),
+		ReviewerId: primitive.NewObjectID().Hex(),
+		Reason:     "customer confirmed the payment",
+	}
+	rsp := &pkg.OrderReviewResponse{}
+	err := suite.service.ApproveHeldOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Equal(suite.T(), pkg.OrderReviewStatusApproved, rsp.Item.Status)
+	assert.Equal(suite.T(), req.ReviewerId, rsp.Item.ReviewerId)
+	assert.Equal(suite.T(), req.Reason, rsp.Item.Reason)
+	assert.NotNil(suite.T(), rsp.Item.ResolvedAt)
+
+	order, err = suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), recurringpb.OrderStatusNew, order.PrivateStatus)
+	assert.Equal(suite.T(), pkg.OrderReviewStatusApproved, order.PrivateMetadata[pkg.OrderPrivateMetadataReviewStatus])
+
+	rsp1 := suite.sendPayment(order.Uuid)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+	assert.True(suite.T(), rsp1.NeedRedirect)
+	assert.NotEmpty(suite.T(), rsp1.RedirectUrl)
+}
+
+func (suite *OrderReviewTestSuite) TestOrderReview_RejectHeldOrder_Ok() {
+	order, review := suite.createHeldOrder()
+
+	req := &pkg.ResolveHeldOrderRequest{
+		ReviewId:   review.Id.Hex(),
+		ReviewerId: primitive.NewObjectID().Hex(),
+		Reason:     "stolen card",
+	}
+	rsp := &pkg.OrderReviewResponse{}
+	err := suite.service.RejectHeldOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Equal(suite.T(), pkg.OrderReviewStatusRejected, rsp.Item.Status)
+
+	order, err = suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), recurringpb.OrderStatusPaymentSystemDeclined, order.PrivateStatus)
+	assert.Equal(suite.T(), recurringpb.OrderPublicStatusRejected, order.GetPublicStatus())
+
+	history, err := suite.service.orderHistoryRepository.FindByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.OrderHistoryTypeReviewResolved, history[len(history)-1].Type)
+	assert.Equal(suite.T(), req.ReviewerId, history[len(history)-1].Data[pkg.OrderHistoryFieldReviewerId])
+	assert.Equal(suite.T(), req.Reason, history[len(history)-1].Data[pkg.OrderHistoryFieldReason])
+
+	err = suite.service.ApproveHeldOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), orderReviewErrorAlreadyResolved, rsp.Message)
+}
+
+func (suite *OrderReviewTestSuite) TestOrderReview_RejectHeldOrder_KeysReleased() {
+	order, review := suite.createHeldOrder()
+
+	order.ProductType = pkg.OrderType_key
+	order.Keys = []string{primitive.NewObjectID().Hex()}
+	err := suite.service.orderRepository.Update(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	keyRepository := &mocks.KeyRepositoryInterface{}
+	keyRepository.On("CancelById", mock.Anything, order.Keys[0]).Return(&billingpb.Key{}, nil)
+	suite.service.keyRepository = keyRepository
+
+	req := &pkg.ResolveHeldOrderRequest{
+		ReviewId:   review.Id.Hex(),
+		ReviewerId: primitive.NewObjectID().Hex(),
+		Reason:     "stolen card",
+	}
+	rsp := &pkg.OrderReviewResponse{}
+	err = suite.service.RejectHeldOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	keyRepository.AssertCalled(suite.T(), "CancelById", mock.Anything, order.Keys[0])
+}
+
+func (suite *OrderReviewTestSuite) TestOrderReview_ResolveResolvedReviewCopy_Error() {
+	order, review := suite.createHeldOrder()
+	staleReview := *review
+
+	req := &pkg.ResolveHeldOrderRequest{
+		ReviewId:   review.Id.Hex(),
+		ReviewerId: primitive.NewObjectID().Hex(),
+		Reason:     "customer confirmed the payment",
+	}
+	rsp := &pkg.OrderReviewResponse{}
+	err := suite.service.ApproveHeldOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	err = suite.service.resolveHeldOrder(context.TODO(), &staleReview, pkg.OrderReviewStatusRejected)
+	assert.Error(suite.T(), err)
+	e, ok := err.(*billingpb.ResponseError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, e.Status)
+	assert.Equal(suite.T(), orderReviewErrorAlreadyResolved, e.Message)
+
+	review, err = suite.service.orderReviewRepository.GetById(context.TODO(), review.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.OrderReviewStatusApproved, review.Status)
+
+	order, err = suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), recurringpb.OrderStatusNew, order.PrivateStatus)
+}
+
+func (suite *OrderReviewTestSuite) TestOrderReview_RejectHeldOrder_PromoCodeReleased() {
+	order, review := suite.createHeldOrder()
+
+	promoCode := &intPkg.PromoCode{
+		Id:               primitive.NewObjectID(),
+		ProjectId:        review.ProjectId,
+		Code:             "REVIEW",
+		Type:             pkg.PromoCodeTypePercent,
+		Percent:          10,
+		MaxUses:          1,
+		ReservedOrderIds: []string{order.Id},
+		Reservations:     []*intPkg.PromoCodeUse{{OrderId: order.Id, CustomerId: order.User.Id}},
+		IsActive:         true,
+	}
+	err := suite.service.promoCodeRepository.Insert(context.TODO(), promoCode)
+	assert.NoError(suite.T(), err)
+
+	order.PrivateMetadata[pkg.OrderPrivateMetadataPromoCodeId] = promoCode.Id.Hex()
+	err = suite.service.orderRepository.Update(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	req := &pkg.ResolveHeldOrderRequest{
+		ReviewId:   review.Id.Hex(),
+		ReviewerId: primitive.NewObjectID().Hex(),
+		Reason:     "stolen card",
+	}
+	rsp := &pkg.OrderReviewResponse{}
+	err = suite.service.RejectHeldOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	promoCode, err = suite.service.promoCodeRepository.GetById(context.TODO(), promoCode.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), promoCode.ReservedOrderIds)
+	assert.Empty(suite.T(), promoCode.Reservations)
+}
+
+func (suite *OrderReviewTestSuite) TestOrderReview_ApproveExpiredReview_Error() {
+	_, review := suite.createHeldOrder()
+
+	review.ExpiresAt = time.Now().Add(-time.Minute)
+	err := suite.service.orderReviewRepository.Update(context.TODO(), review)
+	assert.NoError(suite.T(), err)
+
+	req := &pkg.ResolveHeldOrderRequest{
+		ReviewId:   review.Id.Hex(),
+		ReviewerId: primitive.NewObjectID().Hex(),
+		Reason:     "customer confirmed the payment",
+	}
+	rsp := &pkg.OrderReviewResponse{}
+	err = suite.service.ApproveHeldOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), orderReviewErrorExpired, rsp.Message)
+}
+
+func (suite *OrderReviewTestSuite) TestOrderReview_ExpireHeldOrders_Ok() {
+	order1, review1 := suite.createHeldOrder()
+	order2, _ := suite.createHeldOrder()
+
+	review1.ExpiresAt = time.Now().Add(-time.Minute)
+	err := suite.service.orderReviewRepository.Update(context.TODO(), review1)
+	assert.NoError(suite.T(), err)
+
+	err = suite.service.ExpireHeldOrders(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	review1, err = suite.service.orderReviewRepository.GetById(context.TODO(), review1.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.OrderReviewStatusExpired, review1.Status)
+
+	order1, err = suite.service.orderRepository.GetById(context.TODO(), order1.Id)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), recurringpb.OrderStatusPaymentSystemCanceled, order1.PrivateStatus)
+
+	order2, err = suite.service.orderRepository.GetById(context.TODO(), order2.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.OrderStatusReviewHeld, order2.PrivateStatus)
+}
@@ -11445,6 +11445,38 @@ func (suite *OrderTestSuite) TestOrder_OrderWithProducts_BankingCurrencyNotMatch
 	}
 }
 
+func (suite *OrderTestSuite) TestOrder_OrderCreateProcess_ConvertedBalance_RoyaltyCurrencyNotChanged() {
+	err := suite.service.merchantBalanceRepository.Insert(context.TODO(), &billingpb.MerchantBalance{
+		Id:         primitive.NewObjectID().Hex(),
+		MerchantId: suite.merchant.Id,
+		Currency:   "RUB",
+		CreatedAt:  ptypes.TimestampNow(),
+	})
+	assert.NoError(suite.T(), err)
+
+	req := &billingpb.OrderCreateRequest{
+		Type:          pkg.OrderType_simple,
+		ProjectId:     suite.project.Id,
+		PaymentMethod: suite.paymentMethod.Group,
+		Currency:      "RUB",
+		Amount:        100,
+		Account:       "unit test",
+		Description:   "unit test",
+		User: &billingpb.OrderUser{
+			Email: "test@unit.unit",
+			Ip:    "127.0.0.1",
+		},
+		FormMode: "standalone",
+	}
+
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err = suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), "RUB", rsp.Item.Currency)
+	assert.Equal(suite.T(), suite.merchant.GetPayoutCurrency(), rsp.Item.GetMerchantRoyaltyCurrency())
+}
+
 func (suite *OrderTestSuite) TestOrder_processRecurringSettings_CalculateInterval_AlwaysOne() {
 	req := &billingpb.OrderCreateRequest{
 		Type:            pkg.OrderType_simple,
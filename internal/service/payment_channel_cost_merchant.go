@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"github.com/golang/protobuf/proto"
 	"github.com/paysuper/paysuper-billing-server/internal/helper"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/errors"
@@ -122,6 +123,8 @@ func (s *Service) SetPaymentChannelCostMerchant(
 
 	req.IsActive = true
 
+	var before *billingpb.PaymentChannelCostMerchant
+
 	if req.Id != "" {
 		val, err := s.paymentChannelCostMerchantRepository.GetById(ctx, req.Id)
 		if err != nil {
@@ -129,6 +132,7 @@ func (s *Service) SetPaymentChannelCostMerchant(
 			res.Message = errorPaymentChannelMerchantSetFailed
 			return nil
 		}
+		before = val
 		req.Id = val.Id
 		req.MerchantId = val.MerchantId
 		req.CreatedAt = val.CreatedAt
@@ -156,6 +160,18 @@ func (s *Service) SetPaymentChannelCostMerchant(
 		return nil
 	}
 
+	if err := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "SetPaymentChannelCostMerchant",
+		entityType: pkg.AuditLogEntityPaymentChannelCostMerchant,
+		entityId:   req.Id,
+		before:     before,
+		after:      req,
+	}); err != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	res.Status = billingpb.ResponseStatusOk
 	res.Item = req
 
@@ -173,6 +189,8 @@ func (s *Service) DeletePaymentChannelCostMerchant(
 		res.Message = errorCostRateNotFound
 		return nil
 	}
+
+	before := proto.Clone(pc)
 	err = s.paymentChannelCostMerchantRepository.Delete(ctx, pc)
 	if err != nil {
 		res.Status = billingpb.ResponseStatusSystemError
@@ -180,6 +198,18 @@ func (s *Service) DeletePaymentChannelCostMerchant(
 		return nil
 	}
 
+	if err := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "DeletePaymentChannelCostMerchant",
+		entityType: pkg.AuditLogEntityPaymentChannelCostMerchant,
+		entityId:   pc.Id,
+		before:     before,
+		after:      pc,
+	}); err != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	res.Status = billingpb.ResponseStatusOk
 	return nil
 }
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"github.com/golang/protobuf/proto"
 	"github.com/paysuper/paysuper-billing-server/internal/helper"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/errors"
@@ -145,6 +146,18 @@ func (s *Service) SetPaymentChannelCostSystem(
 		return nil
 	}
 
+	if err := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "SetPaymentChannelCostSystem",
+		entityType: pkg.AuditLogEntityPaymentChannelCostSystem,
+		entityId:   req.Id,
+		before:     val,
+		after:      req,
+	}); err != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	res.Status = billingpb.ResponseStatusOk
 	res.Item = req
 
@@ -162,6 +175,8 @@ func (s *Service) DeletePaymentChannelCostSystem(
 		res.Message = errorCostRateNotFound
 		return nil
 	}
+
+	before := proto.Clone(pc)
 	err = s.paymentChannelCostSystemRepository.Delete(ctx, pc)
 	if err != nil {
 		res.Status = billingpb.ResponseStatusSystemError
@@ -169,6 +184,18 @@ func (s *Service) DeletePaymentChannelCostSystem(
 		return nil
 	}
 
+	if err := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "DeletePaymentChannelCostSystem",
+		entityType: pkg.AuditLogEntityPaymentChannelCostSystem,
+		entityId:   pc.Id,
+		before:     before,
+		after:      pc,
+	}); err != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	res.Status = billingpb.ResponseStatusOk
 	return nil
 }
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"github.com/paysuper/paysuper-billing-server/internal/helper"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+)
+
+var (
+	paymentMethodRuleErrorUnknown              = errors.NewBillingServerErrorMsg("pmrl0001", "payment method rule can't be processed. try request later")
+	paymentMethodRuleErrorNotFound             = errors.NewBillingServerErrorMsg("pmrl0002", "payment method rule with specified data not found")
+	paymentMethodRuleErrorPaymentMethodUnknown = errors.NewBillingServerErrorMsg("pmrl0003", "payment method of rule not found")
+	paymentMethodRuleErrorAmountRangeInvalid   = errors.NewBillingServerErrorMsg("pmrl0004", "maximal amount of payment method rule must be greater than minimal amount")
+	paymentMethodRuleErrorActionInvalid        = errors.NewBillingServerErrorMsg("pmrl0005", "action of payment method rule must be allow or deny")
+	paymentMethodRuleErrorCurrencyRequired     = errors.NewBillingServerErrorMsg("pmrl0006", "currencies of payment method rule are required for amount range")
+)
+
+// CreateOrUpdatePaymentMethodRule saves the rule which enables or disables the payment method of the project for
+// orders by the customer country, order currency, amount range and platform. Amount range is set in the currencies of
+// the rule, so the rule with amount range must have currencies.
+func (s *Service) CreateOrUpdatePaymentMethodRule(
+	ctx context.Context,
+	req *pkg.CreateOrUpdatePaymentMethodRuleRequest,
+	rsp *pkg.PaymentMethodRuleResponse,
+) error {
+	project, err := s.project.GetById(ctx, req.ProjectId)
+
+	if err != nil || project.MerchantId != req.MerchantId {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = projectErrorNotFound
+		return nil
+	}
+
+	if req.Action != pkg.PaymentMethodRuleActionAllow && req.Action != pkg.PaymentMethodRuleActionDeny {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = paymentMethodRuleErrorActionInvalid
+		return nil
+	}
+
+	if _, err = s.paymentMethodRepository.GetById(ctx, req.PaymentMethodId); err != nil {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = paymentMethodRuleErrorPaymentMethodUnknown
+		return nil
+	}
+
+	if req.MaxAmount > 0 && req.MaxAmount <= req.MinAmount {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = paymentMethodRuleErrorAmountRangeInvalid
+		return nil
+	}
+
+	if (req.MinAmount > 0 || req.MaxAmount > 0) && len(req.Currencies) <= 0 {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = paymentMethodRuleErrorCurrencyRequired
+		return nil
+	}
+
+	rule := &intPkg.PaymentMethodRule{}
+
+	if req.Id != "" {
+		rule, err = s.paymentMethodRuleRepository.GetById(ctx, req.Id)
+
+		if err != nil || rule.MerchantId.Hex() != req.MerchantId {
+			rsp.Status = billingpb.ResponseStatusNotFound
+			rsp.Message = paymentMethodRuleErrorNotFound
+			return nil
+		}
+	}
+
+	rule.MerchantId, _ = primitive.ObjectIDFromHex(req.MerchantId)
+	rule.ProjectId, _ = primitive.ObjectIDFromHex(req.ProjectId)
+	rule.PaymentMethodId, _ = primitive.ObjectIDFromHex(req.PaymentMethodId)
+	rule.Action = req.Action
+	rule.Countries = toUpperStrings(req.Countries)
+	rule.Currencies = toUpperStrings(req.Currencies)
+	rule.MinAmount = req.MinAmount
+	rule.MaxAmount = req.MaxAmount
+	rule.Platforms = req.Platforms
+
+	if rule.Id.IsZero() {
+		err = s.paymentMethodRuleRepository.Insert(ctx, rule)
+	} else {
+		err = s.paymentMethodRuleRepository.Update(ctx, rule)
+	}
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = paymentMethodRuleErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getPaymentMethodRuleMessage(rule)
+
+	return nil
+}
+
+func (s *Service) DeletePaymentMethodRule(
+	ctx context.Context,
+	req *pkg.DeletePaymentMethodRuleRequest,
+	rsp *billingpb.EmptyResponseWithStatus,
+) error {
+	rule, err := s.paymentMethodRuleRepository.GetById(ctx, req.Id)
+
+	if err != nil || rule.MerchantId.Hex() != req.MerchantId {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = paymentMethodRuleErrorNotFound
+		return nil
+	}
+
+	if err = s.paymentMethodRuleRepository.Delete(ctx, rule); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = paymentMethodRuleErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+
+	return nil
+}
+
+func (s *Service) ListPaymentMethodRules(
+	ctx context.Context,
+	req *pkg.ListPaymentMethodRulesRequest,
+	rsp *pkg.ListPaymentMethodRulesResponse,
+) error {
+	project, err := s.project.GetById(ctx, req.ProjectId)
+
+	if err != nil || project.MerchantId != req.MerchantId {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = projectErrorNotFound
+		return nil
+	}
+
+	rules, err := s.paymentMethodRuleRepository.GetByProjectId(ctx, req.ProjectId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = paymentMethodRuleErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Items = []*pkg.PaymentMethodRule{}
+
+	for _, rule := range rules {
+		rsp.Items = append(rsp.Items, getPaymentMethodRuleMessage(rule))
+	}
+
+	return nil
+}
+
+// isPaymentMethodAllowedByRules checks the payment method of the order by rules of the project. The payment method
+// is disabled if any deny rule matches the order or if the method has allow rules and none of them matches the order.
+// The payment method without rules is enabled.
+func isPaymentMethodAllowedByRules(
+	rules []*intPkg.PaymentMethodRule,
+	paymentMethodId string,
+	order *billingpb.Order,
+) bool {
+	hasAllowRules := false
+	allowed := false
+
+	for _, rule := range rules {
+		if rule.PaymentMethodId.Hex() != paymentMethodId {
+			continue
+		}
+
+		matched := isPaymentMethodRuleMatched(rule, order)
+
+		if rule.Action == pkg.PaymentMethodRuleActionDeny {
+			if matched {
+				return false
+			}
+			continue
+		}
+
+		hasAllowRules = true
+		allowed = allowed || matched
+	}
+
+	return !hasAllowRules || allowed
+}
+
+func isPaymentMethodRuleMatched(rule *intPkg.PaymentMethodRule, order *billingpb.Order) bool {
+	if len(rule.Countries) > 0 && !helper.Contains(rule.Countries, strings.ToUpper(order.GetCountry())) {
+		return false
+	}
+
+	if len(rule.Currencies) > 0 && !helper.Contains(rule.Currencies, strings.ToUpper(order.Currency)) {
+		return false
+	}
+
+	if order.OrderAmount < rule.MinAmount || (rule.MaxAmount > 0 && order.OrderAmount > rule.MaxAmount) {
+		return false
+	}
+
+	if len(rule.Platforms) > 0 && !helper.Contains(rule.Platforms, order.PlatformId) {
+		return false
+	}
+
+	return true
+}
+
+func toUpperStrings(list []string) []string {
+	result := make([]string, 0, len(list))
+
+	for _, item := range list {
+		result = append(result, strings.ToUpper(item))
+	}
+
+	return result
+}
+
+func getPaymentMethodRuleMessage(rule *intPkg.PaymentMethodRule) *pkg.PaymentMethodRule {
+	return &pkg.PaymentMethodRule{
+		Id:              rule.Id.Hex(),
+		MerchantId:      rule.MerchantId.Hex(),
+		ProjectId:       rule.ProjectId.Hex(),
+		PaymentMethodId: rule.PaymentMethodId.Hex(),
+		Action:          rule.Action,
+		Countries:       rule.Countries,
+		Currencies:      rule.Currencies,
+		MinAmount:       rule.MinAmount,
+		MaxAmount:       rule.MaxAmount,
+		Platforms:       rule.Platforms,
+		CreatedAt:       getTimestampProto(rule.CreatedAt),
+		UpdatedAt:       getTimestampProto(rule.UpdatedAt),
+	}
+}
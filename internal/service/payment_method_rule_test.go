@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/google/uuid"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type PaymentMethodRuleTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+	cookie        string
+}
+
+func Test_PaymentMethodRule(t *testing.T) {
+	suite.Run(t, new(PaymentMethodRuleTestSuite))
+}
+
+func (suite *PaymentMethodRuleTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	var customer *billingpb.Customer
+	suite.merchant, suite.project, suite.paymentMethod, _, customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.cookie, err = suite.service.generateBrowserCookie(&BrowserCookieCustomer{
+		CustomerId: customer.Id,
+		Ip:         "127.0.0.1",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	})
+
+	if err != nil {
+		suite.FailNow("Generate browser cookie failed", "%v", err)
+	}
+}
+
+func (suite *PaymentMethodRuleTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *PaymentMethodRuleTestSuite) createOrder() *billingpb.Order {
+	req := &billingpb.OrderCreateRequest{
+		Type:        pkg.OrderType_simple,
+		ProjectId:   suite.project.Id,
+		Amount:      100,
+		Currency:    "RUB",
+		Account:     "unit test",
+		Description: "unit test",
+		User: &billingpb.OrderUser{
+			Id:    primitive.NewObjectID().Hex(),
+			Uuid:  uuid.New().String(),
+			Email: "test@unit.unit",
+			Ip:    "127.0.0.1",
+			Address: &billingpb.OrderBillingAddress{
+				Country: "RU",
+			},
+		},
+	}
+
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	return rsp.Item
+}
+
+func (suite *PaymentMethodRuleTestSuite) createRule(
+	req *pkg.CreateOrUpdatePaymentMethodRuleRequest,
+) *pkg.PaymentMethodRuleResponse {
+	req.MerchantId = suite.merchant.Id
+	req.ProjectId = suite.project.Id
+	req.PaymentMethodId = suite.paymentMethod.Id
+
+	rsp := &pkg.PaymentMethodRuleResponse{}
+	err := suite.service.CreateOrUpdatePaymentMethodRule(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *PaymentMethodRuleTestSuite) TestPaymentMethodRule_CreateOrUpdate_Ok() {
+	rsp := suite.createRule(&pkg.CreateOrUpdatePaymentMethodRuleRequest{
+		Action:     pkg.PaymentMethodRuleActionDeny,
+		Countries:  []string{"ru", "by"},
+		Currencies: []string{"rub"},
+		MaxAmount:  1000,
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.NotEmpty(suite.T(), rsp.Item.Id)
+	assert.Equal(suite.T(), []string{"RU", "BY"}, rsp.Item.Countries)
+	assert.Equal(suite.T(), []string{"RUB"}, rsp.Item.Currencies)
+
+	rsp = suite.createRule(&pkg.CreateOrUpdatePaymentMethodRuleRequest{
+		Id:        rsp.Item.Id,
+		Action:    pkg.PaymentMethodRuleActionAllow,
+		Platforms: []string{"steam"},
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	rsp1 := &pkg.ListPaymentMethodRulesResponse{}
+	req1 := &pkg.ListPaymentMethodRulesRequest{MerchantId: suite.merchant.Id, ProjectId: suite.project.Id}
+	err := suite.service.ListPaymentMethodRules(context.TODO(), req1, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+	assert.Len(suite.T(), rsp1.Items, 1)
+	assert.Equal(suite.T(), pkg.PaymentMethodRuleActionAllow, rsp1.Items[0].Action)
+	assert.Empty(suite.T(), rsp1.Items[0].Countries)
+	assert.Equal(suite.T(), []string{"steam"}, rsp1.Items[0].Platforms)
+}
+
+func (suite *PaymentMethodRuleTestSuite) TestPaymentMethodRule_CreateOrUpdate_ProjectNotFound_Error() {
+	req := &pkg.CreateOrUpdatePaymentMethodRuleRequest{
+		MerchantId:      primitive.NewObjectID().Hex(),
+		ProjectId:       suite.project.Id,
+		PaymentMethodId: suite.paymentMethod.Id,
+		Action:          pkg.PaymentMethodRuleActionDeny,
+	}
+	rsp := &pkg.PaymentMethodRuleResponse{}
+	err := suite.service.CreateOrUpdatePaymentMethodRule(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), projectErrorNotFound, rsp.Message)
+}
+
+func (suite *PaymentMethodRuleTestSuite) TestPaymentMethodRule_CreateOrUpdate_AmountRangeInvalid_Error() {
+	rsp := suite.createRule(&pkg.CreateOrUpdatePaymentMethodRuleRequest{
+		Action:    pkg.PaymentMethodRuleActionAllow,
+		MinAmount: 100,
+		MaxAmount: 10,
+	})
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), paymentMethodRuleErrorAmountRangeInvalid, rsp.Message)
+}
+
+func (suite *PaymentMethodRuleTestSuite) TestPaymentMethodRule_CreateOrUpdate_CurrencyRequired_Error() {
+	rsp := suite.createRule(&pkg.CreateOrUpdatePaymentMethodRuleRequest{
+		Action:    pkg.PaymentMethodRuleActionDeny,
+		MinAmount: 100,
+	})
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), paymentMethodRuleErrorCurrencyRequired, rsp.Message)
+
+	rsp = suite.createRule(&pkg.CreateOrUpdatePaymentMethodRuleRequest{
+		Action:    pkg.PaymentMethodRuleActionAllow,
+		Countries: []string{"RU"},
+		MaxAmount: 1000,
+	})
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), paymentMethodRuleErrorCurrencyRequired, rsp.Message)
+}
+
+func (suite *PaymentMethodRuleTestSuite) TestPaymentMethodRule_CreateOrUpdate_ActionInvalid_Error() {
+	rsp := suite.createRule(&pkg.CreateOrUpdatePaymentMethodRuleRequest{Action: "block"})
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), paymentMethodRuleErrorActionInvalid, rsp.Message)
+
+	rsp = suite.createRule(&pkg.CreateOrUpdatePaymentMethodRuleRequest{})
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), paymentMethodRuleErrorActionInvalid, rsp.Message)
+}
+
+func (suite *PaymentMethodRuleTestSuite) TestPaymentMethodRule_Delete_Ok() {
+	rsp := suite.createRule(&pkg.CreateOrUpdatePaymentMethodRuleRequest{Action: pkg.PaymentMethodRuleActionDeny})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	req := &pkg.DeletePaymentMethodRuleRequest{Id: rsp.Item.Id, MerchantId: primitive.NewObjectID().Hex()}
+	rsp1 := &billingpb.EmptyResponseWithStatus{}
+	err := suite.service.DeletePaymentMethodRule(context.TODO(), req, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp1.Status)
+
+	req.MerchantId = suite.merchant.Id
+	err = suite.service.DeletePaymentMethodRule(context.TODO(), req, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+
+	rules, err := suite.service.paymentMethodRuleRepository.GetByProjectId(context.TODO(), suite.project.Id)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), rules)
+}
+
+func (suite *PaymentMethodRuleTestSuite) TestPaymentMethodRule_IsPaymentMethodAllowedByRules() {
+	pmId := primitive.NewObjectID()
+	order := &billingpb.Order{
+		Currency:    "RUB",
+		OrderAmount: 100,
+		PlatformId:  "steam",
+		User:        &billingpb.OrderUser{Address: &billingpb.OrderBillingAddress{Country: "RU"}},
+	}
+
+	assert.True(suite.T(), isPaymentMethodAllowedByRules(nil, pmId.Hex(), order))
+
+	deny := &intPkg.PaymentMethodRule{PaymentMethodId: pmId, Action: pkg.PaymentMethodRuleActionDeny, Countries: []string{"RU"}}
+	assert.False(suite.T(), isPaymentMethodAllowedByRules([]*intPkg.PaymentMethodRule{deny}, pmId.Hex(), order))
+	assert.True(suite.T(), isPaymentMethodAllowedByRules([]*intPkg.PaymentMethodRule{deny}, primitive.NewObjectID().Hex(), order))
+
+	allow := &intPkg.PaymentMethodRule{PaymentMethodId: pmId, Action: pkg.PaymentMethodRuleActionAllow, Currencies: []string{"USD"}}
+	assert.False(suite.T(), isPaymentMethodAllowedByRules([]*intPkg.PaymentMethodRule{allow}, pmId.Hex(), order))
+
+	allow.Currencies = []string{"USD", "RUB"}
+	allow.MinAmount = 50
+	allow.MaxAmount = 150
+	assert.True(suite.T(), isPaymentMethodAllowedByRules([]*intPkg.PaymentMethodRule{allow}, pmId.Hex(), order))
+
+	allow.MaxAmount = 99
+	assert.False(suite.T(), isPaymentMethodAllowedByRules([]*intPkg.PaymentMethodRule{allow}, pmId.Hex(), order))
+
+	allow.MaxAmount = 0
+	allow.Platforms = []string{"gog"}
+	assert.False(suite.T(), isPaymentMethodAllowedByRules([]*intPkg.PaymentMethodRule{allow}, pmId.Hex(), order))
+
+	allow.Platforms = []string{"gog", "steam"}
+	assert.True(suite.T(), isPaymentMethodAllowedByRules([]*intPkg.PaymentMethodRule{allow}, pmId.Hex(), order))
+	assert.False(suite.T(), isPaymentMethodAllowedByRules([]*intPkg.PaymentMethodRule{allow, deny}, pmId.Hex(), order))
+}
+
+func (suite *PaymentMethodRuleTestSuite) TestPaymentMethodRule_RenderForm_DeniedMethodSkipped() {
+	order := suite.createOrder()
+
+	rsp := suite.createRule(&pkg.CreateOrUpdatePaymentMethodRuleRequest{
+		Action:    pkg.PaymentMethodRuleActionDeny,
+		Countries: []string{"RU"},
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	processor := &PaymentFormProcessor{service: suite.service, order: order}
+	pms, _ := processor.processRenderFormPaymentMethods(context.TODO())
+
+	for _, pm := range pms {
+		assert.NotEqual(suite.T(), suite.paymentMethod.Id, pm.Id)
+	}
+}
+
+func (suite *PaymentMethodRuleTestSuite) TestPaymentMethodRule_PaymentCreateProcess_DeniedMethod_Error() {
+	order := suite.createOrder()
+
+	rsp := suite.createRule(&pkg.CreateOrUpdatePaymentMethodRuleRequest{
+		Action:     pkg.PaymentMethodRuleActionAllow,
+		Currencies: []string{"USD"},
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	req := &billingpb.PaymentCreateRequest{
+		Data: map[string]string{
+			billingpb.PaymentCreateFieldOrderId:         order.Uuid,
+			billingpb.PaymentCreateFieldPaymentMethodId: suite.paymentMethod.Id,
+			billingpb.PaymentCreateFieldEmail:           "test@unit.unit",
+			billingpb.PaymentCreateFieldPan:             "4000000000000002",
+			billingpb.PaymentCreateFieldCvv:             "123",
+			billingpb.PaymentCreateFieldMonth:           "02",
+			billingpb.PaymentCreateFieldYear:            time.Now().AddDate(1, 0, 0).Format("2006"),
+			billingpb.PaymentCreateFieldHolder:          "MR. CARD HOLDER",
+		},
+		Ip:     "127.0.0.1",
+		Cookie: suite.cookie,
+	}
+	rsp1 := &billingpb.PaymentCreateResponse{}
+	err := suite.service.PaymentCreateProcess(context.TODO(), req, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp1.Status)
+	assert.Equal(suite.T(), orderErrorPaymentMethodNotAllowed, rsp1.Message)
+}
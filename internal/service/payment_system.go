@@ -1,8 +1,14 @@
 package service
 
 import (
+	"context"
 	"github.com/paysuper/paysuper-billing-server/internal/payment_system"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
 	"sync"
 )
 
@@ -15,10 +21,16 @@ const (
 var (
 	registry = map[string]func() payment_system.PaymentSystemInterface{
 		billingpb.PaymentSystemHandlerCardPay: payment_system.NewCardPayHandler,
+		pkg.PaymentSystemHandlerCheckout:      payment_system.NewCheckoutHandler,
 		PaymentSystemHandlerMockOk:            NewPaymentSystemMockOk,
 		PaymentSystemHandlerMockError:         NewPaymentSystemMockError,
 		PaymentSystemHandlerCardPayMock:       NewCardPayMock,
 	}
+
+	// Payment systems which can't create recurring subscriptions, the billing doesn't charge regular payments itself
+	paymentSystemHandlersWithoutSubscriptions = map[string]bool{
+		pkg.PaymentSystemHandlerCheckout: true,
+	}
 )
 
 func NewPaymentSystemGateway() payment_system.PaymentSystemManagerInterface {
@@ -28,26 +40,255 @@ func NewPaymentSystemGateway() payment_system.PaymentSystemManagerInterface {
 	return paymentSystem
 }
 
+func (s *Service) newPaymentSystemGateway() payment_system.PaymentSystemManagerInterface {
+	gateway := &Gateway{
+		gateways: make(map[string]payment_system.PaymentSystemInterface),
+	}
+
+	if s.cfg.PaymentSystemSimulatorEnabled {
+		settings := &payment_system.SimulatorSettings{
+			Outcome:              s.cfg.PaymentSystemSimulatorOutcome,
+			CallbackDelay:        s.cfg.GetPaymentSystemSimulatorCallbackDelay(),
+			DelayedCallbackDelay: s.cfg.GetPaymentSystemSimulatorDelayedCallbackDelay(),
+		}
+		gateway.Register(pkg.PaymentSystemHandlerSimulator, payment_system.NewSimulatorHandler(s, settings))
+	}
+
+	return gateway
+}
+
+// GetOrderPrivateStatus returns the private status of the saved order. The payment system simulator checks it to send
+// the payment callback after the order is saved with the payment system status.
+func (s *Service) GetOrderPrivateStatus(ctx context.Context, orderId string) (int32, error) {
+	order, err := s.orderRepository.GetById(ctx, orderId)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return order.PrivateStatus, nil
+}
+
 type Gateway struct {
 	gateways map[string]payment_system.PaymentSystemInterface
 	mx       sync.Mutex
 }
 
 func (m *Gateway) GetGateway(name string) (payment_system.PaymentSystemInterface, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	gateway, ok := m.gateways[name]
+
+	if ok {
+		return gateway, nil
+	}
+
 	initFn, ok := registry[name]
 
 	if !ok {
 		return nil, payment_system.PaymentSystemErrorHandlerNotFound
 	}
 
+	gateway = initFn()
+	m.gateways[name] = gateway
+
+	return gateway, nil
+}
+
+// Register add payment system handler which can't be created without dependencies, for example the simulator
+// which sends callbacks to the billing service.
+func (m *Gateway) Register(name string, gateway payment_system.PaymentSystemInterface) {
 	m.mx.Lock()
-	gateway, ok := m.gateways[name]
+	m.gateways[name] = gateway
+	m.mx.Unlock()
+}
 
-	if !ok {
-		gateway = initFn()
-		m.gateways[name] = gateway
+// getPaymentSystemsForPaymentMethod returns active payment systems which can process payment by the payment method
+// for the country. Payment systems are ordered by priority, the first one is primary. If the payment method has no
+// routes then the payment system linked to the payment method is used.
+func (s *Service) getPaymentSystemsForPaymentMethod(
+	ctx context.Context,
+	pm *billingpb.PaymentMethod,
+	country string,
+) ([]*billingpb.PaymentSystem, error) {
+	ids := []string{pm.PaymentSystemId}
+	route, err := s.paymentMethodRouteRepository.GetByPaymentMethodAndCountry(ctx, pm.Id, country)
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
 	}
 
-	m.mx.Unlock()
-	return gateway, nil
+	if route != nil && len(route.PaymentSystemIds) > 0 {
+		ids = make([]string, len(route.PaymentSystemIds))
+
+		for i, id := range route.PaymentSystemIds {
+			ids[i] = id.Hex()
+		}
+	}
+
+	var list []*billingpb.PaymentSystem
+
+	for _, id := range ids {
+		ps, err := s.paymentSystemRepository.GetById(ctx, id)
+
+		if err != nil || !ps.IsActive {
+			continue
+		}
+
+		list = append(list, ps)
+	}
+
+	if len(list) <= 0 {
+		return nil, orderErrorPaymentSystemInactive
+	}
+
+	return list, nil
+}
+
+// getRecurringPaymentSystems returns payment systems from the list which can create recurring subscriptions.
+func getRecurringPaymentSystems(list []*billingpb.PaymentSystem) []*billingpb.PaymentSystem {
+	var result []*billingpb.PaymentSystem
+
+	for _, ps := range list {
+		if paymentSystemHandlersWithoutSubscriptions[ps.Handler] {
+			continue
+		}
+
+		result = append(result, ps)
+	}
+
+	return result
+}
+
+// getPaymentSystemParams returns settings of payment method for the payment system.
+// Terminals of payment methods are issued by CardPay, so for other payment systems credentials are taken from config.
+func (s *Service) getPaymentSystemParams(
+	handler string,
+	params *billingpb.PaymentMethodParams,
+	isProduction bool,
+) *billingpb.PaymentMethodParams {
+	switch handler {
+	case pkg.PaymentSystemHandlerCheckout:
+		out := &billingpb.PaymentMethodParams{
+			Currency:           params.Currency,
+			MccCode:            params.MccCode,
+			OperatingCompanyId: params.OperatingCompanyId,
+			Brand:              params.Brand,
+			TerminalId:         s.cfg.CheckoutProcessingChannelId,
+			Secret:             s.cfg.CheckoutSecretKey,
+			SecretCallback:     s.cfg.CheckoutWebhookSecret,
+			ApiUrl:             s.cfg.CheckoutApiSandboxUrl,
+		}
+
+		if isProduction {
+			out.ApiUrl = s.cfg.CheckoutApiUrl
+		}
+
+		return out
+	}
+
+	return params
+}
+
+// createPaymentWithFailover creates payment in the first available payment system from the list. If payment system
+// is unavailable then the order is switched to the next payment system and the switch is recorded to the order history.
+// Orders with the manual capture aren't switched because not every payment system supports authorization only
+// and the payment must not be captured by another payment system without the capture request.
+func (s *Service) createPaymentWithFailover(
+	ctx context.Context,
+	order *billingpb.Order,
+	paymentSystems []*billingpb.PaymentSystem,
+	pmParams *billingpb.PaymentMethodParams,
+	data map[string]string,
+) (string, error) {
+	var lastErr error
+
+	manualCapture := payment_system.IsManualCaptureOrder(order)
+
+	for _, ps := range paymentSystems {
+		if manualCapture && ps.Id != order.PaymentMethod.PaymentSystemId {
+			continue
+		}
+
+		if !s.paymentSystemBreaker.IsAvailable(ps.Id) {
+			continue
+		}
+
+		if ps.Id != order.PaymentMethod.PaymentSystemId {
+			reason := orderErrorPaymentSystemTemporaryUnavailable.Message
+
+			if lastErr != nil {
+				reason = lastErr.Error()
+			}
+
+			s.switchOrderPaymentSystem(ctx, order, ps, pmParams, reason)
+		}
+
+		h, err := s.paymentSystemGateway.GetGateway(ps.Handler)
+
+		if err != nil {
+			return "", err
+		}
+
+		var url string
+
+		if manualCapture {
+			url, err = h.CreateAuthorization(order, s.cfg.GetRedirectUrlSuccess(nil), s.cfg.GetRedirectUrlFail(nil), data)
+		} else {
+			url, err = h.CreatePayment(order, s.cfg.GetRedirectUrlSuccess(nil), s.cfg.GetRedirectUrlFail(nil), data)
+		}
+
+		if err == nil || !payment_system.IsUnavailableError(err) {
+			s.paymentSystemBreaker.Success(ps.Id)
+			return url, err
+		}
+
+		zap.L().Error(
+			"payment system is unavailable, try to create payment in next payment system",
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+			zap.String("payment_system_id", ps.Id),
+			zap.String(pkg.LogFieldHandler, ps.Handler),
+		)
+
+		s.paymentSystemBreaker.Failure(ps.Id)
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		lastErr = orderErrorPaymentSystemTemporaryUnavailable
+	}
+
+	return "", lastErr
+}
+
+func (s *Service) switchOrderPaymentSystem(
+	ctx context.Context,
+	order *billingpb.Order,
+	ps *billingpb.PaymentSystem,
+	pmParams *billingpb.PaymentMethodParams,
+	reason string,
+) {
+	history := &intPkg.OrderHistory{
+		Type: pkg.OrderHistoryTypePaymentSystemSwitched,
+		Data: map[string]string{
+			pkg.OrderHistoryFieldPaymentSystemFrom: order.PaymentMethod.PaymentSystemId,
+			pkg.OrderHistoryFieldPaymentSystemTo:   ps.Id,
+			pkg.OrderHistoryFieldReason:            reason,
+		},
+	}
+	history.OrderId, _ = primitive.ObjectIDFromHex(order.Id)
+
+	order.PaymentMethod.PaymentSystemId = ps.Id
+	order.PaymentMethod.Handler = ps.Handler
+	order.PaymentMethod.Params = s.getPaymentSystemParams(ps.Handler, pmParams, order.IsProduction)
+
+	if err := s.orderHistoryRepository.Insert(ctx, history); err != nil {
+		zap.L().Error(
+			"unable to record payment system switch to order history",
+			zap.Error(err),
+			zap.Any("history", history),
+		)
+	}
 }
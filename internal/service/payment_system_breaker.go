@@ -0,0 +1,91 @@
+package service
+
+import (
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// paymentSystemBreaker is a circuit breaker for payment systems. After threshold of consecutive failures
+// the payment system is skipped for the cooldown window. When the window expires one request is allowed
+// to check the payment system and the next one is not allowed before the new window expires.
+// Success of the request closes the breaker and failure keeps it open.
+type paymentSystemBreaker struct {
+	mx        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	states    map[string]*paymentSystemBreakerState
+	now       func() time.Time
+}
+
+type paymentSystemBreakerState struct {
+	failures  int
+	openUntil time.Time
+}
+
+func newPaymentSystemBreaker(threshold int, cooldown time.Duration) *paymentSystemBreaker {
+	return &paymentSystemBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		states:    make(map[string]*paymentSystemBreakerState),
+		now:       time.Now,
+	}
+}
+
+// IsAvailable returns false while breaker of the payment system is open.
+func (b *paymentSystemBreaker) IsAvailable(id string) bool {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	state, ok := b.states[id]
+
+	if !ok || state.openUntil.IsZero() {
+		return true
+	}
+
+	now := b.now()
+
+	if now.Before(state.openUntil) {
+		return false
+	}
+
+	state.openUntil = now.Add(b.cooldown)
+
+	return true
+}
+
+// Success closes breaker of the payment system.
+func (b *paymentSystemBreaker) Success(id string) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	delete(b.states, id)
+}
+
+// Failure registers failed request to the payment system and opens breaker when threshold is reached.
+func (b *paymentSystemBreaker) Failure(id string) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	state, ok := b.states[id]
+
+	if !ok {
+		state = &paymentSystemBreakerState{}
+		b.states[id] = state
+	}
+
+	state.failures++
+
+	if state.failures < b.threshold {
+		return
+	}
+
+	state.openUntil = b.now().Add(b.cooldown)
+
+	zap.L().Warn(
+		"payment system circuit breaker opened",
+		zap.String("payment_system_id", id),
+		zap.Int("failures", state.failures),
+		zap.Time("open_until", state.openUntil),
+	)
+}
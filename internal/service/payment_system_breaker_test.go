@@ -0,0 +1,64 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type PaymentSystemBreakerTestSuite struct {
+	suite.Suite
+	breaker *paymentSystemBreaker
+	now     time.Time
+}
+
+func Test_PaymentSystemBreaker(t *testing.T) {
+	suite.Run(t, new(PaymentSystemBreakerTestSuite))
+}
+
+func (suite *PaymentSystemBreakerTestSuite) SetupTest() {
+	suite.now = time.Now()
+	suite.breaker = newPaymentSystemBreaker(2, time.Minute)
+	suite.breaker.now = func() time.Time {
+		return suite.now
+	}
+}
+
+func (suite *PaymentSystemBreakerTestSuite) TestPaymentSystemBreaker_OpenAfterThreshold() {
+	assert.True(suite.T(), suite.breaker.IsAvailable("ps"))
+
+	suite.breaker.Failure("ps")
+	assert.True(suite.T(), suite.breaker.IsAvailable("ps"))
+
+	suite.breaker.Failure("ps")
+	assert.False(suite.T(), suite.breaker.IsAvailable("ps"))
+	assert.True(suite.T(), suite.breaker.IsAvailable("other"))
+}
+
+func (suite *PaymentSystemBreakerTestSuite) TestPaymentSystemBreaker_SuccessResetsFailures() {
+	suite.breaker.Failure("ps")
+	suite.breaker.Success("ps")
+	suite.breaker.Failure("ps")
+	assert.True(suite.T(), suite.breaker.IsAvailable("ps"))
+}
+
+func (suite *PaymentSystemBreakerTestSuite) TestPaymentSystemBreaker_HalfOpenAfterCooldown() {
+	suite.breaker.Failure("ps")
+	suite.breaker.Failure("ps")
+	assert.False(suite.T(), suite.breaker.IsAvailable("ps"))
+
+	suite.now = suite.now.Add(time.Minute + time.Second)
+	assert.True(suite.T(), suite.breaker.IsAvailable("ps"))
+	assert.False(suite.T(), suite.breaker.IsAvailable("ps"))
+
+	suite.breaker.Failure("ps")
+	suite.now = suite.now.Add(time.Second)
+	assert.False(suite.T(), suite.breaker.IsAvailable("ps"))
+
+	suite.now = suite.now.Add(time.Minute)
+	assert.True(suite.T(), suite.breaker.IsAvailable("ps"))
+	suite.breaker.Success("ps")
+	assert.True(suite.T(), suite.breaker.IsAvailable("ps"))
+	assert.True(suite.T(), suite.breaker.IsAvailable("ps"))
+}
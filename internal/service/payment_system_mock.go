@@ -110,6 +110,30 @@ func NewCardPayMock() payment_system.PaymentSystemInterface {
 	cpMock.On("DeleteRecurringSubscription", mock.Anything, mock.Anything).
 		Return(nil, nil)
 	cpMock.On("CanSaveCard", mock.Anything).Return(false)
+	cpMock.On("CreateAuthorization", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(
+			func(order *billingpb.Order, successUrl, failUrl string, requisites map[string]string) string {
+				order.PrivateStatus = recurringpb.OrderStatusPaymentSystemCreate
+				return "http://localhost"
+			},
+			nil,
+		)
+	cpMock.On("Capture", mock.Anything, mock.Anything).
+		Return(
+			func(order *billingpb.Order, amount float64) error {
+				order.PrivateStatus = recurringpb.OrderStatusPaymentSystemComplete
+				order.PaymentMethodOrderClosedAt = ptypes.TimestampNow()
+				return nil
+			},
+		)
+	cpMock.On("Void", mock.Anything).
+		Return(
+			func(order *billingpb.Order) error {
+				order.PrivateStatus = pkg.OrderStatusPaymentSystemVoided
+				order.CanceledAt = ptypes.TimestampNow()
+				return nil
+			},
+		)
 	return cpMock
 }
 
@@ -156,10 +180,40 @@ func (m *PaymentSystemMockOk) DeleteRecurringSubscription(_ *billingpb.Order, _
 	return nil
 }
 
+func (m *PaymentSystemMockOk) PauseRecurringSubscription(_ *billingpb.Order, _ *recurringpb.Subscription) error {
+	return nil
+}
+
+func (m *PaymentSystemMockOk) ResumeRecurringSubscription(_ *billingpb.Order, _ *recurringpb.Subscription, _ time.Time) error {
+	return nil
+}
+
+func (m *PaymentSystemMockOk) UpdateRecurringSubscriptionCard(_ *billingpb.Order, _ *recurringpb.Subscription, _ string) error {
+	return nil
+}
+
+func (m *PaymentSystemMockOk) UpdateRecurringSubscriptionPlan(_ *billingpb.Order, _ *recurringpb.Subscription) error {
+	return nil
+}
+
 func (m *PaymentSystemMockOk) CanSaveCard(_ proto.Message) bool {
 	return false
 }
 
+func (m *PaymentSystemMockOk) CreateAuthorization(_ *billingpb.Order, _, _ string, _ map[string]string) (string, error) {
+	return "", nil
+}
+
+func (m *PaymentSystemMockOk) Capture(order *billingpb.Order, _ float64) error {
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemComplete
+	return nil
+}
+
+func (m *PaymentSystemMockOk) Void(order *billingpb.Order) error {
+	order.PrivateStatus = pkg.OrderStatusPaymentSystemVoided
+	return nil
+}
+
 func (m *PaymentSystemMockError) CreatePayment(_ *billingpb.Order, _, _ string, _ map[string]string) (string, error) {
 	return "", nil
 }
@@ -199,6 +253,34 @@ func (m *PaymentSystemMockError) DeleteRecurringSubscription(_ *billingpb.Order,
 	return nil
 }
 
+func (m *PaymentSystemMockError) PauseRecurringSubscription(_ *billingpb.Order, _ *recurringpb.Subscription) error {
+	return errors.New("update recurring subscription status failed")
+}
+
+func (m *PaymentSystemMockError) ResumeRecurringSubscription(_ *billingpb.Order, _ *recurringpb.Subscription, _ time.Time) error {
+	return errors.New("update recurring subscription status failed")
+}
+
+func (m *PaymentSystemMockError) UpdateRecurringSubscriptionCard(_ *billingpb.Order, _ *recurringpb.Subscription, _ string) error {
+	return errors.New("update recurring subscription card failed")
+}
+
+func (m *PaymentSystemMockError) UpdateRecurringSubscriptionPlan(_ *billingpb.Order, _ *recurringpb.Subscription) error {
+	return errors.New("update recurring subscription plan failed")
+}
+
 func (m *PaymentSystemMockError) CanSaveCard(_ proto.Message) bool {
 	return false
 }
+
+func (m *PaymentSystemMockError) CreateAuthorization(_ *billingpb.Order, _, _ string, _ map[string]string) (string, error) {
+	return "", nil
+}
+
+func (m *PaymentSystemMockError) Capture(_ *billingpb.Order, _ float64) error {
+	return payment_system.PaymentSystemErrorOperationNotSupported
+}
+
+func (m *PaymentSystemMockError) Void(_ *billingpb.Order) error {
+	return payment_system.PaymentSystemErrorOperationNotSupported
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	mock2 "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type PaymentSystemTestSuite struct {
+	suite.Suite
+	service    *Service
+	cache      database.CacheInterface
+	pm         *billingpb.PaymentMethod
+	psCardPay  *billingpb.PaymentSystem
+	psCheckout *billingpb.PaymentSystem
+}
+
+func Test_PaymentSystem(t *testing.T) {
+	suite.Run(t, new(PaymentSystemTestSuite))
+}
+
+func (suite *PaymentSystemTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	suite.psCardPay = &billingpb.PaymentSystem{
+		Id:                 primitive.NewObjectID().Hex(),
+		Name:               "CardPay",
+		AccountingCurrency: "RUB",
+		AccountingPeriod:   "every-day",
+		IsActive:           true,
+		Handler:            billingpb.PaymentSystemHandlerCardPay,
+	}
+	suite.psCheckout = &billingpb.PaymentSystem{
+		Id:                 primitive.NewObjectID().Hex(),
+		Name:               "Checkout.com",
+		AccountingCurrency: "USD",
+		AccountingPeriod:   "every-day",
+		IsActive:           true,
+		Handler:            pkg.PaymentSystemHandlerCheckout,
+	}
+
+	err = suite.service.paymentSystemRepository.MultipleInsert(
+		context.TODO(),
+		[]*billingpb.PaymentSystem{suite.psCardPay, suite.psCheckout},
+	)
+
+	if err != nil {
+		suite.FailNow("Insert payment system test data failed", "%v", err)
+	}
+
+	suite.pm = &billingpb.PaymentMethod{
+		Id:              primitive.NewObjectID().Hex(),
+		Name:            "Bank card",
+		Group:           "BANKCARD",
+		ExternalId:      "BANKCARD",
+		Type:            "bank_card",
+		IsActive:        true,
+		PaymentSystemId: suite.psCardPay.Id,
+	}
+}
+
+func (suite *PaymentSystemTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *PaymentSystemTestSuite) addRoute(country string, ps ...*billingpb.PaymentSystem) {
+	pmOid, _ := primitive.ObjectIDFromHex(suite.pm.Id)
+	route := &intPkg.PaymentMethodRoute{PaymentMethodId: pmOid, Country: country}
+
+	for _, v := range ps {
+		oid, _ := primitive.ObjectIDFromHex(v.Id)
+		route.PaymentSystemIds = append(route.PaymentSystemIds, oid)
+	}
+
+	err := suite.service.paymentMethodRouteRepository.Upsert(context.TODO(), route)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *PaymentSystemTestSuite) TestPaymentSystem_GetPaymentSystemsForPaymentMethod_WithoutRoutes() {
+	list, err := suite.service.getPaymentSystemsForPaymentMethod(context.TODO(), suite.pm, "RU")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), list, 1)
+	assert.Equal(suite.T(), suite.psCardPay.Id, list[0].Id)
+}
+
+func (suite *PaymentSystemTestSuite) TestPaymentSystem_GetPaymentSystemsForPaymentMethod_CountryRoute() {
+	suite.addRoute("", suite.psCardPay, suite.psCheckout)
+	suite.addRoute("DE", suite.psCheckout)
+
+	list, err := suite.service.getPaymentSystemsForPaymentMethod(context.TODO(), suite.pm, "DE")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), list, 1)
+	assert.Equal(suite.T(), pkg.PaymentSystemHandlerCheckout, list[0].Handler)
+
+	list, err = suite.service.getPaymentSystemsForPaymentMethod(context.TODO(), suite.pm, "RU")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), list, 2)
+	assert.Equal(suite.T(), suite.psCardPay.Id, list[0].Id)
+	assert.Equal(suite.T(), suite.psCheckout.Id, list[1].Id)
+}
+
+func (suite *PaymentSystemTestSuite) TestPaymentSystem_GetPaymentSystemsForPaymentMethod_RouteUpdated() {
+	suite.addRoute("DE", suite.psCardPay)
+	suite.addRoute("DE", suite.psCheckout, suite.psCardPay)
+
+	list, err := suite.service.getPaymentSystemsForPaymentMethod(context.TODO(), suite.pm, "DE")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), list, 2)
+	assert.Equal(suite.T(), suite.psCheckout.Id, list[0].Id)
+}
+
+func (suite *PaymentSystemTestSuite) TestPaymentSystem_GetPaymentSystemsForPaymentMethod_SkipInactive() {
+	suite.psCheckout.IsActive = false
+	err := suite.service.paymentSystemRepository.Update(context.TODO(), suite.psCheckout)
+	assert.NoError(suite.T(), err)
+
+	suite.addRoute("DE", suite.psCheckout, suite.psCardPay)
+
+	list, err := suite.service.getPaymentSystemsForPaymentMethod(context.TODO(), suite.pm, "DE")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), list, 1)
+	assert.Equal(suite.T(), suite.psCardPay.Id, list[0].Id)
+}
+
+func (suite *PaymentSystemTestSuite) TestPaymentSystem_GetPaymentSystemsForPaymentMethod_AllInactive() {
+	suite.addRoute("DE", &billingpb.PaymentSystem{Id: primitive.NewObjectID().Hex()})
+
+	_, err := suite.service.getPaymentSystemsForPaymentMethod(context.TODO(), suite.pm, "DE")
+	assert.Equal(suite.T(), orderErrorPaymentSystemInactive, err)
+}
+
+func (suite *PaymentSystemTestSuite) TestPaymentSystem_GetRecurringPaymentSystems_SkipCheckout() {
+	list := getRecurringPaymentSystems([]*billingpb.PaymentSystem{suite.psCheckout, suite.psCardPay})
+	assert.Len(suite.T(), list, 1)
+	assert.Equal(suite.T(), suite.psCardPay.Id, list[0].Id)
+
+	list = getRecurringPaymentSystems([]*billingpb.PaymentSystem{suite.psCheckout})
+	assert.Empty(suite.T(), list)
+}
+
+func (suite *PaymentSystemTestSuite) TestPaymentSystem_GetPaymentSystemParams() {
+	params := &billingpb.PaymentMethodParams{
+		Currency:       "USD",
+		TerminalId:     "15993",
+		Secret:         "secret",
+		SecretCallback: "secret_callback",
+		MccCode:        billingpb.MccCodeLowRisk,
+	}
+
+	res := suite.service.getPaymentSystemParams(billingpb.PaymentSystemHandlerCardPay, params, false)
+	assert.Equal(suite.T(), params, res)
+
+	res = suite.service.getPaymentSystemParams(pkg.PaymentSystemHandlerCheckout, params, true)
+	assert.Equal(suite.T(), params.Currency, res.Currency)
+	assert.Equal(suite.T(), params.MccCode, res.MccCode)
+	assert.Equal(suite.T(), suite.service.cfg.CheckoutApiUrl, res.ApiUrl)
+	assert.Equal(suite.T(), suite.service.cfg.CheckoutSecretKey, res.Secret)
+	assert.Equal(suite.T(), "15993", params.TerminalId)
+}
+
+func (suite *PaymentSystemTestSuite) getOrder() *billingpb.Order {
+	return &billingpb.Order{
+		Id: primitive.NewObjectID().Hex(),
+		PaymentMethod: &billingpb.PaymentMethodOrder{
+			Id:              suite.pm.Id,
+			PaymentSystemId: suite.psCardPay.Id,
+			Handler:         suite.psCardPay.Handler,
+			ExternalId:      suite.pm.ExternalId,
+			Params:          &billingpb.PaymentMethodParams{Currency: "USD"},
+		},
+	}
+}
+
+func (suite *PaymentSystemTestSuite) mockGateways(cardPayErr error) (*mocks.PaymentSystemInterface, *mocks.PaymentSystemInterface) {
+	cardPay := &mocks.PaymentSystemInterface{}
+	cardPay.On("CreatePayment", mock2.Anything, mock2.Anything, mock2.Anything, mock2.Anything).Return("", cardPayErr)
+
+	checkout := &mocks.PaymentSystemInterface{}
+	checkout.On("CreatePayment", mock2.Anything, mock2.Anything, mock2.Anything, mock2.Anything).Return("http://localhost/checkout", nil)
+
+	gateway := &mocks.PaymentSystemManagerInterface{}
+	gateway.On("GetGateway", suite.psCardPay.Handler).Return(cardPay, nil)
+	gateway.On("GetGateway", suite.psCheckout.Handler).Return(checkout, nil)
+	suite.service.paymentSystemGateway = gateway
+
+	return cardPay, checkout
+}
+
+func (suite *PaymentSystemTestSuite) TestPaymentSystem_CreatePaymentWithFailover_SwitchToNext() {
+	_, checkout := suite.mockGateways(&url.Error{Op: "Post", URL: "http://localhost", Err: errors.New("connection refused")})
+	order := suite.getOrder()
+	ps := []*billingpb.PaymentSystem{suite.psCardPay, suite.psCheckout}
+
+	redirectUrl, err := suite.service.createPaymentWithFailover(context.TODO(), order, ps, order.PaymentMethod.Params, map[string]string{})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "http://localhost/checkout", redirectUrl)
+	assert.Equal(suite.T(), suite.psCheckout.Id, order.PaymentMethod.PaymentSystemId)
+	assert.Equal(suite.T(), pkg.PaymentSystemHandlerCheckout, order.PaymentMethod.Handler)
+	assert.Equal(suite.T(), suite.service.cfg.CheckoutApiSandboxUrl, order.PaymentMethod.Params.ApiUrl)
+	checkout.AssertNumberOfCalls(suite.T(), "CreatePayment", 1)
+
+	history, err := suite.service.orderHistoryRepository.FindByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), history, 1)
+	assert.Equal(suite.T(), pkg.OrderHistoryTypePaymentSystemSwitched, history[0].Type)
+	assert.Equal(suite.T(), suite.psCardPay.Id, history[0].Data[pkg.OrderHistoryFieldPaymentSystemFrom])
+	assert.Equal(suite.T(), suite.psCheckout.Id, history[0].Data[pkg.OrderHistoryFieldPaymentSystemTo])
+}
+
+func (suite *PaymentSystemTestSuite) TestPaymentSystem_CreatePaymentWithFailover_ManualCapture_NotSwitched() {
+	cardPay, checkout := suite.mockGateways(nil)
+	cardPay.On("CreateAuthorization", mock2.Anything, mock2.Anything, mock2.Anything, mock2.Anything).
+		Return("", &url.Error{Op: "Post", URL: "http://localhost", Err: errors.New("connection refused")})
+	order := suite.getOrder()
+	order.PrivateMetadata = map[string]string{pkg.OrderPrivateMetadataCaptureMode: pkg.OrderCaptureModeManual}
+	ps := []*billingpb.PaymentSystem{suite.psCardPay, suite.psCheckout}
+
+	_, err := suite.service.createPaymentWithFailover(context.TODO(), order, ps, order.PaymentMethod.Params, map[string]string{})
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), suite.psCardPay.Id, order.PaymentMethod.PaymentSystemId)
+	checkout.AssertNotCalled(suite.T(), "CreatePayment", mock2.Anything, mock2.Anything, mock2.Anything, mock2.Anything)
+	checkout.AssertNotCalled(suite.T(), "CreateAuthorization", mock2.Anything, mock2.Anything, mock2.Anything, mock2.Anything)
+
+	history, err := suite.service.orderHistoryRepository.FindByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), history)
+}
+
+func (suite *PaymentSystemTestSuite) TestPaymentSystem_CreatePaymentWithFailover_NotRetryableError() {
+	_, checkout := suite.mockGateways(errors.New("declined"))
+	order := suite.getOrder()
+	ps := []*billingpb.PaymentSystem{suite.psCardPay, suite.psCheckout}
+
+	_, err := suite.service.createPaymentWithFailover(context.TODO(), order, ps, order.PaymentMethod.Params, map[string]string{})
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), suite.psCardPay.Id, order.PaymentMethod.PaymentSystemId)
+	checkout.AssertNotCalled(suite.T(), "CreatePayment", mock2.Anything, mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *PaymentSystemTestSuite) TestPaymentSystem_CreatePaymentWithFailover_SkipOpenBreaker() {
+	cardPay, _ := suite.mockGateways(nil)
+	suite.service.paymentSystemBreaker = newPaymentSystemBreaker(1, time.Minute)
+	suite.service.paymentSystemBreaker.Failure(suite.psCardPay.Id)
+
+	order := suite.getOrder()
+	ps := []*billingpb.PaymentSystem{suite.psCardPay, suite.psCheckout}
+
+	redirectUrl, err := suite.service.createPaymentWithFailover(context.TODO(), order, ps, order.PaymentMethod.Params, map[string]string{})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "http://localhost/checkout", redirectUrl)
+	assert.Equal(suite.T(), suite.psCheckout.Id, order.PaymentMethod.PaymentSystemId)
+	cardPay.AssertNotCalled(suite.T(), "CreatePayment", mock2.Anything, mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *PaymentSystemTestSuite) TestPaymentSystem_CreatePaymentWithFailover_AllUnavailable() {
+	suite.mockGateways(&url.Error{Op: "Post", URL: "http://localhost", Err: errors.New("timeout")})
+	suite.service.paymentSystemBreaker = newPaymentSystemBreaker(1, time.Minute)
+
+	order := suite.getOrder()
+	ps := []*billingpb.PaymentSystem{suite.psCardPay}
+
+	_, err := suite.service.createPaymentWithFailover(context.TODO(), order, ps, order.PaymentMethod.Params, map[string]string{})
+	assert.Error(suite.T(), err)
+	assert.False(suite.T(), suite.service.paymentSystemBreaker.IsAvailable(suite.psCardPay.Id))
+
+	_, err = suite.service.createPaymentWithFailover(context.TODO(), order, ps, order.PaymentMethod.Params, map[string]string{})
+	assert.Equal(suite.T(), orderErrorPaymentSystemTemporaryUnavailable, err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/jinzhu/now"
 	"github.com/paysuper/paysuper-billing-server/internal/helper"
@@ -47,6 +48,7 @@ var (
 	errorPayoutAutoPayoutsDisabled     = errors.NewBillingServerErrorMsg("po000016", "auto payouts disabled")
 	errorPayoutAutoPayoutsWithErrors   = errors.NewBillingServerErrorMsg("po000017", "auto payouts creation finished with errors")
 	errorPayoutRequireFailureFields    = errors.NewBillingServerErrorMsg("po000018", "fields failure_code and failure_message is required when status changing to failure")
+	errorPayoutUpdateConversions       = errors.NewBillingServerErrorMsg("po000019", "merchant balance conversions update failed")
 
 	statusForUpdateBalance = map[string]bool{
 		pkg.PayoutDocumentStatusPending: true,
@@ -89,11 +91,109 @@ func (s *Service) CreatePayoutDocument(
 	return s.createPayoutDocument(ctx, merchant, req, res)
 }
 
+// createPayoutDocument creates payout documents of the merchant, one document per currency in which the merchant
+// has royalty reports or balance conversions which aren't included to payouts yet. Currencies for which the document
+// can't be created are skipped, the error of the first of them is returned if no documents created.
 func (s *Service) createPayoutDocument(
 	ctx context.Context,
 	merchant *billingpb.Merchant,
 	req *billingpb.CreatePayoutDocumentRequest,
 	res *billingpb.CreatePayoutDocumentResponse,
+) error {
+	currencies, err := s.getPayoutDocumentCurrencies(ctx, merchant)
+
+	if err != nil {
+		return err
+	}
+
+	var (
+		items  []*billingpb.PayoutDocument
+		failed *billingpb.CreatePayoutDocumentResponse
+	)
+
+	for _, currency := range currencies {
+		rsp := &billingpb.CreatePayoutDocumentResponse{}
+		err = s.createPayoutDocumentInCurrency(ctx, merchant, currency, req, rsp)
+
+		if err != nil {
+			return err
+		}
+
+		if rsp.Status == billingpb.ResponseStatusOk {
+			items = append(items, rsp.Items...)
+			continue
+		}
+
+		if rsp.Status == billingpb.ResponseStatusSystemError {
+			res.Status = rsp.Status
+			res.Message = rsp.Message
+			return nil
+		}
+
+		if failed == nil {
+			failed = rsp
+		}
+	}
+
+	if len(items) <= 0 {
+		res.Status = failed.Status
+		res.Message = failed.Message
+		return nil
+	}
+
+	res.Items = append(res.Items, items...)
+	res.Status = billingpb.ResponseStatusOk
+	res.Message = nil
+
+	return nil
+}
+
+// getPayoutDocumentCurrencies returns the payout currency of the merchant followed by other currencies of royalty
+// reports and balance conversions of the merchant which aren't included to payouts yet.
+func (s *Service) getPayoutDocumentCurrencies(ctx context.Context, merchant *billingpb.Merchant) ([]string, error) {
+	var currencies []string
+
+	reports, err := s.royaltyReportRepository.GetNonPayoutReports(ctx, merchant.Id, "")
+
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	for _, report := range reports {
+		currencies = append(currencies, report.Currency)
+	}
+
+	conversions, err := s.merchantBalanceConversionRepository.GetNonPayoutConversions(ctx, merchant.Id, "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, conversion := range conversions {
+		if conversion.FromPayoutDocumentId == "" {
+			currencies = append(currencies, conversion.FromCurrency)
+		}
+
+		if conversion.ToPayoutDocumentId == "" {
+			currencies = append(currencies, conversion.ToCurrency)
+		}
+	}
+
+	result := getMerchantCurrencies(merchant.GetPayoutCurrency(), currencies)
+
+	if len(currencies) > 0 && !helper.Contains(currencies, merchant.GetPayoutCurrency()) {
+		result = result[1:]
+	}
+
+	return result, nil
+}
+
+func (s *Service) createPayoutDocumentInCurrency(
+	ctx context.Context,
+	merchant *billingpb.Merchant,
+	currency string,
+	req *billingpb.CreatePayoutDocumentRequest,
+	res *billingpb.CreatePayoutDocumentResponse,
 ) error {
 	arrivalDate, err := ptypes.TimestampProto(now.EndOfDay().Add(time.Hour * 24 * payoutArrivalInDays))
 	if err != nil {
@@ -121,9 +221,22 @@ func (s *Service) createPayoutDocument(
 		AutoincrementId:         autoincrementId,
 	}
 
-	reports, err := s.getPayoutDocumentSources(ctx, merchant)
+	lockToken, err := s.lockMerchantBalance(merchant.Id, currency)
 
 	if err != nil {
+		if err == errorMerchantBalanceLocked {
+			res.Status = billingpb.ResponseStatusBadData
+			res.Message = errorMerchantBalanceLocked
+			return nil
+		}
+		return err
+	}
+
+	defer s.unlockMerchantBalance(merchant.Id, currency, lockToken)
+
+	reports, err := s.getPayoutDocumentSources(ctx, merchant, currency)
+
+	if err != nil && err != errorPayoutSourcesNotFound {
 		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
 			res.Status = billingpb.ResponseStatusBadData
 			res.Message = e
@@ -132,7 +245,19 @@ func (s *Service) createPayoutDocument(
 		return err
 	}
 
-	pd.Currency = reports[0].Currency
+	conversions, err := s.merchantBalanceConversionRepository.GetNonPayoutConversions(ctx, merchant.Id, currency)
+
+	if err != nil {
+		return err
+	}
+
+	if len(reports) <= 0 && len(conversions) <= 0 {
+		res.Status = billingpb.ResponseStatusBadData
+		res.Message = errorPayoutSourcesNotFound
+		return nil
+	}
+
+	pd.Currency = currency
 
 	times := make([]time.Time, 0)
 	stringTimes := make([]string, 0)
@@ -192,6 +317,27 @@ func (s *Service) createPayoutDocument(
 		stringTimes = append(stringTimes, r.StringPeriodFrom, r.StringPeriodTo)
 	}
 
+	conversionIds := make([]primitive.ObjectID, 0, len(conversions))
+
+	for _, c := range conversions {
+		amount := float64(0)
+
+		if c.ToCurrency == currency && c.ToPayoutDocumentId == "" {
+			amount += c.ToAmount
+		}
+
+		if c.FromCurrency == currency && c.FromPayoutDocumentId == "" {
+			amount -= c.FromAmount
+		}
+
+		totalFeesAmount += amount
+		balanceAmount += amount
+
+		conversionIds = append(conversionIds, c.Id)
+		times = append(times, c.CreatedAt)
+		stringTimes = append(stringTimes, c.CreatedAt.Format("2006-01-02"))
+	}
+
 	pd.TotalFees = math.Round(totalFeesAmount*100) / 100
 	pd.Balance = math.Round(balanceAmount*100) / 100
 
@@ -201,7 +347,10 @@ func (s *Service) createPayoutDocument(
 		return nil
 	}
 
-	balance, err := s.getMerchantBalance(ctx, merchant.Id)
+	balance, err := s.getMerchantBalance(ctx, merchant.Id, currency)
+	if err == mongo.ErrNoDocuments {
+		balance, err = s.updateMerchantBalance(ctx, merchant.Id, currency)
+	}
 	if err != nil {
 		res.Status = billingpb.ResponseStatusSystemError
 		res.Message = errorPayoutBalanceError
@@ -271,7 +420,17 @@ func (s *Service) createPayoutDocument(
 		return err
 	}
 
-	_, err = s.updateMerchantBalance(ctx, merchant.Id)
+	if len(conversionIds) > 0 {
+		err = s.merchantBalanceConversionRepository.SetPayoutDocumentId(ctx, conversionIds, currency, pd.Id)
+
+		if err != nil {
+			res.Status = billingpb.ResponseStatusSystemError
+			res.Message = errorPayoutUpdateConversions
+			return nil
+		}
+	}
+
+	_, err = s.updateMerchantBalance(ctx, merchant.Id, currency)
 	if err != nil {
 		e, ok := err.(*billingpb.ResponseErrorMessage)
 
@@ -480,6 +639,7 @@ func (s *Service) UpdatePayoutDocument(
 		return err
 	}
 
+	before := proto.Clone(pd)
 	isChanged := false
 	needBalanceUpdate := false
 
@@ -534,6 +694,8 @@ func (s *Service) UpdatePayoutDocument(
 		pd.FailureTransaction = req.FailureTransaction
 	}
 
+	var auditErr error
+
 	if isChanged {
 		err = s.payoutRepository.Update(ctx, pd, req.Ip, payoutChangeSourceAdmin)
 		if err != nil {
@@ -545,6 +707,17 @@ func (s *Service) UpdatePayoutDocument(
 			return err
 		}
 
+		// The payout document is already saved, so the failed audit log doesn't stop the update of royalty reports
+		// and the balance
+		auditErr = s.addAuditLog(ctx, &auditLogRecord{
+			rpc:        "UpdatePayoutDocument",
+			entityType: pkg.AuditLogEntityPayoutDocument,
+			entityId:   pd.Id,
+			ip:         req.Ip,
+			before:     before,
+			after:      pd,
+		})
+
 		if becomePaid == true {
 			err = s.royaltyReportSetPaid(ctx, pd.SourceId, pd.Id, req.Ip, pkg.RoyaltyReportChangeSourceAdmin)
 			if err != nil {
@@ -563,6 +736,14 @@ func (s *Service) UpdatePayoutDocument(
 
 					return nil
 				}
+
+				err = s.merchantBalanceConversionRepository.UnsetPayoutDocumentId(ctx, pd.Id)
+				if err != nil {
+					res.Status = billingpb.ResponseStatusSystemError
+					res.Message = errorPayoutUpdateConversions
+
+					return nil
+				}
 			}
 		}
 
@@ -572,7 +753,7 @@ func (s *Service) UpdatePayoutDocument(
 	}
 
 	if needBalanceUpdate == true {
-		_, err = s.updateMerchantBalance(ctx, pd.MerchantId)
+		_, err = s.updateMerchantBalance(ctx, pd.MerchantId, pd.Currency)
 		if err != nil {
 			res.Status = billingpb.ResponseStatusSystemError
 			res.Message = errorPayoutUpdateBalance
@@ -581,6 +762,12 @@ func (s *Service) UpdatePayoutDocument(
 		}
 	}
 
+	if auditErr != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	res.Item = pd
 	return nil
 }
@@ -719,8 +906,9 @@ func (s *Service) PayoutDocumentPdfUploaded(
 func (s *Service) getPayoutDocumentSources(
 	ctx context.Context,
 	merchant *billingpb.Merchant,
+	currency string,
 ) ([]*billingpb.RoyaltyReport, error) {
-	result, err := s.royaltyReportRepository.GetNonPayoutReports(ctx, merchant.Id, merchant.GetPayoutCurrency())
+	result, err := s.royaltyReportRepository.GetNonPayoutReports(ctx, merchant.Id, currency)
 
 	if err != nil && err != mongo.ErrNoDocuments {
 		return nil, err
@@ -963,7 +1151,7 @@ func (s *Service) TaskRebuildPayoutsRoyalties() error {
 	merchants, err := s.merchantRepository.GetAll(ctx)
 
 	for _, item := range merchants {
-		_, _ = s.updateMerchantBalance(ctx, item.Id)
+		_ = s.updateMerchantBalances(ctx, item.Id)
 	}
 
 	return nil
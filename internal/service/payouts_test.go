@@ -10,6 +10,7 @@ import (
 	"github.com/paysuper/paysuper-billing-server/internal/config"
 	"github.com/paysuper/paysuper-billing-server/internal/database"
 	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	errors2 "github.com/paysuper/paysuper-billing-server/pkg/errors"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
@@ -589,7 +590,7 @@ func (suite *PayoutsTestSuite) helperInsertPayoutDocuments(data []*billingpb.Pay
 func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Ok_NoPayoutsYet() {
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report6})
 
-	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant)
+	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency())
 	assert.NoError(suite.T(), err)
 	assert.Len(suite.T(), reports, 2)
 }
@@ -597,20 +598,20 @@ func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Ok_NoPayouts
 func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Ok_FilteringByCurrency() {
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report5, suite.report6})
 
-	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant)
+	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency())
 	assert.NoError(suite.T(), err)
 	assert.Len(suite.T(), reports, 2)
 }
 
 func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Fail_NotFound() {
-	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant)
+	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency())
 	assert.EqualError(suite.T(), err, errorPayoutSourcesNotFound.Error())
 	assert.Len(suite.T(), reports, 0)
 }
 
 func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Fail_MerchantNotFound() {
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report6})
-	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), &billingpb.Merchant{Id: primitive.NewObjectID().Hex()})
+	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), &billingpb.Merchant{Id: primitive.NewObjectID().Hex()}, suite.merchant.GetPayoutCurrency())
 	assert.EqualError(suite.T(), err, errorPayoutSourcesNotFound.Error())
 	assert.Len(suite.T(), reports, 0)
 }
@@ -618,7 +619,7 @@ func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Fail_Merchan
 func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Fail_HasPendingReports() {
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report3})
 
-	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant)
+	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency())
 	assert.EqualError(suite.T(), err, errorPayoutSourcesPending.Error())
 	assert.Len(suite.T(), reports, 0)
 }
@@ -626,7 +627,7 @@ func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Fail_HasPend
 func (suite *PayoutsTestSuite) TestPayouts_getPayoutDocumentSources_Fail_HasDisputingReports() {
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report7})
 
-	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant)
+	reports, err := suite.service.getPayoutDocumentSources(context.TODO(), suite.merchant, suite.merchant.GetPayoutCurrency())
 	assert.EqualError(suite.T(), err, errorPayoutSourcesDispute.Error())
 	assert.Len(suite.T(), reports, 0)
 }
@@ -638,7 +639,7 @@ func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Ok_Pending() {
 
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report2})
 
-	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id)
+	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id, "")
 	assert.NoError(suite.T(), err)
 
 	req := &billingpb.CreatePayoutDocumentRequest{
@@ -670,7 +671,7 @@ func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Ok_SkipByAmount(
 
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report2})
 
-	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id)
+	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id, "")
 	assert.NoError(suite.T(), err)
 
 	req := &billingpb.CreatePayoutDocumentRequest{
@@ -703,7 +704,7 @@ func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Ok_SkipByRolling
 
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report2})
 
-	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id)
+	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id, "")
 	assert.NoError(suite.T(), err)
 
 	req1 := &billingpb.CreatePayoutDocumentRequest{
@@ -759,10 +760,111 @@ func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Failed_MerchantN
 	assert.Equal(suite.T(), err, merchantErrorNotFound)
 }
 
+func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Ok_PerCurrency() {
+	reporting := &reportingMocks.ReporterService{}
+	reporting.On("CreateFile", mock2.Anything, mock2.Anything).Return(nil, nil)
+	suite.service.reporterService = reporting
+
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report2, suite.report5})
+
+	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id, "")
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id, suite.report5.Currency)
+	assert.NoError(suite.T(), err)
+
+	req := &billingpb.CreatePayoutDocumentRequest{
+		MerchantId:  suite.merchant.Id,
+		Description: "test payout",
+		Ip:          "127.0.0.1",
+	}
+
+	res := &billingpb.CreatePayoutDocumentResponse{}
+
+	err = suite.service.CreatePayoutDocument(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), res.Status, billingpb.ResponseStatusOk)
+	assert.Len(suite.T(), res.Items, 2)
+
+	assert.Equal(suite.T(), res.Items[0].Currency, suite.merchant.GetPayoutCurrency())
+	assert.EqualValues(suite.T(), res.Items[0].Balance, 13579.5)
+	assert.Len(suite.T(), res.Items[0].SourceId, 2)
+
+	assert.Equal(suite.T(), res.Items[1].Currency, suite.report5.Currency)
+	assert.EqualValues(suite.T(), res.Items[1].Balance, suite.report5.Totals.PayoutAmount)
+	assert.Equal(suite.T(), res.Items[1].Status, pkg.PayoutDocumentStatusSkip)
+	assert.Equal(suite.T(), res.Items[1].SourceId, []string{suite.report5.Id})
+	assert.NotEqual(suite.T(), res.Items[0].AutoincrementId, res.Items[1].AutoincrementId)
+}
+
+func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Ok_WithBalanceConversion() {
+	reporting := &reportingMocks.ReporterService{}
+	reporting.On("CreateFile", mock2.Anything, mock2.Anything).Return(nil, nil)
+	suite.service.reporterService = reporting
+
+	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report2})
+
+	merchantOid, err := primitive.ObjectIDFromHex(suite.merchant.Id)
+	assert.NoError(suite.T(), err)
+
+	conversion := &intPkg.MerchantBalanceConversion{
+		Id:           primitive.NewObjectID(),
+		MerchantId:   merchantOid,
+		FromCurrency: suite.merchant.GetPayoutCurrency(),
+		FromAmount:   1000,
+		ToCurrency:   "USD",
+		ToAmount:     15,
+		Rate:         0.015,
+		RateType:     "paysuper",
+		CreatedAt:    time.Now().UTC(),
+	}
+	err = suite.service.merchantBalanceConversionRepository.Insert(context.TODO(), conversion)
+	assert.NoError(suite.T(), err)
+
+	err = suite.service.updateMerchantBalances(context.TODO(), suite.merchant.Id)
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id, conversion.ToCurrency)
+	assert.NoError(suite.T(), err)
+
+	req := &billingpb.CreatePayoutDocumentRequest{
+		MerchantId:  suite.merchant.Id,
+		Description: "test payout",
+		Ip:          "127.0.0.1",
+	}
+
+	res := &billingpb.CreatePayoutDocumentResponse{}
+
+	err = suite.service.CreatePayoutDocument(context.TODO(), req, res)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), res.Status, billingpb.ResponseStatusOk)
+	assert.Len(suite.T(), res.Items, 2)
+
+	assert.Equal(suite.T(), res.Items[0].Currency, suite.merchant.GetPayoutCurrency())
+	assert.EqualValues(suite.T(), res.Items[0].Balance, 12579.5)
+	assert.Len(suite.T(), res.Items[0].SourceId, 2)
+
+	assert.Equal(suite.T(), res.Items[1].Currency, conversion.ToCurrency)
+	assert.EqualValues(suite.T(), res.Items[1].Balance, conversion.ToAmount)
+	assert.Empty(suite.T(), res.Items[1].SourceId)
+
+	conversions, err := suite.service.merchantBalanceConversionRepository.GetNonPayoutConversions(context.TODO(), suite.merchant.Id, "")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), conversions)
+
+	conversions, err = suite.service.merchantBalanceConversionRepository.Find(context.TODO(), suite.merchant.Id, "", 0, 0)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), conversions, 1)
+	assert.Equal(suite.T(), conversions[0].FromPayoutDocumentId, res.Items[0].Id)
+	assert.Equal(suite.T(), conversions[0].ToPayoutDocumentId, res.Items[1].Id)
+
+	balance, err := suite.service.getMerchantBalance(context.TODO(), suite.merchant.Id, conversion.ToCurrency)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), balance.Total, 0)
+}
+
 func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Failed_ZeroAmount() {
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report4})
 
-	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id)
+	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id, "")
 	assert.NoError(suite.T(), err)
 
 	req := &billingpb.CreatePayoutDocumentRequest{
@@ -801,7 +903,7 @@ func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Failed_NotEnough
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1})
 	suite.helperInsertPayoutDocuments([]*billingpb.PayoutDocument{suite.payout2})
 
-	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id)
+	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id, "")
 	assert.NoError(suite.T(), err)
 
 	req := &billingpb.GetMerchantBalanceRequest{
@@ -842,7 +944,7 @@ func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Failed_InsertErr
 
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report2})
 
-	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id)
+	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id, "")
 	assert.NoError(suite.T(), err)
 
 	req := &billingpb.CreatePayoutDocumentRequest{
@@ -867,7 +969,7 @@ func (suite *PayoutsTestSuite) TestPayouts_CreatePayoutDocument_Failed_InsertErr
 
 	suite.helperInsertRoyaltyReports([]*billingpb.RoyaltyReport{suite.report1, suite.report2})
 
-	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id)
+	_, err := suite.service.updateMerchantBalance(context.TODO(), suite.merchant.Id, "")
 	assert.NoError(suite.T(), err)
 
 	req := &billingpb.CreatePayoutDocumentRequest{
@@ -0,0 +1,589 @@
+package service
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/paysuper/paysuper-billing-server/internal/helper"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	tools "github.com/paysuper/paysuper-tools/number"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	promoCodeErrorUnknown            = errors.NewBillingServerErrorMsg("prmc0001", "promo code can't be processed. try request later")
+	promoCodeErrorNotFound           = errors.NewBillingServerErrorMsg("prmc0002", "promo code with specified data not found")
+	promoCodeErrorAlreadyExists      = errors.NewBillingServerErrorMsg("prmc0003", "promo code with specified code already exists in project")
+	promoCodeErrorPercentInvalid     = errors.NewBillingServerErrorMsg("prmc0004", "percent of promo code must be greater than 0 and less than 100")
+	promoCodeErrorAmountsRequired    = errors.NewBillingServerErrorMsg("prmc0005", "amounts of fixed promo code are required")
+	promoCodeErrorPeriodInvalid      = errors.NewBillingServerErrorMsg("prmc0006", "expiration date of promo code must be later than start date")
+	promoCodeErrorInactive           = errors.NewBillingServerErrorMsg("prmc0007", "promo code is inactive or expired")
+	promoCodeErrorUsageLimitReached  = errors.NewBillingServerErrorMsg("prmc0008", "promo code usage limit reached")
+	promoCodeErrorNotApplicable      = errors.NewBillingServerErrorMsg("prmc0009", "promo code isn't applicable to order")
+	promoCodeErrorOrderStatusInvalid = errors.NewBillingServerErrorMsg("prmc0010", "promo code can't be changed for order in current status")
+
+	// public statuses of orders counted as usages of the promo code
+	promoCodeUsageOrderStatuses = []string{
+		recurringpb.OrderPublicStatusProcessed,
+		recurringpb.OrderPublicStatusRefunded,
+		recurringpb.OrderPublicStatusChargeback,
+	}
+)
+
+// CreateOrUpdatePromoCode saves the promo code of the project. Code is case insensitive and unique in the project.
+func (s *Service) CreateOrUpdatePromoCode(
+	ctx context.Context,
+	req *pkg.CreateOrUpdatePromoCodeRequest,
+	rsp *pkg.PromoCodeResponse,
+) error {
+	project, err := s.project.GetById(ctx, req.ProjectId)
+
+	if err != nil || project.MerchantId != req.MerchantId {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = projectErrorNotFound
+		return nil
+	}
+
+	if req.Type == pkg.PromoCodeTypePercent && (req.Percent <= 0 || req.Percent >= 100) {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = promoCodeErrorPercentInvalid
+		return nil
+	}
+
+	if req.Type == pkg.PromoCodeTypeFixed && len(req.Amounts) <= 0 {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = promoCodeErrorAmountsRequired
+		return nil
+	}
+
+	var startsAt, expiresAt time.Time
+
+	if req.StartsAt != nil {
+		startsAt, _ = ptypes.Timestamp(req.StartsAt)
+	}
+
+	if req.ExpiresAt != nil {
+		expiresAt, err = ptypes.Timestamp(req.ExpiresAt)
+
+		if err != nil || (!startsAt.IsZero() && !expiresAt.After(startsAt)) {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = promoCodeErrorPeriodInvalid
+			return nil
+		}
+	}
+
+	code := normalizePromoCode(req.Code)
+	promoCode := &intPkg.PromoCode{}
+
+	if req.Id != "" {
+		promoCode, err = s.promoCodeRepository.GetById(ctx, req.Id)
+
+		if err != nil || promoCode.MerchantId.Hex() != req.MerchantId {
+			rsp.Status = billingpb.ResponseStatusNotFound
+			rsp.Message = promoCodeErrorNotFound
+			return nil
+		}
+	}
+
+	if existing, err := s.promoCodeRepository.GetByCode(ctx, req.ProjectId, code); err == nil && existing.Id != promoCode.Id {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = promoCodeErrorAlreadyExists
+		return nil
+	}
+
+	promoCode.MerchantId, _ = primitive.ObjectIDFromHex(req.MerchantId)
+	promoCode.ProjectId, _ = primitive.ObjectIDFromHex(req.ProjectId)
+	promoCode.Code = code
+	promoCode.Type = req.Type
+	promoCode.Percent = 0
+	promoCode.Amounts = []*intPkg.PromoCodeAmount{}
+	promoCode.ProductIds = req.ProductIds
+	promoCode.StartsAt = startsAt
+	promoCode.ExpiresAt = expiresAt
+	promoCode.MaxUses = req.MaxUses
+	promoCode.MaxUsesPerCustomer = req.MaxUsesPerCustomer
+	promoCode.IsActive = req.IsActive
+
+	if req.Type == pkg.PromoCodeTypePercent {
+		promoCode.Percent = req.Percent
+	} else {
+		for _, amount := range req.Amounts {
+			promoCode.Amounts = append(promoCode.Amounts, &intPkg.PromoCodeAmount{
+				Currency: strings.ToUpper(amount.Currency),
+				Amount:   amount.Amount,
+			})
+		}
+	}
+
+	if promoCode.Id.IsZero() {
+		err = s.promoCodeRepository.Insert(ctx, promoCode)
+	} else {
+		err = s.promoCodeRepository.Update(ctx, promoCode)
+	}
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = promoCodeErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getPromoCodeMessage(promoCode)
+
+	return nil
+}
+
+func (s *Service) GetPromoCode(
+	ctx context.Context,
+	req *pkg.GetPromoCodeRequest,
+	rsp *pkg.PromoCodeResponse,
+) error {
+	promoCode, err := s.promoCodeRepository.GetById(ctx, req.Id)
+
+	if err != nil || promoCode.MerchantId.Hex() != req.MerchantId {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = promoCodeErrorNotFound
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getPromoCodeMessage(promoCode)
+
+	return nil
+}
+
+func (s *Service) ListPromoCodes(
+	ctx context.Context,
+	req *pkg.ListPromoCodesRequest,
+	rsp *pkg.ListPromoCodesResponse,
+) error {
+	project, err := s.project.GetById(ctx, req.ProjectId)
+
+	if err != nil || project.MerchantId != req.MerchantId {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = projectErrorNotFound
+		return nil
+	}
+
+	promoCodes, err := s.promoCodeRepository.GetByProjectId(ctx, req.ProjectId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = promoCodeErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Items = []*pkg.PromoCode{}
+
+	for _, promoCode := range promoCodes {
+		rsp.Items = append(rsp.Items, getPromoCodeMessage(promoCode))
+	}
+
+	return nil
+}
+
+// ApplyPromoCode applies the promo code entered by customer on the payment form to the order and recalculates
+// the order amounts with the discount. The promo code applied before is replaced, empty code removes it.
+func (s *Service) ApplyPromoCode(
+	ctx context.Context,
+	req *pkg.ApplyPromoCodeRequest,
+	rsp *pkg.ApplyPromoCodeResponse,
+) error {
+	order, err := s.getOrderByUuidToForm(ctx, req.OrderId)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = e
+			return nil
+		}
+		return err
+	}
+
+	if order.PrivateStatus != recurringpb.OrderStatusNew {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = promoCodeErrorOrderStatusInvalid
+		return nil
+	}
+
+	s.releaseOrderPromoCode(ctx, order)
+	removed := s.removeOrderPromoCode(order)
+	code := normalizePromoCode(req.Code)
+
+	if code != "" {
+		promoCode, err := s.promoCodeRepository.GetByCode(ctx, order.GetProjectId(), code)
+
+		if err != nil {
+			rsp.Status = billingpb.ResponseStatusNotFound
+			rsp.Message = promoCodeErrorNotFound
+			return nil
+		}
+
+		discount, err := s.getPromoCodeDiscount(ctx, promoCode, order, order.OrderAmount)
+
+		if err != nil {
+			if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+				rsp.Status = billingpb.ResponseStatusBadData
+				rsp.Message = e
+				return nil
+			}
+			return err
+		}
+
+		s.setOrderPromoCode(order, promoCode, discount)
+		s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypePromoCodeApplied, map[string]string{
+			pkg.OrderHistoryFieldPromoCode:      promoCode.Code,
+			pkg.OrderHistoryFieldDiscountAmount: strconv.FormatFloat(discount, 'f', -1, 64),
+		})
+	} else if removed != "" {
+		s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypePromoCodeRemoved, map[string]string{
+			pkg.OrderHistoryFieldPromoCode: removed,
+		})
+	}
+
+	processor := &OrderCreateRequestProcessor{Service: s, ctx: ctx}
+
+	if err = processor.processOrderVat(order); err != nil {
+		zap.S().Errorw(pkg.MethodFinishedWithError, "err", err.Error(), "method", "processOrderVat")
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = e
+			return nil
+		}
+		return err
+	}
+
+	if err = s.setOrderChargeAmountAndCurrency(ctx, order); err != nil {
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = e
+			return nil
+		}
+		return err
+	}
+
+	if err = s.updateOrder(ctx, order); err != nil {
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = e
+			return nil
+		}
+		return err
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Code, rsp.DiscountAmount = getOrderDiscount(order)
+	rsp.Item = &billingpb.ProcessBillingAddressResponseItem{
+		HasVat:               order.Tax.Rate > 0,
+		VatRate:              tools.ToPrecise(order.Tax.Rate),
+		Vat:                  order.Tax.Amount,
+		VatInChargeCurrency:  s.FormatAmount(order.GetTaxAmountInChargeCurrency(), order.Currency),
+		Amount:               order.OrderAmount,
+		TotalAmount:          order.TotalPaymentAmount,
+		Currency:             order.Currency,
+		ChargeCurrency:       order.ChargeCurrency,
+		ChargeAmount:         order.ChargeAmount,
+		Items:                order.Items,
+		CountryChangeAllowed: order.CountryChangeAllowed(),
+	}
+
+	return nil
+}
+
+// reapplyOrderPromoCode applies the promo code of the order again after the order amount is recalculated by
+// products. Promo code which isn't applicable to the order anymore (for example because of the currency change)
+// is removed from the order.
+func (s *Service) reapplyOrderPromoCode(ctx context.Context, order *billingpb.Order) {
+	id, ok := order.PrivateMetadata[pkg.OrderPrivateMetadataPromoCodeId]
+
+	if !ok {
+		return
+	}
+
+	// order amount is recalculated already, so the saved amount before discount is outdated
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataAmountBeforeDiscount)
+	s.releaseOrderPromoCode(ctx, order)
+	code := s.removeOrderPromoCode(order)
+
+	promoCode, err := s.promoCodeRepository.GetById(ctx, id)
+	discount := float64(0)
+
+	if err == nil {
+		discount, err = s.getPromoCodeDiscount(ctx, promoCode, order, order.OrderAmount)
+	}
+
+	if err != nil {
+		zap.L().Info(
+			"promo code removed from order",
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+			zap.String("promo_code", code),
+		)
+		s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypePromoCodeRemoved, map[string]string{
+			pkg.OrderHistoryFieldPromoCode: code,
+			pkg.OrderHistoryFieldReason:    err.Error(),
+		})
+		return
+	}
+
+	s.setOrderPromoCode(order, promoCode, discount)
+}
+
+// validateOrderPromoCode checks that the promo code applied to the order is still valid before the payment.
+func (s *Service) validateOrderPromoCode(ctx context.Context, order *billingpb.Order) error {
+	id, ok := order.PrivateMetadata[pkg.OrderPrivateMetadataPromoCodeId]
+
+	if !ok {
+		return nil
+	}
+
+	promoCode, err := s.promoCodeRepository.GetById(ctx, id)
+
+	if err != nil {
+		return promoCodeErrorInactive
+	}
+
+	amount, err := strconv.ParseFloat(order.PrivateMetadata[pkg.OrderPrivateMetadataAmountBeforeDiscount], 64)
+
+	if err != nil {
+		return promoCodeErrorUnknown
+	}
+
+	_, err = s.getPromoCodeDiscount(ctx, promoCode, order, amount)
+
+	return err
+}
+
+// getPromoCodeDiscount checks the promo code for the order and returns the discount amount in the order currency.
+// Discount of the promo code with product restrictions is calculated from amounts of the restricted products only.
+func (s *Service) getPromoCodeDiscount(
+	ctx context.Context,
+	promoCode *intPkg.PromoCode,
+	order *billingpb.Order,
+	amount float64,
+) (float64, error) {
+	now := time.Now()
+
+	if !promoCode.IsActive || promoCode.ProjectId.Hex() != order.GetProjectId() ||
+		(!promoCode.StartsAt.IsZero() && now.Before(promoCode.StartsAt)) ||
+		(!promoCode.ExpiresAt.IsZero() && now.After(promoCode.ExpiresAt)) {
+		return 0, promoCodeErrorInactive
+	}
+
+	base := amount
+
+	if len(promoCode.ProductIds) > 0 {
+		base = 0
+
+		for _, item := range order.Items {
+			if helper.Contains(promoCode.ProductIds, item.Id) {
+				base += item.Amount
+			}
+		}
+	}
+
+	discount := float64(0)
+
+	if promoCode.Type == pkg.PromoCodeTypePercent {
+		discount = base * promoCode.Percent / 100
+	} else {
+		for _, v := range promoCode.Amounts {
+			if v.Currency == order.Currency {
+				discount = v.Amount
+				break
+			}
+		}
+	}
+
+	if discount > base {
+		discount = base
+	}
+
+	discount = s.FormatAmount(discount, order.Currency)
+
+	if discount <= 0 || discount >= amount {
+		return 0, promoCodeErrorNotApplicable
+	}
+
+	if promoCode.MaxUses > 0 {
+		count := int64(len(promoCode.ReservedOrderIds))
+
+		// the order which already reserved the promo code is one of its uses
+		if helper.Contains(promoCode.ReservedOrderIds, order.Id) {
+			count--
+		}
+
+		if count >= promoCode.MaxUses {
+			return 0, promoCodeErrorUsageLimitReached
+		}
+	}
+
+	if promoCode.MaxUsesPerCustomer > 0 && order.User != nil && order.User.Id != "" {
+		count, err := s.countPromoCodeUsages(ctx, promoCode, order.User.Id)
+
+		if err != nil {
+			return 0, promoCodeErrorUnknown
+		}
+
+		if count >= promoCode.MaxUsesPerCustomer {
+			return 0, promoCodeErrorUsageLimitReached
+		}
+	}
+
+	return discount, nil
+}
+
+// reserveOrderPromoCode reserves the use of the promo code applied to the order before the payment. Orders are
+// reserved by the conditional update, so concurrent payments can't exceed the usage limit of the promo code and
+// the usage limit per customer.
+// Repeated payment attempt of the order doesn't take one more use of the promo code.
+func (s *Service) reserveOrderPromoCode(ctx context.Context, order *billingpb.Order) error {
+	id, ok := order.PrivateMetadata[pkg.OrderPrivateMetadataPromoCodeId]
+
+	if !ok {
+		return nil
+	}
+
+	promoCode, err := s.promoCodeRepository.GetById(ctx, id)
+
+	if err != nil {
+		return promoCodeErrorInactive
+	}
+
+	customerId := ""
+
+	if order.User != nil {
+		customerId = order.User.Id
+	}
+
+	if promoCode.MaxUses <= 0 && (promoCode.MaxUsesPerCustomer <= 0 || customerId == "") {
+		return nil
+	}
+
+	reserved, err := s.promoCodeRepository.ReserveOrder(ctx, promoCode, order.Id, customerId)
+
+	if err != nil {
+		return promoCodeErrorUnknown
+	}
+
+	if !reserved {
+		return promoCodeErrorUsageLimitReached
+	}
+
+	return nil
+}
+
+// releaseOrderPromoCode returns the use of the promo code reserved by the order which payment failed, which review
+// is rejected or expired or which promo code is removed. Failure is logged by repository only because the order is
+// already rejected.
+func (s *Service) releaseOrderPromoCode(ctx context.Context, order *billingpb.Order) {
+	id, ok := order.PrivateMetadata[pkg.OrderPrivateMetadataPromoCodeId]
+
+	if !ok {
+		return
+	}
+
+	_ = s.promoCodeRepository.ReleaseOrder(ctx, id, order.Id)
+}
+
+// countPromoCodeUsages returns the number of paid orders with the promo code, of the customer if it's specified.
+func (s *Service) countPromoCodeUsages(ctx context.Context, promoCode *intPkg.PromoCode, customerId string) (int64, error) {
+	query := bson.M{
+		"private_metadata." + pkg.OrderPrivateMetadataPromoCodeId: promoCode.Id.Hex(),
+		"status": bson.M{"$in": promoCodeUsageOrderStatuses},
+		"type":   pkg.OrderTypeOrder,
+	}
+
+	if customerId != "" {
+		query["user.id"] = customerId
+	}
+
+	return s.orderRepository.CountBy(ctx, query)
+}
+
+func (s *Service) setOrderPromoCode(order *billingpb.Order, promoCode *intPkg.PromoCode, discount float64) {
+	if order.PrivateMetadata == nil {
+		order.PrivateMetadata = make(map[string]string)
+	}
+
+	order.PrivateMetadata[pkg.OrderPrivateMetadataPromoCodeId] = promoCode.Id.Hex()
+	order.PrivateMetadata[pkg.OrderPrivateMetadataPromoCode] = promoCode.Code
+	order.PrivateMetadata[pkg.OrderPrivateMetadataDiscountAmount] = strconv.FormatFloat(discount, 'f', -1, 64)
+	order.PrivateMetadata[pkg.OrderPrivateMetadataAmountBeforeDiscount] = strconv.FormatFloat(order.OrderAmount, 'f', -1, 64)
+
+	order.OrderAmount = s.FormatAmount(order.OrderAmount-discount, order.Currency)
+	order.TotalPaymentAmount = order.OrderAmount
+	order.ChargeAmount = order.TotalPaymentAmount
+}
+
+// removeOrderPromoCode restores the order amount before discount and returns the removed code.
+func (s *Service) removeOrderPromoCode(order *billingpb.Order) string {
+	code := order.PrivateMetadata[pkg.OrderPrivateMetadataPromoCode]
+
+	if v, ok := order.PrivateMetadata[pkg.OrderPrivateMetadataAmountBeforeDiscount]; ok {
+		if amount, err := strconv.ParseFloat(v, 64); err == nil {
+			order.OrderAmount = amount
+			order.TotalPaymentAmount = amount
+			order.ChargeAmount = amount
+		}
+	}
+
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataPromoCodeId)
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataPromoCode)
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataDiscountAmount)
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataAmountBeforeDiscount)
+
+	return code
+}
+
+// getOrderDiscount returns the promo code applied to the order and the discount amount in the order currency.
+func getOrderDiscount(order *billingpb.Order) (string, float64) {
+	code, ok := order.PrivateMetadata[pkg.OrderPrivateMetadataPromoCode]
+
+	if !ok {
+		return "", 0
+	}
+
+	discount, _ := strconv.ParseFloat(order.PrivateMetadata[pkg.OrderPrivateMetadataDiscountAmount], 64)
+
+	return code, discount
+}
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func getPromoCodeMessage(promoCode *intPkg.PromoCode) *pkg.PromoCode {
+	msg := &pkg.PromoCode{
+		Id:                 promoCode.Id.Hex(),
+		MerchantId:         promoCode.MerchantId.Hex(),
+		ProjectId:          promoCode.ProjectId.Hex(),
+		Code:               promoCode.Code,
+		Type:               promoCode.Type,
+		Percent:            promoCode.Percent,
+		Amounts:            []*pkg.PromoCodeAmount{},
+		ProductIds:         promoCode.ProductIds,
+		StartsAt:           getTimestampProto(promoCode.StartsAt),
+		ExpiresAt:          getTimestampProto(promoCode.ExpiresAt),
+		MaxUses:            promoCode.MaxUses,
+		MaxUsesPerCustomer: promoCode.MaxUsesPerCustomer,
+		IsActive:           promoCode.IsActive,
+		CreatedAt:          getTimestampProto(promoCode.CreatedAt),
+		UpdatedAt:          getTimestampProto(promoCode.UpdatedAt),
+	}
+
+	for _, amount := range promoCode.Amounts {
+		msg.Amounts = append(msg.Amounts, &pkg.PromoCodeAmount{Currency: amount.Currency, Amount: amount.Amount})
+	}
+
+	return msg
+}
@@ -0,0 +1,486 @@
+package service
+
+import (
+	"context"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/uuid"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type PromoCodeTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+	cookie        string
+}
+
+func Test_PromoCode(t *testing.T) {
+	suite.Run(t, new(PromoCodeTestSuite))
+}
+
+func (suite *PromoCodeTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	var customer *billingpb.Customer
+	suite.merchant, suite.project, suite.paymentMethod, _, customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.cookie, err = suite.service.generateBrowserCookie(&BrowserCookieCustomer{
+		CustomerId: customer.Id,
+		Ip:         "127.0.0.1",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	})
+
+	if err != nil {
+		suite.FailNow("Generate browser cookie failed", "%v", err)
+	}
+}
+
+func (suite *PromoCodeTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *PromoCodeTestSuite) createOrder() *billingpb.Order {
+	req := &billingpb.OrderCreateRequest{
+		Type:        pkg.OrderType_simple,
+		ProjectId:   suite.project.Id,
+		Amount:      100,
+		Currency:    "RUB",
+		Account:     "unit test",
+		Description: "unit test",
+		User: &billingpb.OrderUser{
+			Id:    primitive.NewObjectID().Hex(),
+			Uuid:  uuid.New().String(),
+			Email: "test@unit.unit",
+			Ip:    "127.0.0.1",
+			Address: &billingpb.OrderBillingAddress{
+				Country: "RU",
+			},
+		},
+	}
+
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	return rsp.Item
+}
+
+func (suite *PromoCodeTestSuite) createPromoCode(req *pkg.CreateOrUpdatePromoCodeRequest) *pkg.PromoCodeResponse {
+	req.MerchantId = suite.merchant.Id
+	req.ProjectId = suite.project.Id
+
+	rsp := &pkg.PromoCodeResponse{}
+	err := suite.service.CreateOrUpdatePromoCode(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *PromoCodeTestSuite) applyPromoCode(order *billingpb.Order, code string) *pkg.ApplyPromoCodeResponse {
+	req := &pkg.ApplyPromoCodeRequest{OrderId: order.Uuid, Code: code}
+	rsp := &pkg.ApplyPromoCodeResponse{}
+	err := suite.service.ApplyPromoCode(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *PromoCodeTestSuite) TestPromoCode_CreateOrUpdate_Ok() {
+	rsp := suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{
+		Code:     "summer10",
+		Type:     pkg.PromoCodeTypePercent,
+		Percent:  10,
+		IsActive: true,
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.NotEmpty(suite.T(), rsp.Item.Id)
+	assert.Equal(suite.T(), "SUMMER10", rsp.Item.Code)
+	assert.EqualValues(suite.T(), 10, rsp.Item.Percent)
+
+	rsp = suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{
+		Id:      rsp.Item.Id,
+		Code:    "SUMMER10",
+		Type:    pkg.PromoCodeTypeFixed,
+		Percent: 10,
+		Amounts: []*pkg.PromoCodeAmount{{Currency: "rub", Amount: 15}},
+		MaxUses: 100,
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Zero(suite.T(), rsp.Item.Percent)
+	assert.Equal(suite.T(), "RUB", rsp.Item.Amounts[0].Currency)
+	assert.False(suite.T(), rsp.Item.IsActive)
+
+	req := &pkg.ListPromoCodesRequest{MerchantId: suite.merchant.Id, ProjectId: suite.project.Id}
+	rsp1 := &pkg.ListPromoCodesResponse{}
+	err := suite.service.ListPromoCodes(context.TODO(), req, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+	assert.Len(suite.T(), rsp1.Items, 1)
+	assert.EqualValues(suite.T(), 100, rsp1.Items[0].MaxUses)
+}
+
+func (suite *PromoCodeTestSuite) TestPromoCode_CreateOrUpdate_AlreadyExists_Error() {
+	rsp := suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{Code: "SALE", Type: pkg.PromoCodeTypePercent, Percent: 5})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	rsp = suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{Code: "sale", Type: pkg.PromoCodeTypePercent, Percent: 15})
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), promoCodeErrorAlreadyExists, rsp.Message)
+}
+
+func (suite *PromoCodeTestSuite) TestPromoCode_CreateOrUpdate_Invalid_Error() {
+	rsp := suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{Code: "SALE", Type: pkg.PromoCodeTypePercent, Percent: 100})
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), promoCodeErrorPercentInvalid, rsp.Message)
+
+	rsp = suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{Code: "SALE", Type: pkg.PromoCodeTypeFixed})
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), promoCodeErrorAmountsRequired, rsp.Message)
+
+	startsAt, _ := ptypes.TimestampProto(time.Now())
+	expiresAt, _ := ptypes.TimestampProto(time.Now().Add(-time.Hour))
+	rsp = suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{
+		Code:      "SALE",
+		Type:      pkg.PromoCodeTypePercent,
+		Percent:   10,
+		StartsAt:  startsAt,
+		ExpiresAt: expiresAt,
+	})
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), promoCodeErrorPeriodInvalid, rsp.Message)
+}
+
+func (suite *PromoCodeTestSuite) TestPromoCode_ApplyPromoCode_Ok() {
+	rsp := suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{
+		Code:     "SALE10",
+		Type:     pkg.PromoCodeTypePercent,
+		Percent:  10,
+		IsActive: true,
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	order := suite.createOrder()
+
+	rsp1 := suite.applyPromoCode(order, "sale10")
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+	assert.Equal(suite.T(), "SALE10", rsp1.Code)
+	assert.EqualValues(suite.T(), 10, rsp1.DiscountAmount)
+	assert.EqualValues(suite.T(), 90, rsp1.Item.Amount)
+
+	order, err := suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 90, order.OrderAmount)
+	assert.Equal(suite.T(), rsp.Item.Id, order.PrivateMetadata[pkg.OrderPrivateMetadataPromoCodeId])
+	assert.Equal(suite.T(), "100", order.PrivateMetadata[pkg.OrderPrivateMetadataAmountBeforeDiscount])
+
+	rsp1 = suite.applyPromoCode(order, "")
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+	assert.Empty(suite.T(), rsp1.Code)
+	assert.EqualValues(suite.T(), 100, rsp1.Item.Amount)
+
+	history, err := suite.service.orderHistoryRepository.FindByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), history, 2)
+	assert.Equal(suite.T(), pkg.OrderHistoryTypePromoCodeApplied, history[0].Type)
+	assert.Equal(suite.T(), pkg.OrderHistoryTypePromoCodeRemoved, history[1].Type)
+}
+
+func (suite *PromoCodeTestSuite) TestPromoCode_ApplyPromoCode_NotApplicable_Error() {
+	rsp := suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{
+		Code:     "USD5",
+		Type:     pkg.PromoCodeTypeFixed,
+		Amounts:  []*pkg.PromoCodeAmount{{Currency: "USD", Amount: 5}},
+		IsActive: true,
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	rsp = suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{
+		Code:       "PRODUCT",
+		Type:       pkg.PromoCodeTypePercent,
+		Percent:    50,
+		ProductIds: []string{primitive.NewObjectID().Hex()},
+		IsActive:   true,
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	order := suite.createOrder()
+
+	rsp1 := suite.applyPromoCode(order, "USD5")
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp1.Status)
+	assert.Equal(suite.T(), promoCodeErrorNotApplicable, rsp1.Message)
+
+	rsp1 = suite.applyPromoCode(order, "PRODUCT")
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp1.Status)
+	assert.Equal(suite.T(), promoCodeErrorNotApplicable, rsp1.Message)
+
+	rsp1 = suite.applyPromoCode(order, "UNKNOWN")
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp1.Status)
+	assert.Equal(suite.T(), promoCodeErrorNotFound, rsp1.Message)
+}
+
+func (suite *PromoCodeTestSuite) TestPromoCode_ApplyPromoCode_Inactive_Error() {
+	expiresAt, _ := ptypes.TimestampProto(time.Now().Add(time.Hour))
+	rsp := suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{
+		Code:      "SALE",
+		Type:      pkg.PromoCodeTypePercent,
+		Percent:   10,
+		ExpiresAt: expiresAt,
+		IsActive:  true,
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	promoCode, err := suite.service.promoCodeRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	promoCode.ExpiresAt = time.Now().Add(-time.Minute)
+	err = suite.service.promoCodeRepository.Update(context.TODO(), promoCode)
+	assert.NoError(suite.T(), err)
+
+	rsp1 := suite.applyPromoCode(suite.createOrder(), "SALE")
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp1.Status)
+	assert.Equal(suite.T(), promoCodeErrorInactive, rsp1.Message)
+}
+
+func (suite *PromoCodeTestSuite) TestPromoCode_ApplyPromoCode_UsageLimitReached_Error() {
+	rsp := suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{
+		Code:     "ONCE",
+		Type:     pkg.PromoCodeTypeFixed,
+		Amounts:  []*pkg.PromoCodeAmount{{Currency: "RUB", Amount: 20}},
+		MaxUses:  1,
+		IsActive: true,
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	order := suite.createOrder()
+	rsp1 := suite.applyPromoCode(order, "ONCE")
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+
+	order = HelperPayOrder(suite.Suite, suite.service, order, suite.paymentMethod, "RU", suite.cookie)
+	assert.EqualValues(suite.T(), 80, order.OrderAmount)
+	assert.Equal(suite.T(), "ONCE", order.PrivateMetadata[pkg.OrderPrivateMetadataPromoCode])
+
+	payload, err := suite.service.getPayloadForReceipt(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+	assert.Contains(suite.T(), payload.TemplateObjectModel.Fields, "discount")
+
+	rsp1 = suite.applyPromoCode(suite.createOrder(), "ONCE")
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp1.Status)
+	assert.Equal(suite.T(), promoCodeErrorUsageLimitReached, rsp1.Message)
+}
+
+func (suite *PromoCodeTestSuite) TestPromoCode_ReserveOrderPromoCode_Concurrent_LimitNotExceeded() {
+	rsp := suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{
+		Code:     "ONCE",
+		Type:     pkg.PromoCodeTypeFixed,
+		Amounts:  []*pkg.PromoCodeAmount{{Currency: "RUB", Amount: 20}},
+		MaxUses:  1,
+		IsActive: true,
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	var orders []*billingpb.Order
+
+	for i := 0; i < 5; i++ {
+		order := suite.createOrder()
+		rsp1 := suite.applyPromoCode(order, "ONCE")
+		assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+
+		order, err := suite.service.orderRepository.GetById(context.TODO(), order.Id)
+		assert.NoError(suite.T(), err)
+		orders = append(orders, order)
+	}
+
+	var reserved int32
+	wg := sync.WaitGroup{}
+
+	for _, order := range orders {
+		wg.Add(1)
+
+		go func(order *billingpb.Order) {
+			defer wg.Done()
+
+			if err := suite.service.reserveOrderPromoCode(context.TODO(), order); err == nil {
+				atomic.AddInt32(&reserved, 1)
+			} else {
+				assert.Equal(suite.T(), promoCodeErrorUsageLimitReached, err)
+			}
+		}(order)
+	}
+
+	wg.Wait()
+	assert.Equal(suite.T(), int32(1), reserved)
+
+	promoCode, err := suite.service.promoCodeRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), promoCode.ReservedOrderIds, 1)
+}
+
+func (suite *PromoCodeTestSuite) TestPromoCode_ReserveOrderPromoCode_Concurrent_CustomerLimitNotExceeded() {
+	rsp := suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{
+		Code:               "ONCE",
+		Type:               pkg.PromoCodeTypeFixed,
+		Amounts:            []*pkg.PromoCodeAmount{{Currency: "RUB", Amount: 20}},
+		MaxUsesPerCustomer: 1,
+		IsActive:           true,
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	customerId := primitive.NewObjectID().Hex()
+	var orders []*billingpb.Order
+
+	for i := 0; i < 5; i++ {
+		order := suite.createOrder()
+		rsp1 := suite.applyPromoCode(order, "ONCE")
+		assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+
+		order, err := suite.service.orderRepository.GetById(context.TODO(), order.Id)
+		assert.NoError(suite.T(), err)
+		order.User.Id = customerId
+		orders = append(orders, order)
+	}
+
+	var reserved int32
+	wg := sync.WaitGroup{}
+
+	for _, order := range orders {
+		wg.Add(1)
+
+		go func(order *billingpb.Order) {
+			defer wg.Done()
+
+			if err := suite.service.reserveOrderPromoCode(context.TODO(), order); err == nil {
+				atomic.AddInt32(&reserved, 1)
+			} else {
+				assert.Equal(suite.T(), promoCodeErrorUsageLimitReached, err)
+			}
+		}(order)
+	}
+
+	wg.Wait()
+	assert.Equal(suite.T(), int32(1), reserved)
+
+	// other customers still can use the promo code
+	order := suite.createOrder()
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, suite.applyPromoCode(order, "ONCE").Status)
+	order, err := suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.service.reserveOrderPromoCode(context.TODO(), order))
+}
+
+func (suite *PromoCodeTestSuite) TestPromoCode_ReleaseOrderPromoCode_UseReturned() {
+	rsp := suite.createPromoCode(&pkg.CreateOrUpdatePromoCodeRequest{
+		Code:     "ONCE",
+		Type:     pkg.PromoCodeTypeFixed,
+		Amounts:  []*pkg.PromoCodeAmount{{Currency: "RUB", Amount: 20}},
+		MaxUses:  1,
+		IsActive: true,
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	order1 := suite.createOrder()
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, suite.applyPromoCode(order1, "ONCE").Status)
+	order1, err := suite.service.orderRepository.GetById(context.TODO(), order1.Id)
+	assert.NoError(suite.T(), err)
+
+	order2 := suite.createOrder()
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, suite.applyPromoCode(order2, "ONCE").Status)
+	order2, err = suite.service.orderRepository.GetById(context.TODO(), order2.Id)
+	assert.NoError(suite.T(), err)
+
+	assert.NoError(suite.T(), suite.service.reserveOrderPromoCode(context.TODO(), order1))
+	// repeated payment attempt of the same order doesn't take one more use
+	assert.NoError(suite.T(), suite.service.reserveOrderPromoCode(context.TODO(), order1))
+	assert.Equal(suite.T(), promoCodeErrorUsageLimitReached, suite.service.reserveOrderPromoCode(context.TODO(), order2))
+
+	suite.service.releaseOrderPromoCode(context.TODO(), order1)
+	assert.NoError(suite.T(), suite.service.reserveOrderPromoCode(context.TODO(), order2))
+}
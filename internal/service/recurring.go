@@ -389,6 +389,9 @@ func (s *Service) DeleteRecurringSubscription(
 		return nil
 	}
 
+	s.cancelSubscriptionTrial(ctx, subscription.Id)
+	s.cancelSubscriptionPause(ctx, subscription.Id)
+
 	res.Status = billingpb.ResponseStatusOk
 
 	return nil
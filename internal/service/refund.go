@@ -8,6 +8,8 @@ import (
 	"github.com/golang/protobuf/ptypes"
 	"github.com/google/uuid"
 	"github.com/jinzhu/copier"
+	"github.com/paysuper/paysuper-billing-server/internal/payment_system"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/errors"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
@@ -19,7 +21,8 @@ import (
 )
 
 const (
-	refundDefaultReasonMask = "Refund by order #%s"
+	refundDefaultReasonMask     = "Refund by order #%s"
+	refundOrderSequenceAttempts = 5
 )
 
 var (
@@ -30,23 +33,57 @@ var (
 	refundErrorNotFound           = errors.NewBillingServerErrorMsg("rf000005", "refund with specified data not found")
 	refundErrorOrderNotFound      = errors.NewBillingServerErrorMsg("rf000006", "information about payment for refund with specified data not found")
 	refundErrorCostsRatesNotFound = errors.NewBillingServerErrorMsg("rf000007", "settings to calculate commissions for refund not found")
+	refundErrorDisputeOpened      = errors.NewBillingServerErrorMsg("rf000008", "refund for order with opened dispute not allowed")
 )
 
 type createRefundChecked struct {
-	order *billingpb.Order
+	order  *billingpb.Order
+	amount float64
+	items  []*intPkg.RefundItem
 }
 
 type createRefundProcessor struct {
 	service *Service
 	request *billingpb.CreateRefundRequest
-	checked *createRefundChecked
-	ctx     context.Context
+	items   []*pkg.RefundItemRequest
+	// amount of the partial refund of the order in the charge currency, the whole order is refunded without it
+	amount float64
+	// identifier of the created refund allocated in advance, so the repeated creation fails on the duplicate refund
+	refundId string
+	checked  *createRefundChecked
+	ctx      context.Context
 }
 
 func (s *Service) CreateRefund(
 	ctx context.Context,
 	req *billingpb.CreateRefundRequest,
 	rsp *billingpb.CreateRefundResponse,
+) error {
+	projectId := ""
+
+	if getIdempotencyKey(ctx) != "" {
+		if order, err := s.orderRepository.GetByUuidAndMerchantId(ctx, req.OrderId, req.MerchantId); err == nil {
+			projectId = order.GetProjectId()
+		}
+	}
+
+	err := s.processIdempotent(ctx, idempotencyOperationRefundCreate, projectId, req, rsp, func() error {
+		return s.createRefund(ctx, req, rsp)
+	})
+
+	if e, ok := err.(*billingpb.ResponseError); ok {
+		rsp.Status = e.Status
+		rsp.Message = e.Message
+		return nil
+	}
+
+	return err
+}
+
+func (s *Service) createRefund(
+	ctx context.Context,
+	req *billingpb.CreateRefundRequest,
+	rsp *billingpb.CreateRefundResponse,
 ) error {
 	processor := &createRefundProcessor{
 		service: s,
@@ -55,6 +92,14 @@ func (s *Service) CreateRefund(
 		ctx:     ctx,
 	}
 
+	return s.sendRefund(ctx, processor, rsp)
+}
+
+func (s *Service) sendRefund(
+	ctx context.Context,
+	processor *createRefundProcessor,
+	rsp *billingpb.CreateRefundResponse,
+) error {
 	refund, err := processor.processCreateRefund()
 
 	if err != nil {
@@ -64,7 +109,65 @@ func (s *Service) CreateRefund(
 		return nil
 	}
 
-	h, err := s.paymentSystemGateway.GetGateway(processor.checked.order.PaymentMethod.Handler)
+	if !refund.IsChargeback {
+		isHeld, err := s.holdRefundForApproval(ctx, processor.checked.order, refund)
+
+		// the refund which can't be held is rejected, so it doesn't wait for the approval which doesn't exist and
+		// doesn't reserve the order amount
+		if err != nil {
+			processor.rejectRefund(refund)
+
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = refundErrorUnknown
+
+			return nil
+		}
+
+		if isHeld {
+			rsp.Status = billingpb.ResponseStatusOk
+			rsp.Item = refund
+
+			return nil
+		}
+	}
+
+	return s.sendRefundToPaymentSystem(ctx, processor.checked.order, refund, rsp)
+}
+
+// sendRefundToPaymentSystem returns the part of the refund paid by the customer wallet and the gift card to them
+// and sends the rest of the refund to the payment system.
+func (s *Service) sendRefundToPaymentSystem(
+	ctx context.Context,
+	order *billingpb.Order,
+	refund *billingpb.Refund,
+	rsp *billingpb.CreateRefundResponse,
+) error {
+	prepaidRefund, err := s.splitPrepaidRefund(ctx, order, refund)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = refundErrorUnknown
+
+		return nil
+	}
+
+	if prepaidRefund != nil {
+		if err = s.completePrepaidRefund(ctx, order, prepaidRefund, prepaidRefund == refund); err != nil {
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = refundErrorUnknown
+
+			return nil
+		}
+
+		if prepaidRefund == refund {
+			rsp.Status = billingpb.ResponseStatusOk
+			rsp.Item = refund
+
+			return nil
+		}
+	}
+
+	h, err := s.paymentSystemGateway.GetGateway(order.PaymentMethod.Handler)
 
 	if err != nil {
 		zap.S().Errorw(pkg.MethodFinishedWithError, "err", err)
@@ -76,7 +179,7 @@ func (s *Service) CreateRefund(
 		return err
 	}
 
-	err = h.CreateRefund(processor.checked.order, refund)
+	err = h.CreateRefund(order, refund)
 
 	if err != nil {
 		rsp.Status = billingpb.ResponseStatusBadData
@@ -98,6 +201,131 @@ func (s *Service) CreateRefund(
 	return nil
 }
 
+// splitPrepaidRefund returns the part of the refund paid by the customer wallet and the gift card or nil if the order
+// isn't paid by them. Refund of the order paid by them only is returned as is, otherwise the prepaid part is saved as
+// the separate refund and the refund amount is decreased by it. Chargeback is returned by the payment system only.
+func (s *Service) splitPrepaidRefund(
+	ctx context.Context,
+	order *billingpb.Order,
+	refund *billingpb.Refund,
+) (*billingpb.Refund, error) {
+	prepaidAmount := getOrderPrepaidChargeAmount(order)
+
+	if refund.IsChargeback || prepaidAmount <= 0 {
+		return nil, nil
+	}
+
+	amount := tools.FormatAmount(refund.Amount * prepaidAmount / getOrderRefundableAmount(order))
+
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	if order.ChargeAmount <= 0 || amount >= refund.Amount {
+		return refund, nil
+	}
+
+	prepaidRefund := protobuf.Clone(refund).(*billingpb.Refund)
+	prepaidRefund.Id = primitive.NewObjectID().Hex()
+	prepaidRefund.Amount = amount
+	prepaidRefund.SalesTax = float32(tools.FormatAmount(float64(refund.SalesTax) * amount / refund.Amount))
+
+	// prepaid part is saved first, so the failure can't leave the refunded amount available to refund again
+	if err := s.refundRepository.Insert(ctx, prepaidRefund); err != nil {
+		return nil, err
+	}
+
+	refund.Amount = tools.FormatAmount(refund.Amount - amount)
+	refund.SalesTax = float32(tools.FormatAmount(float64(refund.SalesTax - prepaidRefund.SalesTax)))
+
+	if err := s.refundRepository.Update(ctx, refund); err != nil {
+		return nil, err
+	}
+
+	return prepaidRefund, nil
+}
+
+// completePrepaidRefund returns the refund to the customer wallet and the gift card which paid the order
+// in proportion to their charged amounts and completes the refund without the payment system. Keys of the order
+// are revoked by the refund which returns the order lines only.
+func (s *Service) completePrepaidRefund(
+	ctx context.Context,
+	order *billingpb.Order,
+	refund *billingpb.Refund,
+	isRevokeKeys bool,
+) error {
+	prepaidAmount := getOrderPrepaidChargeAmount(order)
+	walletAmount := getOrderWalletChargeAmount(order)
+	walletRefundAmount := tools.FormatAmount(refund.Amount * walletAmount / prepaidAmount)
+
+	if walletAmount > 0 {
+		if err := s.refundOrderWallet(ctx, order, refund, walletRefundAmount); err != nil {
+			return err
+		}
+	}
+
+	if getOrderGiftCardChargeAmount(order) > 0 {
+		if err := s.refundOrderGiftCard(ctx, order, tools.FormatAmount(refund.Amount-walletRefundAmount)); err != nil {
+			return err
+		}
+	}
+
+	refund.Status = pkg.RefundStatusCompleted
+	refund.UpdatedAt = ptypes.TimestampNow()
+	refundOrder, err := s.createOrderByRefund(ctx, order, refund)
+
+	if err != nil {
+		return err
+	}
+
+	refund.CreatedOrderId = refundOrder.Id
+
+	if isRevokeKeys {
+		s.revokeRefundOrderKeys(ctx, refundOrder)
+	}
+
+	if err = s.refundRepository.Update(ctx, refund); err != nil {
+		return err
+	}
+
+	return s.finishRefund(ctx, order, refund, refundOrder)
+}
+
+// refundOrderWallet credits the wallet part of the refund in the charge currency to the customer wallet which paid
+// the order.
+func (s *Service) refundOrderWallet(
+	ctx context.Context,
+	order *billingpb.Order,
+	refund *billingpb.Refund,
+	amount float64,
+) error {
+	wallet, err := s.customerWalletRepository.GetById(ctx, order.PrivateMetadata[pkg.OrderPrivateMetadataWalletId])
+
+	if err != nil {
+		return err
+	}
+
+	if refund.Currency != wallet.Currency {
+		amount = s.FormatAmount(amount*getOrderWalletAmount(order)/getOrderWalletChargeAmount(order), wallet.Currency)
+	}
+
+	credit := &intPkg.StoreCredit{
+		CustomerId: wallet.CustomerId,
+		MerchantId: wallet.MerchantId,
+		ProjectId:  wallet.ProjectId,
+		Amount:     amount,
+		Currency:   wallet.Currency,
+		Source:     pkg.StoreCreditSourceOrderRefund,
+		SourceId:   refund.Id,
+		Reason:     refund.Reason,
+		CreatorId:  refund.CreatorId,
+	}
+
+	_, err = s.topUpCustomerWallet(ctx, credit)
+
+	return err
+}
+
 func (s *Service) ListRefunds(
 	ctx context.Context,
 	req *billingpb.ListRefundsRequest,
@@ -186,6 +414,32 @@ func (s *Service) ProcessRefundCallback(
 
 		refundId = data.(*billingpb.CardPayRefundCallback).MerchantOrder.Id
 		break
+	case pkg.PaymentSystemHandlerCheckout:
+		data = &payment_system.CheckoutWebhook{}
+		err := json.Unmarshal(req.Body, data)
+
+		if err != nil || data.(*payment_system.CheckoutWebhook).Data == nil {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Error = callbackRequestIncorrect
+
+			return nil
+		}
+
+		refundId = data.(*payment_system.CheckoutWebhook).Data.Reference
+		break
+	case pkg.PaymentSystemHandlerSimulator:
+		data = &payment_system.SimulatorCallback{}
+		err := json.Unmarshal(req.Body, data)
+
+		if err != nil || data.(*payment_system.SimulatorCallback).RefundId == "" {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Error = callbackRequestIncorrect
+
+			return nil
+		}
+
+		refundId = data.(*payment_system.SimulatorCallback).RefundId
+		break
 	default:
 		rsp.Status = billingpb.ResponseStatusBadData
 		rsp.Error = callbackHandlerIncorrect
@@ -244,6 +498,10 @@ func (s *Service) ProcessRefundCallback(
 		return nil
 	}
 
+	if refund.Status == pkg.RefundStatusPaymentSystemDeclined && !refund.IsChargeback {
+		s.startRefundFallback(ctx, order, refund)
+	}
+
 	refundOrder := &billingpb.Order{}
 
 	if refund.Status == pkg.RefundStatusCompleted {
@@ -257,6 +515,7 @@ func (s *Service) ProcessRefundCallback(
 			}
 
 			refund.CreatedOrderId = refundOrder.Id
+			s.revokeRefundOrderKeys(ctx, refundOrder)
 		} else {
 			refundOrder, err = s.getOrderById(ctx, refund.CreatedOrderId)
 			if err != nil {
@@ -304,10 +563,31 @@ func (s *Service) ProcessRefundCallback(
 		return nil
 	}
 
-	processor := &createRefundProcessor{service: s, ctx: ctx}
-	refundedAmount, _ := processor.service.refundRepository.GetAmountByOrderId(ctx, order.Id)
+	if err = s.finishRefund(ctx, order, refund, refundOrder); err != nil {
+		rsp.Error = err.Error()
+		rsp.Status = billingpb.ResponseStatusSystemError
+
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
 
-	if refundedAmount == order.ChargeAmount {
+	return nil
+}
+
+// finishRefund marks the order as refunded when the whole order amount or the chargeback is returned, creates
+// accounting entries of the completed refund and sends the refund receipt to the customer.
+func (s *Service) finishRefund(
+	ctx context.Context,
+	order *billingpb.Order,
+	refund *billingpb.Refund,
+	refundOrder *billingpb.Order,
+) error {
+	var err error
+
+	refundedAmount, _ := s.refundRepository.GetCompletedAmountByOrderId(ctx, order.Id)
+
+	if refund.IsChargeback || tools.FormatAmount(refundedAmount) >= getOrderRefundableAmount(order) {
 		if refund.IsChargeback == true {
 			order.PrivateStatus = recurringpb.OrderStatusChargeback
 			order.Status = recurringpb.OrderPublicStatusChargeback
@@ -345,14 +625,9 @@ func (s *Service) ProcessRefundCallback(
 			zap.String("refund-orderId", refundOrder.Id),
 		)
 
-		rsp.Error = err.Error()
-		rsp.Status = billingpb.ResponseStatusSystemError
-
-		return nil
+		return err
 	}
 
-	rsp.Status = billingpb.ResponseStatusOk
-
 	s.sendMailWithReceipt(ctx, refundOrder)
 
 	if order.Recurring {
@@ -465,6 +740,10 @@ func (s *Service) createOrderByRefund(ctx context.Context, order *billingpb.Orde
 
 	refundOrder.ChargeAmount = refund.Amount
 
+	if err = s.setRefundOrderItems(ctx, order, refund, refundOrder); err != nil {
+		return nil, refundErrorUnknown
+	}
+
 	refundOrder.Tax.Amount = tools.FormatAmount(tools.GetPercentPartFromAmount(refund.Amount, refundOrder.Tax.Rate))
 	refundOrder.OrderAmount = tools.FormatAmount(refundOrder.TotalPaymentAmount - refundOrder.Tax.Amount)
 	refundOrder.ReceiptId = uuid.New().String()
@@ -484,11 +763,30 @@ func (p *createRefundProcessor) processCreateRefund() (*billingpb.Refund, error)
 		return nil, err
 	}
 
-	if !p.hasMoneyBackCosts(p.ctx, p.checked.order) {
+	// sequence is read before refunds of the order are checked, so the refund created in meantime changes it
+	sequence, err := p.service.refundRepository.GetOrderSequence(p.ctx, p.checked.order.Id)
+
+	if err != nil {
+		return nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorUnknown)
+	}
+
+	// parts of the order paid by the customer wallet and the gift card are refunded without the payment system
+	if p.checked.order.ChargeAmount > 0 && !p.hasMoneyBackCosts(p.ctx, p.checked.order) {
 		return nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorCostsRatesNotFound)
 	}
 
-	err = p.processRefundsByOrder()
+	if len(p.items) > 0 {
+		err = p.processRefundItems()
+	} else if p.amount > 0 {
+		err = p.processRefundAmount()
+	} else {
+		err = p.processRefundsByOrder()
+	}
+
+	// chargeback is forced by the issuing bank, so it can't be rejected by the spent gift card
+	if err == nil && !p.request.IsChargeback {
+		err = p.processRefundGiftCards()
+	}
 
 	if err != nil {
 		return nil, err
@@ -496,13 +794,17 @@ func (p *createRefundProcessor) processCreateRefund() (*billingpb.Refund, error)
 
 	order := p.checked.order
 
+	if p.refundId == "" {
+		p.refundId = primitive.NewObjectID().Hex()
+	}
+
 	refund := &billingpb.Refund{
-		Id: primitive.NewObjectID().Hex(),
+		Id: p.refundId,
 		OriginalOrder: &billingpb.RefundOrder{
 			Id:   order.Id,
 			Uuid: order.Uuid,
 		},
-		Amount:    order.ChargeAmount,
+		Amount:    p.checked.amount,
 		CreatorId: p.request.CreatorId,
 		Reason:    fmt.Sprintf(refundDefaultReasonMask, p.checked.order.Id),
 		Currency:  order.ChargeCurrency,
@@ -517,13 +819,12 @@ func (p *createRefundProcessor) processCreateRefund() (*billingpb.Refund, error)
 		IsChargeback: p.request.IsChargeback,
 	}
 
-	if refund.IsChargeback == true {
-		refund.Amount = p.checked.order.ChargeAmount
-		refund.IsChargeback = p.request.IsChargeback
-	}
-
 	if order.Tax != nil {
 		refund.SalesTax = float32(order.Tax.Amount)
+
+		if total := getOrderRefundableAmount(order); refund.Amount < total {
+			refund.SalesTax = float32(tools.FormatAmount(order.Tax.Amount * refund.Amount / total))
+		}
 	}
 
 	if p.request.Reason != "" {
@@ -534,9 +835,101 @@ func (p *createRefundProcessor) processCreateRefund() (*billingpb.Refund, error)
 		return nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, orderErrorUnknown)
 	}
 
+	if err = p.insertRefundItems(refund); err != nil {
+		return nil, err
+	}
+
+	if err = p.incrementOrderRefundSequence(refund, sequence); err != nil {
+		return nil, err
+	}
+
 	return refund, nil
 }
 
+// incrementOrderRefundSequence increments the sequence of the order refunds read before the refund was checked.
+// Concurrent refund checked against the same refunds gets the same sequence, so the refund which lost the sequence
+// is checked again against all saved refunds of the order and is rejected if it doesn't fit the order.
+func (p *createRefundProcessor) incrementOrderRefundSequence(refund *billingpb.Refund, sequence int64) error {
+	orderId := p.checked.order.Id
+
+	for attempt := 1; attempt <= refundOrderSequenceAttempts; attempt++ {
+		isIncremented, err := p.service.refundRepository.IncrementOrderSequence(p.ctx, orderId, sequence)
+
+		if err != nil {
+			break
+		}
+
+		if isIncremented {
+			return nil
+		}
+
+		if sequence, err = p.service.refundRepository.GetOrderSequence(p.ctx, orderId); err != nil {
+			break
+		}
+
+		if err = p.checkOrderRefunds(); err != nil {
+			p.rejectRefund(refund)
+			return err
+		}
+	}
+
+	p.rejectRefund(refund)
+
+	return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorUnknown)
+}
+
+// checkOrderRefunds checks that the saved refunds of the order don't exceed the refundable amount of the order
+// and don't return the same order line twice.
+func (p *createRefundProcessor) checkOrderRefunds() error {
+	order := p.checked.order
+	refundedAmount, err := p.service.refundRepository.GetReservedAmountByOrderId(p.ctx, order.Id)
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorUnknown)
+	}
+
+	if tools.FormatAmount(refundedAmount) > getOrderRefundableAmount(order) {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorPaymentAmountLess)
+	}
+
+	if len(p.checked.items) <= 0 {
+		return nil
+	}
+
+	items, err := p.service.refundItemRepository.FindReservedByOrderId(p.ctx, order.Id)
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorUnknown)
+	}
+
+	lines := make(map[int32]bool)
+
+	for _, item := range items {
+		if lines[item.Line] {
+			return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorItemsQuantity)
+		}
+
+		lines[item.Line] = true
+	}
+
+	return nil
+}
+
+// rejectRefund rejects the saved refund, so its amount and order lines are available to refund again.
+func (p *createRefundProcessor) rejectRefund(refund *billingpb.Refund) {
+	refund.Status = pkg.RefundStatusRejected
+	refund.UpdatedAt = ptypes.TimestampNow()
+
+	if err := p.service.refundRepository.Update(p.ctx, refund); err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "refundRepository.Update"),
+			zap.Error(err),
+			zap.String("refundId", refund.Id),
+		)
+	}
+}
+
 func (p *createRefundProcessor) processOrder() error {
 	order, err := p.service.orderRepository.GetByUuidAndMerchantId(p.ctx, p.request.OrderId, p.request.MerchantId)
 
@@ -552,22 +945,61 @@ func (p *createRefundProcessor) processOrder() error {
 		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorNotAllowed)
 	}
 
+	// money of the disputed order is held by the issuing bank, so it can be returned to customer by chargeback only
+	if !p.request.IsChargeback {
+		dispute, err := p.service.disputeRepository.GetOpenByOrderId(p.ctx, order.Id)
+
+		if err != nil {
+			return errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, refundErrorUnknown)
+		}
+
+		if dispute != nil {
+			return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorDisputeOpened)
+		}
+	}
+
 	p.checked.order = order
 
 	return nil
 }
 
+// processRefundsByOrder returns the rest of the order amount which isn't refunded yet, the order lines which aren't
+// returned by the item refunds are returned by this refund. Chargeback returns only the part of the rest charged by
+// the payment system, the parts paid by the customer wallet and the gift card stay with them.
 func (p *createRefundProcessor) processRefundsByOrder() error {
-	refundedAmount, err := p.service.refundRepository.GetAmountByOrderId(p.ctx, p.checked.order.Id)
+	refundedAmount, err := p.service.refundRepository.GetReservedAmountByOrderId(p.ctx, p.checked.order.Id)
 
 	if err != nil {
 		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorUnknown)
 	}
 
-	if refundedAmount > 0 {
+	total := getOrderRefundableAmount(p.checked.order)
+	p.checked.amount = tools.FormatAmount(total - refundedAmount)
+
+	if p.request.IsChargeback && p.checked.amount > 0 {
+		p.checked.amount = tools.FormatAmount(p.checked.amount * p.checked.order.ChargeAmount / total)
+	}
+
+	if p.checked.amount <= 0 {
 		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorPaymentAmountLess)
 	}
 
+	return p.processRefundRestItems()
+}
+
+func (p *createRefundProcessor) processRefundAmount() error {
+	refundedAmount, err := p.service.refundRepository.GetReservedAmountByOrderId(p.ctx, p.checked.order.Id)
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorUnknown)
+	}
+
+	if p.amount > tools.FormatAmount(getOrderRefundableAmount(p.checked.order)-refundedAmount) {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorPaymentAmountLess)
+	}
+
+	p.checked.amount = p.amount
+
 	return nil
 }
 
@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/currenciespb"
+	"github.com/paysuper/paysuper-proto/go/postmarkpb"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	refundErrorApprovalSettingsInvalid = errors.NewBillingServerErrorMsg("rf000011", "refund approval settings are invalid. currency is required for the amount threshold and approver role must be the merchant role")
+	refundErrorApprovalNotPending      = errors.NewBillingServerErrorMsg("rf000012", "refund isn't waiting for approval")
+	refundErrorApproverRoleInvalid     = errors.NewBillingServerErrorMsg("rf000013", "user doesn't have the role to approve or reject the refund")
+	refundErrorApproverIsCreator       = errors.NewBillingServerErrorMsg("rf000014", "refund can't be approved by the user who created it")
+)
+
+// SetRefundApprovalSettings saves the thresholds of the merchant above which refunds wait for the approval.
+// Refunds which are already waiting for the approval keep the approver role from their creation.
+func (s *Service) SetRefundApprovalSettings(
+	ctx context.Context,
+	req *pkg.SetRefundApprovalSettingsRequest,
+	rsp *pkg.RefundApprovalSettingsResponse,
+) error {
+	_, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = merchantErrorNotFound
+		return nil
+	}
+
+	if (req.Amount > 0 && req.Currency == "") || !isMerchantRole(req.ApproverRole) {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = refundErrorApprovalSettingsInvalid
+		return nil
+	}
+
+	settings, err := s.refundApprovalSettingsRepository.GetByMerchantId(ctx, req.MerchantId)
+
+	if err != nil {
+		settings = &intPkg.RefundApprovalSettings{}
+		settings.MerchantId, _ = primitive.ObjectIDFromHex(req.MerchantId)
+	}
+
+	settings.Amount = req.Amount
+	settings.Currency = req.Currency
+	settings.Percent = req.Percent
+	settings.ApproverRole = req.ApproverRole
+
+	if err = s.refundApprovalSettingsRepository.Upsert(ctx, settings); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = refundErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getRefundApprovalSettingsMessage(settings)
+
+	return nil
+}
+
+// GetRefundApprovalSettings returns the refund approval thresholds of the merchant, disabled thresholds are returned
+// for the merchant without own settings.
+func (s *Service) GetRefundApprovalSettings(
+	ctx context.Context,
+	req *pkg.GetRefundApprovalSettingsRequest,
+	rsp *pkg.RefundApprovalSettingsResponse,
+) error {
+	_, err := s.merchantRepository.GetById(ctx, req.MerchantId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = merchantErrorNotFound
+		return nil
+	}
+
+	settings, err := s.refundApprovalSettingsRepository.GetByMerchantId(ctx, req.MerchantId)
+
+	if err != nil {
+		settings = &intPkg.RefundApprovalSettings{ApproverRole: billingpb.RoleMerchantAccounting}
+		settings.MerchantId, _ = primitive.ObjectIDFromHex(req.MerchantId)
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getRefundApprovalSettingsMessage(settings)
+
+	return nil
+}
+
+// ApproveRefund approves the refund which waits for the approval and sends it to the payment system. The refund can
+// be approved only by the merchant user with the approver role who didn't create it.
+func (s *Service) ApproveRefund(
+	ctx context.Context,
+	req *pkg.ReviewRefundRequest,
+	rsp *billingpb.CreateRefundResponse,
+) error {
+	refund, approval, err := s.getPendingRefundApproval(ctx, req)
+
+	if err != nil {
+		rsp.Status = err.(*billingpb.ResponseError).Status
+		rsp.Message = err.(*billingpb.ResponseError).Message
+		return nil
+	}
+
+	if refund.CreatorId == req.UserId {
+		rsp.Status = billingpb.ResponseStatusForbidden
+		rsp.Message = refundErrorApproverIsCreator
+		return nil
+	}
+
+	// order can be disputed or refunded by the payment system while the refund is waiting for the approval
+	processor := &createRefundProcessor{
+		service: s,
+		request: &billingpb.CreateRefundRequest{OrderId: req.OrderId, MerchantId: req.MerchantId},
+		checked: &createRefundChecked{},
+		ctx:     ctx,
+	}
+
+	if err = processor.processOrder(); err != nil {
+		rsp.Status = err.(*billingpb.ResponseError).Status
+		rsp.Message = err.(*billingpb.ResponseError).Message
+		return nil
+	}
+
+	// only the request which moved the approval from pending sends the refund to the payment system
+	if err = s.reviewRefundApproval(ctx, approval, req, pkg.RefundApprovalStatusApproved); err != nil {
+		rsp.Status = err.(*billingpb.ResponseError).Status
+		rsp.Message = err.(*billingpb.ResponseError).Message
+		return nil
+	}
+
+	refund.Status = pkg.RefundStatusCreated
+	refund.UpdatedAt = ptypes.TimestampNow()
+
+	return s.sendRefundToPaymentSystem(ctx, processor.checked.order, refund, rsp)
+}
+
+// RejectRefund rejects the refund which waits for the approval, so the refund amount becomes available to refund again.
+func (s *Service) RejectRefund(
+	ctx context.Context,
+	req *pkg.ReviewRefundRequest,
+	rsp *billingpb.CreateRefundResponse,
+) error {
+	refund, approval, err := s.getPendingRefundApproval(ctx, req)
+
+	if err != nil {
+		rsp.Status = err.(*billingpb.ResponseError).Status
+		rsp.Message = err.(*billingpb.ResponseError).Message
+		return nil
+	}
+
+	if err = s.reviewRefundApproval(ctx, approval, req, pkg.RefundApprovalStatusRejected); err != nil {
+		rsp.Status = err.(*billingpb.ResponseError).Status
+		rsp.Message = err.(*billingpb.ResponseError).Message
+		return nil
+	}
+
+	refund.Status = pkg.RefundStatusRejected
+	refund.UpdatedAt = ptypes.TimestampNow()
+
+	if err = s.refundRepository.Update(ctx, refund); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = refundErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = refund
+
+	return nil
+}
+
+// holdRefundForApproval puts the refund to wait for the approval if it exceeds the thresholds of the merchant and
+// notifies the merchant users with the approver role.
+func (s *Service) holdRefundForApproval(
+	ctx context.Context,
+	order *billingpb.Order,
+	refund *billingpb.Refund,
+) (bool, error) {
+	settings, err := s.refundApprovalSettingsRepository.GetByMerchantId(ctx, order.GetMerchantId())
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	isRequired, err := s.isRefundApprovalRequired(ctx, settings, order, refund)
+
+	if err != nil || !isRequired {
+		return false, err
+	}
+
+	approval := &intPkg.RefundApproval{
+		MerchantId:   settings.MerchantId,
+		CreatorId:    refund.CreatorId,
+		Amount:       refund.Amount,
+		Currency:     refund.Currency,
+		ApproverRole: settings.ApproverRole,
+		Status:       pkg.RefundApprovalStatusPending,
+	}
+	approval.RefundId, _ = primitive.ObjectIDFromHex(refund.Id)
+	approval.OrderId, _ = primitive.ObjectIDFromHex(order.Id)
+
+	// the refund is held before the approval is created, so no approval can be decided for the refund which isn't held
+	refund.Status = pkg.RefundStatusPendingApproval
+	refund.UpdatedAt = ptypes.TimestampNow()
+
+	if err = s.refundRepository.Update(ctx, refund); err != nil {
+		return false, err
+	}
+
+	if err = s.refundApprovalRepository.Insert(ctx, approval); err != nil {
+		return false, err
+	}
+
+	s.notifyRefundApprovers(ctx, order, refund, approval)
+
+	return true, nil
+}
+
+func (s *Service) isRefundApprovalRequired(
+	ctx context.Context,
+	settings *intPkg.RefundApprovalSettings,
+	order *billingpb.Order,
+	refund *billingpb.Refund,
+) (bool, error) {
+	total := getOrderRefundableAmount(order)
+
+	if settings.Percent > 0 && total > 0 && refund.Amount*100/total > settings.Percent {
+		return true, nil
+	}
+
+	if settings.Amount <= 0 {
+		return false, nil
+	}
+
+	amount := refund.Amount
+
+	if settings.Currency != refund.Currency {
+		req := &currenciespb.ExchangeCurrencyCurrentCommonRequest{
+			From:              refund.Currency,
+			To:                settings.Currency,
+			RateType:          currenciespb.RateTypePaysuper,
+			Amount:            refund.Amount,
+			ExchangeDirection: currenciespb.ExchangeDirectionSell,
+		}
+		rsp, err := s.curService.ExchangeCurrencyCurrentCommon(ctx, req)
+
+		if err != nil {
+			zap.L().Error(
+				pkg.ErrorGrpcServiceCallFailed,
+				zap.Error(err),
+				zap.String(errorFieldService, "CurrencyRatesService"),
+				zap.String(errorFieldMethod, "ExchangeCurrencyCurrentCommon"),
+				zap.Any(errorFieldRequest, req),
+			)
+			return false, err
+		}
+
+		amount = rsp.ExchangedAmount
+	}
+
+	return amount > settings.Amount, nil
+}
+
+func (s *Service) getPendingRefundApproval(
+	ctx context.Context,
+	req *pkg.ReviewRefundRequest,
+) (*billingpb.Refund, *intPkg.RefundApproval, error) {
+	refund, err := s.refundRepository.GetById(ctx, req.RefundId)
+
+	if err != nil || refund.OriginalOrder.GetUuid() != req.OrderId {
+		return nil, nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusNotFound, refundErrorNotFound)
+	}
+
+	approval, err := s.refundApprovalRepository.GetByRefundId(ctx, req.RefundId)
+
+	if err != nil {
+		return nil, nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorApprovalNotPending)
+	}
+
+	if approval.MerchantId.Hex() != req.MerchantId {
+		return nil, nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusNotFound, refundErrorNotFound)
+	}
+
+	if refund.Status != pkg.RefundStatusPendingApproval || approval.Status != pkg.RefundApprovalStatusPending {
+		return nil, nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorApprovalNotPending)
+	}
+
+	user, err := s.userRoleRepository.GetMerchantUserByUserId(ctx, req.MerchantId, req.UserId)
+
+	if err != nil || user.Role != approval.ApproverRole {
+		return nil, nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusForbidden, refundErrorApproverRoleInvalid)
+	}
+
+	return refund, approval, nil
+}
+
+// reviewRefundApproval moves the pending approval to the status. The approval reviewed by the concurrent request
+// is rejected as not pending.
+func (s *Service) reviewRefundApproval(
+	ctx context.Context,
+	approval *intPkg.RefundApproval,
+	req *pkg.ReviewRefundRequest,
+	status string,
+) error {
+	approval.Status = status
+	approval.ReviewerId = req.UserId
+	approval.Comment = req.Comment
+	approval.ReviewedAt = time.Now()
+
+	isUpdated, err := s.refundApprovalRepository.UpdateFromStatus(ctx, approval, pkg.RefundApprovalStatusPending)
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, refundErrorUnknown)
+	}
+
+	if !isUpdated {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorApprovalNotPending)
+	}
+
+	return nil
+}
+
+func (s *Service) notifyRefundApprovers(
+	ctx context.Context,
+	order *billingpb.Order,
+	refund *billingpb.Refund,
+	approval *intPkg.RefundApproval,
+) {
+	users, err := s.userRoleRepository.GetUsersForMerchant(ctx, approval.MerchantId.Hex())
+
+	if err != nil {
+		return
+	}
+
+	for _, user := range users {
+		if user.Role != approval.ApproverRole || user.Status != pkg.UserRoleStatusAccepted || user.Email == "" {
+			continue
+		}
+
+		payload := &postmarkpb.Payload{
+			TemplateAlias: s.cfg.EmailTemplates.RefundApprovalRequired,
+			TemplateModel: map[string]string{
+				"order_id":     order.Uuid,
+				"amount":       fmt.Sprintf("%.2f", refund.Amount),
+				"currency":     refund.Currency,
+				"reason":       refund.Reason,
+				"approval_url": s.cfg.GetRefundApprovalUrl(order.Uuid, refund.Id),
+				"current_year": time.Now().UTC().Format("2006"),
+			},
+			To: user.Email,
+		}
+		err = s.postmarkBroker.Publish(postmarkpb.PostmarkSenderTopicName, payload, amqp.Table{})
+
+		if err != nil {
+			zap.L().Error(
+				"Publication message about refund waiting for approval to queue failed",
+				zap.Error(err),
+				zap.String("refund_id", refund.Id),
+				zap.String("topic", postmarkpb.PostmarkSenderTopicName),
+			)
+		}
+	}
+}
+
+func isMerchantRole(role string) bool {
+	for _, item := range merchantUserRoles[pkg.RoleTypeMerchant] {
+		if item.Id == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getRefundApprovalSettingsMessage(settings *intPkg.RefundApprovalSettings) *pkg.RefundApprovalSettings {
+	return &pkg.RefundApprovalSettings{
+		MerchantId:   settings.MerchantId.Hex(),
+		Amount:       settings.Amount,
+		Currency:     settings.Currency,
+		Percent:      settings.Percent,
+		ApproverRole: settings.ApproverRole,
+		UpdatedAt:    getTimestampProto(settings.UpdatedAt),
+	}
+}
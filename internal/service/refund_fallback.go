@@ -0,0 +1,369 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/postmarkpb"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	refundErrorFallbackNotFound           = errors.NewBillingServerErrorMsg("rf000015", "refund fallback not found")
+	refundErrorFallbackDestinationChosen  = errors.NewBillingServerErrorMsg("rf000016", "destination of refund is already chosen")
+	refundErrorFallbackBankAccountMissing = errors.NewBillingServerErrorMsg("rf000017", "bank account is required for refund by bank transfer")
+	refundErrorFallbackNotBankTransfer    = errors.NewBillingServerErrorMsg("rf000018", "refund isn't waiting for bank transfer")
+	refundErrorFallbackCompleted          = errors.NewBillingServerErrorMsg("rf000019", "refund fallback is already completed")
+)
+
+// GetRefundFallback returns the fallback of the declined refund by the token of the link sent to the customer.
+func (s *Service) GetRefundFallback(
+	ctx context.Context,
+	req *pkg.GetRefundFallbackRequest,
+	rsp *pkg.RefundFallbackResponse,
+) error {
+	fallback, err := s.refundFallbackRepository.GetByToken(ctx, req.Token)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = refundErrorFallbackNotFound
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getRefundFallbackMessage(fallback)
+
+	return nil
+}
+
+// SetRefundFallbackDestination saves the way chosen by the customer to return the declined refund. The store credit
+// completes the refund at once, the bank transfer waits for the confirmation of the finance team.
+func (s *Service) SetRefundFallbackDestination(
+	ctx context.Context,
+	req *pkg.SetRefundFallbackDestinationRequest,
+	rsp *pkg.RefundFallbackResponse,
+) error {
+	fallback, err := s.refundFallbackRepository.GetByToken(ctx, req.Token)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = refundErrorFallbackNotFound
+		return nil
+	}
+
+	if fallback.Status != pkg.RefundFallbackStatusAwaitingDetails {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = refundErrorFallbackDestinationChosen
+		return nil
+	}
+
+	fallback.Destination = req.Destination
+
+	switch req.Destination {
+	case pkg.RefundFallbackDestinationBankTransfer:
+		if req.BankAccount == nil {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = refundErrorFallbackBankAccountMissing
+			return nil
+		}
+
+		fallback.BankAccount = &intPkg.RefundBankAccount{
+			Holder:   req.BankAccount.Holder,
+			Iban:     req.BankAccount.Iban,
+			Swift:    req.BankAccount.Swift,
+			BankName: req.BankAccount.BankName,
+			Country:  req.BankAccount.Country,
+		}
+		fallback.Status = pkg.RefundFallbackStatusInProgress
+
+		var isUpdated bool
+		isUpdated, err = s.refundFallbackRepository.UpdateFromStatus(ctx, fallback, pkg.RefundFallbackStatusAwaitingDetails)
+
+		if err == nil && !isUpdated {
+			err = refundErrorFallbackCompleted
+		}
+		break
+	case pkg.RefundFallbackDestinationStoreCredit:
+		err = s.completeRefundFallback(ctx, fallback)
+		break
+	default:
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = refundErrorUnknown
+		return nil
+	}
+
+	if err == refundErrorFallbackCompleted {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = refundErrorFallbackDestinationChosen
+		return nil
+	}
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = refundErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getRefundFallbackMessage(fallback)
+
+	return nil
+}
+
+// CompleteRefundFallback completes the declined refund after the finance team sent the money to the bank account
+// of the customer.
+func (s *Service) CompleteRefundFallback(
+	ctx context.Context,
+	req *pkg.CompleteRefundFallbackRequest,
+	rsp *pkg.RefundFallbackResponse,
+) error {
+	fallback, err := s.refundFallbackRepository.GetByRefundId(ctx, req.RefundId)
+
+	if err != nil || fallback == nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = refundErrorFallbackNotFound
+		return nil
+	}
+
+	if fallback.Destination != pkg.RefundFallbackDestinationBankTransfer ||
+		fallback.Status != pkg.RefundFallbackStatusInProgress {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = refundErrorFallbackNotBankTransfer
+		return nil
+	}
+
+	fallback.TransferReference = req.TransferReference
+
+	err = s.completeRefundFallback(ctx, fallback)
+
+	if err == refundErrorFallbackCompleted {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = refundErrorFallbackNotBankTransfer
+		return nil
+	}
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = refundErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getRefundFallbackMessage(fallback)
+
+	return nil
+}
+
+// startRefundFallback holds the refund declined by the payment system until the customer chooses other way to get
+// the money back and sends the link to choose it. Refund which can't be held stays declined.
+func (s *Service) startRefundFallback(ctx context.Context, order *billingpb.Order, refund *billingpb.Refund) {
+	fallback, err := s.refundFallbackRepository.GetByRefundId(ctx, refund.Id)
+
+	if err != nil {
+		return
+	}
+
+	if fallback != nil {
+		refund.Status = pkg.RefundStatusFallbackInProgress
+
+		if fallback.Status == pkg.RefundFallbackStatusCompleted {
+			refund.Status = pkg.RefundStatusCompleted
+		}
+
+		return
+	}
+
+	if order.GetUser().GetEmail() == "" {
+		zap.L().Error(
+			"refund fallback can't be started without customer email",
+			zap.String("refundId", refund.Id),
+			zap.String("orderId", order.Id),
+		)
+		return
+	}
+
+	fallback = &intPkg.RefundFallback{
+		Token:         s.getTokenString(s.cfg.GetCustomerTokenLength()),
+		CustomerId:    order.GetUser().GetId(),
+		CustomerEmail: order.GetUser().GetEmail(),
+		Amount:        refund.Amount,
+		Currency:      refund.Currency,
+		Status:        pkg.RefundFallbackStatusAwaitingDetails,
+	}
+	fallback.RefundId, _ = primitive.ObjectIDFromHex(refund.Id)
+	fallback.OrderId, _ = primitive.ObjectIDFromHex(order.Id)
+	fallback.MerchantId, _ = primitive.ObjectIDFromHex(order.GetMerchantId())
+	fallback.ProjectId, _ = primitive.ObjectIDFromHex(order.GetProjectId())
+
+	if err = s.refundFallbackRepository.Insert(ctx, fallback); err != nil {
+		return
+	}
+
+	refund.Status = pkg.RefundStatusFallbackInProgress
+	s.sendRefundFallbackEmail(order, fallback)
+}
+
+// completeRefundFallback completes the refund returned to the customer by the chosen destination. The refund order
+// and accounting entries are created as for the refund completed by the payment system. The fallback is marked as
+// completed before the money is credited, so the concurrent request can't credit the refund twice. The store credit
+// is keyed by the fallback identifier, so the completion repeated after failure doesn't credit the refund again.
+func (s *Service) completeRefundFallback(ctx context.Context, fallback *intPkg.RefundFallback) error {
+	refund, err := s.refundRepository.GetById(ctx, fallback.RefundId.Hex())
+
+	if err != nil {
+		return err
+	}
+
+	if refund.Status != pkg.RefundStatusFallbackInProgress {
+		return refundErrorFallbackNotFound
+	}
+
+	order, err := s.getOrderById(ctx, refund.OriginalOrder.Id)
+
+	if err != nil {
+		return err
+	}
+
+	status := fallback.Status
+	fallback.Status = pkg.RefundFallbackStatusCompleted
+	fallback.CompletedAt = time.Now()
+	isUpdated, err := s.refundFallbackRepository.UpdateFromStatus(ctx, fallback, status)
+
+	if err != nil {
+		return err
+	}
+
+	if !isUpdated {
+		return refundErrorFallbackCompleted
+	}
+
+	if fallback.Destination == pkg.RefundFallbackDestinationStoreCredit {
+		credit := &intPkg.StoreCredit{
+			Id:         fallback.Id,
+			CustomerId: fallback.CustomerId,
+			MerchantId: fallback.MerchantId,
+			ProjectId:  fallback.ProjectId,
+			Amount:     fallback.Amount,
+			Currency:   fallback.Currency,
+			Source:     pkg.StoreCreditSourceRefund,
+			SourceId:   refund.Id,
+		}
+
+		if _, err = s.topUpCustomerWallet(ctx, credit); err != nil {
+			s.restoreRefundFallbackStatus(ctx, fallback, status)
+			return err
+		}
+	}
+
+	refund.Status = pkg.RefundStatusCompleted
+	refundOrder, err := s.createOrderByRefund(ctx, order, refund)
+
+	if err != nil {
+		s.restoreRefundFallbackStatus(ctx, fallback, status)
+		return err
+	}
+
+	refund.CreatedOrderId = refundOrder.Id
+	s.revokeRefundOrderKeys(ctx, refundOrder)
+
+	if err = s.refundRepository.Update(ctx, refund); err != nil {
+		return err
+	}
+
+	return s.finishRefund(ctx, order, refund, refundOrder)
+}
+
+// restoreRefundFallbackStatus returns the fallback to the status before the completion if the refund order wasn't
+// created, so the completion can be repeated.
+func (s *Service) restoreRefundFallbackStatus(ctx context.Context, fallback *intPkg.RefundFallback, status string) {
+	fallback.Status = status
+	fallback.CompletedAt = time.Time{}
+
+	if err := s.refundFallbackRepository.Update(ctx, fallback); err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "refundFallbackRepository.Update"),
+			zap.Error(err),
+			zap.String("refundId", fallback.RefundId.Hex()),
+		)
+	}
+}
+
+// getRefundDestination returns the destination of the refund returned to the customer not by the payment system
+// or empty string for the regular refund.
+func (s *Service) getRefundDestination(ctx context.Context, refund *billingpb.Refund) (string, error) {
+	fallback, err := s.refundFallbackRepository.GetByRefundId(ctx, refund.Id)
+
+	if err != nil {
+		return "", err
+	}
+
+	if fallback != nil {
+		return fallback.Destination, nil
+	}
+
+	credit, err := s.storeCreditRepository.GetBySourceId(ctx, pkg.StoreCreditSourceOrderRefund, refund.Id)
+
+	if err != nil || credit == nil {
+		return "", err
+	}
+
+	return pkg.RefundDestinationWallet, nil
+}
+
+func (s *Service) sendRefundFallbackEmail(order *billingpb.Order, fallback *intPkg.RefundFallback) {
+	payload := &postmarkpb.Payload{
+		TemplateAlias: s.cfg.EmailTemplates.RefundFallback,
+		TemplateModel: map[string]string{
+			"project_name":        order.GetProject().GetName()[DefaultLanguage],
+			"order_id":            order.Uuid,
+			"amount":              fmt.Sprintf("%.2f", fallback.Amount),
+			"currency":            fallback.Currency,
+			"refund_fallback_url": s.cfg.GetRefundFallbackUrl(fallback.Token),
+			"current_year":        time.Now().UTC().Format("2006"),
+		},
+		To: fallback.CustomerEmail,
+	}
+	err := s.postmarkBroker.Publish(postmarkpb.PostmarkSenderTopicName, payload, amqp.Table{})
+
+	if err != nil {
+		zap.L().Error(
+			"Publication message about declined refund to queue failed",
+			zap.Error(err),
+			zap.String("refund_fallback_id", fallback.Id.Hex()),
+			zap.String("topic", postmarkpb.PostmarkSenderTopicName),
+		)
+	}
+}
+
+func getRefundFallbackMessage(fallback *intPkg.RefundFallback) *pkg.RefundFallback {
+	msg := &pkg.RefundFallback{
+		Id:                fallback.Id.Hex(),
+		RefundId:          fallback.RefundId.Hex(),
+		Amount:            fallback.Amount,
+		Currency:          fallback.Currency,
+		Destination:       fallback.Destination,
+		Status:            fallback.Status,
+		TransferReference: fallback.TransferReference,
+		CreatedAt:         getTimestampProto(fallback.CreatedAt),
+		CompletedAt:       getTimestampProto(fallback.CompletedAt),
+	}
+
+	if fallback.BankAccount != nil {
+		msg.BankAccount = &pkg.RefundBankAccount{
+			Holder:   fallback.BankAccount.Holder,
+			Iban:     fallback.BankAccount.Iban,
+			Swift:    fallback.BankAccount.Swift,
+			BankName: fallback.BankAccount.BankName,
+			Country:  fallback.BankAccount.Country,
+		}
+	}
+
+	return msg
+}
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	tools "github.com/paysuper/paysuper-tools/number"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+var (
+	refundErrorItemsNotAllowed = errors.NewBillingServerErrorMsg("rf000009", "refund of items available only for orders of products or key products")
+	refundErrorItemsQuantity   = errors.NewBillingServerErrorMsg("rf000010", "order doesn't contain requested quantity of not refunded items")
+)
+
+// CreateItemsRefund creates the refund of the line items of the product or the key product order. The refund amount
+// is the share of the order charge amount which falls on the prices of the items, so the tax and the discount of
+// the order are returned proportionally. The order can be refunded by parts until all items are refunded.
+func (s *Service) CreateItemsRefund(
+	ctx context.Context,
+	req *pkg.CreateItemsRefundRequest,
+	rsp *billingpb.CreateRefundResponse,
+) error {
+	projectId := ""
+
+	if getIdempotencyKey(ctx) != "" {
+		if order, err := s.orderRepository.GetByUuidAndMerchantId(ctx, req.OrderId, req.MerchantId); err == nil {
+			projectId = order.GetProjectId()
+		}
+	}
+
+	err := s.processIdempotent(ctx, idempotencyOperationRefundCreate, projectId, req, rsp, func() error {
+		processor := &createRefundProcessor{
+			service: s,
+			request: &billingpb.CreateRefundRequest{
+				OrderId:    req.OrderId,
+				MerchantId: req.MerchantId,
+				CreatorId:  req.CreatorId,
+				Reason:     req.Reason,
+			},
+			items:   req.Items,
+			checked: &createRefundChecked{},
+			ctx:     ctx,
+		}
+
+		return s.sendRefund(ctx, processor, rsp)
+	})
+
+	if e, ok := err.(*billingpb.ResponseError); ok {
+		rsp.Status = e.Status
+		rsp.Message = e.Message
+		return nil
+	}
+
+	return err
+}
+
+// processRefundItems selects the not refunded order lines of the requested items and calculates the refund amount.
+// The last refund of the order returns the rest of the charge amount, so rounding doesn't leave money on the order.
+func (p *createRefundProcessor) processRefundItems() error {
+	order := p.checked.order
+
+	if order.ProductType != pkg.OrderType_key && order.ProductType != pkg.OrderType_product {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorItemsNotAllowed)
+	}
+
+	total := float64(0)
+
+	for _, item := range order.Items {
+		total += item.Amount
+	}
+
+	if total <= 0 {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorItemsNotAllowed)
+	}
+
+	oid, err := primitive.ObjectIDFromHex(order.Id)
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorUnknown)
+	}
+
+	refunded, err := p.service.getRefundedOrderLines(p.ctx, order.Id)
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorUnknown)
+	}
+
+	refundedAmount, err := p.service.refundRepository.GetReservedAmountByOrderId(p.ctx, order.Id)
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorUnknown)
+	}
+
+	var items []*intPkg.RefundItem
+	selected := make(map[int32]bool)
+	amount := float64(0)
+	refundable := getOrderRefundableAmount(order)
+
+	for _, requested := range p.items {
+		quantity := requested.Quantity
+
+		for i, item := range order.Items {
+			line := int32(i)
+
+			if quantity <= 0 {
+				break
+			}
+
+			if item.Id != requested.ItemId || refunded[line] || selected[line] {
+				continue
+			}
+
+			refundItem := &intPkg.RefundItem{
+				OrderId:      oid,
+				ItemId:       item.Id,
+				Line:         line,
+				Sku:          item.Sku,
+				Name:         item.Name,
+				Amount:       item.Amount,
+				Currency:     item.Currency,
+				RefundAmount: tools.FormatAmount(refundable * item.Amount / total),
+			}
+
+			if order.ProductType == pkg.OrderType_key && i < len(order.Keys) {
+				refundItem.KeyId = order.Keys[i]
+			}
+
+			items = append(items, refundItem)
+			selected[line] = true
+			amount += refundItem.RefundAmount
+			quantity--
+		}
+
+		if quantity > 0 {
+			return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorItemsQuantity)
+		}
+	}
+
+	if len(items) <= 0 {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorItemsQuantity)
+	}
+
+	amount = tools.FormatAmount(amount)
+
+	if len(refunded)+len(items) >= len(order.Items) {
+		rest := tools.FormatAmount(refundable - refundedAmount)
+		last := items[len(items)-1]
+		last.RefundAmount = tools.FormatAmount(last.RefundAmount + rest - amount)
+		amount = rest
+	}
+
+	if amount <= 0 || tools.FormatAmount(refundedAmount+amount) > refundable {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorPaymentAmountLess)
+	}
+
+	p.checked.amount = amount
+	p.checked.items = items
+
+	return nil
+}
+
+// processRefundRestItems selects the order lines which aren't returned by the item refunds of the product or the key
+// product order. The rest of the charge amount is shared between the lines in proportion to their prices.
+func (p *createRefundProcessor) processRefundRestItems() error {
+	order := p.checked.order
+
+	if order.ProductType != pkg.OrderType_key && order.ProductType != pkg.OrderType_product {
+		return nil
+	}
+
+	total := float64(0)
+
+	for _, item := range order.Items {
+		total += item.Amount
+	}
+
+	if total <= 0 {
+		return nil
+	}
+
+	oid, err := primitive.ObjectIDFromHex(order.Id)
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorUnknown)
+	}
+
+	refunded, err := p.service.getRefundedOrderLines(p.ctx, order.Id)
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorUnknown)
+	}
+
+	var items []*intPkg.RefundItem
+	rest := float64(0)
+
+	for i, item := range order.Items {
+		line := int32(i)
+
+		if refunded[line] {
+			continue
+		}
+
+		refundItem := &intPkg.RefundItem{
+			OrderId:  oid,
+			ItemId:   item.Id,
+			Line:     line,
+			Sku:      item.Sku,
+			Name:     item.Name,
+			Amount:   item.Amount,
+			Currency: item.Currency,
+		}
+
+		if order.ProductType == pkg.OrderType_key && i < len(order.Keys) {
+			refundItem.KeyId = order.Keys[i]
+		}
+
+		items = append(items, refundItem)
+		rest += item.Amount
+	}
+
+	amount := float64(0)
+
+	for i, item := range items {
+		if i == len(items)-1 {
+			item.RefundAmount = tools.FormatAmount(p.checked.amount - amount)
+			break
+		}
+
+		item.RefundAmount = tools.FormatAmount(p.checked.amount * item.Amount / rest)
+		amount += item.RefundAmount
+	}
+
+	p.checked.items = items
+
+	return nil
+}
+
+// insertRefundItems saves the items returned by the refund. The refund is rejected if items weren't saved, otherwise
+// the amount of the refund would be held on the order without the refunded lines.
+func (p *createRefundProcessor) insertRefundItems(refund *billingpb.Refund) error {
+	if len(p.checked.items) <= 0 {
+		return nil
+	}
+
+	oid, err := primitive.ObjectIDFromHex(refund.Id)
+
+	if err != nil {
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, orderErrorUnknown)
+	}
+
+	for _, item := range p.checked.items {
+		item.RefundId = oid
+	}
+
+	if err = p.service.refundItemRepository.InsertMany(p.ctx, p.checked.items); err == nil {
+		return nil
+	}
+
+	p.rejectRefund(refund)
+
+	return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, orderErrorUnknown)
+}
+
+// getRefundedOrderLines returns indexes of the order lines which are returned by the refunds of the order.
+// Lines of the rejected and declined refunds are available to refund again.
+func (s *Service) getRefundedOrderLines(ctx context.Context, orderId string) (map[int32]bool, error) {
+	items, err := s.refundItemRepository.FindReservedByOrderId(ctx, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(map[int32]bool)
+
+	for _, item := range items {
+		lines[item.Line] = true
+	}
+
+	return lines, nil
+}
+
+// setRefundOrderItems leaves in the refund order only the items returned by the refund, so the refund receipt and
+// the accounting entries of the refund order list the refunded items.
+func (s *Service) setRefundOrderItems(
+	ctx context.Context,
+	order *billingpb.Order,
+	refund *billingpb.Refund,
+	refundOrder *billingpb.Order,
+) error {
+	items, err := s.refundItemRepository.FindByRefundId(ctx, refund.Id)
+
+	if err != nil || len(items) <= 0 {
+		return err
+	}
+
+	var (
+		orderItems []*billingpb.OrderItem
+		products   []string
+		keys       []string
+	)
+
+	for _, item := range items {
+		if int(item.Line) < len(refundOrder.Items) {
+			orderItems = append(orderItems, refundOrder.Items[item.Line])
+		}
+
+		products = append(products, item.ItemId)
+
+		if item.KeyId != "" {
+			keys = append(keys, item.KeyId)
+		}
+	}
+
+	refundOrder.Items = orderItems
+	refundOrder.Products = products
+	refundOrder.Keys = keys
+
+	if refundable := getOrderRefundableAmount(order); refundable > 0 {
+		refundOrder.TotalPaymentAmount = tools.FormatAmount(order.TotalPaymentAmount * refund.Amount / refundable)
+	}
+
+	return nil
+}
+
+// revokeRefundOrderKeys revokes the keys returned by the refund and cancels the gift cards sold as the keys. Failure
+// is logged only because the money is already returned to the customer.
+func (s *Service) revokeRefundOrderKeys(ctx context.Context, refundOrder *billingpb.Order) {
+	if refundOrder.ProductType != pkg.OrderType_key {
+		return
+	}
+
+	for _, keyId := range refundOrder.Keys {
+		if _, err := s.keyRepository.RevokeById(ctx, keyId); err != nil {
+			zap.L().Error(
+				pkg.MethodFinishedWithError,
+				zap.String("method", "keyRepository.RevokeById"),
+				zap.Error(err),
+				zap.String("orderId", refundOrder.ParentOrder.GetId()),
+				zap.String("keyId", keyId),
+			)
+		}
+
+		s.cancelGiftCard(ctx, keyId)
+	}
+}
@@ -20,8 +20,11 @@ import (
 	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
 	"github.com/paysuper/paysuper-proto/go/recurringpb"
 	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	tools "github.com/paysuper/paysuper-tools/number"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 	"gopkg.in/ProtocolONE/rabbitmq.v1/pkg"
@@ -2892,10 +2895,18 @@ func (suite *RefundTestSuite) TestRefund_ProcessRefundCallback_Declined() {
 	refund, err := suite.service.refundRepository.GetById(context.TODO(), rsp2.Item.Id)
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), refund)
-	assert.Equal(suite.T(), pkg.RefundStatusPaymentSystemDeclined, refund.Status)
+	assert.Equal(suite.T(), pkg.RefundStatusFallbackInProgress, refund.Status)
 	assert.False(suite.T(), refund.IsChargeback)
 	assert.Empty(suite.T(), refund.CreatedOrderId)
 
+	fallback, err := suite.service.refundFallbackRepository.GetByRefundId(context.TODO(), refund.Id)
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), fallback)
+	assert.Equal(suite.T(), pkg.RefundFallbackStatusAwaitingDetails, fallback.Status)
+	assert.Equal(suite.T(), refund.Amount, fallback.Amount)
+	assert.Equal(suite.T(), order.User.Email, fallback.CustomerEmail)
+	assert.NotEmpty(suite.T(), fallback.Token)
+
 	// check RefundAllowed flag for original order has correct value in order
 	originalOrder, err := suite.service.orderRepository.GetById(context.TODO(), refund.OriginalOrder.Id)
 	assert.NoError(suite.T(), err)
@@ -2919,3 +2930,1005 @@ func (suite *RefundTestSuite) TestRefund_ProcessRefundCallback_Declined() {
 	assert.Equal(suite.T(), originalOrderViewPublic.OrderCharge.Currency, originalOrderViewPublicFromJson.OrderCharge.Currency)
 	assert.Equal(suite.T(), originalOrderViewPublic.OrderCharge.AmountRounded, originalOrderViewPublicFromJson.OrderCharge.AmountRounded)
 }
+
+func (suite *RefundTestSuite) TestRefund_CreateItemsRefund_Ok() {
+	order, productIds := suite.createKeyProductsOrder()
+
+	req := &pkg.CreateItemsRefundRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.project.MerchantId,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		Reason:     "unit test",
+		Items:      []*pkg.RefundItemRequest{{ItemId: productIds[0], Quantity: 1}},
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateItemsRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Empty(suite.T(), rsp.Message)
+	assert.NotNil(suite.T(), rsp.Item)
+	assert.Equal(suite.T(), float64(60), rsp.Item.Amount)
+	assert.Equal(suite.T(), float32(8), rsp.Item.SalesTax)
+	assert.Equal(suite.T(), pkg.RefundStatusInProgress, rsp.Item.Status)
+
+	items, err := suite.service.refundItemRepository.FindByRefundId(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), items, 1)
+	assert.Equal(suite.T(), productIds[0], items[0].ItemId)
+	assert.EqualValues(suite.T(), 0, items[0].Line)
+	assert.Equal(suite.T(), order.Keys[0], items[0].KeyId)
+	assert.Equal(suite.T(), float64(60), items[0].RefundAmount)
+
+	req.Items = []*pkg.RefundItemRequest{{ItemId: productIds[0], Quantity: 1}, {ItemId: productIds[1], Quantity: 1}}
+	rsp = &billingpb.CreateRefundResponse{}
+	err = suite.service.CreateItemsRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), float64(90), rsp.Item.Amount)
+
+	items, err = suite.service.refundItemRepository.FindByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), items, 3)
+
+	refundedAmount, err := suite.service.refundRepository.GetAmountByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), order.ChargeAmount, refundedAmount)
+}
+
+func (suite *RefundTestSuite) TestRefund_CreateItemsRefund_QuantityExceeded_Error() {
+	order, productIds := suite.createKeyProductsOrder()
+
+	req := &pkg.CreateItemsRefundRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.project.MerchantId,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		Items:      []*pkg.RefundItemRequest{{ItemId: productIds[0], Quantity: 1}},
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateItemsRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	req.Items = []*pkg.RefundItemRequest{{ItemId: productIds[0], Quantity: 2}}
+	rsp = &billingpb.CreateRefundResponse{}
+	err = suite.service.CreateItemsRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), refundErrorItemsQuantity, rsp.Message)
+	assert.Nil(suite.T(), rsp.Item)
+
+	req.Items = []*pkg.RefundItemRequest{{ItemId: primitive.NewObjectID().Hex(), Quantity: 1}}
+	rsp = &billingpb.CreateRefundResponse{}
+	err = suite.service.CreateItemsRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), refundErrorItemsQuantity, rsp.Message)
+}
+
+func (suite *RefundTestSuite) TestRefund_CreateItemsRefund_SimpleOrder_Error() {
+	order, productIds := suite.createKeyProductsOrder()
+
+	order.ProductType = ""
+	err := suite.service.updateOrder(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	req := &pkg.CreateItemsRefundRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.project.MerchantId,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		Items:      []*pkg.RefundItemRequest{{ItemId: productIds[0], Quantity: 1}},
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err = suite.service.CreateItemsRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), refundErrorItemsNotAllowed, rsp.Message)
+}
+
+func (suite *RefundTestSuite) TestRefund_ProcessRefundCallback_Items_Ok() {
+	order, productIds := suite.createKeyProductsOrder()
+
+	req := &pkg.CreateItemsRefundRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.project.MerchantId,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		Reason:     "unit test",
+		Items:      []*pkg.RefundItemRequest{{ItemId: productIds[1], Quantity: 1}},
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateItemsRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), float64(30), rsp.Item.Amount)
+
+	refundReq := &billingpb.CardPayRefundCallback{
+		MerchantOrder: &billingpb.CardPayMerchantOrder{
+			Id: rsp.Item.Id,
+		},
+		PaymentMethod: order.PaymentMethod.Group,
+		PaymentData: &billingpb.CardPayRefundCallbackPaymentData{
+			Id:              rsp.Item.Id,
+			RemainingAmount: 120,
+		},
+		RefundData: &billingpb.CardPayRefundCallbackRefundData{
+			Amount:   30,
+			Created:  time.Now().Format(payment_system.CardPayDateFormat),
+			Id:       primitive.NewObjectID().Hex(),
+			Currency: rsp.Item.Currency,
+			Status:   billingpb.CardPayPaymentResponseStatusCompleted,
+			AuthCode: primitive.NewObjectID().Hex(),
+			Is_3D:    true,
+			Rrn:      primitive.NewObjectID().Hex(),
+		},
+		CallbackTime: time.Now().Format(payment_system.CardPayDateFormat),
+		Customer: &billingpb.CardPayCustomer{
+			Email: order.User.Email,
+			Id:    order.User.Email,
+		},
+	}
+
+	b, err := json.Marshal(refundReq)
+	assert.NoError(suite.T(), err)
+
+	hash := sha512.New()
+	hash.Write([]byte(string(b) + order.PaymentMethod.Params.SecretCallback))
+
+	req1 := &billingpb.CallbackRequest{
+		Handler:   billingpb.PaymentSystemHandlerCardPay,
+		Body:      b,
+		Signature: hex.EncodeToString(hash.Sum(nil)),
+	}
+	rsp1 := &billingpb.PaymentNotifyResponse{}
+	err = suite.service.ProcessRefundCallback(context.TODO(), req1, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+	assert.Empty(suite.T(), rsp1.Error)
+
+	refund, err := suite.service.refundRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundStatusCompleted, refund.Status)
+	assert.NotEmpty(suite.T(), refund.CreatedOrderId)
+
+	refundOrder, err := suite.service.orderRepository.GetById(context.TODO(), refund.CreatedOrderId)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), refundOrder.Items, 1)
+	assert.Equal(suite.T(), productIds[1], refundOrder.Items[0].Id)
+	assert.Equal(suite.T(), []string{productIds[1]}, refundOrder.Products)
+	assert.Equal(suite.T(), []string{order.Keys[2]}, refundOrder.Keys)
+	assert.Equal(suite.T(), float64(30), refundOrder.ChargeAmount)
+
+	originalOrder, err := suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), originalOrder.Refunded)
+
+	for i, keyId := range order.Keys {
+		oid, err := primitive.ObjectIDFromHex(keyId)
+		assert.NoError(suite.T(), err)
+
+		key := make(bson.M)
+		err = suite.service.db.Collection("key").FindOne(context.TODO(), bson.M{"_id": oid}).Decode(&key)
+		assert.NoError(suite.T(), err)
+
+		_, ok := key["revoked_at"]
+		assert.Equal(suite.T(), i == 2, ok)
+	}
+}
+
+func (suite *RefundTestSuite) TestRefund_CreateRefund_AfterItemsRefund_Ok() {
+	order, productIds := suite.createKeyProductsOrder()
+
+	req := &pkg.CreateItemsRefundRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.project.MerchantId,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		Reason:     "unit test",
+		Items:      []*pkg.RefundItemRequest{{ItemId: productIds[1], Quantity: 1}},
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateItemsRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), float64(30), rsp.Item.Amount)
+
+	req1 := &billingpb.CreateRefundRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.project.MerchantId,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		Reason:     "unit test",
+	}
+	rsp1 := &billingpb.CreateRefundResponse{}
+	err = suite.service.CreateRefund(context.TODO(), req1, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+	assert.Empty(suite.T(), rsp1.Message)
+	assert.Equal(suite.T(), float64(120), rsp1.Item.Amount)
+	assert.Equal(suite.T(), float32(16), rsp1.Item.SalesTax)
+
+	items, err := suite.service.refundItemRepository.FindByRefundId(context.TODO(), rsp1.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), items, 2)
+	assert.EqualValues(suite.T(), 0, items[0].Line)
+	assert.EqualValues(suite.T(), 1, items[1].Line)
+	assert.Equal(suite.T(), []string{order.Keys[0], order.Keys[1]}, []string{items[0].KeyId, items[1].KeyId})
+	assert.Equal(suite.T(), float64(120), tools.FormatAmount(items[0].RefundAmount+items[1].RefundAmount))
+
+	rsp1 = &billingpb.CreateRefundResponse{}
+	err = suite.service.CreateRefund(context.TODO(), req1, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp1.Status)
+	assert.Equal(suite.T(), refundErrorPaymentAmountLess, rsp1.Message)
+}
+
+func (suite *RefundTestSuite) TestRefund_ChargebackDisputedOrder_AfterItemsRefund_Ok() {
+	order, productIds := suite.createKeyProductsOrder()
+
+	req := &pkg.CreateItemsRefundRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.project.MerchantId,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		Reason:     "unit test",
+		Items:      []*pkg.RefundItemRequest{{ItemId: productIds[0], Quantity: 1}},
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateItemsRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), float64(60), rsp.Item.Amount)
+
+	dispute := &intPkg.Dispute{Id: primitive.NewObjectID(), ReasonCode: "4837", ChargebackId: primitive.NewObjectID().Hex()}
+	err = suite.service.chargebackDisputedOrder(context.TODO(), dispute, order)
+	assert.NoError(suite.T(), err)
+
+	refund, err := suite.service.refundRepository.GetById(context.TODO(), dispute.ChargebackId)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), refund.IsChargeback)
+	assert.Equal(suite.T(), float64(90), refund.Amount)
+	assert.Equal(suite.T(), pkg.RefundStatusCompleted, refund.Status)
+
+	items, err := suite.service.refundItemRepository.FindByRefundId(context.TODO(), refund.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), items, 2)
+	assert.EqualValues(suite.T(), 1, items[0].Line)
+	assert.EqualValues(suite.T(), 2, items[1].Line)
+
+	refundOrder, err := suite.service.orderRepository.GetById(context.TODO(), refund.CreatedOrderId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), float64(90), refundOrder.ChargeAmount)
+	assert.Equal(suite.T(), []string{order.Keys[1], order.Keys[2]}, refundOrder.Keys)
+}
+
+func (suite *RefundTestSuite) createKeyProductsOrder() (*billingpb.Order, []string) {
+	req := &billingpb.OrderCreateRequest{
+		Type:        pkg.OrderType_simple,
+		ProjectId:   suite.project.Id,
+		Currency:    "RUB",
+		Amount:      150,
+		Account:     "unit test",
+		Description: "unit test",
+		User: &billingpb.OrderUser{
+			Email: "some_email@unit.com",
+			Ip:    "127.0.0.1",
+			Phone: "123456789",
+		},
+	}
+
+	rsp0 := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp0)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), rsp0.Status, billingpb.ResponseStatusOk)
+	rsp := rsp0.Item
+
+	expireYear := time.Now().AddDate(1, 0, 0)
+
+	createPaymentRequest := &billingpb.PaymentCreateRequest{
+		Data: map[string]string{
+			billingpb.PaymentCreateFieldOrderId:         rsp.Uuid,
+			billingpb.PaymentCreateFieldPaymentMethodId: suite.pmBankCard.Id,
+			billingpb.PaymentCreateFieldEmail:           "test@unit.unit",
+			billingpb.PaymentCreateFieldPan:             "4000000000000002",
+			billingpb.PaymentCreateFieldCvv:             "123",
+			billingpb.PaymentCreateFieldMonth:           "02",
+			billingpb.PaymentCreateFieldYear:            expireYear.Format("2006"),
+			billingpb.PaymentCreateFieldHolder:          "Mr. Card Holder",
+		},
+		Cookie: suite.cookie,
+	}
+
+	rsp1 := &billingpb.PaymentCreateResponse{}
+	err = suite.service.PaymentCreateProcess(context.TODO(), createPaymentRequest, rsp1)
+	assert.NoError(suite.T(), err)
+
+	order, err := suite.service.orderRepository.GetById(context.TODO(), rsp.Id)
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), order)
+
+	productIds := []string{primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()}
+	order.ProductType = pkg.OrderType_key
+	order.Products = []string{productIds[0], productIds[0], productIds[1]}
+	order.Items = []*billingpb.OrderItem{
+		{Id: productIds[0], Name: "Game", Amount: 40, Currency: "RUB"},
+		{Id: productIds[0], Name: "Game", Amount: 40, Currency: "RUB"},
+		{Id: productIds[1], Name: "DLC", Amount: 20, Currency: "RUB"},
+	}
+	order.Keys = make([]string, len(order.Products))
+
+	for i, productId := range order.Products {
+		key := &billingpb.Key{
+			Id:           primitive.NewObjectID().Hex(),
+			Code:         "code",
+			KeyProductId: productId,
+			PlatformId:   "steam",
+			OrderId:      order.Id,
+		}
+		err = suite.service.keyRepository.Insert(context.TODO(), key)
+		assert.NoError(suite.T(), err)
+		order.Keys[i] = key.Id
+	}
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemComplete
+	order.ChargeAmount = 150
+	order.TotalPaymentAmount = 150
+	order.Tax = &billingpb.OrderTax{
+		Type:     taxTypeVat,
+		Rate:     20,
+		Amount:   20,
+		Currency: "RUB",
+	}
+	order.PaymentMethod.Params.Currency = "USD"
+	order.PaymentMethodOrderClosedAt, _ = ptypes.TimestampProto(time.Now().Add(-30 * time.Minute))
+	err = suite.service.updateOrder(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	entryTypes := []string{
+		pkg.AccountingEntryTypeMerchantTaxFeeCostValue,
+		pkg.AccountingEntryTypeMerchantTaxFeeCentralBankFx,
+		pkg.AccountingEntryTypeRealTaxFee,
+	}
+	var accountingEntries []*billingpb.AccountingEntry
+
+	for _, entryType := range entryTypes {
+		accountingEntries = append(accountingEntries, &billingpb.AccountingEntry{
+			Id:     primitive.NewObjectID().Hex(),
+			Object: pkg.ObjectTypeBalanceTransaction,
+			Type:   entryType,
+			Source: &billingpb.AccountingEntrySource{
+				Id:   order.Id,
+				Type: repository.CollectionOrder,
+			},
+			MerchantId: order.GetMerchantId(),
+			Status:     pkg.BalanceTransactionStatusAvailable,
+			CreatedAt:  ptypes.TimestampNow(),
+			Country:    order.GetCountry(),
+			Currency:   order.GetMerchantRoyaltyCurrency(),
+		})
+	}
+
+	err = suite.service.accountingRepository.MultipleInsert(context.TODO(), accountingEntries)
+	assert.NoError(suite.T(), err)
+
+	return order, productIds
+}
+
+func (suite *RefundTestSuite) TestRefund_CreateRefund_ApprovalRequired_Ok() {
+	order, _ := suite.createKeyProductsOrder()
+	suite.setRefundApprovalSettings(0, "", 50)
+
+	creatorId := primitive.NewObjectID().Hex()
+	req := &billingpb.CreateRefundRequest{
+		OrderId:    order.Uuid,
+		CreatorId:  creatorId,
+		Reason:     "unit test",
+		MerchantId: suite.project.MerchantId,
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), pkg.RefundStatusPendingApproval, rsp.Item.Status)
+	assert.Empty(suite.T(), rsp.Item.ExternalId)
+
+	approval, err := suite.service.refundApprovalRepository.GetByRefundId(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundApprovalStatusPending, approval.Status)
+	assert.Equal(suite.T(), billingpb.RoleMerchantAccounting, approval.ApproverRole)
+
+	reviewReq := &pkg.ReviewRefundRequest{
+		MerchantId: suite.project.MerchantId,
+		OrderId:    order.Uuid,
+		RefundId:   rsp.Item.Id,
+		UserId:     suite.addMerchantUser(billingpb.RoleMerchantDeveloper),
+	}
+	rsp1 := &billingpb.CreateRefundResponse{}
+	err = suite.service.ApproveRefund(context.TODO(), reviewReq, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, rsp1.Status)
+	assert.Equal(suite.T(), refundErrorApproverRoleInvalid, rsp1.Message)
+
+	reviewReq.UserId = suite.addMerchantUser(billingpb.RoleMerchantAccounting)
+	rsp1 = &billingpb.CreateRefundResponse{}
+	err = suite.service.ApproveRefund(context.TODO(), reviewReq, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+	assert.Equal(suite.T(), pkg.RefundStatusInProgress, rsp1.Item.Status)
+	assert.NotEmpty(suite.T(), rsp1.Item.ExternalId)
+
+	approval, err = suite.service.refundApprovalRepository.GetByRefundId(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundApprovalStatusApproved, approval.Status)
+	assert.Equal(suite.T(), reviewReq.UserId, approval.ReviewerId)
+
+	rsp1 = &billingpb.CreateRefundResponse{}
+	err = suite.service.ApproveRefund(context.TODO(), reviewReq, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp1.Status)
+	assert.Equal(suite.T(), refundErrorApprovalNotPending, rsp1.Message)
+}
+
+func (suite *RefundTestSuite) TestRefund_CreateRefund_ApprovalNotCreated_Rejected() {
+	order, _ := suite.createKeyProductsOrder()
+	suite.setRefundApprovalSettings(0, "", 50)
+
+	approvalRepository := &mocks.RefundApprovalRepositoryInterface{}
+	approvalRepository.On("Insert", mock.Anything, mock.Anything).Return(refundErrorUnknown)
+	suite.service.refundApprovalRepository = approvalRepository
+
+	req := &billingpb.CreateRefundRequest{
+		OrderId:    order.Uuid,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		Reason:     "unit test",
+		MerchantId: suite.project.MerchantId,
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+	assert.Equal(suite.T(), refundErrorUnknown, rsp.Message)
+
+	// the refund without the approval doesn't wait for it and doesn't reserve the order amount
+	refunds, err := suite.service.refundRepository.FindByOrderUuid(context.TODO(), order.Uuid, 10, 0)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), refunds, 1)
+	assert.Equal(suite.T(), pkg.RefundStatusRejected, refunds[0].Status)
+
+	amount, err := suite.service.refundRepository.GetReservedAmountByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), amount)
+}
+
+func (suite *RefundTestSuite) TestRefund_ApproveRefund_ConcurrentReview_Error() {
+	order, _ := suite.createKeyProductsOrder()
+	suite.setRefundApprovalSettings(0, "", 50)
+
+	req := &billingpb.CreateRefundRequest{
+		OrderId:    order.Uuid,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		MerchantId: suite.project.MerchantId,
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundStatusPendingApproval, rsp.Item.Status)
+
+	staleApproval, err := suite.service.refundApprovalRepository.GetByRefundId(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+
+	reviewReq := &pkg.ReviewRefundRequest{
+		MerchantId: suite.project.MerchantId,
+		OrderId:    order.Uuid,
+		RefundId:   rsp.Item.Id,
+		UserId:     suite.addMerchantUser(billingpb.RoleMerchantAccounting),
+	}
+	rsp1 := &billingpb.CreateRefundResponse{}
+	err = suite.service.ApproveRefund(context.TODO(), reviewReq, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+
+	err = suite.service.reviewRefundApproval(context.TODO(), staleApproval, reviewReq, pkg.RefundApprovalStatusApproved)
+	assert.Equal(suite.T(), errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, refundErrorApprovalNotPending), err)
+}
+
+func (suite *RefundTestSuite) TestRefund_ApproveRefund_ApproverIsCreator_Error() {
+	order, _ := suite.createKeyProductsOrder()
+	suite.setRefundApprovalSettings(0, "", 50)
+
+	userId := suite.addMerchantUser(billingpb.RoleMerchantAccounting)
+	req := &billingpb.CreateRefundRequest{
+		OrderId:    order.Uuid,
+		CreatorId:  userId,
+		MerchantId: suite.project.MerchantId,
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundStatusPendingApproval, rsp.Item.Status)
+
+	reviewReq := &pkg.ReviewRefundRequest{
+		MerchantId: suite.project.MerchantId,
+		OrderId:    order.Uuid,
+		RefundId:   rsp.Item.Id,
+		UserId:     userId,
+	}
+	rsp1 := &billingpb.CreateRefundResponse{}
+	err = suite.service.ApproveRefund(context.TODO(), reviewReq, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, rsp1.Status)
+	assert.Equal(suite.T(), refundErrorApproverIsCreator, rsp1.Message)
+}
+
+func (suite *RefundTestSuite) TestRefund_RejectRefund_Ok() {
+	order, _ := suite.createKeyProductsOrder()
+	suite.setRefundApprovalSettings(100, order.ChargeCurrency, 0)
+
+	req := &billingpb.CreateRefundRequest{
+		OrderId:    order.Uuid,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		MerchantId: suite.project.MerchantId,
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundStatusPendingApproval, rsp.Item.Status)
+
+	reviewReq := &pkg.ReviewRefundRequest{
+		MerchantId: suite.project.MerchantId,
+		OrderId:    order.Uuid,
+		RefundId:   rsp.Item.Id,
+		UserId:     suite.addMerchantUser(billingpb.RoleMerchantAccounting),
+		Comment:    "unit test",
+	}
+	rsp1 := &billingpb.CreateRefundResponse{}
+	err = suite.service.RejectRefund(context.TODO(), reviewReq, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+	assert.Equal(suite.T(), pkg.RefundStatusRejected, rsp1.Item.Status)
+
+	approval, err := suite.service.refundApprovalRepository.GetByRefundId(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundApprovalStatusRejected, approval.Status)
+	assert.Equal(suite.T(), "unit test", approval.Comment)
+
+	refundedAmount, err := suite.service.refundRepository.GetReservedAmountByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), refundedAmount)
+}
+
+func (suite *RefundTestSuite) TestRefund_CreateRefund_BelowApprovalThreshold_Ok() {
+	order, _ := suite.createKeyProductsOrder()
+	suite.setRefundApprovalSettings(1000, order.ChargeCurrency, 0)
+
+	req := &billingpb.CreateRefundRequest{
+		OrderId:    order.Uuid,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		MerchantId: suite.project.MerchantId,
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), pkg.RefundStatusInProgress, rsp.Item.Status)
+
+	_, err = suite.service.refundApprovalRepository.GetByRefundId(context.TODO(), rsp.Item.Id)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *RefundTestSuite) TestRefund_SetRefundApprovalSettings_InvalidRole_Error() {
+	req := &pkg.SetRefundApprovalSettingsRequest{
+		MerchantId:   suite.project.MerchantId,
+		Percent:      50,
+		ApproverRole: billingpb.RoleSystemAdmin,
+	}
+	rsp := &pkg.RefundApprovalSettingsResponse{}
+	err := suite.service.SetRefundApprovalSettings(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), refundErrorApprovalSettingsInvalid, rsp.Message)
+
+	req.ApproverRole = billingpb.RoleMerchantAccounting
+	req.Amount = 100
+	rsp = &pkg.RefundApprovalSettingsResponse{}
+	err = suite.service.SetRefundApprovalSettings(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), refundErrorApprovalSettingsInvalid, rsp.Message)
+}
+
+func (suite *RefundTestSuite) setRefundApprovalSettings(amount float64, currency string, percent float64) {
+	req := &pkg.SetRefundApprovalSettingsRequest{
+		MerchantId:   suite.project.MerchantId,
+		Amount:       amount,
+		Currency:     currency,
+		Percent:      percent,
+		ApproverRole: billingpb.RoleMerchantAccounting,
+	}
+	rsp := &pkg.RefundApprovalSettingsResponse{}
+	err := suite.service.SetRefundApprovalSettings(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+}
+
+func (suite *RefundTestSuite) addMerchantUser(role string) string {
+	user := &billingpb.UserRole{
+		Id:         primitive.NewObjectID().Hex(),
+		MerchantId: suite.project.MerchantId,
+		UserId:     primitive.NewObjectID().Hex(),
+		Email:      "approver@unit.test",
+		Role:       role,
+		Status:     pkg.UserRoleStatusAccepted,
+	}
+	err := suite.service.userRoleRepository.AddMerchantUser(context.TODO(), user)
+	assert.NoError(suite.T(), err)
+
+	return user.UserId
+}
+
+func (suite *RefundTestSuite) TestRefund_SetRefundFallbackDestination_StoreCredit_Ok() {
+	order, _ := suite.createKeyProductsOrder()
+	fallback := suite.declineRefund(order)
+
+	req := &pkg.SetRefundFallbackDestinationRequest{
+		Token:       fallback.Token,
+		Destination: pkg.RefundFallbackDestinationStoreCredit,
+	}
+	rsp := &pkg.RefundFallbackResponse{}
+	err := suite.service.SetRefundFallbackDestination(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), pkg.RefundFallbackStatusCompleted, rsp.Item.Status)
+	assert.NotNil(suite.T(), rsp.Item.CompletedAt)
+
+	refund, err := suite.service.refundRepository.GetById(context.TODO(), fallback.RefundId.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundStatusCompleted, refund.Status)
+	assert.NotEmpty(suite.T(), refund.CreatedOrderId)
+
+	credits, err := suite.service.storeCreditRepository.FindByCustomerId(
+		context.TODO(),
+		fallback.CustomerId,
+		order.GetProjectId(),
+	)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), credits, 1)
+	assert.Equal(suite.T(), refund.Amount, credits[0].Amount)
+	assert.Equal(suite.T(), refund.Currency, credits[0].Currency)
+	assert.Equal(suite.T(), pkg.StoreCreditSourceRefund, credits[0].Source)
+	assert.Equal(suite.T(), refund.Id, credits[0].SourceId)
+
+	entries := suite.getRefundAccountingEntries(refund)
+	assert.Contains(suite.T(), entries, pkg.AccountingEntryTypeRefundStoreCredit)
+	assert.NotContains(suite.T(), entries, pkg.AccountingEntryTypeRefundBankTransfer)
+	assert.Equal(suite.T(), refund.Amount, entries[pkg.AccountingEntryTypeRefundStoreCredit].OriginalAmount)
+	assert.Zero(suite.T(), entries[pkg.AccountingEntryTypeRealRefundFee].Amount)
+	assert.Zero(suite.T(), entries[pkg.AccountingEntryTypeRealRefundFixedFee].Amount)
+
+	originalOrder, err := suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), originalOrder.Refunded)
+
+	rsp = &pkg.RefundFallbackResponse{}
+	err = suite.service.SetRefundFallbackDestination(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), refundErrorFallbackDestinationChosen, rsp.Message)
+}
+
+func (suite *RefundTestSuite) TestRefund_CompleteRefundFallback_ConcurrentCompletion_Error() {
+	order, _ := suite.createKeyProductsOrder()
+	fallback := suite.declineRefund(order)
+	fallback.Destination = pkg.RefundFallbackDestinationStoreCredit
+
+	staleFallback := &intPkg.RefundFallback{}
+	*staleFallback = *fallback
+
+	err := suite.service.completeRefundFallback(context.TODO(), fallback)
+	assert.NoError(suite.T(), err)
+
+	refund, err := suite.service.refundRepository.GetById(context.TODO(), fallback.RefundId.Hex())
+	assert.NoError(suite.T(), err)
+	refund.Status = pkg.RefundStatusFallbackInProgress
+	err = suite.service.refundRepository.Update(context.TODO(), refund)
+	assert.NoError(suite.T(), err)
+
+	err = suite.service.completeRefundFallback(context.TODO(), staleFallback)
+	assert.Equal(suite.T(), refundErrorFallbackCompleted, err)
+
+	credits, err := suite.service.storeCreditRepository.FindByCustomerId(
+		context.TODO(),
+		fallback.CustomerId,
+		order.GetProjectId(),
+	)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), credits, 1)
+}
+
+func (suite *RefundTestSuite) TestRefund_CompleteRefundFallback_RepeatedAfterCredit_CreditedOnce() {
+	order, _ := suite.createKeyProductsOrder()
+	fallback := suite.declineRefund(order)
+	fallback.Destination = pkg.RefundFallbackDestinationStoreCredit
+
+	// wallet is credited by the completion failed before the store credit was saved
+	credit := &intPkg.StoreCredit{
+		Id:         fallback.Id,
+		CustomerId: fallback.CustomerId,
+		MerchantId: fallback.MerchantId,
+		ProjectId:  fallback.ProjectId,
+		Amount:     fallback.Amount,
+		Currency:   fallback.Currency,
+	}
+	_, err := suite.service.customerWalletRepository.Increase(
+		context.TODO(),
+		&intPkg.CustomerWallet{
+			CustomerId: credit.CustomerId,
+			MerchantId: credit.MerchantId,
+			ProjectId:  credit.ProjectId,
+			Currency:   credit.Currency,
+		},
+		0,
+	)
+	assert.NoError(suite.T(), err)
+	wallet, err := suite.service.customerWalletRepository.GetByCustomerId(
+		context.TODO(),
+		fallback.CustomerId,
+		order.GetProjectId(),
+		fallback.Currency,
+	)
+	assert.NoError(suite.T(), err)
+	isAdded, err := suite.service.customerWalletRepository.IncreaseOnce(context.TODO(), wallet.Id.Hex(), credit.Id, credit.Amount)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), isAdded)
+
+	err = suite.service.completeRefundFallback(context.TODO(), fallback)
+	assert.NoError(suite.T(), err)
+
+	wallet, err = suite.service.customerWalletRepository.GetById(context.TODO(), wallet.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), fallback.Amount, wallet.Balance)
+
+	credits, err := suite.service.storeCreditRepository.FindByCustomerId(
+		context.TODO(),
+		fallback.CustomerId,
+		order.GetProjectId(),
+	)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), credits, 1)
+	assert.Equal(suite.T(), fallback.Id, credits[0].Id)
+}
+
+func (suite *RefundTestSuite) TestRefund_CompleteRefundFallback_BankTransfer_Ok() {
+	order, _ := suite.createKeyProductsOrder()
+	fallback := suite.declineRefund(order)
+
+	req := &pkg.SetRefundFallbackDestinationRequest{
+		Token:       fallback.Token,
+		Destination: pkg.RefundFallbackDestinationBankTransfer,
+		BankAccount: &pkg.RefundBankAccount{
+			Holder:  "Mr. Card Holder",
+			Iban:    "DE89370400440532013000",
+			Swift:   "COBADEFFXXX",
+			Country: "DE",
+		},
+	}
+	rsp := &pkg.RefundFallbackResponse{}
+	err := suite.service.SetRefundFallbackDestination(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), pkg.RefundFallbackStatusInProgress, rsp.Item.Status)
+	assert.Equal(suite.T(), req.BankAccount.Iban, rsp.Item.BankAccount.Iban)
+
+	refund, err := suite.service.refundRepository.GetById(context.TODO(), fallback.RefundId.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundStatusFallbackInProgress, refund.Status)
+
+	req1 := &pkg.CompleteRefundFallbackRequest{
+		RefundId:          refund.Id,
+		TransferReference: "TR-0001",
+	}
+	rsp1 := &pkg.RefundFallbackResponse{}
+	err = suite.service.CompleteRefundFallback(context.TODO(), req1, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+	assert.Equal(suite.T(), pkg.RefundFallbackStatusCompleted, rsp1.Item.Status)
+	assert.Equal(suite.T(), req1.TransferReference, rsp1.Item.TransferReference)
+
+	refund, err = suite.service.refundRepository.GetById(context.TODO(), refund.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundStatusCompleted, refund.Status)
+	assert.NotEmpty(suite.T(), refund.CreatedOrderId)
+
+	entries := suite.getRefundAccountingEntries(refund)
+	assert.Contains(suite.T(), entries, pkg.AccountingEntryTypeRefundBankTransfer)
+	assert.Equal(suite.T(), refund.Amount, entries[pkg.AccountingEntryTypeRefundBankTransfer].OriginalAmount)
+
+	credits, err := suite.service.storeCreditRepository.FindByCustomerId(
+		context.TODO(),
+		fallback.CustomerId,
+		order.GetProjectId(),
+	)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), credits)
+
+	rsp1 = &pkg.RefundFallbackResponse{}
+	err = suite.service.CompleteRefundFallback(context.TODO(), req1, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp1.Status)
+	assert.Equal(suite.T(), refundErrorFallbackNotBankTransfer, rsp1.Message)
+}
+
+func (suite *RefundTestSuite) TestRefund_SetRefundFallbackDestination_BankAccountMissing_Error() {
+	order, _ := suite.createKeyProductsOrder()
+	fallback := suite.declineRefund(order)
+
+	req := &pkg.SetRefundFallbackDestinationRequest{
+		Token:       fallback.Token,
+		Destination: pkg.RefundFallbackDestinationBankTransfer,
+	}
+	rsp := &pkg.RefundFallbackResponse{}
+	err := suite.service.SetRefundFallbackDestination(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), refundErrorFallbackBankAccountMissing, rsp.Message)
+
+	rsp1 := &pkg.RefundFallbackResponse{}
+	err = suite.service.GetRefundFallback(context.TODO(), &pkg.GetRefundFallbackRequest{Token: fallback.Token}, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+	assert.Equal(suite.T(), pkg.RefundFallbackStatusAwaitingDetails, rsp1.Item.Status)
+	assert.Empty(suite.T(), rsp1.Item.Destination)
+}
+
+func (suite *RefundTestSuite) declineRefund(order *billingpb.Order) *intPkg.RefundFallback {
+	req := &billingpb.CreateRefundRequest{
+		OrderId:    order.Uuid,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		Reason:     "unit test decline",
+		MerchantId: suite.project.MerchantId,
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	refundReq := &billingpb.CardPayRefundCallback{
+		MerchantOrder: &billingpb.CardPayMerchantOrder{
+			Id: rsp.Item.Id,
+		},
+		PaymentMethod: order.PaymentMethod.Group,
+		PaymentData: &billingpb.CardPayRefundCallbackPaymentData{
+			Id:              rsp.Item.Id,
+			RemainingAmount: order.ChargeAmount,
+		},
+		RefundData: &billingpb.CardPayRefundCallbackRefundData{
+			Amount:   rsp.Item.Amount,
+			Created:  time.Now().Format(payment_system.CardPayDateFormat),
+			Id:       primitive.NewObjectID().Hex(),
+			Currency: rsp.Item.Currency,
+			Status:   billingpb.CardPayPaymentResponseStatusDeclined,
+			AuthCode: primitive.NewObjectID().Hex(),
+			Is_3D:    true,
+			Rrn:      primitive.NewObjectID().Hex(),
+		},
+		CallbackTime: time.Now().Format(payment_system.CardPayDateFormat),
+		Customer: &billingpb.CardPayCustomer{
+			Email: order.User.Email,
+			Id:    order.User.Email,
+		},
+	}
+
+	b, err := json.Marshal(refundReq)
+	assert.NoError(suite.T(), err)
+
+	hash := sha512.New()
+	hash.Write([]byte(string(b) + order.PaymentMethod.Params.SecretCallback))
+
+	req1 := &billingpb.CallbackRequest{
+		Handler:   billingpb.PaymentSystemHandlerCardPay,
+		Body:      b,
+		Signature: hex.EncodeToString(hash.Sum(nil)),
+	}
+	rsp1 := &billingpb.PaymentNotifyResponse{}
+	err = suite.service.ProcessRefundCallback(context.TODO(), req1, rsp1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+
+	fallback, err := suite.service.refundFallbackRepository.GetByRefundId(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), fallback)
+
+	return fallback
+}
+
+func (suite *RefundTestSuite) getRefundAccountingEntries(refund *billingpb.Refund) map[string]*billingpb.AccountingEntry {
+	aes, err := suite.service.accountingRepository.FindBySource(
+		context.TODO(),
+		refund.CreatedOrderId,
+		repository.CollectionRefund,
+	)
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), aes)
+
+	entries := make(map[string]*billingpb.AccountingEntry)
+
+	for _, ae := range aes {
+		entries[ae.Type] = ae
+	}
+
+	return entries
+}
+
+func (suite *RefundTestSuite) TestRefund_CreateItemsRefund_ConcurrentRefund_Rejected() {
+	order, productIds := suite.createKeyProductsOrder()
+
+	req := &pkg.CreateItemsRefundRequest{
+		OrderId:    order.Uuid,
+		MerchantId: suite.project.MerchantId,
+		CreatorId:  primitive.NewObjectID().Hex(),
+		Reason:     "unit test",
+		Items:      []*pkg.RefundItemRequest{{ItemId: productIds[0], Quantity: 1}},
+	}
+	rsp := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateItemsRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	// the second refund is checked against the refunds of the order read before the first one was created
+	suite.service.refundRepository = &staleRefundRepository{
+		RefundRepositoryInterface: suite.service.refundRepository,
+		isStaleSequence:           true,
+		isStaleAmount:             true,
+	}
+	suite.service.refundItemRepository = &staleRefundItemRepository{
+		RefundItemRepositoryInterface: suite.service.refundItemRepository,
+		isStaleItems:                  true,
+	}
+
+	rsp = &billingpb.CreateRefundResponse{}
+	err = suite.service.CreateItemsRefund(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), refundErrorItemsQuantity, rsp.Message)
+
+	items, err := suite.service.refundItemRepository.FindReservedByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), items, 1)
+
+	refundedAmount, err := suite.service.refundRepository.GetReservedAmountByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), float64(60), refundedAmount)
+}
+
+type staleRefundRepository struct {
+	repository.RefundRepositoryInterface
+	isStaleSequence bool
+	isStaleAmount   bool
+}
+
+func (r *staleRefundRepository) GetOrderSequence(ctx context.Context, orderId string) (int64, error) {
+	if r.isStaleSequence {
+		r.isStaleSequence = false
+		return 0, nil
+	}
+
+	return r.RefundRepositoryInterface.GetOrderSequence(ctx, orderId)
+}
+
+func (r *staleRefundRepository) GetReservedAmountByOrderId(ctx context.Context, orderId string) (float64, error) {
+	if r.isStaleAmount {
+		r.isStaleAmount = false
+		return 0, nil
+	}
+
+	return r.RefundRepositoryInterface.GetReservedAmountByOrderId(ctx, orderId)
+}
+
+type staleRefundItemRepository struct {
+	repository.RefundItemRepositoryInterface
+	isStaleItems bool
+}
+
+func (r *staleRefundItemRepository) FindReservedByOrderId(ctx context.Context, orderId string) ([]*intPkg.RefundItem, error) {
+	if r.isStaleItems {
+		r.isStaleItems = false
+		return nil, nil
+	}
+
+	return r.RefundItemRepositoryInterface.FindReservedByOrderId(ctx, orderId)
+}
@@ -154,7 +154,7 @@ func (s *Service) AutoAcceptRoyaltyReports(
 			return err
 		}
 
-		_, err = s.updateMerchantBalance(ctx, report.MerchantId)
+		_, err = s.updateMerchantBalance(ctx, report.MerchantId, report.Currency)
 		if err != nil {
 			return err
 		}
@@ -272,7 +272,7 @@ func (s *Service) MerchantReviewRoyaltyReport(
 	}
 
 	if req.IsAccepted {
-		_, err = s.updateMerchantBalance(ctx, report.MerchantId)
+		_, err = s.updateMerchantBalance(ctx, report.MerchantId, report.Currency)
 		if err != nil {
 			rsp.Status = billingpb.ResponseStatusSystemError
 			rsp.Message = royaltyReportUpdateBalanceError
@@ -476,7 +476,7 @@ func (s *Service) ChangeRoyaltyReport(
 
 	s.sendRoyaltyReportNotification(ctx, report)
 
-	_, err = s.updateMerchantBalance(ctx, report.MerchantId)
+	_, err = s.updateMerchantBalance(ctx, report.MerchantId, report.Currency)
 	if err != nil {
 		return err
 	}
@@ -592,6 +592,8 @@ func (h *royaltyHandler) getRoyaltyReportRollingReserves(
 	return
 }
 
+// createMerchantRoyaltyReport creates or updates royalty reports of the merchant for the period, one report
+// per currency in which the merchant has royalty. The report in the payout currency of the merchant is created always.
 func (h *royaltyHandler) createMerchantRoyaltyReport(ctx context.Context, merchantId primitive.ObjectID) error {
 	zap.L().Info("start generating royalty reports for merchant", zap.String("merchant_id", merchantId.Hex()))
 
@@ -600,12 +602,33 @@ func (h *royaltyHandler) createMerchantRoyaltyReport(ctx context.Context, mercha
 		return merchantErrorNotFound
 	}
 
-	existingReport := h.royaltyReportRepository.GetReportExists(ctx, merchant.Id, merchant.GetPayoutCurrency(), h.from, h.to)
+	currencies, err := h.orderViewRepository.GetRoyaltyCurrencies(ctx, merchant.Id, orderStatusForRoyaltyReports, h.from, h.to)
+	if err != nil {
+		return err
+	}
+
+	for _, currency := range getMerchantCurrencies(merchant.GetPayoutCurrency(), currencies) {
+		if err = h.createMerchantRoyaltyReportInCurrency(ctx, merchant, currency); err != nil {
+			return err
+		}
+	}
+
+	zap.L().Info("generating royalty reports for merchant finished", zap.String("merchant_id", merchantId.Hex()))
+
+	return nil
+}
+
+func (h *royaltyHandler) createMerchantRoyaltyReportInCurrency(
+	ctx context.Context,
+	merchant *billingpb.Merchant,
+	currency string,
+) error {
+	existingReport := h.royaltyReportRepository.GetReportExists(ctx, merchant.Id, currency, h.from, h.to)
 	if existingReport != nil && existingReport.Status != billingpb.RoyaltyReportStatusPending {
 		return royaltyReportErrorAlreadyExistsAndCannotBeUpdated
 	}
 
-	newReport, ordersIds, err := h.buildMerchantRoyaltyReportRoundedAmounts(ctx, merchant, true)
+	newReport, ordersIds, err := h.buildMerchantRoyaltyReportRoundedAmounts(ctx, merchant, currency, true)
 	if err != nil {
 		return err
 	}
@@ -661,23 +684,16 @@ func (h *royaltyHandler) createMerchantRoyaltyReport(ctx context.Context, mercha
 		}
 	}
 
-	err = h.Service.renderRoyaltyReport(ctx, newReport, merchant)
-	if err != nil {
-		return err
-	}
-
-	zap.L().Info("generating royalty reports for merchant finished", zap.String("merchant_id", merchantId.Hex()))
-
-	return nil
+	return h.Service.renderRoyaltyReport(ctx, newReport, merchant)
 }
 
 func (h *royaltyHandler) buildMerchantRoyaltyReport(
-	ctx context.Context, merchant *billingpb.Merchant, hasExistsReportId bool,
+	ctx context.Context, merchant *billingpb.Merchant, currency string, hasExistsReportId bool,
 ) (*billingpb.RoyaltyReport, []primitive.ObjectID, error) {
 	summaryItems, summaryTotal, ordersIds, err := h.orderViewRepository.GetRoyaltySummary(
 		ctx,
 		merchant.Id,
-		merchant.GetPayoutCurrency(),
+		currency,
 		h.from,
 		h.to,
 		hasExistsReportId,
@@ -687,12 +703,12 @@ func (h *royaltyHandler) buildMerchantRoyaltyReport(
 		return nil, nil, err
 	}
 
-	corrections, correctionsTotal, err := h.getRoyaltyReportCorrections(ctx, merchant.Id, merchant.GetPayoutCurrency())
+	corrections, correctionsTotal, err := h.getRoyaltyReportCorrections(ctx, merchant.Id, currency)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	reserves, reservesTotal, err := h.getRoyaltyReportRollingReserves(ctx, merchant.Id, merchant.GetPayoutCurrency())
+	reserves, reservesTotal, err := h.getRoyaltyReportRollingReserves(ctx, merchant.Id, currency)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -701,7 +717,7 @@ func (h *royaltyHandler) buildMerchantRoyaltyReport(
 		Id:                 primitive.NewObjectID().Hex(),
 		MerchantId:         merchant.Id,
 		OperatingCompanyId: merchant.OperatingCompanyId,
-		Currency:           merchant.GetPayoutCurrency(),
+		Currency:           currency,
 		Status:             billingpb.RoyaltyReportStatusPending,
 		CreatedAt:          ptypes.TimestampNow(),
 		UpdatedAt:          ptypes.TimestampNow(),
@@ -1213,13 +1229,12 @@ func (s *Service) markOrdersIncludedToRoyaltyReport(
 }
 
 func (h *royaltyHandler) buildMerchantRoyaltyReportRoundedAmounts(
-	ctx context.Context, merchant *billingpb.Merchant, hasExistsReportId bool,
+	ctx context.Context, merchant *billingpb.Merchant, currency string, hasExistsReportId bool,
 ) (*billingpb.RoyaltyReport, []primitive.ObjectID, error) {
-	merchantPayoutCurrency := merchant.GetPayoutCurrency()
 	summaryItems, summaryTotal, ordersIds, err := h.orderViewRepository.GetRoyaltySummaryRoundedAmounts(
 		ctx,
 		merchant.Id,
-		merchantPayoutCurrency,
+		currency,
 		h.from,
 		h.to,
 		hasExistsReportId,
@@ -1229,12 +1244,12 @@ func (h *royaltyHandler) buildMerchantRoyaltyReportRoundedAmounts(
 		return nil, nil, err
 	}
 
-	corrections, correctionsTotal, err := h.getRoyaltyReportCorrections(ctx, merchant.Id, merchantPayoutCurrency)
+	corrections, correctionsTotal, err := h.getRoyaltyReportCorrections(ctx, merchant.Id, currency)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	reserves, reservesTotal, err := h.getRoyaltyReportRollingReserves(ctx, merchant.Id, merchantPayoutCurrency)
+	reserves, reservesTotal, err := h.getRoyaltyReportRollingReserves(ctx, merchant.Id, currency)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1243,7 +1258,7 @@ func (h *royaltyHandler) buildMerchantRoyaltyReportRoundedAmounts(
 		Id:                 primitive.NewObjectID().Hex(),
 		MerchantId:         merchant.Id,
 		OperatingCompanyId: merchant.OperatingCompanyId,
-		Currency:           merchant.GetPayoutCurrency(),
+		Currency:           currency,
 		Status:             billingpb.RoyaltyReportStatusPending,
 		CreatedAt:          ptypes.TimestampNow(),
 		UpdatedAt:          ptypes.TimestampNow(),
@@ -0,0 +1,578 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/uuid"
+	"github.com/jinzhu/copier"
+	"github.com/paysuper/paysuper-billing-server/internal/payment_system"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/postmarkpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	stringTools "github.com/paysuper/paysuper-tools/string"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+const (
+	savedCardExpiringOrdersLimit = int64(100)
+)
+
+var (
+	savedCardUpdateErrorUnknown              = errors.NewBillingServerErrorMsg("cu000001", "saved card update can't be processed. try request later")
+	savedCardUpdateErrorNotFound             = errors.NewBillingServerErrorMsg("cu000002", "saved card update not found")
+	savedCardUpdateErrorExpired              = errors.NewBillingServerErrorMsg("cu000003", "saved card update link is expired")
+	savedCardUpdateErrorCompleted            = errors.NewBillingServerErrorMsg("cu000004", "saved card is already updated")
+	savedCardUpdateErrorSubscriptionNotFound = errors.NewBillingServerErrorMsg("cu000005", "active subscription of customer not found")
+	savedCardUpdateErrorVerification         = errors.NewBillingServerErrorMsg("cu000006", "verification of new card failed")
+)
+
+// GetSavedCardUpdate returns the card update by the token of the link sent to the customer.
+func (s *Service) GetSavedCardUpdate(
+	ctx context.Context,
+	req *pkg.GetSavedCardUpdateRequest,
+	rsp *pkg.SavedCardUpdateResponse,
+) error {
+	update, err := s.savedCardUpdateRepository.GetByToken(ctx, req.Token)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = savedCardUpdateErrorNotFound
+		return nil
+	}
+
+	if update.Status == pkg.SavedCardUpdateStatusPending && time.Now().After(update.ExpiresAt) {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = savedCardUpdateErrorExpired
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getSavedCardUpdateMessage(update)
+
+	return nil
+}
+
+// VerifySavedCardUpdate starts the zero-amount payment to verify the new card of the customer. The new card is filed
+// for regular payments of the subscription of the card update on the successful payment callback.
+func (s *Service) VerifySavedCardUpdate(
+	ctx context.Context,
+	req *pkg.VerifySavedCardUpdateRequest,
+	rsp *pkg.SavedCardUpdateResponse,
+) error {
+	update, err := s.savedCardUpdateRepository.GetByToken(ctx, req.Token)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = savedCardUpdateErrorNotFound
+		return nil
+	}
+
+	if update.Status != pkg.SavedCardUpdateStatusPending {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = savedCardUpdateErrorCompleted
+		return nil
+	}
+
+	if time.Now().After(update.ExpiresAt) {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = savedCardUpdateErrorExpired
+		return nil
+	}
+
+	validator := &bankCardValidator{
+		Pan:    req.Pan,
+		Cvv:    req.Cvv,
+		Month:  req.Month,
+		Year:   req.Year,
+		Holder: req.Holder,
+	}
+
+	if err = validator.Validate(); err != nil {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = err.(*billingpb.ResponseErrorMessage)
+		return nil
+	}
+
+	subscriptionRsp, err := s.rep.GetSubscription(ctx, &recurringpb.GetSubscriptionRequest{Id: update.SubscriptionId})
+
+	if err != nil || subscriptionRsp.Status != billingpb.ResponseStatusOk || !subscriptionRsp.Subscription.IsActive {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = savedCardUpdateErrorSubscriptionNotFound
+		return nil
+	}
+
+	periodOrder, h, err := s.getSubscriptionPaymentSystem(ctx, subscriptionRsp.Subscription)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = orderErrorPaymentSystemInactive
+		return nil
+	}
+
+	order, err := s.createSavedCardUpdateOrder(ctx, periodOrder, update, req)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = savedCardUpdateErrorUnknown
+		return nil
+	}
+
+	requisites := map[string]string{
+		billingpb.PaymentCreateFieldPan:       req.Pan,
+		billingpb.PaymentCreateFieldCvv:       req.Cvv,
+		billingpb.PaymentCreateFieldMonth:     order.PaymentRequisites[billingpb.PaymentCreateFieldMonth],
+		billingpb.PaymentCreateFieldYear:      order.PaymentRequisites[billingpb.PaymentCreateFieldYear],
+		billingpb.PaymentCreateFieldHolder:    req.Holder,
+		billingpb.PaymentCreateFieldStoreData: "1",
+	}
+	url, err := h.CreatePayment(order, s.cfg.GetRedirectUrlSuccess(nil), s.cfg.GetRedirectUrlFail(nil), requisites)
+
+	if updErr := s.updateOrder(ctx, order); updErr != nil {
+		zap.L().Error(
+			"order update after saved card verification failed",
+			zap.Error(updErr),
+			zap.String("order_id", order.Id),
+		)
+	}
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = savedCardUpdateErrorVerification
+		return nil
+	}
+
+	update.OrderId = order.Id
+
+	if err = s.savedCardUpdateRepository.Update(ctx, update); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = savedCardUpdateErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getSavedCardUpdateMessage(update)
+	rsp.RedirectUrl = url
+
+	return nil
+}
+
+// NotifyExpiringSavedCards sends links to replace the saved cards which expire next month to customers with active
+// recurring subscriptions. The link is sent once for every saved card. Customers are found by orders paid with
+// the saved card expiring next month, the orders are read by pages.
+func (s *Service) NotifyExpiringSavedCards(ctx context.Context) error {
+	now := time.Now().UTC()
+	nextMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	month := int(nextMonth.Month())
+	year := strconv.Itoa(nextMonth.Year())
+
+	filter := bson.M{
+		"payment_requisites.year":  bson.M{"$in": []string{year, year[2:]}},
+		"payment_requisites.month": bson.M{"$in": []string{strconv.Itoa(month), fmt.Sprintf("%02d", month)}},
+		"payment_requisites.saved": "1",
+	}
+	opts := options.Find().SetSort(bson.M{"_id": 1}).SetLimit(savedCardExpiringOrdersLimit)
+	notified := make(map[string]bool)
+
+	for {
+		orders, err := s.orderRepository.GetManyBy(ctx, filter, opts)
+
+		if err != nil {
+			return err
+		}
+
+		for _, order := range orders {
+			if order.User == nil || order.User.Id == "" || notified[order.User.Id] {
+				continue
+			}
+
+			notified[order.User.Id] = true
+			s.notifyCustomerExpiringSavedCards(ctx, order.User.Id, nextMonth)
+		}
+
+		if int64(len(orders)) < savedCardExpiringOrdersLimit {
+			break
+		}
+
+		lastId, _ := primitive.ObjectIDFromHex(orders[len(orders)-1].Id)
+		filter["_id"] = bson.M{"$gt": lastId}
+	}
+
+	return nil
+}
+
+// notifyCustomerExpiringSavedCards creates card updates of the saved cards of the customer which expire in the month
+// of the date.
+func (s *Service) notifyCustomerExpiringSavedCards(ctx context.Context, customerId string, expireDate time.Time) {
+	customer, err := s.customerRepository.GetById(ctx, customerId)
+
+	if err != nil {
+		return
+	}
+
+	cards, err := s.rep.FindSavedCards(ctx, &recurringpb.SavedCardRequest{Token: customer.Id})
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorGrpcServiceCallFailed,
+			zap.Error(err),
+			zap.String(errorFieldService, recurringpb.PayOneRepositoryServiceName),
+			zap.String(errorFieldMethod, "FindSavedCards"),
+			zap.String("customer_id", customer.Id),
+		)
+		return
+	}
+
+	for _, card := range cards.SavedCards {
+		if !card.IsActive || card.RecurringId == "" || !isSavedCardExpiring(card.Expire, expireDate) {
+			continue
+		}
+
+		if _, err = s.savedCardUpdateRepository.GetBySavedCardId(ctx, card.Id); err == nil {
+			continue
+		}
+
+		if err = s.addSavedCardUpdate(ctx, customer, card, expireDate); err != nil {
+			zap.L().Error(
+				"saved card update creation failed",
+				zap.Error(err),
+				zap.String("customer_id", customer.Id),
+				zap.String("saved_card_id", card.Id),
+			)
+		}
+	}
+}
+
+// addSavedCardUpdate creates the card update of the expiring card and sends the link to the customer. Cards of
+// customers without active subscriptions aren't used for regular payments, so they're skipped.
+func (s *Service) addSavedCardUpdate(
+	ctx context.Context,
+	customer *billingpb.Customer,
+	card *recurringpb.SavedCard,
+	expireDate time.Time,
+) error {
+	subscriptions, err := s.rep.FindSubscriptions(ctx, &recurringpb.FindSubscriptionsRequest{CustomerId: customer.Id})
+
+	if err != nil {
+		return err
+	}
+
+	var subscription *recurringpb.Subscription
+
+	for _, v := range subscriptions.List {
+		if v.IsActive {
+			subscription = v
+			break
+		}
+	}
+
+	if subscription == nil {
+		return nil
+	}
+
+	update := &intPkg.SavedCardUpdate{
+		Token:          s.getTokenString(s.cfg.GetCustomerTokenLength()),
+		SavedCardId:    card.Id,
+		CustomerId:     customer.Id,
+		SubscriptionId: subscription.Id,
+		CustomerEmail:  customer.Email,
+		MaskedPan:      card.MaskedPan,
+		ExpireMonth:    card.Expire.Month,
+		ExpireYear:     card.Expire.Year,
+		Status:         pkg.SavedCardUpdateStatusPending,
+		ExpiresAt:      expireDate.AddDate(0, 1, 0),
+	}
+	update.MerchantId, _ = primitive.ObjectIDFromHex(subscription.MerchantId)
+	update.ProjectId, _ = primitive.ObjectIDFromHex(subscription.ProjectId)
+
+	if update.CustomerEmail == "" && subscription.CustomerInfo != nil {
+		update.CustomerEmail = subscription.CustomerInfo.Email
+	}
+
+	if update.CustomerEmail == "" {
+		return nil
+	}
+
+	if err = s.savedCardUpdateRepository.Insert(ctx, update); err != nil {
+		return err
+	}
+
+	project, err := s.project.GetById(ctx, subscription.ProjectId)
+
+	if err != nil {
+		return err
+	}
+
+	s.sendSavedCardExpiringEmail(project, update)
+
+	return nil
+}
+
+// createSavedCardUpdateOrder creates the zero-amount order to verify the new card. The order is copied from
+// the subscription order to charge it by the same project and payment method settings, but it sells nothing, so
+// items, products and keys of the subscription order aren't copied.
+func (s *Service) createSavedCardUpdateOrder(
+	ctx context.Context,
+	periodOrder *billingpb.Order,
+	update *intPkg.SavedCardUpdate,
+	req *pkg.VerifySavedCardUpdateRequest,
+) (*billingpb.Order, error) {
+	order := new(billingpb.Order)
+
+	if err := copier.Copy(&order, &periodOrder); err != nil {
+		return nil, err
+	}
+
+	year := req.Year
+
+	if len(year) < 3 {
+		year = strconv.Itoa(time.Now().UTC().Year())[:2] + year
+	}
+
+	order.Id = primitive.NewObjectID().Hex()
+	order.Uuid = uuid.New().String()
+	order.ReceiptId = uuid.New().String()
+	order.CreatedAt = ptypes.TimestampNow()
+	order.UpdatedAt = ptypes.TimestampNow()
+	order.Canceled = false
+	order.CanceledAt = nil
+	order.ReceiptUrl = ""
+	order.RoyaltyReportId = ""
+	order.RecurringId = ""
+	order.RecurringSettings = nil
+	order.PrivateStatus = recurringpb.OrderStatusNew
+	order.ParentOrder = &billingpb.ParentOrder{
+		Id:   periodOrder.Id,
+		Uuid: periodOrder.Uuid,
+	}
+	order.OrderAmount = 0
+	order.TotalPaymentAmount = 0
+	order.ChargeAmount = 0
+	order.Tax = nil
+	order.ProductType = pkg.OrderType_simple
+	order.Items = nil
+	order.Products = nil
+	order.Keys = nil
+	order.IsKeyProductNotified = false
+	order.PaymentRequisites = map[string]string{
+		billingpb.PaymentCreateFieldPan:    stringTools.MaskBankCardNumber(req.Pan),
+		billingpb.PaymentCreateFieldMonth:  req.Month,
+		billingpb.PaymentCreateFieldYear:   year,
+		billingpb.PaymentCreateFieldHolder: req.Holder,
+	}
+	order.PrivateMetadata = map[string]string{pkg.OrderPrivateMetadataSavedCardUpdate: update.Id.Hex()}
+
+	if req.Ip != "" && order.User != nil {
+		order.User.Ip = req.Ip
+	}
+
+	if err := s.orderRepository.Insert(ctx, order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// processSavedCardUpdateCallback completes the card update by the callback of the verification payment.
+// The verification doesn't charge the customer, so the order isn't processed as the payment.
+func (s *Service) processSavedCardUpdateCallback(
+	ctx context.Context,
+	order *billingpb.Order,
+	h payment_system.PaymentSystemInterface,
+	data proto.Message,
+	pErr error,
+	rsp *billingpb.PaymentNotifyResponse,
+) error {
+	if err := s.updateOrder(ctx, order); err != nil {
+		zap.S().Errorw(pkg.MethodFinishedWithError, "err", err.Error())
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Status = pkg.StatusErrorSystem
+			rsp.Error = e.Message
+			return nil
+		}
+		return err
+	}
+
+	if pErr != nil {
+		return nil
+	}
+
+	if order.PrivateStatus == recurringpb.OrderStatusPaymentSystemComplete && h.CanSaveCard(data) {
+		recurringId := h.GetRecurringId(data)
+		s.saveRecurringCard(ctx, order, recurringId)
+
+		if err := s.completeSavedCardUpdate(ctx, order, recurringId); err != nil {
+			zap.L().Error(
+				"saved card update completion failed",
+				zap.Error(err),
+				zap.String("order_id", order.Id),
+			)
+		}
+	}
+
+	rsp.Status = pkg.StatusOK
+
+	return nil
+}
+
+// completeSavedCardUpdate files the new card for regular payments of the subscription of the card update and deletes
+// the expiring card. The expiring card is kept while any other active subscription of the customer is charged from it.
+func (s *Service) completeSavedCardUpdate(ctx context.Context, order *billingpb.Order, recurringId string) error {
+	update, err := s.savedCardUpdateRepository.GetById(ctx, order.PrivateMetadata[pkg.OrderPrivateMetadataSavedCardUpdate])
+
+	if err != nil {
+		return err
+	}
+
+	if update.Status != pkg.SavedCardUpdateStatusPending {
+		return nil
+	}
+
+	subscriptionRsp, err := s.rep.GetSubscription(ctx, &recurringpb.GetSubscriptionRequest{Id: update.SubscriptionId})
+
+	if err == nil && subscriptionRsp.Status != billingpb.ResponseStatusOk {
+		err = savedCardUpdateErrorSubscriptionNotFound
+	}
+
+	var (
+		subscriptionOrder *billingpb.Order
+		h                 payment_system.PaymentSystemInterface
+	)
+
+	if err == nil {
+		subscriptionOrder, h, err = s.getSubscriptionPaymentSystem(ctx, subscriptionRsp.Subscription)
+	}
+
+	if err == nil {
+		err = h.UpdateRecurringSubscriptionCard(subscriptionOrder, subscriptionRsp.Subscription, recurringId)
+	}
+
+	update.Status = pkg.SavedCardUpdateStatusCompleted
+	update.FailedSubscriptionIds = []string{}
+
+	if err != nil {
+		zap.L().Error(
+			"card replacement of recurring subscription failed",
+			zap.Error(err),
+			zap.String("subscription_id", update.SubscriptionId),
+		)
+
+		update.Status = pkg.SavedCardUpdateStatusFailed
+		update.FailedSubscriptionIds = append(update.FailedSubscriptionIds, update.SubscriptionId)
+	}
+
+	if update.Status == pkg.SavedCardUpdateStatusCompleted && !s.isSavedCardChargedBySubscriptions(ctx, update) {
+		req := &recurringpb.DeleteSavedCardRequest{Id: update.SavedCardId, Token: update.CustomerId}
+		deleteRsp, err := s.rep.DeleteSavedCard(ctx, req)
+
+		if err != nil || deleteRsp.Status != billingpb.ResponseStatusOk {
+			zap.L().Error(
+				pkg.ErrorGrpcServiceCallFailed,
+				zap.Error(err),
+				zap.String(errorFieldService, recurringpb.PayOneRepositoryServiceName),
+				zap.String(errorFieldMethod, "DeleteSavedCard"),
+				zap.Any(errorFieldRequest, req),
+			)
+		}
+	}
+
+	update.OrderId = order.Id
+	update.RecurringId = recurringId
+	update.CompletedAt = time.Now()
+
+	return s.savedCardUpdateRepository.Update(ctx, update)
+}
+
+// isSavedCardChargedBySubscriptions checks the expiring card of the card update is used by any other active
+// subscription of the customer. The card is considered used if subscriptions can't be checked.
+func (s *Service) isSavedCardChargedBySubscriptions(ctx context.Context, update *intPkg.SavedCardUpdate) bool {
+	req := &recurringpb.FindSubscriptionsRequest{CustomerId: update.CustomerId}
+	subscriptions, err := s.rep.FindSubscriptions(ctx, req)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.ErrorGrpcServiceCallFailed,
+			zap.Error(err),
+			zap.String(errorFieldService, recurringpb.PayOneRepositoryServiceName),
+			zap.String(errorFieldMethod, "FindSubscriptions"),
+			zap.Any(errorFieldRequest, req),
+		)
+		return true
+	}
+
+	for _, subscription := range subscriptions.List {
+		if subscription.IsActive && subscription.Id != update.SubscriptionId && subscription.MaskedPan == update.MaskedPan {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Service) sendSavedCardExpiringEmail(project *billingpb.Project, update *intPkg.SavedCardUpdate) {
+	payload := &postmarkpb.Payload{
+		TemplateAlias: s.cfg.EmailTemplates.SavedCardExpiring,
+		TemplateModel: map[string]string{
+			"project_name":    project.Name[DefaultLanguage],
+			"masked_pan":      update.MaskedPan,
+			"expire_month":    update.ExpireMonth,
+			"expire_year":     update.ExpireYear,
+			"update_card_url": s.cfg.GetSavedCardUpdateUrl(update.Token),
+			"current_year":    time.Now().UTC().Format("2006"),
+		},
+		To: update.CustomerEmail,
+	}
+	err := s.postmarkBroker.Publish(postmarkpb.PostmarkSenderTopicName, payload, amqp.Table{})
+
+	if err != nil {
+		zap.L().Error(
+			"Publication message about expiring saved card to queue failed",
+			zap.Error(err),
+			zap.String("saved_card_update_id", update.Id.Hex()),
+			zap.String("topic", postmarkpb.PostmarkSenderTopicName),
+		)
+	}
+}
+
+// isSavedCardExpiring checks the card expires in the month of the date.
+func isSavedCardExpiring(expire *recurringpb.CardExpire, date time.Time) bool {
+	if expire == nil {
+		return false
+	}
+
+	year := expire.Year
+
+	if len(year) < 3 {
+		year = strconv.Itoa(date.Year())[:2] + year
+	}
+
+	month, err := strconv.Atoi(expire.Month)
+
+	if err != nil {
+		return false
+	}
+
+	return year == strconv.Itoa(date.Year()) && time.Month(month) == date.Month()
+}
+
+func getSavedCardUpdateMessage(update *intPkg.SavedCardUpdate) *pkg.SavedCardUpdate {
+	return &pkg.SavedCardUpdate{
+		Id:          update.Id.Hex(),
+		MaskedPan:   update.MaskedPan,
+		ExpireMonth: update.ExpireMonth,
+		ExpireYear:  update.ExpireYear,
+		Status:      update.Status,
+		ExpiresAt:   getTimestampProto(update.ExpiresAt),
+		CompletedAt: getTimestampProto(update.CompletedAt),
+	}
+}
@@ -0,0 +1,467 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	recurringMocks "github.com/paysuper/paysuper-proto/go/recurringpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type SavedCardUpdateTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+	customer      *billingpb.Customer
+	cookie        string
+}
+
+func Test_SavedCardUpdate(t *testing.T) {
+	suite.Run(t, new(SavedCardUpdateTestSuite))
+}
+
+func (suite *SavedCardUpdateTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	suite.merchant, suite.project, suite.paymentMethod, _, suite.customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.cookie, err = suite.service.generateBrowserCookie(&BrowserCookieCustomer{
+		CustomerId: suite.customer.Id,
+		Ip:         "127.0.0.1",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	})
+
+	if err != nil {
+		suite.FailNow("Generate browser cookie failed", "%v", err)
+	}
+}
+
+func (suite *SavedCardUpdateTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *SavedCardUpdateTestSuite) createRecurringOrder(metadata map[string]string) *billingpb.OrderCreateProcessResponse {
+	paymentMethod, _ := suite.service.paymentMethodRepository.GetById(context.TODO(), suite.paymentMethod.Id)
+	paymentMethod.RecurringAllowed = true
+	_ = suite.service.paymentMethodRepository.Update(context.TODO(), paymentMethod)
+
+	req := &billingpb.OrderCreateRequest{
+		Type:          pkg.OrderType_simple,
+		ProjectId:     suite.project.Id,
+		PaymentMethod: paymentMethod.Group,
+		Currency:      "RUB",
+		Amount:        100,
+		Account:       "unit test",
+		Description:   "unit test",
+		User: &billingpb.OrderUser{
+			Email: "test@unit.unit",
+			Ip:    "127.0.0.1",
+		},
+		FormMode:        "standalone",
+		RecurringPeriod: recurringpb.RecurringPeriodMonth,
+		PrivateMetadata: metadata,
+	}
+
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *SavedCardUpdateTestSuite) createSubscription() (*recurringpb.Subscription, *recurringMocks.RepositoryService) {
+	rsp := suite.createRecurringOrder(nil)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	subscription := &recurringpb.Subscription{
+		Id:           primitive.NewObjectID().Hex(),
+		OrderId:      rsp.Item.Id,
+		CustomerId:   suite.customer.Id,
+		MerchantId:   suite.merchant.Id,
+		ProjectId:    suite.project.Id,
+		IsActive:     true,
+		Period:       recurringpb.RecurringPeriodMonth,
+		Amount:       31,
+		Currency:     "RUB",
+		CustomerInfo: &recurringpb.CustomerInfo{Email: "test@unit.unit"},
+	}
+
+	order := rsp.Item
+	order.RecurringId = subscription.Id
+	order.PaymentMethod = &billingpb.PaymentMethodOrder{
+		Id:      suite.paymentMethod.Id,
+		Name:    suite.paymentMethod.Name,
+		Handler: suite.paymentMethod.Handler,
+	}
+	err := suite.service.orderRepository.Update(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	recurring := &recurringMocks.RepositoryService{}
+	recurring.On("GetSubscription", mock.Anything, mock.Anything).
+		Return(&recurringpb.GetSubscriptionResponse{Status: billingpb.ResponseStatusOk, Subscription: subscription}, nil)
+	recurring.On("FindSubscriptions", mock.Anything, mock.Anything).
+		Return(&recurringpb.FindSubscriptionsResponse{List: []*recurringpb.Subscription{subscription}}, nil)
+	suite.service.rep = recurring
+
+	return subscription, recurring
+}
+
+func (suite *SavedCardUpdateTestSuite) createSavedCardUpdate(subscription *recurringpb.Subscription) *intPkg.SavedCardUpdate {
+	update := &intPkg.SavedCardUpdate{
+		Token:          primitive.NewObjectID().Hex(),
+		SavedCardId:    primitive.NewObjectID().Hex(),
+		CustomerId:     subscription.CustomerId,
+		SubscriptionId: subscription.Id,
+		CustomerEmail:  "test@unit.unit",
+		MaskedPan:      "400000******0002",
+		Status:         pkg.SavedCardUpdateStatusPending,
+		ExpiresAt:      time.Now().AddDate(0, 1, 0),
+	}
+	err := suite.service.savedCardUpdateRepository.Insert(context.TODO(), update)
+	assert.NoError(suite.T(), err)
+
+	return update
+}
+
+func (suite *SavedCardUpdateTestSuite) TestSavedCardUpdate_IsSavedCardExpiring_Ok() {
+	date := time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.True(suite.T(), isSavedCardExpiring(&recurringpb.CardExpire{Month: "12", Year: "2020"}, date))
+	assert.True(suite.T(), isSavedCardExpiring(&recurringpb.CardExpire{Month: "12", Year: "20"}, date))
+	assert.False(suite.T(), isSavedCardExpiring(&recurringpb.CardExpire{Month: "11", Year: "2020"}, date))
+	assert.False(suite.T(), isSavedCardExpiring(&recurringpb.CardExpire{Month: "12", Year: "2021"}, date))
+	assert.False(suite.T(), isSavedCardExpiring(nil, date))
+}
+
+func (suite *SavedCardUpdateTestSuite) TestSavedCardUpdate_NotifyExpiringSavedCards_Ok() {
+	subscription, recurring := suite.createSubscription()
+
+	now := time.Now().UTC()
+	nextMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+
+	order, err := suite.service.getOrderById(context.TODO(), subscription.OrderId)
+	assert.NoError(suite.T(), err)
+	order.User.Id = suite.customer.Id
+	order.PaymentRequisites = map[string]string{
+		billingpb.PaymentCreateFieldMonth: fmt.Sprintf("%02d", nextMonth.Month()),
+		billingpb.PaymentCreateFieldYear:  strconv.Itoa(nextMonth.Year()),
+		"saved":                           "1",
+	}
+	err = suite.service.orderRepository.Update(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	card := &recurringpb.SavedCard{
+		Id:          primitive.NewObjectID().Hex(),
+		Token:       suite.customer.Id,
+		MaskedPan:   "400000******0002",
+		RecurringId: "recurring_id",
+		Expire:      &recurringpb.CardExpire{Month: strconv.Itoa(int(nextMonth.Month())), Year: strconv.Itoa(nextMonth.Year())},
+		IsActive:    true,
+	}
+	validCard := &recurringpb.SavedCard{
+		Id:          primitive.NewObjectID().Hex(),
+		Token:       suite.customer.Id,
+		MaskedPan:   "555555******4444",
+		RecurringId: "recurring_id2",
+		Expire:      &recurringpb.CardExpire{Month: "12", Year: strconv.Itoa(nextMonth.Year() + 2)},
+		IsActive:    true,
+	}
+	recurring.On("FindSavedCards", mock.Anything, mock.Anything).
+		Return(&recurringpb.SavedCardList{SavedCards: []*recurringpb.SavedCard{card, validCard}}, nil)
+
+	err = suite.service.NotifyExpiringSavedCards(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	update, err := suite.service.savedCardUpdateRepository.GetBySavedCardId(context.TODO(), card.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SavedCardUpdateStatusPending, update.Status)
+	assert.NotEmpty(suite.T(), update.Token)
+	assert.NotEmpty(suite.T(), update.CustomerEmail)
+
+	_, err = suite.service.savedCardUpdateRepository.GetBySavedCardId(context.TODO(), validCard.Id)
+	assert.Error(suite.T(), err)
+
+	err = suite.service.NotifyExpiringSavedCards(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	repeated, err := suite.service.savedCardUpdateRepository.GetBySavedCardId(context.TODO(), card.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), update.Token, repeated.Token)
+}
+
+func (suite *SavedCardUpdateTestSuite) TestSavedCardUpdate_VerifySavedCardUpdate_Ok() {
+	subscription, _ := suite.createSubscription()
+	update := suite.createSavedCardUpdate(subscription)
+
+	paymentSystem := &mocks.PaymentSystemInterface{}
+	paymentSystem.On("CreatePayment", mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(requisites map[string]string) bool {
+		return requisites[billingpb.PaymentCreateFieldStoreData] == "1"
+	})).Return("http://localhost/3ds", nil)
+	gatewayManagerMock := &mocks.PaymentSystemManagerInterface{}
+	gatewayManagerMock.On("GetGateway", mock.Anything).Return(paymentSystem, nil)
+	suite.service.paymentSystemGateway = gatewayManagerMock
+
+	req := &pkg.VerifySavedCardUpdateRequest{
+		Token:  update.Token,
+		Pan:    "4000000000000002",
+		Cvv:    "123",
+		Month:  "12",
+		Year:   strconv.Itoa(time.Now().Year() + 2),
+		Holder: "unit test",
+	}
+	rsp := &pkg.SavedCardUpdateResponse{}
+	err := suite.service.VerifySavedCardUpdate(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Equal(suite.T(), "http://localhost/3ds", rsp.RedirectUrl)
+
+	update, err = suite.service.savedCardUpdateRepository.GetById(context.TODO(), update.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), update.OrderId)
+
+	order, err := suite.service.getOrderById(context.TODO(), update.OrderId)
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), order.ChargeAmount)
+	assert.Empty(suite.T(), order.RecurringId)
+	assert.Equal(suite.T(), subscription.OrderId, order.ParentOrder.Id)
+	assert.Equal(suite.T(), update.Id.Hex(), order.PrivateMetadata[pkg.OrderPrivateMetadataSavedCardUpdate])
+	assert.Equal(suite.T(), pkg.OrderType_simple, order.ProductType)
+	assert.Empty(suite.T(), order.Items)
+	assert.Empty(suite.T(), order.Products)
+	assert.Empty(suite.T(), order.Keys)
+}
+
+func (suite *SavedCardUpdateTestSuite) TestSavedCardUpdate_VerifySavedCardUpdate_Error() {
+	subscription, _ := suite.createSubscription()
+	update := suite.createSavedCardUpdate(subscription)
+
+	req := &pkg.VerifySavedCardUpdateRequest{
+		Token:  update.Token,
+		Pan:    "4000000000000002",
+		Cvv:    "123",
+		Month:  "12",
+		Year:   "2019",
+		Holder: "unit test",
+	}
+	rsp := &pkg.SavedCardUpdateResponse{}
+	err := suite.service.VerifySavedCardUpdate(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), bankCardIsExpired, rsp.Message)
+
+	update.ExpiresAt = time.Now().Add(-time.Minute)
+	err = suite.service.savedCardUpdateRepository.Update(context.TODO(), update)
+	assert.NoError(suite.T(), err)
+
+	rsp = &pkg.SavedCardUpdateResponse{}
+	err = suite.service.VerifySavedCardUpdate(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), savedCardUpdateErrorExpired, rsp.Message)
+
+	req.Token = "unknown"
+	rsp = &pkg.SavedCardUpdateResponse{}
+	err = suite.service.VerifySavedCardUpdate(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), savedCardUpdateErrorNotFound, rsp.Message)
+}
+
+func (suite *SavedCardUpdateTestSuite) TestSavedCardUpdate_CompleteSavedCardUpdate_Ok() {
+	subscription, recurring := suite.createSubscription()
+	update := suite.createSavedCardUpdate(subscription)
+
+	recurring.On("DeleteSavedCard", mock.Anything, mock.MatchedBy(func(req *recurringpb.DeleteSavedCardRequest) bool {
+		return req.Id == update.SavedCardId && req.Token == update.CustomerId
+	})).Return(&recurringpb.DeleteSavedCardResponse{Status: billingpb.ResponseStatusOk}, nil)
+
+	paymentSystem := &mocks.PaymentSystemInterface{}
+	paymentSystem.On("UpdateRecurringSubscriptionCard", mock.Anything, mock.Anything, "new_recurring_id").Return(nil)
+	gatewayManagerMock := &mocks.PaymentSystemManagerInterface{}
+	gatewayManagerMock.On("GetGateway", mock.Anything).Return(paymentSystem, nil)
+	suite.service.paymentSystemGateway = gatewayManagerMock
+
+	order := &billingpb.Order{
+		Id:              primitive.NewObjectID().Hex(),
+		PrivateMetadata: map[string]string{pkg.OrderPrivateMetadataSavedCardUpdate: update.Id.Hex()},
+	}
+	err := suite.service.completeSavedCardUpdate(context.TODO(), order, "new_recurring_id")
+	assert.NoError(suite.T(), err)
+	paymentSystem.AssertCalled(suite.T(), "UpdateRecurringSubscriptionCard", mock.Anything, mock.Anything, "new_recurring_id")
+	recurring.AssertCalled(suite.T(), "DeleteSavedCard", mock.Anything, mock.Anything)
+
+	update, err = suite.service.savedCardUpdateRepository.GetById(context.TODO(), update.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SavedCardUpdateStatusCompleted, update.Status)
+	assert.Equal(suite.T(), "new_recurring_id", update.RecurringId)
+	assert.Equal(suite.T(), order.Id, update.OrderId)
+
+	rsp := &pkg.SavedCardUpdateResponse{}
+	err = suite.service.GetSavedCardUpdate(context.TODO(), &pkg.GetSavedCardUpdateRequest{Token: update.Token}, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), pkg.SavedCardUpdateStatusCompleted, rsp.Item.Status)
+}
+
+func (suite *SavedCardUpdateTestSuite) TestSavedCardUpdate_CompleteSavedCardUpdate_OnlyTargetSubscriptionUpdated() {
+	subscription, _ := suite.createSubscription()
+	update := suite.createSavedCardUpdate(subscription)
+
+	// another subscription of the customer is charged from the expiring card too
+	other := &recurringpb.Subscription{
+		Id:         primitive.NewObjectID().Hex(),
+		OrderId:    subscription.OrderId,
+		CustomerId: subscription.CustomerId,
+		MerchantId: subscription.MerchantId,
+		ProjectId:  subscription.ProjectId,
+		IsActive:   true,
+		MaskedPan:  update.MaskedPan,
+	}
+
+	recurring := &recurringMocks.RepositoryService{}
+	recurring.On("GetSubscription", mock.Anything, mock.Anything).
+		Return(&recurringpb.GetSubscriptionResponse{Status: billingpb.ResponseStatusOk, Subscription: subscription}, nil)
+	recurring.On("FindSubscriptions", mock.Anything, mock.Anything).
+		Return(&recurringpb.FindSubscriptionsResponse{List: []*recurringpb.Subscription{other, subscription}}, nil)
+	recurring.On("DeleteSavedCard", mock.Anything, mock.Anything).
+		Return(&recurringpb.DeleteSavedCardResponse{Status: billingpb.ResponseStatusOk}, nil)
+	suite.service.rep = recurring
+
+	paymentSystem := &mocks.PaymentSystemInterface{}
+	paymentSystem.On("UpdateRecurringSubscriptionCard", mock.Anything, mock.Anything, "new_recurring_id").Return(nil)
+	gatewayManagerMock := &mocks.PaymentSystemManagerInterface{}
+	gatewayManagerMock.On("GetGateway", mock.Anything).Return(paymentSystem, nil)
+	suite.service.paymentSystemGateway = gatewayManagerMock
+
+	order := &billingpb.Order{
+		Id:              primitive.NewObjectID().Hex(),
+		PrivateMetadata: map[string]string{pkg.OrderPrivateMetadataSavedCardUpdate: update.Id.Hex()},
+	}
+	err := suite.service.completeSavedCardUpdate(context.TODO(), order, "new_recurring_id")
+	assert.NoError(suite.T(), err)
+	paymentSystem.AssertNumberOfCalls(suite.T(), "UpdateRecurringSubscriptionCard", 1)
+	paymentSystem.AssertCalled(suite.T(), "UpdateRecurringSubscriptionCard", mock.Anything, subscription, "new_recurring_id")
+	recurring.AssertNotCalled(suite.T(), "DeleteSavedCard", mock.Anything, mock.Anything)
+
+	update, err = suite.service.savedCardUpdateRepository.GetById(context.TODO(), update.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SavedCardUpdateStatusCompleted, update.Status)
+}
+
+func (suite *SavedCardUpdateTestSuite) TestSavedCardUpdate_CompleteSavedCardUpdate_PaymentSystemError() {
+	subscription, recurring := suite.createSubscription()
+	update := suite.createSavedCardUpdate(subscription)
+
+	recurring.On("DeleteSavedCard", mock.Anything, mock.Anything).
+		Return(&recurringpb.DeleteSavedCardResponse{Status: billingpb.ResponseStatusOk}, nil)
+
+	paymentSystem := &mocks.PaymentSystemInterface{}
+	paymentSystem.On("UpdateRecurringSubscriptionCard", mock.Anything, mock.Anything, "new_recurring_id").
+		Return(errors.New("payment system error"))
+	gatewayManagerMock := &mocks.PaymentSystemManagerInterface{}
+	gatewayManagerMock.On("GetGateway", mock.Anything).Return(paymentSystem, nil)
+	suite.service.paymentSystemGateway = gatewayManagerMock
+
+	order := &billingpb.Order{
+		Id:              primitive.NewObjectID().Hex(),
+		PrivateMetadata: map[string]string{pkg.OrderPrivateMetadataSavedCardUpdate: update.Id.Hex()},
+	}
+	err := suite.service.completeSavedCardUpdate(context.TODO(), order, "new_recurring_id")
+	assert.NoError(suite.T(), err)
+	recurring.AssertNotCalled(suite.T(), "DeleteSavedCard", mock.Anything, mock.Anything)
+
+	update, err = suite.service.savedCardUpdateRepository.GetById(context.TODO(), update.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SavedCardUpdateStatusFailed, update.Status)
+	assert.Equal(suite.T(), []string{subscription.Id}, update.FailedSubscriptionIds)
+}
@@ -113,6 +113,34 @@ type Service struct {
 	dashboardRepository                    repository.DashboardRepositoryInterface
 	validateUserBroker                     rabbitmq.BrokerInterface
 	autoincrementRepository                repository.AutoincrementRepositoryInterface
+	paymentMethodRouteRepository           repository.PaymentMethodRouteRepositoryInterface
+	orderHistoryRepository                 repository.OrderHistoryRepositoryInterface
+	idempotencyKeyRepository               repository.IdempotencyKeyRepositoryInterface
+	disputeRepository                      repository.DisputeRepositoryInterface
+	orderReviewRepository                  repository.OrderReviewRepositoryInterface
+	paymentMethodRuleRepository            repository.PaymentMethodRuleRepositoryInterface
+	promoCodeRepository                    repository.PromoCodeRepositoryInterface
+	subscriptionTrialRepository            repository.SubscriptionTrialRepositoryInterface
+	subscriptionDunningRepository          repository.SubscriptionDunningRepositoryInterface
+	dunningScheduleRepository              repository.DunningScheduleRepositoryInterface
+	subscriptionPlanChangeRepository       repository.SubscriptionPlanChangeRepositoryInterface
+	subscriptionPauseRepository            repository.SubscriptionPauseRepositoryInterface
+	savedCardUpdateRepository              repository.SavedCardUpdateRepositoryInterface
+	refundItemRepository                   repository.RefundItemRepositoryInterface
+	refundApprovalSettingsRepository       repository.RefundApprovalSettingsRepositoryInterface
+	refundApprovalRepository               repository.RefundApprovalRepositoryInterface
+	refundFallbackRepository               repository.RefundFallbackRepositoryInterface
+	storeCreditRepository                  repository.StoreCreditRepositoryInterface
+	customerWalletRepository               repository.CustomerWalletRepositoryInterface
+	giftCardRepository                     repository.GiftCardRepositoryInterface
+	journalEntryRepository                 repository.JournalEntryRepositoryInterface
+	reconciliationRunRepository            repository.ReconciliationRunRepositoryInterface
+	reconciliationItemRepository           repository.ReconciliationItemRepositoryInterface
+	auditLogRepository                     repository.AuditLogRepositoryInterface
+	merchantBalanceConversionRepository    repository.MerchantBalanceConversionRepositoryInterface
+	projectCaptureSettingsRepository       repository.ProjectCaptureSettingsRepositoryInterface
+	paymentSystemBreaker                   *paymentSystemBreaker
+	fraudRules                             []fraudRule
 	moneyRegistry                          map[string]*helper.Money
 	moneyRegistryMx                        sync.Mutex
 }
@@ -159,7 +187,9 @@ func NewBillingService(
 func (s *Service) Init() (err error) {
 	s.centrifugoPaymentForm = newCentrifugo(s.cfg.CentrifugoPaymentForm, httpTools.NewLoggedHttpClient(zap.S()))
 	s.centrifugoDashboard = newCentrifugo(s.cfg.CentrifugoDashboard, httpTools.NewLoggedHttpClient(zap.S()))
-	s.paymentSystemGateway = NewPaymentSystemGateway()
+	s.paymentSystemGateway = s.newPaymentSystemGateway()
+	s.paymentSystemBreaker = newPaymentSystemBreaker(s.cfg.PaymentSystemBreakerThreshold, s.cfg.GetPaymentSystemBreakerCooldown())
+	s.fraudRules = newFraudRules(s)
 
 	s.refundRepository = repository.NewRefundRepository(s.db)
 	s.orderRepository = repository.NewOrderRepository(s.db)
@@ -202,6 +232,32 @@ func (s *Service) Init() (err error) {
 	s.feedbackRepository = repository.NewFeedbackRepository(s.db)
 	s.dashboardRepository = repository.NewDashboardRepository(s.db, s.cacher)
 	s.autoincrementRepository = repository.NewAutoincrementRepository(s.db)
+	s.paymentMethodRouteRepository = repository.NewPaymentMethodRouteRepository(s.db)
+	s.orderHistoryRepository = repository.NewOrderHistoryRepository(s.db)
+	s.idempotencyKeyRepository = repository.NewIdempotencyKeyRepository(s.db)
+	s.disputeRepository = repository.NewDisputeRepository(s.db)
+	s.orderReviewRepository = repository.NewOrderReviewRepository(s.db)
+	s.paymentMethodRuleRepository = repository.NewPaymentMethodRuleRepository(s.db)
+	s.promoCodeRepository = repository.NewPromoCodeRepository(s.db)
+	s.subscriptionTrialRepository = repository.NewSubscriptionTrialRepository(s.db)
+	s.subscriptionDunningRepository = repository.NewSubscriptionDunningRepository(s.db)
+	s.dunningScheduleRepository = repository.NewDunningScheduleRepository(s.db)
+	s.subscriptionPlanChangeRepository = repository.NewSubscriptionPlanChangeRepository(s.db)
+	s.subscriptionPauseRepository = repository.NewSubscriptionPauseRepository(s.db)
+	s.savedCardUpdateRepository = repository.NewSavedCardUpdateRepository(s.db)
+	s.refundItemRepository = repository.NewRefundItemRepository(s.db)
+	s.refundApprovalSettingsRepository = repository.NewRefundApprovalSettingsRepository(s.db)
+	s.refundApprovalRepository = repository.NewRefundApprovalRepository(s.db)
+	s.refundFallbackRepository = repository.NewRefundFallbackRepository(s.db)
+	s.storeCreditRepository = repository.NewStoreCreditRepository(s.db)
+	s.customerWalletRepository = repository.NewCustomerWalletRepository(s.db)
+	s.giftCardRepository = repository.NewGiftCardRepository(s.db)
+	s.journalEntryRepository = repository.NewJournalEntryRepository(s.db)
+	s.reconciliationRunRepository = repository.NewReconciliationRunRepository(s.db)
+	s.reconciliationItemRepository = repository.NewReconciliationItemRepository(s.db)
+	s.auditLogRepository = repository.NewAuditLogRepository(s.db)
+	s.merchantBalanceConversionRepository = repository.NewMerchantBalanceConversionRepository(s.db)
+	s.projectCaptureSettingsRepository = repository.NewProjectCaptureSettingsRepository(s.db)
 
 	sCurr, err := s.curService.GetSupportedCurrencies(context.TODO(), &currenciespb.EmptyRequest{})
 	if err != nil {
@@ -0,0 +1,749 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/paysuper/paysuper-billing-server/internal/payment_system"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/currenciespb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	tools "github.com/paysuper/paysuper-tools/number"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	reconciliationDefaultLimit  = int64(100)
+	reconciliationDailyTotalDay = "2006-01-02"
+
+	// reconciliationFeeToleranceRate is the allowed difference between settled and expected fees relative to the
+	// expected fee. Expected fee is converted to the currency of the report by the rate of the billing server, which
+	// differs from the rate of the payment system.
+	reconciliationFeeToleranceRate = 0.005
+)
+
+var (
+	reconciliationErrorUnknown             = errors.NewBillingServerErrorMsg("sr000001", "settlement reconciliation can't be processed. try request later")
+	reconciliationErrorRunNotFound         = errors.NewBillingServerErrorMsg("sr000002", "reconciliation run not found")
+	reconciliationErrorItemNotFound        = errors.NewBillingServerErrorMsg("sr000003", "reconciliation item not found")
+	reconciliationErrorItemMatched         = errors.NewBillingServerErrorMsg("sr000004", "matched reconciliation item doesn't need resolution")
+	reconciliationErrorItemAlreadyResolved = errors.NewBillingServerErrorMsg("sr000005", "reconciliation item already resolved")
+	reconciliationErrorReportEmpty         = errors.NewBillingServerErrorMsg("sr000006", "settlement report doesn't contain payments or refunds")
+	reconciliationErrorReportImported      = errors.NewBillingServerErrorMsg("sr000007", "settlement report already imported")
+
+	// reconciliationFeeEntryTypes are the accounting entries of the payment system fee charged for the transaction.
+	reconciliationFeeEntryTypes = map[string][]string{
+		payment_system.SettlementReportLineTypePayment: {
+			pkg.AccountingEntryTypeMerchantMethodFeeCostValue,
+			pkg.AccountingEntryTypeRealMerchantMethodFixedFeeCostValue,
+		},
+		payment_system.SettlementReportLineTypeRefund: {
+			pkg.AccountingEntryTypeRealRefundFee,
+			pkg.AccountingEntryTypeRealRefundFixedFee,
+		},
+	}
+
+	// reconciliationSettledOrderStatuses are the statuses of orders which payment must be in the settlement report.
+	reconciliationSettledOrderStatuses = []int32{
+		recurringpb.OrderStatusPaymentSystemComplete,
+		recurringpb.OrderStatusProjectComplete,
+		recurringpb.OrderStatusRefund,
+		recurringpb.OrderStatusChargeback,
+	}
+)
+
+type settlementReconciliation struct {
+	service *Service
+	ctx     context.Context
+	run     *intPkg.ReconciliationRun
+	items   []*intPkg.ReconciliationItem
+	matched map[string]bool
+	totals  map[string]*intPkg.ReconciliationDailyTotal
+}
+
+// ImportSettlementReport checks the settlement report file of the payment system against orders and refunds.
+// Transactions of the report are matched by the identifiers of the payment system, settled amounts are compared
+// to amounts of orders and refunds and settled fees are compared to the fee cost accounting entries. Orders and
+// refunds of the report period which are absent in the report are added to the run as missing items. The report is
+// imported once by its identifier.
+func (s *Service) ImportSettlementReport(
+	ctx context.Context,
+	req *pkg.ImportSettlementReportRequest,
+	rsp *pkg.ReconciliationRunResponse,
+) error {
+	lines, err := payment_system.ParseSettlementReport(req.PaymentSystem, req.File)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = reconciliationErrorUnknown
+
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Message = e
+		}
+
+		return nil
+	}
+
+	if len(lines) <= 0 {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = reconciliationErrorReportEmpty
+		return nil
+	}
+
+	reportId := req.ReportId
+
+	if reportId == "" {
+		hash := sha256.Sum256(req.File)
+		reportId = hex.EncodeToString(hash[:])
+	}
+
+	run, err := s.reconciliationRunRepository.GetByReportId(ctx, req.PaymentSystem, reportId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = reconciliationErrorUnknown
+		return nil
+	}
+
+	if run != nil {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = reconciliationErrorReportImported
+		return nil
+	}
+
+	reconciliation := &settlementReconciliation{
+		service: s,
+		ctx:     ctx,
+		run: &intPkg.ReconciliationRun{
+			Id:            primitive.NewObjectID(),
+			PaymentSystem: req.PaymentSystem,
+			ReportId:      reportId,
+			FileName:      req.FileName,
+			Status:        pkg.ReconciliationRunStatusOpen,
+			CreatorId:     req.UserId,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+		},
+		matched: make(map[string]bool),
+		totals:  make(map[string]*intPkg.ReconciliationDailyTotal),
+	}
+
+	if err = reconciliation.process(lines); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = reconciliationErrorUnknown
+		return nil
+	}
+
+	if err = s.reconciliationRunRepository.Insert(ctx, reconciliation.run); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = reconciliationErrorUnknown
+		return nil
+	}
+
+	if err = s.reconciliationItemRepository.MultipleInsert(ctx, reconciliation.items); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = reconciliationErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getReconciliationRunMessage(reconciliation.run)
+
+	return nil
+}
+
+// GetReconciliationRun returns the reconciliation run by unique identifier.
+func (s *Service) GetReconciliationRun(
+	ctx context.Context,
+	req *pkg.GetReconciliationRunRequest,
+	rsp *pkg.ReconciliationRunResponse,
+) error {
+	run, err := s.reconciliationRunRepository.GetById(ctx, req.RunId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = reconciliationErrorRunNotFound
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getReconciliationRunMessage(run)
+
+	return nil
+}
+
+// ListReconciliationRuns returns reconciliation runs by payment system and status, the newest runs go first.
+func (s *Service) ListReconciliationRuns(
+	ctx context.Context,
+	req *pkg.ListReconciliationRunsRequest,
+	rsp *pkg.ListReconciliationRunsResponse,
+) error {
+	if req.Limit <= 0 {
+		req.Limit = reconciliationDefaultLimit
+	}
+
+	count, err := s.reconciliationRunRepository.FindCount(ctx, req.PaymentSystem, req.Status)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = reconciliationErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Count = count
+	rsp.Items = []*pkg.ReconciliationRun{}
+
+	if count <= 0 {
+		return nil
+	}
+
+	runs, err := s.reconciliationRunRepository.Find(ctx, req.PaymentSystem, req.Status, req.Offset, req.Limit)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = reconciliationErrorUnknown
+		return nil
+	}
+
+	for _, run := range runs {
+		rsp.Items = append(rsp.Items, getReconciliationRunMessage(run))
+	}
+
+	return nil
+}
+
+// ListReconciliationItems returns items of the reconciliation run by status in order of lines of the report.
+func (s *Service) ListReconciliationItems(
+	ctx context.Context,
+	req *pkg.ListReconciliationItemsRequest,
+	rsp *pkg.ListReconciliationItemsResponse,
+) error {
+	if _, err := s.reconciliationRunRepository.GetById(ctx, req.RunId); err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = reconciliationErrorRunNotFound
+		return nil
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = reconciliationDefaultLimit
+	}
+
+	count, err := s.reconciliationItemRepository.FindCount(ctx, req.RunId, req.Status)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = reconciliationErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Count = count
+	rsp.Items = []*pkg.ReconciliationItem{}
+
+	if count <= 0 {
+		return nil
+	}
+
+	items, err := s.reconciliationItemRepository.Find(ctx, req.RunId, req.Status, req.Offset, req.Limit)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = reconciliationErrorUnknown
+		return nil
+	}
+
+	for _, item := range items {
+		rsp.Items = append(rsp.Items, getReconciliationItemMessage(item))
+	}
+
+	return nil
+}
+
+// ResolveReconciliationItem marks the unmatched item as resolved by the finance team. The run becomes resolved
+// when all its unmatched items are resolved.
+func (s *Service) ResolveReconciliationItem(
+	ctx context.Context,
+	req *pkg.ResolveReconciliationItemRequest,
+	rsp *pkg.ReconciliationItemResponse,
+) error {
+	item, err := s.reconciliationItemRepository.GetById(ctx, req.ItemId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = reconciliationErrorItemNotFound
+		return nil
+	}
+
+	if item.Status == pkg.ReconciliationItemStatusMatched {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = reconciliationErrorItemMatched
+		return nil
+	}
+
+	if item.IsResolved {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = reconciliationErrorItemAlreadyResolved
+		return nil
+	}
+
+	run, err := s.reconciliationRunRepository.GetById(ctx, item.RunId.Hex())
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = reconciliationErrorRunNotFound
+		return nil
+	}
+
+	item.IsResolved = true
+	item.Resolution = req.Resolution
+	item.ResolvedBy = req.UserId
+	item.ResolvedAt = time.Now()
+
+	if err = s.reconciliationItemRepository.Update(ctx, item); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = reconciliationErrorUnknown
+		return nil
+	}
+
+	unresolved, err := s.reconciliationItemRepository.CountUnresolved(ctx, run.Id.Hex())
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = reconciliationErrorUnknown
+		return nil
+	}
+
+	run.UnresolvedCount = int32(unresolved)
+	run.UpdatedAt = time.Now()
+
+	if run.UnresolvedCount <= 0 {
+		run.Status = pkg.ReconciliationRunStatusResolved
+	}
+
+	if err = s.reconciliationRunRepository.Update(ctx, run); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = reconciliationErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getReconciliationItemMessage(item)
+
+	return nil
+}
+
+func (h *settlementReconciliation) process(lines []*payment_system.SettlementReportLine) error {
+	for _, line := range lines {
+		if h.run.PeriodFrom.IsZero() || line.Date.Before(h.run.PeriodFrom) {
+			h.run.PeriodFrom = line.Date
+		}
+
+		if line.Date.After(h.run.PeriodTo) {
+			h.run.PeriodTo = line.Date
+		}
+
+		item, err := h.matchLine(line)
+
+		if err != nil {
+			return err
+		}
+
+		h.addItem(item)
+	}
+
+	h.run.LinesCount = int32(len(lines))
+	h.run.PeriodFrom = h.run.PeriodFrom.Truncate(24 * time.Hour)
+	h.run.PeriodTo = h.run.PeriodTo.Truncate(24 * time.Hour).Add(24 * time.Hour)
+
+	if err := h.addMissingOrders(); err != nil {
+		return err
+	}
+
+	if err := h.addMissingRefunds(); err != nil {
+		return err
+	}
+
+	for _, item := range h.items {
+		h.countItem(item)
+	}
+
+	keys := make([]string, 0, len(h.totals))
+
+	for key := range h.totals {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		total := h.totals[key]
+		total.MissingAmount = tools.FormatAmount(total.MissingAmount)
+		total.ExtraAmount = tools.FormatAmount(total.ExtraAmount)
+		total.AmountDifference = tools.FormatAmount(total.AmountDifference)
+		total.FeeDifference = tools.FormatAmount(total.FeeDifference)
+		h.run.DailyTotals = append(h.run.DailyTotals, total)
+	}
+
+	if h.run.UnresolvedCount <= 0 {
+		h.run.Status = pkg.ReconciliationRunStatusResolved
+	}
+
+	return nil
+}
+
+// matchLine finds the order or the refund of the settled transaction and compares the settled amount and fee
+// to the expected ones. The transaction repeated in the report is the extra one.
+func (h *settlementReconciliation) matchLine(line *payment_system.SettlementReportLine) (*intPkg.ReconciliationItem, error) {
+	item := &intPkg.ReconciliationItem{
+		Status:        pkg.ReconciliationItemStatusExtra,
+		Type:          line.Type,
+		TransactionId: line.TransactionId,
+		Line:          int32(line.Line),
+		Date:          line.Date,
+		Amount:        line.Amount,
+		Fee:           line.Fee,
+		Currency:      line.Currency,
+	}
+
+	key := line.Type + ":" + line.TransactionId
+
+	if h.matched[key] {
+		return item, nil
+	}
+
+	var (
+		sourceId   string
+		sourceType string
+	)
+
+	if line.Type == payment_system.SettlementReportLineTypePayment {
+		order, err := h.service.orderRepository.GetOneBy(h.ctx, bson.M{
+			"pm_order_id":            line.TransactionId,
+			"type":                   pkg.OrderTypeOrder,
+			"payment_method.handler": h.run.PaymentSystem,
+		})
+
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return item, nil
+			}
+
+			return nil, err
+		}
+
+		item.OrderId = order.Id
+		item.ExpectedAmount = order.ChargeAmount
+		item.ExpectedCurrency = order.ChargeCurrency
+		sourceId = order.Id
+		sourceType = repository.CollectionOrder
+	} else {
+		refund, err := h.service.refundRepository.GetByExternalId(h.ctx, line.TransactionId)
+
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return item, nil
+			}
+
+			return nil, err
+		}
+
+		item.OrderId = refund.OriginalOrder.GetId()
+		item.RefundId = refund.Id
+		item.ExpectedAmount = refund.Amount
+		item.ExpectedCurrency = refund.Currency
+		sourceId = refund.CreatedOrderId
+		sourceType = repository.CollectionRefund
+	}
+
+	h.matched[key] = true
+	expectedFee, err := h.getExpectedFee(line, sourceId, sourceType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	item.ExpectedFee = expectedFee
+
+	switch {
+	case item.ExpectedCurrency != line.Currency || tools.FormatAmount(item.ExpectedAmount) != tools.FormatAmount(line.Amount):
+		item.Status = pkg.ReconciliationItemStatusAmountMismatch
+	case h.service.FormatAmount(math.Abs(item.ExpectedFee-line.Fee), line.Currency) > h.getFeeTolerance(item.ExpectedFee, line.Currency):
+		item.Status = pkg.ReconciliationItemStatusFeeMismatch
+	default:
+		item.Status = pkg.ReconciliationItemStatusMatched
+	}
+
+	return item, nil
+}
+
+// getFeeTolerance returns the allowed difference between the settled and the expected fee. It's relative to the fee
+// and isn't less than the minor unit of the currency.
+func (h *settlementReconciliation) getFeeTolerance(fee float64, currency string) float64 {
+	tolerance := math.Pow10(-int(h.service.getCurrencyPrecision(currency)))
+	return math.Max(tolerance, h.service.FormatAmount(math.Abs(fee)*reconciliationFeeToleranceRate, currency))
+}
+
+// getExpectedFee returns the sum of the fee cost accounting entries of the transaction in the currency
+// of the settlement report line.
+func (h *settlementReconciliation) getExpectedFee(
+	line *payment_system.SettlementReportLine,
+	sourceId, sourceType string,
+) (float64, error) {
+	fee := float64(0)
+
+	if sourceId == "" {
+		return fee, nil
+	}
+
+	for _, entryType := range reconciliationFeeEntryTypes[line.Type] {
+		entry, err := h.service.accountingRepository.ApplyObjectSource(
+			h.ctx,
+			pkg.ObjectTypeBalanceTransaction,
+			entryType,
+			sourceId,
+			sourceType,
+			&billingpb.AccountingEntry{},
+		)
+
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				continue
+			}
+
+			return 0, err
+		}
+
+		amount, err := h.service.exchangeCurrencyByDateCommon(h.ctx, &currenciespb.ExchangeCurrencyByDateCommonRequest{
+			From:              entry.Currency,
+			To:                line.Currency,
+			RateType:          currenciespb.RateTypePaysuper,
+			ExchangeDirection: currenciespb.ExchangeDirectionBuy,
+			Amount:            entry.Amount,
+			Datetime:          getTimestampProto(line.Date),
+		})
+
+		if err != nil {
+			return 0, err
+		}
+
+		fee += amount
+	}
+
+	return tools.FormatAmount(fee), nil
+}
+
+// addMissingOrders adds the settled orders of the report period which payments are absent in the report.
+func (h *settlementReconciliation) addMissingOrders() error {
+	orders, err := h.service.orderRepository.GetManyBy(h.ctx, bson.M{
+		"type":                   pkg.OrderTypeOrder,
+		"payment_method.handler": h.run.PaymentSystem,
+		"pm_order_close_date":    bson.M{"$gte": h.run.PeriodFrom, "$lt": h.run.PeriodTo},
+		"private_status":         bson.M{"$in": reconciliationSettledOrderStatuses},
+		// verification payments of saved card updates don't move money
+		"private_metadata." + pkg.OrderPrivateMetadataSavedCardUpdate: bson.M{"$exists": false},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if h.matched[payment_system.SettlementReportLineTypePayment+":"+order.Transaction] {
+			continue
+		}
+
+		item := &intPkg.ReconciliationItem{
+			Status:           pkg.ReconciliationItemStatusMissing,
+			Type:             payment_system.SettlementReportLineTypePayment,
+			TransactionId:    order.Transaction,
+			OrderId:          order.Id,
+			ExpectedAmount:   order.ChargeAmount,
+			ExpectedCurrency: order.ChargeCurrency,
+		}
+
+		if order.PaymentMethodOrderClosedAt != nil {
+			item.Date, _ = ptypes.Timestamp(order.PaymentMethodOrderClosedAt)
+		}
+
+		h.addItem(item)
+	}
+
+	return nil
+}
+
+// addMissingRefunds adds the refunds of the report period which are absent in the report. Refunds paid by bank
+// transfer or store credit after the gateway decline aren't settled by the payment system and skipped.
+func (h *settlementReconciliation) addMissingRefunds() error {
+	orders, err := h.service.orderRepository.GetManyBy(h.ctx, bson.M{
+		"type":                   pkg.OrderTypeRefund,
+		"payment_method.handler": h.run.PaymentSystem,
+		"created_at":             bson.M{"$gte": h.run.PeriodFrom, "$lt": h.run.PeriodTo},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if order.Refund == nil || order.Refund.ReceiptNumber == "" {
+			continue
+		}
+
+		refund, err := h.service.refundRepository.GetById(h.ctx, order.Refund.ReceiptNumber)
+
+		if err != nil {
+			return err
+		}
+
+		if h.matched[payment_system.SettlementReportLineTypeRefund+":"+refund.ExternalId] {
+			continue
+		}
+
+		destination, err := h.service.getRefundDestination(h.ctx, refund)
+
+		if err != nil {
+			return err
+		}
+
+		if destination != "" {
+			continue
+		}
+
+		item := &intPkg.ReconciliationItem{
+			Status:           pkg.ReconciliationItemStatusMissing,
+			Type:             payment_system.SettlementReportLineTypeRefund,
+			TransactionId:    refund.ExternalId,
+			OrderId:          refund.OriginalOrder.GetId(),
+			RefundId:         refund.Id,
+			ExpectedAmount:   refund.Amount,
+			ExpectedCurrency: refund.Currency,
+		}
+
+		if order.CreatedAt != nil {
+			item.Date, _ = ptypes.Timestamp(order.CreatedAt)
+		}
+
+		h.addItem(item)
+	}
+
+	return nil
+}
+
+func (h *settlementReconciliation) addItem(item *intPkg.ReconciliationItem) {
+	item.Id = primitive.NewObjectID()
+	item.RunId = h.run.Id
+	item.CreatedAt = h.run.CreatedAt
+	h.items = append(h.items, item)
+}
+
+// countItem updates counters of the run and adds the unmatched item to the totals of its day and currency.
+func (h *settlementReconciliation) countItem(item *intPkg.ReconciliationItem) {
+	if item.Status == pkg.ReconciliationItemStatusMatched {
+		h.run.MatchedCount++
+		return
+	}
+
+	h.run.UnresolvedCount++
+	currency := item.Currency
+
+	if item.Status == pkg.ReconciliationItemStatusMissing {
+		currency = item.ExpectedCurrency
+	}
+
+	key := item.Date.Format(reconciliationDailyTotalDay) + ":" + currency
+	total, ok := h.totals[key]
+
+	if !ok {
+		total = &intPkg.ReconciliationDailyTotal{
+			Date:     item.Date.Format(reconciliationDailyTotalDay),
+			Currency: currency,
+		}
+		h.totals[key] = total
+	}
+
+	switch item.Status {
+	case pkg.ReconciliationItemStatusMissing:
+		h.run.MissingCount++
+		total.MissingAmount += item.ExpectedAmount
+	case pkg.ReconciliationItemStatusExtra:
+		h.run.ExtraCount++
+		total.ExtraAmount += item.Amount
+	case pkg.ReconciliationItemStatusAmountMismatch:
+		h.run.AmountMismatchCount++
+		total.AmountDifference += item.Amount - item.ExpectedAmount
+	case pkg.ReconciliationItemStatusFeeMismatch:
+		h.run.FeeMismatchCount++
+		total.FeeDifference += item.Fee - item.ExpectedFee
+	}
+}
+
+func getReconciliationRunMessage(run *intPkg.ReconciliationRun) *pkg.ReconciliationRun {
+	msg := &pkg.ReconciliationRun{
+		Id:                  run.Id.Hex(),
+		PaymentSystem:       run.PaymentSystem,
+		ReportId:            run.ReportId,
+		FileName:            run.FileName,
+		Status:              run.Status,
+		PeriodFrom:          getTimestampProto(run.PeriodFrom),
+		PeriodTo:            getTimestampProto(run.PeriodTo),
+		LinesCount:          run.LinesCount,
+		MatchedCount:        run.MatchedCount,
+		MissingCount:        run.MissingCount,
+		ExtraCount:          run.ExtraCount,
+		AmountMismatchCount: run.AmountMismatchCount,
+		FeeMismatchCount:    run.FeeMismatchCount,
+		UnresolvedCount:     run.UnresolvedCount,
+		DailyTotals:         []*pkg.ReconciliationDailyTotal{},
+		CreatorId:           run.CreatorId,
+		CreatedAt:           getTimestampProto(run.CreatedAt),
+		UpdatedAt:           getTimestampProto(run.UpdatedAt),
+	}
+
+	for _, total := range run.DailyTotals {
+		msg.DailyTotals = append(msg.DailyTotals, &pkg.ReconciliationDailyTotal{
+			Date:             total.Date,
+			Currency:         total.Currency,
+			MissingAmount:    total.MissingAmount,
+			ExtraAmount:      total.ExtraAmount,
+			AmountDifference: total.AmountDifference,
+			FeeDifference:    total.FeeDifference,
+		})
+	}
+
+	return msg
+}
+
+func getReconciliationItemMessage(item *intPkg.ReconciliationItem) *pkg.ReconciliationItem {
+	return &pkg.ReconciliationItem{
+		Id:               item.Id.Hex(),
+		RunId:            item.RunId.Hex(),
+		Status:           item.Status,
+		Type:             item.Type,
+		TransactionId:    item.TransactionId,
+		OrderId:          item.OrderId,
+		RefundId:         item.RefundId,
+		Line:             item.Line,
+		Date:             getTimestampProto(item.Date),
+		Amount:           item.Amount,
+		Fee:              item.Fee,
+		Currency:         item.Currency,
+		ExpectedAmount:   item.ExpectedAmount,
+		ExpectedFee:      item.ExpectedFee,
+		ExpectedCurrency: item.ExpectedCurrency,
+		IsResolved:       item.IsResolved,
+		Resolution:       item.Resolution,
+		ResolvedBy:       item.ResolvedBy,
+		ResolvedAt:       getTimestampProto(item.ResolvedAt),
+	}
+}
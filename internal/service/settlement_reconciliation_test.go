@@ -0,0 +1,482 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	"github.com/paysuper/paysuper-billing-server/internal/payment_system"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type SettlementReconciliationTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+}
+
+func Test_SettlementReconciliation(t *testing.T) {
+	suite.Run(t, new(SettlementReconciliationTestSuite))
+}
+
+func (suite *SettlementReconciliationTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	_, suite.project, suite.paymentMethod, _, _ = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+}
+
+func (suite *SettlementReconciliationTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_ImportSettlementReport_Ok() {
+	matched := suite.payOrder()
+	mismatched := suite.payOrder()
+	missing := suite.payOrder()
+
+	refunded := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.project, suite.paymentMethod, "")
+	refund := HelperMakeRefund(suite.Suite, suite.service, refunded, 10, false)
+	suite.setCardPayHandler(refunded)
+	suite.setRefundCardPayHandler(refund)
+
+	refund, err := suite.service.refundRepository.GetById(context.TODO(), refund.Id)
+	assert.NoError(suite.T(), err)
+
+	report := suite.getSettlementReport(
+		suite.getPaymentLine(matched, matched.ChargeAmount, suite.getExpectedFee(payment_system.SettlementReportLineTypePayment, matched.Id, repository.CollectionOrder, matched.ChargeCurrency)),
+		suite.getPaymentLine(mismatched, mismatched.ChargeAmount+1, 0),
+		suite.getLine("unknown_transaction", "PAYMENT", 50, "RUB", 0),
+		suite.getLine(refund.ExternalId, "REFUND", refund.Amount, refund.Currency, suite.getExpectedFee(payment_system.SettlementReportLineTypeRefund, refund.CreatedOrderId, repository.CollectionRefund, refund.Currency)),
+		suite.getPaymentLine(refunded, refunded.ChargeAmount, suite.getExpectedFee(payment_system.SettlementReportLineTypePayment, refunded.Id, repository.CollectionOrder, refunded.ChargeCurrency)),
+	)
+
+	rsp := suite.importSettlementReport(report)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	run := rsp.Item
+	assert.Equal(suite.T(), pkg.ReconciliationRunStatusOpen, run.Status)
+	assert.EqualValues(suite.T(), 5, run.LinesCount)
+	assert.EqualValues(suite.T(), 3, run.MatchedCount)
+	assert.EqualValues(suite.T(), 1, run.AmountMismatchCount)
+	assert.EqualValues(suite.T(), 1, run.ExtraCount)
+	assert.EqualValues(suite.T(), 1, run.MissingCount)
+	assert.EqualValues(suite.T(), 0, run.FeeMismatchCount)
+	assert.EqualValues(suite.T(), 3, run.UnresolvedCount)
+	assert.Len(suite.T(), run.DailyTotals, 1)
+	assert.Equal(suite.T(), "RUB", run.DailyTotals[0].Currency)
+	assert.Equal(suite.T(), float64(50), run.DailyTotals[0].ExtraAmount)
+	assert.Equal(suite.T(), float64(1), run.DailyTotals[0].AmountDifference)
+	assert.Equal(suite.T(), missing.ChargeAmount, run.DailyTotals[0].MissingAmount)
+
+	items := suite.listReconciliationItems(run.Id, pkg.ReconciliationItemStatusMissing)
+	assert.Len(suite.T(), items, 1)
+	assert.Equal(suite.T(), missing.Id, items[0].OrderId)
+	assert.Equal(suite.T(), missing.Transaction, items[0].TransactionId)
+
+	items = suite.listReconciliationItems(run.Id, pkg.ReconciliationItemStatusAmountMismatch)
+	assert.Len(suite.T(), items, 1)
+	assert.Equal(suite.T(), mismatched.Id, items[0].OrderId)
+	assert.Equal(suite.T(), mismatched.ChargeAmount, items[0].ExpectedAmount)
+
+	items = suite.listReconciliationItems(run.Id, pkg.ReconciliationItemStatusMatched)
+	assert.Len(suite.T(), items, 3)
+	assert.Equal(suite.T(), refund.Id, items[1].RefundId)
+	assert.Equal(suite.T(), payment_system.SettlementReportLineTypeRefund, items[1].Type)
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_ImportSettlementReport_FeeMismatch_Ok() {
+	order := suite.payOrder()
+	fee := suite.getExpectedFee(payment_system.SettlementReportLineTypePayment, order.Id, repository.CollectionOrder, order.ChargeCurrency)
+
+	rsp := suite.importSettlementReport(suite.getSettlementReport(suite.getPaymentLine(order, order.ChargeAmount, fee+1)))
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 1, rsp.Item.FeeMismatchCount)
+	assert.EqualValues(suite.T(), 1, rsp.Item.UnresolvedCount)
+	assert.Len(suite.T(), rsp.Item.DailyTotals, 1)
+	assert.Equal(suite.T(), float64(1), rsp.Item.DailyTotals[0].FeeDifference)
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_ImportSettlementReport_Duplicate_Ok() {
+	order := suite.payOrder()
+	fee := suite.getExpectedFee(payment_system.SettlementReportLineTypePayment, order.Id, repository.CollectionOrder, order.ChargeCurrency)
+	line := suite.getPaymentLine(order, order.ChargeAmount, fee)
+
+	rsp := suite.importSettlementReport(suite.getSettlementReport(line, line))
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 1, rsp.Item.MatchedCount)
+	assert.EqualValues(suite.T(), 1, rsp.Item.ExtraCount)
+	assert.EqualValues(suite.T(), 0, rsp.Item.MissingCount)
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_ImportSettlementReport_FeeWithinTolerance_Ok() {
+	order := suite.payOrder()
+	fee := suite.getExpectedFee(payment_system.SettlementReportLineTypePayment, order.Id, repository.CollectionOrder, order.ChargeCurrency)
+
+	rsp := suite.importSettlementReport(suite.getSettlementReport(suite.getPaymentLine(order, order.ChargeAmount, fee+0.01)))
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.EqualValues(suite.T(), 1, rsp.Item.MatchedCount)
+	assert.EqualValues(suite.T(), 0, rsp.Item.FeeMismatchCount)
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_GetFeeTolerance_Ok() {
+	reconciliation := &settlementReconciliation{service: suite.service, ctx: context.TODO()}
+	assert.Equal(suite.T(), 0.01, reconciliation.getFeeTolerance(1, "USD"))
+	assert.Equal(suite.T(), float64(5), reconciliation.getFeeTolerance(1000, "USD"))
+	assert.Equal(suite.T(), float64(1), reconciliation.getFeeTolerance(100, "JPY"))
+	assert.Equal(suite.T(), float64(5), reconciliation.getFeeTolerance(1000, "JPY"))
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_ImportSettlementReport_AlreadyImported_Error() {
+	report := suite.getSettlementReport(suite.getLine("1001", "PAYMENT", 10, "RUB", 0))
+
+	rsp := suite.importSettlementReport(report)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.NotEmpty(suite.T(), rsp.Item.ReportId)
+
+	rsp = suite.importSettlementReport(report)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), reconciliationErrorReportImported, rsp.Message)
+	assert.Nil(suite.T(), rsp.Item)
+
+	req := &pkg.ImportSettlementReportRequest{
+		PaymentSystem: billingpb.PaymentSystemHandlerCardPay,
+		File:          suite.getSettlementReport(suite.getLine("1002", "PAYMENT", 10, "RUB", 0)),
+		ReportId:      "report_1",
+	}
+	rsp = &pkg.ReconciliationRunResponse{}
+	err := suite.service.ImportSettlementReport(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), "report_1", rsp.Item.ReportId)
+
+	req.File = suite.getSettlementReport(suite.getLine("1003", "PAYMENT", 10, "RUB", 0))
+	rsp = &pkg.ReconciliationRunResponse{}
+	err = suite.service.ImportSettlementReport(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), reconciliationErrorReportImported, rsp.Message)
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_ImportSettlementReport_AllMatched_Ok() {
+	order := suite.payOrder()
+	fee := suite.getExpectedFee(payment_system.SettlementReportLineTypePayment, order.Id, repository.CollectionOrder, order.ChargeCurrency)
+
+	rsp := suite.importSettlementReport(suite.getSettlementReport(suite.getPaymentLine(order, order.ChargeAmount, fee)))
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Equal(suite.T(), pkg.ReconciliationRunStatusResolved, rsp.Item.Status)
+	assert.EqualValues(suite.T(), 0, rsp.Item.UnresolvedCount)
+	assert.Empty(suite.T(), rsp.Item.DailyTotals)
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_ImportSettlementReport_NotSupported_Error() {
+	req := &pkg.ImportSettlementReportRequest{
+		PaymentSystem: PaymentSystemHandlerCardPayMock,
+		File:          suite.getSettlementReport(suite.getLine("1001", "PAYMENT", 10, "RUB", 0)),
+	}
+	rsp := &pkg.ReconciliationRunResponse{}
+	err := suite.service.ImportSettlementReport(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), payment_system.PaymentSystemErrorSettlementReportNotSupported, rsp.Message)
+	assert.Nil(suite.T(), rsp.Item)
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_ImportSettlementReport_Empty_Error() {
+	rsp := suite.importSettlementReport(suite.getSettlementReport(suite.getLine("1001", "PAYOUT", 10, "RUB", 0)))
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), reconciliationErrorReportEmpty, rsp.Message)
+	assert.Nil(suite.T(), rsp.Item)
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_ImportSettlementReport_InsertFailed_Error() {
+	runRep := &mocks.ReconciliationRunRepositoryInterface{}
+	runRep.On("GetByReportId", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	runRep.On("Insert", mock.Anything, mock.Anything).Return(errors.New("some error"))
+	suite.service.reconciliationRunRepository = runRep
+
+	rsp := suite.importSettlementReport(suite.getSettlementReport(suite.getLine("1001", "PAYMENT", 10, "RUB", 0)))
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+	assert.Equal(suite.T(), reconciliationErrorUnknown, rsp.Message)
+	assert.Nil(suite.T(), rsp.Item)
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_ResolveReconciliationItem_Ok() {
+	run := suite.importSettlementReport(suite.getSettlementReport(
+		suite.getLine("1001", "PAYMENT", 10, "RUB", 0),
+		suite.getLine("1002", "REFUND", 5, "RUB", 0),
+	)).Item
+	assert.EqualValues(suite.T(), 2, run.UnresolvedCount)
+
+	items := suite.listReconciliationItems(run.Id, pkg.ReconciliationItemStatusExtra)
+	assert.Len(suite.T(), items, 2)
+
+	userId := "5bdc39a95d1e1100019fb7df"
+	rsp := suite.resolveReconciliationItem(items[0].Id, userId)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.True(suite.T(), rsp.Item.IsResolved)
+	assert.Equal(suite.T(), userId, rsp.Item.ResolvedBy)
+	assert.NotNil(suite.T(), rsp.Item.ResolvedAt)
+
+	run = suite.getReconciliationRun(run.Id)
+	assert.Equal(suite.T(), pkg.ReconciliationRunStatusOpen, run.Status)
+	assert.EqualValues(suite.T(), 1, run.UnresolvedCount)
+
+	rsp = suite.resolveReconciliationItem(items[1].Id, userId)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	run = suite.getReconciliationRun(run.Id)
+	assert.Equal(suite.T(), pkg.ReconciliationRunStatusResolved, run.Status)
+	assert.EqualValues(suite.T(), 0, run.UnresolvedCount)
+
+	listRsp := &pkg.ListReconciliationRunsResponse{}
+	err := suite.service.ListReconciliationRuns(
+		context.TODO(),
+		&pkg.ListReconciliationRunsRequest{Status: pkg.ReconciliationRunStatusResolved},
+		listRsp,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, listRsp.Status)
+	assert.EqualValues(suite.T(), 1, listRsp.Count)
+	assert.Equal(suite.T(), run.Id, listRsp.Items[0].Id)
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_ResolveReconciliationItem_AlreadyResolved_Error() {
+	run := suite.importSettlementReport(suite.getSettlementReport(suite.getLine("1001", "PAYMENT", 10, "RUB", 0))).Item
+	items := suite.listReconciliationItems(run.Id, "")
+	assert.Len(suite.T(), items, 1)
+
+	rsp := suite.resolveReconciliationItem(items[0].Id, "")
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	rsp = suite.resolveReconciliationItem(items[0].Id, "")
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), reconciliationErrorItemAlreadyResolved, rsp.Message)
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_ResolveReconciliationItem_Matched_Error() {
+	order := suite.payOrder()
+	fee := suite.getExpectedFee(payment_system.SettlementReportLineTypePayment, order.Id, repository.CollectionOrder, order.ChargeCurrency)
+	run := suite.importSettlementReport(suite.getSettlementReport(suite.getPaymentLine(order, order.ChargeAmount, fee))).Item
+
+	items := suite.listReconciliationItems(run.Id, pkg.ReconciliationItemStatusMatched)
+	assert.Len(suite.T(), items, 1)
+
+	rsp := suite.resolveReconciliationItem(items[0].Id, "")
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), reconciliationErrorItemMatched, rsp.Message)
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_ResolveReconciliationItem_NotFound_Error() {
+	rsp := suite.resolveReconciliationItem("5bdc39a95d1e1100019fb7df", "")
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), reconciliationErrorItemNotFound, rsp.Message)
+}
+
+func (suite *SettlementReconciliationTestSuite) TestSettlementReconciliation_GetReconciliationRun_NotFound_Error() {
+	rsp := &pkg.ReconciliationRunResponse{}
+	err := suite.service.GetReconciliationRun(
+		context.TODO(),
+		&pkg.GetReconciliationRunRequest{RunId: "5bdc39a95d1e1100019fb7df"},
+		rsp,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), reconciliationErrorRunNotFound, rsp.Message)
+}
+
+func (suite *SettlementReconciliationTestSuite) payOrder() *billingpb.Order {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.project, suite.paymentMethod, "")
+	suite.setCardPayHandler(order)
+
+	return order
+}
+
+// setCardPayHandler moves the order paid by the mock handler to the CardPay handler, which settlement
+// reports are supported.
+func (suite *SettlementReconciliationTestSuite) setCardPayHandler(order *billingpb.Order) {
+	order, err := suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+
+	order.PaymentMethod.Handler = billingpb.PaymentSystemHandlerCardPay
+	err = suite.service.updateOrder(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *SettlementReconciliationTestSuite) setRefundCardPayHandler(refund *billingpb.Refund) {
+	order, err := suite.service.orderRepository.GetOneBy(
+		context.TODO(),
+		bson.M{"type": pkg.OrderTypeRefund, "refund.receipt_number": refund.Id},
+	)
+	assert.NoError(suite.T(), err)
+	suite.setCardPayHandler(order)
+
+	if refund.ExternalId == "" {
+		refund.ExternalId = fmt.Sprintf("refund_%s", refund.Id)
+		err = suite.service.refundRepository.Update(context.TODO(), refund)
+		assert.NoError(suite.T(), err)
+	}
+}
+
+func (suite *SettlementReconciliationTestSuite) getExpectedFee(lineType, sourceId, sourceType, currency string) float64 {
+	reconciliation := &settlementReconciliation{service: suite.service, ctx: context.TODO()}
+	line := &payment_system.SettlementReportLine{Type: lineType, Currency: currency, Date: time.Now().UTC()}
+	fee, err := reconciliation.getExpectedFee(line, sourceId, sourceType)
+	assert.NoError(suite.T(), err)
+
+	return fee
+}
+
+func (suite *SettlementReconciliationTestSuite) getPaymentLine(order *billingpb.Order, amount, fee float64) string {
+	order, err := suite.service.orderRepository.GetById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+
+	return suite.getLine(order.Transaction, "PAYMENT", amount, order.ChargeCurrency, fee)
+}
+
+func (suite *SettlementReconciliationTestSuite) getLine(id, lineType string, amount float64, currency string, fee float64) string {
+	return fmt.Sprintf("%s,%s,%s,%.2f,%s,%.2f", id, lineType, time.Now().UTC().Format("2006-01-02 15:04:05"), amount, currency, fee)
+}
+
+func (suite *SettlementReconciliationTestSuite) getSettlementReport(lines ...string) []byte {
+	report := "transaction_id,transaction_type,transaction_date,amount,currency,fee\n"
+
+	for _, line := range lines {
+		report += line + "\n"
+	}
+
+	return []byte(report)
+}
+
+func (suite *SettlementReconciliationTestSuite) importSettlementReport(report []byte) *pkg.ReconciliationRunResponse {
+	req := &pkg.ImportSettlementReportRequest{
+		PaymentSystem: billingpb.PaymentSystemHandlerCardPay,
+		FileName:      "settlement.csv",
+		File:          report,
+		UserId:        "5bdc39a95d1e1100019fb7df",
+	}
+	rsp := &pkg.ReconciliationRunResponse{}
+	err := suite.service.ImportSettlementReport(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *SettlementReconciliationTestSuite) getReconciliationRun(id string) *pkg.ReconciliationRun {
+	rsp := &pkg.ReconciliationRunResponse{}
+	err := suite.service.GetReconciliationRun(context.TODO(), &pkg.GetReconciliationRunRequest{RunId: id}, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	return rsp.Item
+}
+
+func (suite *SettlementReconciliationTestSuite) listReconciliationItems(runId, status string) []*pkg.ReconciliationItem {
+	rsp := &pkg.ListReconciliationItemsResponse{}
+	err := suite.service.ListReconciliationItems(
+		context.TODO(),
+		&pkg.ListReconciliationItemsRequest{RunId: runId, Status: status},
+		rsp,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	return rsp.Items
+}
+
+func (suite *SettlementReconciliationTestSuite) resolveReconciliationItem(id, userId string) *pkg.ReconciliationItemResponse {
+	req := &pkg.ResolveReconciliationItemRequest{
+		ItemId:     id,
+		Resolution: "checked with the payment system",
+		UserId:     userId,
+	}
+	rsp := &pkg.ReconciliationItemResponse{}
+	err := suite.service.ResolveReconciliationItem(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
@@ -0,0 +1,657 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/uuid"
+	"github.com/jinzhu/copier"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/postmarkpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	// subscriptionDunningRetryCallbackTimeout is the time of waiting for the payment system callback of the retry,
+	// the retry without the callback is failed.
+	subscriptionDunningRetryCallbackTimeout = 24 * time.Hour
+)
+
+var (
+	dunningErrorUnknown            = errors.NewBillingServerErrorMsg("dn000001", "subscription dunning can't be processed. try request later")
+	dunningErrorRetryDaysInvalid   = errors.NewBillingServerErrorMsg("dn000002", "retry days of dunning schedule must be positive and in ascending order")
+	dunningErrorNotFound           = errors.NewBillingServerErrorMsg("dn000003", "subscription dunning with specified data not found")
+	dunningErrorSavedCardNotFound  = errors.NewBillingServerErrorMsg("dn000004", "saved card of customer for retry of subscription payment not found")
+	dunningErrorSubscriptionUpdate = errors.NewBillingServerErrorMsg("dn000005", "subscription status can't be changed by dunning")
+)
+
+// SetDunningSchedule saves the schedule of retries of failed recurring payments of the project. Dunning which is
+// already in progress keeps the schedule from the payment failure.
+func (s *Service) SetDunningSchedule(
+	ctx context.Context,
+	req *pkg.SetDunningScheduleRequest,
+	rsp *pkg.DunningScheduleResponse,
+) error {
+	project, err := s.project.GetById(ctx, req.ProjectId)
+
+	if err != nil || project.MerchantId != req.MerchantId {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = projectErrorNotFound
+		return nil
+	}
+
+	if len(req.RetryDays) <= 0 || len(req.RetryDays) > pkg.SubscriptionDunningMaxRetries {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = dunningErrorRetryDaysInvalid
+		return nil
+	}
+
+	for i, days := range req.RetryDays {
+		if days <= 0 || (i > 0 && days <= req.RetryDays[i-1]) {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = dunningErrorRetryDaysInvalid
+			return nil
+		}
+	}
+
+	schedule, err := s.dunningScheduleRepository.GetByProjectId(ctx, req.ProjectId)
+
+	if err != nil {
+		schedule = &intPkg.DunningSchedule{}
+		schedule.MerchantId, _ = primitive.ObjectIDFromHex(req.MerchantId)
+		schedule.ProjectId, _ = primitive.ObjectIDFromHex(req.ProjectId)
+	}
+
+	schedule.RetryDays = req.RetryDays
+	schedule.UnpaidDays = req.UnpaidDays
+
+	if err = s.dunningScheduleRepository.Upsert(ctx, schedule); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = dunningErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getDunningScheduleMessage(schedule)
+
+	return nil
+}
+
+// GetDunningSchedule returns the schedule of retries of failed recurring payments of the project, the default
+// schedule is returned for the project without own schedule.
+func (s *Service) GetDunningSchedule(
+	ctx context.Context,
+	req *pkg.GetDunningScheduleRequest,
+	rsp *pkg.DunningScheduleResponse,
+) error {
+	project, err := s.project.GetById(ctx, req.ProjectId)
+
+	if err != nil || project.MerchantId != req.MerchantId {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = projectErrorNotFound
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getDunningScheduleMessage(s.getDunningSchedule(ctx, project))
+
+	return nil
+}
+
+// ListSubscriptionDunning returns the history of failed payments of the recurring subscription, the latest goes first.
+func (s *Service) ListSubscriptionDunning(
+	ctx context.Context,
+	req *pkg.ListSubscriptionDunningRequest,
+	rsp *pkg.ListSubscriptionDunningResponse,
+) error {
+	list, err := s.subscriptionDunningRepository.FindBySubscriptionId(ctx, req.SubscriptionId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = dunningErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Items = []*pkg.SubscriptionDunning{}
+
+	for _, dunning := range list {
+		if dunning.MerchantId.Hex() != req.MerchantId {
+			rsp.Status = billingpb.ResponseStatusNotFound
+			rsp.Message = dunningErrorNotFound
+			rsp.Items = nil
+			return nil
+		}
+
+		rsp.Items = append(rsp.Items, getSubscriptionDunningMessage(dunning))
+	}
+
+	return nil
+}
+
+// ProcessSubscriptionDunning retries failed payments of past due subscriptions by the schedule and cancels unpaid
+// subscriptions at the end of the unpaid period.
+func (s *Service) ProcessSubscriptionDunning(ctx context.Context) error {
+	list, err := s.subscriptionDunningRepository.FindDue(ctx, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	for _, dunning := range list {
+		if dunning.Status == pkg.SubscriptionDunningStatusPastDue {
+			err = s.retrySubscriptionDunning(ctx, dunning)
+		} else {
+			err = s.cancelSubscriptionDunning(ctx, dunning)
+		}
+
+		if err != nil {
+			zap.L().Error(
+				"subscription dunning processing failed",
+				zap.Error(err),
+				zap.String("dunning_id", dunning.Id.Hex()),
+				zap.String("subscription_id", dunning.SubscriptionId),
+			)
+		}
+	}
+
+	return nil
+}
+
+// startSubscriptionDunning moves the subscription with the failed regular payment to past due status. The payment of
+// the order is retried by the schedule of the project.
+func (s *Service) startSubscriptionDunning(
+	ctx context.Context,
+	order *billingpb.Order,
+	subscription *recurringpb.Subscription,
+) error {
+	if _, err := s.subscriptionDunningRepository.GetOpenBySubscriptionId(ctx, subscription.Id); err == nil {
+		return nil
+	}
+
+	project, err := s.project.GetById(ctx, subscription.ProjectId)
+
+	if err != nil {
+		return err
+	}
+
+	schedule := s.getDunningSchedule(ctx, project)
+
+	dunning := &intPkg.SubscriptionDunning{
+		SubscriptionId: subscription.Id,
+		CustomerId:     subscription.CustomerId,
+		CustomerEmail:  order.ReceiptEmail,
+		Status:         pkg.SubscriptionDunningStatusPastDue,
+		RetryDays:      schedule.RetryDays,
+		UnpaidDays:     schedule.UnpaidDays,
+		Amount:         order.ChargeAmount,
+		Currency:       order.ChargeCurrency,
+		CreatedAt:      time.Now(),
+	}
+	dunning.OrderId, _ = primitive.ObjectIDFromHex(order.Id)
+	dunning.MerchantId, _ = primitive.ObjectIDFromHex(subscription.MerchantId)
+	dunning.ProjectId, _ = primitive.ObjectIDFromHex(subscription.ProjectId)
+	dunning.NextAttemptAt = dunning.CreatedAt.AddDate(0, 0, int(dunning.RetryDays[0]))
+
+	if dunning.CustomerEmail == "" && subscription.CustomerInfo != nil {
+		dunning.CustomerEmail = subscription.CustomerInfo.Email
+	}
+
+	if err = s.subscriptionDunningRepository.Insert(ctx, dunning); err != nil {
+		return err
+	}
+
+	s.notifySubscriptionDunning(ctx, project, dunning, "")
+	s.sendSubscriptionDunningEmail(project, dunning)
+
+	return nil
+}
+
+// recoverSubscriptionDunning closes the dunning of the subscription paid by the payment system after the failure.
+func (s *Service) recoverSubscriptionDunning(ctx context.Context, subscriptionId string) {
+	dunning, err := s.subscriptionDunningRepository.GetOpenBySubscriptionId(ctx, subscriptionId)
+
+	if err != nil {
+		return
+	}
+
+	if err = s.closeSubscriptionDunning(ctx, dunning, pkg.SubscriptionDunningStatusRecovered, false); err != nil {
+		zap.L().Error(
+			"subscription dunning recovery failed",
+			zap.Error(err),
+			zap.String("dunning_id", dunning.Id.Hex()),
+			zap.String("subscription_id", subscriptionId),
+		)
+	}
+}
+
+// retrySubscriptionDunning retries the failed payment. The result of the retry is known from the payment system
+// callback, so the retry waits for it until the callback timeout. Retry without the callback in time is failed.
+// The retry order is reserved in the dunning before the payment, so the attempt is charged only once by concurrent
+// processing.
+func (s *Service) retrySubscriptionDunning(ctx context.Context, dunning *intPkg.SubscriptionDunning) error {
+	if dunning.RetryOrderId != "" {
+		zap.L().Info(
+			"subscription payment retry callback not received",
+			zap.String("dunning_id", dunning.Id.Hex()),
+			zap.String("order_id", dunning.RetryOrderId),
+		)
+
+		return s.failSubscriptionDunningAttempt(ctx, dunning)
+	}
+
+	dunning.RetryOrderId = primitive.NewObjectID().Hex()
+	dunning.NextAttemptAt = time.Now().Add(subscriptionDunningRetryCallbackTimeout)
+
+	ok, err := s.subscriptionDunningRepository.UpdateFromState(ctx, dunning, dunning.Status, dunning.Attempts, "")
+
+	if err != nil || !ok {
+		return err
+	}
+
+	if err = s.retrySubscriptionPayment(ctx, dunning); err != nil {
+		zap.L().Info(
+			"subscription payment retry failed",
+			zap.Error(err),
+			zap.String("dunning_id", dunning.Id.Hex()),
+			zap.Int32("attempts", dunning.Attempts),
+		)
+
+		return s.failSubscriptionDunningAttempt(ctx, dunning)
+	}
+
+	return nil
+}
+
+// processSubscriptionDunningCallback processes the payment system callback of the retry order. The subscription is
+// recovered by the paid retry, the declined retry is the failed attempt of the dunning.
+func (s *Service) processSubscriptionDunningCallback(ctx context.Context, order *billingpb.Order) error {
+	dunning, err := s.subscriptionDunningRepository.GetById(ctx, order.PrivateMetadata[pkg.OrderPrivateMetadataSubscriptionDunning])
+
+	if err != nil {
+		return err
+	}
+
+	isCurrentRetry := dunning.RetryOrderId == order.Id
+
+	switch order.PrivateStatus {
+	case recurringpb.OrderStatusPaymentSystemComplete:
+		// retry paid after the callback timeout still recovers the subscription
+		if dunning.Status != pkg.SubscriptionDunningStatusPastDue && dunning.Status != pkg.SubscriptionDunningStatusUnpaid {
+			return nil
+		}
+
+		return s.closeSubscriptionDunning(ctx, dunning, pkg.SubscriptionDunningStatusRecovered, isCurrentRetry)
+	case recurringpb.OrderStatusPaymentSystemDeclined, recurringpb.OrderStatusPaymentSystemCanceled:
+		if !isCurrentRetry || dunning.Status != pkg.SubscriptionDunningStatusPastDue {
+			return nil
+		}
+
+		return s.failSubscriptionDunningAttempt(ctx, dunning)
+	}
+
+	return nil
+}
+
+// failSubscriptionDunningAttempt counts the failed retry. The next retry is scheduled, after the last failed retry
+// the subscription becomes unpaid and it's deactivated until the cancellation. The attempt already counted by the
+// concurrent processing of the dunning is skipped.
+func (s *Service) failSubscriptionDunningAttempt(ctx context.Context, dunning *intPkg.SubscriptionDunning) error {
+	status, attempts, retryOrderId := dunning.Status, dunning.Attempts, dunning.RetryOrderId
+
+	dunning.Attempts++
+	dunning.RetryOrderId = ""
+
+	project, err := s.project.GetById(ctx, dunning.ProjectId.Hex())
+
+	if err != nil {
+		return err
+	}
+
+	if int(dunning.Attempts) < len(dunning.RetryDays) {
+		dunning.NextAttemptAt = dunning.CreatedAt.AddDate(0, 0, int(dunning.RetryDays[dunning.Attempts]))
+
+		ok, err := s.subscriptionDunningRepository.UpdateFromState(ctx, dunning, status, attempts, retryOrderId)
+
+		if err != nil || !ok {
+			return err
+		}
+
+		s.sendSubscriptionDunningEmail(project, dunning)
+		return nil
+	}
+
+	dunning.Status = pkg.SubscriptionDunningStatusUnpaid
+	dunning.NextAttemptAt = time.Now().AddDate(0, 0, int(dunning.UnpaidDays))
+
+	ok, err := s.subscriptionDunningRepository.UpdateFromState(ctx, dunning, status, attempts, retryOrderId)
+
+	if err != nil || !ok {
+		return err
+	}
+
+	if err = s.setSubscriptionActive(ctx, dunning.SubscriptionId, false); err != nil {
+		return err
+	}
+
+	s.notifySubscriptionDunning(ctx, project, dunning, pkg.SubscriptionDunningStatusPastDue)
+	s.sendSubscriptionDunningEmail(project, dunning)
+
+	return nil
+}
+
+// retrySubscriptionPayment charges the retry order of the dunning copied from the order of the failed payment by the
+// latest saved card of the customer, so the card updated by the customer after the failure is used. The order of the
+// failed payment keeps its status, each retry has own order. The customer wallet, the gift card and the promo code of
+// the failed order aren't applied to the retry, the retry is charged by the payment system in full.
+func (s *Service) retrySubscriptionPayment(ctx context.Context, dunning *intPkg.SubscriptionDunning) error {
+	failedOrder, err := s.getOrderById(ctx, dunning.OrderId.Hex())
+
+	if err != nil {
+		return err
+	}
+
+	recurringId, err := s.getCustomerRecurringId(ctx, dunning.CustomerId)
+
+	if err != nil {
+		return err
+	}
+
+	h, err := s.paymentSystemGateway.GetGateway(failedOrder.PaymentMethod.Handler)
+
+	if err != nil {
+		return err
+	}
+
+	order := new(billingpb.Order)
+
+	if err = copier.Copy(&order, &failedOrder); err != nil {
+		return err
+	}
+
+	order.Id = dunning.RetryOrderId
+	order.Uuid = uuid.New().String()
+	order.ReceiptId = uuid.New().String()
+	order.CreatedAt = ptypes.TimestampNow()
+	order.UpdatedAt = ptypes.TimestampNow()
+	order.Canceled = false
+	order.CanceledAt = nil
+	order.ReceiptUrl = ""
+	order.RoyaltyReportId = ""
+	order.PrivateStatus = recurringpb.OrderStatusNew
+	order.ParentOrder = &billingpb.ParentOrder{
+		Id:   failedOrder.Id,
+		Uuid: failedOrder.Uuid,
+	}
+	order.PrivateMetadata = make(map[string]string, len(failedOrder.PrivateMetadata)+1)
+
+	for k, v := range failedOrder.PrivateMetadata {
+		order.PrivateMetadata[k] = v
+	}
+
+	prepaidAmount := getOrderPrepaidAmount(order)
+
+	s.removeOrderWallet(order)
+	s.removeOrderGiftCard(order)
+
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataWalletCharged)
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataGiftCardCharged)
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataPromoCodeId)
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataPromoCode)
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataDiscountAmount)
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataAmountBeforeDiscount)
+
+	if prepaidAmount > 0 {
+		if err = s.setOrderChargeAmountAndCurrency(ctx, order); err != nil {
+			return err
+		}
+	}
+
+	order.PrivateMetadata[pkg.OrderPrivateMetadataSubscriptionDunning] = dunning.Id.Hex()
+
+	if err = s.orderRepository.Insert(ctx, order); err != nil {
+		return err
+	}
+
+	requisites := map[string]string{billingpb.PaymentCreateFieldRecurringId: recurringId}
+	_, err = h.CreatePayment(order, s.cfg.GetRedirectUrlSuccess(nil), s.cfg.GetRedirectUrlFail(nil), requisites)
+
+	if updErr := s.updateOrder(ctx, order); updErr != nil {
+		zap.L().Error(
+			"order update after subscription payment retry failed",
+			zap.Error(updErr),
+			zap.String("order_id", order.Id),
+		)
+	}
+
+	return err
+}
+
+// getCustomerRecurringId returns the payment system identifier of the latest saved card of the customer to charge
+// the customer without the card data.
+func (s *Service) getCustomerRecurringId(ctx context.Context, customerId string) (string, error) {
+	cards, err := s.rep.FindSavedCards(ctx, &recurringpb.SavedCardRequest{Token: customerId})
+
+	if err != nil {
+		return "", err
+	}
+
+	recurringId := ""
+
+	for _, v := range cards.SavedCards {
+		if v.IsActive && v.RecurringId != "" {
+			recurringId = v.RecurringId
+		}
+	}
+
+	if recurringId == "" {
+		return "", dunningErrorSavedCardNotFound
+	}
+
+	return recurringId, nil
+}
+
+// cancelSubscriptionDunning cancels the unpaid subscription at the payment system and in the recurring service.
+func (s *Service) cancelSubscriptionDunning(ctx context.Context, dunning *intPkg.SubscriptionDunning) error {
+	rsp, err := s.rep.GetSubscription(ctx, &recurringpb.GetSubscriptionRequest{Id: dunning.SubscriptionId})
+
+	if err == nil && rsp.Status == billingpb.ResponseStatusOk {
+		order, err := s.getOrderById(ctx, dunning.OrderId.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		h, err := s.paymentSystemGateway.GetGateway(order.PaymentMethod.Handler)
+
+		if err != nil {
+			return err
+		}
+
+		if err = h.DeleteRecurringSubscription(order, rsp.Subscription); err != nil {
+			return err
+		}
+
+		rspDelete, err := s.rep.DeleteSubscription(ctx, rsp.Subscription)
+
+		if err != nil || rspDelete.Status != billingpb.ResponseStatusOk {
+			return dunningErrorSubscriptionUpdate
+		}
+	}
+
+	return s.closeSubscriptionDunning(ctx, dunning, pkg.SubscriptionDunningStatusCancelled, false)
+}
+
+// closeSubscriptionDunning closes the dunning with the final status, the paid retry is counted as the attempt. The
+// dunning already changed by the concurrent processing isn't closed.
+func (s *Service) closeSubscriptionDunning(
+	ctx context.Context,
+	dunning *intPkg.SubscriptionDunning,
+	status string,
+	isRetryPaid bool,
+) error {
+	previousStatus, attempts, retryOrderId := dunning.Status, dunning.Attempts, dunning.RetryOrderId
+
+	if isRetryPaid {
+		dunning.Attempts++
+		dunning.RetryOrderId = ""
+	}
+
+	dunning.Status = status
+	dunning.ClosedAt = time.Now()
+
+	ok, err := s.subscriptionDunningRepository.UpdateFromState(ctx, dunning, previousStatus, attempts, retryOrderId)
+
+	if err != nil || !ok {
+		return err
+	}
+
+	if status == pkg.SubscriptionDunningStatusRecovered && previousStatus == pkg.SubscriptionDunningStatusUnpaid {
+		if err = s.setSubscriptionActive(ctx, dunning.SubscriptionId, true); err != nil {
+			return err
+		}
+	}
+
+	project, err := s.project.GetById(ctx, dunning.ProjectId.Hex())
+
+	if err != nil {
+		return err
+	}
+
+	s.notifySubscriptionDunning(ctx, project, dunning, previousStatus)
+
+	return nil
+}
+
+func (s *Service) setSubscriptionActive(ctx context.Context, subscriptionId string, isActive bool) error {
+	rsp, err := s.rep.GetSubscription(ctx, &recurringpb.GetSubscriptionRequest{Id: subscriptionId})
+
+	if err != nil || rsp.Status != billingpb.ResponseStatusOk {
+		return dunningErrorSubscriptionUpdate
+	}
+
+	rsp.Subscription.IsActive = isActive
+	rspUpdate, err := s.rep.UpdateSubscription(ctx, rsp.Subscription)
+
+	if err != nil || rspUpdate.Status != billingpb.ResponseStatusOk {
+		return dunningErrorSubscriptionUpdate
+	}
+
+	return nil
+}
+
+func (s *Service) getDunningSchedule(ctx context.Context, project *billingpb.Project) *intPkg.DunningSchedule {
+	schedule, err := s.dunningScheduleRepository.GetByProjectId(ctx, project.Id)
+
+	if err == nil && len(schedule.RetryDays) > 0 {
+		return schedule
+	}
+
+	schedule = &intPkg.DunningSchedule{
+		RetryDays:  s.cfg.DunningRetryDays,
+		UnpaidDays: s.cfg.DunningUnpaidDays,
+	}
+	schedule.MerchantId, _ = primitive.ObjectIDFromHex(project.MerchantId)
+	schedule.ProjectId, _ = primitive.ObjectIDFromHex(project.Id)
+
+	return schedule
+}
+
+func (s *Service) notifySubscriptionDunning(
+	_ context.Context,
+	project *billingpb.Project,
+	dunning *intPkg.SubscriptionDunning,
+	previousStatus string,
+) {
+	payload := &pkg.SubscriptionDunningNotification{
+		Url:            project.UrlProcessPayment,
+		SecretKey:      project.GetSecretKey(),
+		IsLiveProject:  project.Status == billingpb.ProjectStatusInProduction,
+		PreviousStatus: previousStatus,
+		Dunning:        getSubscriptionDunningMessage(dunning),
+	}
+	err := s.broker.Publish(pkg.PayOneTopicNotifySubscriptionName, payload, amqp.Table{"x-retry-count": int32(0)})
+
+	if err != nil {
+		zap.L().Error(
+			brokerPublicationFailed,
+			zap.Error(err),
+			zap.String("topic", pkg.PayOneTopicNotifySubscriptionName),
+			zap.Any("payload", payload),
+		)
+	}
+}
+
+func (s *Service) sendSubscriptionDunningEmail(project *billingpb.Project, dunning *intPkg.SubscriptionDunning) {
+	if dunning.CustomerEmail == "" {
+		return
+	}
+
+	nextAttemptDate := ""
+
+	if dunning.Status == pkg.SubscriptionDunningStatusPastDue {
+		nextAttemptDate = dunning.NextAttemptAt.Format(billingpb.FilterDateFormat)
+	}
+
+	payload := &postmarkpb.Payload{
+		TemplateAlias: s.cfg.EmailTemplates.SubscriptionPaymentFailed,
+		TemplateModel: map[string]string{
+			"project_name":      project.Name[DefaultLanguage],
+			"amount":            fmt.Sprintf("%.2f", dunning.Amount),
+			"currency":          dunning.Currency,
+			"status":            dunning.Status,
+			"attempts":          fmt.Sprintf("%d", dunning.Attempts),
+			"next_attempt_date": nextAttemptDate,
+			"update_card_url":   s.cfg.GetSubscriptionUpdateCardUrl(dunning.SubscriptionId),
+			"current_year":      time.Now().UTC().Format("2006"),
+		},
+		To: dunning.CustomerEmail,
+	}
+	err := s.postmarkBroker.Publish(postmarkpb.PostmarkSenderTopicName, payload, amqp.Table{})
+
+	if err != nil {
+		zap.L().Error(
+			"Publication message about failed subscription payment to queue failed",
+			zap.Error(err),
+			zap.String("dunning_id", dunning.Id.Hex()),
+			zap.String("topic", postmarkpb.PostmarkSenderTopicName),
+		)
+	}
+}
+
+func getDunningScheduleMessage(schedule *intPkg.DunningSchedule) *pkg.DunningSchedule {
+	return &pkg.DunningSchedule{
+		MerchantId: schedule.MerchantId.Hex(),
+		ProjectId:  schedule.ProjectId.Hex(),
+		RetryDays:  schedule.RetryDays,
+		UnpaidDays: schedule.UnpaidDays,
+		IsDefault:  schedule.Id.IsZero(),
+		UpdatedAt:  getTimestampProto(schedule.UpdatedAt),
+	}
+}
+
+func getSubscriptionDunningMessage(dunning *intPkg.SubscriptionDunning) *pkg.SubscriptionDunning {
+	return &pkg.SubscriptionDunning{
+		Id:             dunning.Id.Hex(),
+		SubscriptionId: dunning.SubscriptionId,
+		OrderId:        dunning.OrderId.Hex(),
+		MerchantId:     dunning.MerchantId.Hex(),
+		ProjectId:      dunning.ProjectId.Hex(),
+		CustomerId:     dunning.CustomerId,
+		Status:         dunning.Status,
+		Attempts:       dunning.Attempts,
+		RetryDays:      dunning.RetryDays,
+		Amount:         dunning.Amount,
+		Currency:       dunning.Currency,
+		NextAttemptAt:  getTimestampProto(dunning.NextAttemptAt),
+		CreatedAt:      getTimestampProto(dunning.CreatedAt),
+		ClosedAt:       getTimestampProto(dunning.ClosedAt),
+	}
+}
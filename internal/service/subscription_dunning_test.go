@@ -0,0 +1,494 @@
+package service
+
+import (
+	"context"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	recurringMocks "github.com/paysuper/paysuper-proto/go/recurringpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type SubscriptionDunningTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+	cookie        string
+}
+
+func Test_SubscriptionDunning(t *testing.T) {
+	suite.Run(t, new(SubscriptionDunningTestSuite))
+}
+
+func (suite *SubscriptionDunningTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	var customer *billingpb.Customer
+	suite.merchant, suite.project, suite.paymentMethod, _, customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.cookie, err = suite.service.generateBrowserCookie(&BrowserCookieCustomer{
+		CustomerId: customer.Id,
+		Ip:         "127.0.0.1",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	})
+
+	if err != nil {
+		suite.FailNow("Generate browser cookie failed", "%v", err)
+	}
+}
+
+func (suite *SubscriptionDunningTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *SubscriptionDunningTestSuite) createRecurringOrder(metadata map[string]string) *billingpb.OrderCreateProcessResponse {
+	paymentMethod, _ := suite.service.paymentMethodRepository.GetById(context.TODO(), suite.paymentMethod.Id)
+	paymentMethod.RecurringAllowed = true
+	_ = suite.service.paymentMethodRepository.Update(context.TODO(), paymentMethod)
+
+	req := &billingpb.OrderCreateRequest{
+		Type:          pkg.OrderType_simple,
+		ProjectId:     suite.project.Id,
+		PaymentMethod: paymentMethod.Group,
+		Currency:      "RUB",
+		Amount:        100,
+		Account:       "unit test",
+		Description:   "unit test",
+		User: &billingpb.OrderUser{
+			Email: "test@unit.unit",
+			Ip:    "127.0.0.1",
+		},
+		FormMode:        "standalone",
+		RecurringPeriod: recurringpb.RecurringPeriodMonth,
+		PrivateMetadata: metadata,
+	}
+
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *SubscriptionDunningTestSuite) createDunning(status string, attempts int32) *intPkg.SubscriptionDunning {
+	rsp := suite.createRecurringOrder(nil)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	order := rsp.Item
+	order.PaymentMethod = &billingpb.PaymentMethodOrder{
+		Id:      suite.paymentMethod.Id,
+		Name:    suite.paymentMethod.Name,
+		Handler: suite.paymentMethod.Handler,
+	}
+	err := suite.service.updateOrder(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	dunning := &intPkg.SubscriptionDunning{
+		SubscriptionId: primitive.NewObjectID().Hex(),
+		CustomerId:     primitive.NewObjectID().Hex(),
+		CustomerEmail:  "test@unit.unit",
+		Status:         status,
+		Attempts:       attempts,
+		RetryDays:      []int32{1, 3},
+		UnpaidDays:     7,
+		Amount:         100,
+		Currency:       "RUB",
+		NextAttemptAt:  time.Now().Add(-time.Hour),
+		CreatedAt:      time.Now().AddDate(0, 0, -1),
+	}
+	dunning.OrderId, _ = primitive.ObjectIDFromHex(order.Id)
+	dunning.MerchantId, _ = primitive.ObjectIDFromHex(suite.merchant.Id)
+	dunning.ProjectId, _ = primitive.ObjectIDFromHex(suite.project.Id)
+
+	err = suite.service.subscriptionDunningRepository.Insert(context.TODO(), dunning)
+	assert.NoError(suite.T(), err)
+
+	return dunning
+}
+
+func (suite *SubscriptionDunningTestSuite) mockRecurring(dunning *intPkg.SubscriptionDunning, cards []*recurringpb.SavedCard) *recurringMocks.RepositoryService {
+	subscription := &recurringpb.Subscription{
+		Id:         dunning.SubscriptionId,
+		CustomerId: dunning.CustomerId,
+		MerchantId: suite.merchant.Id,
+		ProjectId:  suite.project.Id,
+		IsActive:   true,
+	}
+
+	recurring := &recurringMocks.RepositoryService{}
+	recurring.On("FindSavedCards", mock.Anything, mock.Anything).
+		Return(&recurringpb.SavedCardList{SavedCards: cards}, nil)
+	recurring.On("GetSubscription", mock.Anything, mock.Anything).
+		Return(&recurringpb.GetSubscriptionResponse{Status: billingpb.ResponseStatusOk, Subscription: subscription}, nil)
+	recurring.On("UpdateSubscription", mock.Anything, mock.Anything).
+		Return(&recurringpb.UpdateSubscriptionResponse{Status: billingpb.ResponseStatusOk}, nil)
+	recurring.On("DeleteSubscription", mock.Anything, mock.Anything).
+		Return(&recurringpb.DeleteSubscriptionResponse{Status: billingpb.ResponseStatusOk}, nil)
+	suite.service.rep = recurring
+
+	return recurring
+}
+
+func (suite *SubscriptionDunningTestSuite) TestSubscriptionDunning_SetDunningSchedule_Ok() {
+	req := &pkg.GetDunningScheduleRequest{MerchantId: suite.merchant.Id, ProjectId: suite.project.Id}
+	rsp := &pkg.DunningScheduleResponse{}
+	err := suite.service.GetDunningSchedule(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.True(suite.T(), rsp.Item.IsDefault)
+	assert.Equal(suite.T(), suite.service.cfg.DunningRetryDays, rsp.Item.RetryDays)
+
+	reqSet := &pkg.SetDunningScheduleRequest{
+		MerchantId: suite.merchant.Id,
+		ProjectId:  suite.project.Id,
+		RetryDays:  []int32{2, 5, 10},
+		UnpaidDays: 3,
+	}
+	err = suite.service.SetDunningSchedule(context.TODO(), reqSet, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	reqSet.RetryDays = []int32{3, 6}
+	err = suite.service.SetDunningSchedule(context.TODO(), reqSet, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	rsp = &pkg.DunningScheduleResponse{}
+	err = suite.service.GetDunningSchedule(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.False(suite.T(), rsp.Item.IsDefault)
+	assert.Equal(suite.T(), []int32{3, 6}, rsp.Item.RetryDays)
+	assert.EqualValues(suite.T(), 3, rsp.Item.UnpaidDays)
+}
+
+func (suite *SubscriptionDunningTestSuite) TestSubscriptionDunning_SetDunningSchedule_RetryDaysInvalid_Error() {
+	for _, days := range [][]int32{{}, {3, 1}, {1, 1}, {0, 2}} {
+		req := &pkg.SetDunningScheduleRequest{
+			MerchantId: suite.merchant.Id,
+			ProjectId:  suite.project.Id,
+			RetryDays:  days,
+		}
+		rsp := &pkg.DunningScheduleResponse{}
+		err := suite.service.SetDunningSchedule(context.TODO(), req, rsp)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+		assert.Equal(suite.T(), dunningErrorRetryDaysInvalid, rsp.Message)
+	}
+
+	req := &pkg.SetDunningScheduleRequest{
+		MerchantId: primitive.NewObjectID().Hex(),
+		ProjectId:  suite.project.Id,
+		RetryDays:  []int32{1},
+	}
+	rsp := &pkg.DunningScheduleResponse{}
+	err := suite.service.SetDunningSchedule(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), projectErrorNotFound, rsp.Message)
+}
+
+func (suite *SubscriptionDunningTestSuite) TestSubscriptionDunning_StartSubscriptionDunning_Ok() {
+	rsp := suite.createRecurringOrder(nil)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	subscription := &recurringpb.Subscription{
+		Id:         primitive.NewObjectID().Hex(),
+		CustomerId: primitive.NewObjectID().Hex(),
+		MerchantId: suite.merchant.Id,
+		ProjectId:  suite.project.Id,
+	}
+
+	err := suite.service.startSubscriptionDunning(context.TODO(), rsp.Item, subscription)
+	assert.NoError(suite.T(), err)
+
+	// the second failure of the same subscription doesn't start the new dunning
+	err = suite.service.startSubscriptionDunning(context.TODO(), rsp.Item, subscription)
+	assert.NoError(suite.T(), err)
+
+	list, err := suite.service.subscriptionDunningRepository.FindBySubscriptionId(context.TODO(), subscription.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), list, 1)
+	assert.Equal(suite.T(), pkg.SubscriptionDunningStatusPastDue, list[0].Status)
+	assert.Equal(suite.T(), suite.service.cfg.DunningRetryDays, list[0].RetryDays)
+	assert.True(suite.T(), list[0].NextAttemptAt.After(time.Now()))
+
+	req := &pkg.ListSubscriptionDunningRequest{MerchantId: suite.merchant.Id, SubscriptionId: subscription.Id}
+	rspList := &pkg.ListSubscriptionDunningResponse{}
+	err = suite.service.ListSubscriptionDunning(context.TODO(), req, rspList)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rspList.Status)
+	assert.Len(suite.T(), rspList.Items, 1)
+
+	req.MerchantId = primitive.NewObjectID().Hex()
+	rspList = &pkg.ListSubscriptionDunningResponse{}
+	err = suite.service.ListSubscriptionDunning(context.TODO(), req, rspList)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rspList.Status)
+	assert.Equal(suite.T(), dunningErrorNotFound, rspList.Message)
+}
+
+func (suite *SubscriptionDunningTestSuite) TestSubscriptionDunning_ProcessSubscriptionDunning_RetryFailed_Ok() {
+	dunning := suite.createDunning(pkg.SubscriptionDunningStatusPastDue, 0)
+	suite.mockRecurring(dunning, []*recurringpb.SavedCard{})
+
+	err := suite.service.ProcessSubscriptionDunning(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	dunning, err = suite.service.subscriptionDunningRepository.GetById(context.TODO(), dunning.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SubscriptionDunningStatusPastDue, dunning.Status)
+	assert.EqualValues(suite.T(), 1, dunning.Attempts)
+	assert.True(suite.T(), dunning.NextAttemptAt.After(time.Now()))
+
+	dunning.NextAttemptAt = time.Now().Add(-time.Hour)
+	err = suite.service.subscriptionDunningRepository.Update(context.TODO(), dunning)
+	assert.NoError(suite.T(), err)
+
+	err = suite.service.ProcessSubscriptionDunning(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	dunning, err = suite.service.subscriptionDunningRepository.GetById(context.TODO(), dunning.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SubscriptionDunningStatusUnpaid, dunning.Status)
+	assert.EqualValues(suite.T(), 2, dunning.Attempts)
+	assert.True(suite.T(), dunning.NextAttemptAt.After(time.Now().AddDate(0, 0, 6)))
+}
+
+func (suite *SubscriptionDunningTestSuite) retryDunning(dunning *intPkg.SubscriptionDunning) (*intPkg.SubscriptionDunning, *billingpb.Order) {
+	suite.mockRecurring(dunning, []*recurringpb.SavedCard{{RecurringId: "recurring_id", IsActive: true}})
+
+	paymentSystem := &mocks.PaymentSystemInterface{}
+	paymentSystem.On("CreatePayment", mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(requisites map[string]string) bool {
+		return requisites[billingpb.PaymentCreateFieldRecurringId] == "recurring_id"
+	})).Return("", nil)
+	gatewayManagerMock := &mocks.PaymentSystemManagerInterface{}
+	gatewayManagerMock.On("GetGateway", mock.Anything).Return(paymentSystem, nil)
+	suite.service.paymentSystemGateway = gatewayManagerMock
+
+	err := suite.service.ProcessSubscriptionDunning(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	dunning, err = suite.service.subscriptionDunningRepository.GetById(context.TODO(), dunning.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SubscriptionDunningStatusPastDue, dunning.Status)
+	assert.EqualValues(suite.T(), 0, dunning.Attempts)
+	assert.NotEmpty(suite.T(), dunning.RetryOrderId)
+	assert.NotEqual(suite.T(), dunning.OrderId.Hex(), dunning.RetryOrderId)
+	assert.True(suite.T(), dunning.NextAttemptAt.After(time.Now()))
+
+	order, err := suite.service.getOrderById(context.TODO(), dunning.RetryOrderId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), dunning.Id.Hex(), order.PrivateMetadata[pkg.OrderPrivateMetadataSubscriptionDunning])
+	assert.Equal(suite.T(), dunning.OrderId.Hex(), order.ParentOrder.Id)
+
+	return dunning, order
+}
+
+func (suite *SubscriptionDunningTestSuite) TestSubscriptionDunning_ProcessSubscriptionDunning_Recovered_Ok() {
+	dunning, order := suite.retryDunning(suite.createDunning(pkg.SubscriptionDunningStatusPastDue, 0))
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemComplete
+	err := suite.service.processSubscriptionDunningCallback(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	dunning, err = suite.service.subscriptionDunningRepository.GetById(context.TODO(), dunning.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SubscriptionDunningStatusRecovered, dunning.Status)
+	assert.EqualValues(suite.T(), 1, dunning.Attempts)
+	assert.Empty(suite.T(), dunning.RetryOrderId)
+	assert.False(suite.T(), dunning.ClosedAt.IsZero())
+}
+
+func (suite *SubscriptionDunningTestSuite) TestSubscriptionDunning_ProcessSubscriptionDunning_PrepaidMetadataNotCopied() {
+	dunning := suite.createDunning(pkg.SubscriptionDunningStatusPastDue, 0)
+
+	failedOrder, err := suite.service.getOrderById(context.TODO(), dunning.OrderId.Hex())
+	assert.NoError(suite.T(), err)
+
+	failedOrder.PrivateMetadata = map[string]string{
+		pkg.OrderPrivateMetadataWalletId:             primitive.NewObjectID().Hex(),
+		pkg.OrderPrivateMetadataWalletAmount:         "10",
+		pkg.OrderPrivateMetadataWalletCharged:        "1",
+		pkg.OrderPrivateMetadataGiftCardId:           primitive.NewObjectID().Hex(),
+		pkg.OrderPrivateMetadataGiftCardAmount:       "5",
+		pkg.OrderPrivateMetadataGiftCardCharged:      "1",
+		pkg.OrderPrivateMetadataPromoCodeId:          primitive.NewObjectID().Hex(),
+		pkg.OrderPrivateMetadataPromoCode:            "PROMO",
+		pkg.OrderPrivateMetadataDiscountAmount:       "20",
+		pkg.OrderPrivateMetadataAmountBeforeDiscount: "120",
+	}
+	err = suite.service.updateOrder(context.TODO(), failedOrder)
+	assert.NoError(suite.T(), err)
+
+	_, order := suite.retryDunning(dunning)
+
+	for k := range failedOrder.PrivateMetadata {
+		assert.NotContains(suite.T(), order.PrivateMetadata, k)
+	}
+
+	assert.Equal(suite.T(), order.TotalPaymentAmount, order.ChargeAmount)
+}
+
+func (suite *SubscriptionDunningTestSuite) TestSubscriptionDunning_FailSubscriptionDunningAttempt_StaleDunning_Skipped() {
+	dunning, order := suite.retryDunning(suite.createDunning(pkg.SubscriptionDunningStatusPastDue, 0))
+
+	staleDunning, err := suite.service.subscriptionDunningRepository.GetById(context.TODO(), dunning.Id.Hex())
+	assert.NoError(suite.T(), err)
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemComplete
+	err = suite.service.processSubscriptionDunningCallback(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	// the callback timeout processed concurrently with the paid callback doesn't reopen the recovered dunning
+	err = suite.service.failSubscriptionDunningAttempt(context.TODO(), staleDunning)
+	assert.NoError(suite.T(), err)
+
+	dunning, err = suite.service.subscriptionDunningRepository.GetById(context.TODO(), dunning.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SubscriptionDunningStatusRecovered, dunning.Status)
+	assert.EqualValues(suite.T(), 1, dunning.Attempts)
+	assert.Empty(suite.T(), dunning.RetryOrderId)
+}
+
+func (suite *SubscriptionDunningTestSuite) TestSubscriptionDunning_ProcessSubscriptionDunning_RetryDeclined_Ok() {
+	dunning, order := suite.retryDunning(suite.createDunning(pkg.SubscriptionDunningStatusPastDue, 0))
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemDeclined
+	err := suite.service.processSubscriptionDunningCallback(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	// the repeated callback of the same retry isn't counted twice
+	err = suite.service.processSubscriptionDunningCallback(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	dunning, err = suite.service.subscriptionDunningRepository.GetById(context.TODO(), dunning.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SubscriptionDunningStatusPastDue, dunning.Status)
+	assert.EqualValues(suite.T(), 1, dunning.Attempts)
+	assert.Empty(suite.T(), dunning.RetryOrderId)
+	assert.True(suite.T(), dunning.ClosedAt.IsZero())
+}
+
+func (suite *SubscriptionDunningTestSuite) TestSubscriptionDunning_ProcessSubscriptionDunning_RetryCallbackTimeout_Ok() {
+	dunning, _ := suite.retryDunning(suite.createDunning(pkg.SubscriptionDunningStatusPastDue, 0))
+
+	dunning.NextAttemptAt = time.Now().Add(-time.Hour)
+	err := suite.service.subscriptionDunningRepository.Update(context.TODO(), dunning)
+	assert.NoError(suite.T(), err)
+
+	err = suite.service.ProcessSubscriptionDunning(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	dunning, err = suite.service.subscriptionDunningRepository.GetById(context.TODO(), dunning.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SubscriptionDunningStatusPastDue, dunning.Status)
+	assert.EqualValues(suite.T(), 1, dunning.Attempts)
+	assert.Empty(suite.T(), dunning.RetryOrderId)
+}
+
+func (suite *SubscriptionDunningTestSuite) TestSubscriptionDunning_ProcessSubscriptionDunning_Cancelled_Ok() {
+	dunning := suite.createDunning(pkg.SubscriptionDunningStatusUnpaid, 2)
+	recurring := suite.mockRecurring(dunning, []*recurringpb.SavedCard{})
+
+	paymentSystem := &mocks.PaymentSystemInterface{}
+	paymentSystem.On("DeleteRecurringSubscription", mock.Anything, mock.Anything).Return(nil)
+	gatewayManagerMock := &mocks.PaymentSystemManagerInterface{}
+	gatewayManagerMock.On("GetGateway", mock.Anything).Return(paymentSystem, nil)
+	suite.service.paymentSystemGateway = gatewayManagerMock
+
+	err := suite.service.ProcessSubscriptionDunning(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	dunning, err = suite.service.subscriptionDunningRepository.GetById(context.TODO(), dunning.Id.Hex())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SubscriptionDunningStatusCancelled, dunning.Status)
+	assert.False(suite.T(), dunning.ClosedAt.IsZero())
+	recurring.AssertCalled(suite.T(), "DeleteSubscription", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/paysuper/paysuper-billing-server/internal/payment_system"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	pauseErrorUnknown              = errors.NewBillingServerErrorMsg("su000001", "subscription pause can't be processed. try request later")
+	pauseErrorSubscriptionInactive = errors.NewBillingServerErrorMsg("su000002", "only active subscription with paid period can be paused")
+	pauseErrorDunningOpened        = errors.NewBillingServerErrorMsg("su000003", "subscription with failed payment can't be paused")
+	pauseErrorTrialActive          = errors.NewBillingServerErrorMsg("su000004", "subscription can't be paused during trial")
+	pauseErrorResumeDateInvalid    = errors.NewBillingServerErrorMsg("su000005", "pause must be set by number of periods from 1 to 12 or by resume date within a year")
+	pauseErrorNotPaused            = errors.NewBillingServerErrorMsg("su000006", "subscription isn't paused")
+	pauseErrorPaymentSystem        = errors.NewBillingServerErrorMsg("su000007", "subscription status can't be changed on payment system")
+)
+
+// PauseSubscription stops regular payments of the active subscription for the number of periods or until the date.
+// The subscription is deactivated on the payment system and in the recurring service until the resume.
+func (s *Service) PauseSubscription(
+	ctx context.Context,
+	req *pkg.PauseSubscriptionRequest,
+	rsp *pkg.SubscriptionPauseResponse,
+) error {
+	subscription, err := s.getPermittedSubscription(ctx, req.Cookie, req.MerchantId, req.Id)
+
+	if err != nil {
+		rsp.Status = err.(*billingpb.ResponseError).Status
+		rsp.Message = err.(*billingpb.ResponseError).Message
+		return nil
+	}
+
+	if !subscription.IsActive || subscription.LastPaymentAt == nil {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = pauseErrorSubscriptionInactive
+		return nil
+	}
+
+	if _, err = s.subscriptionDunningRepository.GetOpenBySubscriptionId(ctx, subscription.Id); err == nil {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = pauseErrorDunningOpened
+		return nil
+	}
+
+	trial, err := s.subscriptionTrialRepository.GetBySubscriptionId(ctx, subscription.Id)
+
+	if err == nil && trial.Status == pkg.SubscriptionTrialStatusActive {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = pauseErrorTrialActive
+		return nil
+	}
+
+	now := time.Now()
+	resumeAt, err := getSubscriptionResumeDate(req.Periods, req.ResumeDate, subscription.Period, now)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = pauseErrorResumeDateInvalid
+		return nil
+	}
+
+	order, h, err := s.getSubscriptionPaymentSystem(ctx, subscription)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = orderErrorPaymentSystemInactive
+		return nil
+	}
+
+	if err = h.PauseRecurringSubscription(order, subscription); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = pauseErrorPaymentSystem
+		return nil
+	}
+
+	subscription.IsActive = false
+
+	if err = s.updateSubscription(ctx, subscription); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = pauseErrorUnknown
+		return nil
+	}
+
+	lastPaymentAt, _ := ptypes.Timestamp(subscription.LastPaymentAt)
+
+	pause := &intPkg.SubscriptionPause{
+		SubscriptionId: subscription.Id,
+		CustomerId:     subscription.CustomerId,
+		Status:         pkg.SubscriptionPauseStatusPaused,
+		Periods:        req.Periods,
+		PeriodEndsAt:   getSubscriptionPeriodEnd(lastPaymentAt, subscription.Period),
+		PausedAt:       now,
+		ResumeAt:       resumeAt,
+	}
+	pause.MerchantId, _ = primitive.ObjectIDFromHex(subscription.MerchantId)
+	pause.ProjectId, _ = primitive.ObjectIDFromHex(subscription.ProjectId)
+
+	if err = s.subscriptionPauseRepository.Insert(ctx, pause); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = pauseErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getSubscriptionPauseMessage(pause)
+
+	return nil
+}
+
+// ResumeSubscription resumes the paused subscription before the planned resume date.
+func (s *Service) ResumeSubscription(
+	ctx context.Context,
+	req *pkg.ResumeSubscriptionRequest,
+	rsp *pkg.SubscriptionPauseResponse,
+) error {
+	subscription, err := s.getPermittedSubscription(ctx, req.Cookie, req.MerchantId, req.Id)
+
+	if err != nil {
+		rsp.Status = err.(*billingpb.ResponseError).Status
+		rsp.Message = err.(*billingpb.ResponseError).Message
+		return nil
+	}
+
+	pause, err := s.subscriptionPauseRepository.GetPausedBySubscriptionId(ctx, subscription.Id)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = pauseErrorNotPaused
+		return nil
+	}
+
+	if err = s.resumeSubscriptionPause(ctx, pause, subscription); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = pauseErrorUnknown
+
+		if err == pauseErrorPaymentSystem {
+			rsp.Message = pauseErrorPaymentSystem
+		}
+
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getSubscriptionPauseMessage(pause)
+
+	return nil
+}
+
+// ResumeSubscriptions resumes subscriptions which pause ended.
+func (s *Service) ResumeSubscriptions(ctx context.Context) error {
+	pauses, err := s.subscriptionPauseRepository.FindDue(ctx, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	for _, pause := range pauses {
+		rsp, err := s.rep.GetSubscription(ctx, &recurringpb.GetSubscriptionRequest{Id: pause.SubscriptionId})
+
+		if err == nil && rsp.Status == billingpb.ResponseStatusOk {
+			err = s.resumeSubscriptionPause(ctx, pause, rsp.Subscription)
+		} else if err == nil {
+			err = errors.NewBillingServerResponseError(rsp.Status, rsp.Message)
+		}
+
+		if err != nil {
+			zap.L().Error(
+				"subscription resume failed",
+				zap.Error(err),
+				zap.String("subscription_id", pause.SubscriptionId),
+			)
+		}
+	}
+
+	return nil
+}
+
+// resumeSubscriptionPause activates the paused subscription. Paused time isn't counted in the subscription term,
+// so the end date and the date of the last payment are moved by the paused time. The next regular payment is
+// charged when the paid time left at the pause is over, the new dates are sent to the payment system with
+// the activation. Payment system subscription is paused again when the subscription can't be updated.
+func (s *Service) resumeSubscriptionPause(
+	ctx context.Context,
+	pause *intPkg.SubscriptionPause,
+	subscription *recurringpb.Subscription,
+) error {
+	order, h, err := s.getSubscriptionPaymentSystem(ctx, subscription)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	pausedTime := now.Sub(pause.PausedAt)
+	paidTimeLeft := pause.PeriodEndsAt.Sub(pause.PausedAt)
+
+	if paidTimeLeft < 0 {
+		paidTimeLeft = 0
+	}
+
+	nextBillingAt := now.Add(paidTimeLeft)
+	expireAt, _ := ptypes.Timestamp(subscription.ExpireAt)
+	lastPaymentAt, _ := ptypes.Timestamp(subscription.LastPaymentAt)
+	previousExpireAt, previousLastPaymentAt := subscription.ExpireAt, subscription.LastPaymentAt
+
+	subscription.ExpireAt, _ = ptypes.TimestampProto(expireAt.Add(pausedTime))
+	subscription.LastPaymentAt, _ = ptypes.TimestampProto(lastPaymentAt.Add(pausedTime))
+
+	if err = h.ResumeRecurringSubscription(order, subscription, nextBillingAt); err != nil {
+		zap.L().Error(
+			"subscription resume on payment system failed",
+			zap.Error(err),
+			zap.String("subscription_id", subscription.Id),
+		)
+
+		subscription.ExpireAt, subscription.LastPaymentAt = previousExpireAt, previousLastPaymentAt
+		return pauseErrorPaymentSystem
+	}
+
+	subscription.IsActive = true
+
+	if err = s.updateSubscription(ctx, subscription); err != nil {
+		subscription.IsActive = false
+		subscription.ExpireAt, subscription.LastPaymentAt = previousExpireAt, previousLastPaymentAt
+
+		if errPause := h.PauseRecurringSubscription(order, subscription); errPause != nil {
+			zap.L().Error(
+				"subscription pause restore on payment system failed",
+				zap.Error(errPause),
+				zap.String("subscription_id", subscription.Id),
+			)
+		}
+
+		return err
+	}
+
+	pause.Status = pkg.SubscriptionPauseStatusResumed
+	pause.ResumedAt = now
+	pause.NextBillingAt = nextBillingAt
+
+	return s.subscriptionPauseRepository.Update(ctx, pause)
+}
+
+// cancelSubscriptionPause closes the pause of the subscription deleted by the customer or the merchant.
+func (s *Service) cancelSubscriptionPause(ctx context.Context, subscriptionId string) {
+	pause, err := s.subscriptionPauseRepository.GetPausedBySubscriptionId(ctx, subscriptionId)
+
+	if err != nil {
+		return
+	}
+
+	pause.Status = pkg.SubscriptionPauseStatusCancelled
+
+	if err = s.subscriptionPauseRepository.Update(ctx, pause); err != nil {
+		zap.L().Error(
+			"subscription pause cancellation failed",
+			zap.Error(err),
+			zap.String("subscription_id", subscriptionId),
+		)
+	}
+}
+
+// getPermittedSubscription returns the subscription if it's available for the customer from the cookie or
+// for the merchant.
+func (s *Service) getPermittedSubscription(
+	ctx context.Context,
+	cookie, merchantId, subscriptionId string,
+) (*recurringpb.Subscription, error) {
+	var customerId string
+
+	browserCookie, err := s.findAndParseBrowserCookie(cookie)
+
+	if err != nil {
+		return nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusForbidden, recurringCustomerNotFound)
+	}
+
+	if browserCookie != nil {
+		customerId = browserCookie.CustomerId
+	}
+
+	rsp, err := s.rep.GetSubscription(ctx, &recurringpb.GetSubscriptionRequest{Id: subscriptionId})
+
+	if err != nil || rsp.Status != billingpb.ResponseStatusOk {
+		return nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusNotFound, recurringErrorSubscriptionNotFound)
+	}
+
+	if err = s.checkSubscriptionPermission(customerId, merchantId, rsp.Subscription); err != nil {
+		return nil, errors.NewBillingServerResponseError(billingpb.ResponseStatusForbidden, recurringErrorAccessDeny)
+	}
+
+	return rsp.Subscription, nil
+}
+
+func (s *Service) getSubscriptionPaymentSystem(
+	ctx context.Context,
+	subscription *recurringpb.Subscription,
+) (*billingpb.Order, payment_system.PaymentSystemInterface, error) {
+	order, err := s.orderRepository.GetById(ctx, subscription.OrderId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h, err := s.paymentSystemGateway.GetGateway(order.PaymentMethod.Handler)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return order, h, nil
+}
+
+func (s *Service) updateSubscription(ctx context.Context, subscription *recurringpb.Subscription) error {
+	rsp, err := s.rep.UpdateSubscription(ctx, subscription)
+
+	if err != nil || rsp.Status != billingpb.ResponseStatusOk {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("Method", "UpdateSubscription"),
+			zap.Error(err),
+			zap.String("subscriptionId", subscription.Id),
+			zap.Any("update_response", rsp),
+		)
+
+		return pauseErrorUnknown
+	}
+
+	return nil
+}
+
+// getSubscriptionResumeDate returns the resume date of the pause set by the number of periods or by the date.
+// Pause is limited by a year.
+func getSubscriptionResumeDate(periods int32, resumeDate, period string, date time.Time) (time.Time, error) {
+	if (periods > 0) == (resumeDate != "") {
+		return time.Time{}, pauseErrorResumeDateInvalid
+	}
+
+	resumeAt := date
+
+	if periods > 0 {
+		if periods > pkg.SubscriptionPauseMaxPeriods {
+			return time.Time{}, pauseErrorResumeDateInvalid
+		}
+
+		for i := int32(0); i < periods; i++ {
+			resumeAt = getSubscriptionPeriodEnd(resumeAt, period)
+		}
+	} else {
+		var err error
+
+		if resumeAt, err = time.Parse(billingpb.FilterDateFormat, resumeDate); err != nil {
+			return time.Time{}, pauseErrorResumeDateInvalid
+		}
+	}
+
+	if resumeAt.IsZero() || !resumeAt.After(date) || resumeAt.After(date.AddDate(1, 0, 0)) {
+		return time.Time{}, pauseErrorResumeDateInvalid
+	}
+
+	return resumeAt, nil
+}
+
+func getSubscriptionPauseMessage(pause *intPkg.SubscriptionPause) *pkg.SubscriptionPause {
+	return &pkg.SubscriptionPause{
+		Id:             pause.Id.Hex(),
+		SubscriptionId: pause.SubscriptionId,
+		Status:         pause.Status,
+		Periods:        pause.Periods,
+		PausedAt:       getTimestampProto(pause.PausedAt),
+		ResumeAt:       getTimestampProto(pause.ResumeAt),
+		ResumedAt:      getTimestampProto(pause.ResumedAt),
+		NextBillingAt:  getTimestampProto(pause.NextBillingAt),
+	}
+}
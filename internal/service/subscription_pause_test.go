@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	recurringMocks "github.com/paysuper/paysuper-proto/go/recurringpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type SubscriptionPauseTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+	cookie        string
+}
+
+func Test_SubscriptionPause(t *testing.T) {
+	suite.Run(t, new(SubscriptionPauseTestSuite))
+}
+
+func (suite *SubscriptionPauseTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	var customer *billingpb.Customer
+	suite.merchant, suite.project, suite.paymentMethod, _, customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.cookie, err = suite.service.generateBrowserCookie(&BrowserCookieCustomer{
+		CustomerId: customer.Id,
+		Ip:         "127.0.0.1",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	})
+
+	if err != nil {
+		suite.FailNow("Generate browser cookie failed", "%v", err)
+	}
+}
+
+func (suite *SubscriptionPauseTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *SubscriptionPauseTestSuite) createRecurringOrder(metadata map[string]string) *billingpb.OrderCreateProcessResponse {
+	paymentMethod, _ := suite.service.paymentMethodRepository.GetById(context.TODO(), suite.paymentMethod.Id)
+	paymentMethod.RecurringAllowed = true
+	_ = suite.service.paymentMethodRepository.Update(context.TODO(), paymentMethod)
+
+	req := &billingpb.OrderCreateRequest{
+		Type:          pkg.OrderType_simple,
+		ProjectId:     suite.project.Id,
+		PaymentMethod: paymentMethod.Group,
+		Currency:      "RUB",
+		Amount:        100,
+		Account:       "unit test",
+		Description:   "unit test",
+		User: &billingpb.OrderUser{
+			Email: "test@unit.unit",
+			Ip:    "127.0.0.1",
+		},
+		FormMode:        "standalone",
+		RecurringPeriod: recurringpb.RecurringPeriodMonth,
+		PrivateMetadata: metadata,
+	}
+
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *SubscriptionPauseTestSuite) createPaidSubscription(lastPaymentAt time.Time) *recurringpb.Subscription {
+	rsp := suite.createRecurringOrder(nil)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	subscription := &recurringpb.Subscription{
+		Id:         primitive.NewObjectID().Hex(),
+		OrderId:    rsp.Item.Id,
+		CustomerId: primitive.NewObjectID().Hex(),
+		MerchantId: suite.merchant.Id,
+		ProjectId:  suite.project.Id,
+		IsActive:   true,
+		Period:     recurringpb.RecurringPeriodMonth,
+		Amount:     31,
+		Currency:   "RUB",
+	}
+	subscription.LastPaymentAt, _ = ptypes.TimestampProto(lastPaymentAt)
+	subscription.ExpireAt, _ = ptypes.TimestampProto(lastPaymentAt.AddDate(1, 0, 0))
+
+	order := rsp.Item
+	order.RecurringId = subscription.Id
+	order.PrivateStatus = recurringpb.OrderStatusProjectComplete
+	order.OrderAmount = 31
+	order.TotalPaymentAmount = 31
+	order.ChargeAmount = 31
+	order.ChargeCurrency = "RUB"
+	order.PaymentMethod = &billingpb.PaymentMethodOrder{
+		Id:      suite.paymentMethod.Id,
+		Name:    suite.paymentMethod.Name,
+		Handler: suite.paymentMethod.Handler,
+	}
+	err := suite.service.orderRepository.Update(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	recurring := &recurringMocks.RepositoryService{}
+	recurring.On("GetSubscription", mock.Anything, mock.Anything).
+		Return(&recurringpb.GetSubscriptionResponse{Status: billingpb.ResponseStatusOk, Subscription: subscription}, nil)
+	recurring.On("UpdateSubscription", mock.Anything, mock.Anything).
+		Return(&recurringpb.UpdateSubscriptionResponse{Status: billingpb.ResponseStatusOk}, nil)
+	suite.service.rep = recurring
+
+	return subscription
+}
+
+func (suite *SubscriptionPauseTestSuite) mockPaymentSystem(method string, err error) *mocks.PaymentSystemInterface {
+	paymentSystem := &mocks.PaymentSystemInterface{}
+	paymentSystem.On(method, mock.Anything, mock.Anything).Return(err)
+	gatewayManagerMock := &mocks.PaymentSystemManagerInterface{}
+	gatewayManagerMock.On("GetGateway", mock.Anything).Return(paymentSystem, nil)
+	suite.service.paymentSystemGateway = gatewayManagerMock
+
+	return paymentSystem
+}
+
+func (suite *SubscriptionPauseTestSuite) mockPaymentSystemResume(err error) *mocks.PaymentSystemInterface {
+	paymentSystem := &mocks.PaymentSystemInterface{}
+	paymentSystem.On("ResumeRecurringSubscription", mock.Anything, mock.Anything, mock.Anything).Return(err)
+	paymentSystem.On("PauseRecurringSubscription", mock.Anything, mock.Anything).Return(nil)
+	gatewayManagerMock := &mocks.PaymentSystemManagerInterface{}
+	gatewayManagerMock.On("GetGateway", mock.Anything).Return(paymentSystem, nil)
+	suite.service.paymentSystemGateway = gatewayManagerMock
+
+	return paymentSystem
+}
+
+func (suite *SubscriptionPauseTestSuite) TestSubscriptionPause_GetSubscriptionResumeDate_Ok() {
+	date := time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	resumeAt, err := getSubscriptionResumeDate(2, "", recurringpb.RecurringPeriodMonth, date)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), date.AddDate(0, 2, 0), resumeAt)
+
+	resumeAt, err = getSubscriptionResumeDate(0, "2020-03-15", recurringpb.RecurringPeriodMonth, date)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), time.Date(2020, 3, 15, 0, 0, 0, 0, time.UTC), resumeAt)
+
+	_, err = getSubscriptionResumeDate(0, "", recurringpb.RecurringPeriodMonth, date)
+	assert.Equal(suite.T(), pauseErrorResumeDateInvalid, err)
+
+	_, err = getSubscriptionResumeDate(1, "2020-03-15", recurringpb.RecurringPeriodMonth, date)
+	assert.Equal(suite.T(), pauseErrorResumeDateInvalid, err)
+
+	_, err = getSubscriptionResumeDate(pkg.SubscriptionPauseMaxPeriods+1, "", recurringpb.RecurringPeriodDay, date)
+	assert.Equal(suite.T(), pauseErrorResumeDateInvalid, err)
+
+	_, err = getSubscriptionResumeDate(2, "", recurringpb.RecurringPeriodYear, date)
+	assert.Equal(suite.T(), pauseErrorResumeDateInvalid, err)
+
+	_, err = getSubscriptionResumeDate(0, "2020-01-10", recurringpb.RecurringPeriodMonth, date)
+	assert.Equal(suite.T(), pauseErrorResumeDateInvalid, err)
+}
+
+func (suite *SubscriptionPauseTestSuite) TestSubscriptionPause_PauseSubscription_Ok() {
+	subscription := suite.createPaidSubscription(time.Now().AddDate(0, 0, -10))
+	paymentSystem := suite.mockPaymentSystem("PauseRecurringSubscription", nil)
+
+	req := &pkg.PauseSubscriptionRequest{
+		Id:         subscription.Id,
+		MerchantId: suite.merchant.Id,
+		Periods:    2,
+	}
+	rsp := &pkg.SubscriptionPauseResponse{}
+	err := suite.service.PauseSubscription(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Equal(suite.T(), pkg.SubscriptionPauseStatusPaused, rsp.Item.Status)
+	assert.EqualValues(suite.T(), 2, rsp.Item.Periods)
+	assert.False(suite.T(), subscription.IsActive)
+	paymentSystem.AssertCalled(suite.T(), "PauseRecurringSubscription", mock.Anything, mock.Anything)
+
+	pause, err := suite.service.subscriptionPauseRepository.GetPausedBySubscriptionId(context.TODO(), subscription.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), rsp.Item.Id, pause.Id.Hex())
+
+	rsp = &pkg.SubscriptionPauseResponse{}
+	err = suite.service.PauseSubscription(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), pauseErrorSubscriptionInactive, rsp.Message)
+}
+
+func (suite *SubscriptionPauseTestSuite) TestSubscriptionPause_PauseSubscription_ValidationError() {
+	subscription := suite.createPaidSubscription(time.Now().AddDate(0, 0, -10))
+	suite.mockPaymentSystem("PauseRecurringSubscription", nil)
+
+	req := &pkg.PauseSubscriptionRequest{
+		Id:         subscription.Id,
+		MerchantId: suite.merchant.Id,
+		ResumeDate: time.Now().AddDate(2, 0, 0).Format(billingpb.FilterDateFormat),
+	}
+	rsp := &pkg.SubscriptionPauseResponse{}
+	err := suite.service.PauseSubscription(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), pauseErrorResumeDateInvalid, rsp.Message)
+
+	req.MerchantId = primitive.NewObjectID().Hex()
+	rsp = &pkg.SubscriptionPauseResponse{}
+	err = suite.service.PauseSubscription(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, rsp.Status)
+	assert.Equal(suite.T(), recurringErrorAccessDeny, rsp.Message)
+
+	subscription.LastPaymentAt = nil
+	req.MerchantId = suite.merchant.Id
+	rsp = &pkg.SubscriptionPauseResponse{}
+	err = suite.service.PauseSubscription(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), pauseErrorSubscriptionInactive, rsp.Message)
+}
+
+func (suite *SubscriptionPauseTestSuite) TestSubscriptionPause_ResumeSubscription_Ok() {
+	lastPaymentAt := time.Now().AddDate(0, 0, -10)
+	subscription := suite.createPaidSubscription(lastPaymentAt)
+	expireAt, _ := ptypes.Timestamp(subscription.ExpireAt)
+	paymentSystem := suite.mockPaymentSystemResume(nil)
+
+	req := &pkg.ResumeSubscriptionRequest{Id: subscription.Id, MerchantId: suite.merchant.Id}
+	rsp := &pkg.SubscriptionPauseResponse{}
+	err := suite.service.ResumeSubscription(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), pauseErrorNotPaused, rsp.Message)
+
+	pausedAt := time.Now().AddDate(0, 0, -5)
+	pause := &intPkg.SubscriptionPause{
+		SubscriptionId: subscription.Id,
+		Status:         pkg.SubscriptionPauseStatusPaused,
+		PeriodEndsAt:   getSubscriptionPeriodEnd(lastPaymentAt, subscription.Period),
+		PausedAt:       pausedAt,
+		ResumeAt:       time.Now().AddDate(0, 1, 0),
+	}
+	err = suite.service.subscriptionPauseRepository.Insert(context.TODO(), pause)
+	assert.NoError(suite.T(), err)
+	subscription.IsActive = false
+
+	rsp = &pkg.SubscriptionPauseResponse{}
+	err = suite.service.ResumeSubscription(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Equal(suite.T(), pkg.SubscriptionPauseStatusResumed, rsp.Item.Status)
+	assert.True(suite.T(), subscription.IsActive)
+
+	pausedTime := time.Now().Sub(pausedAt)
+	newExpireAt, _ := ptypes.Timestamp(subscription.ExpireAt)
+	newLastPaymentAt, _ := ptypes.Timestamp(subscription.LastPaymentAt)
+	nextBillingAt, _ := ptypes.Timestamp(rsp.Item.NextBillingAt)
+	assert.WithinDuration(suite.T(), expireAt.Add(pausedTime), newExpireAt, time.Minute)
+	assert.WithinDuration(suite.T(), lastPaymentAt.Add(pausedTime), newLastPaymentAt, time.Minute)
+	assert.WithinDuration(suite.T(), getSubscriptionPeriodEnd(newLastPaymentAt, subscription.Period), nextBillingAt, time.Minute)
+	paymentSystem.AssertCalled(suite.T(), "ResumeRecurringSubscription", mock.Anything, mock.Anything, mock.MatchedBy(func(t time.Time) bool {
+		return t.Equal(nextBillingAt)
+	}))
+
+	_, err = suite.service.subscriptionPauseRepository.GetPausedBySubscriptionId(context.TODO(), subscription.Id)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *SubscriptionPauseTestSuite) TestSubscriptionPause_ResumeSubscription_PaymentSystemError() {
+	subscription := suite.createPaidSubscription(time.Now().AddDate(0, 0, -10))
+	subscription.IsActive = false
+	expireAt := subscription.ExpireAt
+	suite.mockPaymentSystemResume(pauseErrorPaymentSystem)
+
+	pause := &intPkg.SubscriptionPause{
+		SubscriptionId: subscription.Id,
+		Status:         pkg.SubscriptionPauseStatusPaused,
+		PeriodEndsAt:   time.Now().AddDate(0, 0, 20),
+		PausedAt:       time.Now().AddDate(0, 0, -5),
+		ResumeAt:       time.Now().AddDate(0, 1, 0),
+	}
+	err := suite.service.subscriptionPauseRepository.Insert(context.TODO(), pause)
+	assert.NoError(suite.T(), err)
+
+	req := &pkg.ResumeSubscriptionRequest{Id: subscription.Id, MerchantId: suite.merchant.Id}
+	rsp := &pkg.SubscriptionPauseResponse{}
+	err = suite.service.ResumeSubscription(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+	assert.Equal(suite.T(), pauseErrorPaymentSystem, rsp.Message)
+	assert.False(suite.T(), subscription.IsActive)
+	assert.Equal(suite.T(), expireAt, subscription.ExpireAt)
+	suite.service.rep.(*recurringMocks.RepositoryService).AssertNotCalled(suite.T(), "UpdateSubscription", mock.Anything, mock.Anything)
+
+	_, err = suite.service.subscriptionPauseRepository.GetPausedBySubscriptionId(context.TODO(), subscription.Id)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *SubscriptionPauseTestSuite) TestSubscriptionPause_ResumeSubscriptions_Ok() {
+	subscription := suite.createPaidSubscription(time.Now().AddDate(0, -2, 0))
+	subscription.IsActive = false
+	paymentSystem := suite.mockPaymentSystemResume(nil)
+
+	pause := &intPkg.SubscriptionPause{
+		SubscriptionId: subscription.Id,
+		Status:         pkg.SubscriptionPauseStatusPaused,
+		PeriodEndsAt:   time.Now().AddDate(0, -1, 10),
+		PausedAt:       time.Now().AddDate(0, -1, 0),
+		ResumeAt:       time.Now().Add(-time.Minute),
+	}
+	err := suite.service.subscriptionPauseRepository.Insert(context.TODO(), pause)
+	assert.NoError(suite.T(), err)
+
+	err = suite.service.ResumeSubscriptions(context.TODO())
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), subscription.IsActive)
+	paymentSystem.AssertCalled(suite.T(), "ResumeRecurringSubscription", mock.Anything, mock.Anything, mock.Anything)
+
+	_, err = suite.service.subscriptionPauseRepository.GetPausedBySubscriptionId(context.TODO(), subscription.Id)
+	assert.Error(suite.T(), err)
+}
@@ -0,0 +1,551 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/uuid"
+	"github.com/jinzhu/copier"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"time"
+)
+
+var (
+	planChangeErrorUnknown               = errors.NewBillingServerErrorMsg("sp000001", "subscription plan can't be changed. try request later")
+	planChangeErrorSubscriptionInactive  = errors.NewBillingServerErrorMsg("sp000002", "plan can be changed only for active subscription with paid period")
+	planChangeErrorPlanInvalid           = errors.NewBillingServerErrorMsg("sp000003", "amount of subscription plan must be greater than zero and period must be one of day, week, month, year")
+	planChangeErrorPlanNotChanged        = errors.NewBillingServerErrorMsg("sp000004", "new subscription plan is equal to current plan")
+	planChangeErrorPeriodOrderNotFound   = errors.NewBillingServerErrorMsg("sp000005", "paid order of current subscription period not found")
+	planChangeErrorProrationChargeFailed = errors.NewBillingServerErrorMsg("sp000006", "proration of subscription plan change can't be charged")
+	planChangeErrorProrationRefundFailed = errors.NewBillingServerErrorMsg("sp000007", "proration of subscription plan change can't be refunded")
+	planChangeErrorPaymentSystem         = errors.NewBillingServerErrorMsg("sp000008", "subscription plan can't be changed on payment system")
+	planChangeErrorPending               = errors.NewBillingServerErrorMsg("sp000009", "previous plan change of subscription is waiting for proration charge")
+	planChangeErrorSavedCardNotFound     = errors.NewBillingServerErrorMsg("sp000010", "saved card of subscription for proration charge not found")
+)
+
+// ChangeSubscriptionPlan changes the amount or the period of regular payments of the active subscription. The rest of
+// the current period is prorated by the daily price of the current and the new plan: the positive proration is
+// charged by the new order with the saved card of the customer and the plan is changed when the payment system
+// confirms the charge, the negative proration is refunded from the order of the current period after the plan change.
+// Accounting entries are created by the payment and the refund processing.
+// Regular payments with the new plan start at the end of the current period. The plan can't be changed while
+// the proration charge of the previous change is pending.
+func (s *Service) ChangeSubscriptionPlan(
+	ctx context.Context,
+	req *pkg.ChangeSubscriptionPlanRequest,
+	rsp *pkg.ChangeSubscriptionPlanResponse,
+) error {
+	var customerId string
+
+	browserCookie, err := s.findAndParseBrowserCookie(req.Cookie)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusForbidden
+		rsp.Message = recurringCustomerNotFound
+		return nil
+	}
+
+	if browserCookie != nil {
+		customerId = browserCookie.CustomerId
+	}
+
+	subscriptionRsp, err := s.rep.GetSubscription(ctx, &recurringpb.GetSubscriptionRequest{Id: req.Id})
+
+	if err != nil || subscriptionRsp.Status != billingpb.ResponseStatusOk {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = recurringErrorSubscriptionNotFound
+		return nil
+	}
+
+	subscription := subscriptionRsp.Subscription
+
+	if err = s.checkSubscriptionPermission(customerId, req.MerchantId, subscription); err != nil {
+		rsp.Status = billingpb.ResponseStatusForbidden
+		rsp.Message = recurringErrorAccessDeny
+		return nil
+	}
+
+	if !subscription.IsActive || subscription.LastPaymentAt == nil {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = planChangeErrorSubscriptionInactive
+		return nil
+	}
+
+	if req.Amount <= 0 || getSubscriptionPeriodEnd(time.Now(), req.Period).IsZero() {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = planChangeErrorPlanInvalid
+		return nil
+	}
+
+	if _, err = s.subscriptionPlanChangeRepository.GetPendingBySubscriptionId(ctx, subscription.Id); err == nil {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = planChangeErrorPending
+		return nil
+	}
+
+	amount := s.FormatAmount(req.Amount, subscription.Currency)
+
+	if amount == subscription.Amount && req.Period == subscription.Period {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = planChangeErrorPlanNotChanged
+		return nil
+	}
+
+	periodOrder, err := s.orderRepository.GetOneBy(
+		ctx,
+		bson.M{
+			"recurring_id": subscription.Id,
+			"private_status": bson.M{"$in": []int32{
+				recurringpb.OrderStatusPaymentSystemComplete,
+				recurringpb.OrderStatusProjectComplete,
+			}},
+		},
+		options.FindOne().SetSort(bson.M{"pm_order_close_date": -1}),
+	)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = planChangeErrorPeriodOrderNotFound
+		return nil
+	}
+
+	periodStart, _ := ptypes.Timestamp(subscription.LastPaymentAt)
+	proration, periodEnd := getSubscriptionProration(subscription, amount, req.Period, periodStart, time.Now())
+
+	change := &intPkg.SubscriptionPlanChange{
+		Id:              primitive.NewObjectID(),
+		SubscriptionId:  subscription.Id,
+		CustomerId:      subscription.CustomerId,
+		Status:          pkg.SubscriptionPlanChangeStatusPending,
+		PreviousAmount:  subscription.Amount,
+		PreviousPeriod:  subscription.Period,
+		Amount:          amount,
+		Period:          req.Period,
+		Currency:        subscription.Currency,
+		ProrationAmount: s.FormatAmount(proration, subscription.Currency),
+		PeriodEndsAt:    periodEnd,
+	}
+	change.MerchantId, _ = primitive.ObjectIDFromHex(subscription.MerchantId)
+	change.ProjectId, _ = primitive.ObjectIDFromHex(subscription.ProjectId)
+	change.PeriodOrderId, _ = primitive.ObjectIDFromHex(periodOrder.Id)
+
+	if change.ProrationAmount > 0 {
+		// the pending change is saved before the charge, so the concurrent change of the subscription is rejected
+		// by the unique index of pending changes
+		change.OrderId = primitive.NewObjectID().Hex()
+
+		if err = s.subscriptionPlanChangeRepository.Insert(ctx, change); err != nil {
+			rsp.Status = billingpb.ResponseStatusSystemError
+			rsp.Message = planChangeErrorUnknown
+
+			if _, err = s.subscriptionPlanChangeRepository.GetPendingBySubscriptionId(ctx, subscription.Id); err == nil {
+				rsp.Status = billingpb.ResponseStatusBadData
+				rsp.Message = planChangeErrorPending
+			}
+
+			return nil
+		}
+
+		if err = s.chargeSubscriptionProration(ctx, periodOrder, subscription, change); err != nil {
+			zap.L().Error(
+				"subscription plan change proration charge failed",
+				zap.Error(err),
+				zap.String("subscription_id", subscription.Id),
+				zap.Float64("proration", change.ProrationAmount),
+			)
+
+			change.Status = pkg.SubscriptionPlanChangeStatusFailed
+			_ = s.subscriptionPlanChangeRepository.Update(ctx, change)
+
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = planChangeErrorProrationChargeFailed
+			return nil
+		}
+
+		rsp.Status = billingpb.ResponseStatusOk
+		rsp.Item = getSubscriptionPlanChangeMessage(change)
+
+		return nil
+	}
+
+	if err = s.updateSubscriptionPlan(ctx, periodOrder, subscription, change.Amount, change.Period); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = planChangeErrorUnknown
+
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Message = e
+		}
+
+		return nil
+	}
+
+	if change.ProrationAmount < 0 {
+		actorId := req.MerchantId
+
+		if actorId == "" {
+			actorId = customerId
+		}
+
+		change.RefundId, err = s.refundSubscriptionProration(ctx, periodOrder, -change.ProrationAmount, change, actorId)
+
+		if err != nil {
+			zap.L().Error(
+				"subscription plan change proration refund failed",
+				zap.Error(err),
+				zap.String("subscription_id", subscription.Id),
+				zap.Float64("proration", change.ProrationAmount),
+			)
+
+			err = s.updateSubscriptionPlan(ctx, periodOrder, subscription, change.PreviousAmount, change.PreviousPeriod)
+
+			if err != nil {
+				zap.L().Error(
+					"subscription plan restore after failed proration refund failed",
+					zap.Error(err),
+					zap.String("subscription_id", subscription.Id),
+				)
+			}
+
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = planChangeErrorProrationRefundFailed
+			return nil
+		}
+	}
+
+	change.Status = pkg.SubscriptionPlanChangeStatusCompleted
+
+	if err = s.subscriptionPlanChangeRepository.Insert(ctx, change); err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = planChangeErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getSubscriptionPlanChangeMessage(change)
+
+	return nil
+}
+
+// processSubscriptionPlanChangeCallback processes the payment system callback of the proration charge order. The plan
+// is changed by the paid charge, the charge is refunded when the plan can't be changed after the payment.
+func (s *Service) processSubscriptionPlanChangeCallback(ctx context.Context, order *billingpb.Order) error {
+	change, err := s.subscriptionPlanChangeRepository.GetById(ctx, order.PrivateMetadata[pkg.OrderPrivateMetadataSubscriptionPlanChange])
+
+	if err != nil {
+		return err
+	}
+
+	if change.Status != pkg.SubscriptionPlanChangeStatusPending || change.OrderId != order.Id {
+		return nil
+	}
+
+	switch order.PrivateStatus {
+	case recurringpb.OrderStatusPaymentSystemComplete:
+		if err = s.completeSubscriptionPlanChange(ctx, change); err == nil {
+			change.Status = pkg.SubscriptionPlanChangeStatusCompleted
+			break
+		}
+
+		zap.L().Error(
+			"subscription plan change after proration charge failed",
+			zap.Error(err),
+			zap.String("subscription_id", change.SubscriptionId),
+			zap.String("order_id", order.Id),
+		)
+
+		change.Status = pkg.SubscriptionPlanChangeStatusFailed
+		change.RefundId, err = s.refundSubscriptionProration(ctx, order, order.ChargeAmount, change, change.CustomerId)
+
+		if err != nil {
+			zap.L().Error(
+				"refund of proration charge of failed subscription plan change failed",
+				zap.Error(err),
+				zap.String("subscription_id", change.SubscriptionId),
+				zap.String("order_id", order.Id),
+			)
+		}
+	case recurringpb.OrderStatusPaymentSystemDeclined, recurringpb.OrderStatusPaymentSystemCanceled:
+		change.Status = pkg.SubscriptionPlanChangeStatusFailed
+	default:
+		return nil
+	}
+
+	return s.subscriptionPlanChangeRepository.Update(ctx, change)
+}
+
+// completeSubscriptionPlanChange changes the plan of the subscription with the paid proration charge.
+func (s *Service) completeSubscriptionPlanChange(ctx context.Context, change *intPkg.SubscriptionPlanChange) error {
+	periodOrder, err := s.getOrderById(ctx, change.PeriodOrderId.Hex())
+
+	if err != nil {
+		return err
+	}
+
+	subscriptionRsp, err := s.rep.GetSubscription(ctx, &recurringpb.GetSubscriptionRequest{Id: change.SubscriptionId})
+
+	if err != nil || subscriptionRsp.Status != billingpb.ResponseStatusOk {
+		return recurringErrorSubscriptionNotFound
+	}
+
+	return s.updateSubscriptionPlan(ctx, periodOrder, subscriptionRsp.Subscription, change.Amount, change.Period)
+}
+
+// updateSubscriptionPlan sets the amount and the period of regular payments of the subscription at the payment system
+// and in the recurring service. Payment system plan is restored when the subscription can't be updated.
+func (s *Service) updateSubscriptionPlan(
+	ctx context.Context,
+	periodOrder *billingpb.Order,
+	subscription *recurringpb.Subscription,
+	amount float64,
+	period string,
+) error {
+	h, err := s.paymentSystemGateway.GetGateway(periodOrder.PaymentMethod.Handler)
+
+	if err != nil {
+		return orderErrorPaymentSystemInactive
+	}
+
+	previousAmount, previousPeriod := subscription.Amount, subscription.Period
+	subscription.Amount = amount
+	subscription.Period = period
+
+	if err = h.UpdateRecurringSubscriptionPlan(periodOrder, subscription); err != nil {
+		zap.L().Error(
+			"subscription plan change on payment system failed",
+			zap.Error(err),
+			zap.String("subscription_id", subscription.Id),
+		)
+
+		subscription.Amount, subscription.Period = previousAmount, previousPeriod
+		return planChangeErrorPaymentSystem
+	}
+
+	updateRsp, err := s.rep.UpdateSubscription(ctx, subscription)
+
+	if err != nil || updateRsp.Status != billingpb.ResponseStatusOk {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("Method", "UpdateSubscription"),
+			zap.Error(err),
+			zap.String("subscriptionId", subscription.Id),
+			zap.Any("update_response", updateRsp),
+		)
+
+		subscription.Amount, subscription.Period = previousAmount, previousPeriod
+
+		if err = h.UpdateRecurringSubscriptionPlan(periodOrder, subscription); err != nil {
+			zap.L().Error(
+				"subscription plan restore on payment system failed",
+				zap.Error(err),
+				zap.String("subscription_id", subscription.Id),
+			)
+		}
+
+		return planChangeErrorUnknown
+	}
+
+	return nil
+}
+
+// chargeSubscriptionProration creates the order of the proration charge of the saved plan change from the order of
+// the current period and pays it with the saved card of the subscription. The order isn't recurring, so it doesn't
+// create the new subscription.
+func (s *Service) chargeSubscriptionProration(
+	ctx context.Context,
+	periodOrder *billingpb.Order,
+	subscription *recurringpb.Subscription,
+	change *intPkg.SubscriptionPlanChange,
+) error {
+	recurringId, err := s.getSubscriptionRecurringId(ctx, subscription)
+
+	if err != nil {
+		return err
+	}
+
+	h, err := s.paymentSystemGateway.GetGateway(periodOrder.PaymentMethod.Handler)
+
+	if err != nil {
+		return err
+	}
+
+	order := new(billingpb.Order)
+
+	if err = copier.Copy(&order, &periodOrder); err != nil {
+		return err
+	}
+
+	ratio := change.ProrationAmount / periodOrder.ChargeAmount
+
+	order.Id = change.OrderId
+	order.Uuid = uuid.New().String()
+	order.ReceiptId = uuid.New().String()
+	order.CreatedAt = ptypes.TimestampNow()
+	order.UpdatedAt = ptypes.TimestampNow()
+	order.Canceled = false
+	order.CanceledAt = nil
+	order.ReceiptUrl = ""
+	order.RoyaltyReportId = ""
+	order.RecurringId = ""
+	order.RecurringSettings = nil
+	order.PrivateStatus = recurringpb.OrderStatusNew
+	order.ParentOrder = &billingpb.ParentOrder{
+		Id:   periodOrder.Id,
+		Uuid: periodOrder.Uuid,
+	}
+	order.OrderAmount = s.FormatAmount(periodOrder.OrderAmount*ratio, order.Currency)
+	order.TotalPaymentAmount = s.FormatAmount(periodOrder.TotalPaymentAmount*ratio, order.Currency)
+	order.ChargeAmount = change.ProrationAmount
+
+	if order.Tax != nil {
+		order.Tax.Amount = s.FormatAmount(periodOrder.Tax.Amount*ratio, order.Currency)
+	}
+
+	order.PrivateMetadata = map[string]string{pkg.OrderPrivateMetadataSubscriptionPlanChange: change.Id.Hex()}
+
+	if err = s.orderRepository.Insert(ctx, order); err != nil {
+		return err
+	}
+
+	requisites := map[string]string{billingpb.PaymentCreateFieldRecurringId: recurringId}
+	_, err = h.CreatePayment(order, s.cfg.GetRedirectUrlSuccess(nil), s.cfg.GetRedirectUrlFail(nil), requisites)
+
+	if updErr := s.updateOrder(ctx, order); updErr != nil {
+		zap.L().Error(
+			"order update after subscription proration charge failed",
+			zap.Error(updErr),
+			zap.String("order_id", order.Id),
+		)
+	}
+
+	return err
+}
+
+// getSubscriptionRecurringId returns the payment system identifier of the saved card which the subscription is charged
+// from. The latest card of the customer may belong to the other subscription, so it isn't used for the proration.
+func (s *Service) getSubscriptionRecurringId(ctx context.Context, subscription *recurringpb.Subscription) (string, error) {
+	cards, err := s.rep.FindSavedCards(ctx, &recurringpb.SavedCardRequest{Token: subscription.CustomerId})
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, v := range cards.SavedCards {
+		if v.IsActive && v.RecurringId != "" && v.MaskedPan == subscription.MaskedPan {
+			return v.RecurringId, nil
+		}
+	}
+
+	return "", planChangeErrorSavedCardNotFound
+}
+
+// refundSubscriptionProration refunds the amount of the order in the charge currency: the proration credit from
+// the order of the current period or the proration charge of the failed plan change. The refund is the part of
+// the plan change, so it isn't held for the approval and the order can be refunded partially more than once.
+func (s *Service) refundSubscriptionProration(
+	ctx context.Context,
+	order *billingpb.Order,
+	amount float64,
+	change *intPkg.SubscriptionPlanChange,
+	actorId string,
+) (string, error) {
+	reason := fmt.Sprintf("Proration of plan change of subscription %s", change.SubscriptionId)
+
+	if order.Id == change.OrderId {
+		reason = fmt.Sprintf("Proration charge of failed plan change of subscription %s", change.SubscriptionId)
+	}
+
+	processor := &createRefundProcessor{
+		service: s,
+		request: &billingpb.CreateRefundRequest{
+			OrderId:    order.Uuid,
+			CreatorId:  actorId,
+			Reason:     reason,
+			MerchantId: change.MerchantId.Hex(),
+		},
+		amount:  s.FormatAmount(amount, order.ChargeCurrency),
+		checked: &createRefundChecked{},
+		ctx:     ctx,
+	}
+	refund, err := processor.processCreateRefund()
+
+	if err != nil {
+		return "", err
+	}
+
+	rsp := &billingpb.CreateRefundResponse{}
+
+	if err = s.sendRefundToPaymentSystem(ctx, processor.checked.order, refund, rsp); err != nil {
+		return "", err
+	}
+
+	if rsp.Status != billingpb.ResponseStatusOk {
+		return "", rsp.Message
+	}
+
+	return refund.Id, nil
+}
+
+// getSubscriptionProration returns the prorated amount of the plan change for the rest of the current period and
+// the end of the current period. Amount is the difference of daily prices of the new and the current plan multiplied
+// by the number of days left.
+func getSubscriptionProration(
+	subscription *recurringpb.Subscription,
+	amount float64,
+	period string,
+	periodStart, date time.Time,
+) (float64, time.Time) {
+	periodEnd := getSubscriptionPeriodEnd(periodStart, subscription.Period)
+
+	if periodEnd.IsZero() || !date.Before(periodEnd) {
+		return 0, periodEnd
+	}
+
+	daysLeft := periodEnd.Sub(date).Hours() / 24
+	currentDailyPrice := subscription.Amount / (periodEnd.Sub(periodStart).Hours() / 24)
+	dailyPrice := amount / (getSubscriptionPeriodEnd(periodStart, period).Sub(periodStart).Hours() / 24)
+
+	return (dailyPrice - currentDailyPrice) * daysLeft, periodEnd
+}
+
+// getSubscriptionPeriodEnd returns the end of the subscription period started at the date or zero time for
+// the unknown period.
+func getSubscriptionPeriodEnd(start time.Time, period string) time.Time {
+	switch period {
+	case recurringpb.RecurringPeriodDay:
+		return start.AddDate(0, 0, 1)
+	case recurringpb.RecurringPeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case recurringpb.RecurringPeriodMonth:
+		return start.AddDate(0, 1, 0)
+	case recurringpb.RecurringPeriodYear:
+		return start.AddDate(1, 0, 0)
+	}
+
+	return time.Time{}
+}
+
+func getSubscriptionPlanChangeMessage(change *intPkg.SubscriptionPlanChange) *pkg.SubscriptionPlanChange {
+	return &pkg.SubscriptionPlanChange{
+		Id:              change.Id.Hex(),
+		SubscriptionId:  change.SubscriptionId,
+		PreviousAmount:  change.PreviousAmount,
+		PreviousPeriod:  change.PreviousPeriod,
+		Amount:          change.Amount,
+		Period:          change.Period,
+		Currency:        change.Currency,
+		ProrationAmount: change.ProrationAmount,
+		OrderId:         change.OrderId,
+		RefundId:        change.RefundId,
+		PeriodEndsAt:    getTimestampProto(change.PeriodEndsAt),
+		CreatedAt:       getTimestampProto(change.CreatedAt),
+		Status:          change.Status,
+	}
+}
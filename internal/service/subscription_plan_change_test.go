@@ -0,0 +1,414 @@
+package service
+
+import (
+	"context"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	recurringMocks "github.com/paysuper/paysuper-proto/go/recurringpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type SubscriptionPlanChangeTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+	cookie        string
+}
+
+func Test_SubscriptionPlanChange(t *testing.T) {
+	suite.Run(t, new(SubscriptionPlanChangeTestSuite))
+}
+
+func (suite *SubscriptionPlanChangeTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	var customer *billingpb.Customer
+	suite.merchant, suite.project, suite.paymentMethod, _, customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.cookie, err = suite.service.generateBrowserCookie(&BrowserCookieCustomer{
+		CustomerId: customer.Id,
+		Ip:         "127.0.0.1",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	})
+
+	if err != nil {
+		suite.FailNow("Generate browser cookie failed", "%v", err)
+	}
+}
+
+func (suite *SubscriptionPlanChangeTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *SubscriptionPlanChangeTestSuite) createRecurringOrder(metadata map[string]string) *billingpb.OrderCreateProcessResponse {
+	paymentMethod, _ := suite.service.paymentMethodRepository.GetById(context.TODO(), suite.paymentMethod.Id)
+	paymentMethod.RecurringAllowed = true
+	_ = suite.service.paymentMethodRepository.Update(context.TODO(), paymentMethod)
+
+	req := &billingpb.OrderCreateRequest{
+		Type:          pkg.OrderType_simple,
+		ProjectId:     suite.project.Id,
+		PaymentMethod: paymentMethod.Group,
+		Currency:      "RUB",
+		Amount:        100,
+		Account:       "unit test",
+		Description:   "unit test",
+		User: &billingpb.OrderUser{
+			Email: "test@unit.unit",
+			Ip:    "127.0.0.1",
+		},
+		FormMode:        "standalone",
+		RecurringPeriod: recurringpb.RecurringPeriodMonth,
+		PrivateMetadata: metadata,
+	}
+
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *SubscriptionPlanChangeTestSuite) createPaidSubscription(lastPaymentAt time.Time) *recurringpb.Subscription {
+	rsp := suite.createRecurringOrder(nil)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	subscription := &recurringpb.Subscription{
+		Id:         primitive.NewObjectID().Hex(),
+		OrderId:    rsp.Item.Id,
+		CustomerId: primitive.NewObjectID().Hex(),
+		MerchantId: suite.merchant.Id,
+		ProjectId:  suite.project.Id,
+		IsActive:   true,
+		Period:     recurringpb.RecurringPeriodMonth,
+		Amount:     31,
+		Currency:   "RUB",
+		MaskedPan:  "400000******0002",
+	}
+	subscription.LastPaymentAt, _ = ptypes.TimestampProto(lastPaymentAt)
+
+	order := rsp.Item
+	order.RecurringId = subscription.Id
+	order.PrivateStatus = recurringpb.OrderStatusProjectComplete
+	order.OrderAmount = 31
+	order.TotalPaymentAmount = 31
+	order.ChargeAmount = 31
+	order.ChargeCurrency = "RUB"
+	order.PaymentMethod = &billingpb.PaymentMethodOrder{
+		Id:      suite.paymentMethod.Id,
+		Name:    suite.paymentMethod.Name,
+		Handler: suite.paymentMethod.Handler,
+	}
+	err := suite.service.orderRepository.Update(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	recurring := &recurringMocks.RepositoryService{}
+	recurring.On("GetSubscription", mock.Anything, mock.Anything).
+		Return(&recurringpb.GetSubscriptionResponse{Status: billingpb.ResponseStatusOk, Subscription: subscription}, nil)
+	// the latest card of the customer isn't the card of the subscription
+	cards := []*recurringpb.SavedCard{
+		{RecurringId: "recurring_id", MaskedPan: subscription.MaskedPan, IsActive: true},
+		{RecurringId: "other_recurring_id", MaskedPan: "555555******4444", IsActive: true},
+	}
+	recurring.On("FindSavedCards", mock.Anything, mock.Anything).
+		Return(&recurringpb.SavedCardList{SavedCards: cards}, nil)
+	recurring.On("UpdateSubscription", mock.Anything, mock.Anything).
+		Return(&recurringpb.UpdateSubscriptionResponse{Status: billingpb.ResponseStatusOk}, nil)
+	suite.service.rep = recurring
+
+	return subscription
+}
+
+func (suite *SubscriptionPlanChangeTestSuite) TestSubscriptionPlanChange_GetSubscriptionProration_Ok() {
+	subscription := &recurringpb.Subscription{Period: recurringpb.RecurringPeriodMonth, Amount: 31}
+	periodStart := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	date := time.Date(2020, 1, 16, 0, 0, 0, 0, time.UTC)
+
+	proration, periodEnd := getSubscriptionProration(subscription, 62, recurringpb.RecurringPeriodMonth, periodStart, date)
+	assert.InDelta(suite.T(), 16, proration, 0.0001)
+	assert.Equal(suite.T(), time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), periodEnd)
+
+	proration, _ = getSubscriptionProration(subscription, 15.5, recurringpb.RecurringPeriodMonth, periodStart, date)
+	assert.InDelta(suite.T(), -8, proration, 0.0001)
+
+	proration, _ = getSubscriptionProration(subscription, 366, recurringpb.RecurringPeriodYear, periodStart, date)
+	assert.InDelta(suite.T(), 0, proration, 0.0001)
+
+	proration, _ = getSubscriptionProration(subscription, 62, recurringpb.RecurringPeriodMonth, periodStart, periodEnd)
+	assert.Zero(suite.T(), proration)
+}
+
+func (suite *SubscriptionPlanChangeTestSuite) upgradeSubscriptionPlan(
+	planUpdateErr error,
+) (*recurringpb.Subscription, *mocks.PaymentSystemInterface, *billingpb.Order) {
+	subscription := suite.createPaidSubscription(time.Now().AddDate(0, 0, -10))
+
+	paymentSystem := &mocks.PaymentSystemInterface{}
+	paymentSystem.On("CreatePayment", mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(requisites map[string]string) bool {
+		return requisites[billingpb.PaymentCreateFieldRecurringId] == "recurring_id"
+	})).Return("", nil)
+	paymentSystem.On("UpdateRecurringSubscriptionPlan", mock.Anything, mock.Anything).Return(planUpdateErr)
+	paymentSystem.On("CreateRefund", mock.Anything, mock.Anything).Return(nil)
+	gatewayManagerMock := &mocks.PaymentSystemManagerInterface{}
+	gatewayManagerMock.On("GetGateway", mock.Anything).Return(paymentSystem, nil)
+	suite.service.paymentSystemGateway = gatewayManagerMock
+
+	req := &pkg.ChangeSubscriptionPlanRequest{
+		Id:         subscription.Id,
+		MerchantId: suite.merchant.Id,
+		Amount:     62,
+		Period:     recurringpb.RecurringPeriodMonth,
+	}
+	rsp := &pkg.ChangeSubscriptionPlanResponse{}
+	err := suite.service.ChangeSubscriptionPlan(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Equal(suite.T(), pkg.SubscriptionPlanChangeStatusPending, rsp.Item.Status)
+	assert.EqualValues(suite.T(), 31, rsp.Item.PreviousAmount)
+	assert.EqualValues(suite.T(), 62, rsp.Item.Amount)
+	assert.True(suite.T(), rsp.Item.ProrationAmount > 0)
+	assert.NotEmpty(suite.T(), rsp.Item.OrderId)
+	assert.Empty(suite.T(), rsp.Item.RefundId)
+
+	// plan isn't changed until the proration charge is paid
+	paymentSystem.AssertNotCalled(suite.T(), "UpdateRecurringSubscriptionPlan", mock.Anything, mock.Anything)
+
+	order, err := suite.service.getOrderById(context.TODO(), rsp.Item.OrderId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), subscription.OrderId, order.ParentOrder.Id)
+	assert.Equal(suite.T(), rsp.Item.ProrationAmount, order.ChargeAmount)
+	assert.Empty(suite.T(), order.RecurringId)
+	assert.Nil(suite.T(), order.RecurringSettings)
+	assert.Equal(suite.T(), rsp.Item.Id, order.PrivateMetadata[pkg.OrderPrivateMetadataSubscriptionPlanChange])
+
+	return subscription, paymentSystem, order
+}
+
+func (suite *SubscriptionPlanChangeTestSuite) TestSubscriptionPlanChange_ChangeSubscriptionPlan_Upgrade_Ok() {
+	subscription, paymentSystem, order := suite.upgradeSubscriptionPlan(nil)
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemComplete
+	err := suite.service.processSubscriptionPlanChangeCallback(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	paymentSystem.AssertCalled(suite.T(), "UpdateRecurringSubscriptionPlan", mock.Anything, mock.MatchedBy(func(s *recurringpb.Subscription) bool {
+		return s.Amount == 62 && s.Period == recurringpb.RecurringPeriodMonth
+	}))
+
+	list, err := suite.service.subscriptionPlanChangeRepository.FindBySubscriptionId(context.TODO(), subscription.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), list, 1)
+	assert.Equal(suite.T(), pkg.SubscriptionPlanChangeStatusCompleted, list[0].Status)
+}
+
+func (suite *SubscriptionPlanChangeTestSuite) TestSubscriptionPlanChange_ChangeSubscriptionPlan_PendingChange_Error() {
+	subscription, paymentSystem, order := suite.upgradeSubscriptionPlan(nil)
+
+	req := &pkg.ChangeSubscriptionPlanRequest{
+		Id:         subscription.Id,
+		MerchantId: suite.merchant.Id,
+		Amount:     93,
+		Period:     recurringpb.RecurringPeriodMonth,
+	}
+	rsp := &pkg.ChangeSubscriptionPlanResponse{}
+	err := suite.service.ChangeSubscriptionPlan(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), planChangeErrorPending, rsp.Message)
+	paymentSystem.AssertNumberOfCalls(suite.T(), "CreatePayment", 1)
+
+	// the plan can be changed again after the pending change is finished
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemDeclined
+	err = suite.service.processSubscriptionPlanChangeCallback(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	rsp = &pkg.ChangeSubscriptionPlanResponse{}
+	err = suite.service.ChangeSubscriptionPlan(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Equal(suite.T(), pkg.SubscriptionPlanChangeStatusPending, rsp.Item.Status)
+}
+
+func (suite *SubscriptionPlanChangeTestSuite) TestSubscriptionPlanChange_ChangeSubscriptionPlan_UpgradeDeclined() {
+	subscription, paymentSystem, order := suite.upgradeSubscriptionPlan(nil)
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemDeclined
+	err := suite.service.processSubscriptionPlanChangeCallback(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	paymentSystem.AssertNotCalled(suite.T(), "UpdateRecurringSubscriptionPlan", mock.Anything, mock.Anything)
+
+	list, err := suite.service.subscriptionPlanChangeRepository.FindBySubscriptionId(context.TODO(), subscription.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), list, 1)
+	assert.Equal(suite.T(), pkg.SubscriptionPlanChangeStatusFailed, list[0].Status)
+}
+
+func (suite *SubscriptionPlanChangeTestSuite) TestSubscriptionPlanChange_ChangeSubscriptionPlan_UpgradePaymentSystemError() {
+	subscription, paymentSystem, order := suite.upgradeSubscriptionPlan(planChangeErrorUnknown)
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemComplete
+	err := suite.service.processSubscriptionPlanChangeCallback(context.TODO(), order)
+	assert.NoError(suite.T(), err)
+
+	paymentSystem.AssertCalled(suite.T(), "UpdateRecurringSubscriptionPlan", mock.Anything, mock.Anything)
+	suite.service.rep.(*recurringMocks.RepositoryService).AssertNotCalled(suite.T(), "UpdateSubscription", mock.Anything, mock.Anything)
+
+	list, err := suite.service.subscriptionPlanChangeRepository.FindBySubscriptionId(context.TODO(), subscription.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), list, 1)
+	assert.Equal(suite.T(), pkg.SubscriptionPlanChangeStatusFailed, list[0].Status)
+}
+
+func (suite *SubscriptionPlanChangeTestSuite) TestSubscriptionPlanChange_ChangeSubscriptionPlan_PaymentSystemError() {
+	subscription := suite.createPaidSubscription(time.Now().AddDate(0, 0, -10))
+
+	paymentSystem := &mocks.PaymentSystemInterface{}
+	paymentSystem.On("UpdateRecurringSubscriptionPlan", mock.Anything, mock.Anything).Return(planChangeErrorUnknown)
+	gatewayManagerMock := &mocks.PaymentSystemManagerInterface{}
+	gatewayManagerMock.On("GetGateway", mock.Anything).Return(paymentSystem, nil)
+	suite.service.paymentSystemGateway = gatewayManagerMock
+
+	req := &pkg.ChangeSubscriptionPlanRequest{
+		Id:         subscription.Id,
+		MerchantId: suite.merchant.Id,
+		Amount:     15.5,
+		Period:     recurringpb.RecurringPeriodMonth,
+	}
+	rsp := &pkg.ChangeSubscriptionPlanResponse{}
+	err := suite.service.ChangeSubscriptionPlan(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusSystemError, rsp.Status)
+	assert.Equal(suite.T(), planChangeErrorPaymentSystem, rsp.Message)
+	paymentSystem.AssertNotCalled(suite.T(), "CreateRefund", mock.Anything, mock.Anything)
+
+	list, err := suite.service.subscriptionPlanChangeRepository.FindBySubscriptionId(context.TODO(), subscription.Id)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), list)
+}
+
+func (suite *SubscriptionPlanChangeTestSuite) TestSubscriptionPlanChange_ChangeSubscriptionPlan_ValidationError() {
+	subscription := suite.createPaidSubscription(time.Now().AddDate(0, 0, -10))
+
+	req := &pkg.ChangeSubscriptionPlanRequest{
+		Id:         subscription.Id,
+		MerchantId: suite.merchant.Id,
+		Amount:     31,
+		Period:     recurringpb.RecurringPeriodMonth,
+	}
+	rsp := &pkg.ChangeSubscriptionPlanResponse{}
+	err := suite.service.ChangeSubscriptionPlan(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), planChangeErrorPlanNotChanged, rsp.Message)
+
+	req.Period = "decade"
+	rsp = &pkg.ChangeSubscriptionPlanResponse{}
+	err = suite.service.ChangeSubscriptionPlan(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), planChangeErrorPlanInvalid, rsp.Message)
+
+	req.Period = recurringpb.RecurringPeriodYear
+	req.MerchantId = primitive.NewObjectID().Hex()
+	rsp = &pkg.ChangeSubscriptionPlanResponse{}
+	err = suite.service.ChangeSubscriptionPlan(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, rsp.Status)
+	assert.Equal(suite.T(), recurringErrorAccessDeny, rsp.Message)
+
+	subscription.IsActive = false
+	req.MerchantId = suite.merchant.Id
+	rsp = &pkg.ChangeSubscriptionPlanResponse{}
+	err = suite.service.ChangeSubscriptionPlan(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), planChangeErrorSubscriptionInactive, rsp.Message)
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/paysuper/paysuper-billing-server/internal/payment_system"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+const (
+	// subscriptionTrialMinPrice is the least trial price in the order currency. The payment system doesn't verify
+	// and tokenize the card by the zero amount payment, so the initial payment of the trial charges at least it.
+	subscriptionTrialMinPrice = float64(1)
+)
+
+// FindSubscriptionTrials returns trials of recurring subscriptions. Response of GetSubscription and FindSubscriptions
+// doesn't contain the trial state, so it's requested for the found subscriptions separately.
+func (s *Service) FindSubscriptionTrials(
+	ctx context.Context,
+	req *pkg.FindSubscriptionTrialsRequest,
+	rsp *pkg.FindSubscriptionTrialsResponse,
+) error {
+	var customerId string
+
+	browserCookie, err := s.findAndParseBrowserCookie(req.Cookie)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusForbidden
+		rsp.Message = recurringCustomerNotFound
+		return nil
+	}
+
+	if browserCookie != nil {
+		customerId = browserCookie.CustomerId
+	}
+
+	if customerId == "" && req.MerchantId == "" {
+		rsp.Status = billingpb.ResponseStatusForbidden
+		rsp.Message = recurringErrorAccessDeny
+		return nil
+	}
+
+	trials, err := s.subscriptionTrialRepository.FindBySubscriptionIds(ctx, req.SubscriptionIds)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = recurringErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Items = []*pkg.SubscriptionTrial{}
+
+	for _, trial := range trials {
+		if (customerId != "" && trial.CustomerId != customerId) ||
+			(req.MerchantId != "" && trial.MerchantId.Hex() != req.MerchantId) {
+			rsp.Status = billingpb.ResponseStatusForbidden
+			rsp.Message = recurringErrorAccessDeny
+			rsp.Items = nil
+			return nil
+		}
+
+		rsp.Items = append(rsp.Items, getSubscriptionTrialMessage(trial))
+	}
+
+	return nil
+}
+
+// ConvertSubscriptionTrials closes trials which ended. Regular payments of the subscription are started by
+// the payment system at the trial end, so the trial of the active subscription is converted to the paid plan and
+// the trial of the subscription deleted or deactivated during the trial is canceled. The card verification charge
+// of the free trial is refunded in both cases.
+func (s *Service) ConvertSubscriptionTrials(ctx context.Context) error {
+	trials, err := s.subscriptionTrialRepository.FindEnded(ctx, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	for _, trial := range trials {
+		rsp, err := s.rep.GetSubscription(ctx, &recurringpb.GetSubscriptionRequest{Id: trial.SubscriptionId})
+
+		if err == nil && rsp.Status == billingpb.ResponseStatusOk && rsp.Subscription != nil &&
+			rsp.Subscription.IsActive {
+			trial.Status = pkg.SubscriptionTrialStatusConverted
+			trial.ConvertedAt = time.Now()
+		} else {
+			trial.Status = pkg.SubscriptionTrialStatusCanceled
+			trial.CanceledAt = time.Now()
+		}
+
+		if err = s.subscriptionTrialRepository.Update(ctx, trial); err != nil {
+			zap.L().Error(
+				"subscription trial conversion failed",
+				zap.Error(err),
+				zap.String("subscription_id", trial.SubscriptionId),
+			)
+			continue
+		}
+
+		s.refundSubscriptionTrialPrice(ctx, trial)
+
+		if trial.Status != pkg.SubscriptionTrialStatusConverted {
+			continue
+		}
+
+		msg := fmt.Sprintf(
+			"Trial of subscription %s converted to paid plan with payments of %.2f %s",
+			trial.SubscriptionId,
+			trial.PlanAmount,
+			trial.Currency,
+		)
+		_, err = s.addNotification(ctx, msg, trial.MerchantId.Hex(), "", nil)
+
+		if err != nil {
+			zap.L().Error(
+				"subscription trial notification sending failed",
+				zap.Error(err),
+				zap.String("subscription_id", trial.SubscriptionId),
+			)
+		}
+	}
+
+	return nil
+}
+
+// applySubscriptionTrial changes amounts of the first payment of the recurring subscription to the trial price.
+// Regular amounts are saved to the order private metadata, the plan of the subscription is created with them.
+// Trial price not set by the merchant defaults to the minimal one.
+func (s *Service) applySubscriptionTrial(order *billingpb.Order) error {
+	if payment_system.GetRecurringTrialDays(order) <= 0 {
+		return nil
+	}
+
+	price := subscriptionTrialMinPrice
+
+	if value, ok := order.PrivateMetadata[pkg.OrderPrivateMetadataTrialPrice]; ok {
+		var err error
+
+		if price, err = strconv.ParseFloat(value, 64); err != nil {
+			return orderErrorRecurringTrialPriceInvalid
+		}
+	}
+
+	if price < subscriptionTrialMinPrice || price >= order.OrderAmount {
+		return orderErrorRecurringTrialPriceInvalid
+	}
+
+	order.PrivateMetadata[pkg.OrderPrivateMetadataTrialRegularAmount] = strconv.FormatFloat(order.OrderAmount, 'f', -1, 64)
+	order.PrivateMetadata[pkg.OrderPrivateMetadataTrialPlanAmount] = strconv.FormatFloat(order.ChargeAmount, 'f', -1, 64)
+
+	ratio := price / order.OrderAmount
+
+	order.OrderAmount = price
+	order.TotalPaymentAmount = s.FormatAmount(order.TotalPaymentAmount*ratio, order.Currency)
+	order.ChargeAmount = s.FormatAmount(order.ChargeAmount*ratio, order.ChargeCurrency)
+
+	if order.Tax != nil {
+		order.Tax.Amount = s.FormatAmount(order.Tax.Amount*ratio, order.Currency)
+	}
+
+	return nil
+}
+
+// restoreSubscriptionTrialAmounts returns the regular order amount changed by the trial of the previous payment
+// attempt, so the order amounts are calculated from the beginning on the new attempt.
+func (s *Service) restoreSubscriptionTrialAmounts(order *billingpb.Order) {
+	value, ok := order.PrivateMetadata[pkg.OrderPrivateMetadataTrialRegularAmount]
+
+	if !ok {
+		return
+	}
+
+	if amount, err := strconv.ParseFloat(value, 64); err == nil {
+		order.OrderAmount = amount
+	}
+
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataTrialRegularAmount)
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataTrialPlanAmount)
+}
+
+// addSubscriptionTrial starts the trial of the recurring subscription created for the order.
+func (s *Service) addSubscriptionTrial(
+	ctx context.Context,
+	order *billingpb.Order,
+	subscription *recurringpb.Subscription,
+) error {
+	days := payment_system.GetRecurringTrialDays(order)
+
+	if days <= 0 {
+		return nil
+	}
+
+	trial := &intPkg.SubscriptionTrial{
+		SubscriptionId: subscription.Id,
+		CustomerId:     subscription.CustomerId,
+		Status:         pkg.SubscriptionTrialStatusActive,
+		TrialDays:      int32(days),
+		TrialPrice:     order.ChargeAmount,
+		IsFree:         order.PrivateMetadata[pkg.OrderPrivateMetadataTrialPrice] == "",
+		PlanAmount:     subscription.Amount,
+		Currency:       subscription.Currency,
+		TrialEndsAt:    time.Now().AddDate(0, 0, days),
+	}
+	trial.OrderId, _ = primitive.ObjectIDFromHex(order.Id)
+	trial.MerchantId, _ = primitive.ObjectIDFromHex(subscription.MerchantId)
+	trial.ProjectId, _ = primitive.ObjectIDFromHex(subscription.ProjectId)
+
+	return s.subscriptionTrialRepository.Insert(ctx, trial)
+}
+
+// cancelSubscriptionTrial cancels the active trial of the subscription deleted by the customer or the merchant.
+func (s *Service) cancelSubscriptionTrial(ctx context.Context, subscriptionId string) {
+	trial, err := s.subscriptionTrialRepository.GetBySubscriptionId(ctx, subscriptionId)
+
+	if err != nil || trial.Status != pkg.SubscriptionTrialStatusActive {
+		return
+	}
+
+	trial.Status = pkg.SubscriptionTrialStatusCanceled
+	trial.CanceledAt = time.Now()
+
+	if err = s.subscriptionTrialRepository.Update(ctx, trial); err != nil {
+		zap.L().Error(
+			"subscription trial cancellation failed",
+			zap.Error(err),
+			zap.String("subscription_id", subscriptionId),
+		)
+		return
+	}
+
+	s.refundSubscriptionTrialPrice(ctx, trial)
+}
+
+// refundSubscriptionTrialPrice refunds the minimal price charged to verify the card of the free trial. Failure is
+// logged only because the trial is already closed.
+func (s *Service) refundSubscriptionTrialPrice(ctx context.Context, trial *intPkg.SubscriptionTrial) {
+	if !trial.IsFree || trial.RefundId != "" {
+		return
+	}
+
+	refundId, err := s.createSubscriptionTrialRefund(ctx, trial)
+
+	if err == nil {
+		trial.RefundId = refundId
+		err = s.subscriptionTrialRepository.Update(ctx, trial)
+	}
+
+	if err != nil {
+		zap.L().Error(
+			"subscription trial price refund failed",
+			zap.Error(err),
+			zap.String("subscription_id", trial.SubscriptionId),
+		)
+	}
+}
+
+// createSubscriptionTrialRefund refunds the whole rest of the trial order, so the repeated refund of the trial is
+// rejected as the refund of the refunded order.
+func (s *Service) createSubscriptionTrialRefund(ctx context.Context, trial *intPkg.SubscriptionTrial) (string, error) {
+	order, err := s.orderRepository.GetById(ctx, trial.OrderId.Hex())
+
+	if err != nil {
+		return "", err
+	}
+
+	processor := &createRefundProcessor{
+		service: s,
+		request: &billingpb.CreateRefundRequest{
+			OrderId:    order.Uuid,
+			MerchantId: trial.MerchantId.Hex(),
+			CreatorId:  primitive.NilObjectID.Hex(),
+			Reason:     fmt.Sprintf("Card verification charge of trial of subscription %s", trial.SubscriptionId),
+		},
+		checked: &createRefundChecked{},
+		ctx:     ctx,
+	}
+	refund, err := processor.processCreateRefund()
+
+	if err != nil {
+		return "", err
+	}
+
+	rsp := &billingpb.CreateRefundResponse{}
+
+	if err = s.sendRefundToPaymentSystem(ctx, processor.checked.order, refund, rsp); err != nil {
+		return "", err
+	}
+
+	if rsp.Status != billingpb.ResponseStatusOk {
+		return "", rsp.Message
+	}
+
+	return refund.Id, nil
+}
+
+func getSubscriptionTrialMessage(trial *intPkg.SubscriptionTrial) *pkg.SubscriptionTrial {
+	return &pkg.SubscriptionTrial{
+		SubscriptionId: trial.SubscriptionId,
+		MerchantId:     trial.MerchantId.Hex(),
+		ProjectId:      trial.ProjectId.Hex(),
+		CustomerId:     trial.CustomerId,
+		Status:         trial.Status,
+		TrialDays:      trial.TrialDays,
+		TrialPrice:     trial.TrialPrice,
+		PlanAmount:     trial.PlanAmount,
+		Currency:       trial.Currency,
+		TrialEndsAt:    getTimestampProto(trial.TrialEndsAt),
+		ConvertedAt:    getTimestampProto(trial.ConvertedAt),
+		CanceledAt:     getTimestampProto(trial.CanceledAt),
+		CreatedAt:      getTimestampProto(trial.CreatedAt),
+	}
+}
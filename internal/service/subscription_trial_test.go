@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	"github.com/paysuper/paysuper-billing-server/internal/payment_system"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	recurringMocks "github.com/paysuper/paysuper-proto/go/recurringpb/mocks"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+	"time"
+)
+
+type SubscriptionTrialTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant      *billingpb.Merchant
+	project       *billingpb.Project
+	paymentMethod *billingpb.PaymentMethod
+	cookie        string
+}
+
+func Test_SubscriptionTrial(t *testing.T) {
+	suite.Run(t, new(SubscriptionTrialTestSuite))
+}
+
+func (suite *SubscriptionTrialTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	var customer *billingpb.Customer
+	suite.merchant, suite.project, suite.paymentMethod, _, customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+
+	suite.cookie, err = suite.service.generateBrowserCookie(&BrowserCookieCustomer{
+		CustomerId: customer.Id,
+		Ip:         "127.0.0.1",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	})
+
+	if err != nil {
+		suite.FailNow("Generate browser cookie failed", "%v", err)
+	}
+}
+
+func (suite *SubscriptionTrialTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *SubscriptionTrialTestSuite) createRecurringOrder(metadata map[string]string) *billingpb.OrderCreateProcessResponse {
+	paymentMethod, _ := suite.service.paymentMethodRepository.GetById(context.TODO(), suite.paymentMethod.Id)
+	paymentMethod.RecurringAllowed = true
+	_ = suite.service.paymentMethodRepository.Update(context.TODO(), paymentMethod)
+
+	req := &billingpb.OrderCreateRequest{
+		Type:          pkg.OrderType_simple,
+		ProjectId:     suite.project.Id,
+		PaymentMethod: paymentMethod.Group,
+		Currency:      "RUB",
+		Amount:        100,
+		Account:       "unit test",
+		Description:   "unit test",
+		User: &billingpb.OrderUser{
+			Email: "test@unit.unit",
+			Ip:    "127.0.0.1",
+		},
+		FormMode:        "standalone",
+		RecurringPeriod: recurringpb.RecurringPeriodMonth,
+		PrivateMetadata: metadata,
+	}
+
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *SubscriptionTrialTestSuite) createTrial(status string, trialEndsAt time.Time) *intPkg.SubscriptionTrial {
+	trial := &intPkg.SubscriptionTrial{
+		SubscriptionId: primitive.NewObjectID().Hex(),
+		OrderId:        primitive.NewObjectID(),
+		CustomerId:     primitive.NewObjectID().Hex(),
+		Status:         status,
+		TrialDays:      14,
+		PlanAmount:     100,
+		Currency:       "RUB",
+		TrialEndsAt:    trialEndsAt,
+	}
+	trial.MerchantId, _ = primitive.ObjectIDFromHex(suite.merchant.Id)
+	trial.ProjectId, _ = primitive.ObjectIDFromHex(suite.project.Id)
+
+	err := suite.service.subscriptionTrialRepository.Insert(context.TODO(), trial)
+	assert.NoError(suite.T(), err)
+
+	return trial
+}
+
+func (suite *SubscriptionTrialTestSuite) TestSubscriptionTrial_OrderCreateProcess_Ok() {
+	rsp := suite.createRecurringOrder(map[string]string{
+		pkg.OrderPrivateMetadataTrialDays:  "14",
+		pkg.OrderPrivateMetadataTrialPrice: "1.99",
+	})
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.Equal(suite.T(), "14", rsp.Item.PrivateMetadata[pkg.OrderPrivateMetadataTrialDays])
+}
+
+func (suite *SubscriptionTrialTestSuite) TestSubscriptionTrial_OrderCreateProcess_TrialDaysInvalid_Error() {
+	for _, days := range []string{"0", "-1", "abc", "400"} {
+		rsp := suite.createRecurringOrder(map[string]string{pkg.OrderPrivateMetadataTrialDays: days})
+		assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+		assert.Equal(suite.T(), orderErrorRecurringTrialDaysInvalid, rsp.Message)
+	}
+}
+
+func (suite *SubscriptionTrialTestSuite) TestSubscriptionTrial_OrderCreateProcess_TrialPriceInvalid_Error() {
+	rsp := suite.createRecurringOrder(map[string]string{
+		pkg.OrderPrivateMetadataTrialDays:  "14",
+		pkg.OrderPrivateMetadataTrialPrice: "-1",
+	})
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), orderErrorRecurringTrialPriceInvalid, rsp.Message)
+}
+
+func (suite *SubscriptionTrialTestSuite) TestSubscriptionTrial_OrderCreateProcess_ZeroTrialPrice_Error() {
+	rsp := suite.createRecurringOrder(map[string]string{
+		pkg.OrderPrivateMetadataTrialDays:  "14",
+		pkg.OrderPrivateMetadataTrialPrice: "0",
+	})
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp.Status)
+	assert.Equal(suite.T(), orderErrorRecurringTrialPriceInvalid, rsp.Message)
+}
+
+func (suite *SubscriptionTrialTestSuite) TestSubscriptionTrial_ApplySubscriptionTrial_MinPriceByDefault() {
+	order := &billingpb.Order{
+		OrderAmount:        100,
+		TotalPaymentAmount: 100,
+		ChargeAmount:       100,
+		Currency:           "RUB",
+		ChargeCurrency:     "RUB",
+		RecurringSettings:  &billingpb.OrderRecurringSettings{Period: recurringpb.RecurringPeriodMonth},
+		PrivateMetadata:    map[string]string{pkg.OrderPrivateMetadataTrialDays: "14"},
+	}
+
+	err := suite.service.applySubscriptionTrial(order)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), subscriptionTrialMinPrice, order.OrderAmount)
+	assert.EqualValues(suite.T(), subscriptionTrialMinPrice, order.ChargeAmount)
+	assert.EqualValues(suite.T(), 100, payment_system.GetRecurringPlanAmount(order))
+
+	suite.service.restoreSubscriptionTrialAmounts(order)
+	order.ChargeAmount = 100
+	order.PrivateMetadata[pkg.OrderPrivateMetadataTrialPrice] = "0"
+	err = suite.service.applySubscriptionTrial(order)
+	assert.Equal(suite.T(), orderErrorRecurringTrialPriceInvalid, err)
+}
+
+func (suite *SubscriptionTrialTestSuite) TestSubscriptionTrial_ApplyAndRestoreAmounts_Ok() {
+	order := &billingpb.Order{
+		OrderAmount:        100,
+		TotalPaymentAmount: 120,
+		ChargeAmount:       120,
+		Currency:           "RUB",
+		ChargeCurrency:     "RUB",
+		Tax:                &billingpb.OrderTax{Amount: 20, Currency: "RUB"},
+		RecurringSettings:  &billingpb.OrderRecurringSettings{Period: recurringpb.RecurringPeriodMonth},
+		PrivateMetadata: map[string]string{
+			pkg.OrderPrivateMetadataTrialDays:  "14",
+			pkg.OrderPrivateMetadataTrialPrice: "10",
+		},
+	}
+
+	err := suite.service.applySubscriptionTrial(order)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 10, order.OrderAmount)
+	assert.EqualValues(suite.T(), 2, order.Tax.Amount)
+	assert.EqualValues(suite.T(), 12, order.TotalPaymentAmount)
+	assert.EqualValues(suite.T(), 12, order.ChargeAmount)
+	assert.EqualValues(suite.T(), 120, payment_system.GetRecurringPlanAmount(order))
+
+	suite.service.restoreSubscriptionTrialAmounts(order)
+	assert.EqualValues(suite.T(), 100, order.OrderAmount)
+	assert.NotContains(suite.T(), order.PrivateMetadata, pkg.OrderPrivateMetadataTrialRegularAmount)
+	assert.NotContains(suite.T(), order.PrivateMetadata, pkg.OrderPrivateMetadataTrialPlanAmount)
+
+	order.PrivateMetadata[pkg.OrderPrivateMetadataTrialPrice] = "100"
+	err = suite.service.applySubscriptionTrial(order)
+	assert.Equal(suite.T(), orderErrorRecurringTrialPriceInvalid, err)
+}
+
+func (suite *SubscriptionTrialTestSuite) TestSubscriptionTrial_ConvertSubscriptionTrials_Ok() {
+	converted := suite.createTrial(pkg.SubscriptionTrialStatusActive, time.Now().Add(-time.Hour))
+	canceled := suite.createTrial(pkg.SubscriptionTrialStatusActive, time.Now().Add(-time.Hour))
+	active := suite.createTrial(pkg.SubscriptionTrialStatusActive, time.Now().Add(time.Hour))
+
+	recurring := &recurringMocks.RepositoryService{}
+	recurring.On("GetSubscription", mock.Anything, mock.MatchedBy(func(req *recurringpb.GetSubscriptionRequest) bool {
+		return req.Id == converted.SubscriptionId
+	})).
+		Return(&recurringpb.GetSubscriptionResponse{
+			Status:       billingpb.ResponseStatusOk,
+			Subscription: &recurringpb.Subscription{Id: converted.SubscriptionId, IsActive: true},
+		}, nil)
+	recurring.On("GetSubscription", mock.Anything, mock.Anything).
+		Return(&recurringpb.GetSubscriptionResponse{
+			Status:       billingpb.ResponseStatusOk,
+			Subscription: &recurringpb.Subscription{Id: canceled.SubscriptionId, IsActive: false},
+		}, nil)
+	suite.service.rep = recurring
+
+	err := suite.service.ConvertSubscriptionTrials(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	trial, err := suite.service.subscriptionTrialRepository.GetBySubscriptionId(context.TODO(), converted.SubscriptionId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SubscriptionTrialStatusConverted, trial.Status)
+	assert.False(suite.T(), trial.ConvertedAt.IsZero())
+
+	trial, err = suite.service.subscriptionTrialRepository.GetBySubscriptionId(context.TODO(), canceled.SubscriptionId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SubscriptionTrialStatusCanceled, trial.Status)
+	assert.False(suite.T(), trial.CanceledAt.IsZero())
+
+	trial, err = suite.service.subscriptionTrialRepository.GetBySubscriptionId(context.TODO(), active.SubscriptionId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SubscriptionTrialStatusActive, trial.Status)
+}
+
+func (suite *SubscriptionTrialTestSuite) TestSubscriptionTrial_ConvertSubscriptionTrials_FreeTrialRefunded() {
+	order := HelperCreateAndPayOrder(suite.Suite, suite.service, 100, "RUB", "RU", suite.project, suite.paymentMethod, suite.cookie)
+
+	trial := suite.createTrial(pkg.SubscriptionTrialStatusActive, time.Now().Add(-time.Hour))
+	trial.OrderId, _ = primitive.ObjectIDFromHex(order.Id)
+	trial.IsFree = true
+	err := suite.service.subscriptionTrialRepository.Update(context.TODO(), trial)
+	assert.NoError(suite.T(), err)
+
+	recurring := &recurringMocks.RepositoryService{}
+	recurring.On("GetSubscription", mock.Anything, mock.Anything).
+		Return(&recurringpb.GetSubscriptionResponse{
+			Status:       billingpb.ResponseStatusOk,
+			Subscription: &recurringpb.Subscription{Id: trial.SubscriptionId, IsActive: true},
+		}, nil)
+	suite.service.rep = recurring
+
+	err = suite.service.ConvertSubscriptionTrials(context.TODO())
+	assert.NoError(suite.T(), err)
+
+	trial, err = suite.service.subscriptionTrialRepository.GetBySubscriptionId(context.TODO(), trial.SubscriptionId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.SubscriptionTrialStatusConverted, trial.Status)
+	assert.NotEmpty(suite.T(), trial.RefundId)
+
+	refund, err := suite.service.refundRepository.GetById(context.TODO(), trial.RefundId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), order.Id, refund.OriginalOrder.Id)
+	assert.Equal(suite.T(), order.ChargeAmount, refund.Amount)
+}
+
+func (suite *SubscriptionTrialTestSuite) TestSubscriptionTrial_FindSubscriptionTrials_Ok() {
+	trial := suite.createTrial(pkg.SubscriptionTrialStatusActive, time.Now().Add(time.Hour))
+
+	req := &pkg.FindSubscriptionTrialsRequest{
+		MerchantId:      suite.merchant.Id,
+		SubscriptionIds: []string{trial.SubscriptionId, primitive.NewObjectID().Hex()},
+	}
+	rsp := &pkg.FindSubscriptionTrialsResponse{}
+	err := suite.service.FindSubscriptionTrials(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+	assert.Len(suite.T(), rsp.Items, 1)
+	assert.Equal(suite.T(), trial.SubscriptionId, rsp.Items[0].SubscriptionId)
+	assert.Equal(suite.T(), pkg.SubscriptionTrialStatusActive, rsp.Items[0].Status)
+}
+
+func (suite *SubscriptionTrialTestSuite) TestSubscriptionTrial_FindSubscriptionTrials_AccessDeny_Error() {
+	trial := suite.createTrial(pkg.SubscriptionTrialStatusActive, time.Now().Add(time.Hour))
+
+	req := &pkg.FindSubscriptionTrialsRequest{SubscriptionIds: []string{trial.SubscriptionId}}
+	rsp := &pkg.FindSubscriptionTrialsResponse{}
+	err := suite.service.FindSubscriptionTrials(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, rsp.Status)
+
+	req.Cookie = suite.cookie
+	rsp = &pkg.FindSubscriptionTrialsResponse{}
+	err = suite.service.FindSubscriptionTrials(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusForbidden, rsp.Status)
+	assert.Equal(suite.T(), recurringErrorAccessDeny, rsp.Message)
+}
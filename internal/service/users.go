@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/protobuf/proto"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	errors2 "github.com/paysuper/paysuper-billing-server/pkg/errors"
 	"github.com/paysuper/paysuper-proto/go/billingpb"
@@ -704,6 +705,7 @@ func (s *Service) ChangeRoleForMerchantUser(ctx context.Context, req *billingpb.
 		return nil
 	}
 
+	before := proto.Clone(user)
 	user.Role = req.Role
 	err = s.userRoleRepository.UpdateMerchantUser(ctx, user)
 
@@ -715,6 +717,15 @@ func (s *Service) ChangeRoleForMerchantUser(ctx context.Context, req *billingpb.
 		return nil
 	}
 
+	// The role is already changed, so the failed audit log doesn't stop the update of the role in casbin
+	auditErr := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "ChangeRoleForMerchantUser",
+		entityType: pkg.AuditLogEntityUserRole,
+		entityId:   user.Id,
+		before:     before,
+		after:      user,
+	})
+
 	if user.UserId != "" {
 		casbinUserId := fmt.Sprintf(pkg.CasbinMerchantUserMask, user.MerchantId, user.UserId)
 
@@ -740,6 +751,12 @@ func (s *Service) ChangeRoleForMerchantUser(ctx context.Context, req *billingpb.
 		}
 	}
 
+	if auditErr != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	res.Status = billingpb.ResponseStatusOk
 
 	return nil
@@ -756,6 +773,7 @@ func (s *Service) ChangeRoleForAdminUser(ctx context.Context, req *billingpb.Cha
 		return nil
 	}
 
+	before := proto.Clone(user)
 	user.Role = req.Role
 	err = s.userRoleRepository.UpdateAdminUser(ctx, user)
 
@@ -768,6 +786,14 @@ func (s *Service) ChangeRoleForAdminUser(ctx context.Context, req *billingpb.Cha
 		return nil
 	}
 
+	auditErr := s.addAuditLog(ctx, &auditLogRecord{
+		rpc:        "ChangeRoleForAdminUser",
+		entityType: pkg.AuditLogEntityUserRole,
+		entityId:   user.Id,
+		before:     before,
+		after:      user,
+	})
+
 	if user.UserId != "" {
 		_, err = s.casbinService.DeleteUser(ctx, &casbinProto.UserRoleRequest{User: user.UserId})
 
@@ -791,6 +817,12 @@ func (s *Service) ChangeRoleForAdminUser(ctx context.Context, req *billingpb.Cha
 		}
 	}
 
+	if auditErr != nil {
+		res.Status = billingpb.ResponseStatusSystemError
+		res.Message = auditLogErrorUnknown
+		return nil
+	}
+
 	res.Status = billingpb.ResponseStatusOk
 
 	return nil
@@ -320,6 +320,36 @@ func (suite *UsersTestSuite) Test_ChangeAdminUserRole_Ok() {
 	shouldBe.EqualValues(billingpb.ResponseStatusOk, res.Status)
 }
 
+func (suite *UsersTestSuite) Test_ChangeRoleForAdminUser_AuditLogError_CasbinUpdated() {
+	shouldBe := require.New(suite.T())
+
+	repository := &mocks.UserRoleRepositoryInterface{}
+	repository.
+		On("GetAdminUserById", mock.Anything, mock.Anything).
+		Return(&billingpb.UserRole{Role: "test", UserId: primitive.NewObjectID().Hex()}, nil)
+	repository.On("UpdateAdminUser", mock.Anything, mock.Anything).Return(nil)
+	suite.service.userRoleRepository = repository
+
+	auditLog := &mocks.AuditLogRepositoryInterface{}
+	auditLog.On("Insert", mock.Anything, mock.Anything).Return(errors.New("error"))
+	suite.service.auditLogRepository = auditLog
+
+	casbin := &casbinMocks.CasbinService{}
+	casbin.On("DeleteUser", mock.Anything, mock.Anything, mock.Anything).Return(&casbinProto.Empty{}, nil)
+	casbin.On("AddRoleForUser", mock.Anything, mock.Anything, mock.Anything).Return(&casbinProto.Empty{}, nil)
+	suite.service.casbinService = casbin
+
+	res := &billingpb.EmptyResponseWithStatus{}
+	err := suite.service.ChangeRoleForAdminUser(context.TODO(), &billingpb.ChangeRoleForAdminUserRequest{
+		RoleId: primitive.NewObjectID().Hex(),
+		Role:   "test_role",
+	}, res)
+	shouldBe.NoError(err)
+	shouldBe.EqualValues(billingpb.ResponseStatusSystemError, res.Status)
+	shouldBe.EqualValues(auditLogErrorUnknown, res.Message)
+	casbin.AssertCalled(suite.T(), "AddRoleForUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func (suite *UsersTestSuite) Test_ChangeRoleForMerchantUser_Error_SetRoleOwner() {
 	shouldBe := require.New(suite.T())
 
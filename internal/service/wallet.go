@@ -0,0 +1,695 @@
+package service
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/paysuper/paysuper-billing-server/internal/helper"
+	intPkg "github.com/paysuper/paysuper-billing-server/internal/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/errors"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	tools "github.com/paysuper/paysuper-tools/number"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	walletErrorUnknown             = errors.NewBillingServerErrorMsg("wl000001", "customer wallet can't be processed. try request later")
+	walletErrorNotFound            = errors.NewBillingServerErrorMsg("wl000002", "customer wallet with specified data not found")
+	walletErrorInsufficientBalance = errors.NewBillingServerErrorMsg("wl000003", "customer wallet balance is less than requested amount")
+	walletErrorCustomerRequired    = errors.NewBillingServerErrorMsg("wl000004", "customer wallet can be used by identified customer only")
+	walletErrorOrderStatusInvalid  = errors.NewBillingServerErrorMsg("wl000005", "customer wallet can't be changed for order in current status")
+	walletErrorAmountExceedsOrder  = errors.NewBillingServerErrorMsg("wl000006", "wallet amount can't be greater than order amount")
+	walletErrorOrderNotApplicable  = errors.NewBillingServerErrorMsg("wl000007", "customer wallet isn't applicable to order")
+)
+
+// GetCustomerWallets returns balances of the customer wallets on the project in all currencies.
+func (s *Service) GetCustomerWallets(
+	ctx context.Context,
+	req *pkg.GetCustomerWalletsRequest,
+	rsp *pkg.CustomerWalletsResponse,
+) error {
+	wallets, err := s.customerWalletRepository.FindByCustomerId(ctx, req.CustomerId, req.ProjectId)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = walletErrorUnknown
+		return nil
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Items = []*pkg.CustomerWallet{}
+
+	for _, wallet := range wallets {
+		rsp.Items = append(rsp.Items, getCustomerWalletMessage(wallet))
+	}
+
+	return nil
+}
+
+// AddWalletCredit tops up the customer wallet with the goodwill credit of the merchant. The credit is paid by
+// the merchant, so the accounting entry of the credit is created on the merchant balance.
+func (s *Service) AddWalletCredit(
+	ctx context.Context,
+	req *pkg.AddWalletCreditRequest,
+	rsp *pkg.CustomerWalletResponse,
+) error {
+	project, err := s.project.GetById(ctx, req.ProjectId)
+
+	if err != nil || project.MerchantId != req.MerchantId {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = projectErrorNotFound
+		return nil
+	}
+
+	if _, err = s.customerRepository.GetById(ctx, req.CustomerId); err != nil {
+		rsp.Status = billingpb.ResponseStatusNotFound
+		rsp.Message = customerNotFound
+		return nil
+	}
+
+	credit := &intPkg.StoreCredit{
+		CustomerId: req.CustomerId,
+		Amount:     req.Amount,
+		Currency:   strings.ToUpper(req.Currency),
+		Source:     pkg.StoreCreditSourceGoodwill,
+		Reason:     req.Reason,
+		CreatorId:  req.CreatorId,
+	}
+	credit.MerchantId, _ = primitive.ObjectIDFromHex(req.MerchantId)
+	credit.ProjectId, _ = primitive.ObjectIDFromHex(req.ProjectId)
+
+	wallet, err := s.topUpCustomerWallet(ctx, credit)
+
+	if err != nil {
+		rsp.Status = billingpb.ResponseStatusSystemError
+		rsp.Message = walletErrorUnknown
+		return nil
+	}
+
+	entryReq := &billingpb.CreateAccountingEntryRequest{
+		Type:       pkg.AccountingEntryTypeWalletGoodwill,
+		MerchantId: req.MerchantId,
+		Amount:     credit.Amount,
+		Currency:   credit.Currency,
+		Status:     pkg.BalanceTransactionStatusAvailable,
+		Date:       time.Now().Unix(),
+		Reason:     req.Reason,
+	}
+	entryRsp := &billingpb.CreateAccountingEntryResponse{}
+	err = s.CreateAccountingEntry(ctx, entryReq, entryRsp)
+
+	if err != nil || entryRsp.Status != billingpb.ResponseStatusOk {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "CreateAccountingEntry"),
+			zap.Error(err),
+			zap.Any("response", entryRsp),
+			zap.String("store_credit_id", credit.Id.Hex()),
+		)
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.Item = getCustomerWalletMessage(wallet)
+
+	return nil
+}
+
+// ApplyWalletToOrder applies the balance of the customer wallet in the order currency to the order on the payment
+// form. The rest of the order amount is charged by the payment system, order fully paid by the wallet is completed
+// at once. Zero amount removes the wallet from the order.
+func (s *Service) ApplyWalletToOrder(
+	ctx context.Context,
+	req *pkg.ApplyWalletRequest,
+	rsp *pkg.ApplyWalletResponse,
+) error {
+	order, err := s.getOrderByUuidToForm(ctx, req.OrderId)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = e
+			return nil
+		}
+		return err
+	}
+
+	// charged wallet pays the payment which is already sent to the payment system
+	if order.PrivateStatus != recurringpb.OrderStatusNew || isOrderPrepaidAmountCharged(order) {
+		rsp.Status = billingpb.ResponseStatusBadData
+		rsp.Message = walletErrorOrderStatusInvalid
+		return nil
+	}
+
+	removed := s.removeOrderWallet(order)
+	amount := s.FormatAmount(req.Amount, order.Currency)
+
+	if amount > 0 {
+		if !helper.IsIdentified(order.GetUser().GetId()) {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = walletErrorCustomerRequired
+			return nil
+		}
+
+		// regular payments of the subscription are charged without the customer, so the wallet can't pay them
+		if order.RecurringSettings != nil {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = walletErrorOrderNotApplicable
+			return nil
+		}
+
+		if amount+getOrderGiftCardAmount(order) > order.TotalPaymentAmount {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = walletErrorAmountExceedsOrder
+			return nil
+		}
+
+		wallet, err := s.customerWalletRepository.GetByCustomerId(ctx, order.User.Id, order.GetProjectId(), order.Currency)
+
+		if err != nil {
+			rsp.Status = billingpb.ResponseStatusNotFound
+			rsp.Message = walletErrorNotFound
+			return nil
+		}
+
+		if wallet.Balance < amount {
+			rsp.Status = billingpb.ResponseStatusBadData
+			rsp.Message = walletErrorInsufficientBalance
+			return nil
+		}
+
+		s.setOrderWallet(order, wallet, amount)
+		s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypeWalletApplied, map[string]string{
+			pkg.OrderHistoryFieldWalletAmount: strconv.FormatFloat(amount, 'f', -1, 64),
+		})
+	} else if removed > 0 {
+		s.addOrderPaymentHistory(ctx, order, pkg.OrderHistoryTypeWalletRemoved, map[string]string{
+			pkg.OrderHistoryFieldWalletAmount: strconv.FormatFloat(removed, 'f', -1, 64),
+		})
+	}
+
+	rsp.IsPaid, err = s.processPrepaidOrder(ctx, order)
+
+	if err != nil {
+		if e, ok := err.(*billingpb.ResponseError); ok {
+			rsp.Status = e.Status
+			rsp.Message = e.Message
+			return nil
+		}
+		return err
+	}
+
+	rsp.Status = billingpb.ResponseStatusOk
+	rsp.WalletAmount = getOrderWalletAmount(order)
+	rsp.Item = s.getPrepaidOrderAmounts(order)
+
+	return nil
+}
+
+// topUpCustomerWallet adds the credit to the customer wallet in the credit currency and saves the credit as
+// the movement of the wallet. The credit with the preset identifier is added to the wallet only once, so the top up
+// failed after the wallet was increased can be repeated with the same identifier.
+func (s *Service) topUpCustomerWallet(ctx context.Context, credit *intPkg.StoreCredit) (*intPkg.CustomerWallet, error) {
+	wallet := &intPkg.CustomerWallet{
+		CustomerId: credit.CustomerId,
+		MerchantId: credit.MerchantId,
+		ProjectId:  credit.ProjectId,
+		Currency:   credit.Currency,
+	}
+
+	if credit.Id.IsZero() {
+		wallet, err := s.customerWalletRepository.Increase(ctx, wallet, credit.Amount)
+
+		if err != nil {
+			return nil, err
+		}
+
+		credit.WalletId = wallet.Id
+
+		if err = s.storeCreditRepository.Insert(ctx, credit); err != nil {
+			return nil, err
+		}
+
+		return wallet, nil
+	}
+
+	wallet, err := s.customerWalletRepository.Increase(ctx, wallet, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.customerWalletRepository.IncreaseOnce(ctx, wallet.Id.Hex(), credit.Id, credit.Amount); err != nil {
+		return nil, err
+	}
+
+	credit.WalletId = wallet.Id
+	err = s.storeCreditRepository.Insert(ctx, credit)
+
+	if err != nil && !mongodb.IsDuplicate(err) {
+		return nil, err
+	}
+
+	return s.customerWalletRepository.GetById(ctx, wallet.Id.Hex())
+}
+
+// processPrepaidOrder recalculates the order amounts after the customer wallet or the gift card is applied to
+// the order on the payment form. Order fully paid by them is completed without the payment system.
+func (s *Service) processPrepaidOrder(ctx context.Context, order *billingpb.Order) (bool, error) {
+	processor := &OrderCreateRequestProcessor{Service: s, ctx: ctx}
+
+	if err := processor.processOrderVat(order); err != nil {
+		zap.S().Errorw(pkg.MethodFinishedWithError, "err", err.Error(), "method", "processOrderVat")
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			return false, errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, e)
+		}
+		return false, err
+	}
+
+	if err := s.setOrderChargeAmountAndCurrency(ctx, order); err != nil {
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			return false, errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, e)
+		}
+		return false, err
+	}
+
+	isPaid := getOrderPrepaidAmount(order) > 0 && order.ChargeAmount <= 0
+	var err error
+
+	if isPaid {
+		err = s.completePrepaidOrder(ctx, order)
+	} else {
+		err = s.updateOrder(ctx, order)
+	}
+
+	if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+		return false, errors.NewBillingServerResponseError(billingpb.ResponseStatusSystemError, e)
+	}
+
+	return isPaid, err
+}
+
+// completePrepaidOrder completes the order fully paid by the customer wallet and the gift card without
+// the payment system.
+func (s *Service) completePrepaidOrder(ctx context.Context, order *billingpb.Order) error {
+	// prepaid amounts are charged by the conditional update of the order first, so only one of the concurrent
+	// requests completes the order
+	if err := s.chargeOrderPrepaidAmounts(ctx, order); err != nil {
+		_ = s.updateOrder(ctx, order)
+		return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, err.(*billingpb.ResponseErrorMessage))
+	}
+
+	// keys are reserved by the payment create, so the key order paid without the payment system reserves them here
+	if order.ProductType == pkg.OrderType_key {
+		processor := &PaymentCreateProcessor{service: s}
+
+		if err := processor.reserveKeysForOrder(ctx, order); err != nil {
+			s.releaseOrderPrepaidAmounts(ctx, order)
+			if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+				return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, e)
+			}
+			return err
+		}
+	}
+
+	if err := s.reserveOrderPromoCode(ctx, order); err != nil {
+		s.releaseOrderPrepaidAmounts(ctx, order)
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			return errors.NewBillingServerResponseError(billingpb.ResponseStatusBadData, e)
+		}
+		return err
+	}
+
+	order.PrivateStatus = recurringpb.OrderStatusPaymentSystemComplete
+	order.PaymentMethodOrderClosedAt = ptypes.TimestampNow()
+	// parts of the order paid by the wallet and the gift card are refunded to them
+	order.IsRefundAllowed = true
+
+	if err := s.updateOrder(ctx, order); err != nil {
+		return err
+	}
+
+	if err := s.paymentSystemPaymentCallbackComplete(ctx, order); err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "paymentSystemPaymentCallbackComplete"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+		)
+	}
+
+	if err := s.onPaymentNotify(ctx, order); err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "onPaymentNotify"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+		)
+	}
+
+	s.sendMailWithReceipt(ctx, order)
+
+	return nil
+}
+
+// chargeOrderPrepaidAmounts spends the wallet and the gift card amounts of the order before the payment system
+// charges the rest of the order. Wallet is returned if the gift card can't be charged.
+func (s *Service) chargeOrderPrepaidAmounts(ctx context.Context, order *billingpb.Order) error {
+	if err := s.chargeOrderWallet(ctx, order); err != nil {
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			return e
+		}
+		return walletErrorInsufficientBalance
+	}
+
+	if err := s.chargeOrderGiftCard(ctx, order); err != nil {
+		s.releaseOrderWallet(ctx, order)
+		if e, ok := err.(*billingpb.ResponseErrorMessage); ok {
+			return e
+		}
+		return giftCardErrorInsufficientBalance
+	}
+
+	return nil
+}
+
+// releaseOrderPrepaidAmounts returns the charged wallet and gift card amounts of the order which payment failed and
+// saves the order without the charge flags.
+func (s *Service) releaseOrderPrepaidAmounts(ctx context.Context, order *billingpb.Order) {
+	s.releaseOrderWallet(ctx, order)
+	s.releaseOrderGiftCard(ctx, order)
+	_ = s.updateOrder(ctx, order)
+}
+
+// validateOrderWallet checks that the customer wallet applied to the order still has the balance to pay the order
+// before the payment.
+func (s *Service) validateOrderWallet(ctx context.Context, order *billingpb.Order) error {
+	amount := getOrderWalletAmount(order)
+
+	if amount <= 0 || order.PrivateMetadata[pkg.OrderPrivateMetadataWalletCharged] != "" {
+		return nil
+	}
+
+	if getOrderPrepaidAmount(order) > order.TotalPaymentAmount {
+		return walletErrorAmountExceedsOrder
+	}
+
+	wallet, err := s.customerWalletRepository.GetById(ctx, order.PrivateMetadata[pkg.OrderPrivateMetadataWalletId])
+
+	if err != nil {
+		return walletErrorNotFound
+	}
+
+	if wallet.Balance < amount {
+		return walletErrorInsufficientBalance
+	}
+
+	return nil
+}
+
+// chargeOrderWallet spends the wallet amount of the order before the payment system charges the rest of the order.
+// Wallet is decreased by the conditional update, so concurrent payments can't spend more than the wallet balance.
+// Charge flag is set by the conditional update of the order before the wallet is decreased, so the repeated or
+// concurrent payment attempt or callback doesn't spend the wallet again.
+func (s *Service) chargeOrderWallet(ctx context.Context, order *billingpb.Order) error {
+	amount := getOrderWalletAmount(order)
+
+	if amount <= 0 || order.PrivateMetadata[pkg.OrderPrivateMetadataWalletCharged] != "" {
+		return nil
+	}
+
+	ok, err := s.setOrderPrivateMetadataFlag(ctx, order, pkg.OrderPrivateMetadataWalletCharged)
+
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return walletErrorOrderStatusInvalid
+	}
+
+	walletId := order.PrivateMetadata[pkg.OrderPrivateMetadataWalletId]
+	wallet, err := s.customerWalletRepository.Decrease(ctx, walletId, amount)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "customerWalletRepository.Decrease"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+			zap.String("wallet_id", walletId),
+		)
+		_, _ = s.unsetOrderPrivateMetadataFlag(ctx, order, pkg.OrderPrivateMetadataWalletCharged)
+		return err
+	}
+
+	credit := &intPkg.StoreCredit{
+		WalletId:   wallet.Id,
+		CustomerId: wallet.CustomerId,
+		MerchantId: wallet.MerchantId,
+		ProjectId:  wallet.ProjectId,
+		Amount:     -amount,
+		Currency:   wallet.Currency,
+		Source:     pkg.StoreCreditSourcePayment,
+		SourceId:   order.Id,
+	}
+
+	if err = s.storeCreditRepository.Insert(ctx, credit); err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "storeCreditRepository.Insert"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+			zap.String("wallet_id", walletId),
+		)
+	}
+
+	return nil
+}
+
+// releaseOrderWallet returns the charged wallet amount to the wallet of the order which payment failed or was
+// declined. Charge flag is removed by the conditional update of the order before the wallet is increased, so only
+// one of the concurrent callbacks returns the amount. Failure is logged only because the order is already rejected.
+func (s *Service) releaseOrderWallet(ctx context.Context, order *billingpb.Order) {
+	amount := getOrderWalletAmount(order)
+
+	if amount <= 0 || order.PrivateMetadata[pkg.OrderPrivateMetadataWalletCharged] == "" {
+		return
+	}
+
+	walletId := order.PrivateMetadata[pkg.OrderPrivateMetadataWalletId]
+	wallet, err := s.customerWalletRepository.GetById(ctx, walletId)
+
+	if err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "customerWalletRepository.GetById"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+			zap.String("wallet_id", walletId),
+		)
+		return
+	}
+
+	ok, err := s.unsetOrderPrivateMetadataFlag(ctx, order, pkg.OrderPrivateMetadataWalletCharged)
+
+	if err != nil {
+		return
+	}
+
+	// amount is already returned by the concurrent callback
+	if !ok {
+		delete(order.PrivateMetadata, pkg.OrderPrivateMetadataWalletCharged)
+		return
+	}
+
+	credit := &intPkg.StoreCredit{
+		CustomerId: wallet.CustomerId,
+		MerchantId: wallet.MerchantId,
+		ProjectId:  wallet.ProjectId,
+		Amount:     amount,
+		Currency:   wallet.Currency,
+		Source:     pkg.StoreCreditSourcePaymentCanceled,
+		SourceId:   order.Id,
+	}
+
+	if _, err = s.topUpCustomerWallet(ctx, credit); err != nil {
+		zap.L().Error(
+			pkg.MethodFinishedWithError,
+			zap.String("method", "topUpCustomerWallet"),
+			zap.Error(err),
+			zap.String("order_id", order.Id),
+			zap.String("wallet_id", walletId),
+		)
+		_, _ = s.setOrderPrivateMetadataFlag(ctx, order, pkg.OrderPrivateMetadataWalletCharged)
+	}
+}
+
+// setOrderPrivateMetadataFlag sets the flag of the order private metadata by the conditional update of the order,
+// so only one of the concurrent requests sets it. Returns false if the flag is already set in the database.
+func (s *Service) setOrderPrivateMetadataFlag(ctx context.Context, order *billingpb.Order, key string) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(order.Id)
+
+	if err != nil {
+		return false, err
+	}
+
+	field := "private_metadata." + key
+	filter := bson.M{"_id": oid, field: bson.M{"$exists": false}}
+	ok, err := s.orderRepository.UpdateOneBy(ctx, filter, bson.M{"$set": bson.M{field: "1"}})
+
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if order.PrivateMetadata == nil {
+		order.PrivateMetadata = make(map[string]string)
+	}
+
+	order.PrivateMetadata[key] = "1"
+
+	return true, nil
+}
+
+// unsetOrderPrivateMetadataFlag removes the flag of the order private metadata by the conditional update of
+// the order, so only one of the concurrent requests removes it. Returns false if the flag isn't set in the database.
+func (s *Service) unsetOrderPrivateMetadataFlag(ctx context.Context, order *billingpb.Order, key string) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(order.Id)
+
+	if err != nil {
+		return false, err
+	}
+
+	field := "private_metadata." + key
+	filter := bson.M{"_id": oid, field: bson.M{"$exists": true}}
+	ok, err := s.orderRepository.UpdateOneBy(ctx, filter, bson.M{"$unset": bson.M{field: ""}})
+
+	if err != nil || !ok {
+		return false, err
+	}
+
+	delete(order.PrivateMetadata, key)
+
+	return true, nil
+}
+
+func (s *Service) setOrderWallet(order *billingpb.Order, wallet *intPkg.CustomerWallet, amount float64) {
+	if order.PrivateMetadata == nil {
+		order.PrivateMetadata = make(map[string]string)
+	}
+
+	order.PrivateMetadata[pkg.OrderPrivateMetadataWalletId] = wallet.Id.Hex()
+	order.PrivateMetadata[pkg.OrderPrivateMetadataWalletAmount] = strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// removeOrderWallet removes the customer wallet from the order and returns the removed wallet amount.
+func (s *Service) removeOrderWallet(order *billingpb.Order) float64 {
+	amount := getOrderWalletAmount(order)
+
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataWalletId)
+	delete(order.PrivateMetadata, pkg.OrderPrivateMetadataWalletAmount)
+
+	return amount
+}
+
+// getOrderWalletAmount returns the amount of the order paid by the customer wallet in the order currency.
+func getOrderWalletAmount(order *billingpb.Order) float64 {
+	value, ok := order.PrivateMetadata[pkg.OrderPrivateMetadataWalletAmount]
+
+	if !ok {
+		return 0
+	}
+
+	amount, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		return 0
+	}
+
+	return amount
+}
+
+// getOrderWalletChargeAmount returns the charged wallet amount of the order in the charge currency. Wallet amount is
+// converted by the rate of the order part charged by the payment system.
+func getOrderWalletChargeAmount(order *billingpb.Order) float64 {
+	amount := getOrderWalletAmount(order)
+
+	if amount <= 0 || order.PrivateMetadata[pkg.OrderPrivateMetadataWalletCharged] == "" {
+		return 0
+	}
+
+	return getOrderPrepaidAmountInChargeCurrency(order, amount)
+}
+
+// getOrderPrepaidChargeAmount returns the charged wallet and gift card amounts of the order in the charge currency.
+func getOrderPrepaidChargeAmount(order *billingpb.Order) float64 {
+	return tools.FormatAmount(getOrderWalletChargeAmount(order) + getOrderGiftCardChargeAmount(order))
+}
+
+// getOrderPrepaidAmountInChargeCurrency converts the prepaid amount of the order to the charge currency by the rate
+// of the order part charged by the payment system.
+func getOrderPrepaidAmountInChargeCurrency(order *billingpb.Order, amount float64) float64 {
+	if order.ChargeCurrency == order.Currency {
+		return amount
+	}
+
+	rest := order.TotalPaymentAmount - getOrderPrepaidAmount(order)
+
+	if rest <= 0 {
+		return amount
+	}
+
+	return tools.FormatAmount(amount * order.ChargeAmount / rest)
+}
+
+// getOrderRefundableAmount returns the amount of the order which can be refunded in the charge currency: the amount
+// charged by the payment system and the amounts paid by the customer wallet and the gift card.
+func getOrderRefundableAmount(order *billingpb.Order) float64 {
+	return tools.FormatAmount(order.ChargeAmount + getOrderPrepaidChargeAmount(order))
+}
+
+// isOrderPrepaidAmountCharged checks that the wallet or the gift card applied to the order is already charged by
+// the payment.
+func isOrderPrepaidAmountCharged(order *billingpb.Order) bool {
+	return order.PrivateMetadata[pkg.OrderPrivateMetadataWalletCharged] != "" ||
+		order.PrivateMetadata[pkg.OrderPrivateMetadataGiftCardCharged] != ""
+}
+
+// getOrderPrepaidAmount returns the amount of the order paid by the customer wallet and the gift card in the order
+// currency.
+func getOrderPrepaidAmount(order *billingpb.Order) float64 {
+	return getOrderWalletAmount(order) + getOrderGiftCardAmount(order)
+}
+
+func getCustomerWalletMessage(wallet *intPkg.CustomerWallet) *pkg.CustomerWallet {
+	return &pkg.CustomerWallet{
+		Id:         wallet.Id.Hex(),
+		CustomerId: wallet.CustomerId,
+		ProjectId:  wallet.ProjectId.Hex(),
+		Currency:   wallet.Currency,
+		Balance:    wallet.Balance,
+		UpdatedAt:  getTimestampProto(wallet.UpdatedAt),
+	}
+}
+
+func (s *Service) getPrepaidOrderAmounts(order *billingpb.Order) *billingpb.ProcessBillingAddressResponseItem {
+	return &billingpb.ProcessBillingAddressResponseItem{
+		HasVat:               order.Tax.Rate > 0,
+		VatRate:              tools.ToPrecise(order.Tax.Rate),
+		Vat:                  order.Tax.Amount,
+		VatInChargeCurrency:  s.FormatAmount(order.GetTaxAmountInChargeCurrency(), order.Currency),
+		Amount:               order.OrderAmount,
+		TotalAmount:          order.TotalPaymentAmount,
+		Currency:             order.Currency,
+		ChargeCurrency:       order.ChargeCurrency,
+		ChargeAmount:         order.ChargeAmount,
+		Items:                order.Items,
+		CountryChangeAllowed: order.CountryChangeAllowed(),
+	}
+}
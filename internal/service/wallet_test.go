@@ -0,0 +1,463 @@
+package service
+
+import (
+	"context"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/google/uuid"
+	"github.com/paysuper/paysuper-billing-server/internal/config"
+	"github.com/paysuper/paysuper-billing-server/internal/database"
+	"github.com/paysuper/paysuper-billing-server/internal/mocks"
+	"github.com/paysuper/paysuper-billing-server/internal/repository"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+	casbinMocks "github.com/paysuper/paysuper-proto/go/casbinpb/mocks"
+	"github.com/paysuper/paysuper-proto/go/recurringpb"
+	reportingMocks "github.com/paysuper/paysuper-proto/go/reporterpb/mocks"
+	tools "github.com/paysuper/paysuper-tools/number"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mongodb "gopkg.in/paysuper/paysuper-database-mongo.v2"
+	"testing"
+)
+
+type WalletTestSuite struct {
+	suite.Suite
+	service *Service
+	cache   database.CacheInterface
+
+	merchant *billingpb.Merchant
+	project  *billingpb.Project
+	customer *billingpb.Customer
+}
+
+func Test_Wallet(t *testing.T) {
+	suite.Run(t, new(WalletTestSuite))
+}
+
+func (suite *WalletTestSuite) SetupTest() {
+	cfg, err := config.NewConfig()
+
+	if err != nil {
+		suite.FailNow("Config load failed", "%v", err)
+	}
+
+	m, err := migrate.New("file://../../migrations/tests", cfg.MongoDsn)
+
+	if err != nil {
+		suite.FailNow("Migrate init failed", "%v", err)
+	}
+
+	err = m.Up()
+
+	if err != nil && err.Error() != "no change" {
+		suite.FailNow("Migrations failed", "%v", err)
+	}
+
+	db, err := mongodb.NewDatabase()
+
+	if err != nil {
+		suite.FailNow("Database connection failed", "%v", err)
+	}
+
+	redisdb := mocks.NewTestRedis()
+	suite.cache, err = database.NewCacheRedis(redisdb, "cache")
+
+	if err != nil {
+		suite.FailNow("Cache redis initialize failed", "%v", err)
+	}
+
+	suite.service = NewBillingService(
+		db,
+		cfg,
+		mocks.NewGeoIpServiceTestOk(),
+		mocks.NewRepositoryServiceOk(),
+		mocks.NewTaxServiceOkMock(),
+		mocks.NewBrokerMockOk(),
+		redisdb,
+		suite.cache,
+		mocks.NewCurrencyServiceMockOk(),
+		mocks.NewDocumentSignerMockOk(),
+		&reportingMocks.ReporterService{},
+		mocks.NewFormatterOK(),
+		mocks.NewBrokerMockOk(),
+		&casbinMocks.CasbinService{},
+		mocks.NewNotifierOk(),
+		mocks.NewBrokerMockOk(),
+	)
+
+	if err := suite.service.Init(); err != nil {
+		suite.FailNow("Billing service initialization failed", "%v", err)
+	}
+
+	centrifugoMock := &mocks.CentrifugoInterface{}
+	centrifugoMock.On("GetChannelToken", mock.Anything, mock.Anything).Return("token")
+	centrifugoMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.service.centrifugoDashboard = centrifugoMock
+	suite.service.centrifugoPaymentForm = centrifugoMock
+
+	suite.merchant, suite.project, _, _, suite.customer = HelperCreateEntitiesForTests(suite.Suite, suite.service)
+}
+
+func (suite *WalletTestSuite) TearDownTest() {
+	err := suite.service.db.Drop()
+
+	if err != nil {
+		suite.FailNow("Database deletion failed", "%v", err)
+	}
+
+	err = suite.service.db.Close()
+
+	if err != nil {
+		suite.FailNow("Database close failed", "%v", err)
+	}
+}
+
+func (suite *WalletTestSuite) createOrder() *billingpb.Order {
+	req := &billingpb.OrderCreateRequest{
+		Type:        pkg.OrderType_simple,
+		ProjectId:   suite.project.Id,
+		Amount:      100,
+		Currency:    "RUB",
+		Account:     "unit test",
+		Description: "unit test",
+		User: &billingpb.OrderUser{
+			Id:    suite.customer.Id,
+			Uuid:  uuid.New().String(),
+			Email: "test@unit.unit",
+			Ip:    "127.0.0.1",
+			Address: &billingpb.OrderBillingAddress{
+				Country: "RU",
+			},
+		},
+	}
+
+	rsp := &billingpb.OrderCreateProcessResponse{}
+	err := suite.service.OrderCreateProcess(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp.Status)
+
+	return rsp.Item
+}
+
+func (suite *WalletTestSuite) addWalletCredit(amount float64) *pkg.CustomerWalletResponse {
+	req := &pkg.AddWalletCreditRequest{
+		MerchantId: suite.merchant.Id,
+		ProjectId:  suite.project.Id,
+		CustomerId: suite.customer.Id,
+		CreatorId:  suite.merchant.Id,
+		Amount:     amount,
+		Currency:   "RUB",
+		Reason:     "unit test",
+	}
+	rsp := &pkg.CustomerWalletResponse{}
+	err := suite.service.AddWalletCredit(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *WalletTestSuite) applyWallet(order *billingpb.Order, amount float64) *pkg.ApplyWalletResponse {
+	req := &pkg.ApplyWalletRequest{OrderId: order.Uuid, Amount: amount}
+	rsp := &pkg.ApplyWalletResponse{}
+	err := suite.service.ApplyWalletToOrder(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+
+	return rsp
+}
+
+func (suite *WalletTestSuite) TestWallet_AddWalletCredit_Ok() {
+	rsp := suite.addWalletCredit(30)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.EqualValues(suite.T(), 30, rsp.Item.Balance)
+	assert.Equal(suite.T(), "RUB", rsp.Item.Currency)
+
+	rsp = suite.addWalletCredit(20)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+	assert.EqualValues(suite.T(), 50, rsp.Item.Balance)
+
+	rsp1 := &pkg.CustomerWalletsResponse{}
+	err := suite.service.GetCustomerWallets(
+		context.TODO(),
+		&pkg.GetCustomerWalletsRequest{CustomerId: suite.customer.Id, ProjectId: suite.project.Id},
+		rsp1,
+	)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusOk, rsp1.Status)
+	assert.Len(suite.T(), rsp1.Items, 1)
+
+	credits, err := suite.service.storeCreditRepository.FindByCustomerId(context.TODO(), suite.customer.Id, suite.project.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), credits, 2)
+	assert.Equal(suite.T(), pkg.StoreCreditSourceGoodwill, credits[0].Source)
+	assert.Equal(suite.T(), rsp.Item.Id, credits[0].WalletId.Hex())
+}
+
+func (suite *WalletTestSuite) TestWallet_AddWalletCredit_ProjectNotFound_Error() {
+	req := &pkg.AddWalletCreditRequest{
+		MerchantId: suite.customer.Id,
+		ProjectId:  suite.project.Id,
+		CustomerId: suite.customer.Id,
+		Amount:     10,
+		Currency:   "RUB",
+	}
+	rsp := &pkg.CustomerWalletResponse{}
+	err := suite.service.AddWalletCredit(context.TODO(), req, rsp)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), billingpb.ResponseStatusNotFound, rsp.Status)
+	assert.Equal(suite.T(), projectErrorNotFound, rsp.Message)
+}
+
+func (suite *WalletTestSuite) TestWallet_ApplyWalletToOrder_Ok() {
+	rsp := suite.addWalletCredit(30)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	order := suite.createOrder()
+
+	rsp1 := suite.applyWallet(order, 30)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+	assert.EqualValues(suite.T(), 30, rsp1.WalletAmount)
+	assert.False(suite.T(), rsp1.IsPaid)
+	assert.EqualValues(suite.T(), rsp1.Item.TotalAmount-30, rsp1.Item.ChargeAmount)
+
+	order, err := suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), rsp.Item.Id, order.PrivateMetadata[pkg.OrderPrivateMetadataWalletId])
+	assert.Equal(suite.T(), "30", order.PrivateMetadata[pkg.OrderPrivateMetadataWalletAmount])
+	assert.NoError(suite.T(), suite.service.validateOrderWallet(context.TODO(), order))
+
+	rsp1 = suite.applyWallet(order, 0)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+	assert.Zero(suite.T(), rsp1.WalletAmount)
+	assert.Equal(suite.T(), rsp1.Item.TotalAmount, rsp1.Item.ChargeAmount)
+
+	history, err := suite.service.orderHistoryRepository.FindByOrderId(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), history, 2)
+	assert.Equal(suite.T(), pkg.OrderHistoryTypeWalletApplied, history[0].Type)
+	assert.Equal(suite.T(), pkg.OrderHistoryTypeWalletRemoved, history[1].Type)
+}
+
+func (suite *WalletTestSuite) TestWallet_ApplyWalletToOrder_InsufficientBalance_Error() {
+	rsp := suite.addWalletCredit(10)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	order := suite.createOrder()
+
+	rsp1 := suite.applyWallet(order, 30)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp1.Status)
+	assert.Equal(suite.T(), walletErrorInsufficientBalance, rsp1.Message)
+
+	rsp1 = suite.applyWallet(order, order.TotalPaymentAmount+1)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp1.Status)
+	assert.Equal(suite.T(), walletErrorAmountExceedsOrder, rsp1.Message)
+}
+
+func (suite *WalletTestSuite) TestWallet_ApplyWalletToOrder_FullyPaid_Ok() {
+	order := suite.createOrder()
+
+	// order amounts are recalculated with the tax on the payment form
+	rsp1 := suite.applyWallet(order, 0)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+	total := rsp1.Item.TotalAmount
+
+	rsp := suite.addWalletCredit(total)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	rsp1 = suite.applyWallet(order, total)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+	assert.True(suite.T(), rsp1.IsPaid)
+	assert.Zero(suite.T(), rsp1.Item.ChargeAmount)
+
+	order, err := suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), recurringpb.OrderStatusPaymentSystemComplete, order.PrivateStatus)
+	assert.NotEmpty(suite.T(), order.PrivateMetadata[pkg.OrderPrivateMetadataWalletCharged])
+
+	wallet, err := suite.service.customerWalletRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), wallet.Balance)
+
+	// repeated charge of the paid order doesn't spend the wallet again
+	assert.NoError(suite.T(), suite.service.chargeOrderWallet(context.TODO(), order))
+	wallet, err = suite.service.customerWalletRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), wallet.Balance)
+}
+
+func (suite *WalletTestSuite) TestWallet_OrderView_WalletRevenue_Ok() {
+	order := suite.createOrder()
+
+	rsp1 := suite.applyWallet(order, 0)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+	total := rsp1.Item.TotalAmount
+
+	rsp := suite.addWalletCredit(total)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	rsp1 = suite.applyWallet(order, total)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+	assert.True(suite.T(), rsp1.IsPaid)
+
+	entries, err := suite.service.accountingRepository.FindBySource(context.TODO(), order.Id, repository.CollectionOrder)
+	assert.NoError(suite.T(), err)
+
+	amounts := make(map[string]*billingpb.AccountingEntry)
+
+	for _, entry := range entries {
+		amounts[entry.Type] = entry
+	}
+
+	payment, ok := amounts[pkg.AccountingEntryTypeWalletPayment]
+	assert.True(suite.T(), ok)
+	// merchant entries are posted for the order fully paid by the wallet
+	taxFee, ok := amounts[pkg.AccountingEntryTypeMerchantTaxFeeCostValue]
+	assert.True(suite.T(), ok)
+	taxFeeFx, ok := amounts[pkg.AccountingEntryTypeMerchantTaxFeeCentralBankFx]
+	assert.True(suite.T(), ok)
+
+	err = suite.service.updateOrderView(context.TODO(), []string{order.Id})
+	assert.NoError(suite.T(), err)
+
+	view, err := suite.service.orderViewRepository.GetPrivateOrderBy(context.TODO(), order.Id, "", "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), payment.Currency, view.MerchantPayoutCurrency)
+	assert.Equal(suite.T(), tools.ToPrecise(payment.Amount), tools.ToPrecise(view.GrossRevenue.Amount))
+	assert.Equal(suite.T(), tools.ToPrecise(taxFee.Amount+taxFeeFx.Amount), tools.ToPrecise(view.TaxFeeTotal.Amount))
+	assert.Equal(suite.T(), tools.ToPrecise(payment.Amount-taxFee.Amount-taxFeeFx.Amount), tools.ToPrecise(view.NetRevenue.Amount))
+	assert.Equal(suite.T(), payment.Currency, view.NetRevenue.Currency)
+}
+
+func (suite *WalletTestSuite) TestWallet_ReleaseOrderWallet_Ok() {
+	rsp := suite.addWalletCredit(30)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	order := suite.createOrder()
+
+	rsp1 := suite.applyWallet(order, 30)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+
+	order, err := suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+
+	// wallet is charged before the payment system charges the rest of the order
+	assert.NoError(suite.T(), suite.service.chargeOrderWallet(context.TODO(), order))
+	assert.NoError(suite.T(), suite.service.updateOrder(context.TODO(), order))
+
+	wallet, err := suite.service.customerWalletRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), wallet.Balance)
+
+	// second payment can't spend the charged balance
+	order1 := suite.createOrder()
+	rsp1 = suite.applyWallet(order1, 30)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp1.Status)
+	assert.Equal(suite.T(), walletErrorInsufficientBalance, rsp1.Message)
+
+	// charged wallet can't be changed on the payment form
+	rsp1 = suite.applyWallet(order, 0)
+	assert.Equal(suite.T(), billingpb.ResponseStatusBadData, rsp1.Status)
+	assert.Equal(suite.T(), walletErrorOrderStatusInvalid, rsp1.Message)
+
+	// declined payment returns the wallet amount
+	suite.service.releaseOrderWallet(context.TODO(), order)
+	assert.Empty(suite.T(), order.PrivateMetadata[pkg.OrderPrivateMetadataWalletCharged])
+
+	wallet, err = suite.service.customerWalletRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 30, wallet.Balance)
+
+	credits, err := suite.service.storeCreditRepository.FindByCustomerId(context.TODO(), suite.customer.Id, suite.project.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), credits, 3)
+	assert.Equal(suite.T(), pkg.StoreCreditSourcePayment, credits[1].Source)
+	assert.EqualValues(suite.T(), -30, credits[1].Amount)
+	assert.Equal(suite.T(), pkg.StoreCreditSourcePaymentCanceled, credits[2].Source)
+	assert.EqualValues(suite.T(), 30, credits[2].Amount)
+
+	// repeated release doesn't return the amount again
+	suite.service.releaseOrderWallet(context.TODO(), order)
+	wallet, err = suite.service.customerWalletRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 30, wallet.Balance)
+}
+
+func (suite *WalletTestSuite) TestWallet_ChargeAndReleaseOrderWallet_Concurrent_Once() {
+	rsp := suite.addWalletCredit(30)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	order := suite.createOrder()
+
+	rsp1 := suite.applyWallet(order, 30)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+
+	order1, err := suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	order2, err := suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+
+	// concurrent payment read the order before the wallet was charged
+	assert.NoError(suite.T(), suite.service.chargeOrderWallet(context.TODO(), order1))
+	assert.Equal(suite.T(), walletErrorOrderStatusInvalid, suite.service.chargeOrderWallet(context.TODO(), order2))
+
+	wallet, err := suite.service.customerWalletRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), wallet.Balance)
+
+	order1, err = suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	order2, err = suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), order2.PrivateMetadata[pkg.OrderPrivateMetadataWalletCharged])
+
+	// concurrent callbacks of the declined payment return the wallet amount once
+	suite.service.releaseOrderWallet(context.TODO(), order1)
+	suite.service.releaseOrderWallet(context.TODO(), order2)
+	assert.Empty(suite.T(), order2.PrivateMetadata[pkg.OrderPrivateMetadataWalletCharged])
+
+	wallet, err = suite.service.customerWalletRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 30, wallet.Balance)
+}
+
+func (suite *WalletTestSuite) TestWallet_CreateRefund_FullyPaidOrder_Ok() {
+	order := suite.createOrder()
+
+	rsp1 := suite.applyWallet(order, 0)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+	total := rsp1.Item.TotalAmount
+
+	rsp := suite.addWalletCredit(total)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp.Status, "%v", rsp.Message)
+
+	rsp1 = suite.applyWallet(order, total)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp1.Status, "%v", rsp1.Message)
+	assert.True(suite.T(), rsp1.IsPaid)
+
+	req := &billingpb.CreateRefundRequest{
+		OrderId:    order.Uuid,
+		CreatorId:  suite.merchant.Id,
+		Reason:     "unit test",
+		MerchantId: suite.merchant.Id,
+	}
+	rsp2 := &billingpb.CreateRefundResponse{}
+	err := suite.service.CreateRefund(context.TODO(), req, rsp2)
+	assert.NoError(suite.T(), err)
+	assert.Equalf(suite.T(), billingpb.ResponseStatusOk, rsp2.Status, "%v", rsp2.Message)
+	assert.Equal(suite.T(), pkg.RefundStatusCompleted, rsp2.Item.Status)
+	assert.Equal(suite.T(), total, rsp2.Item.Amount)
+	assert.NotEmpty(suite.T(), rsp2.Item.CreatedOrderId)
+
+	wallet, err := suite.service.customerWalletRepository.GetById(context.TODO(), rsp.Item.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), total, wallet.Balance)
+
+	destination, err := suite.service.getRefundDestination(context.TODO(), rsp2.Item)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), pkg.RefundDestinationWallet, destination)
+
+	order, err = suite.service.getOrderById(context.TODO(), order.Id)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), recurringpb.OrderStatusRefund, order.PrivateStatus)
+	assert.True(suite.T(), order.Refunded)
+}
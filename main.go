@@ -15,6 +15,10 @@ func main() {
 	date := app.CliArgs.Get("date").String("")
 	orderId := app.CliArgs.Get("orderid").String("")
 	force := strings.ToLower(app.CliArgs.Get("force").String("")) == "true"
+	dateFrom := app.CliArgs.Get("from").String("")
+	dateTo := app.CliArgs.Get("to").String("")
+	format := app.CliArgs.Get("format").String("")
+	output := app.CliArgs.Get("output").String("")
 
 	if task != "" {
 
@@ -63,9 +67,45 @@ func main() {
 			err = app.TaskRebuildAccountingEntries(orderId, force)
 			break
 
+		case "export_accounting_entries":
+			err = app.TaskExportAccountingEntries(dateFrom, dateTo, format, output)
+			break
+
 		case "fix_reports_dates":
 			err = app.TaskFixReportDates()
 			break
+
+		case "expire_disputes":
+			err = app.TaskExpireDisputes()
+			break
+
+		case "expire_held_orders":
+			err = app.TaskExpireHeldOrders()
+			break
+
+		case "convert_subscription_trials":
+			err = app.TaskConvertSubscriptionTrials()
+			break
+
+		case "process_subscription_dunning":
+			err = app.TaskProcessSubscriptionDunning()
+			break
+
+		case "resume_subscriptions":
+			err = app.TaskResumeSubscriptions()
+			break
+
+		case "notify_expiring_cards":
+			err = app.TaskNotifyExpiringSavedCards()
+			break
+
+		case "expire_gift_cards":
+			err = app.TaskExpireGiftCards()
+			break
+
+		case "chain_audit_log":
+			err = app.TaskChainAuditLog()
+			break
 		}
 
 		if err != nil {
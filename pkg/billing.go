@@ -0,0 +1,2139 @@
+package pkg
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/paysuper/paysuper-proto/go/billingpb"
+)
+
+// Messages of the billing server methods which are missing in billingpb. Fields are tagged the same way as
+// generated messages, so the messages can be sent with protobuf and json codecs.
+
+type CaptureOrderRequest struct {
+	// The unique identifier for the order.
+	OrderId string `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id" validate:"required,uuid"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The amount to capture in the charge currency. The full authorized amount is captured if the amount is zero.
+	Amount float64 `protobuf:"fixed64,3,opt,name=amount,proto3" json:"amount" validate:"omitempty,numeric,gte=0"`
+}
+
+func (m *CaptureOrderRequest) Reset()         { *m = CaptureOrderRequest{} }
+func (m *CaptureOrderRequest) String() string { return proto.CompactTextString(m) }
+func (*CaptureOrderRequest) ProtoMessage()    {}
+
+type VoidOrderRequest struct {
+	// The unique identifier for the order.
+	OrderId string `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id" validate:"required,uuid"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+}
+
+func (m *VoidOrderRequest) Reset()         { *m = VoidOrderRequest{} }
+func (m *VoidOrderRequest) String() string { return proto.CompactTextString(m) }
+func (*VoidOrderRequest) ProtoMessage()    {}
+
+type OrderPaymentResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *billingpb.Order                `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *OrderPaymentResponse) Reset()         { *m = OrderPaymentResponse{} }
+func (m *OrderPaymentResponse) String() string { return proto.CompactTextString(m) }
+func (*OrderPaymentResponse) ProtoMessage()    {}
+
+func (m *OrderPaymentResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ProjectCaptureSettings struct {
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id"`
+	// Has a value of true if payments of the project orders are only authorized and must be captured by the separate request.
+	ManualCapture bool `protobuf:"varint,2,opt,name=manual_capture,json=manualCapture,proto3" json:"manual_capture"`
+	// The date of the settings last update.
+	UpdatedAt *timestamp.Timestamp `protobuf:"bytes,3,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at"`
+}
+
+func (m *ProjectCaptureSettings) Reset()         { *m = ProjectCaptureSettings{} }
+func (m *ProjectCaptureSettings) String() string { return proto.CompactTextString(m) }
+func (*ProjectCaptureSettings) ProtoMessage()    {}
+
+type SetProjectCaptureSettingsRequest struct {
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// Has a value of true if payments of the project orders must be only authorized.
+	ManualCapture bool `protobuf:"varint,3,opt,name=manual_capture,json=manualCapture,proto3" json:"manual_capture"`
+}
+
+func (m *SetProjectCaptureSettingsRequest) Reset()         { *m = SetProjectCaptureSettingsRequest{} }
+func (m *SetProjectCaptureSettingsRequest) String() string { return proto.CompactTextString(m) }
+func (*SetProjectCaptureSettingsRequest) ProtoMessage()    {}
+
+type GetProjectCaptureSettingsRequest struct {
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+}
+
+func (m *GetProjectCaptureSettingsRequest) Reset()         { *m = GetProjectCaptureSettingsRequest{} }
+func (m *GetProjectCaptureSettingsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetProjectCaptureSettingsRequest) ProtoMessage()    {}
+
+type ProjectCaptureSettingsResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *ProjectCaptureSettings         `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *ProjectCaptureSettingsResponse) Reset()         { *m = ProjectCaptureSettingsResponse{} }
+func (m *ProjectCaptureSettingsResponse) String() string { return proto.CompactTextString(m) }
+func (*ProjectCaptureSettingsResponse) ProtoMessage()    {}
+
+func (m *ProjectCaptureSettingsResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type DisputeEvidence struct {
+	// The unique identifier for the evidence document.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The document name.
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name"`
+	// The URL to download the document.
+	Url string `protobuf:"bytes,3,opt,name=url,proto3" json:"url"`
+	// The document description.
+	Description string `protobuf:"bytes,4,opt,name=description,proto3" json:"description"`
+	// The date of the document upload.
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at"`
+}
+
+func (m *DisputeEvidence) Reset()         { *m = DisputeEvidence{} }
+func (m *DisputeEvidence) String() string { return proto.CompactTextString(m) }
+func (*DisputeEvidence) ProtoMessage()    {}
+
+type Dispute struct {
+	// The unique identifier for the dispute.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The unique identifier for the disputed order.
+	OrderId string `protobuf:"bytes,2,opt,name=order_id,json=orderId,proto3" json:"order_id"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,3,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,4,opt,name=project_id,json=projectId,proto3" json:"project_id"`
+	// The dispute status. Available values: open, evidence_submitted, won, lost.
+	Status string `protobuf:"bytes,5,opt,name=status,proto3" json:"status"`
+	// The reason code of the chargeback assigned by the card network.
+	ReasonCode string `protobuf:"bytes,6,opt,name=reason_code,json=reasonCode,proto3" json:"reason_code"`
+	// The reason description of the chargeback.
+	Reason string `protobuf:"bytes,7,opt,name=reason,proto3" json:"reason"`
+	// The disputed amount.
+	Amount float64 `protobuf:"fixed64,8,opt,name=amount,proto3" json:"amount"`
+	// The disputed amount currency. Three-letter Currency Code ISO 4217, in uppercase.
+	Currency string `protobuf:"bytes,9,opt,name=currency,proto3" json:"currency"`
+	// The last date to submit evidence of the dispute.
+	EvidenceDueAt *timestamp.Timestamp `protobuf:"bytes,10,opt,name=evidence_due_at,json=evidenceDueAt,proto3" json:"evidence_due_at"`
+	// The list of evidence documents.
+	Evidence []*DisputeEvidence `protobuf:"bytes,11,rep,name=evidence,proto3" json:"evidence"`
+	// The date of the representment submission.
+	RepresentmentSubmittedAt *timestamp.Timestamp `protobuf:"bytes,12,opt,name=representment_submitted_at,json=representmentSubmittedAt,proto3" json:"representment_submitted_at"`
+	// The unique identifier for the chargeback refund created by the lost dispute.
+	ChargebackId string `protobuf:"bytes,13,opt,name=chargeback_id,json=chargebackId,proto3" json:"chargeback_id"`
+	// The date of the dispute creation.
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,14,opt,name=created_at,json=createdAt,proto3" json:"created_at"`
+	// The date of the dispute last update.
+	UpdatedAt *timestamp.Timestamp `protobuf:"bytes,15,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at"`
+	// The date of the dispute closing.
+	ClosedAt *timestamp.Timestamp `protobuf:"bytes,16,opt,name=closed_at,json=closedAt,proto3" json:"closed_at"`
+}
+
+func (m *Dispute) Reset()         { *m = Dispute{} }
+func (m *Dispute) String() string { return proto.CompactTextString(m) }
+func (*Dispute) ProtoMessage()    {}
+
+type CreateDisputeRequest struct {
+	// The unique identifier for the disputed order.
+	OrderId string `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id" validate:"required,uuid"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The reason code of the chargeback assigned by the card network.
+	ReasonCode string `protobuf:"bytes,3,opt,name=reason_code,json=reasonCode,proto3" json:"reason_code" validate:"required"`
+	// The reason description of the chargeback.
+	Reason string `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason" validate:"omitempty,max=255"`
+	// The last date to submit evidence of the dispute. The default evidence period is used if the date isn't set.
+	EvidenceDueAt *timestamp.Timestamp `protobuf:"bytes,5,opt,name=evidence_due_at,json=evidenceDueAt,proto3" json:"evidence_due_at"`
+}
+
+func (m *CreateDisputeRequest) Reset()         { *m = CreateDisputeRequest{} }
+func (m *CreateDisputeRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateDisputeRequest) ProtoMessage()    {}
+
+type AddDisputeEvidenceRequest struct {
+	// The unique identifier for the dispute.
+	DisputeId string `protobuf:"bytes,1,opt,name=dispute_id,json=disputeId,proto3" json:"dispute_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The document name.
+	Name string `protobuf:"bytes,3,opt,name=name,proto3" json:"name" validate:"required,max=255"`
+	// The URL to download the document.
+	Url string `protobuf:"bytes,4,opt,name=url,proto3" json:"url" validate:"required,url"`
+	// The document description.
+	Description string `protobuf:"bytes,5,opt,name=description,proto3" json:"description" validate:"omitempty,max=1024"`
+}
+
+func (m *AddDisputeEvidenceRequest) Reset()         { *m = AddDisputeEvidenceRequest{} }
+func (m *AddDisputeEvidenceRequest) String() string { return proto.CompactTextString(m) }
+func (*AddDisputeEvidenceRequest) ProtoMessage()    {}
+
+type SubmitDisputeRepresentmentRequest struct {
+	// The unique identifier for the dispute.
+	DisputeId string `protobuf:"bytes,1,opt,name=dispute_id,json=disputeId,proto3" json:"dispute_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+}
+
+func (m *SubmitDisputeRepresentmentRequest) Reset()         { *m = SubmitDisputeRepresentmentRequest{} }
+func (m *SubmitDisputeRepresentmentRequest) String() string { return proto.CompactTextString(m) }
+func (*SubmitDisputeRepresentmentRequest) ProtoMessage()    {}
+
+type SetDisputeOutcomeRequest struct {
+	// The unique identifier for the dispute.
+	DisputeId string `protobuf:"bytes,1,opt,name=dispute_id,json=disputeId,proto3" json:"dispute_id" validate:"required,hexadecimal,len=24"`
+	// The final outcome of the dispute. Available values: won, lost.
+	Outcome string `protobuf:"bytes,2,opt,name=outcome,proto3" json:"outcome" validate:"required,oneof=won lost"`
+}
+
+func (m *SetDisputeOutcomeRequest) Reset()         { *m = SetDisputeOutcomeRequest{} }
+func (m *SetDisputeOutcomeRequest) String() string { return proto.CompactTextString(m) }
+func (*SetDisputeOutcomeRequest) ProtoMessage()    {}
+
+type GetDisputeRequest struct {
+	// The unique identifier for the dispute.
+	DisputeId string `protobuf:"bytes,1,opt,name=dispute_id,json=disputeId,proto3" json:"dispute_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the merchant. The dispute of any merchant is returned if the identifier is empty.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"omitempty,hexadecimal,len=24"`
+}
+
+func (m *GetDisputeRequest) Reset()         { *m = GetDisputeRequest{} }
+func (m *GetDisputeRequest) String() string { return proto.CompactTextString(m) }
+func (*GetDisputeRequest) ProtoMessage()    {}
+
+type ListDisputesRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"omitempty,hexadecimal,len=24"`
+	// The unique identifier for the disputed order.
+	OrderId string `protobuf:"bytes,2,opt,name=order_id,json=orderId,proto3" json:"order_id" validate:"omitempty,uuid"`
+	// The dispute status. Available values: open, evidence_submitted, won, lost.
+	Status string `protobuf:"bytes,3,opt,name=status,proto3" json:"status" validate:"omitempty,oneof=open evidence_submitted won lost"`
+	// The number of disputes returned in one page. Default value is 100.
+	Limit int64 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit" validate:"omitempty,numeric,gte=0"`
+	// The ranking number of the first item on the page.
+	Offset int64 `protobuf:"varint,5,opt,name=offset,proto3" json:"offset" validate:"omitempty,numeric,gte=0"`
+}
+
+func (m *ListDisputesRequest) Reset()         { *m = ListDisputesRequest{} }
+func (m *ListDisputesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListDisputesRequest) ProtoMessage()    {}
+
+type DisputeResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *Dispute                        `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *DisputeResponse) Reset()         { *m = DisputeResponse{} }
+func (m *DisputeResponse) String() string { return proto.CompactTextString(m) }
+func (*DisputeResponse) ProtoMessage()    {}
+
+func (m *DisputeResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ListDisputesResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Count   int64                           `protobuf:"varint,3,opt,name=count,proto3" json:"count"`
+	Items   []*Dispute                      `protobuf:"bytes,4,rep,name=items,proto3" json:"items"`
+}
+
+func (m *ListDisputesResponse) Reset()         { *m = ListDisputesResponse{} }
+func (m *ListDisputesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListDisputesResponse) ProtoMessage()    {}
+
+func (m *ListDisputesResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type OrderReview struct {
+	// The unique identifier for the review.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The unique identifier for the held order.
+	OrderId string `protobuf:"bytes,2,opt,name=order_id,json=orderId,proto3" json:"order_id"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,3,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,4,opt,name=project_id,json=projectId,proto3" json:"project_id"`
+	// The review status. Available values: held, approved, rejected, expired.
+	Status string `protobuf:"bytes,5,opt,name=status,proto3" json:"status"`
+	// The risk score of the payment calculated by the fraud screening.
+	FraudScore int32 `protobuf:"varint,6,opt,name=fraud_score,json=fraudScore,proto3" json:"fraud_score"`
+	// The list of fraud rules matched by the payment.
+	FraudRules []string `protobuf:"bytes,7,rep,name=fraud_rules,json=fraudRules,proto3" json:"fraud_rules"`
+	// The payment amount.
+	Amount float64 `protobuf:"fixed64,8,opt,name=amount,proto3" json:"amount"`
+	// The payment currency. Three-letter Currency Code ISO 4217, in uppercase.
+	Currency string `protobuf:"bytes,9,opt,name=currency,proto3" json:"currency"`
+	// The unique identifier for the user who resolved the review.
+	ReviewerId string `protobuf:"bytes,10,opt,name=reviewer_id,json=reviewerId,proto3" json:"reviewer_id"`
+	// The reason of the review resolution.
+	Reason string `protobuf:"bytes,11,opt,name=reason,proto3" json:"reason"`
+	// The date after which the held order is canceled.
+	ExpiresAt *timestamp.Timestamp `protobuf:"bytes,12,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at"`
+	// The date of the review creation.
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,13,opt,name=created_at,json=createdAt,proto3" json:"created_at"`
+	// The date of the review last update.
+	UpdatedAt *timestamp.Timestamp `protobuf:"bytes,14,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at"`
+	// The date of the review resolution.
+	ResolvedAt *timestamp.Timestamp `protobuf:"bytes,15,opt,name=resolved_at,json=resolvedAt,proto3" json:"resolved_at"`
+}
+
+func (m *OrderReview) Reset()         { *m = OrderReview{} }
+func (m *OrderReview) String() string { return proto.CompactTextString(m) }
+func (*OrderReview) ProtoMessage()    {}
+
+type ListHeldOrdersRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"omitempty,hexadecimal,len=24"`
+	// The review status. Available values: held, approved, rejected, expired. Default value is held.
+	Status string `protobuf:"bytes,2,opt,name=status,proto3" json:"status" validate:"omitempty,oneof=held approved rejected expired"`
+	// The number of reviews returned in one page. Default value is 100.
+	Limit int64 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit" validate:"omitempty,numeric,gte=0"`
+	// The ranking number of the first item on the page.
+	Offset int64 `protobuf:"varint,4,opt,name=offset,proto3" json:"offset" validate:"omitempty,numeric,gte=0"`
+}
+
+func (m *ListHeldOrdersRequest) Reset()         { *m = ListHeldOrdersRequest{} }
+func (m *ListHeldOrdersRequest) String() string { return proto.CompactTextString(m) }
+func (*ListHeldOrdersRequest) ProtoMessage()    {}
+
+type ResolveHeldOrderRequest struct {
+	// The unique identifier for the review.
+	ReviewId string `protobuf:"bytes,1,opt,name=review_id,json=reviewId,proto3" json:"review_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the user who resolves the review.
+	ReviewerId string `protobuf:"bytes,2,opt,name=reviewer_id,json=reviewerId,proto3" json:"reviewer_id" validate:"required,hexadecimal,len=24"`
+	// The reason of the review resolution.
+	Reason string `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason" validate:"required,max=1024"`
+}
+
+func (m *ResolveHeldOrderRequest) Reset()         { *m = ResolveHeldOrderRequest{} }
+func (m *ResolveHeldOrderRequest) String() string { return proto.CompactTextString(m) }
+func (*ResolveHeldOrderRequest) ProtoMessage()    {}
+
+type OrderReviewResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *OrderReview                    `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *OrderReviewResponse) Reset()         { *m = OrderReviewResponse{} }
+func (m *OrderReviewResponse) String() string { return proto.CompactTextString(m) }
+func (*OrderReviewResponse) ProtoMessage()    {}
+
+func (m *OrderReviewResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ListHeldOrdersResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Count   int64                           `protobuf:"varint,3,opt,name=count,proto3" json:"count"`
+	Items   []*OrderReview                  `protobuf:"bytes,4,rep,name=items,proto3" json:"items"`
+}
+
+func (m *ListHeldOrdersResponse) Reset()         { *m = ListHeldOrdersResponse{} }
+func (m *ListHeldOrdersResponse) String() string { return proto.CompactTextString(m) }
+func (*ListHeldOrdersResponse) ProtoMessage()    {}
+
+func (m *ListHeldOrdersResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type PaymentMethodRule struct {
+	// The unique identifier for the rule.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,3,opt,name=project_id,json=projectId,proto3" json:"project_id"`
+	// The unique identifier for the payment method.
+	PaymentMethodId string `protobuf:"bytes,4,opt,name=payment_method_id,json=paymentMethodId,proto3" json:"payment_method_id"`
+	// The rule action. Available values: allow, deny.
+	Action string `protobuf:"bytes,5,opt,name=action,proto3" json:"action"`
+	// The list of customer countries matched by the rule. Two-letter country codes in ISO 3166-1, in uppercase.
+	Countries []string `protobuf:"bytes,6,rep,name=countries,proto3" json:"countries"`
+	// The list of order currencies matched by the rule. Three-letter Currency Code ISO 4217, in uppercase.
+	Currencies []string `protobuf:"bytes,7,rep,name=currencies,proto3" json:"currencies"`
+	// The minimal order amount matched by the rule.
+	MinAmount float64 `protobuf:"fixed64,8,opt,name=min_amount,json=minAmount,proto3" json:"min_amount"`
+	// The maximal order amount matched by the rule. Zero value means no upper limit.
+	MaxAmount float64 `protobuf:"fixed64,9,opt,name=max_amount,json=maxAmount,proto3" json:"max_amount"`
+	// The list of key product platforms matched by the rule.
+	Platforms []string `protobuf:"bytes,10,rep,name=platforms,proto3" json:"platforms"`
+	// The date of the rule creation.
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,11,opt,name=created_at,json=createdAt,proto3" json:"created_at"`
+	// The date of the rule last update.
+	UpdatedAt *timestamp.Timestamp `protobuf:"bytes,12,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at"`
+}
+
+func (m *PaymentMethodRule) Reset()         { *m = PaymentMethodRule{} }
+func (m *PaymentMethodRule) String() string { return proto.CompactTextString(m) }
+func (*PaymentMethodRule) ProtoMessage()    {}
+
+type CreateOrUpdatePaymentMethodRuleRequest struct {
+	// The unique identifier for the rule. The new rule is created if the identifier is empty.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id" validate:"omitempty,hexadecimal,len=24"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,3,opt,name=project_id,json=projectId,proto3" json:"project_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the payment method.
+	PaymentMethodId string `protobuf:"bytes,4,opt,name=payment_method_id,json=paymentMethodId,proto3" json:"payment_method_id" validate:"required,hexadecimal,len=24"`
+	// The rule action. Available values: allow, deny.
+	Action string `protobuf:"bytes,5,opt,name=action,proto3" json:"action" validate:"required,oneof=allow deny"`
+	// The list of customer countries matched by the rule. Two-letter country codes in ISO 3166-1, in uppercase.
+	Countries []string `protobuf:"bytes,6,rep,name=countries,proto3" json:"countries" validate:"omitempty,dive,len=2"`
+	// The list of order currencies matched by the rule. Three-letter Currency Code ISO 4217, in uppercase.
+	Currencies []string `protobuf:"bytes,7,rep,name=currencies,proto3" json:"currencies" validate:"omitempty,dive,len=3"`
+	// The minimal order amount matched by the rule.
+	MinAmount float64 `protobuf:"fixed64,8,opt,name=min_amount,json=minAmount,proto3" json:"min_amount" validate:"omitempty,numeric,gte=0"`
+	// The maximal order amount matched by the rule. Zero value means no upper limit.
+	MaxAmount float64 `protobuf:"fixed64,9,opt,name=max_amount,json=maxAmount,proto3" json:"max_amount" validate:"omitempty,numeric,gte=0"`
+	// The list of key product platforms matched by the rule.
+	Platforms []string `protobuf:"bytes,10,rep,name=platforms,proto3" json:"platforms" validate:"omitempty,dive,required"`
+}
+
+func (m *CreateOrUpdatePaymentMethodRuleRequest) Reset() {
+	*m = CreateOrUpdatePaymentMethodRuleRequest{}
+}
+func (m *CreateOrUpdatePaymentMethodRuleRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateOrUpdatePaymentMethodRuleRequest) ProtoMessage()    {}
+
+type DeletePaymentMethodRuleRequest struct {
+	// The unique identifier for the rule.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+}
+
+func (m *DeletePaymentMethodRuleRequest) Reset()         { *m = DeletePaymentMethodRuleRequest{} }
+func (m *DeletePaymentMethodRuleRequest) String() string { return proto.CompactTextString(m) }
+func (*DeletePaymentMethodRuleRequest) ProtoMessage()    {}
+
+type ListPaymentMethodRulesRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,2,opt,name=project_id,json=projectId,proto3" json:"project_id" validate:"required,hexadecimal,len=24"`
+}
+
+func (m *ListPaymentMethodRulesRequest) Reset()         { *m = ListPaymentMethodRulesRequest{} }
+func (m *ListPaymentMethodRulesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListPaymentMethodRulesRequest) ProtoMessage()    {}
+
+type PaymentMethodRuleResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *PaymentMethodRule              `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *PaymentMethodRuleResponse) Reset()         { *m = PaymentMethodRuleResponse{} }
+func (m *PaymentMethodRuleResponse) String() string { return proto.CompactTextString(m) }
+func (*PaymentMethodRuleResponse) ProtoMessage()    {}
+
+func (m *PaymentMethodRuleResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ListPaymentMethodRulesResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Items   []*PaymentMethodRule            `protobuf:"bytes,3,rep,name=items,proto3" json:"items"`
+}
+
+func (m *ListPaymentMethodRulesResponse) Reset()         { *m = ListPaymentMethodRulesResponse{} }
+func (m *ListPaymentMethodRulesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListPaymentMethodRulesResponse) ProtoMessage()    {}
+
+func (m *ListPaymentMethodRulesResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type PromoCodeAmount struct {
+	// The three-letter Currency Code ISO 4217, in uppercase.
+	Currency string `protobuf:"bytes,1,opt,name=currency,proto3" json:"currency" validate:"required,len=3"`
+	// The fixed discount amount in the currency.
+	Amount float64 `protobuf:"fixed64,2,opt,name=amount,proto3" json:"amount" validate:"required,numeric,gt=0"`
+}
+
+func (m *PromoCodeAmount) Reset()         { *m = PromoCodeAmount{} }
+func (m *PromoCodeAmount) String() string { return proto.CompactTextString(m) }
+func (*PromoCodeAmount) ProtoMessage()    {}
+
+type PromoCode struct {
+	// The unique identifier for the promo code.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,3,opt,name=project_id,json=projectId,proto3" json:"project_id"`
+	// The code which customer enters on the payment form, in uppercase.
+	Code string `protobuf:"bytes,4,opt,name=code,proto3" json:"code"`
+	// The discount type. Available values: percent, fixed.
+	Type string `protobuf:"bytes,5,opt,name=type,proto3" json:"type"`
+	// The discount percent of the order amount for the percent promo code.
+	Percent float64 `protobuf:"fixed64,6,opt,name=percent,proto3" json:"percent"`
+	// The list of discount amounts by currencies for the fixed promo code.
+	Amounts []*PromoCodeAmount `protobuf:"bytes,7,rep,name=amounts,proto3" json:"amounts"`
+	// The list of products discounted by the promo code. Empty list means the whole order is discounted.
+	ProductIds []string `protobuf:"bytes,8,rep,name=product_ids,json=productIds,proto3" json:"product_ids"`
+	// The date of the promo code validity start.
+	StartsAt *timestamp.Timestamp `protobuf:"bytes,9,opt,name=starts_at,json=startsAt,proto3" json:"starts_at"`
+	// The date of the promo code validity end.
+	ExpiresAt *timestamp.Timestamp `protobuf:"bytes,10,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at"`
+	// The maximal number of paid orders with the promo code. Zero value means no limit.
+	MaxUses int64 `protobuf:"varint,11,opt,name=max_uses,json=maxUses,proto3" json:"max_uses"`
+	// The maximal number of paid orders with the promo code for one customer. Zero value means no limit.
+	MaxUsesPerCustomer int64 `protobuf:"varint,12,opt,name=max_uses_per_customer,json=maxUsesPerCustomer,proto3" json:"max_uses_per_customer"`
+	// Has a true value if the promo code can be applied.
+	IsActive bool `protobuf:"varint,13,opt,name=is_active,json=isActive,proto3" json:"is_active"`
+	// The date of the promo code creation.
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,14,opt,name=created_at,json=createdAt,proto3" json:"created_at"`
+	// The date of the promo code last update.
+	UpdatedAt *timestamp.Timestamp `protobuf:"bytes,15,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at"`
+}
+
+func (m *PromoCode) Reset()         { *m = PromoCode{} }
+func (m *PromoCode) String() string { return proto.CompactTextString(m) }
+func (*PromoCode) ProtoMessage()    {}
+
+type CreateOrUpdatePromoCodeRequest struct {
+	// The unique identifier for the promo code. The new promo code is created if the identifier is empty.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id" validate:"omitempty,hexadecimal,len=24"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,3,opt,name=project_id,json=projectId,proto3" json:"project_id" validate:"required,hexadecimal,len=24"`
+	// The code which customer enters on the payment form. The code is case insensitive.
+	Code string `protobuf:"bytes,4,opt,name=code,proto3" json:"code" validate:"required,alphanum,max=64"`
+	// The discount type. Available values: percent, fixed.
+	Type string `protobuf:"bytes,5,opt,name=type,proto3" json:"type" validate:"required,oneof=percent fixed"`
+	// The discount percent of the order amount for the percent promo code.
+	Percent float64 `protobuf:"fixed64,6,opt,name=percent,proto3" json:"percent" validate:"omitempty,numeric,gte=0"`
+	// The list of discount amounts by currencies for the fixed promo code.
+	Amounts []*PromoCodeAmount `protobuf:"bytes,7,rep,name=amounts,proto3" json:"amounts" validate:"omitempty,dive"`
+	// The list of products discounted by the promo code. Empty list means the whole order is discounted.
+	ProductIds []string `protobuf:"bytes,8,rep,name=product_ids,json=productIds,proto3" json:"product_ids" validate:"omitempty,dive,hexadecimal,len=24"`
+	// The date of the promo code validity start. Empty value means the promo code is valid from the creation.
+	StartsAt *timestamp.Timestamp `protobuf:"bytes,9,opt,name=starts_at,json=startsAt,proto3" json:"starts_at"`
+	// The date of the promo code validity end. Empty value means the promo code doesn't expire.
+	ExpiresAt *timestamp.Timestamp `protobuf:"bytes,10,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at"`
+	// The maximal number of paid orders with the promo code. Zero value means no limit.
+	MaxUses int64 `protobuf:"varint,11,opt,name=max_uses,json=maxUses,proto3" json:"max_uses" validate:"omitempty,gte=0"`
+	// The maximal number of paid orders with the promo code for one customer. Zero value means no limit.
+	MaxUsesPerCustomer int64 `protobuf:"varint,12,opt,name=max_uses_per_customer,json=maxUsesPerCustomer,proto3" json:"max_uses_per_customer" validate:"omitempty,gte=0"`
+	// Has a true value if the promo code can be applied.
+	IsActive bool `protobuf:"varint,13,opt,name=is_active,json=isActive,proto3" json:"is_active"`
+}
+
+func (m *CreateOrUpdatePromoCodeRequest) Reset()         { *m = CreateOrUpdatePromoCodeRequest{} }
+func (m *CreateOrUpdatePromoCodeRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateOrUpdatePromoCodeRequest) ProtoMessage()    {}
+
+type GetPromoCodeRequest struct {
+	// The unique identifier for the promo code.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+}
+
+func (m *GetPromoCodeRequest) Reset()         { *m = GetPromoCodeRequest{} }
+func (m *GetPromoCodeRequest) String() string { return proto.CompactTextString(m) }
+func (*GetPromoCodeRequest) ProtoMessage()    {}
+
+type ListPromoCodesRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,2,opt,name=project_id,json=projectId,proto3" json:"project_id" validate:"required,hexadecimal,len=24"`
+}
+
+func (m *ListPromoCodesRequest) Reset()         { *m = ListPromoCodesRequest{} }
+func (m *ListPromoCodesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListPromoCodesRequest) ProtoMessage()    {}
+
+type ApplyPromoCodeRequest struct {
+	// The unique identifier for the order.
+	OrderId string `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id" validate:"required,uuid"`
+	// The code entered by customer. Empty value removes the promo code applied to the order.
+	Code string `protobuf:"bytes,2,opt,name=code,proto3" json:"code" validate:"omitempty,max=64"`
+}
+
+func (m *ApplyPromoCodeRequest) Reset()         { *m = ApplyPromoCodeRequest{} }
+func (m *ApplyPromoCodeRequest) String() string { return proto.CompactTextString(m) }
+func (*ApplyPromoCodeRequest) ProtoMessage()    {}
+
+type PromoCodeResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *PromoCode                      `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *PromoCodeResponse) Reset()         { *m = PromoCodeResponse{} }
+func (m *PromoCodeResponse) String() string { return proto.CompactTextString(m) }
+func (*PromoCodeResponse) ProtoMessage()    {}
+
+func (m *PromoCodeResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ListPromoCodesResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Items   []*PromoCode                    `protobuf:"bytes,3,rep,name=items,proto3" json:"items"`
+}
+
+func (m *ListPromoCodesResponse) Reset()         { *m = ListPromoCodesResponse{} }
+func (m *ListPromoCodesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListPromoCodesResponse) ProtoMessage()    {}
+
+func (m *ListPromoCodesResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ApplyPromoCodeResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// The order amounts recalculated with the discount.
+	Item *billingpb.ProcessBillingAddressResponseItem `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+	// The code applied to the order.
+	Code string `protobuf:"bytes,4,opt,name=code,proto3" json:"code"`
+	// The discount amount in the order currency.
+	DiscountAmount float64 `protobuf:"fixed64,5,opt,name=discount_amount,json=discountAmount,proto3" json:"discount_amount"`
+}
+
+func (m *ApplyPromoCodeResponse) Reset()         { *m = ApplyPromoCodeResponse{} }
+func (m *ApplyPromoCodeResponse) String() string { return proto.CompactTextString(m) }
+func (*ApplyPromoCodeResponse) ProtoMessage()    {}
+
+func (m *ApplyPromoCodeResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type SubscriptionTrial struct {
+	// The unique identifier for the recurring subscription.
+	SubscriptionId string `protobuf:"bytes,1,opt,name=subscription_id,json=subscriptionId,proto3" json:"subscription_id"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,3,opt,name=project_id,json=projectId,proto3" json:"project_id"`
+	// The unique identifier for the customer.
+	CustomerId string `protobuf:"bytes,4,opt,name=customer_id,json=customerId,proto3" json:"customer_id"`
+	// The trial status. Available values: active, converted, canceled.
+	Status string `protobuf:"bytes,5,opt,name=status,proto3" json:"status"`
+	// The trial length in days.
+	TrialDays int32 `protobuf:"varint,6,opt,name=trial_days,json=trialDays,proto3" json:"trial_days"`
+	// The trial price charged by the first payment of the subscription. Zero value means the free trial.
+	TrialPrice float64 `protobuf:"fixed64,7,opt,name=trial_price,json=trialPrice,proto3" json:"trial_price"`
+	// The amount of regular payments of the subscription after the trial.
+	PlanAmount float64 `protobuf:"fixed64,8,opt,name=plan_amount,json=planAmount,proto3" json:"plan_amount"`
+	// The currency of the trial price and plan amount. Three-letter currency code in ISO 4217, in uppercase.
+	Currency string `protobuf:"bytes,9,opt,name=currency,proto3" json:"currency"`
+	// The date of the trial end.
+	TrialEndsAt *timestamp.Timestamp `protobuf:"bytes,10,opt,name=trial_ends_at,json=trialEndsAt,proto3" json:"trial_ends_at"`
+	// The date of the trial conversion to the paid plan.
+	ConvertedAt *timestamp.Timestamp `protobuf:"bytes,11,opt,name=converted_at,json=convertedAt,proto3" json:"converted_at"`
+	// The date of the trial cancellation.
+	CanceledAt *timestamp.Timestamp `protobuf:"bytes,12,opt,name=canceled_at,json=canceledAt,proto3" json:"canceled_at"`
+	// The date of the trial start.
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,13,opt,name=created_at,json=createdAt,proto3" json:"created_at"`
+}
+
+func (m *SubscriptionTrial) Reset()         { *m = SubscriptionTrial{} }
+func (m *SubscriptionTrial) String() string { return proto.CompactTextString(m) }
+func (*SubscriptionTrial) ProtoMessage()    {}
+
+type FindSubscriptionTrialsRequest struct {
+	// The customer browser cookie. Required if the request is sent by the customer.
+	Cookie string `protobuf:"bytes,1,opt,name=cookie,proto3" json:"cookie"`
+	// The unique identifier for the merchant. Required if the request is sent by the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"omitempty,hexadecimal,len=24"`
+	// The list of recurring subscriptions identifiers returned by GetSubscription or FindSubscriptions.
+	SubscriptionIds []string `protobuf:"bytes,3,rep,name=subscription_ids,json=subscriptionIds,proto3" json:"subscription_ids" validate:"required,min=1"`
+}
+
+func (m *FindSubscriptionTrialsRequest) Reset()         { *m = FindSubscriptionTrialsRequest{} }
+func (m *FindSubscriptionTrialsRequest) String() string { return proto.CompactTextString(m) }
+func (*FindSubscriptionTrialsRequest) ProtoMessage()    {}
+
+type FindSubscriptionTrialsResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// The list of trials of the subscriptions. Subscriptions without trial are skipped.
+	Items []*SubscriptionTrial `protobuf:"bytes,3,rep,name=items,proto3" json:"items"`
+}
+
+func (m *FindSubscriptionTrialsResponse) Reset()         { *m = FindSubscriptionTrialsResponse{} }
+func (m *FindSubscriptionTrialsResponse) String() string { return proto.CompactTextString(m) }
+func (*FindSubscriptionTrialsResponse) ProtoMessage()    {}
+
+func (m *FindSubscriptionTrialsResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type DunningSchedule struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,2,opt,name=project_id,json=projectId,proto3" json:"project_id"`
+	// The list of days since the payment failure to retry the failed recurring payment.
+	RetryDays []int32 `protobuf:"varint,3,rep,packed,name=retry_days,json=retryDays,proto3" json:"retry_days"`
+	// The number of days the subscription stays unpaid after the last failed retry before the cancellation.
+	UnpaidDays int32 `protobuf:"varint,4,opt,name=unpaid_days,json=unpaidDays,proto3" json:"unpaid_days"`
+	// Has a true value if the project hasn't own schedule and the default schedule is used.
+	IsDefault bool `protobuf:"varint,5,opt,name=is_default,json=isDefault,proto3" json:"is_default"`
+	// The date of the schedule last update.
+	UpdatedAt *timestamp.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at"`
+}
+
+func (m *DunningSchedule) Reset()         { *m = DunningSchedule{} }
+func (m *DunningSchedule) String() string { return proto.CompactTextString(m) }
+func (*DunningSchedule) ProtoMessage()    {}
+
+type SetDunningScheduleRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,2,opt,name=project_id,json=projectId,proto3" json:"project_id" validate:"required,hexadecimal,len=24"`
+	// The list of days since the payment failure to retry the failed recurring payment, in ascending order.
+	RetryDays []int32 `protobuf:"varint,3,rep,packed,name=retry_days,json=retryDays,proto3" json:"retry_days" validate:"required,min=1,max=10,dive,gt=0"`
+	// The number of days the subscription stays unpaid after the last failed retry before the cancellation.
+	UnpaidDays int32 `protobuf:"varint,4,opt,name=unpaid_days,json=unpaidDays,proto3" json:"unpaid_days" validate:"omitempty,gte=0"`
+}
+
+func (m *SetDunningScheduleRequest) Reset()         { *m = SetDunningScheduleRequest{} }
+func (m *SetDunningScheduleRequest) String() string { return proto.CompactTextString(m) }
+func (*SetDunningScheduleRequest) ProtoMessage()    {}
+
+type GetDunningScheduleRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,2,opt,name=project_id,json=projectId,proto3" json:"project_id" validate:"required,hexadecimal,len=24"`
+}
+
+func (m *GetDunningScheduleRequest) Reset()         { *m = GetDunningScheduleRequest{} }
+func (m *GetDunningScheduleRequest) String() string { return proto.CompactTextString(m) }
+func (*GetDunningScheduleRequest) ProtoMessage()    {}
+
+type DunningScheduleResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *DunningSchedule                `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *DunningScheduleResponse) Reset()         { *m = DunningScheduleResponse{} }
+func (m *DunningScheduleResponse) String() string { return proto.CompactTextString(m) }
+func (*DunningScheduleResponse) ProtoMessage()    {}
+
+func (m *DunningScheduleResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type SubscriptionDunning struct {
+	// The unique identifier for the dunning.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The unique identifier for the recurring subscription.
+	SubscriptionId string `protobuf:"bytes,2,opt,name=subscription_id,json=subscriptionId,proto3" json:"subscription_id"`
+	// The unique identifier for the order of the failed payment.
+	OrderId string `protobuf:"bytes,3,opt,name=order_id,json=orderId,proto3" json:"order_id"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,4,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,5,opt,name=project_id,json=projectId,proto3" json:"project_id"`
+	// The unique identifier for the customer.
+	CustomerId string `protobuf:"bytes,6,opt,name=customer_id,json=customerId,proto3" json:"customer_id"`
+	// The dunning status. Available values: past_due, unpaid, cancelled, recovered.
+	Status string `protobuf:"bytes,7,opt,name=status,proto3" json:"status"`
+	// The number of retries of the failed payment.
+	Attempts int32 `protobuf:"varint,8,opt,name=attempts,proto3" json:"attempts"`
+	// The list of days since the payment failure to retry the failed payment.
+	RetryDays []int32 `protobuf:"varint,9,rep,packed,name=retry_days,json=retryDays,proto3" json:"retry_days"`
+	// The amount of the failed payment.
+	Amount float64 `protobuf:"fixed64,10,opt,name=amount,proto3" json:"amount"`
+	// The currency of the failed payment. Three-letter currency code in ISO 4217, in uppercase.
+	Currency string `protobuf:"bytes,11,opt,name=currency,proto3" json:"currency"`
+	// The date of the next retry for the past due subscription or the cancellation date for the unpaid subscription.
+	NextAttemptAt *timestamp.Timestamp `protobuf:"bytes,12,opt,name=next_attempt_at,json=nextAttemptAt,proto3" json:"next_attempt_at"`
+	// The date of the payment failure.
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,13,opt,name=created_at,json=createdAt,proto3" json:"created_at"`
+	// The date of the subscription recovery or cancellation.
+	ClosedAt *timestamp.Timestamp `protobuf:"bytes,14,opt,name=closed_at,json=closedAt,proto3" json:"closed_at"`
+}
+
+func (m *SubscriptionDunning) Reset()         { *m = SubscriptionDunning{} }
+func (m *SubscriptionDunning) String() string { return proto.CompactTextString(m) }
+func (*SubscriptionDunning) ProtoMessage()    {}
+
+type ListSubscriptionDunningRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the recurring subscription.
+	SubscriptionId string `protobuf:"bytes,2,opt,name=subscription_id,json=subscriptionId,proto3" json:"subscription_id" validate:"required"`
+}
+
+func (m *ListSubscriptionDunningRequest) Reset()         { *m = ListSubscriptionDunningRequest{} }
+func (m *ListSubscriptionDunningRequest) String() string { return proto.CompactTextString(m) }
+func (*ListSubscriptionDunningRequest) ProtoMessage()    {}
+
+type ListSubscriptionDunningResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Items   []*SubscriptionDunning          `protobuf:"bytes,3,rep,name=items,proto3" json:"items"`
+}
+
+func (m *ListSubscriptionDunningResponse) Reset()         { *m = ListSubscriptionDunningResponse{} }
+func (m *ListSubscriptionDunningResponse) String() string { return proto.CompactTextString(m) }
+func (*ListSubscriptionDunningResponse) ProtoMessage()    {}
+
+func (m *ListSubscriptionDunningResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+// SubscriptionDunningNotification is the webhook about the status change of the dunning of the recurring subscription
+// which is sent to the merchant by the notifier.
+type SubscriptionDunningNotification struct {
+	// The URL of the project to send the webhook.
+	Url string `protobuf:"bytes,1,opt,name=url,proto3" json:"url"`
+	// The secret key of the project to sign the webhook.
+	SecretKey string `protobuf:"bytes,2,opt,name=secret_key,json=secretKey,proto3" json:"secret_key"`
+	// Has a true value if the project is in production.
+	IsLiveProject bool `protobuf:"varint,3,opt,name=is_live_project,json=isLiveProject,proto3" json:"is_live_project"`
+	// The previous status of the dunning, empty for the new dunning.
+	PreviousStatus string `protobuf:"bytes,4,opt,name=previous_status,json=previousStatus,proto3" json:"previous_status"`
+	// The dunning with the new status.
+	Dunning *SubscriptionDunning `protobuf:"bytes,5,opt,name=dunning,proto3" json:"dunning"`
+}
+
+func (m *SubscriptionDunningNotification) Reset()         { *m = SubscriptionDunningNotification{} }
+func (m *SubscriptionDunningNotification) String() string { return proto.CompactTextString(m) }
+func (*SubscriptionDunningNotification) ProtoMessage()    {}
+
+type ChangeSubscriptionPlanRequest struct {
+	// The unique identifier for the recurring subscription.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id" validate:"required"`
+	// The customer browser cookie. Required if the request is sent by the customer.
+	Cookie string `protobuf:"bytes,2,opt,name=cookie,proto3" json:"cookie"`
+	// The unique identifier for the merchant. Required if the request is sent by the merchant.
+	MerchantId string `protobuf:"bytes,3,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"omitempty,hexadecimal,len=24"`
+	// The new amount of regular payments in the subscription currency.
+	Amount float64 `protobuf:"fixed64,4,opt,name=amount,proto3" json:"amount" validate:"required,gt=0"`
+	// The new period of regular payments. Available values: day, week, month, year.
+	Period string `protobuf:"bytes,5,opt,name=period,proto3" json:"period" validate:"required,oneof=day week month year"`
+}
+
+func (m *ChangeSubscriptionPlanRequest) Reset()         { *m = ChangeSubscriptionPlanRequest{} }
+func (m *ChangeSubscriptionPlanRequest) String() string { return proto.CompactTextString(m) }
+func (*ChangeSubscriptionPlanRequest) ProtoMessage()    {}
+
+type SubscriptionPlanChange struct {
+	// The unique identifier for the plan change.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The unique identifier for the recurring subscription.
+	SubscriptionId string `protobuf:"bytes,2,opt,name=subscription_id,json=subscriptionId,proto3" json:"subscription_id"`
+	// The amount of regular payments before the change.
+	PreviousAmount float64 `protobuf:"fixed64,3,opt,name=previous_amount,json=previousAmount,proto3" json:"previous_amount"`
+	// The period of regular payments before the change.
+	PreviousPeriod string `protobuf:"bytes,4,opt,name=previous_period,json=previousPeriod,proto3" json:"previous_period"`
+	// The new amount of regular payments.
+	Amount float64 `protobuf:"fixed64,5,opt,name=amount,proto3" json:"amount"`
+	// The new period of regular payments.
+	Period string `protobuf:"bytes,6,opt,name=period,proto3" json:"period"`
+	// The subscription currency. Three-letter currency code in ISO 4217, in uppercase.
+	Currency string `protobuf:"bytes,7,opt,name=currency,proto3" json:"currency"`
+	// The prorated amount for the rest of the current period. The positive amount is charged from the customer,
+	// the negative amount is refunded to the customer.
+	ProrationAmount float64 `protobuf:"fixed64,8,opt,name=proration_amount,json=prorationAmount,proto3" json:"proration_amount"`
+	// The unique identifier for the order of the proration charge.
+	OrderId string `protobuf:"bytes,9,opt,name=order_id,json=orderId,proto3" json:"order_id"`
+	// The unique identifier for the refund of the proration credit.
+	RefundId string `protobuf:"bytes,10,opt,name=refund_id,json=refundId,proto3" json:"refund_id"`
+	// The end date of the current period. Regular payments with the new plan start from this date.
+	PeriodEndsAt *timestamp.Timestamp `protobuf:"bytes,11,opt,name=period_ends_at,json=periodEndsAt,proto3" json:"period_ends_at"`
+	// The date of the plan change.
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,12,opt,name=created_at,json=createdAt,proto3" json:"created_at"`
+	// The plan change status. Available values: pending, completed, failed.
+	Status string `protobuf:"bytes,13,opt,name=status,proto3" json:"status"`
+}
+
+func (m *SubscriptionPlanChange) Reset()         { *m = SubscriptionPlanChange{} }
+func (m *SubscriptionPlanChange) String() string { return proto.CompactTextString(m) }
+func (*SubscriptionPlanChange) ProtoMessage()    {}
+
+type ChangeSubscriptionPlanResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *SubscriptionPlanChange         `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *ChangeSubscriptionPlanResponse) Reset()         { *m = ChangeSubscriptionPlanResponse{} }
+func (m *ChangeSubscriptionPlanResponse) String() string { return proto.CompactTextString(m) }
+func (*ChangeSubscriptionPlanResponse) ProtoMessage()    {}
+
+func (m *ChangeSubscriptionPlanResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type PauseSubscriptionRequest struct {
+	// The unique identifier for the recurring subscription.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id" validate:"required"`
+	// The customer browser cookie. Required if the request is sent by the customer.
+	Cookie string `protobuf:"bytes,2,opt,name=cookie,proto3" json:"cookie"`
+	// The unique identifier for the merchant. Required if the request is sent by the merchant.
+	MerchantId string `protobuf:"bytes,3,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"omitempty,hexadecimal,len=24"`
+	// The number of subscription periods to pause. Required if resume_date isn't set.
+	Periods int32 `protobuf:"varint,4,opt,name=periods,proto3" json:"periods" validate:"omitempty,min=1,max=12"`
+	// The date to resume the subscription in the YYYY-MM-DD format. Required if periods isn't set.
+	ResumeDate string `protobuf:"bytes,5,opt,name=resume_date,json=resumeDate,proto3" json:"resume_date" validate:"omitempty,datetime=2006-01-02"`
+}
+
+func (m *PauseSubscriptionRequest) Reset()         { *m = PauseSubscriptionRequest{} }
+func (m *PauseSubscriptionRequest) String() string { return proto.CompactTextString(m) }
+func (*PauseSubscriptionRequest) ProtoMessage()    {}
+
+type ResumeSubscriptionRequest struct {
+	// The unique identifier for the recurring subscription.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id" validate:"required"`
+	// The customer browser cookie. Required if the request is sent by the customer.
+	Cookie string `protobuf:"bytes,2,opt,name=cookie,proto3" json:"cookie"`
+	// The unique identifier for the merchant. Required if the request is sent by the merchant.
+	MerchantId string `protobuf:"bytes,3,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"omitempty,hexadecimal,len=24"`
+}
+
+func (m *ResumeSubscriptionRequest) Reset()         { *m = ResumeSubscriptionRequest{} }
+func (m *ResumeSubscriptionRequest) String() string { return proto.CompactTextString(m) }
+func (*ResumeSubscriptionRequest) ProtoMessage()    {}
+
+type SubscriptionPause struct {
+	// The unique identifier for the pause.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The unique identifier for the recurring subscription.
+	SubscriptionId string `protobuf:"bytes,2,opt,name=subscription_id,json=subscriptionId,proto3" json:"subscription_id"`
+	// The pause status. Available values: paused, resumed, cancelled.
+	Status string `protobuf:"bytes,3,opt,name=status,proto3" json:"status"`
+	// The number of paused subscription periods, zero for the pause until the date.
+	Periods int32 `protobuf:"varint,4,opt,name=periods,proto3" json:"periods"`
+	// The date of the pause.
+	PausedAt *timestamp.Timestamp `protobuf:"bytes,5,opt,name=paused_at,json=pausedAt,proto3" json:"paused_at"`
+	// The planned date of the resume.
+	ResumeAt *timestamp.Timestamp `protobuf:"bytes,6,opt,name=resume_at,json=resumeAt,proto3" json:"resume_at"`
+	// The actual date of the resume.
+	ResumedAt *timestamp.Timestamp `protobuf:"bytes,7,opt,name=resumed_at,json=resumedAt,proto3" json:"resumed_at"`
+	// The date of the next regular payment after the resume.
+	NextBillingAt *timestamp.Timestamp `protobuf:"bytes,8,opt,name=next_billing_at,json=nextBillingAt,proto3" json:"next_billing_at"`
+}
+
+func (m *SubscriptionPause) Reset()         { *m = SubscriptionPause{} }
+func (m *SubscriptionPause) String() string { return proto.CompactTextString(m) }
+func (*SubscriptionPause) ProtoMessage()    {}
+
+type SubscriptionPauseResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *SubscriptionPause              `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *SubscriptionPauseResponse) Reset()         { *m = SubscriptionPauseResponse{} }
+func (m *SubscriptionPauseResponse) String() string { return proto.CompactTextString(m) }
+func (*SubscriptionPauseResponse) ProtoMessage()    {}
+
+func (m *SubscriptionPauseResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type GetSavedCardUpdateRequest struct {
+	// The token of the card update link sent to the customer.
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token" validate:"required"`
+}
+
+func (m *GetSavedCardUpdateRequest) Reset()         { *m = GetSavedCardUpdateRequest{} }
+func (m *GetSavedCardUpdateRequest) String() string { return proto.CompactTextString(m) }
+func (*GetSavedCardUpdateRequest) ProtoMessage()    {}
+
+type VerifySavedCardUpdateRequest struct {
+	// The token of the card update link sent to the customer.
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token" validate:"required"`
+	// The customer IP address.
+	Ip string `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip" validate:"omitempty,ip"`
+	// The number of the new bank card.
+	Pan string `protobuf:"bytes,3,opt,name=pan,proto3" json:"pan" validate:"required"`
+	// The CVV of the new bank card.
+	Cvv string `protobuf:"bytes,4,opt,name=cvv,proto3" json:"cvv" validate:"required"`
+	// The expiration month of the new bank card.
+	Month string `protobuf:"bytes,5,opt,name=month,proto3" json:"month" validate:"required"`
+	// The expiration year of the new bank card.
+	Year string `protobuf:"bytes,6,opt,name=year,proto3" json:"year" validate:"required"`
+	// The holder name of the new bank card.
+	Holder string `protobuf:"bytes,7,opt,name=holder,proto3" json:"holder" validate:"required"`
+}
+
+func (m *VerifySavedCardUpdateRequest) Reset()         { *m = VerifySavedCardUpdateRequest{} }
+func (m *VerifySavedCardUpdateRequest) String() string { return proto.CompactTextString(m) }
+func (*VerifySavedCardUpdateRequest) ProtoMessage()    {}
+
+type SavedCardUpdate struct {
+	// The unique identifier for the card update.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The masked number of the expiring card.
+	MaskedPan string `protobuf:"bytes,2,opt,name=masked_pan,json=maskedPan,proto3" json:"masked_pan"`
+	// The expiration month of the expiring card.
+	ExpireMonth string `protobuf:"bytes,3,opt,name=expire_month,json=expireMonth,proto3" json:"expire_month"`
+	// The expiration year of the expiring card.
+	ExpireYear string `protobuf:"bytes,4,opt,name=expire_year,json=expireYear,proto3" json:"expire_year"`
+	// The card update status. Available values: pending, completed.
+	Status string `protobuf:"bytes,5,opt,name=status,proto3" json:"status"`
+	// The date until the card update link is available.
+	ExpiresAt *timestamp.Timestamp `protobuf:"bytes,6,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at"`
+	// The date of the card replacement.
+	CompletedAt *timestamp.Timestamp `protobuf:"bytes,7,opt,name=completed_at,json=completedAt,proto3" json:"completed_at"`
+}
+
+func (m *SavedCardUpdate) Reset()         { *m = SavedCardUpdate{} }
+func (m *SavedCardUpdate) String() string { return proto.CompactTextString(m) }
+func (*SavedCardUpdate) ProtoMessage()    {}
+
+type SavedCardUpdateResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *SavedCardUpdate                `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+	// The URL to redirect the customer to complete the verification of the new card, for example by 3-D Secure.
+	RedirectUrl string `protobuf:"bytes,4,opt,name=redirect_url,json=redirectUrl,proto3" json:"redirect_url,omitempty"`
+}
+
+func (m *SavedCardUpdateResponse) Reset()         { *m = SavedCardUpdateResponse{} }
+func (m *SavedCardUpdateResponse) String() string { return proto.CompactTextString(m) }
+func (*SavedCardUpdateResponse) ProtoMessage()    {}
+
+func (m *SavedCardUpdateResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type RefundItemRequest struct {
+	// The unique identifier for the product or the key product of the order item.
+	ItemId string `protobuf:"bytes,1,opt,name=item_id,json=itemId,proto3" json:"item_id" validate:"required,hexadecimal,len=24"`
+	// The number of the order items of the product to refund.
+	Quantity int32 `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity" validate:"required,min=1"`
+}
+
+func (m *RefundItemRequest) Reset()         { *m = RefundItemRequest{} }
+func (m *RefundItemRequest) String() string { return proto.CompactTextString(m) }
+func (*RefundItemRequest) ProtoMessage()    {}
+
+type CreateItemsRefundRequest struct {
+	// The unique identifier for the order.
+	OrderId string `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id" validate:"required,uuid"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the user who created the refund.
+	CreatorId string `protobuf:"bytes,3,opt,name=creator_id,json=creatorId,proto3" json:"creator_id" validate:"required,hexadecimal,len=24"`
+	// The refund reason.
+	Reason string `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason" validate:"omitempty,max=255"`
+	// The list of the order items to refund.
+	Items []*RefundItemRequest `protobuf:"bytes,5,rep,name=items,proto3" json:"items" validate:"required,min=1,dive"`
+}
+
+func (m *CreateItemsRefundRequest) Reset()         { *m = CreateItemsRefundRequest{} }
+func (m *CreateItemsRefundRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateItemsRefundRequest) ProtoMessage()    {}
+
+type RefundApprovalSettings struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id"`
+	// The refund amount above which the refund requires the approval. Zero amount disables the threshold.
+	Amount float64 `protobuf:"fixed64,2,opt,name=amount,proto3" json:"amount"`
+	// The three-letter currency code of the threshold amount in ISO 4217 alphabetic format.
+	Currency string `protobuf:"bytes,3,opt,name=currency,proto3" json:"currency"`
+	// The percentage of the order amount above which the refund requires the approval. Zero percentage disables the threshold.
+	Percent float64 `protobuf:"fixed64,4,opt,name=percent,proto3" json:"percent"`
+	// The role of the merchant user who can approve or reject the refund.
+	ApproverRole string `protobuf:"bytes,5,opt,name=approver_role,json=approverRole,proto3" json:"approver_role"`
+	// The date of the settings last update.
+	UpdatedAt *timestamp.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at"`
+}
+
+func (m *RefundApprovalSettings) Reset()         { *m = RefundApprovalSettings{} }
+func (m *RefundApprovalSettings) String() string { return proto.CompactTextString(m) }
+func (*RefundApprovalSettings) ProtoMessage()    {}
+
+type SetRefundApprovalSettingsRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The refund amount above which the refund requires the approval. Zero amount disables the threshold.
+	Amount float64 `protobuf:"fixed64,2,opt,name=amount,proto3" json:"amount" validate:"omitempty,gte=0"`
+	// The three-letter currency code of the threshold amount in ISO 4217 alphabetic format. Required if amount is set.
+	Currency string `protobuf:"bytes,3,opt,name=currency,proto3" json:"currency" validate:"omitempty,alpha,len=3"`
+	// The percentage of the order amount above which the refund requires the approval. Zero percentage disables the threshold.
+	Percent float64 `protobuf:"fixed64,4,opt,name=percent,proto3" json:"percent" validate:"omitempty,gte=0,lte=100"`
+	// The role of the merchant user who can approve or reject the refund.
+	ApproverRole string `protobuf:"bytes,5,opt,name=approver_role,json=approverRole,proto3" json:"approver_role" validate:"required"`
+}
+
+func (m *SetRefundApprovalSettingsRequest) Reset()         { *m = SetRefundApprovalSettingsRequest{} }
+func (m *SetRefundApprovalSettingsRequest) String() string { return proto.CompactTextString(m) }
+func (*SetRefundApprovalSettingsRequest) ProtoMessage()    {}
+
+type GetRefundApprovalSettingsRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+}
+
+func (m *GetRefundApprovalSettingsRequest) Reset()         { *m = GetRefundApprovalSettingsRequest{} }
+func (m *GetRefundApprovalSettingsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetRefundApprovalSettingsRequest) ProtoMessage()    {}
+
+type RefundApprovalSettingsResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *RefundApprovalSettings         `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *RefundApprovalSettingsResponse) Reset()         { *m = RefundApprovalSettingsResponse{} }
+func (m *RefundApprovalSettingsResponse) String() string { return proto.CompactTextString(m) }
+func (*RefundApprovalSettingsResponse) ProtoMessage()    {}
+
+func (m *RefundApprovalSettingsResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ReviewRefundRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the order.
+	OrderId string `protobuf:"bytes,2,opt,name=order_id,json=orderId,proto3" json:"order_id" validate:"required,uuid"`
+	// The unique identifier for the refund.
+	RefundId string `protobuf:"bytes,3,opt,name=refund_id,json=refundId,proto3" json:"refund_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the user who approves or rejects the refund.
+	UserId string `protobuf:"bytes,4,opt,name=user_id,json=userId,proto3" json:"user_id" validate:"required,hexadecimal,len=24"`
+	// The comment of the user to the decision.
+	Comment string `protobuf:"bytes,5,opt,name=comment,proto3" json:"comment" validate:"omitempty,max=255"`
+}
+
+func (m *ReviewRefundRequest) Reset()         { *m = ReviewRefundRequest{} }
+func (m *ReviewRefundRequest) String() string { return proto.CompactTextString(m) }
+func (*ReviewRefundRequest) ProtoMessage()    {}
+
+type RefundBankAccount struct {
+	// The name of the bank account holder.
+	Holder string `protobuf:"bytes,1,opt,name=holder,proto3" json:"holder" validate:"required,max=255"`
+	// The international bank account number.
+	Iban string `protobuf:"bytes,2,opt,name=iban,proto3" json:"iban" validate:"required,alphanum,max=34"`
+	// The SWIFT code of the bank.
+	Swift string `protobuf:"bytes,3,opt,name=swift,proto3" json:"swift" validate:"required,alphanum,min=8,max=11"`
+	// The name of the bank.
+	BankName string `protobuf:"bytes,4,opt,name=bank_name,json=bankName,proto3" json:"bank_name" validate:"omitempty,max=255"`
+	// The two-letter country code of the bank in ISO 3166-1 format.
+	Country string `protobuf:"bytes,5,opt,name=country,proto3" json:"country" validate:"required,len=2"`
+}
+
+func (m *RefundBankAccount) Reset()         { *m = RefundBankAccount{} }
+func (m *RefundBankAccount) String() string { return proto.CompactTextString(m) }
+func (*RefundBankAccount) ProtoMessage()    {}
+
+type GetRefundFallbackRequest struct {
+	// The token of the refund fallback link sent to the customer.
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token" validate:"required"`
+}
+
+func (m *GetRefundFallbackRequest) Reset()         { *m = GetRefundFallbackRequest{} }
+func (m *GetRefundFallbackRequest) String() string { return proto.CompactTextString(m) }
+func (*GetRefundFallbackRequest) ProtoMessage()    {}
+
+type SetRefundFallbackDestinationRequest struct {
+	// The token of the refund fallback link sent to the customer.
+	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token" validate:"required"`
+	// The way to return the refund to the customer. Available values: bank_transfer, store_credit.
+	Destination string `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination" validate:"required,oneof=bank_transfer store_credit"`
+	// The bank account of the customer. Required for the bank transfer.
+	BankAccount *RefundBankAccount `protobuf:"bytes,3,opt,name=bank_account,json=bankAccount,proto3" json:"bank_account,omitempty"`
+}
+
+func (m *SetRefundFallbackDestinationRequest) Reset()         { *m = SetRefundFallbackDestinationRequest{} }
+func (m *SetRefundFallbackDestinationRequest) String() string { return proto.CompactTextString(m) }
+func (*SetRefundFallbackDestinationRequest) ProtoMessage()    {}
+
+type CompleteRefundFallbackRequest struct {
+	// The unique identifier for the refund.
+	RefundId string `protobuf:"bytes,1,opt,name=refund_id,json=refundId,proto3" json:"refund_id" validate:"required,hexadecimal,len=24"`
+	// The reference of the bank transfer to the customer.
+	TransferReference string `protobuf:"bytes,2,opt,name=transfer_reference,json=transferReference,proto3" json:"transfer_reference" validate:"required,max=255"`
+}
+
+func (m *CompleteRefundFallbackRequest) Reset()         { *m = CompleteRefundFallbackRequest{} }
+func (m *CompleteRefundFallbackRequest) String() string { return proto.CompactTextString(m) }
+func (*CompleteRefundFallbackRequest) ProtoMessage()    {}
+
+type RefundFallback struct {
+	// The unique identifier for the refund fallback.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The unique identifier for the refund.
+	RefundId string `protobuf:"bytes,2,opt,name=refund_id,json=refundId,proto3" json:"refund_id"`
+	// The refund amount.
+	Amount float64 `protobuf:"fixed64,3,opt,name=amount,proto3" json:"amount"`
+	// The three-letter currency code of the refund in ISO 4217 alphabetic format.
+	Currency string `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency"`
+	// The way to return the refund to the customer. Available values: bank_transfer, store_credit.
+	Destination string `protobuf:"bytes,5,opt,name=destination,proto3" json:"destination"`
+	// The bank account of the customer for the bank transfer.
+	BankAccount *RefundBankAccount `protobuf:"bytes,6,opt,name=bank_account,json=bankAccount,proto3" json:"bank_account,omitempty"`
+	// The refund fallback status. Available values: awaiting_details, in_progress, completed.
+	Status string `protobuf:"bytes,7,opt,name=status,proto3" json:"status"`
+	// The reference of the bank transfer to the customer.
+	TransferReference string `protobuf:"bytes,8,opt,name=transfer_reference,json=transferReference,proto3" json:"transfer_reference"`
+	// The date of the refund fallback creation.
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at"`
+	// The date of the money return to the customer.
+	CompletedAt *timestamp.Timestamp `protobuf:"bytes,10,opt,name=completed_at,json=completedAt,proto3" json:"completed_at"`
+}
+
+func (m *RefundFallback) Reset()         { *m = RefundFallback{} }
+func (m *RefundFallback) String() string { return proto.CompactTextString(m) }
+func (*RefundFallback) ProtoMessage()    {}
+
+type RefundFallbackResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *RefundFallback                 `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *RefundFallbackResponse) Reset()         { *m = RefundFallbackResponse{} }
+func (m *RefundFallbackResponse) String() string { return proto.CompactTextString(m) }
+func (*RefundFallbackResponse) ProtoMessage()    {}
+
+func (m *RefundFallbackResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type CustomerWallet struct {
+	// The unique identifier for the wallet.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The unique identifier for the customer.
+	CustomerId string `protobuf:"bytes,2,opt,name=customer_id,json=customerId,proto3" json:"customer_id"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,3,opt,name=project_id,json=projectId,proto3" json:"project_id"`
+	// The three-letter currency code of the wallet in ISO 4217 alphabetic format.
+	Currency string `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency"`
+	// The wallet balance.
+	Balance float64 `protobuf:"fixed64,5,opt,name=balance,proto3" json:"balance"`
+	// The date of the last wallet balance change.
+	UpdatedAt *timestamp.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at"`
+}
+
+func (m *CustomerWallet) Reset()         { *m = CustomerWallet{} }
+func (m *CustomerWallet) String() string { return proto.CompactTextString(m) }
+func (*CustomerWallet) ProtoMessage()    {}
+
+type GetCustomerWalletsRequest struct {
+	// The unique identifier for the customer.
+	CustomerId string `protobuf:"bytes,1,opt,name=customer_id,json=customerId,proto3" json:"customer_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,2,opt,name=project_id,json=projectId,proto3" json:"project_id" validate:"required,hexadecimal,len=24"`
+}
+
+func (m *GetCustomerWalletsRequest) Reset()         { *m = GetCustomerWalletsRequest{} }
+func (m *GetCustomerWalletsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetCustomerWalletsRequest) ProtoMessage()    {}
+
+type CustomerWalletsResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Items   []*CustomerWallet               `protobuf:"bytes,3,rep,name=items,proto3" json:"items"`
+}
+
+func (m *CustomerWalletsResponse) Reset()         { *m = CustomerWalletsResponse{} }
+func (m *CustomerWalletsResponse) String() string { return proto.CompactTextString(m) }
+func (*CustomerWalletsResponse) ProtoMessage()    {}
+
+func (m *CustomerWalletsResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type AddWalletCreditRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,2,opt,name=project_id,json=projectId,proto3" json:"project_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the customer.
+	CustomerId string `protobuf:"bytes,3,opt,name=customer_id,json=customerId,proto3" json:"customer_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the user who added the credit.
+	CreatorId string `protobuf:"bytes,4,opt,name=creator_id,json=creatorId,proto3" json:"creator_id" validate:"required,hexadecimal,len=24"`
+	// The credit amount.
+	Amount float64 `protobuf:"fixed64,5,opt,name=amount,proto3" json:"amount" validate:"required,numeric,gt=0"`
+	// The three-letter currency code of the credit in ISO 4217 alphabetic format.
+	Currency string `protobuf:"bytes,6,opt,name=currency,proto3" json:"currency" validate:"required,len=3"`
+	// The reason of the goodwill credit.
+	Reason string `protobuf:"bytes,7,opt,name=reason,proto3" json:"reason" validate:"required,max=255"`
+}
+
+func (m *AddWalletCreditRequest) Reset()         { *m = AddWalletCreditRequest{} }
+func (m *AddWalletCreditRequest) String() string { return proto.CompactTextString(m) }
+func (*AddWalletCreditRequest) ProtoMessage()    {}
+
+type CustomerWalletResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *CustomerWallet                 `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *CustomerWalletResponse) Reset()         { *m = CustomerWalletResponse{} }
+func (m *CustomerWalletResponse) String() string { return proto.CompactTextString(m) }
+func (*CustomerWalletResponse) ProtoMessage()    {}
+
+func (m *CustomerWalletResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ApplyWalletRequest struct {
+	// The unique identifier for the order.
+	OrderId string `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id" validate:"required,uuid"`
+	// The amount of the order paid by the customer wallet in the order currency. Zero value removes the wallet
+	// from the order.
+	Amount float64 `protobuf:"fixed64,2,opt,name=amount,proto3" json:"amount" validate:"omitempty,numeric,gte=0"`
+}
+
+func (m *ApplyWalletRequest) Reset()         { *m = ApplyWalletRequest{} }
+func (m *ApplyWalletRequest) String() string { return proto.CompactTextString(m) }
+func (*ApplyWalletRequest) ProtoMessage()    {}
+
+type ApplyWalletResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// The order amounts recalculated with the wallet payment.
+	Item *billingpb.ProcessBillingAddressResponseItem `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+	// The amount of the order paid by the customer wallet in the order currency.
+	WalletAmount float64 `protobuf:"fixed64,4,opt,name=wallet_amount,json=walletAmount,proto3" json:"wallet_amount"`
+	// Has a boolean value true if the order is fully paid by the customer wallet.
+	IsPaid bool `protobuf:"varint,5,opt,name=is_paid,json=isPaid,proto3" json:"is_paid"`
+}
+
+func (m *ApplyWalletResponse) Reset()         { *m = ApplyWalletResponse{} }
+func (m *ApplyWalletResponse) String() string { return proto.CompactTextString(m) }
+func (*ApplyWalletResponse) ProtoMessage()    {}
+
+func (m *ApplyWalletResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type GenerateGiftCardsRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the key product which sells the gift cards.
+	KeyProductId string `protobuf:"bytes,2,opt,name=key_product_id,json=keyProductId,proto3" json:"key_product_id" validate:"required,hexadecimal,len=24"`
+	// The unique identifier for the platform of the key product.
+	PlatformId string `protobuf:"bytes,3,opt,name=platform_id,json=platformId,proto3" json:"platform_id" validate:"required,max=255"`
+	// The number of the gift cards to generate.
+	Count int32 `protobuf:"varint,4,opt,name=count,proto3" json:"count" validate:"required,numeric,gte=1,lte=1000"`
+	// The monetary value of the gift card.
+	Value float64 `protobuf:"fixed64,5,opt,name=value,proto3" json:"value" validate:"required,numeric,gt=0"`
+	// The three-letter currency code of the gift card value in ISO 4217 alphabetic format.
+	Currency string `protobuf:"bytes,6,opt,name=currency,proto3" json:"currency" validate:"required,len=3"`
+	// The number of days the gift card is valid after the sale. Zero value means the gift card doesn't expire.
+	ValidityDays int32 `protobuf:"varint,7,opt,name=validity_days,json=validityDays,proto3" json:"validity_days" validate:"omitempty,numeric,gte=0"`
+}
+
+func (m *GenerateGiftCardsRequest) Reset()         { *m = GenerateGiftCardsRequest{} }
+func (m *GenerateGiftCardsRequest) String() string { return proto.CompactTextString(m) }
+func (*GenerateGiftCardsRequest) ProtoMessage()    {}
+
+type GenerateGiftCardsResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// The number of the generated gift cards added to the keys of the key product.
+	Count int32 `protobuf:"varint,3,opt,name=count,proto3" json:"count"`
+}
+
+func (m *GenerateGiftCardsResponse) Reset()         { *m = GenerateGiftCardsResponse{} }
+func (m *GenerateGiftCardsResponse) String() string { return proto.CompactTextString(m) }
+func (*GenerateGiftCardsResponse) ProtoMessage()    {}
+
+func (m *GenerateGiftCardsResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type GiftCard struct {
+	// The unique identifier for the gift card.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The unique identifier for the key product which sells the gift card.
+	KeyProductId string `protobuf:"bytes,2,opt,name=key_product_id,json=keyProductId,proto3" json:"key_product_id"`
+	// The monetary value of the gift card.
+	Value float64 `protobuf:"fixed64,3,opt,name=value,proto3" json:"value"`
+	// The rest of the gift card value available to spend.
+	Balance float64 `protobuf:"fixed64,4,opt,name=balance,proto3" json:"balance"`
+	// The three-letter currency code of the gift card in ISO 4217 alphabetic format.
+	Currency string `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency"`
+	// The gift card status. Available values: issued, active, redeemed, expired, canceled.
+	Status string `protobuf:"bytes,6,opt,name=status,proto3" json:"status"`
+	// The date of the gift card sale.
+	ActivatedAt *timestamp.Timestamp `protobuf:"bytes,7,opt,name=activated_at,json=activatedAt,proto3" json:"activated_at"`
+	// The date of the gift card expiration.
+	ExpiresAt *timestamp.Timestamp `protobuf:"bytes,8,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at"`
+}
+
+func (m *GiftCard) Reset()         { *m = GiftCard{} }
+func (m *GiftCard) String() string { return proto.CompactTextString(m) }
+func (*GiftCard) ProtoMessage()    {}
+
+type GetGiftCardRequest struct {
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id" validate:"required,hexadecimal,len=24"`
+	// The gift card code.
+	Code string `protobuf:"bytes,2,opt,name=code,proto3" json:"code" validate:"required,max=64"`
+}
+
+func (m *GetGiftCardRequest) Reset()         { *m = GetGiftCardRequest{} }
+func (m *GetGiftCardRequest) String() string { return proto.CompactTextString(m) }
+func (*GetGiftCardRequest) ProtoMessage()    {}
+
+type GiftCardResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *GiftCard                       `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *GiftCardResponse) Reset()         { *m = GiftCardResponse{} }
+func (m *GiftCardResponse) String() string { return proto.CompactTextString(m) }
+func (*GiftCardResponse) ProtoMessage()    {}
+
+func (m *GiftCardResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type RedeemGiftCardRequest struct {
+	// The unique identifier for the project.
+	ProjectId string `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id" validate:"required,hexadecimal,len=24"`
+	// The gift card code.
+	Code string `protobuf:"bytes,2,opt,name=code,proto3" json:"code" validate:"required,max=64"`
+	// The unique identifier for the customer whose wallet is topped up by the gift card. It can be the buyer of
+	// the gift card or the recipient of the gift.
+	CustomerId string `protobuf:"bytes,3,opt,name=customer_id,json=customerId,proto3" json:"customer_id" validate:"required,hexadecimal,len=24"`
+}
+
+func (m *RedeemGiftCardRequest) Reset()         { *m = RedeemGiftCardRequest{} }
+func (m *RedeemGiftCardRequest) String() string { return proto.CompactTextString(m) }
+func (*RedeemGiftCardRequest) ProtoMessage()    {}
+
+type ApplyGiftCardRequest struct {
+	// The unique identifier for the order.
+	OrderId string `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id" validate:"required,uuid"`
+	// The gift card code entered by customer. Empty value removes the gift card applied to the order.
+	Code string `protobuf:"bytes,2,opt,name=code,proto3" json:"code" validate:"omitempty,max=64"`
+}
+
+func (m *ApplyGiftCardRequest) Reset()         { *m = ApplyGiftCardRequest{} }
+func (m *ApplyGiftCardRequest) String() string { return proto.CompactTextString(m) }
+func (*ApplyGiftCardRequest) ProtoMessage()    {}
+
+type ApplyGiftCardResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// The order amounts recalculated with the gift card payment.
+	Item *billingpb.ProcessBillingAddressResponseItem `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+	// The amount of the order paid by the gift card in the order currency.
+	GiftCardAmount float64 `protobuf:"fixed64,4,opt,name=gift_card_amount,json=giftCardAmount,proto3" json:"gift_card_amount"`
+	// Has a boolean value true if the order is fully paid by the gift card and the customer wallet.
+	IsPaid bool `protobuf:"varint,5,opt,name=is_paid,json=isPaid,proto3" json:"is_paid"`
+}
+
+func (m *ApplyGiftCardResponse) Reset()         { *m = ApplyGiftCardResponse{} }
+func (m *ApplyGiftCardResponse) String() string { return proto.CompactTextString(m) }
+func (*ApplyGiftCardResponse) ProtoMessage()    {}
+
+func (m *ApplyGiftCardResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type GetTrialBalanceRequest struct {
+	// The unique identifier for the operating company.
+	OperatingCompanyId string `protobuf:"bytes,1,opt,name=operating_company_id,json=operatingCompanyId,proto3" json:"operating_company_id" validate:"required,hexadecimal,len=24"`
+	// Three-letter currency code by ISO 4217, in uppercase.
+	Currency string `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency" validate:"required,len=3"`
+	// The date in Unix time of the trial balance. Zero value returns the trial balance on the current date.
+	Date int64 `protobuf:"varint,3,opt,name=date,proto3" json:"date" validate:"omitempty,numeric,gte=0"`
+}
+
+func (m *GetTrialBalanceRequest) Reset()         { *m = GetTrialBalanceRequest{} }
+func (m *GetTrialBalanceRequest) String() string { return proto.CompactTextString(m) }
+func (*GetTrialBalanceRequest) ProtoMessage()    {}
+
+type TrialBalanceAccount struct {
+	// The ledger account. Available values: gateway_receivable, merchant_payable, platform_revenue, tax_payable,
+	// rolling_reserve, customer_credit.
+	Account string `protobuf:"bytes,1,opt,name=account,proto3" json:"account"`
+	// The sum of debits of the account.
+	Debit float64 `protobuf:"fixed64,2,opt,name=debit,proto3" json:"debit"`
+	// The sum of credits of the account.
+	Credit float64 `protobuf:"fixed64,3,opt,name=credit,proto3" json:"credit"`
+	// The debit balance of the account. Negative value is the credit balance.
+	Balance float64 `protobuf:"fixed64,4,opt,name=balance,proto3" json:"balance"`
+}
+
+func (m *TrialBalanceAccount) Reset()         { *m = TrialBalanceAccount{} }
+func (m *TrialBalanceAccount) String() string { return proto.CompactTextString(m) }
+func (*TrialBalanceAccount) ProtoMessage()    {}
+
+type TrialBalance struct {
+	// The unique identifier for the operating company.
+	OperatingCompanyId string `protobuf:"bytes,1,opt,name=operating_company_id,json=operatingCompanyId,proto3" json:"operating_company_id"`
+	// Three-letter currency code by ISO 4217, in uppercase.
+	Currency string `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency"`
+	// The list of the ledger accounts.
+	Accounts []*TrialBalanceAccount `protobuf:"bytes,3,rep,name=accounts,proto3" json:"accounts"`
+	// The sum of debits of all accounts.
+	TotalDebit float64 `protobuf:"fixed64,4,opt,name=total_debit,json=totalDebit,proto3" json:"total_debit"`
+	// The sum of credits of all accounts.
+	TotalCredit float64 `protobuf:"fixed64,5,opt,name=total_credit,json=totalCredit,proto3" json:"total_credit"`
+	// Has a boolean value true if the sum of debits is equal to the sum of credits.
+	IsBalanced bool `protobuf:"varint,6,opt,name=is_balanced,json=isBalanced,proto3" json:"is_balanced"`
+}
+
+func (m *TrialBalance) Reset()         { *m = TrialBalance{} }
+func (m *TrialBalance) String() string { return proto.CompactTextString(m) }
+func (*TrialBalance) ProtoMessage()    {}
+
+type TrialBalanceResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *TrialBalance                   `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *TrialBalanceResponse) Reset()         { *m = TrialBalanceResponse{} }
+func (m *TrialBalanceResponse) String() string { return proto.CompactTextString(m) }
+func (*TrialBalanceResponse) ProtoMessage()    {}
+
+func (m *TrialBalanceResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ImportSettlementReportRequest struct {
+	// The handler of the payment system which settled the transactions. Available values: cardpay.
+	PaymentSystem string `protobuf:"bytes,1,opt,name=payment_system,json=paymentSystem,proto3" json:"payment_system" validate:"required"`
+	// The name of the settlement report file.
+	FileName string `protobuf:"bytes,2,opt,name=file_name,json=fileName,proto3" json:"file_name" validate:"omitempty,max=255"`
+	// The content of the settlement report file.
+	File []byte `protobuf:"bytes,3,opt,name=file,proto3" json:"file" validate:"required"`
+	// The unique identifier for the user who imports the settlement report.
+	UserId string `protobuf:"bytes,4,opt,name=user_id,json=userId,proto3" json:"user_id" validate:"omitempty,hexadecimal,len=24"`
+	// The identifier of the settlement report assigned by the payment system. If it's empty, the SHA-256 hash of the
+	// file content is used. The report with the same identifier can be imported only once.
+	ReportId string `protobuf:"bytes,5,opt,name=report_id,json=reportId,proto3" json:"report_id" validate:"omitempty,max=255"`
+}
+
+func (m *ImportSettlementReportRequest) Reset()         { *m = ImportSettlementReportRequest{} }
+func (m *ImportSettlementReportRequest) String() string { return proto.CompactTextString(m) }
+func (*ImportSettlementReportRequest) ProtoMessage()    {}
+
+type ReconciliationDailyTotal struct {
+	// The day of unmatched items in the format YYYY-MM-DD.
+	Date string `protobuf:"bytes,1,opt,name=date,proto3" json:"date"`
+	// Three-letter currency code by ISO 4217, in uppercase.
+	Currency string `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency"`
+	// The amount of transactions which are absent in the settlement report.
+	MissingAmount float64 `protobuf:"fixed64,3,opt,name=missing_amount,json=missingAmount,proto3" json:"missing_amount"`
+	// The amount of settled transactions unknown for the billing server.
+	ExtraAmount float64 `protobuf:"fixed64,4,opt,name=extra_amount,json=extraAmount,proto3" json:"extra_amount"`
+	// The difference between settled and expected amounts of transactions with amount mismatch.
+	AmountDifference float64 `protobuf:"fixed64,5,opt,name=amount_difference,json=amountDifference,proto3" json:"amount_difference"`
+	// The difference between settled and expected fees of transactions with fee mismatch.
+	FeeDifference float64 `protobuf:"fixed64,6,opt,name=fee_difference,json=feeDifference,proto3" json:"fee_difference"`
+}
+
+func (m *ReconciliationDailyTotal) Reset()         { *m = ReconciliationDailyTotal{} }
+func (m *ReconciliationDailyTotal) String() string { return proto.CompactTextString(m) }
+func (*ReconciliationDailyTotal) ProtoMessage()    {}
+
+type ReconciliationRun struct {
+	// The unique identifier for the reconciliation run.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The handler of the payment system.
+	PaymentSystem string `protobuf:"bytes,2,opt,name=payment_system,json=paymentSystem,proto3" json:"payment_system"`
+	// The name of the settlement report file.
+	FileName string `protobuf:"bytes,3,opt,name=file_name,json=fileName,proto3" json:"file_name"`
+	// The reconciliation run status. Available values: open, resolved.
+	Status string `protobuf:"bytes,4,opt,name=status,proto3" json:"status"`
+	// The first day of the settlement report.
+	PeriodFrom *timestamp.Timestamp `protobuf:"bytes,5,opt,name=period_from,json=periodFrom,proto3" json:"period_from"`
+	// The end of the last day of the settlement report.
+	PeriodTo *timestamp.Timestamp `protobuf:"bytes,6,opt,name=period_to,json=periodTo,proto3" json:"period_to"`
+	// The number of transactions in the settlement report.
+	LinesCount int32 `protobuf:"varint,7,opt,name=lines_count,json=linesCount,proto3" json:"lines_count"`
+	// The number of transactions matched to orders and refunds without differences.
+	MatchedCount int32 `protobuf:"varint,8,opt,name=matched_count,json=matchedCount,proto3" json:"matched_count"`
+	// The number of transactions which are absent in the settlement report.
+	MissingCount int32 `protobuf:"varint,9,opt,name=missing_count,json=missingCount,proto3" json:"missing_count"`
+	// The number of settled transactions unknown for the billing server.
+	ExtraCount int32 `protobuf:"varint,10,opt,name=extra_count,json=extraCount,proto3" json:"extra_count"`
+	// The number of transactions with amount mismatch.
+	AmountMismatchCount int32 `protobuf:"varint,11,opt,name=amount_mismatch_count,json=amountMismatchCount,proto3" json:"amount_mismatch_count"`
+	// The number of transactions with fee mismatch.
+	FeeMismatchCount int32 `protobuf:"varint,12,opt,name=fee_mismatch_count,json=feeMismatchCount,proto3" json:"fee_mismatch_count"`
+	// The number of unmatched items which aren't resolved yet.
+	UnresolvedCount int32 `protobuf:"varint,13,opt,name=unresolved_count,json=unresolvedCount,proto3" json:"unresolved_count"`
+	// The totals of unmatched items by days and currencies.
+	DailyTotals []*ReconciliationDailyTotal `protobuf:"bytes,14,rep,name=daily_totals,json=dailyTotals,proto3" json:"daily_totals"`
+	// The unique identifier for the user who imported the settlement report.
+	CreatorId string `protobuf:"bytes,15,opt,name=creator_id,json=creatorId,proto3" json:"creator_id"`
+	// The date of the reconciliation run creation.
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,16,opt,name=created_at,json=createdAt,proto3" json:"created_at"`
+	// The date of the reconciliation run last update.
+	UpdatedAt *timestamp.Timestamp `protobuf:"bytes,17,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at"`
+	// The identifier of the settlement report.
+	ReportId string `protobuf:"bytes,18,opt,name=report_id,json=reportId,proto3" json:"report_id"`
+}
+
+func (m *ReconciliationRun) Reset()         { *m = ReconciliationRun{} }
+func (m *ReconciliationRun) String() string { return proto.CompactTextString(m) }
+func (*ReconciliationRun) ProtoMessage()    {}
+
+type ReconciliationItem struct {
+	// The unique identifier for the reconciliation item.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The unique identifier for the reconciliation run.
+	RunId string `protobuf:"bytes,2,opt,name=run_id,json=runId,proto3" json:"run_id"`
+	// The reconciliation item status. Available values: matched, missing, extra, amount_mismatch, fee_mismatch.
+	Status string `protobuf:"bytes,3,opt,name=status,proto3" json:"status"`
+	// The transaction type. Available values: payment, refund.
+	Type string `protobuf:"bytes,4,opt,name=type,proto3" json:"type"`
+	// The identifier of the transaction in the payment system.
+	TransactionId string `protobuf:"bytes,5,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id"`
+	// The unique identifier for the order of the payment or the refund.
+	OrderId string `protobuf:"bytes,6,opt,name=order_id,json=orderId,proto3" json:"order_id"`
+	// The unique identifier for the refund.
+	RefundId string `protobuf:"bytes,7,opt,name=refund_id,json=refundId,proto3" json:"refund_id"`
+	// The line number of the transaction in the settlement report. Zero value for missing transactions.
+	Line int32 `protobuf:"varint,8,opt,name=line,proto3" json:"line"`
+	// The date of the transaction.
+	Date *timestamp.Timestamp `protobuf:"bytes,9,opt,name=date,proto3" json:"date"`
+	// The settled amount of the transaction.
+	Amount float64 `protobuf:"fixed64,10,opt,name=amount,proto3" json:"amount"`
+	// The fee of the payment system charged for the transaction.
+	Fee float64 `protobuf:"fixed64,11,opt,name=fee,proto3" json:"fee"`
+	// The settled currency. Three-letter currency code by ISO 4217, in uppercase.
+	Currency string `protobuf:"bytes,12,opt,name=currency,proto3" json:"currency"`
+	// The amount of the transaction by the billing server.
+	ExpectedAmount float64 `protobuf:"fixed64,13,opt,name=expected_amount,json=expectedAmount,proto3" json:"expected_amount"`
+	// The fee of the payment system by the accounting entries of the transaction.
+	ExpectedFee float64 `protobuf:"fixed64,14,opt,name=expected_fee,json=expectedFee,proto3" json:"expected_fee"`
+	// The currency of the transaction by the billing server.
+	ExpectedCurrency string `protobuf:"bytes,15,opt,name=expected_currency,json=expectedCurrency,proto3" json:"expected_currency"`
+	// Has a boolean value true if the unmatched item is resolved by the finance team.
+	IsResolved bool `protobuf:"varint,16,opt,name=is_resolved,json=isResolved,proto3" json:"is_resolved"`
+	// The comment of the finance team about the resolution.
+	Resolution string `protobuf:"bytes,17,opt,name=resolution,proto3" json:"resolution"`
+	// The unique identifier for the user who resolved the item.
+	ResolvedBy string `protobuf:"bytes,18,opt,name=resolved_by,json=resolvedBy,proto3" json:"resolved_by"`
+	// The date of the item resolution.
+	ResolvedAt *timestamp.Timestamp `protobuf:"bytes,19,opt,name=resolved_at,json=resolvedAt,proto3" json:"resolved_at"`
+}
+
+func (m *ReconciliationItem) Reset()         { *m = ReconciliationItem{} }
+func (m *ReconciliationItem) String() string { return proto.CompactTextString(m) }
+func (*ReconciliationItem) ProtoMessage()    {}
+
+type GetReconciliationRunRequest struct {
+	// The unique identifier for the reconciliation run.
+	RunId string `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id" validate:"required,hexadecimal,len=24"`
+}
+
+func (m *GetReconciliationRunRequest) Reset()         { *m = GetReconciliationRunRequest{} }
+func (m *GetReconciliationRunRequest) String() string { return proto.CompactTextString(m) }
+func (*GetReconciliationRunRequest) ProtoMessage()    {}
+
+type ListReconciliationRunsRequest struct {
+	// The handler of the payment system.
+	PaymentSystem string `protobuf:"bytes,1,opt,name=payment_system,json=paymentSystem,proto3" json:"payment_system"`
+	// The reconciliation run status. Available values: open, resolved.
+	Status string `protobuf:"bytes,2,opt,name=status,proto3" json:"status" validate:"omitempty,oneof=open resolved"`
+	// The number of reconciliation runs returned in one page. Default value is 100.
+	Limit int64 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit" validate:"omitempty,numeric,gte=0"`
+	// The ranking number of the first item on the page.
+	Offset int64 `protobuf:"varint,4,opt,name=offset,proto3" json:"offset" validate:"omitempty,numeric,gte=0"`
+}
+
+func (m *ListReconciliationRunsRequest) Reset()         { *m = ListReconciliationRunsRequest{} }
+func (m *ListReconciliationRunsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListReconciliationRunsRequest) ProtoMessage()    {}
+
+type ListReconciliationItemsRequest struct {
+	// The unique identifier for the reconciliation run.
+	RunId string `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id" validate:"required,hexadecimal,len=24"`
+	// The reconciliation item status. Available values: matched, missing, extra, amount_mismatch, fee_mismatch.
+	Status string `protobuf:"bytes,2,opt,name=status,proto3" json:"status" validate:"omitempty,oneof=matched missing extra amount_mismatch fee_mismatch"`
+	// The number of reconciliation items returned in one page. Default value is 100.
+	Limit int64 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit" validate:"omitempty,numeric,gte=0"`
+	// The ranking number of the first item on the page.
+	Offset int64 `protobuf:"varint,4,opt,name=offset,proto3" json:"offset" validate:"omitempty,numeric,gte=0"`
+}
+
+func (m *ListReconciliationItemsRequest) Reset()         { *m = ListReconciliationItemsRequest{} }
+func (m *ListReconciliationItemsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListReconciliationItemsRequest) ProtoMessage()    {}
+
+type ResolveReconciliationItemRequest struct {
+	// The unique identifier for the reconciliation item.
+	ItemId string `protobuf:"bytes,1,opt,name=item_id,json=itemId,proto3" json:"item_id" validate:"required,hexadecimal,len=24"`
+	// The comment of the finance team about the resolution.
+	Resolution string `protobuf:"bytes,2,opt,name=resolution,proto3" json:"resolution" validate:"required,max=1000"`
+	// The unique identifier for the user who resolves the item.
+	UserId string `protobuf:"bytes,3,opt,name=user_id,json=userId,proto3" json:"user_id" validate:"omitempty,hexadecimal,len=24"`
+}
+
+func (m *ResolveReconciliationItemRequest) Reset()         { *m = ResolveReconciliationItemRequest{} }
+func (m *ResolveReconciliationItemRequest) String() string { return proto.CompactTextString(m) }
+func (*ResolveReconciliationItemRequest) ProtoMessage()    {}
+
+type ReconciliationRunResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *ReconciliationRun              `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *ReconciliationRunResponse) Reset()         { *m = ReconciliationRunResponse{} }
+func (m *ReconciliationRunResponse) String() string { return proto.CompactTextString(m) }
+func (*ReconciliationRunResponse) ProtoMessage()    {}
+
+func (m *ReconciliationRunResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ListReconciliationRunsResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Count   int64                           `protobuf:"varint,3,opt,name=count,proto3" json:"count"`
+	Items   []*ReconciliationRun            `protobuf:"bytes,4,rep,name=items,proto3" json:"items"`
+}
+
+func (m *ListReconciliationRunsResponse) Reset()         { *m = ListReconciliationRunsResponse{} }
+func (m *ListReconciliationRunsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListReconciliationRunsResponse) ProtoMessage()    {}
+
+func (m *ListReconciliationRunsResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ReconciliationItemResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *ReconciliationItem             `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *ReconciliationItemResponse) Reset()         { *m = ReconciliationItemResponse{} }
+func (m *ReconciliationItemResponse) String() string { return proto.CompactTextString(m) }
+func (*ReconciliationItemResponse) ProtoMessage()    {}
+
+func (m *ReconciliationItemResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ListReconciliationItemsResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Count   int64                           `protobuf:"varint,3,opt,name=count,proto3" json:"count"`
+	Items   []*ReconciliationItem           `protobuf:"bytes,4,rep,name=items,proto3" json:"items"`
+}
+
+func (m *ListReconciliationItemsResponse) Reset()         { *m = ListReconciliationItemsResponse{} }
+func (m *ListReconciliationItemsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListReconciliationItemsResponse) ProtoMessage()    {}
+
+func (m *ListReconciliationItemsResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ExportAccountingEntriesRequest struct {
+	// The unique identifier for the operating company. Entries of all operating companies are exported if it's empty.
+	OperatingCompanyId string `protobuf:"bytes,1,opt,name=operating_company_id,json=operatingCompanyId,proto3" json:"operating_company_id" validate:"omitempty,hexadecimal,len=24"`
+	// The unique identifier for the merchant. Entries of all merchants are exported if it's empty.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"omitempty,hexadecimal,len=24"`
+	// The start date of the period in Unix time.
+	DateFrom int64 `protobuf:"varint,3,opt,name=date_from,json=dateFrom,proto3" json:"date_from" validate:"required,numeric,gt=0"`
+	// The end date of the period in Unix time. Entries created at this date aren't exported.
+	DateTo int64 `protobuf:"varint,4,opt,name=date_to,json=dateTo,proto3" json:"date_to" validate:"required,numeric,gt=0"`
+	// The format of the export file. Available values: csv, gl_csv. Default value is csv.
+	Format string `protobuf:"bytes,5,opt,name=format,proto3" json:"format" validate:"omitempty,oneof=csv gl_csv"`
+}
+
+func (m *ExportAccountingEntriesRequest) Reset()         { *m = ExportAccountingEntriesRequest{} }
+func (m *ExportAccountingEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*ExportAccountingEntriesRequest) ProtoMessage()    {}
+
+type AccountingExport struct {
+	// The name of the export file.
+	FileName string `protobuf:"bytes,1,opt,name=file_name,json=fileName,proto3" json:"file_name"`
+	// The MIME type of the export file.
+	ContentType string `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type"`
+	// The content of the export file.
+	File []byte `protobuf:"bytes,3,opt,name=file,proto3" json:"file"`
+	// The number of rows in the export file without the header.
+	RowsCount int32 `protobuf:"varint,4,opt,name=rows_count,json=rowsCount,proto3" json:"rows_count"`
+}
+
+func (m *AccountingExport) Reset()         { *m = AccountingExport{} }
+func (m *AccountingExport) String() string { return proto.CompactTextString(m) }
+func (*AccountingExport) ProtoMessage()    {}
+
+type ExportAccountingEntriesResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *AccountingExport               `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *ExportAccountingEntriesResponse) Reset()         { *m = ExportAccountingEntriesResponse{} }
+func (m *ExportAccountingEntriesResponse) String() string { return proto.CompactTextString(m) }
+func (*ExportAccountingEntriesResponse) ProtoMessage()    {}
+
+func (m *ExportAccountingEntriesResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type AuditLogChange struct {
+	// The name of the changed field. Names of nested fields are separated by dots.
+	Field string `protobuf:"bytes,1,opt,name=field,proto3" json:"field"`
+	// The JSON representation of the field value before the change.
+	Before string `protobuf:"bytes,2,opt,name=before,proto3" json:"before"`
+	// The JSON representation of the field value after the change.
+	After string `protobuf:"bytes,3,opt,name=after,proto3" json:"after"`
+}
+
+func (m *AuditLogChange) Reset()         { *m = AuditLogChange{} }
+func (m *AuditLogChange) String() string { return proto.CompactTextString(m) }
+func (*AuditLogChange) ProtoMessage()    {}
+
+type AuditLogEntry struct {
+	// The unique identifier for the audit log entry.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The sequence number of the entry in the audit log chain.
+	Sequence int64 `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence"`
+	// The unique identifier for the user who made the change.
+	ActorId string `protobuf:"bytes,3,opt,name=actor_id,json=actorId,proto3" json:"actor_id"`
+	// The IP address of the user who made the change.
+	Ip string `protobuf:"bytes,4,opt,name=ip,proto3" json:"ip"`
+	// The name of the RPC which made the change.
+	Rpc string `protobuf:"bytes,5,opt,name=rpc,proto3" json:"rpc"`
+	// The type of the changed entity. Available values: merchant, payment_channel_cost_system,
+	// payment_channel_cost_merchant, money_back_cost_system, money_back_cost_merchant, payout_document, user_role,
+	// accounting_entry, merchant_balance_conversion.
+	EntityType string `protobuf:"bytes,6,opt,name=entity_type,json=entityType,proto3" json:"entity_type"`
+	// The unique identifier for the changed entity.
+	EntityId string `protobuf:"bytes,7,opt,name=entity_id,json=entityId,proto3" json:"entity_id"`
+	// The list of changed fields of the entity.
+	Changes []*AuditLogChange `protobuf:"bytes,8,rep,name=changes,proto3" json:"changes"`
+	// The hash of the previous entry of the audit log chain.
+	PreviousHash string `protobuf:"bytes,9,opt,name=previous_hash,json=previousHash,proto3" json:"previous_hash"`
+	// The hash of the entry.
+	Hash string `protobuf:"bytes,10,opt,name=hash,proto3" json:"hash"`
+	// The date of the change.
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,11,opt,name=created_at,json=createdAt,proto3" json:"created_at"`
+}
+
+func (m *AuditLogEntry) Reset()         { *m = AuditLogEntry{} }
+func (m *AuditLogEntry) String() string { return proto.CompactTextString(m) }
+func (*AuditLogEntry) ProtoMessage()    {}
+
+type ListAuditLogRequest struct {
+	// The type of the changed entity.
+	EntityType string `protobuf:"bytes,1,opt,name=entity_type,json=entityType,proto3" json:"entity_type"`
+	// The unique identifier for the changed entity.
+	EntityId string `protobuf:"bytes,2,opt,name=entity_id,json=entityId,proto3" json:"entity_id"`
+	// The unique identifier for the user who made the changes.
+	ActorId string `protobuf:"bytes,3,opt,name=actor_id,json=actorId,proto3" json:"actor_id"`
+	// The start date of the period when the changes were made.
+	DateFrom int64 `protobuf:"varint,4,opt,name=date_from,json=dateFrom,proto3" json:"date_from" validate:"omitempty,numeric,gt=0"`
+	// The end date of the period when the changes were made.
+	DateTo int64 `protobuf:"varint,5,opt,name=date_to,json=dateTo,proto3" json:"date_to" validate:"omitempty,numeric,gt=0"`
+	// The number of entries returned in one page. Default value is 100.
+	Limit int64 `protobuf:"varint,6,opt,name=limit,proto3" json:"limit" validate:"omitempty,numeric,gte=0"`
+	// The ranking number of the first item on the page.
+	Offset int64 `protobuf:"varint,7,opt,name=offset,proto3" json:"offset" validate:"omitempty,numeric,gte=0"`
+}
+
+func (m *ListAuditLogRequest) Reset()         { *m = ListAuditLogRequest{} }
+func (m *ListAuditLogRequest) String() string { return proto.CompactTextString(m) }
+func (*ListAuditLogRequest) ProtoMessage()    {}
+
+type ListAuditLogResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Count   int64                           `protobuf:"varint,3,opt,name=count,proto3" json:"count"`
+	Items   []*AuditLogEntry                `protobuf:"bytes,4,rep,name=items,proto3" json:"items"`
+}
+
+func (m *ListAuditLogResponse) Reset()         { *m = ListAuditLogResponse{} }
+func (m *ListAuditLogResponse) String() string { return proto.CompactTextString(m) }
+func (*ListAuditLogResponse) ProtoMessage()    {}
+
+func (m *ListAuditLogResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type AuditLogVerification struct {
+	// Has true value if the audit log chain isn't broken.
+	IsValid bool `protobuf:"varint,1,opt,name=is_valid,json=isValid,proto3" json:"is_valid"`
+	// The number of checked entries.
+	EntriesCount int64 `protobuf:"varint,2,opt,name=entries_count,json=entriesCount,proto3" json:"entries_count"`
+	// The sequence number of the first entry which is changed or removed.
+	BrokenSequence int64 `protobuf:"varint,3,opt,name=broken_sequence,json=brokenSequence,proto3" json:"broken_sequence,omitempty"`
+}
+
+func (m *AuditLogVerification) Reset()         { *m = AuditLogVerification{} }
+func (m *AuditLogVerification) String() string { return proto.CompactTextString(m) }
+func (*AuditLogVerification) ProtoMessage()    {}
+
+type VerifyAuditLogResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *AuditLogVerification           `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *VerifyAuditLogResponse) Reset()         { *m = VerifyAuditLogResponse{} }
+func (m *VerifyAuditLogResponse) String() string { return proto.CompactTextString(m) }
+func (*VerifyAuditLogResponse) ProtoMessage()    {}
+
+func (m *VerifyAuditLogResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ListMerchantBalancesRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+}
+
+func (m *ListMerchantBalancesRequest) Reset()         { *m = ListMerchantBalancesRequest{} }
+func (m *ListMerchantBalancesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListMerchantBalancesRequest) ProtoMessage()    {}
+
+type ListMerchantBalancesResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Items   []*billingpb.MerchantBalance    `protobuf:"bytes,3,rep,name=items,proto3" json:"items"`
+}
+
+func (m *ListMerchantBalancesResponse) Reset()         { *m = ListMerchantBalancesResponse{} }
+func (m *ListMerchantBalancesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListMerchantBalancesResponse) ProtoMessage()    {}
+
+func (m *ListMerchantBalancesResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type MerchantBalanceConversion struct {
+	// The unique identifier for the conversion.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id"`
+	// The three-letter currency code of the balance from which the amount is converted.
+	FromCurrency string `protobuf:"bytes,3,opt,name=from_currency,json=fromCurrency,proto3" json:"from_currency"`
+	// The amount withdrawn from the balance.
+	FromAmount float64 `protobuf:"fixed64,4,opt,name=from_amount,json=fromAmount,proto3" json:"from_amount"`
+	// The three-letter currency code of the balance to which the amount is converted.
+	ToCurrency string `protobuf:"bytes,5,opt,name=to_currency,json=toCurrency,proto3" json:"to_currency"`
+	// The amount added to the balance.
+	ToAmount float64 `protobuf:"fixed64,6,opt,name=to_amount,json=toAmount,proto3" json:"to_amount"`
+	// The exchange rate used for the conversion.
+	Rate float64 `protobuf:"fixed64,7,opt,name=rate,proto3" json:"rate"`
+	// The type of the exchange rate.
+	RateType string `protobuf:"bytes,8,opt,name=rate_type,json=rateType,proto3" json:"rate_type"`
+	// The unique identifier for the payout document which includes the withdrawn amount.
+	FromPayoutDocumentId string `protobuf:"bytes,9,opt,name=from_payout_document_id,json=fromPayoutDocumentId,proto3" json:"from_payout_document_id"`
+	// The unique identifier for the payout document which includes the added amount.
+	ToPayoutDocumentId string `protobuf:"bytes,10,opt,name=to_payout_document_id,json=toPayoutDocumentId,proto3" json:"to_payout_document_id"`
+	// The unique identifier for the user who requested the conversion.
+	UserId string `protobuf:"bytes,11,opt,name=user_id,json=userId,proto3" json:"user_id"`
+	// The date of the conversion.
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,12,opt,name=created_at,json=createdAt,proto3" json:"created_at"`
+}
+
+func (m *MerchantBalanceConversion) Reset()         { *m = MerchantBalanceConversion{} }
+func (m *MerchantBalanceConversion) String() string { return proto.CompactTextString(m) }
+func (*MerchantBalanceConversion) ProtoMessage()    {}
+
+type ConvertMerchantBalanceRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The three-letter currency code of the balance from which the amount is converted.
+	FromCurrency string `protobuf:"bytes,2,opt,name=from_currency,json=fromCurrency,proto3" json:"from_currency" validate:"required,alpha,len=3"`
+	// The three-letter currency code of the balance to which the amount is converted.
+	ToCurrency string `protobuf:"bytes,3,opt,name=to_currency,json=toCurrency,proto3" json:"to_currency" validate:"required,alpha,len=3"`
+	// The amount to convert in the currency of the source balance.
+	Amount float64 `protobuf:"fixed64,4,opt,name=amount,proto3" json:"amount" validate:"required,numeric,gt=0"`
+	// The unique identifier for the user who requests the conversion.
+	UserId string `protobuf:"bytes,5,opt,name=user_id,json=userId,proto3" json:"user_id" validate:"omitempty,hexadecimal,len=24"`
+}
+
+func (m *ConvertMerchantBalanceRequest) Reset()         { *m = ConvertMerchantBalanceRequest{} }
+func (m *ConvertMerchantBalanceRequest) String() string { return proto.CompactTextString(m) }
+func (*ConvertMerchantBalanceRequest) ProtoMessage()    {}
+
+type ConvertMerchantBalanceResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Item    *MerchantBalanceConversion      `protobuf:"bytes,3,opt,name=item,proto3" json:"item,omitempty"`
+}
+
+func (m *ConvertMerchantBalanceResponse) Reset()         { *m = ConvertMerchantBalanceResponse{} }
+func (m *ConvertMerchantBalanceResponse) String() string { return proto.CompactTextString(m) }
+func (*ConvertMerchantBalanceResponse) ProtoMessage()    {}
+
+func (m *ConvertMerchantBalanceResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+type ListMerchantBalanceConversionsRequest struct {
+	// The unique identifier for the merchant.
+	MerchantId string `protobuf:"bytes,1,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id" validate:"required,hexadecimal,len=24"`
+	// The three-letter currency code of the balance. Conversions in all currencies are returned if it's empty.
+	Currency string `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency" validate:"omitempty,alpha,len=3"`
+	// The number of conversions returned in one page. Default value is 100.
+	Limit int64 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit" validate:"omitempty,numeric,gte=0"`
+	// The ranking number of the first item on the page.
+	Offset int64 `protobuf:"varint,4,opt,name=offset,proto3" json:"offset" validate:"omitempty,numeric,gte=0"`
+}
+
+func (m *ListMerchantBalanceConversionsRequest) Reset()         { *m = ListMerchantBalanceConversionsRequest{} }
+func (m *ListMerchantBalanceConversionsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListMerchantBalanceConversionsRequest) ProtoMessage()    {}
+
+type ListMerchantBalanceConversionsResponse struct {
+	Status  int32                           `protobuf:"varint,1,opt,name=status,proto3" json:"status"`
+	Message *billingpb.ResponseErrorMessage `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Count   int64                           `protobuf:"varint,3,opt,name=count,proto3" json:"count"`
+	Items   []*MerchantBalanceConversion    `protobuf:"bytes,4,rep,name=items,proto3" json:"items"`
+}
+
+func (m *ListMerchantBalanceConversionsResponse) Reset() {
+	*m = ListMerchantBalanceConversionsResponse{}
+}
+func (m *ListMerchantBalanceConversionsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListMerchantBalanceConversionsResponse) ProtoMessage()    {}
+
+func (m *ListMerchantBalanceConversionsResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
@@ -26,6 +26,8 @@ const (
 	RefundStatusCompleted             = int32(3)
 	RefundStatusPaymentSystemDeclined = int32(4)
 	RefundStatusPaymentSystemCanceled = int32(5)
+	RefundStatusPendingApproval       = int32(6)
+	RefundStatusFallbackInProgress    = int32(7)
 
 	PaymentSystemErrorCreateRefundFailed   = "refund can't be create. try request later"
 	PaymentSystemErrorCreateRefundRejected = "refund create request rejected"
@@ -87,6 +89,15 @@ const (
 	AccountingEntryTypeRealRefundTaxFee                = "real_refund_tax_fee"
 	AccountingEntryTypeRealRefundFee                   = "real_refund_fee"
 	AccountingEntryTypeRealRefundFixedFee              = "real_refund_fixed_fee"
+	AccountingEntryTypeRefundBankTransfer              = "refund_bank_transfer"
+	AccountingEntryTypeRefundStoreCredit               = "refund_store_credit"
+	AccountingEntryTypeWalletGoodwill                  = "wallet_goodwill"
+	AccountingEntryTypeWalletPayment                   = "wallet_payment"
+	AccountingEntryTypeWalletPaymentTaxFee             = "wallet_payment_tax_fee"
+	AccountingEntryTypeWalletRefund                    = "wallet_refund"
+	AccountingEntryTypeWalletRefundTaxFee              = "wallet_refund_tax_fee"
+	AccountingEntryTypeGiftCardPayment                 = "gift_card_payment"
+	AccountingEntryTypeGiftCardBreakage                = "gift_card_breakage"
 	AccountingEntryTypeMerchantRefund                  = "merchant_refund"
 	AccountingEntryTypePsMerchantRefundFx              = "ps_merchant_refund_fx"
 	AccountingEntryTypeMerchantRefundFee               = "merchant_refund_fee"
@@ -105,6 +116,15 @@ const (
 	AccountingEntryTypeMerchantRollingReserveRelease   = "merchant_rolling_reserve_release"
 	AccountingEntryTypeMerchantRoyaltyCorrection       = "merchant_royalty_correction"
 
+	// Accounts of the double-entry ledger. Assets are increased by debits, liabilities and revenue by credits.
+	LedgerAccountGatewayReceivable = "gateway_receivable"
+	LedgerAccountMerchantPayable   = "merchant_payable"
+	LedgerAccountPlatformRevenue   = "platform_revenue"
+	LedgerAccountTaxPayable        = "tax_payable"
+	LedgerAccountRollingReserve    = "rolling_reserve"
+	LedgerAccountCustomerCredit    = "customer_credit"
+	LedgerAccountRefundPayable     = "refund_payable"
+
 	BalanceTransactionStatusAvailable = "available"
 
 	ErrorTimeConversion       = "Time conversion error"
@@ -209,6 +229,225 @@ const (
 	PaymentSystemActionRecurringPlan               = "recurring_plans"
 	PaymentSystemActionDeleteRecurringPlan         = "recurring_plans_delete"
 	PaymentSystemActionUpdateRecurringSubscription = "recurring_subscription_update"
+	PaymentSystemActionUpdatePayment               = "payment_update"
+
+	PaymentSystemHandlerCheckout  = "checkout"
+	PaymentSystemHandlerSimulator = "simulator"
+
+	OrderHistoryTypePaymentSystemSwitched = "payment_system_switched"
+	OrderHistoryTypePaymentCaptured       = "payment_captured"
+	OrderHistoryTypePaymentVoided         = "payment_voided"
+	OrderHistoryTypeDisputeOpened         = "dispute_opened"
+	OrderHistoryTypeDisputeClosed         = "dispute_closed"
+	OrderHistoryTypeFraudScreening        = "fraud_screening"
+	OrderHistoryTypeReviewHeld            = "review_held"
+	OrderHistoryTypeReviewResolved        = "review_resolved"
+	OrderHistoryTypePromoCodeApplied      = "promo_code_applied"
+	OrderHistoryTypePromoCodeRemoved      = "promo_code_removed"
+	OrderHistoryTypeWalletApplied         = "wallet_applied"
+	OrderHistoryTypeWalletRemoved         = "wallet_removed"
+	OrderHistoryTypeGiftCardApplied       = "gift_card_applied"
+	OrderHistoryTypeGiftCardRemoved       = "gift_card_removed"
+
+	OrderHistoryFieldPaymentSystemFrom = "payment_system_from"
+	OrderHistoryFieldPaymentSystemTo   = "payment_system_to"
+	OrderHistoryFieldReason            = "reason"
+	OrderHistoryFieldAuthorizedAmount  = "authorized_amount"
+	OrderHistoryFieldCapturedAmount    = "captured_amount"
+	OrderHistoryFieldDisputeId         = "dispute_id"
+	OrderHistoryFieldReasonCode        = "reason_code"
+	OrderHistoryFieldOutcome           = "outcome"
+	OrderHistoryFieldFraudScore        = "fraud_score"
+	OrderHistoryFieldFraudAction       = "fraud_action"
+	OrderHistoryFieldFraudRules        = "fraud_rules"
+	OrderHistoryFieldReviewId          = "review_id"
+	OrderHistoryFieldReviewStatus      = "review_status"
+	OrderHistoryFieldReviewerId        = "reviewer_id"
+	OrderHistoryFieldPromoCode         = "promo_code"
+	OrderHistoryFieldDiscountAmount    = "discount_amount"
+	OrderHistoryFieldWalletAmount      = "wallet_amount"
+	OrderHistoryFieldGiftCardId        = "gift_card_id"
+	OrderHistoryFieldGiftCardAmount    = "gift_card_amount"
+
+	// Private statuses of the order for two-step payments. Values are out of range of statuses declared in recurringpb.
+	OrderStatusPaymentSystemAuthorized = int32(100)
+	OrderStatusPaymentSystemVoided     = int32(101)
+
+	// Private status of the order which payment is held by the fraud screening until manual review.
+	OrderStatusReviewHeld = int32(102)
+
+	// Key of the order private metadata with the capture mode of the payment, for example "capture_mode": "manual".
+	// The value is set from the project capture settings, values passed in the order request are ignored.
+	OrderPrivateMetadataCaptureMode = "capture_mode"
+	OrderCaptureModeManual          = "manual"
+
+	// Key of the order private metadata set while the authorized payment is being captured or voided
+	OrderPrivateMetadataPaymentUpdate = "payment_update"
+
+	// Keys of the order private metadata with the result of the fraud screening of the payment
+	OrderPrivateMetadataFraudScore  = "fraud_score"
+	OrderPrivateMetadataFraudAction = "fraud_action"
+	OrderPrivateMetadataFraudRules  = "fraud_rules"
+
+	// Key of the order private metadata with the status of the manual review of the held payment
+	OrderPrivateMetadataReviewStatus = "review_status"
+
+	// Keys of the order private metadata with the promo code applied to the order
+	OrderPrivateMetadataPromoCodeId          = "promo_code_id"
+	OrderPrivateMetadataPromoCode            = "promo_code"
+	OrderPrivateMetadataDiscountAmount       = "discount_amount"
+	OrderPrivateMetadataAmountBeforeDiscount = "amount_before_discount"
+
+	FraudActionAllow   = "allow"
+	FraudActionReview  = "review"
+	FraudActionDecline = "decline"
+
+	FraudRuleCustomerVelocity = "customer_velocity"
+	FraudRuleIpVelocity       = "ip_velocity"
+	FraudRuleBinVelocity      = "bin_velocity"
+	FraudRuleCountryMismatch  = "ip_bin_country_mismatch"
+	FraudRuleEmailDomain      = "email_domain_blacklist"
+
+	DisputeStatusOpen              = "open"
+	DisputeStatusEvidenceSubmitted = "evidence_submitted"
+	DisputeStatusWon               = "won"
+	DisputeStatusLost              = "lost"
+
+	PromoCodeTypePercent = "percent"
+	PromoCodeTypeFixed   = "fixed"
+
+	PaymentMethodRuleActionAllow = "allow"
+	PaymentMethodRuleActionDeny  = "deny"
+
+	OrderReviewStatusHeld     = "held"
+	OrderReviewStatusApproved = "approved"
+	OrderReviewStatusRejected = "rejected"
+	OrderReviewStatusExpired  = "expired"
+
+	// Keys of the order private metadata with the trial settings of the recurring subscription, for example
+	// "trial_days": "14", "trial_price": "1.99". Trial price is in the order currency, the minimal
+	// trial price by default as the payment system doesn't accept the zero amount initial payment.
+	OrderPrivateMetadataTrialDays  = "trial_days"
+	OrderPrivateMetadataTrialPrice = "trial_price"
+
+	// Keys of the order private metadata with amounts of the regular payments of the subscription with trial
+	OrderPrivateMetadataTrialRegularAmount = "trial_regular_amount"
+	OrderPrivateMetadataTrialPlanAmount    = "trial_plan_amount"
+
+	// Key of the order private metadata with the identifier of the subscription plan change charged by the order
+	OrderPrivateMetadataSubscriptionPlanChange = "subscription_plan_change"
+
+	// Key of the order private metadata with the identifier of the subscription dunning retried by the order
+	OrderPrivateMetadataSubscriptionDunning = "subscription_dunning"
+
+	// Key of the order private metadata with the identifier of the saved card update verified by the order
+	OrderPrivateMetadataSavedCardUpdate = "saved_card_update"
+
+	// Keys of the order private metadata with the customer wallet paying the order. Wallet amount is in the order
+	// currency, the rest of the order amount is charged by the payment system. Wallet is charged before the payment
+	// system charges the rest and is returned when the payment is declined.
+	OrderPrivateMetadataWalletId      = "wallet_id"
+	OrderPrivateMetadataWalletAmount  = "wallet_amount"
+	OrderPrivateMetadataWalletCharged = "wallet_charged"
+
+	// Keys of the order private metadata with the gift card paying the order. Gift card amount is in the order
+	// currency, the gift card is charged when order is paid.
+	OrderPrivateMetadataGiftCardId      = "gift_card_id"
+	OrderPrivateMetadataGiftCardAmount  = "gift_card_amount"
+	OrderPrivateMetadataGiftCardCharged = "gift_card_charged"
+
+	// Statuses of the plan change of the recurring subscription. Plan is changed when the proration charge is paid,
+	// the change is failed by the declined charge or the plan update error.
+	SubscriptionPlanChangeStatusPending   = "pending"
+	SubscriptionPlanChangeStatusCompleted = "completed"
+	SubscriptionPlanChangeStatusFailed    = "failed"
+
+	SubscriptionTrialStatusActive    = "active"
+	SubscriptionTrialStatusConverted = "converted"
+	SubscriptionTrialStatusCanceled  = "canceled"
+
+	// Statuses of the dunning of the recurring subscription with failed payment. Failed payment is retried by
+	// the schedule while the subscription is past due, after the last failed retry the subscription becomes unpaid
+	// and it's cancelled at the end of the unpaid period. Subscription paid by one of retries is recovered.
+	SubscriptionDunningStatusPastDue   = "past_due"
+	SubscriptionDunningStatusUnpaid    = "unpaid"
+	SubscriptionDunningStatusCancelled = "cancelled"
+	SubscriptionDunningStatusRecovered = "recovered"
+
+	SubscriptionDunningMaxRetries = 10
+
+	SubscriptionPauseStatusPaused    = "paused"
+	SubscriptionPauseStatusResumed   = "resumed"
+	SubscriptionPauseStatusCancelled = "cancelled"
+
+	SubscriptionPauseMaxPeriods = 12
+
+	// Statuses of the saved card update. The update is partial when the new card isn't filed for some of
+	// subscriptions of the customer and failed when it isn't filed for any subscription, the expiring card is kept
+	// in both cases.
+	SavedCardUpdateStatusPending   = "pending"
+	SavedCardUpdateStatusCompleted = "completed"
+	SavedCardUpdateStatusPartial   = "partial"
+	SavedCardUpdateStatusFailed    = "failed"
+
+	RefundApprovalStatusPending  = "pending"
+	RefundApprovalStatusApproved = "approved"
+	RefundApprovalStatusRejected = "rejected"
+
+	RefundFallbackStatusAwaitingDetails = "awaiting_details"
+	RefundFallbackStatusInProgress      = "in_progress"
+	RefundFallbackStatusCompleted       = "completed"
+
+	RefundFallbackDestinationBankTransfer = "bank_transfer"
+	RefundFallbackDestinationStoreCredit  = "store_credit"
+
+	// Part of the order paid by the customer wallet is refunded to the wallet without the payment system.
+	RefundDestinationWallet = "wallet"
+
+	StoreCreditSourceRefund   = "refund"
+	StoreCreditSourceGoodwill = "goodwill"
+	StoreCreditSourceGiftCard = "gift_card"
+	StoreCreditSourcePayment  = "payment"
+
+	StoreCreditSourcePaymentCanceled = "payment_canceled"
+	StoreCreditSourceOrderRefund     = "order_refund"
+
+	// Statuses of the gift card. Issued card waits in the key stock of the key product, sold card is active until
+	// its balance is spent or it expires. Card of the refunded order is canceled.
+	GiftCardStatusIssued   = "issued"
+	GiftCardStatusActive   = "active"
+	GiftCardStatusRedeemed = "redeemed"
+	GiftCardStatusExpired  = "expired"
+	GiftCardStatusCanceled = "canceled"
+
+	// Statuses of the reconciliation of the settlement report of the payment system. Run is open until all
+	// unmatched items are resolved by the finance team.
+	ReconciliationRunStatusOpen     = "open"
+	ReconciliationRunStatusResolved = "resolved"
+
+	// Statuses of the reconciliation item. Missing item is the transaction which is absent in the settlement report,
+	// extra item is the settled transaction unknown for the billing server.
+	ReconciliationItemStatusMatched        = "matched"
+	ReconciliationItemStatusMissing        = "missing"
+	ReconciliationItemStatusExtra          = "extra"
+	ReconciliationItemStatusAmountMismatch = "amount_mismatch"
+	ReconciliationItemStatusFeeMismatch    = "fee_mismatch"
+
+	// Types of the entities changes of which are recorded to the audit log.
+	AuditLogEntityMerchant                   = "merchant"
+	AuditLogEntityPaymentChannelCostSystem   = "payment_channel_cost_system"
+	AuditLogEntityPaymentChannelCostMerchant = "payment_channel_cost_merchant"
+	AuditLogEntityMoneyBackCostSystem        = "money_back_cost_system"
+	AuditLogEntityMoneyBackCostMerchant      = "money_back_cost_merchant"
+	AuditLogEntityPayoutDocument             = "payout_document"
+	AuditLogEntityUserRole                   = "user_role"
+	AuditLogEntityAccountingEntry            = "accounting_entry"
+	AuditLogEntityMerchantBalanceConversion  = "merchant_balance_conversion"
+
+	AccountingExportFormatCsv   = "csv"
+	AccountingExportFormatGlCsv = "gl_csv"
+
+	PayOneTopicNotifySubscriptionName = "notify-subscription"
 
 	MerchantOperationTypeLowRisk  = "low-risk"
 	MerchantOperationTypeHighRisk = "high-risk"
@@ -242,6 +481,10 @@ const (
 	AdminOnboardingRequestsUrl = "%s/agreement-requests"
 	UserInviteUrl              = "%s/login?invite_token=%s"
 	SystemPayoutUrl            = "%s/system-payouts/%s"
+	SubscriptionUpdateCardUrl  = "%s/subscriptions/%s"
+	SavedCardUpdateUrl         = "%s/cards/update/%s"
+	RefundApprovalUrl          = "%s/transactions/%s/refunds/%s"
+	RefundFallbackUrl          = "%s/refunds/fallback/%s"
 
 	OrderType_simple         = "simple"
 	OrderType_key            = "key"
@@ -345,5 +588,24 @@ var (
 			Path:   "/api/recurring_subscriptions/%s",
 			Method: http.MethodPatch,
 		},
+		PaymentSystemActionUpdatePayment: {
+			Path:   "/api/payments/%s",
+			Method: http.MethodPatch,
+		},
+	}
+
+	CheckoutPaths = map[string]*Path{
+		PaymentSystemActionCreatePayment: {
+			Path:   "/payments",
+			Method: http.MethodPost,
+		},
+		PaymentSystemActionRecurringPayment: {
+			Path:   "/payments",
+			Method: http.MethodPost,
+		},
+		PaymentSystemActionRefund: {
+			Path:   "/payments/%s/refunds",
+			Method: http.MethodPost,
+		},
 	}
 )